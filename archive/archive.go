@@ -0,0 +1,342 @@
+// Package archive extracts the archives of source trees given as the
+// locations of an analysis, zip and tar files possibly compressed with gzip,
+// to a workspace removed once the analysis is done. Entries escaping the
+// directory they are extracted to, through their paths or their links, are
+// rejected and the size of the extracted files is limited.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Limits are the most an archive may extract, zero means no limit
+type Limits struct {
+	// MaxSize is the most bytes of the extracted files
+	MaxSize int64
+	// MaxFiles is the most files, directories and links extracted
+	MaxFiles int
+}
+
+// DefaultLimits are the limits of the archives extracted by the analyzer
+var DefaultLimits = Limits{
+	MaxSize:  10 << 30,
+	MaxFiles: 1000000,
+}
+
+// Extensions are the extensions of the archives that are extracted, the
+// binaries of the providers such as jars are analyzed as they are
+var Extensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// IsArchive reports whether the path is a regular file with one of the
+// Extensions
+func IsArchive(path string) bool {
+	if extension(path) == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func extension(path string) string {
+	lower := strings.ToLower(path)
+	for _, ext := range Extensions {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// Workspace is a directory the archives are extracted to, every archive is
+// extracted once to a directory of its own named after it. The directory is
+// created when the first archive is extracted.
+type Workspace struct {
+	parent    string
+	dir       string
+	limits    Limits
+	mutex     sync.Mutex
+	extracted map[string]string
+}
+
+// NewWorkspace returns a workspace in a new temporary directory of the
+// parent directory, the default directory for temporary files when empty.
+func NewWorkspace(parent string, limits Limits) *Workspace {
+	return &Workspace{parent: parent, limits: limits, extracted: map[string]string{}}
+}
+
+// Dir is the directory of the workspace, empty until an archive is extracted
+func (w *Workspace) Dir() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.dir
+}
+
+// Extract extracts the archive to a directory of the workspace and returns
+// the directory. An archive already extracted is not extracted again.
+func (w *Workspace) Extract(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if dir, ok := w.extracted[abs]; ok {
+		return dir, nil
+	}
+	if w.dir == "" {
+		dir, err := os.MkdirTemp(w.parent, "konveyor-archives-")
+		if err != nil {
+			return "", fmt.Errorf("unable to create the archive workspace: %w", err)
+		}
+		w.dir = dir
+	}
+	name := filepath.Base(abs)
+	name = name[:len(name)-len(extension(name))]
+	dir := filepath.Join(w.dir, fmt.Sprintf("%d-%s", len(w.extracted)+1, name))
+	if err := Extract(abs, dir, w.limits); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	w.extracted[abs] = dir
+	return dir, nil
+}
+
+// Close removes the workspace and everything extracted to it
+func (w *Workspace) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(w.dir)
+}
+
+// Extract extracts the zip or tar archive at path to the directory. It fails
+// on the entries whose paths or links lead out of the directory and when the
+// extracted files go over the limits.
+func Extract(path string, dir string, limits Limits) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	x := &extractor{dir: dir, limits: limits}
+	var err error
+	switch extension(path) {
+	case ".zip":
+		err = x.zip(path)
+	case ".tar":
+		err = x.tar(path, false)
+	case ".tar.gz", ".tgz":
+		err = x.tar(path, true)
+	default:
+		err = fmt.Errorf("unknown archive format, the extension must be one of %v", Extensions)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to extract %s: %w", path, err)
+	}
+	return nil
+}
+
+// ErrLimit is returned when an archive goes over the limits
+var ErrLimit = errors.New("archive is over the limits")
+
+type extractor struct {
+	dir    string
+	limits Limits
+	size   int64
+	files  int
+}
+
+func (x *extractor) zip(path string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.mkdir(f.Name)
+		case mode&fs.ModeSymlink != 0:
+			err = x.zipSymlink(f)
+		case mode.IsRegular():
+			err = x.zipFile(f)
+		default:
+			err = fmt.Errorf("%s is not a file, a directory or a link", f.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) zipFile(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return x.file(f.Name, f.Mode(), rc)
+}
+
+func (x *extractor) zipSymlink(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	target, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return err
+	}
+	return x.symlink(f.Name, string(target))
+}
+
+func (x *extractor) tar(path string, gzipped bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = x.mkdir(header.Name)
+		case tar.TypeReg:
+			err = x.file(header.Name, header.FileInfo().Mode(), tr)
+		case tar.TypeSymlink:
+			err = x.symlink(header.Name, header.Linkname)
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			err = fmt.Errorf("%s is not a file, a directory or a symbolic link", header.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// path returns the path the entry is extracted to, entries must stay in the
+// directory
+func (x *extractor) path(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(strings.TrimSuffix(name, "/")))
+	if !filepath.IsLocal(clean) {
+		return "", fmt.Errorf("%s is outside of the archive", name)
+	}
+	x.files++
+	if x.limits.MaxFiles > 0 && x.files > x.limits.MaxFiles {
+		return "", fmt.Errorf("%w: more than %d files", ErrLimit, x.limits.MaxFiles)
+	}
+	return filepath.Join(x.dir, clean), nil
+}
+
+func (x *extractor) mkdir(name string) error {
+	path, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(path, 0755)
+}
+
+func (x *extractor) file(name string, mode fs.FileMode, r io.Reader) error {
+	path, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// the files are readable by the providers, executables stay executable
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644|mode.Perm()&0111)
+	if err != nil {
+		return err
+	}
+	if x.limits.MaxSize > 0 {
+		// one byte more than the limit tells an archive over it
+		r = io.LimitReader(r, x.limits.MaxSize-x.size+1)
+	}
+	n, err := io.Copy(f, r)
+	x.size += n
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if x.limits.MaxSize > 0 && x.size > x.limits.MaxSize {
+		return fmt.Errorf("%w: more than %d bytes", ErrLimit, x.limits.MaxSize)
+	}
+	return nil
+}
+
+// symlink creates a link whose target is in the directory. The target is
+// resolved from the real parent of the link, it may only go up the directory
+// tree before going down so that it leads where it looks like it leads even
+// through the other links, which are in the directory too.
+func (x *extractor) symlink(name string, target string) error {
+	path, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if !x.inside(filepath.Dir(path), filepath.FromSlash(target)) {
+		return fmt.Errorf("the link %s to %s is outside of the archive", name, target)
+	}
+	return os.Symlink(filepath.FromSlash(target), path)
+}
+
+func (x *extractor) inside(parent string, target string) bool {
+	if target == "" || filepath.IsAbs(target) || filepath.VolumeName(target) != "" || strings.HasPrefix(target, string(filepath.Separator)) {
+		return false
+	}
+	down := false
+	for _, element := range strings.Split(target, string(filepath.Separator)) {
+		if element == ".." && down {
+			return false
+		}
+		if element != ".." && element != "." && element != "" {
+			down = true
+		}
+	}
+	realDir, err := filepath.EvalSymlinks(x.dir)
+	if err != nil {
+		return false
+	}
+	realParent, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(realDir, filepath.Join(realParent, target))
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
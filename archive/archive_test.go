@@ -0,0 +1,184 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type entry struct {
+	name    string
+	content string
+	link    string
+	dir     bool
+}
+
+func writeZip(t *testing.T, path string, entries []entry) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		content := e.content
+		switch {
+		case e.dir:
+			header.Name += "/"
+			header.SetMode(os.ModeDir | 0755)
+		case e.link != "":
+			header.SetMode(os.ModeSymlink | 0777)
+			content = e.link
+		default:
+			header.SetMode(0644)
+		}
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string, entries []entry) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	w := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		switch {
+		case e.dir:
+			header = &tar.Header{Name: e.name + "/", Mode: 0755, Typeflag: tar.TypeDir}
+		case e.link != "":
+			header = &tar.Header{Name: e.name, Linkname: e.link, Mode: 0777, Typeflag: tar.TypeSymlink}
+		}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := w.Write([]byte(e.content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var project = []entry{
+	{name: "app", dir: true},
+	{name: "app/pom.xml", content: "<project/>"},
+	{name: "app/src/main/java/App.java", content: "class App {}"},
+	{name: "app/lib", link: "src/main/java"},
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		entries []entry
+		limits  Limits
+		errMsg  string
+	}{
+		{name: "zip", ext: ".zip", entries: project},
+		{name: "tar.gz", ext: ".tar.gz", entries: project},
+		{name: "path outside", ext: ".zip", entries: []entry{{name: "../evil", content: "evil"}}, errMsg: "../evil is outside of the archive"},
+		{name: "absolute path", ext: ".tar.gz", entries: []entry{{name: "/tmp/evil", content: "evil"}}, errMsg: "/tmp/evil is outside of the archive"},
+		{name: "absolute link", ext: ".tar.gz", entries: []entry{{name: "passwd", link: "/etc/passwd"}}, errMsg: "the link passwd to /etc/passwd is outside of the archive"},
+		{name: "link outside", ext: ".zip", entries: []entry{{name: "app/parent", link: "../.."}}, errMsg: "the link app/parent to ../.. is outside of the archive"},
+		{name: "link up through a link", ext: ".tar.gz", entries: []entry{
+			{name: "app/here", link: "."},
+			{name: "app/up", link: "here/../.."},
+		}, errMsg: "the link app/up to here/../.. is outside of the archive"},
+		{name: "link up", ext: ".tar.gz", entries: []entry{
+			{name: "app/lib/a.jar", content: "jar"},
+			{name: "app/src/a.jar", link: "../lib/a.jar"},
+		}},
+		{name: "size limit", ext: ".zip", entries: project, limits: Limits{MaxSize: 15}, errMsg: "archive is over the limits: more than 15 bytes"},
+		{name: "file limit", ext: ".tar.gz", entries: project, limits: Limits{MaxFiles: 3}, errMsg: "archive is over the limits: more than 3 files"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "project"+tt.ext)
+			if tt.ext == ".zip" {
+				writeZip(t, path, tt.entries)
+			} else {
+				writeTarGz(t, path, tt.entries)
+			}
+			dir := filepath.Join(t.TempDir(), "extracted")
+			err := Extract(path, dir, tt.limits)
+			if tt.errMsg != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tt.errMsg) {
+					t.Fatalf("Extract() error = %v, want %s", err, tt.errMsg)
+				}
+				if strings.HasPrefix(tt.errMsg, "archive is over") && !errors.Is(err, ErrLimit) {
+					t.Errorf("Extract() error = %v, want ErrLimit", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			for _, e := range tt.entries {
+				if e.content == "" {
+					continue
+				}
+				got, err := os.ReadFile(filepath.Join(dir, e.name))
+				if err != nil || string(got) != e.content {
+					t.Errorf("%s = %q %v, want %q", e.name, got, err, e.content)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	parent := t.TempDir()
+	path := filepath.Join(t.TempDir(), "project.tgz")
+	writeTarGz(t, path, project)
+	if !IsArchive(path) || IsArchive(filepath.Dir(path)) || IsArchive(filepath.Join(parent, "missing.zip")) {
+		t.Fatal("only the archive files are archives")
+	}
+	workspace := NewWorkspace(parent, DefaultLimits)
+	if entries, _ := os.ReadDir(parent); len(entries) != 0 || workspace.Dir() != "" {
+		t.Errorf("the workspace must only be created with the first archive, got %v", entries)
+	}
+	dir, err := workspace.Extract(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(dir) != workspace.Dir() || filepath.Base(dir) != "1-project" {
+		t.Errorf("Extract() = %s, want 1-project in the workspace", dir)
+	}
+	if again, err := workspace.Extract(path); err != nil || again != dir {
+		t.Errorf("Extract() = %s %v, want the archive extracted once to %s", again, err, dir)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "app", "lib", "App.java")); err != nil || string(content) != "class App {}" {
+		t.Errorf("App.java = %q %v", content, err)
+	}
+	if err := workspace.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(workspace.Dir()); !os.IsNotExist(err) {
+		t.Errorf("the workspace must be removed, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/archive"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// withExtractedArchives replaces the locations of the provider init configs
+// that are archives by the directories of the workspace they are extracted
+// to. It returns the configs along with the locations of the archives keyed
+// by the directories they are extracted to.
+func withExtractedArchives(configs []provider.Config, workspace *archive.Workspace, log logr.Logger) ([]provider.Config, map[string]string, error) {
+	aliases := map[string]string{}
+	for _, config := range configs {
+		for i, initConf := range config.InitConfig {
+			if initConf.Location == "" || !archive.IsArchive(initConf.Location) {
+				continue
+			}
+			dir, err := workspace.Extract(initConf.Location)
+			if err != nil {
+				return nil, nil, err
+			}
+			log.Info("extracted archive", "provider", config.Name, "archive", initConf.Location, "dir", dir)
+			aliases[dir] = initConf.Location
+			config.InitConfig[i].Location = dir
+		}
+	}
+	return configs, aliases, nil
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	logrusr "github.com/bombsimon/logrusr/v3"
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/convert"
+	"github.com/konveyor/analyzer-lsp/process"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/grpc"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	batchSummaryFile  = "summary.yaml"
+	batchSettingsFile = "provider_settings.yaml"
+	batchOutputFile   = "output.yaml"
+	batchLogFile      = "analysis.log"
+)
+
+// Statuses of the analyses of a batch
+const (
+	batchSucceeded = "succeeded"
+	// batchPolicyFailed is the status of the analyses that wrote their
+	// output and met a --fail-on policy
+	batchPolicyFailed = "policy-failed"
+	batchFailed       = "failed"
+)
+
+// batchManifest lists the applications of a batch. The fields of the
+// manifest are the defaults of its applications, the paths are relative
+// to the manifest.
+type batchManifest struct {
+	ProviderSettings string   `yaml:"providerSettings,omitempty"`
+	Rules            []string `yaml:"rules,omitempty"`
+	LabelSelector    string   `yaml:"labelSelector,omitempty"`
+	// Args are added to the arguments of the analyses, before the ones of
+	// the applications
+	Args []string   `yaml:"args,omitempty"`
+	Apps []batchApp `yaml:"apps"`
+}
+
+type batchApp struct {
+	// Name is the directory of the outputs of the application, the base
+	// name of its location by default
+	Name string `yaml:"name,omitempty"`
+	// Location replaces the locations of the providers of the settings
+	Location         string   `yaml:"location"`
+	ProviderSettings string   `yaml:"providerSettings,omitempty"`
+	Rules            []string `yaml:"rules,omitempty"`
+	LabelSelector    string   `yaml:"labelSelector,omitempty"`
+	Args             []string `yaml:"args,omitempty"`
+}
+
+// batchSummary is the roll-up of the analyses of a batch
+type batchSummary struct {
+	Started   time.Time         `yaml:"started"`
+	Duration  string            `yaml:"duration"`
+	Succeeded int               `yaml:"succeeded"`
+	Failed    int               `yaml:"failed"`
+	Apps      []batchAppSummary `yaml:"apps"`
+}
+
+type batchAppSummary struct {
+	Name     string `yaml:"name"`
+	Location string `yaml:"location"`
+	Status   string `yaml:"status"`
+	ExitCode int    `yaml:"exitCode"`
+	Error    string `yaml:"error,omitempty"`
+	Duration string `yaml:"duration"`
+	// Output and Log are relative to the output directory
+	Output string `yaml:"output,omitempty"`
+	Log    string `yaml:"log"`
+	// Violations and Incidents are the numbers of violations and of their
+	// incidents in the output
+	Violations int `yaml:"violations"`
+	Incidents  int `yaml:"incidents"`
+}
+
+// BatchCmd analyzes the applications of a manifest, every application with
+// an analysis of its own in the output directory, and writes a summary of
+// the analyses.
+func BatchCmd() *cobra.Command {
+	var (
+		manifestFile   string
+		outputDir      string
+		parallelism    int
+		shareProviders bool
+	)
+	batchCmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Analyze the applications of a manifest",
+		Long: "Analyze every application of the manifest, with bounded parallelism, writing the output and the " +
+			"logs of every analysis to a directory of its own in the output directory and a summary of the " +
+			"analyses to " + batchSummaryFile + ", rolled up by the portfolio command into " + batchPortfolioFile + ". The provider binaries with the same settings are started once " +
+			"and shared by the analyses.",
+		RunE: func(c *cobra.Command, args []string) error {
+			if manifestFile == "" {
+				return fmt.Errorf("the manifest of the applications must be given with --manifest")
+			}
+			if outputDir == "" {
+				return fmt.Errorf("the output directory must be given with --output-dir")
+			}
+			if parallelism < 1 {
+				return fmt.Errorf("parallelism must be at least 1")
+			}
+			manifest, err := loadBatchManifest(manifestFile)
+			if err != nil {
+				return err
+			}
+			c.SilenceUsage = true
+			logrusLog := logrus.New()
+			logrusLog.SetOutput(os.Stdout)
+			logrusLog.SetLevel(logrus.Level(logLevel))
+			log := logrusr.New(logrusLog)
+
+			if reaped := process.ReapOrphans(log); reaped > 0 {
+				log.Info("stopped orphaned processes of a previous analysis", "groups", reaped)
+			}
+			self, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(outputDir, 0755); err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			shared := &sharedProviders{log: log, providers: map[string]*sharedProvider{}}
+			defer shared.stop()
+
+			summary := batchSummary{Started: time.Now().UTC(), Apps: make([]batchAppSummary, len(manifest.Apps))}
+			sem := make(chan struct{}, parallelism)
+			wg := sync.WaitGroup{}
+			for i, app := range manifest.Apps {
+				appDir := filepath.Join(outputDir, app.Name)
+				if err := os.MkdirAll(appDir, 0755); err != nil {
+					return err
+				}
+				settings, _, err := batchSettings(ctx, manifest, app, shared, shareProviders)
+				if err != nil {
+					summary.Apps[i] = batchAppSummary{Name: app.Name, Location: app.Location, Status: batchFailed, ExitCode: 1, Error: err.Error()}
+					log.Error(err, "unable to create the provider settings of the application", "app", app.Name)
+					continue
+				}
+				settingsPath := filepath.Join(appDir, batchSettingsFile)
+				if err := os.WriteFile(settingsPath, settings, 0644); err != nil {
+					return err
+				}
+				wg.Add(1)
+				sem <- struct{}{}
+				go func(i int, app batchApp) {
+					defer wg.Done()
+					defer func() { <-sem }()
+					summary.Apps[i] = runBatchApp(ctx, log, self, manifest, app, appDir, settingsPath)
+				}(i, app)
+			}
+			wg.Wait()
+
+			policyFailed := false
+			for _, app := range summary.Apps {
+				switch app.Status {
+				case batchFailed:
+					summary.Failed++
+				case batchPolicyFailed:
+					policyFailed = true
+					summary.Succeeded++
+				default:
+					summary.Succeeded++
+				}
+			}
+			summary.Duration = time.Since(summary.Started).Round(time.Millisecond).String()
+			b, err := yaml.Marshal(summary)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(outputDir, batchSummaryFile), b, 0644); err != nil {
+				return err
+			}
+			portfolio, err := newPortfolio(batchOutputs(outputDir, summary.Apps), defaultTopRules)
+			if err != nil {
+				return err
+			}
+			if b, err = yaml.Marshal(portfolio); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(outputDir, batchPortfolioFile), b, 0644); err != nil {
+				return err
+			}
+			log.Info("analyzed the applications", "succeeded", summary.Succeeded, "failed", summary.Failed, "summary", filepath.Join(outputDir, batchSummaryFile))
+			if summary.Failed > 0 {
+				return fmt.Errorf("%d of %d analyses failed", summary.Failed, len(summary.Apps))
+			}
+			if policyFailed {
+				shared.stop()
+				os.Exit(EXIT_ON_ERROR_CODE)
+			}
+			return nil
+		},
+	}
+	batchCmd.Flags().StringVar(&manifestFile, "manifest", "", "path to the manifest of the applications to analyze")
+	batchCmd.Flags().StringVar(&outputDir, "output-dir", "", "directory to write the outputs of the analyses and their summary to")
+	batchCmd.Flags().IntVar(&parallelism, "parallelism", 1, "number of applications analyzed at once")
+	batchCmd.Flags().BoolVar(&shareProviders, "share-providers", true, "start the provider binaries with the same settings once for every analysis, the providers run from an image are started by every analysis as their container mounts its locations")
+	batchCmd.Flags().IntVar(&logLevel, "verbose", 9, "level for logging output")
+	return batchCmd
+}
+
+// loadBatchManifest reads the manifest, the paths of the manifest are made
+// relative to the working directory and the applications get their names
+func loadBatchManifest(path string) (batchManifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return batchManifest{}, err
+	}
+	manifest := batchManifest{}
+	if err := yaml.UnmarshalStrict(content, &manifest); err != nil {
+		return batchManifest{}, fmt.Errorf("unable to read the manifest %s: %w", path, err)
+	}
+	if len(manifest.Apps) == 0 {
+		return batchManifest{}, fmt.Errorf("the manifest %s has no applications", path)
+	}
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	resolveAll := func(paths []string) []string {
+		resolved := []string{}
+		for _, p := range paths {
+			resolved = append(resolved, resolve(p))
+		}
+		return resolved
+	}
+	manifest.ProviderSettings = resolve(manifest.ProviderSettings)
+	manifest.Rules = resolveAll(manifest.Rules)
+	names := map[string]bool{}
+	for i := range manifest.Apps {
+		app := &manifest.Apps[i]
+		if app.Location == "" {
+			return batchManifest{}, fmt.Errorf("application %d of the manifest has no location", i+1)
+		}
+		app.Location = resolve(app.Location)
+		app.ProviderSettings = resolve(app.ProviderSettings)
+		app.Rules = resolveAll(app.Rules)
+		if app.ProviderSettings == "" {
+			app.ProviderSettings = manifest.ProviderSettings
+		}
+		if app.ProviderSettings == "" {
+			return batchManifest{}, fmt.Errorf("application %s has no provider settings", app.Location)
+		}
+		if app.Name == "" {
+			app.Name = filepath.Base(app.Location)
+		}
+		if !filepath.IsLocal(app.Name) || filepath.Base(app.Name) != app.Name {
+			return batchManifest{}, fmt.Errorf("the name of application %s must be a file name", app.Name)
+		}
+		if names[app.Name] {
+			return batchManifest{}, fmt.Errorf("several applications are named %s, their names must be unique", app.Name)
+		}
+		names[app.Name] = true
+	}
+	return manifest, nil
+}
+
+// sharedProviders are the provider binaries started once for the analyses
+// of a batch, by a digest of their settings
+type sharedProviders struct {
+	log       logr.Logger
+	mutex     sync.Mutex
+	providers map[string]*sharedProvider
+	// keep are the keys of the providers of the current settings once they
+	// are reloaded, the other providers are stopped once no analysis uses
+	// them
+	keep map[string]bool
+}
+
+type sharedProvider struct {
+	name    string
+	address string
+	stop    func()
+	// analyses is the number of analyses using the provider
+	analyses int
+}
+
+// address returns the address of the provider of the settings, started the
+// first time it is asked for, the analysis releases it once it is done
+func (s *sharedProviders) address(ctx context.Context, key string, config provider.Config) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if p, ok := s.providers[key]; ok {
+		p.analyses++
+		return p.address, nil
+	}
+	address, stop, err := grpc.Launch(ctx, s.log.WithName(config.Name), config)
+	if err != nil {
+		return "", err
+	}
+	s.log.Info("started shared provider", "provider", config.Name, "address", address)
+	s.providers[key] = &sharedProvider{name: config.Name, address: address, stop: stop, analyses: 1}
+	return address, nil
+}
+
+// release tells the providers are no longer used by an analysis, stopping
+// the ones of previous settings
+func (s *sharedProviders) release(keys []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, key := range keys {
+		if p, ok := s.providers[key]; ok {
+			p.analyses--
+		}
+	}
+	s.stopRetired()
+}
+
+// retire keeps the providers of the keys, the other providers are stopped
+// once their analyses are done. It returns the names of the providers to
+// stop.
+func (s *sharedProviders) retire(keep map[string]bool) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keep = keep
+	names := []string{}
+	for key, p := range s.providers {
+		if !keep[key] {
+			names = append(names, p.name)
+		}
+	}
+	sort.Strings(names)
+	s.stopRetired()
+	return names
+}
+
+func (s *sharedProviders) stopRetired() {
+	if s.keep == nil {
+		return
+	}
+	for key, p := range s.providers {
+		if !s.keep[key] && p.analyses <= 0 {
+			p.stop()
+			delete(s.providers, key)
+			s.log.Info("stopped shared provider of previous settings", "provider", p.name, "address", p.address)
+		}
+	}
+}
+
+func (s *sharedProviders) stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, p := range s.providers {
+		p.stop()
+		delete(s.providers, key)
+	}
+}
+
+// sharedProviderKey returns the key of the provider of the settings, the
+// provider binaries with the same settings but their init configs serve
+// every analysis
+func sharedProviderKey(settings map[string]interface{}) (string, error) {
+	key := map[string]interface{}{}
+	for k, v := range settings {
+		if k != "initConfig" {
+			key[k] = v
+		}
+	}
+	b, err := yaml.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(b)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// batchSettings returns the provider settings of the application, its
+// location replaces the locations of the settings and the providers shared
+// by the analyses are given by their address. The keys of the shared
+// providers are returned to release them once the analysis is done.
+func batchSettings(ctx context.Context, manifest batchManifest, app batchApp, shared *sharedProviders, share bool) ([]byte, []string, error) {
+	// the settings are checked as the analyzer does, they are then changed
+	// as they are written to keep the other settings of the user as they are
+	configs, err := provider.GetConfig(app.ProviderSettings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get configuration: %w", err)
+	}
+	content, err := os.ReadFile(app.ProviderSettings)
+	if err != nil {
+		return nil, nil, err
+	}
+	settings := []map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &settings); err != nil {
+		return nil, nil, err
+	}
+	keys := []string{}
+	for i, s := range settings {
+		initConfigs, _ := s["initConfig"].([]interface{})
+		for _, ic := range initConfigs {
+			if m, ok := ic.(map[interface{}]interface{}); ok {
+				m["location"] = app.Location
+			}
+		}
+		if !share || i >= len(configs) || configs[i].BinaryPath == "" {
+			continue
+		}
+		key, err := sharedProviderKey(s)
+		if err != nil {
+			return nil, keys, err
+		}
+		config := configs[i]
+		for j := range config.InitConfig {
+			config.InitConfig[j].Location = app.Location
+		}
+		address, err := shared.address(ctx, key, config)
+		if err != nil {
+			shared.log.Error(err, "unable to start shared provider, the analysis starts it", "provider", config.Name, "app", app.Name)
+			continue
+		}
+		keys = append(keys, key)
+		delete(s, "binaryPath")
+		s["address"] = address
+	}
+	b, err := yaml.Marshal(settings)
+	return b, keys, err
+}
+
+// runBatchApp runs the analysis of the application with the analyzer
+func runBatchApp(ctx context.Context, log logr.Logger, self string, manifest batchManifest, app batchApp, appDir string, settingsPath string) (summary batchAppSummary) {
+	summary = batchAppSummary{
+		Name:     app.Name,
+		Location: app.Location,
+		Log:      filepath.Join(app.Name, batchLogFile),
+	}
+	logPath := filepath.Join(appDir, batchLogFile)
+	output := filepath.Join(appDir, batchOutputFile)
+	args := []string{"--provider-settings", settingsPath, "--output-file", output}
+	rules := app.Rules
+	if len(rules) == 0 {
+		rules = manifest.Rules
+	}
+	for _, r := range rules {
+		args = append(args, "--rules", r)
+	}
+	labelSelector := app.LabelSelector
+	if labelSelector == "" {
+		labelSelector = manifest.LabelSelector
+	}
+	if labelSelector != "" {
+		args = append(args, "--label-selector", labelSelector)
+	}
+	args = append(args, manifest.Args...)
+	args = append(args, app.Args...)
+
+	start := time.Now()
+	defer func() {
+		summary.Duration = time.Since(start).Round(time.Millisecond).String()
+	}()
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		summary.Status, summary.ExitCode, summary.Error = batchFailed, 1, err.Error()
+		return summary
+	}
+	defer logFile.Close()
+	log.Info("analyzing application", "app", app.Name, "location", app.Location)
+	cmd := exec.CommandContext(ctx, self, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	err = cmd.Run()
+	exitErr := &exec.ExitError{}
+	switch {
+	case err == nil:
+		summary.Status = batchSucceeded
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == EXIT_ON_ERROR_CODE:
+		summary.Status, summary.ExitCode = batchPolicyFailed, EXIT_ON_ERROR_CODE
+	case errors.As(err, &exitErr):
+		summary.Status, summary.ExitCode, summary.Error = batchFailed, exitErr.ExitCode(), fmt.Sprintf("the analysis failed, see %s", logPath)
+	default:
+		summary.Status, summary.ExitCode, summary.Error = batchFailed, 1, err.Error()
+	}
+	log.Info("analyzed application", "app", app.Name, "status", summary.Status)
+	if summary.Status == batchFailed {
+		return summary
+	}
+	result, err := convert.ReadFile(output)
+	if err != nil {
+		summary.Status, summary.Error = batchFailed, fmt.Sprintf("unable to read the output: %v", err)
+		return summary
+	}
+	summary.Output = filepath.Join(app.Name, batchOutputFile)
+	for _, ruleSet := range result.RuleSets {
+		summary.Violations += len(ruleSet.Violations)
+		for _, v := range ruleSet.Violations {
+			summary.Incidents += len(v.Incidents)
+		}
+	}
+	return summary
+}
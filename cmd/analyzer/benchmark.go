@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+const (
+	benchmarkMemoryPollInterval = 250 * time.Millisecond
+	bytesInMB                   = 1024 * 1024
+)
+
+// capacityPlan is the projection of a full run created from a sampled run
+type capacityPlan struct {
+	SampleFraction     float64 `yaml:"sampleFraction"`
+	TotalRules         int     `yaml:"totalRules"`
+	SampledRules       int     `yaml:"sampledRules"`
+	SampleDuration     string  `yaml:"sampleDuration"`
+	ProjectedDuration  string  `yaml:"projectedDuration"`
+	SampledIncidents   int     `yaml:"sampledIncidents"`
+	ProjectedIncidents int     `yaml:"projectedIncidents"`
+	// Memory is only measured for the analyzer process, providers running
+	// in their own process or container are not accounted for.
+	BaselineMemoryMB    uint64 `yaml:"baselineMemoryMB"`
+	SampledPeakMemoryMB uint64 `yaml:"sampledPeakMemoryMB"`
+	ProjectedMemoryMB   uint64 `yaml:"projectedMemoryMB"`
+	SuggestedWorkers    int    `yaml:"suggestedWorkers"`
+	SuggestedLimits     struct {
+		Incidents int `yaml:"limitIncidents"`
+		CodeSnips int `yaml:"limitCodeSnips"`
+	} `yaml:"suggestedLimits"`
+	Notes []string `yaml:"notes,omitempty"`
+}
+
+// sampleRuleSets keeps an evenly distributed fraction of the rules of every ruleset.
+// Rulesets with rules always keep at least one rule. Only the rules are sampled,
+// the projection assumes the cost of the rules doesn't depend on which ones are
+// sampled, the files of the application are all analyzed by the sampled rules.
+func sampleRuleSets(ruleSets []engine.RuleSet, fraction float64) ([]engine.RuleSet, int, int) {
+	step := int(math.Max(1, math.Round(1/fraction)))
+	total := 0
+	sampled := 0
+	sampledRuleSets := []engine.RuleSet{}
+	for _, rs := range ruleSets {
+		total += len(rs.Rules)
+		rules := []engine.Rule{}
+		for i, rule := range rs.Rules {
+			if i%step == 0 {
+				rules = append(rules, rule)
+			}
+		}
+		sampled += len(rules)
+		rs.Rules = rules
+		sampledRuleSets = append(sampledRuleSets, rs)
+	}
+	return sampledRuleSets, total, sampled
+}
+
+// runCapacityBenchmark runs a sample of the rules and extrapolates the duration
+// and memory of the full run, it does not write any analysis output.
+func runCapacityBenchmark(ctx context.Context, log logr.Logger, eng engine.RuleEngine, ruleSets []engine.RuleSet, fraction float64, selectors ...engine.RuleSelector) capacityPlan {
+	sampledRuleSets, total, sampled := sampleRuleSets(ruleSets, fraction)
+	plan := capacityPlan{
+		SampleFraction: fraction,
+		TotalRules:     total,
+		SampledRules:   sampled,
+	}
+
+	stats := runtime.MemStats{}
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	baseline := stats.HeapAlloc
+	peak := baseline
+
+	done := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(benchmarkMemoryPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s := runtime.MemStats{}
+				runtime.ReadMemStats(&s)
+				if s.HeapAlloc > peak {
+					peak = s.HeapAlloc
+				}
+			}
+		}
+	}()
+
+	log.Info("running rule sample for capacity plan", "sampledRules", sampled, "totalRules", total)
+	start := time.Now()
+	results := eng.RunRules(ctx, sampledRuleSets, selectors...)
+	elapsed := time.Since(start)
+	close(done)
+	wg.Wait()
+	runtime.ReadMemStats(&stats)
+	if stats.HeapAlloc > peak {
+		peak = stats.HeapAlloc
+	}
+
+	plan.SampledIncidents = countIncidents(results)
+	scale := 1.0
+	if sampled > 0 {
+		scale = float64(total) / float64(sampled)
+	}
+	plan.SampleDuration = elapsed.Round(time.Millisecond).String()
+	plan.ProjectedDuration = time.Duration(float64(elapsed) * scale).Round(time.Second).String()
+	plan.ProjectedIncidents = int(math.Ceil(float64(plan.SampledIncidents) * scale))
+	plan.BaselineMemoryMB = baseline / bytesInMB
+	plan.SampledPeakMemoryMB = peak / bytesInMB
+	plan.ProjectedMemoryMB = (baseline + uint64(float64(peak-baseline)*scale)) / bytesInMB
+
+	plan.SuggestedWorkers = runtime.NumCPU()
+	if plan.SuggestedWorkers > total && total > 0 {
+		plan.SuggestedWorkers = total
+	}
+	plan.SuggestedLimits.Incidents = limitIncidents
+	plan.SuggestedLimits.CodeSnips = limitCodeSnips
+	if limitIncidents == 0 {
+		plan.SuggestedLimits.Incidents = 1500
+		plan.Notes = append(plan.Notes, "incidents are not limited, consider setting --limit-incidents to reduce memory usage")
+	} else if total > 0 && plan.ProjectedIncidents/total > limitIncidents/2 {
+		plan.SuggestedLimits.Incidents = limitIncidents / 2
+		plan.Notes = append(plan.Notes, "the projected number of incidents per rule is high, consider lowering --limit-incidents")
+	}
+	if limitCodeSnips == 0 {
+		plan.SuggestedLimits.CodeSnips = 20
+		plan.Notes = append(plan.Notes, "code snippets are not limited, consider setting --limit-code-snips to reduce memory usage")
+	}
+	if sampled < 10 {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("only %d rules were sampled, the projection may not be accurate", sampled))
+	}
+	return plan
+}
+
+func countIncidents(ruleSets []konveyor.RuleSet) int {
+	count := 0
+	for _, rs := range ruleSets {
+		for _, v := range rs.Violations {
+			count += len(v.Incidents)
+		}
+		for _, v := range rs.Insights {
+			count += len(v.Incidents)
+		}
+	}
+	return count
+}
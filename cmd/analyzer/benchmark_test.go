@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"go.lsp.dev/uri"
+)
+
+// benchmarkConditional matches with an incident in every file
+type benchmarkConditional struct {
+	files int
+}
+
+func (b benchmarkConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx engine.ConditionContext) (engine.ConditionResponse, error) {
+	response := engine.ConditionResponse{Matched: true}
+	for i := 0; i < b.files; i++ {
+		response.Incidents = append(response.Incidents, engine.IncidentContext{
+			FileURI: uri.File(fmt.Sprintf("/app/File%d.java", i)),
+		})
+	}
+	return response, nil
+}
+
+func benchmarkRuleSets(rules ...int) []engine.RuleSet {
+	message := "message"
+	effort := 1
+	ruleSets := []engine.RuleSet{}
+	for i, count := range rules {
+		ruleSet := engine.RuleSet{Name: fmt.Sprintf("ruleset-%d", i)}
+		for j := 0; j < count; j++ {
+			ruleSet.Rules = append(ruleSet.Rules, engine.Rule{
+				RuleMeta: engine.RuleMeta{RuleID: fmt.Sprintf("rule-%d-%d", i, j), Effort: &effort},
+				Perform:  engine.Perform{Message: engine.Message{Text: &message}},
+				When:     benchmarkConditional{files: 3},
+			})
+		}
+		ruleSets = append(ruleSets, ruleSet)
+	}
+	return ruleSets
+}
+
+func Test_sampleRuleSets(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []int
+		fraction float64
+		want     [][]string
+		sampled  int
+	}{
+		{
+			name:     "every rule",
+			rules:    []int{3},
+			fraction: 1,
+			want:     [][]string{{"rule-0-0", "rule-0-1", "rule-0-2"}},
+			sampled:  3,
+		},
+		{
+			name:     "evenly distributed",
+			rules:    []int{10},
+			fraction: 0.25,
+			want:     [][]string{{"rule-0-0", "rule-0-4", "rule-0-8"}},
+			sampled:  3,
+		},
+		{
+			name:     "a rule of every ruleset",
+			rules:    []int{2, 1, 0},
+			fraction: 0.1,
+			want:     [][]string{{"rule-0-0"}, {"rule-1-0"}, {}},
+			sampled:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleSets, total, sampled := sampleRuleSets(benchmarkRuleSets(tt.rules...), tt.fraction)
+			got := [][]string{}
+			for _, ruleSet := range ruleSets {
+				ids := []string{}
+				for _, rule := range ruleSet.Rules {
+					ids = append(ids, rule.RuleID)
+				}
+				got = append(got, ids)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected the rules %v, got %v", tt.want, got)
+			}
+			wantTotal := 0
+			for _, count := range tt.rules {
+				wantTotal += count
+			}
+			if total != wantTotal || sampled != tt.sampled {
+				t.Errorf("expected %d rules of %d sampled, got %d of %d", tt.sampled, wantTotal, sampled, total)
+			}
+		})
+	}
+}
+
+func Test_runCapacityBenchmark(t *testing.T) {
+	incidents, codeSnips := limitIncidents, limitCodeSnips
+	defer func() { limitIncidents, limitCodeSnips = incidents, codeSnips }()
+	limitIncidents, limitCodeSnips = 0, 0
+	eng := engine.CreateRuleEngine(context.Background(), 2, logr.Discard())
+	defer eng.Stop()
+
+	plan := runCapacityBenchmark(context.Background(), logr.Discard(), eng, benchmarkRuleSets(8), 0.5)
+	if plan.TotalRules != 8 || plan.SampledRules != 4 {
+		t.Errorf("expected 4 rules of 8 sampled, got %d of %d", plan.SampledRules, plan.TotalRules)
+	}
+	// every sampled rule has an incident in each of the 3 files
+	if plan.SampledIncidents != 12 || plan.ProjectedIncidents != 24 {
+		t.Errorf("expected 12 sampled and 24 projected incidents, got %d and %d", plan.SampledIncidents, plan.ProjectedIncidents)
+	}
+	if plan.SuggestedLimits.Incidents != 1500 || plan.SuggestedLimits.CodeSnips != 20 {
+		t.Errorf("expected the default limits to be suggested, got %+v", plan.SuggestedLimits)
+	}
+	wantNotes := []string{
+		"incidents are not limited, consider setting --limit-incidents to reduce memory usage",
+		"code snippets are not limited, consider setting --limit-code-snips to reduce memory usage",
+		"only 4 rules were sampled, the projection may not be accurate",
+	}
+	if !reflect.DeepEqual(plan.Notes, wantNotes) {
+		t.Errorf("expected the notes %q, got %q", wantNotes, plan.Notes)
+	}
+	if plan.ProjectedMemoryMB < plan.BaselineMemoryMB {
+		t.Errorf("expected the projected memory %d to be at least the baseline %d", plan.ProjectedMemoryMB, plan.BaselineMemoryMB)
+	}
+}
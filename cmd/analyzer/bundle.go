@@ -0,0 +1,246 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"gopkg.in/yaml.v2"
+)
+
+// captureManifest describes the content of a capture bundle
+type captureManifest struct {
+	RuleID      string             `yaml:"ruleID"`
+	RuleFile    string             `yaml:"ruleFile"`
+	CreatedAt   string             `yaml:"createdAt"`
+	Args        []string           `yaml:"args"`
+	Evaluations int                `yaml:"evaluations"`
+	FileSlices  []captureFileSlice `yaml:"fileSlices,omitempty"`
+}
+
+// captureFileSlice is the part of a file of the application an incident was
+// found in, stored in the bundle at Path
+type captureFileSlice struct {
+	File      string `yaml:"file"`
+	StartLine int    `yaml:"startLine"`
+	EndLine   int    `yaml:"endLine"`
+	Path      string `yaml:"path"`
+}
+
+// captureResult is what the analysis resulted in for the captured rule
+type captureResult struct {
+	RuleSet   string              `yaml:"ruleSet,omitempty"`
+	Status    string              `yaml:"status"`
+	Violation *konveyor.Violation `yaml:"violation,omitempty"`
+	Error     *konveyor.RuleError `yaml:"error,omitempty"`
+}
+
+// writeCaptureBundle writes a gzipped tarball with the rule, the provider
+// conditions evaluated for it with their responses, the slices of the files
+// incidents were found in and the result of the rule.
+func writeCaptureBundle(path string, recorder *provider.EvaluationRecorder, rulePaths []string, rulesets []konveyor.RuleSet, contextLines int) error {
+	ruleFile, rule, ruleSet, err := findRule(rulePaths, recorder.RuleID())
+	if err != nil {
+		return err
+	}
+	records := recorder.Records()
+	manifest := captureManifest{
+		RuleID:      recorder.RuleID(),
+		RuleFile:    ruleFile,
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+		Args:        os.Args,
+		Evaluations: len(records),
+	}
+	files := map[string][]byte{}
+	for _, record := range records {
+		for _, incident := range record.Response.Incidents {
+			slice, content, err := sliceIncidentFile(incident, contextLines)
+			if err != nil || slice == nil {
+				continue
+			}
+			if _, ok := files[slice.Path]; !ok {
+				files[slice.Path] = content
+				manifest.FileSlices = append(manifest.FileSlices, *slice)
+			}
+		}
+	}
+
+	entries := []struct {
+		name  string
+		value interface{}
+	}{
+		{"manifest.yaml", manifest},
+		{"rule.yaml", []yaml.MapSlice{rule}},
+		{"evaluations.yaml", records},
+		{"result.yaml", ruleResult(recorder.RuleID(), rulesets)},
+	}
+	if ruleSet != nil {
+		entries = append(entries, struct {
+			name  string
+			value interface{}
+		}{"ruleset.yaml", ruleSet})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, entry := range entries {
+		content, err := yaml.Marshal(entry.value)
+		if err != nil {
+			return fmt.Errorf("unable to marshal %s: %w", entry.name, err)
+		}
+		if err := addTarFile(tw, entry.name, content); err != nil {
+			return err
+		}
+	}
+	for _, slice := range manifest.FileSlices {
+		if err := addTarFile(tw, slice.Path, files[slice.Path]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func addTarFile(tw *tar.Writer, name string, content []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: time.Now().Truncate(time.Second),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(content)
+	return err
+}
+
+// findRule returns the file of the rule with the ID, the rule as written in
+// it and the ruleset of the file when there is one
+func findRule(rulePaths []string, ruleID string) (string, yaml.MapSlice, yaml.MapSlice, error) {
+	var (
+		found     string
+		foundRule yaml.MapSlice
+	)
+	for _, rulePath := range rulePaths {
+		err := filepath.Walk(rulePath, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || found != "" {
+				return err
+			}
+			if ext := filepath.Ext(p); (ext != ".yaml" && ext != ".yml") || info.Name() == "ruleset.yaml" {
+				return nil
+			}
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			rules := []yaml.MapSlice{}
+			if err := yaml.Unmarshal(content, &rules); err != nil {
+				// not a rule file, the parser already reported it
+				return nil
+			}
+			for _, rule := range rules {
+				for _, item := range rule {
+					if item.Key == "ruleID" && item.Value == ruleID {
+						found, foundRule = p, rule
+						return nil
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return "", nil, nil, err
+		}
+	}
+	if found == "" {
+		return "", nil, nil, fmt.Errorf("unable to find rule %s in the rules", ruleID)
+	}
+	var ruleSet yaml.MapSlice
+	if content, err := os.ReadFile(filepath.Join(filepath.Dir(found), "ruleset.yaml")); err == nil {
+		if err := yaml.Unmarshal(content, &ruleSet); err != nil {
+			ruleSet = nil
+		}
+	}
+	return found, foundRule, ruleSet, nil
+}
+
+// sliceIncidentFile returns the lines around the incident, nil when the
+// incident has no line or is not in a local file
+func sliceIncidentFile(incident provider.IncidentContext, contextLines int) (*captureFileSlice, []byte, error) {
+	if incident.LineNumber == nil || !strings.HasPrefix(string(incident.FileURI), "file://") {
+		return nil, nil, nil
+	}
+	file := incident.FileURI.Filename()
+	slice := &captureFileSlice{
+		File:      file,
+		StartLine: max(1, *incident.LineNumber-contextLines),
+		EndLine:   *incident.LineNumber + contextLines,
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	content := strings.Builder{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for line < slice.EndLine && scanner.Scan() {
+		line++
+		if line >= slice.StartLine {
+			content.WriteString(scanner.Text())
+			content.WriteString("\n")
+		}
+	}
+	if line < slice.StartLine {
+		return nil, nil, nil
+	}
+	slice.EndLine = line
+	slice.Path = fmt.Sprintf("files/%s.L%d-%d", strings.TrimPrefix(filepath.ToSlash(file), "/"), slice.StartLine, slice.EndLine)
+	return slice, []byte(content.String()), scanner.Err()
+}
+
+func ruleResult(ruleID string, rulesets []konveyor.RuleSet) captureResult {
+	for _, rs := range rulesets {
+		if v, ok := rs.Violations[ruleID]; ok {
+			return captureResult{RuleSet: rs.Name, Status: "violation", Violation: &v}
+		}
+		if v, ok := rs.Insights[ruleID]; ok {
+			return captureResult{RuleSet: rs.Name, Status: "insight", Violation: &v}
+		}
+		if e, ok := rs.Errors[ruleID]; ok {
+			return captureResult{RuleSet: rs.Name, Status: "error", Error: &e}
+		}
+		for _, id := range rs.Unmatched {
+			if id == ruleID {
+				return captureResult{RuleSet: rs.Name, Status: "unmatched"}
+			}
+		}
+		for _, id := range rs.Skipped {
+			if id == ruleID {
+				return captureResult{RuleSet: rs.Name, Status: "skipped"}
+			}
+		}
+		for _, id := range rs.Canceled {
+			if id == ruleID {
+				return captureResult{RuleSet: rs.Name, Status: "canceled"}
+			}
+		}
+	}
+	return captureResult{Status: "not run"}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// gitChangedFiles returns the absolute paths of the files of the git
+// repositories of the locations changed since they branched from the ref,
+// committed or not, along with the untracked files. Deleted files are left out
+// as there is nothing to analyze in them.
+func gitChangedFiles(locations []string, ref string) ([]string, error) {
+	seen := map[string]bool{}
+	files := []string{}
+	for _, location := range locations {
+		top, err := git(location, "rev-parse", "--show-toplevel")
+		if err != nil {
+			return nil, fmt.Errorf("location %s is not in a git repository: %w", location, err)
+		}
+		top = strings.TrimSpace(top)
+		if seen[top] {
+			continue
+		}
+		seen[top] = true
+		changed, err := git(top, "diff", "--name-only", "--diff-filter=d", "--merge-base", ref)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the files changed since %s in %s: %w", ref, top, err)
+		}
+		untracked, err := git(top, "ls-files", "--others", "--exclude-standard")
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the untracked files of %s: %w", top, err)
+		}
+		for _, file := range strings.Split(changed+"\n"+untracked, "\n") {
+			if file = strings.TrimSpace(file); file != "" {
+				files = append(files, filepath.Join(top, filepath.FromSlash(file)))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/spf13/cobra"
+)
+
+// ProviderConfigDocsCmd generates the reference of the providerSpecificConfig
+// of the providers from their schemas.
+func ProviderConfigDocsCmd() *cobra.Command {
+	var docsOutput string
+	docsCmd := &cobra.Command{
+		Use:   "provider-config-docs",
+		Short: "Generate the reference of the provider specific config of the providers",
+		RunE: func(c *cobra.Command, args []string) error {
+			docs := provider.ConfigSchemaDocs()
+			if docsOutput == "" {
+				fmt.Print(docs)
+				return nil
+			}
+			return os.WriteFile(docsOutput, []byte(docs), 0644)
+		},
+	}
+	docsCmd.Flags().StringVar(&docsOutput, "output", "", "file to write the reference to, printed when not given")
+	return docsCmd
+}
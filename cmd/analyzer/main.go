@@ -6,23 +6,30 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	logrusr "github.com/bombsimon/logrusr/v3"
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/archive"
 	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/feature"
+	"github.com/konveyor/analyzer-lsp/output/convert"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/pathfilter"
+	"github.com/konveyor/analyzer-lsp/process"
+	"github.com/konveyor/analyzer-lsp/progress"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/konveyor/analyzer-lsp/provider/lib"
 	"github.com/konveyor/analyzer-lsp/tracing"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/swaggest/openapi-go/openapi3"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v2"
 )
@@ -32,24 +39,72 @@ const (
 )
 
 var (
-	settingsFile      string
-	rulesFile         []string
-	outputViolations  string
-	errorOnViolations bool
-	labelSelector     string
-	depLabelSelector  string
-	incidentSelector  string
-	logLevel          int
-	enableJaeger      bool
-	jaegerEndpoint    string
-	limitIncidents    int
-	limitCodeSnips    int
-	analysisMode      string
-	noDependencyRules bool
-	contextLines      int
-	getOpenAPISpec    string
-	treeOutput        bool
-	depOutputFile     string
+	settingsFile            string
+	rulesFile               []string
+	outputViolations        string
+	errorOnViolations       bool
+	labelSelector           string
+	depLabelSelector        string
+	incidentSelector        string
+	logLevel                int
+	enableJaeger            bool
+	jaegerEndpoint          string
+	limitIncidents          int
+	limitCodeSnips          int
+	codeSnipMaxFileSize     int64
+	maxRuleWorkers          int
+	analysisMode            string
+	noDependencyRules       bool
+	contextLines            int
+	getOpenAPISpec          string
+	treeOutput              bool
+	depOutputFile           string
+	depOutputFormat         string
+	benchmarkSample         float64
+	featureFlagsFile        string
+	depCacheDir             string
+	effortModel             string
+	effortConfig            string
+	ruleOverrides           string
+	providerInitParallelism int
+	scopeReferences         []string
+	scopeChangedFiles       string
+	includedPaths           []string
+	excludedPaths           []string
+	selectorReferences      []string
+	captureBundle           string
+	coverageReport          string
+	coverageHistory         []string
+	baselineFile            string
+	baselineOnlyNew         bool
+	collapseIncidents       bool
+	applyFixes              string
+	severityThreshold       string
+	failOn                  []string
+	taskReport              string
+	preparedDir             string
+	outputAPIVersion        string
+	progressFormat          string
+	progressOutput          string
+	progressHeaders         []string
+	categorySelector        string
+	outputPerCategory       bool
+	limitInsightIncidents   int
+	limitInsights           int
+	insightsOverflowFile    string
+	outputFormat            string
+	outputGzip              bool
+	outputChunkByRuleSet    bool
+	deterministic           bool
+	archiveWorkspace        string
+	archiveMaxSize          int64
+	archiveMaxFiles         int
+	conditionCache          bool
+	conditionCacheDir       string
+	dryRun                  bool
+
+	locationPrefixStrategy string
+	stripLocationPrefixes  []string
 )
 
 func AnalysisCmd() *cobra.Command {
@@ -79,10 +134,16 @@ func AnalysisCmd() *cobra.Command {
 			// need to do research on mapping in logrusr to level here TODO
 			logrusLog.SetLevel(logrus.Level(logLevel))
 			log := logrusr.New(logrusLog)
+			startTime := time.Now()
 
 			// This will globally prevent the yaml library from auto-wrapping lines at 80 characters
 			yaml.FutureLineWrap()
 
+			// providers and language servers left behind by an analysis that crashed
+			if reaped := process.ReapOrphans(log); reaped > 0 {
+				log.Info("stopped orphaned processes of a previous analysis", "groups", reaped)
+			}
+
 			ctx, cancelFunc := context.WithCancel(context.Background())
 			defer cancelFunc()
 
@@ -95,6 +156,32 @@ func AnalysisCmd() *cobra.Command {
 				}
 				selectors = append(selectors, selector)
 			}
+			if categorySelector != "" {
+				// validated with the flags
+				selector, _ := engine.NewCategorySelector(categorySelector)
+				selectors = append(selectors, selector)
+			}
+			for _, reference := range selectorReferences {
+				selector, err := engine.NewRegisteredSelector(log, reference)
+				if err != nil {
+					errLog.Error(err, "failed to create rule selector", "selector", reference)
+					os.Exit(1)
+				}
+				selectors = append(selectors, selector)
+			}
+			var scope engine.Scope
+			if len(scopeReferences) > 0 {
+				registeredScopes := []engine.Scope{}
+				for _, reference := range scopeReferences {
+					s, err := engine.NewRegisteredScope(log, reference)
+					if err != nil {
+						errLog.Error(err, "failed to create scope", "scope", reference)
+						os.Exit(1)
+					}
+					registeredScopes = append(registeredScopes, s)
+				}
+				scope = engine.NewScope(registeredScopes...)
+			}
 
 			var dependencyLabelSelector *labels.LabelSelector[*konveyor.Dep]
 			var err error
@@ -121,12 +208,72 @@ func AnalysisCmd() *cobra.Command {
 			ctx, mainSpan := tracing.StartNewSpan(ctx, "main")
 			defer mainSpan.End()
 
+			progressOptions := []progress.Option{}
+			closeProgress := func() error { return nil }
+			if progressFormat != "" {
+				var reporter progress.Reporter
+				reporter, closeProgress, err = progressReporter(progressFormat, progressOutput, progressHeaders, log)
+				if err != nil {
+					errLog.Error(err, "unable to create progress output", "file", progressOutput)
+					os.Exit(1)
+				}
+				defer closeProgress()
+				progressOptions = append(progressOptions, progress.WithReporter(reporter))
+			}
+			if enableJaeger {
+				progressOptions = append(progressOptions, progress.WithOTelReporter(ctx))
+			}
+			// the collector is created without reporters too, the analysis is
+			// canceled with its control
+			collector := progress.NewCollector(progressOptions...)
+			control := collector.Control()
+			defer cancelOnSignal(control, cancelFunc, log)()
+			// the stages are weighted by how long they usually take
+			analysisTask := collector.Root()
+			parsingTask := analysisTask.Task(progress.StageRuleParsing, "parsing", 1)
+			initTask := analysisTask.Task(progress.StageProviderInit, "providers", 3)
+			rulesTask := analysisTask.Task(progress.StageRuleExecution, "rules", 6)
+
+			featureFlags, err := feature.Load(featureFlagsFile)
+			if err != nil {
+				errLog.Error(err, "unable to load feature flags")
+				os.Exit(1)
+			}
+			if len(featureFlags) > 0 {
+				log.Info("feature flags loaded", "flags", featureFlags.String())
+			}
+
 			// Get the configs
 			configs, err := provider.GetConfig(settingsFile)
 			if err != nil {
 				errLog.Error(err, "unable to get configuration")
 				os.Exit(1)
 			}
+			if preparedDir != "" {
+				dir, err := filepath.Abs(preparedDir)
+				if err != nil {
+					errLog.Error(err, "unable to find prepared directory")
+					os.Exit(1)
+				}
+				if err := checkPreparedDir(dir, configs); err != nil {
+					errLog.Error(err, "unable to use prepared directory")
+					os.Exit(1)
+				}
+				configs = withPreparedDir(configs, dir)
+			}
+			workspace := archive.NewWorkspace(archiveWorkspace, archive.Limits{MaxSize: archiveMaxSize, MaxFiles: archiveMaxFiles})
+			defer workspace.Close()
+			// deferred calls do not run on os.Exit, the extracted archives are
+			// removed before exiting
+			exit := func(code int) {
+				workspace.Close()
+				os.Exit(code)
+			}
+			configs, locationAliases, err := withExtractedArchives(configs, workspace, log)
+			if err != nil {
+				errLog.Error(err, "unable to extract the archive of a location")
+				exit(1)
+			}
 
 			// we add builtin configs by default for all locations
 			defaultBuiltinConfigs := []provider.InitConfig{}
@@ -160,8 +307,47 @@ func AnalysisCmd() *cobra.Command {
 				InitConfig: defaultBuiltinConfigs,
 			})
 
+			if scopeChangedFiles != "" {
+				locations := []string{}
+				for _, initConf := range defaultBuiltinConfigs {
+					locations = append(locations, initConf.Location)
+				}
+				changedFiles, err := gitChangedFiles(locations, scopeChangedFiles)
+				if err != nil {
+					errLog.Error(err, "unable to get the changed files", "ref", scopeChangedFiles)
+					exit(1)
+				}
+				log.Info("limiting the analysis to the changed files", "ref", scopeChangedFiles, "files", len(changedFiles))
+				changedScope := engine.ChangedFilesScope(changedFiles, log)
+				if scope != nil {
+					scope = engine.NewScope(scope, changedScope)
+				} else {
+					scope = changedScope
+				}
+			}
+
+			if len(includedPaths) > 0 || len(excludedPaths) > 0 {
+				filters := []*pathfilter.Filter{}
+				for _, initConf := range defaultBuiltinConfigs {
+					filter, err := pathfilter.New(initConf.Location, includedPaths, excludedPaths)
+					if err != nil {
+						errLog.Error(err, "invalid included or excluded paths")
+						exit(1)
+					}
+					filters = append(filters, filter)
+				}
+				pathScope := engine.PathFilterScope(filters, log)
+				if scope != nil {
+					scope = engine.NewScope(scope, pathScope)
+				} else {
+					scope = pathScope
+				}
+			}
+
 			providers := map[string]provider.InternalProviderClient{}
+			providersMetadata := []konveyor.ProviderMetadata{}
 			providerLocations := []string{}
+			providerConfigs := []provider.Config{}
 			for _, config := range finalConfigs {
 				config.ContextLines = contextLines
 				for _, ind := range config.InitConfig {
@@ -176,20 +362,51 @@ func AnalysisCmd() *cobra.Command {
 					}
 					config.InitConfig = inits
 				}
+				if len(featureFlags) > 0 {
+					inits := []provider.InitConfig{}
+					for _, i := range config.InitConfig {
+						i.ProviderSpecificConfig = withFeatureFlags(i.ProviderSpecificConfig, featureFlags)
+						inits = append(inits, i)
+					}
+					config.InitConfig = inits
+				}
+				if len(includedPaths) > 0 || len(excludedPaths) > 0 {
+					inits := []provider.InitConfig{}
+					for _, i := range config.InitConfig {
+						i.ProviderSpecificConfig = withPaths(i.ProviderSpecificConfig, includedPaths, excludedPaths)
+						inits = append(inits, i)
+					}
+					config.InitConfig = inits
+				}
+				providerConfigs = append(providerConfigs, config)
 				prov, err := lib.GetProviderClient(config, log)
 				if err != nil {
 					errLog.Error(err, "unable to create provider client")
-					os.Exit(1)
+					exit(1)
 				}
 				providers[config.Name] = prov
-				if s, ok := prov.(provider.Startable); ok {
+				if outputAPIVersion != convert.V1 {
+					m, err := providerMetadata(config, prov)
+					if err != nil {
+						errLog.Error(err, "unable to create provider metadata", "provider", config.Name)
+					} else {
+						providersMetadata = append(providersMetadata, m)
+					}
+				}
+				if s, ok := prov.(provider.Startable); ok && !dryRun {
 					if err := s.Start(ctx); err != nil {
 						errLog.Error(err, "unable to create provider client")
-						os.Exit(1)
+						exit(1)
 					}
 				}
 			}
 
+			var recorder *provider.EvaluationRecorder
+			if captureBundle != "" {
+				recorder = provider.NewEvaluationRecorder(captureBundle)
+				ctx = provider.WithEvaluationRecorder(ctx, recorder)
+			}
+
 			engineCtx, engineSpan := tracing.StartNewSpan(ctx, "rule-engine")
 			//start up the rule eng
 			eng := engine.CreateRuleEngine(engineCtx,
@@ -197,73 +414,162 @@ func AnalysisCmd() *cobra.Command {
 				log,
 				engine.WithIncidentLimit(limitIncidents),
 				engine.WithCodeSnipLimit(limitCodeSnips),
+				engine.WithCodeSnipMaxFileSize(codeSnipMaxFileSize),
 				engine.WithContextLines(contextLines),
 				engine.WithIncidentSelector(incidentSelector),
 				engine.WithLocationPrefixes(providerLocations),
+				engine.WithLocationPrefixStrategy(engine.LocationPrefixStrategy(locationPrefixStrategy)),
+				engine.WithStripPrefixes(stripLocationPrefixes),
+				engine.WithLocationAliases(locationAliases),
+				engine.WithConditionCaching(conditionCache),
+				engine.WithFeatureFlags(featureFlags),
+				engine.WithCollapsedIncidents(collapseIncidents),
+				engine.WithMaxWorkers(maxRuleWorkers),
+				engine.WithProviderLimits(providerLimits(configs)),
 			)
 
 			if getOpenAPISpec != "" {
-				sc := createOpenAPISchema(providers, log)
+				sc, err := createOpenAPISchema(providers, log)
+				if err != nil {
+					errLog.Error(err, "unable to create inital schema")
+					exit(1)
+				}
 				b, err := json.Marshal(sc)
 				if err != nil {
 					errLog.Error(err, "unable to create inital schema")
-					os.Exit(1)
+					exit(1)
 				}
 
 				err = os.WriteFile(getOpenAPISpec, b, 0644)
 				if err != nil {
 					errLog.Error(err, "error writing output file", "file", getOpenAPISpec)
-					os.Exit(1) // Treat the error as a fatal error
+					exit(1) // Treat the error as a fatal error
 				}
-				os.Exit(0)
+				exit(0)
 			}
 
-			parser := parser.RuleParser{
+			var depCache *provider.DependencyConditionCache
+			if depCacheDir != "" {
+				depCache, err = provider.NewDependencyConditionCache(depCacheDir)
+				if err != nil {
+					errLog.Error(err, "unable to create dependency cache")
+					exit(1)
+				}
+			}
+
+			var resultCache *provider.ConditionResultCache
+			if conditionCacheDir != "" && !dryRun {
+				resultCache, err = provider.NewConditionResultCache(conditionCacheDir, providerConfigs, providerVersions(providers))
+				if err != nil {
+					errLog.Error(err, "unable to create condition cache")
+					exit(1)
+				}
+				changed, err := resultCache.DetectFileChanges()
+				if err != nil {
+					errLog.Error(err, "unable to detect the changed files")
+					exit(1)
+				}
+				removed, err := resultCache.NotifyFileChanges(changed)
+				if err != nil {
+					errLog.Error(err, "unable to invalidate the condition cache")
+					exit(1)
+				}
+				log.Info("invalidated the condition cache", "changedFiles", len(changed), "removedResponses", removed)
+			}
+
+			ruleParser := parser.RuleParser{
 				ProviderNameToClient: providers,
 				Log:                  log.WithName("parser"),
 				NoDependencyRules:    noDependencyRules,
 				DepLabelSelector:     dependencyLabelSelector,
+				DependencyCache:      depCache,
+				ConditionResultCache: resultCache,
 			}
 			ruleSets := []engine.RuleSet{}
 			needProviders := map[string]provider.InternalProviderClient{}
+			parsingTask.SetTotal(len(rulesFile))
 			for _, f := range rulesFile {
-				internRuleSet, internNeedProviders, err := parser.LoadRules(f)
+				internRuleSet, internNeedProviders, err := ruleParser.LoadRules(f)
 				if err != nil {
 					errLog.Error(err, "unable to parse all the rules for ruleset", "file", f)
 				}
+				parsingTask.Add(1, f)
 				ruleSets = append(ruleSets, internRuleSet...)
 				for k, v := range internNeedProviders {
 					needProviders[k] = v
 				}
 			}
-			// Now that we have all the providers, we need to start them.
-			additionalBuiltinConfigs := []provider.InitConfig{}
-			for name, provider := range needProviders {
-				switch name {
-				// other providers can return additional configs for the builtin provider
-				// therefore, we initiate builtin provider separately at the end
-				case "builtin":
-					continue
-				default:
-					initCtx, initSpan := tracing.StartNewSpan(ctx, "init",
-						attribute.Key("provider").String(name))
-					additionalBuiltinConfs, err := provider.ProviderInit(initCtx, nil)
+			overrides := []konveyor.RuleOverride{}
+			if ruleOverrides != "" {
+				// validated with the flags
+				overrides, _ = konveyor.LoadRuleOverrides(ruleOverrides)
+				ruleIDs := map[string]bool{}
+				for i := range ruleSets {
+					for _, rule := range ruleSets[i].Rules {
+						ruleIDs[rule.RuleID] = true
+					}
+					ruleSets[i].Rules, _ = parser.OverrideRules(ruleSets[i].Rules, overrides)
+				}
+				for _, o := range overrides {
+					if !ruleIDs[o.RuleID] {
+						log.Info("override of a rule not in the rulesets", "ruleID", o.RuleID, "file", ruleOverrides)
+					}
+				}
+			}
+			unavailableProviderRules := []konveyor.CoverageRule{}
+			if coverageReport != "" {
+				for _, f := range rulesFile {
+					rules, err := ruleParser.UnavailableProviderRules(f)
 					if err != nil {
-						errLog.Error(err, "unable to init the providers", "provider", name)
-						os.Exit(1)
+						errLog.Error(err, "unable to find the rules using unavailable providers", "file", f)
 					}
-					if additionalBuiltinConfs != nil {
-						additionalBuiltinConfigs = append(additionalBuiltinConfigs, additionalBuiltinConfs...)
+					unavailableProviderRules = append(unavailableProviderRules, rules...)
+				}
+			}
+			parsingTask.Done()
+			if dryRun {
+				leftOut := []parser.LeftOutRule{}
+				for _, f := range rulesFile {
+					rules, err := ruleParser.LeftOutRules(f, ruleSets)
+					if err != nil {
+						errLog.Error(err, "unable to find the rules left out of the rulesets", "file", f)
 					}
-					initSpan.End()
+					leftOut = append(leftOut, rules...)
+				}
+				engineSpan.End()
+				eng.Stop()
+				for _, provider := range providers {
+					provider.Stop()
 				}
+				b, err := yaml.Marshal(planAnalysis(ruleSets, leftOut, selectors...))
+				if err != nil {
+					errLog.Error(err, "unable to marshal analysis plan")
+					exit(1)
+				}
+				fmt.Printf("%s", string(b))
+				return
 			}
+			// Now that we have all the providers, we need to start them.
+			if err := provider.InitProviders(progress.WithTask(ctx, initTask), log, needProviders, providerInitParallelism); err != nil {
+				errLog.Error(err, "unable to init the providers")
+				exit(1)
+			}
+			initTask.Done()
 
-			if builtinClient, ok := needProviders["builtin"]; ok {
-				if _, err = builtinClient.ProviderInit(ctx, additionalBuiltinConfigs); err != nil {
-					errLog.Error(err, "unable to init builtin provider")
-					os.Exit(1)
+			if benchmarkSample > 0 {
+				plan := runCapacityBenchmark(ctx, log, eng, ruleSets, benchmarkSample, selectors...)
+				engineSpan.End()
+				eng.Stop()
+				for _, provider := range needProviders {
+					provider.Stop()
+				}
+				b, err := yaml.Marshal(plan)
+				if err != nil {
+					errLog.Error(err, "unable to marshal capacity plan")
+					exit(1)
 				}
+				fmt.Printf("%s", string(b))
+				return
 			}
 
 			wg := &sync.WaitGroup{}
@@ -272,11 +578,11 @@ func AnalysisCmd() *cobra.Command {
 			if depOutputFile != "" {
 				depCtx, depSpan = tracing.StartNewSpan(ctx, "dep")
 				wg.Add(1)
-				go DependencyOutput(depCtx, providers, log, errLog, depOutputFile, wg)
+				go DependencyOutput(depCtx, providers, log, errLog, depOutputFile, applicationName(defaultBuiltinConfigs), wg)
 			}
 
 			// This will already wait
-			rulesets := eng.RunRules(ctx, ruleSets, selectors...)
+			rulesets := eng.RunRulesScoped(progress.WithTask(ctx, rulesTask), ruleSets, scope, selectors...)
 			engineSpan.End()
 			wg.Wait()
 			if depSpan != nil {
@@ -287,22 +593,146 @@ func AnalysisCmd() *cobra.Command {
 			for _, provider := range needProviders {
 				provider.Stop()
 			}
+			if control.State() == progress.StateCanceled {
+				log.Info("analysis was canceled, the output has the results of the rules evaluated until then", "reason", control.Reason())
+			}
 
 			sort.SliceStable(rulesets, func(i, j int) bool {
 				return rulesets[i].Name < rulesets[j].Name
 			})
+			if deterministic {
+				konveyor.SortRuleSets(rulesets)
+			}
+			if baselineFile != "" {
+				baseline, err := loadBaseline(baselineFile)
+				if err != nil {
+					errLog.Error(err, "unable to load baseline", "file", baselineFile)
+					exit(1)
+				}
+				found := baseline.Apply(rulesets, baselineOnlyNew)
+				log.Info("compared the incidents to the baseline", "file", baselineFile, "inBaseline", found)
+			}
+			if severityThreshold != "" {
+				removed := konveyor.ApplySeverityThreshold(rulesets, konveyor.Severity(severityThreshold))
+				log.Info("left out the violations below the severity threshold", "threshold", severityThreshold, "violations", removed)
+			}
+			konveyor.ApplyEffortModel(rulesets, konveyor.EffortModel(effortModel))
+
+			if coverageReport != "" {
+				if err := writeCoverageReport(coverageReport, rulesets, unavailableProviderRules); err != nil {
+					errLog.Error(err, "unable to write coverage report", "file", coverageReport)
+				}
+			}
+
+			if taskReport != "" {
+				if err := writeTaskReport(taskReport, rulesets); err != nil {
+					errLog.Error(err, "unable to write task report", "file", taskReport)
+				}
+			}
+
+			if recorder != nil {
+				bundlePath := filepath.Join(filepath.Dir(outputViolations),
+					strings.ReplaceAll(captureBundle, string(filepath.Separator), "_")+"-bundle.tar.gz")
+				if err := writeCaptureBundle(bundlePath, recorder, rulesFile, rulesets, contextLines); err != nil {
+					errLog.Error(err, "unable to write capture bundle", "ruleID", captureBundle)
+				} else {
+					log.Info("wrote capture bundle", "ruleID", captureBundle, "file", bundlePath)
+				}
+			}
+
+			if applyFixes != "" {
+				fixed, err := konveyor.ApplyFixes(rulesets, konveyor.FixMode(applyFixes), providerLocations)
+				if err != nil {
+					errLog.Error(err, "unable to apply fixes")
+				}
+				for _, f := range fixed {
+					if konveyor.FixMode(applyFixes) == konveyor.DryRunFixes {
+						fmt.Print(f.Diff)
+					}
+					log.Info("fixed file", "file", f.File, "fixes", f.Fixes, "mode", applyFixes)
+				}
+			}
+
+			insightsOverflow := konveyor.LimitInsights(rulesets, konveyor.InsightLimits{
+				IncidentsPerInsight: limitInsightIncidents,
+				InsightsPerRuleSet:  limitInsights,
+			})
+			if len(insightsOverflow) > 0 {
+				log.Info("left the insights over the limits out of the output", "rulesets", len(insightsOverflow))
+			}
 
 			// Write results out to CLI
-			b, _ := yaml.Marshal(rulesets)
-			if errorOnViolations && len(rulesets) != 0 {
-				fmt.Printf("%s", string(b))
-				os.Exit(EXIT_ON_ERROR_CODE)
+			output := konveyor.Output{RuleSets: rulesets}
+			if outputAPIVersion != convert.V1 {
+				metadata := &konveyor.Metadata{
+					Providers:        providersMetadata,
+					LabelSelector:    labelSelector,
+					DepLabelSelector: depLabelSelector,
+					IncidentSelector: incidentSelector,
+					CategorySelector: categorySelector,
+					RuleOverrides:    overrides,
+					FeatureFlags:     featureFlags,
+					StartTime:        startTime,
+					EndTime:          time.Now(),
+					CancelReason:     control.Reason(),
+					Host:             hostMetadata(),
+				}
+				if effortConfig != "" {
+					// validated with the flags
+					config, _ := konveyor.LoadEffortConfig(effortConfig)
+					config.Model = konveyor.EffortModel(effortModel)
+					metadata.Effort, err = konveyor.NewEffortScore(rulesets, config)
+					if err != nil {
+						errLog.Error(err, "unable to compute the effort score", "file", effortConfig)
+					}
+				}
+				metadata.AnalyzerVersion, metadata.AnalyzerRevision = analyzerVersion()
+				metadata.RulesDigest, err = rulesDigest(rulesFile)
+				if err != nil {
+					errLog.Error(err, "unable to create the digest of the rules")
+				}
+				output.Metadata = metadata
+			}
+			if errorOnViolations && hasViolations(rulesets) {
+				// validated with the flags
+				convert.Write(os.Stdout, output, convert.WriteOptions{Version: outputAPIVersion, Format: convert.Format(outputFormat)})
+				exit(EXIT_ON_ERROR_CODE)
 			}
 
-			err = os.WriteFile(outputViolations, b, 0644)
+			err = convert.WriteFile(outputViolations, output, outputWriteOptions())
 			if err != nil {
 				errLog.Error(err, "error writing output file", "file", outputViolations)
-				os.Exit(1) // Treat the error as a fatal error
+				exit(1) // Treat the error as a fatal error
+			}
+			if insightsOverflowFile != "" {
+				overflow := output
+				overflow.RuleSets = insightsOverflow
+				if err := convert.WriteFile(insightsOverflowFile, overflow, outputWriteOptions()); err != nil {
+					errLog.Error(err, "error writing the insights overflow file", "file", insightsOverflowFile)
+					exit(1)
+				}
+			}
+			if outputPerCategory {
+				if err := writeCategoryOutputs(outputViolations, output); err != nil {
+					errLog.Error(err, "error writing the output files of the categories")
+					exit(1)
+				}
+			}
+			if control.State() != progress.StateCanceled {
+				analysisTask.Done()
+			}
+			// before the analysis exits with the failure policies
+			if err := closeProgress(); err != nil {
+				errLog.Error(err, "unable to report the end of the analysis", "progress", progressOutput)
+			}
+
+			for _, text := range failOn {
+				// validated with the flags
+				policy, _ := konveyor.ParseFailurePolicy(text)
+				if count, failed := policy.Evaluate(rulesets); failed {
+					errLog.Info("analysis failed the failure policy", "policy", policy.String(), "incidents", count)
+					exit(EXIT_ON_ERROR_CODE)
+				}
 			}
 		},
 	}
@@ -311,20 +741,74 @@ func AnalysisCmd() *cobra.Command {
 	rootCmd.Flags().StringArrayVar(&rulesFile, "rules", []string{"rule-example.yaml"}, "filename or directory containing rule files")
 	rootCmd.Flags().StringVar(&outputViolations, "output-file", "output.yaml", "filepath to to store rule violations")
 	rootCmd.Flags().BoolVar(&errorOnViolations, "error-on-violation", false, "exit with 3 if any violation are found will also print violations to console")
+	rootCmd.Flags().MarkDeprecated("error-on-violation", "use --fail-on count>0 instead")
+	rootCmd.Flags().StringArrayVar(&failOn, "fail-on", []string{}, "exit with 3 after writing the output when the policy is met, comma separated terms such as category=mandatory,severity>=high,count>10 select the violations with category and severity and compare the number of their incidents, not in the baseline, with count. Failing any of the policies fails the analysis")
+	rootCmd.Flags().StringVar(&severityThreshold, "severity-threshold", "", fmt.Sprintf("leave the violations less severe than the threshold, one of %v, out of the output and of the --fail-on policies, violations of rules without a severity are below every threshold", konveyor.Severities))
 	rootCmd.Flags().StringVar(&labelSelector, "label-selector", "", "an expression to select rules based on labels")
+	rootCmd.Flags().StringVar(&categorySelector, "category-selector", "", fmt.Sprintf("comma separated categories of the rules to run, of %v, the rules without a category only tagging the application are always run", konveyor.Categories))
 	rootCmd.Flags().StringVar(&depLabelSelector, "dep-label-selector", "", "an expression to select dependencies based on labels. This will filter out the violations from these dependencies as well these dependencies when matching dependency conditions")
 	rootCmd.Flags().StringVar(&incidentSelector, "incident-selector", "", "an expression to select incidents based on custom variables. ex: (!package=io.konveyor.demo.config-utils)")
 	rootCmd.Flags().IntVar(&logLevel, "verbose", 9, "level for logging output")
 	rootCmd.Flags().BoolVar(&enableJaeger, "enable-jaeger", false, "enable tracer exports to jaeger endpoint")
 	rootCmd.Flags().StringVar(&jaegerEndpoint, "jaeger-endpoint", "http://localhost:14268/api/traces", "jaeger endpoint to collect tracing data")
 	rootCmd.Flags().IntVar(&limitIncidents, "limit-incidents", 1500, "Set this to the limit incidents that a given rule can give, zero means no limit")
+	rootCmd.Flags().IntVar(&maxRuleWorkers, "max-rule-workers", 40, "most rules evaluated at once, the rules are evaluated by 10 workers scaling up to it when rules wait for one")
+	rootCmd.Flags().IntVar(&limitInsightIncidents, "limit-insight-incidents", 0, "limit the incidents of an insight kept in the output, the number of incidents left out is written to its omittedIncidents, zero means no limit other than --limit-incidents")
+	rootCmd.Flags().IntVar(&limitInsights, "limit-insights", 0, "limit the insights of a ruleset kept in the output, the insights with the smallest rule IDs are kept and the number of insights left out is written to the omittedInsights of the ruleset, zero means no limit")
+	rootCmd.Flags().StringVar(&insightsOverflowFile, "insights-overflow-file", "", "path to write the insights and incidents of insights left out of the output because of --limit-insight-incidents and --limit-insights to, with the same API version as the output")
 	rootCmd.Flags().IntVar(&limitCodeSnips, "limit-code-snips", 20, "limit the number code snippets that are retrieved for a file while evaluating a rule, 0 means no limit")
+	rootCmd.Flags().Int64Var(&codeSnipMaxFileSize, "code-snip-max-file-size", engine.DefaultCodeSnipMaxFileSize, fmt.Sprintf("most bytes of a file code snippets are taken from, the incidents of bigger files have no code snippet and a %s variable telling why instead, zero means no limit", engine.CodeSnipWarningVariable))
 	rootCmd.Flags().StringVar(&analysisMode, "analysis-mode", "", "select one of full or source-only to tell the providers what to analyize. This can be given on a per provider setting, but this flag will override")
 	rootCmd.Flags().BoolVar(&noDependencyRules, "no-dependency-rules", false, "Disable dependency analysis rules")
+	rootCmd.Flags().BoolVar(&collapseIncidents, "collapse-incidents", false, "keep one of the incidents found on the same line by several rules of the same family, the rules with the konveyor.io/family label or the same ID but its number, the other rules are listed in its alsoMatchedBy")
 	rootCmd.Flags().IntVar(&contextLines, "context-lines", 10, "When violation occurs, A part of source code is added to the output, So this flag configures the number of source code lines to be printed to the output.")
 	rootCmd.Flags().StringVar(&getOpenAPISpec, "get-openapi-spec", "", "Get the openAPI spec for the rulesets, rules and provider capabilities and put in file passed in.")
 	rootCmd.Flags().BoolVar(&treeOutput, "tree", false, "output dependencies as a tree")
 	rootCmd.Flags().StringVar(&depOutputFile, "dep-output-file", "", "path to dependency output file")
+	rootCmd.Flags().StringVar(&depOutputFormat, "dep-output-format", "yaml", fmt.Sprintf("format of the dependency output file, yaml or the SBOM formats %v", konveyor.SBOMFormats))
+	rootCmd.Flags().StringVar(&locationPrefixStrategy, "location-prefix-strategy", string(engine.RelativeToRootStrategy), "how file paths of incidents are written, one of relative (relative to locations given as relative paths), absolute (unchanged) or strip (remove the locations and --strip-location-prefix values)")
+	rootCmd.Flags().StringArrayVar(&stripLocationPrefixes, "strip-location-prefix", []string{}, "path prefix to remove from file paths of incidents when using the strip location prefix strategy")
+	rootCmd.Flags().BoolVar(&conditionCache, "condition-cache", false, "evaluate the provider conditions repeated by several rules once per analysis, the conditions with the same provider, capability, fields, tags and chained context share the response of the provider")
+	rootCmd.Flags().StringVar(&conditionCacheDir, "condition-cache-dir", "", "directory to cache the responses of the provider conditions in across analyses, the responses are invalidated when the files of the provider locations change")
+	rootCmd.Flags().StringVar(&depCacheDir, "dependency-cache-dir", "", "directory to cache dependency rule results in, analyses of applications with the same dependencies can share it to skip re-evaluating dependency rules")
+	rootCmd.Flags().StringVar(&effortModel, "effort-model", string(konveyor.LinearEffortModel), "how the effort of a violation scales with its incidents, one of linear, log or sqrt. Other than linear, the scaled effort is written to the weightedEffort field of violations")
+	rootCmd.Flags().StringVar(&effortConfig, "effort-config", "", fmt.Sprintf("path to the config of the effort score of the application written to the metadata of the output, with the weights of the categories, the most incidents of a violation counted, a CEL expression computing the effort of a violation or the calculator, one of %v or a calculator registered by a program embedding the analyzer. Requires --output-api-version v2", konveyor.RegisteredEffortCalculators()))
+	rootCmd.Flags().StringVar(&ruleOverrides, "rule-overrides", "", "path to a YAML file mapping rule IDs to overrides applied to the rules of every ruleset, disabled leaves the rule out, effort, category, severity, description and message replace the fields of the rule, labels replace the labels with the same key and are added to the others and messageSuffix is appended to the message. The overrides are written to the metadata of the output")
+	rootCmd.Flags().IntVar(&providerInitParallelism, "provider-init-parallelism", 0, "number of providers initialized at the same time, all the providers are initialized at once by default. The builtin provider is always initialized after the others")
+	rootCmd.Flags().StringArrayVar(&scopeReferences, "scope", []string{}, fmt.Sprintf("scope to limit the analysis with as <name>=<arguments>, one of %v or a scope registered by a program embedding the analyzer", engine.RegisteredScopes()))
+	rootCmd.Flags().StringArrayVar(&includedPaths, "include", []string{}, "path or glob of paths relative to the locations to limit the analysis to, such as src/main/**/*.java")
+	rootCmd.Flags().StringArrayVar(&excludedPaths, "exclude", []string{}, "path or glob of paths relative to the locations to leave out of the analysis, such as **/test")
+	rootCmd.Flags().StringVar(&scopeChangedFiles, "scope-changed-files", "", "limit the analysis to the files changed since the git ref, such as the target branch of a pull request, along with the uncommitted and untracked files")
+	rootCmd.Flags().StringArrayVar(&selectorReferences, "rule-selector", []string{}, fmt.Sprintf("rule selector to select the rules to run with as <name>=<arguments>, one of %v or a selector registered by a program embedding the analyzer", engine.RegisteredSelectors()))
+	rootCmd.Flags().StringVar(&featureFlagsFile, "feature-flags", "", "path to a YAML file mapping experimental feature names to true or false, flags can also be set with "+feature.EnvVar)
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the plan of the analysis instead of running it, the rules evaluated by every provider with their cost and the rules skipped with the reason, selector, unavailable-provider, missing-capability, no-dependency-rules or not-loaded. The providers are not initialized and no output file is written")
+	rootCmd.Flags().Float64Var(&benchmarkSample, "benchmark-sample", 0, "run only this fraction (0-1] of the rules and print a capacity plan projecting the duration and memory of the full analysis, the rules are sampled and not the files, every sampled rule is evaluated on the whole application. No output file is written")
+	rootCmd.Flags().StringVar(&captureBundle, "capture-bundle", "", "rule ID to capture the evaluation of, the rule, the provider conditions evaluated with their responses and the slices of the files incidents were found in are written to <ruleID>-bundle.tar.gz next to the output file")
+	rootCmd.Flags().StringVar(&coverageReport, "coverage-report", "", "path to write a report of the rules skipped by selectors, using unavailable providers or never matched to")
+	rootCmd.Flags().StringVar(&baselineFile, "baseline", "", "output file of a previous analysis, incidents found in it are marked with baseline: true")
+	rootCmd.Flags().StringVar(&applyFixes, "apply-fixes", "", fmt.Sprintf("apply the search and replace fixes of the incidents, one of %v, dry-run prints the changes as a diff instead of writing them", konveyor.FixModes))
+	rootCmd.Flags().BoolVar(&baselineOnlyNew, "baseline-only-new", false, "leave the incidents found in the baseline out of the output instead of marking them, to fail CI on new violations only")
+	rootCmd.Flags().StringArrayVar(&coverageHistory, "coverage-history", []string{}, "output file of a previous analysis with the same rules, rules matched in any of them are not reported as never matched in the coverage report")
+	rootCmd.Flags().StringVar(&taskReport, "task-report", "", "path to write the violations rolled up by the migration task of their rules to, with the rules, incidents and effort of every task")
+	rootCmd.Flags().StringVar(&archiveWorkspace, "archive-workspace", "", "directory to extract the zip and tar archives given as locations of the providers to, in a temporary directory removed once the analysis is done, the default directory for temporary files by default. The incidents of their files are reported under the paths of the archives")
+	rootCmd.Flags().Int64Var(&archiveMaxSize, "archive-max-size", archive.DefaultLimits.MaxSize, "most bytes extracted from an archive given as a location, zero means no limit")
+	rootCmd.Flags().IntVar(&archiveMaxFiles, "archive-max-files", archive.DefaultLimits.MaxFiles, "most files extracted from an archive given as a location, zero means no limit")
+	rootCmd.Flags().StringVar(&preparedDir, "prepared-dir", "", "directory prepared with the prepare command, the providers reuse the artifacts of the preparation instead of preparing again")
+	rootCmd.Flags().BoolVar(&outputPerCategory, "output-per-category", false, "also write the violations of every category to an output file of its own next to the output file, such as output-mandatory.yaml, with the same API version")
+	rootCmd.Flags().StringVar(&outputFormat, "output-format", string(convert.YAML), fmt.Sprintf("format of the output file, one of %v, the rulesets are written one at a time", convert.Formats))
+	rootCmd.Flags().BoolVar(&outputGzip, "output-gzip", false, "compress the output file with gzip, --baseline and --coverage-history read compressed outputs")
+	rootCmd.Flags().BoolVar(&outputChunkByRuleSet, "output-chunk-by-ruleset", false, "write every ruleset to a file of its own, numbered in the order of the rulesets, in the directory at --output-file along with the metadata, --baseline and --coverage-history read the directory as an output")
+	rootCmd.Flags().BoolVar(&deterministic, "deterministic", true, "sort the rulesets, violations, incidents, tags, labels and dependencies of the outputs in the documented order, so that analyses of the same application with the same rules write the same outputs, also in JSON. The YAML output always sorts the fields of the rulesets")
+	rootCmd.Flags().StringVar(&outputAPIVersion, "output-api-version", convert.V1, fmt.Sprintf("API version of the output, one of %v. v1 is a list of rulesets, v2 a document with the apiVersion, the rulesets and a metadata section telling what produced it, the analyzer version, the providers and hashes of their configs, the digest of the rules, the selectors, the start and end time and the host", convert.Versions))
+	rootCmd.Flags().StringVar(&progressFormat, "progress-format", "", fmt.Sprintf("report the progress of the analysis, of the providers initializing and the locations they prepare and of the rules evaluated, with the format, one of %v. text writes a line for every change, bar redraws a progress bar, json writes the events as JSON, one per line, and webhook posts them as JSON to the --progress-output url along with a summary once the analysis ends", append(progress.Formats, progress.WebhookFormat)))
+	rootCmd.Flags().StringVar(&progressOutput, "progress-output", "", "path to write the progress to, stderr by default, or the url of the webhook to post it to")
+	rootCmd.Flags().StringArrayVar(&progressHeaders, "progress-header", []string{}, "header of the requests posting the progress to the webhook as \"Name: value\", such as \"Authorization: Bearer <token>\"")
+	rootCmd.AddCommand(ValidateCmd())
+	rootCmd.AddCommand(PrepareCmd())
+	rootCmd.AddCommand(BatchCmd())
+	rootCmd.AddCommand(PortfolioCmd())
+	rootCmd.AddCommand(ServeCmd())
+	rootCmd.AddCommand(ProviderConfigDocsCmd())
 
 	return rootCmd
 }
@@ -337,6 +821,46 @@ func main() {
 	}
 }
 
+// withFeatureFlags returns a copy of the provider specific config with the feature flags
+func withFeatureFlags(providerSpecificConfig map[string]interface{}, flags feature.Flags) map[string]interface{} {
+	config := map[string]interface{}{}
+	for k, v := range providerSpecificConfig {
+		config[k] = v
+	}
+	configFlags := map[string]interface{}{}
+	for name, enabled := range flags {
+		configFlags[name] = enabled
+	}
+	config[provider.FeatureFlagsConfigKey] = configFlags
+	return config
+}
+
+// withPaths returns a copy of the provider specific config with the included
+// and excluded paths added to the ones of the provider
+func withPaths(providerSpecificConfig map[string]interface{}, included, excluded []string) map[string]interface{} {
+	config := map[string]interface{}{}
+	for k, v := range providerSpecificConfig {
+		config[k] = v
+	}
+	for key, paths := range map[string][]string{
+		provider.IncludedPathsConfigKey: included,
+		provider.ExcludedPathsConfigKey: excluded,
+	} {
+		if len(paths) == 0 {
+			continue
+		}
+		configPaths := []interface{}{}
+		if existing, ok := config[key].([]interface{}); ok {
+			configPaths = append(configPaths, existing...)
+		}
+		for _, path := range paths {
+			configPaths = append(configPaths, path)
+		}
+		config[key] = configPaths
+	}
+	return config
+}
+
 func validateFlags() error {
 	_, err := os.Stat(settingsFile)
 	if err != nil {
@@ -351,6 +875,104 @@ func validateFlags() error {
 			}
 		}
 	}
+	if !slices.Contains(konveyor.EffortModels, konveyor.EffortModel(effortModel)) {
+		return fmt.Errorf("must select one of %v for effort model", konveyor.EffortModels)
+	}
+	if len(coverageHistory) > 0 && coverageReport == "" {
+		return fmt.Errorf("--coverage-history can only be used with --coverage-report")
+	}
+	for _, f := range coverageHistory {
+		if _, err := os.Stat(f); err != nil {
+			return fmt.Errorf("unable to find coverage history file %s", f)
+		}
+	}
+	for _, text := range failOn {
+		if _, err := konveyor.ParseFailurePolicy(text); err != nil {
+			return fmt.Errorf("invalid --fail-on policy %s: %w", text, err)
+		}
+	}
+	if depOutputFormat != "yaml" && !slices.Contains(konveyor.SBOMFormats, konveyor.SBOMFormat(depOutputFormat)) {
+		return fmt.Errorf("dep-output-format must be yaml or one of %v, not %s", konveyor.SBOMFormats, depOutputFormat)
+	}
+	if severityThreshold != "" && !konveyor.Severity(severityThreshold).Valid() {
+		return fmt.Errorf("must select one of %v for severity threshold", konveyor.Severities)
+	}
+	if limitInsightIncidents < 0 || limitInsights < 0 {
+		return fmt.Errorf("--limit-insight-incidents and --limit-insights must be positive or zero")
+	}
+	if codeSnipMaxFileSize < 0 {
+		return fmt.Errorf("--code-snip-max-file-size must be positive or zero")
+	}
+	if insightsOverflowFile != "" && limitInsightIncidents == 0 && limitInsights == 0 {
+		return fmt.Errorf("--insights-overflow-file can only be used with --limit-insight-incidents or --limit-insights")
+	}
+	if categorySelector != "" {
+		if _, err := engine.NewCategorySelector(categorySelector); err != nil {
+			return fmt.Errorf("invalid --category-selector: %w", err)
+		}
+	}
+	if applyFixes != "" && !slices.Contains(konveyor.FixModes, konveyor.FixMode(applyFixes)) {
+		return fmt.Errorf("apply-fixes must be one of %v, not %s", konveyor.FixModes, applyFixes)
+	}
+	if !slices.Contains(convert.Versions, outputAPIVersion) {
+		return fmt.Errorf("output-api-version must be one of %v, not %s", convert.Versions, outputAPIVersion)
+	}
+	if ruleOverrides != "" {
+		if _, err := konveyor.LoadRuleOverrides(ruleOverrides); err != nil {
+			return err
+		}
+	}
+	if effortConfig != "" {
+		if outputAPIVersion == convert.V1 {
+			return fmt.Errorf("--effort-config writes the effort score to the metadata of the output, it requires --output-api-version %s", convert.V2)
+		}
+		config, err := konveyor.LoadEffortConfig(effortConfig)
+		if err != nil {
+			return err
+		}
+		if _, _, err := konveyor.NewEffortCalculator(config); err != nil {
+			return fmt.Errorf("invalid --effort-config: %w", err)
+		}
+	}
+	if !slices.Contains(convert.Formats, convert.Format(outputFormat)) {
+		return fmt.Errorf("output-format must be one of %v, not %s", convert.Formats, outputFormat)
+	}
+	if progressFormat != "" && progressFormat != progress.WebhookFormat && !slices.Contains(progress.Formats, progressFormat) {
+		return fmt.Errorf("progress-format must be one of %v, not %s", append(progress.Formats, progress.WebhookFormat), progressFormat)
+	}
+	if progressOutput != "" && progressFormat == "" {
+		return fmt.Errorf("--progress-output can only be used with --progress-format")
+	}
+	if progressFormat == progress.WebhookFormat {
+		if err := validateWebhook(progressOutput); err != nil {
+			return fmt.Errorf("--progress-output must be the url of the webhook: %w", err)
+		}
+	} else if len(progressHeaders) > 0 {
+		return fmt.Errorf("--progress-header can only be used with the %s progress format", progress.WebhookFormat)
+	}
+	if baselineOnlyNew && baselineFile == "" {
+		return fmt.Errorf("--baseline-only-new can only be used with --baseline")
+	}
+	if baselineFile != "" {
+		if _, err := os.Stat(baselineFile); err != nil {
+			return fmt.Errorf("unable to find baseline file %s", baselineFile)
+		}
+	}
+	if providerInitParallelism < 0 {
+		return fmt.Errorf("provider init parallelism must not be negative")
+	}
+	if benchmarkSample < 0 || benchmarkSample > 1 {
+		return fmt.Errorf("benchmark sample must be a fraction between 0 and 1")
+	}
+	if dryRun && benchmarkSample > 0 {
+		return fmt.Errorf("--dry-run can't be used with --benchmark-sample")
+	}
+	if !slices.Contains(engine.LocationPrefixStrategies, engine.LocationPrefixStrategy(locationPrefixStrategy)) {
+		return fmt.Errorf("must select one of %v for location prefix strategy", engine.LocationPrefixStrategies)
+	}
+	if len(stripLocationPrefixes) > 0 && locationPrefixStrategy != string(engine.StripPrefixStrategy) {
+		return fmt.Errorf("--strip-location-prefix can only be used with the %s location prefix strategy", engine.StripPrefixStrategy)
+	}
 	m := provider.AnalysisMode(strings.ToLower(analysisMode))
 	if analysisMode != "" && !(m == provider.FullAnalysisMode || m == provider.SourceOnlyAnalysisMode) {
 		return fmt.Errorf("must select one of %s or %s for analysis mode", provider.FullAnalysisMode, provider.SourceOnlyAnalysisMode)
@@ -359,13 +981,110 @@ func validateFlags() error {
 	return nil
 }
 
-func createOpenAPISchema(providers map[string]provider.InternalProviderClient, log logr.Logger) openapi3.Spec {
+// writeCoverageReport writes the coverage report of the rulesets and the
+// outputs of the coverage history to the file.
+func writeCoverageReport(path string, rulesets []konveyor.RuleSet, unavailableProviderRules []konveyor.CoverageRule) error {
+	previous := [][]konveyor.RuleSet{}
+	for _, f := range coverageHistory {
+		run, err := convert.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("unable to read coverage history file %s: %w", f, err)
+		}
+		previous = append(previous, run.RuleSets)
+	}
+	report := konveyor.NewCoverageReport(rulesets, previous...)
+	report.UnavailableProviders = unavailableProviderRules
+	b, err := yaml.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// writeCategoryOutputs writes the violations of every category of the output
+// to <output file>-<category><extension>, the output of a category without
+// violations has no rulesets
+func writeCategoryOutputs(path string, output konveyor.Output) error {
+	ext := filepath.Ext(path)
+	for category, ruleSets := range konveyor.PartitionByCategory(output.RuleSets) {
+		output.RuleSets = ruleSets
+		categoryPath := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), category, ext)
+		if err := convert.WriteFile(categoryPath, output, outputWriteOptions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputWriteOptions returns how the output files are written
+func outputWriteOptions() convert.WriteOptions {
+	return convert.WriteOptions{
+		Version:        outputAPIVersion,
+		Format:         convert.Format(outputFormat),
+		Gzip:           outputGzip,
+		ChunkByRuleSet: outputChunkByRuleSet,
+	}
+}
+
+// hasViolations reports whether any of the rulesets has violations
+func hasViolations(rulesets []konveyor.RuleSet) bool {
+	for _, rs := range rulesets {
+		if len(rs.Violations) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// providerVersions returns the builds of the providers, the providers run in
+// the analyzer are built with it
+func providerVersions(providers map[string]provider.InternalProviderClient) map[string]string {
+	versions := map[string]string{}
+	for name, client := range providers {
+		if v, ok := client.(provider.VersionedClient); ok {
+			versions[name] = v.ProviderVersion()
+		} else {
+			versions[name] = provider.BuildVersion()
+		}
+	}
+	return versions
+}
+
+// providerLimits returns the most rules of each provider evaluated at once
+func providerLimits(configs []provider.Config) map[string]int {
+	limits := map[string]int{}
+	for _, c := range configs {
+		if c.MaxConcurrent > 0 {
+			limits[c.Name] = c.MaxConcurrent
+		}
+	}
+	return limits
+}
+
+// loadBaseline reads the baseline from the output file of a previous analysis.
+func loadBaseline(path string) (konveyor.Baseline, error) {
+	previous, err := convert.ReadFile(path)
+	if err != nil {
+		return konveyor.Baseline{}, fmt.Errorf("unable to read baseline file %s: %w", path, err)
+	}
+	return konveyor.NewBaseline(previous.RuleSets), nil
+}
+
+// writeTaskReport writes the task summaries of the rulesets to the file.
+func writeTaskReport(path string, rulesets []konveyor.RuleSet) error {
+	b, err := yaml.Marshal(konveyor.NewTaskSummaries(rulesets))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func createOpenAPISchema(providers map[string]provider.InternalProviderClient, log logr.Logger) (openapi3.Spec, error) {
 
 	// in the future loop and build the openapi spec here:
 	spec, err := parser.CreateSchema()
 	if err != nil {
-		log.Error(err, "unable to create inital schema")
-		os.Exit(1)
+		return openapi3.Spec{}, err
 	}
 
 	AndOrRefRuleRef := []openapi3.SchemaOrRef{}
@@ -399,6 +1118,16 @@ func createOpenAPISchema(providers map[string]provider.InternalProviderClient, l
 								Type: &provider.SchemaTypeBool,
 							},
 						},
+						"branch": {
+							Schema: &openapi3.Schema{
+								Type: &provider.SchemaTypeString,
+							},
+						},
+						"message": {
+							Schema: &openapi3.Schema{
+								Type: &provider.SchemaTypeString,
+							},
+						},
 					},
 				},
 			}
@@ -426,11 +1155,56 @@ func createOpenAPISchema(providers map[string]provider.InternalProviderClient, l
 			Ref: "#/components/schemas/or",
 		},
 	})
+	AndOrRefRuleRef = append(AndOrRefRuleRef, openapi3.SchemaOrRef{
+		SchemaReference: &openapi3.SchemaReference{
+			Ref: "#/components/schemas/expr",
+		},
+	})
+	andConditions := openapi3.SchemaOrRef{
+		Schema: &openapi3.Schema{
+			Type: &provider.SchemaTypeArray,
+			Items: &openapi3.SchemaOrRef{
+				Schema: &openapi3.Schema{
+					Type:  &provider.SchemaTypeObject,
+					OneOf: AndOrRefRuleRef,
+				},
+			},
+		},
+	}
 	spec.MapOfSchemaOrRefValues["and"] = openapi3.SchemaOrRef{
 		Schema: &openapi3.Schema{
 			Type: &provider.SchemaTypeObject,
 			Properties: map[string]openapi3.SchemaOrRef{
 				"and": {
+					Schema: &openapi3.Schema{
+						OneOf: []openapi3.SchemaOrRef{
+							andConditions,
+							{
+								// the conditions must match in the same files with the file scope
+								Schema: &openapi3.Schema{
+									Type: &provider.SchemaTypeObject,
+									Properties: map[string]openapi3.SchemaOrRef{
+										"scope": {
+											Schema: &openapi3.Schema{
+												Type: &provider.SchemaTypeString,
+												Enum: []interface{}{"rule", "file"},
+											},
+										},
+										"conditions": andConditions,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	spec.MapOfSchemaOrRefValues["or"] = openapi3.SchemaOrRef{
+		Schema: &openapi3.Schema{
+			Type: &provider.SchemaTypeObject,
+			Properties: map[string]openapi3.SchemaOrRef{
+				"or": {
 					Schema: &openapi3.Schema{
 						Type: &provider.SchemaTypeArray,
 						Items: &openapi3.SchemaOrRef{
@@ -444,11 +1218,16 @@ func createOpenAPISchema(providers map[string]provider.InternalProviderClient, l
 			},
 		},
 	}
-	spec.MapOfSchemaOrRefValues["or"] = openapi3.SchemaOrRef{
+	spec.MapOfSchemaOrRefValues["expr"] = openapi3.SchemaOrRef{
 		Schema: &openapi3.Schema{
 			Type: &provider.SchemaTypeObject,
 			Properties: map[string]openapi3.SchemaOrRef{
-				"or": {
+				"expr": {
+					Schema: &openapi3.Schema{
+						Type: &provider.SchemaTypeString,
+					},
+				},
+				"conditions": {
 					Schema: &openapi3.Schema{
 						Type: &provider.SchemaTypeArray,
 						Items: &openapi3.SchemaOrRef{
@@ -469,6 +1248,12 @@ func createOpenAPISchema(providers map[string]provider.InternalProviderClient, l
 			OneOf: AndOrRefRuleRef,
 		},
 	}
+	spec.MapOfSchemaOrRefValues["rule"].Schema.Properties["unless"] = openapi3.SchemaOrRef{
+		Schema: &openapi3.Schema{
+			Type:  &provider.SchemaTypeObject,
+			OneOf: AndOrRefRuleRef,
+		},
+	}
 	sc := openapi3.Spec{
 		Components: &openapi3.Components{
 			Schemas: &spec,
@@ -480,10 +1265,20 @@ func createOpenAPISchema(providers map[string]provider.InternalProviderClient, l
 		},
 	}
 
-	return sc
+	return sc, nil
 }
 
-func DependencyOutput(ctx context.Context, providers map[string]provider.InternalProviderClient, log logr.Logger, errLog logr.Logger, depOutputFile string, wg *sync.WaitGroup) {
+// applicationName names the application in the SBOMs after its location
+func applicationName(configs []provider.InitConfig) string {
+	for _, c := range configs {
+		if c.Location != "" {
+			return filepath.Base(c.Location)
+		}
+	}
+	return "application"
+}
+
+func DependencyOutput(ctx context.Context, providers map[string]provider.InternalProviderClient, log logr.Logger, errLog logr.Logger, depOutputFile string, name string, wg *sync.WaitGroup) {
 	defer wg.Done()
 	var depsFlat []konveyor.DepsFlatItem
 	var depsTree []konveyor.DepsTreeItem
@@ -500,6 +1295,11 @@ func DependencyOutput(ctx context.Context, providers map[string]provider.Interna
 				continue
 			}
 			for u, ds := range deps {
+				ds, cycles := provider.BreakDepDAGCycles(ds)
+				for _, cycle := range cycles {
+					log.Info("dependency cycle found", "provider", name, "file", u, "cycle", strings.Join(cycle, " -> "))
+				}
+				provider.SetDepDAGPaths(ds)
 				depsTree = append(depsTree, konveyor.DepsTreeItem{
 					FileURI:      string(u),
 					Provider:     name,
@@ -530,7 +1330,19 @@ func DependencyOutput(ctx context.Context, providers map[string]provider.Interna
 
 	var b []byte
 	var err error
-	if treeOutput {
+	if depOutputFormat != "yaml" {
+		sbom := konveyor.NewSBOM(name, time.Now())
+		sbom.AddDeps(depsFlat)
+		sbom.AddDepTrees(depsTree)
+		b, err = sbom.Marshal(konveyor.SBOMFormat(depOutputFormat))
+		if err != nil {
+			errLog.Error(err, "failed to create the SBOM of the dependencies")
+			return
+		}
+	} else if treeOutput {
+		if deterministic {
+			konveyor.SortDepsTree(depsTree)
+		}
 		b, err = yaml.Marshal(depsTree)
 		if err != nil {
 			errLog.Error(err, "failed to marshal dependency data as yaml")
@@ -545,6 +1357,9 @@ func DependencyOutput(ctx context.Context, providers map[string]provider.Interna
 				return depsFlat[i].Provider < depsFlat[j].Provider
 			}
 		})
+		if deterministic {
+			konveyor.SortDepsFlat(depsFlat)
+		}
 
 		b, err = yaml.Marshal(depsFlat)
 		if err != nil {
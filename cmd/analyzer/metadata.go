@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"gopkg.in/yaml.v2"
+)
+
+// analyzerVersion returns the version of the analyzer module and the commit
+// it was built from
+func analyzerVersion() (string, string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(unknown)", ""
+	}
+	revision, modified := "", false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return info.Main.Version, revision
+}
+
+// rulesDigest returns the digest of the rule files, each file contributes its
+// path relative to the rules path it was found in and its content, so that
+// the same rules moved elsewhere have the same digest
+func rulesDigest(rulePaths []string) (string, error) {
+	h := sha256.New()
+	for _, rulePath := range rulePaths {
+		files := []string{}
+		err := filepath.WalkDir(rulePath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return "", err
+			}
+			rel, err := filepath.Rel(rulePath, file)
+			if err != nil || rel == "." {
+				rel = filepath.Base(file)
+			}
+			h.Write([]byte(filepath.ToSlash(rel)))
+			h.Write([]byte{0})
+			h.Write(content)
+			h.Write([]byte{0})
+		}
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// providerMetadata returns the metadata of a provider created with the
+// config
+func providerMetadata(config provider.Config, client provider.InternalProviderClient) (konveyor.ProviderMetadata, error) {
+	b, err := yaml.Marshal(config)
+	if err != nil {
+		return konveyor.ProviderMetadata{}, err
+	}
+	sum := sha256.Sum256(b)
+	m := konveyor.ProviderMetadata{
+		Name:       config.Name,
+		ConfigHash: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+	if v, ok := client.(provider.VersionedClient); ok {
+		m.ProtocolVersion = v.ProtocolVersion()
+	}
+	return m, nil
+}
+
+// hostMetadata returns the host the analysis is run on
+func hostMetadata() konveyor.HostMetadata {
+	hostname, _ := os.Hostname()
+	return konveyor.HostMetadata{
+		Hostname: hostname,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		CPUs:     runtime.NumCPU(),
+	}
+}
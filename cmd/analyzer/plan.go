@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/parser"
+)
+
+// selectorReason is the reason of the rules the selectors leave out
+const selectorReason = "selector"
+
+// analysisPlan is what an analysis would evaluate, printed by --dry-run
+type analysisPlan struct {
+	TotalRules    int `yaml:"totalRules"`
+	SelectedRules int `yaml:"selectedRules"`
+	// Providers are the providers of the selected rules, with the rules
+	// evaluated by each of them. A rule with conditions of several providers
+	// is under each of them.
+	Providers []providerPlan `yaml:"providers"`
+	// Skipped are the rules of the rule files that would not be evaluated
+	Skipped []skippedRule `yaml:"skipped,omitempty"`
+}
+
+type providerPlan struct {
+	Name string `yaml:"name"`
+	// Costs are the number of rules by cost, the cheap rules are evaluated
+	// first
+	Costs map[engine.Cost]int `yaml:"costs"`
+	Rules []plannedRule       `yaml:"rules"`
+}
+
+type plannedRule struct {
+	RuleSet string      `yaml:"ruleSet,omitempty"`
+	RuleID  string      `yaml:"ruleID"`
+	Cost    engine.Cost `yaml:"cost"`
+}
+
+type skippedRule struct {
+	RuleSet string `yaml:"ruleSet,omitempty"`
+	RuleID  string `yaml:"ruleID"`
+	File    string `yaml:"file,omitempty"`
+	// Reason is selector, unavailable-provider, missing-capability,
+	// no-dependency-rules or not-loaded
+	Reason string `yaml:"reason"`
+	// Providers are the provider conditions the rule is skipped for, as
+	// <provider>.<capability>
+	Providers []string `yaml:"providers,omitempty"`
+}
+
+// planAnalysis creates the plan of the analysis of the rulesets loaded with
+// the selectors, leftOut being the rules of the rule files not loaded
+func planAnalysis(ruleSets []engine.RuleSet, leftOut []parser.LeftOutRule, selectors ...engine.RuleSelector) analysisPlan {
+	plan := analysisPlan{Providers: []providerPlan{}}
+	providers := map[string]*providerPlan{}
+	for _, rule := range engine.PlanRules(ruleSets, selectors...) {
+		plan.TotalRules++
+		if !rule.Selected {
+			plan.Skipped = append(plan.Skipped, skippedRule{
+				RuleSet: rule.RuleSet,
+				RuleID:  rule.RuleID,
+				Reason:  selectorReason,
+			})
+			continue
+		}
+		plan.SelectedRules++
+		for _, name := range rule.Providers {
+			p, ok := providers[name]
+			if !ok {
+				p = &providerPlan{Name: name, Costs: map[engine.Cost]int{}}
+				providers[name] = p
+			}
+			p.Costs[rule.Cost]++
+			p.Rules = append(p.Rules, plannedRule{RuleSet: rule.RuleSet, RuleID: rule.RuleID, Cost: rule.Cost})
+		}
+	}
+	for _, p := range providers {
+		plan.Providers = append(plan.Providers, *p)
+	}
+	sort.Slice(plan.Providers, func(i, j int) bool {
+		return plan.Providers[i].Name < plan.Providers[j].Name
+	})
+	for _, rule := range leftOut {
+		plan.TotalRules++
+		plan.Skipped = append(plan.Skipped, skippedRule{
+			RuleSet:   rule.RuleSet,
+			RuleID:    rule.RuleID,
+			File:      rule.File,
+			Reason:    rule.Reason,
+			Providers: rule.Providers,
+		})
+	}
+	return plan
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/convert"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	batchPortfolioFile = "portfolio.yaml"
+	// defaultTopRules is the number of rules in the top rules of a portfolio
+	defaultTopRules = 10
+)
+
+// portfolioOutput is the output of the analysis of an application
+type portfolioOutput struct {
+	name string
+	path string
+}
+
+// PortfolioCmd rolls up the outputs of the analyses of several applications,
+// given as files or as the output directory of a batch.
+func PortfolioCmd() *cobra.Command {
+	var (
+		batchDir   string
+		outputFile string
+		topRules   int
+	)
+	portfolioCmd := &cobra.Command{
+		Use:   "portfolio [[name=]output...]",
+		Short: "Summarize the outputs of the analyses of several applications",
+		Long: "Summarize the outputs of the analyses of several applications, the issues, incidents and effort of " +
+			"every application, the rules with violations in the most applications, the applications of every tag " +
+			"and the total effort. The outputs are given as name=path, the name of an application being the path " +
+			"of its output when it is left out, or with the output directory of a batch.",
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) == 0 && batchDir == "" {
+				return fmt.Errorf("outputs or a batch directory must be given")
+			}
+			if topRules < 0 {
+				return fmt.Errorf("top rules must not be negative")
+			}
+			c.SilenceUsage = true
+			outputs := []portfolioOutput{}
+			if batchDir != "" {
+				content, err := os.ReadFile(filepath.Join(batchDir, batchSummaryFile))
+				if err != nil {
+					return err
+				}
+				summary := batchSummary{}
+				if err := yaml.Unmarshal(content, &summary); err != nil {
+					return fmt.Errorf("unable to read the summary of the batch: %w", err)
+				}
+				outputs = append(outputs, batchOutputs(batchDir, summary.Apps)...)
+			}
+			for _, arg := range args {
+				name, path, ok := strings.Cut(arg, "=")
+				if !ok {
+					path = name
+				}
+				outputs = append(outputs, portfolioOutput{name: name, path: path})
+			}
+			portfolio, err := newPortfolio(outputs, topRules)
+			if err != nil {
+				return err
+			}
+			b, err := yaml.Marshal(portfolio)
+			if err != nil {
+				return err
+			}
+			if outputFile == "" {
+				_, err = os.Stdout.Write(b)
+				return err
+			}
+			return os.WriteFile(outputFile, b, 0644)
+		},
+	}
+	portfolioCmd.Flags().StringVar(&batchDir, "batch-dir", "", "output directory of a batch, the outputs of its succeeded analyses are summarized")
+	portfolioCmd.Flags().StringVar(&outputFile, "output-file", "", "path to write the summary to, stdout by default")
+	portfolioCmd.Flags().IntVar(&topRules, "top-rules", defaultTopRules, "number of rules with violations in the most applications to list, zero lists every rule")
+	return portfolioCmd
+}
+
+// batchOutputs returns the names and outputs of the analyses of the batch in
+// dir that wrote an output
+func batchOutputs(dir string, apps []batchAppSummary) []portfolioOutput {
+	outputs := []portfolioOutput{}
+	for _, app := range apps {
+		if app.Output != "" {
+			outputs = append(outputs, portfolioOutput{name: app.Name, path: filepath.Join(dir, app.Output)})
+		}
+	}
+	return outputs
+}
+
+// newPortfolio reads the outputs and rolls them up
+func newPortfolio(outputs []portfolioOutput, topRules int) (konveyor.PortfolioSummary, error) {
+	apps := []konveyor.Application{}
+	for _, output := range outputs {
+		result, err := convert.ReadFile(output.path)
+		if err != nil {
+			return konveyor.PortfolioSummary{}, fmt.Errorf("unable to read the output of %s: %w", output.name, err)
+		}
+		apps = append(apps, konveyor.Application{Name: output.name, RuleSets: result.RuleSets})
+	}
+	return konveyor.NewPortfolioSummary(apps, topRules), nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"time"
+
+	logrusr "github.com/bombsimon/logrusr/v3"
+	"github.com/konveyor/analyzer-lsp/process"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/lib"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const preparedManifestFile = "prepared.yaml"
+
+// preparedManifest records the providers prepared in a directory, the
+// analyses reusing it must analyze the same locations.
+type preparedManifest struct {
+	Created   time.Time          `yaml:"created"`
+	Providers []preparedProvider `yaml:"providers"`
+}
+
+type preparedProvider struct {
+	Name      string   `yaml:"name"`
+	Locations []string `yaml:"locations"`
+}
+
+// PrepareCmd runs the preparation of the providers, the decompilation of
+// dependencies, the build of symbol caches and the resolution of
+// dependencies, and keeps their artifacts in a directory that analyses are
+// given with --prepared-dir.
+func PrepareCmd() *cobra.Command {
+	var (
+		prepareSettings string
+		prepareDir      string
+		prepareMode     string
+	)
+	prepareCmd := &cobra.Command{
+		Use:   "prepare",
+		Short: "Prepare the providers and keep their artifacts for later analyses",
+		Long: "Initialize the providers and resolve the dependencies of the locations, the artifacts of the " +
+			"preparation are kept in the prepared directory. Analyses given the directory with --prepared-dir " +
+			"reuse them, a pipeline can cache it between analysis attempts.",
+		RunE: func(c *cobra.Command, args []string) error {
+			if prepareDir == "" {
+				return fmt.Errorf("the directory to prepare must be given with --prepared-dir")
+			}
+			if prepareMode != "" && prepareMode != string(provider.FullAnalysisMode) && prepareMode != string(provider.SourceOnlyAnalysisMode) {
+				return fmt.Errorf("must select one of %s or %s for analysis mode", provider.FullAnalysisMode, provider.SourceOnlyAnalysisMode)
+			}
+			c.SilenceUsage = true
+			logrusLog := logrus.New()
+			logrusLog.SetOutput(os.Stdout)
+			logrusLog.SetLevel(logrus.Level(logLevel))
+			log := logrusr.New(logrusLog)
+
+			if reaped := process.ReapOrphans(log); reaped > 0 {
+				log.Info("stopped orphaned processes of a previous analysis", "groups", reaped)
+			}
+
+			dir, err := filepath.Abs(prepareDir)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			configs, err := provider.GetConfig(prepareSettings)
+			if err != nil {
+				return fmt.Errorf("unable to get configuration: %w", err)
+			}
+			configs = withPreparedDir(configs, dir)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			manifest := preparedManifest{Created: time.Now().UTC()}
+			providers := map[string]provider.InternalProviderClient{}
+			defer func() {
+				for _, prov := range providers {
+					prov.Stop()
+				}
+			}()
+			for _, config := range configs {
+				if config.Name == "builtin" {
+					continue
+				}
+				locations := []string{}
+				for i, initConfig := range config.InitConfig {
+					if err := os.MkdirAll(initConfig.ProviderSpecificConfig[provider.PreparedDirConfigKey].(string), 0755); err != nil {
+						return err
+					}
+					if prepareMode != "" {
+						config.InitConfig[i].AnalysisMode = provider.AnalysisMode(prepareMode)
+					}
+					locations = append(locations, initConfig.Location)
+				}
+				manifest.Providers = append(manifest.Providers, preparedProvider{Name: config.Name, Locations: locations})
+				prov, err := lib.GetProviderClient(config, log)
+				if err != nil {
+					return fmt.Errorf("unable to create provider client %s: %w", config.Name, err)
+				}
+				providers[config.Name] = prov
+				if s, ok := prov.(provider.Startable); ok {
+					if err := s.Start(ctx); err != nil {
+						return fmt.Errorf("unable to start provider %s: %w", config.Name, err)
+					}
+				}
+			}
+			if err := provider.InitProviders(ctx, log, providers, 0); err != nil {
+				return err
+			}
+			for name, prov := range providers {
+				if !provider.HasCapability(prov.Capabilities(), "dependency") {
+					continue
+				}
+				deps, err := prov.GetDependencies(ctx)
+				if err != nil {
+					return fmt.Errorf("unable to resolve the dependencies of provider %s: %w", name, err)
+				}
+				log.Info("resolved dependencies", "provider", name, "files", len(deps))
+			}
+
+			b, err := yaml.Marshal(manifest)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(dir, preparedManifestFile), b, 0644); err != nil {
+				return err
+			}
+			log.Info("prepared providers", "dir", dir, "providers", len(providers))
+			return nil
+		},
+	}
+	prepareCmd.Flags().StringVar(&prepareSettings, "provider-settings", "provider_settings.json", "path to the provider settings")
+	prepareCmd.Flags().StringVar(&prepareDir, "prepared-dir", "", "directory to keep the artifacts of the preparation in")
+	prepareCmd.Flags().StringVar(&prepareMode, "analysis-mode", "", "select one of full or source-only to tell the providers what to prepare, it must be the analysis mode of the analyses")
+	prepareCmd.Flags().IntVar(&logLevel, "verbose", 9, "level for logging output")
+	return prepareCmd
+}
+
+// withPreparedDir sets the directory every provider init config keeps the
+// artifacts of its preparation in, the builtin provider has none.
+func withPreparedDir(configs []provider.Config, dir string) []provider.Config {
+	for _, config := range configs {
+		if config.Name == "builtin" {
+			continue
+		}
+		for i := range config.InitConfig {
+			if config.InitConfig[i].ProviderSpecificConfig == nil {
+				config.InitConfig[i].ProviderSpecificConfig = map[string]interface{}{}
+			}
+			config.InitConfig[i].ProviderSpecificConfig[provider.PreparedDirConfigKey] = filepath.Join(dir, config.Name, strconv.Itoa(i))
+		}
+	}
+	return configs
+}
+
+// checkPreparedDir checks that the directory was prepared for the locations
+// of the providers of the configs.
+func checkPreparedDir(dir string, configs []provider.Config) error {
+	content, err := os.ReadFile(filepath.Join(dir, preparedManifestFile))
+	if err != nil {
+		return fmt.Errorf("%s is not a prepared directory, run the prepare command first: %w", dir, err)
+	}
+	manifest := preparedManifest{}
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return fmt.Errorf("unable to read %s: %w", preparedManifestFile, err)
+	}
+	prepared := map[string][]string{}
+	for _, p := range manifest.Providers {
+		prepared[p.Name] = p.Locations
+	}
+	for _, config := range configs {
+		if config.Name == "builtin" {
+			continue
+		}
+		locations, ok := prepared[config.Name]
+		if !ok {
+			return fmt.Errorf("provider %s was not prepared in %s", config.Name, dir)
+		}
+		current := []string{}
+		for _, initConfig := range config.InitConfig {
+			current = append(current, initConfig.Location)
+		}
+		if !reflect.DeepEqual(locations, current) {
+			return fmt.Errorf("provider %s was prepared for locations %v, not %v", config.Name, locations, current)
+		}
+	}
+	return nil
+}
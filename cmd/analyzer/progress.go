@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/progress"
+)
+
+const (
+	// progressInterval is the most often the progress of a task is reported
+	progressInterval = 500 * time.Millisecond
+	// webhookInterval is the most often the progress of a task is posted to
+	// a webhook
+	webhookInterval = 5 * time.Second
+)
+
+// progressReporter returns the reporter of the progress of the analysis with
+// the progress format to the progress output, stderr when it is not given,
+// and a function closing the output. The webhook format posts to the
+// progress output with the headers.
+func progressReporter(format, output string, headers []string, log logr.Logger) (progress.Reporter, func() error, error) {
+	if format == progress.WebhookFormat {
+		header, err := parseHeaders(headers)
+		if err != nil {
+			return nil, nil, err
+		}
+		reporter := progress.NewWebhookReporter(output, header, log)
+		return progress.NewThrottledReporter(reporter, webhookInterval), closeOnce(reporter.Close), nil
+	}
+	var w io.Writer = os.Stderr
+	closer := func() error { return nil }
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, closer = f, f.Close
+	}
+	reporter, err := progress.NewReporter(format, w)
+	if err != nil {
+		closer()
+		return nil, nil, err
+	}
+	return progress.NewThrottledReporter(reporter, progressInterval), closeOnce(closer), nil
+}
+
+// closeOnce returns a function calling close the first time it is called
+func closeOnce(close func() error) func() error {
+	var once sync.Once
+	var err error
+	return func() error {
+		once.Do(func() { err = close() })
+		return err
+	}
+}
+
+// parseHeaders parses the headers given as "Name: value"
+func parseHeaders(headers []string) (http.Header, error) {
+	header := http.Header{}
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("header %q must be given as \"Name: value\"", h)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+// validateWebhook checks the url of the webhook progress is posted to
+func validateWebhook(webhook string) error {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%s is not an http or https url", webhook)
+	}
+	return nil
+}
+
+// cancelOnSignal cancels the analysis with its control on the first interrupt
+// or termination signal, the rules being evaluated finish and the output has
+// the results of the ones evaluated until then. The next signal cancels the
+// context of the analysis, stopping it at once. It returns a function
+// stopping the handling of the signals.
+func cancelOnSignal(control *progress.Control, cancel context.CancelFunc, log logr.Logger) func() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case s := <-signals:
+			log.Info("canceling the analysis, signal it again to stop it at once", "signal", s.String())
+			control.Cancel("received signal " + s.String())
+		case <-stop:
+			return
+		}
+		select {
+		case <-signals:
+			cancel()
+		case <-stop:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(stop)
+	}
+}
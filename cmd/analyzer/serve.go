@@ -0,0 +1,792 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	logrusr "github.com/bombsimon/logrusr/v3"
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/atomicfile"
+	"github.com/konveyor/analyzer-lsp/process"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const serveRunFile = "run.json"
+
+// Statuses of the runs of the server besides the ones of the analyses of a
+// batch
+const (
+	runQueued   = "queued"
+	runRunning  = "running"
+	runCanceled = "canceled"
+)
+
+// serveRun is an analysis run by the server, kept in a directory of its own
+// of the runs directory with its provider settings, output and log
+type serveRun struct {
+	ID            string     `json:"id"`
+	Location      string     `json:"location"`
+	Rules         []string   `json:"rules,omitempty"`
+	LabelSelector string     `json:"labelSelector,omitempty"`
+	Status        string     `json:"status"`
+	ExitCode      int        `json:"exitCode"`
+	Error         string     `json:"error,omitempty"`
+	Created       time.Time  `json:"created"`
+	Started       *time.Time `json:"started,omitempty"`
+	Finished      *time.Time `json:"finished,omitempty"`
+	Violations    int        `json:"violations"`
+	Incidents     int        `json:"incidents"`
+	// ProviderGeneration is the generation of the provider settings of the
+	// run, known once it is running
+	ProviderGeneration int `json:"providerGeneration,omitempty"`
+	// Size is the bytes of the files of the run, known once it is done
+	Size int64 `json:"size"`
+}
+
+// done reports whether the run is completed, its files are then only
+// removed by the retention of the runs or by deleting it
+func (r *serveRun) done() bool {
+	return r.Status != runQueued && r.Status != runRunning
+}
+
+// runRequest is the body of the requests starting a run, the rules and the
+// label selector of the server are used when they are not given
+type runRequest struct {
+	Location      string   `json:"location"`
+	Rules         []string `json:"rules,omitempty"`
+	LabelSelector string   `json:"labelSelector,omitempty"`
+}
+
+// Statuses of the reloads of the provider settings
+const (
+	reloadDraining = "draining"
+	reloadDone     = "done"
+	reloadFailed   = "failed"
+)
+
+// providerReload is the status of the last reload of the provider settings
+type providerReload struct {
+	// Generation is the generation of the provider settings of the new runs
+	Generation int        `json:"generation"`
+	State      string     `json:"state"`
+	Error      string     `json:"error,omitempty"`
+	Started    time.Time  `json:"started"`
+	Finished   *time.Time `json:"finished,omitempty"`
+	// Added, Changed and Removed are the names of the providers whose
+	// settings were added, changed or removed
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	// Restarted are the names of the shared providers stopped once the runs
+	// using them are done, the new runs start them with the new settings
+	Restarted []string `json:"restarted,omitempty"`
+	// Draining are the runs still using the previous provider settings
+	Draining []string `json:"draining,omitempty"`
+}
+
+// providerSettings is a generation of the provider settings of the server
+type providerSettings struct {
+	generation int
+	content    []byte
+	// digests are the digests of the settings of the providers by name
+	digests map[string]string
+	// keys are the keys of the shared providers of the settings
+	keys map[string]bool
+}
+
+// loadProviderSettings reads and checks the provider settings
+func loadProviderSettings(path string, generation int) (*providerSettings, error) {
+	if _, err := provider.GetConfig(path); err != nil {
+		return nil, fmt.Errorf("unable to get configuration: %w", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	settings := []map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &settings); err != nil {
+		return nil, err
+	}
+	p := &providerSettings{generation: generation, content: content, digests: map[string]string{}, keys: map[string]bool{}}
+	for _, s := range settings {
+		name, _ := s["name"].(string)
+		b, err := yaml.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(b)
+		p.digests[name] = hex.EncodeToString(digest[:])
+		key, err := sharedProviderKey(s)
+		if err != nil {
+			return nil, err
+		}
+		p.keys[key] = true
+	}
+	return p, nil
+}
+
+// runRetention is how many completed runs are kept, zero meaning no limit
+type runRetention struct {
+	MaxRuns int
+	MaxAge  time.Duration
+	// MaxDisk is the most bytes of the files of the completed runs
+	MaxDisk int64
+}
+
+// expired returns the completed runs to remove to meet the retention, the
+// oldest first
+func (r runRetention) expired(runs []*serveRun, now time.Time) []*serveRun {
+	completed := []*serveRun{}
+	var size int64
+	for _, run := range runs {
+		if run.done() {
+			completed = append(completed, run)
+			size += run.Size
+		}
+	}
+	sort.Slice(completed, func(i, j int) bool {
+		if !completed[i].Finished.Equal(*completed[j].Finished) {
+			return completed[i].Finished.Before(*completed[j].Finished)
+		}
+		return completed[i].ID < completed[j].ID
+	})
+	expired := []*serveRun{}
+	for _, run := range completed {
+		tooMany := r.MaxRuns > 0 && len(completed)-len(expired) > r.MaxRuns
+		tooOld := r.MaxAge > 0 && now.Sub(*run.Finished) > r.MaxAge
+		tooBig := r.MaxDisk > 0 && size > r.MaxDisk
+		if !tooMany && !tooOld && !tooBig {
+			// the next runs are more recent
+			break
+		}
+		expired = append(expired, run)
+		size -= run.Size
+	}
+	return expired
+}
+
+// ServeCmd serves an HTTP API running analyses, the runs are kept in the
+// runs directory until the retention removes them or they are deleted.
+func ServeCmd() *cobra.Command {
+	var (
+		address          string
+		providerSettings string
+		rules            []string
+		labelSelector    string
+		runsDir          string
+		parallelism      int
+		shareProviders   bool
+		retention        runRetention
+		pruneInterval    time.Duration
+	)
+	serveCmd := &cobra.Command{
+		Use:   "serve [-- analyzer flags]",
+		Short: "Serve an HTTP API running analyses",
+		Long: "Serve an HTTP API running analyses of the locations posted to /runs, --parallelism of them at once, " +
+			"with the provider settings of the server. The provider settings, output and log of every run are kept " +
+			"in a directory of its own of the runs directory, the completed runs are removed once there are more " +
+			"than --max-runs of them, they are older than --max-run-age or their files take more than --max-disk " +
+			"bytes, the oldest first. The provider settings are reloaded by posting to /providers/reload, the new runs " +
+			"use the new settings and the shared providers whose settings changed are stopped once the runs using them " +
+			"are done. The flags after -- are added to the arguments of every analysis.",
+		RunE: func(c *cobra.Command, args []string) (err error) {
+			if providerSettings == "" {
+				return fmt.Errorf("the provider settings of the runs must be given with --provider-settings")
+			}
+			if runsDir == "" {
+				return fmt.Errorf("the directory of the runs must be given with --runs-dir")
+			}
+			if parallelism < 1 {
+				return fmt.Errorf("parallelism must be at least 1")
+			}
+			if retention.MaxRuns < 0 || retention.MaxAge < 0 || retention.MaxDisk < 0 {
+				return fmt.Errorf("the retention limits can't be negative")
+			}
+			if pruneInterval <= 0 {
+				return fmt.Errorf("prune-interval must be positive")
+			}
+			// the runs are analyzed with the settings and rules whatever the
+			// directory of the server
+			if providerSettings, err = filepath.Abs(providerSettings); err != nil {
+				return err
+			}
+			for i := range rules {
+				if rules[i], err = filepath.Abs(rules[i]); err != nil {
+					return err
+				}
+			}
+			c.SilenceUsage = true
+			logrusLog := logrus.New()
+			logrusLog.SetOutput(os.Stdout)
+			logrusLog.SetLevel(logrus.Level(logLevel))
+			log := logrusr.New(logrusLog)
+
+			if reaped := process.ReapOrphans(log); reaped > 0 {
+				log.Info("stopped orphaned processes of a previous analysis", "groups", reaped)
+			}
+			self, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(runsDir, 0755); err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			settings, err := loadProviderSettings(providerSettings, 1)
+			if err != nil {
+				return err
+			}
+			shared := &sharedProviders{log: log, providers: map[string]*sharedProvider{}}
+			defer shared.stop()
+			s := &runServer{
+				log:  log,
+				self: self,
+				dir:  runsDir,
+				defaults: batchManifest{
+					ProviderSettings: providerSettings,
+					Rules:            rules,
+					LabelSelector:    labelSelector,
+					Args:             args,
+				},
+				settings:  settings,
+				reload:    providerReload{Generation: 1, State: reloadDone, Started: time.Now().UTC()},
+				shared:    shared,
+				share:     shareProviders,
+				retention: retention,
+				ctx:       ctx,
+				slots:     make(chan struct{}, parallelism),
+				runs:      map[string]*serveRun{},
+				cancels:   map[string]context.CancelFunc{},
+				finished:  map[string]chan struct{}{},
+			}
+			if err := s.load(); err != nil {
+				return err
+			}
+			s.prune()
+			go func() {
+				ticker := time.NewTicker(pruneInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						s.prune()
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			server := &http.Server{Addr: address, Handler: s.handler()}
+			errs := make(chan error, 1)
+			go func() {
+				errs <- server.ListenAndServe()
+			}()
+			log.Info("serving the analysis API", "address", address, "runs", runsDir)
+			select {
+			case err := <-errs:
+				return err
+			case <-ctx.Done():
+			}
+			log.Info("stopping the server, the runs in progress are canceled")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+			s.wg.Wait()
+			return nil
+		},
+	}
+	serveCmd.Flags().StringVar(&address, "address", "localhost:8080", "address the API is served on")
+	serveCmd.Flags().StringVar(&providerSettings, "provider-settings", "", "path to the provider settings of the runs, the location of a run replaces their locations. They are read again when they are reloaded")
+	serveCmd.Flags().StringArrayVar(&rules, "rules", []string{}, "filename or directory containing rule files, the rules of the runs that do not give theirs")
+	serveCmd.Flags().StringVar(&labelSelector, "label-selector", "", "an expression to select rules based on labels, the selector of the runs that do not give theirs")
+	serveCmd.Flags().StringVar(&runsDir, "runs-dir", "", "directory the runs are kept in")
+	serveCmd.Flags().IntVar(&parallelism, "parallelism", 1, "number of runs analyzed at once, the other runs are queued")
+	serveCmd.Flags().BoolVar(&shareProviders, "share-providers", true, "start the provider binaries once for every run, the providers run from an image are started by every run as their container mounts its location")
+	serveCmd.Flags().IntVar(&retention.MaxRuns, "max-runs", 100, "most completed runs kept, zero means no limit")
+	serveCmd.Flags().DurationVar(&retention.MaxAge, "max-run-age", 7*24*time.Hour, "most time a completed run is kept, zero means no limit")
+	serveCmd.Flags().Int64Var(&retention.MaxDisk, "max-disk", 0, "most bytes of the files of the completed runs kept, zero means no limit")
+	serveCmd.Flags().DurationVar(&pruneInterval, "prune-interval", 10*time.Minute, "how often the completed runs are pruned, they are also pruned when a run is done")
+	serveCmd.Flags().IntVar(&logLevel, "verbose", 9, "level for logging output")
+	return serveCmd
+}
+
+// runServer runs the analyses posted to its API
+type runServer struct {
+	log       logr.Logger
+	self      string
+	dir       string
+	defaults  batchManifest
+	shared    *sharedProviders
+	share     bool
+	retention runRetention
+	ctx       context.Context
+	// slots bounds the runs analyzed at once
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mutex sync.Mutex
+	// settings are the provider settings of the new runs, reloaded from the
+	// provider settings of the defaults
+	settings *providerSettings
+	reload   providerReload
+	runs     map[string]*serveRun
+	cancels  map[string]context.CancelFunc
+	// finished are closed when the runs are no longer analyzed
+	finished map[string]chan struct{}
+}
+
+// load reads the runs of the runs directory, the runs that were not done
+// when the server stopped failed
+func (s *runServer) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(s.dir, entry.Name(), serveRunFile))
+		if err != nil {
+			continue
+		}
+		run := &serveRun{}
+		if err := json.Unmarshal(content, run); err != nil || run.ID != entry.Name() {
+			s.log.Info("skipping the directory of an unknown run", "dir", entry.Name())
+			continue
+		}
+		if !run.done() {
+			now := time.Now().UTC()
+			run.Status, run.ExitCode, run.Error = batchFailed, 1, "the server stopped before the run was done"
+			run.Finished = &now
+			run.Size = dirSize(filepath.Join(s.dir, run.ID))
+			if err := s.save(run); err != nil {
+				return err
+			}
+		}
+		s.runs[run.ID] = run
+	}
+	return nil
+}
+
+// save writes the run to its directory
+func (s *runServer) save(run *serveRun) error {
+	content, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(filepath.Join(s.dir, run.ID, serveRunFile), content, 0644)
+}
+
+// start queues the run of the request
+func (s *runServer) start(req runRequest) (serveRun, error) {
+	if req.Location == "" {
+		return serveRun{}, fmt.Errorf("the location of the run is missing")
+	}
+	location, err := filepath.Abs(req.Location)
+	if err != nil {
+		return serveRun{}, err
+	}
+	if _, err := os.Stat(location); err != nil {
+		return serveRun{}, fmt.Errorf("unable to find the location of the run: %w", err)
+	}
+	if len(req.Rules) == 0 && len(s.defaults.Rules) == 0 {
+		return serveRun{}, fmt.Errorf("the rules of the run are missing and the server has none")
+	}
+	id, err := newRunID()
+	if err != nil {
+		return serveRun{}, err
+	}
+	if err := os.MkdirAll(filepath.Join(s.dir, id), 0755); err != nil {
+		return serveRun{}, err
+	}
+	run := &serveRun{
+		ID:            id,
+		Location:      location,
+		Rules:         req.Rules,
+		LabelSelector: req.LabelSelector,
+		Status:        runQueued,
+		Created:       time.Now().UTC(),
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	finished := make(chan struct{})
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.save(run); err != nil {
+		cancel()
+		return serveRun{}, err
+	}
+	s.runs[id] = run
+	s.cancels[id] = cancel
+	s.finished[id] = finished
+	s.wg.Add(1)
+	go s.execute(ctx, run, finished)
+	return *run, nil
+}
+
+// execute analyzes the run once a slot is free
+func (s *runServer) execute(ctx context.Context, run *serveRun, finished chan struct{}) {
+	defer s.wg.Done()
+	defer close(finished)
+	defer s.prune()
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		s.finish(run, batchAppSummary{Status: runCanceled})
+		return
+	}
+	runDir := filepath.Join(s.dir, run.ID)
+	settingsPath := filepath.Join(runDir, batchSettingsFile)
+	s.mutex.Lock()
+	now := time.Now().UTC()
+	run.Status, run.Started = runRunning, &now
+	run.ProviderGeneration = s.settings.generation
+	s.save(run)
+	// the settings of the generation are written as the settings of the run,
+	// they are then changed for its location
+	content := s.settings.content
+	app := batchApp{
+		Name:             run.ID,
+		Location:         run.Location,
+		ProviderSettings: settingsPath,
+		Rules:            run.Rules,
+		LabelSelector:    run.LabelSelector,
+	}
+	s.mutex.Unlock()
+
+	summary := batchAppSummary{Status: batchFailed, ExitCode: 1}
+	var keys []string
+	err := os.WriteFile(settingsPath, content, 0644)
+	if err == nil {
+		var settings []byte
+		settings, keys, err = batchSettings(ctx, s.defaults, app, s.shared, s.share)
+		if err == nil {
+			err = os.WriteFile(settingsPath, settings, 0644)
+		}
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	} else {
+		summary = runBatchApp(ctx, s.log, s.self, s.defaults, app, runDir, settingsPath)
+	}
+	if ctx.Err() != nil {
+		summary = batchAppSummary{Status: runCanceled}
+		// the providers started by the analysis were not stopped by it
+		process.ReapOrphans(s.log)
+	}
+	s.shared.release(keys)
+	s.finish(run, summary)
+}
+
+// finish records the result of the run
+func (s *runServer) finish(run *serveRun, summary batchAppSummary) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now().UTC()
+	run.Status, run.ExitCode, run.Error = summary.Status, summary.ExitCode, summary.Error
+	run.Violations, run.Incidents = summary.Violations, summary.Incidents
+	run.Finished = &now
+	run.Size = dirSize(filepath.Join(s.dir, run.ID))
+	delete(s.cancels, run.ID)
+	if err := s.save(run); err != nil {
+		s.log.Error(err, "unable to save the run", "run", run.ID)
+	}
+	s.log.Info("run is done", "run", run.ID, "status", run.Status)
+	s.drain()
+}
+
+// reloadProviders reloads the provider settings for the new runs, the
+// shared providers whose settings changed are stopped once the runs using
+// them are done. The previous settings are kept when the new ones are
+// invalid.
+func (s *runServer) reloadProviders() (providerReload, error) {
+	s.mutex.Lock()
+	reload := providerReload{Generation: s.settings.generation, Started: time.Now().UTC()}
+	settings, err := loadProviderSettings(s.defaults.ProviderSettings, s.settings.generation+1)
+	if err != nil {
+		now := time.Now().UTC()
+		reload.State, reload.Error, reload.Finished = reloadFailed, err.Error(), &now
+		s.reload = reload
+		s.mutex.Unlock()
+		s.log.Error(err, "unable to reload the provider settings, the previous settings are kept")
+		return reload, err
+	}
+	reload.Generation = settings.generation
+	for name, digest := range settings.digests {
+		previous, ok := s.settings.digests[name]
+		switch {
+		case !ok:
+			reload.Added = append(reload.Added, name)
+		case previous != digest:
+			reload.Changed = append(reload.Changed, name)
+		}
+	}
+	for name := range s.settings.digests {
+		if _, ok := settings.digests[name]; !ok {
+			reload.Removed = append(reload.Removed, name)
+		}
+	}
+	sort.Strings(reload.Added)
+	sort.Strings(reload.Changed)
+	sort.Strings(reload.Removed)
+	s.settings = settings
+	s.mutex.Unlock()
+
+	// the runs starting now take the shared providers of the new settings
+	reload.Restarted = s.shared.retire(settings.keys)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	reload.State = reloadDraining
+	s.reload = reload
+	s.log.Info("reloaded the provider settings", "generation", reload.Generation,
+		"added", reload.Added, "changed", reload.Changed, "removed", reload.Removed, "restarted", reload.Restarted)
+	s.drain()
+	return s.reload, nil
+}
+
+// drain updates the runs still using previous provider settings, the reload
+// is done once there are none
+func (s *runServer) drain() {
+	if s.reload.State != reloadDraining {
+		return
+	}
+	draining := []string{}
+	for _, run := range s.runs {
+		if run.Status == runRunning && run.ProviderGeneration < s.settings.generation {
+			draining = append(draining, run.ID)
+		}
+	}
+	sort.Strings(draining)
+	s.reload.Draining = draining
+	if len(draining) == 0 {
+		now := time.Now().UTC()
+		s.reload.State, s.reload.Finished = reloadDone, &now
+		s.log.Info("runs of the previous provider settings are done", "generation", s.reload.Generation)
+	}
+}
+
+func (s *runServer) reloadStatus() providerReload {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.reload
+}
+
+// remove cancels the run when it is not done and removes it
+func (s *runServer) remove(id string) bool {
+	s.mutex.Lock()
+	_, ok := s.runs[id]
+	cancel := s.cancels[id]
+	finished := s.finished[id]
+	s.mutex.Unlock()
+	if !ok {
+		return false
+	}
+	if cancel != nil {
+		cancel()
+	}
+	if finished != nil {
+		<-finished
+	}
+	s.mutex.Lock()
+	delete(s.runs, id)
+	delete(s.finished, id)
+	s.mutex.Unlock()
+	if err := os.RemoveAll(filepath.Join(s.dir, id)); err != nil {
+		s.log.Error(err, "unable to remove the files of the run", "run", id)
+	}
+	return true
+}
+
+// prune removes the completed runs exceeding the retention
+func (s *runServer) prune() {
+	s.mutex.Lock()
+	runs := make([]*serveRun, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, run)
+	}
+	expired := s.retention.expired(runs, time.Now().UTC())
+	for _, run := range expired {
+		delete(s.runs, run.ID)
+		delete(s.finished, run.ID)
+	}
+	s.mutex.Unlock()
+	for _, run := range expired {
+		if err := os.RemoveAll(filepath.Join(s.dir, run.ID)); err != nil {
+			s.log.Error(err, "unable to remove the files of the run", "run", run.ID)
+			continue
+		}
+		s.log.Info("pruned run", "run", run.ID, "finished", run.Finished, "size", run.Size)
+	}
+}
+
+// list returns the runs by creation time
+func (s *runServer) list() []serveRun {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	runs := make([]serveRun, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, *run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].Created.Equal(runs[j].Created) {
+			return runs[i].Created.Before(runs[j].Created)
+		}
+		return runs[i].ID < runs[j].ID
+	})
+	return runs
+}
+
+func (s *runServer) get(id string) (serveRun, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return serveRun{}, false
+	}
+	return *run, true
+}
+
+// handler serves the API of the runs:
+//
+//	GET    /runs             the runs
+//	POST   /runs             starts a run of the runRequest of the body
+//	GET    /runs/{id}        the run
+//	DELETE /runs/{id}        cancels the run when it is not done and removes it
+//	GET    /runs/{id}/output the output of the run
+//	GET    /runs/{id}/log    the log of the run
+//	GET    /providers/reload the status of the last reload of the provider settings
+//	POST   /providers/reload reloads the provider settings
+func (s *runServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/providers/reload", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.reloadStatus())
+		case http.MethodPost:
+			reload, err := s.reloadProviders()
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, reload)
+				return
+			}
+			writeJSON(w, http.StatusAccepted, reload)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		}
+	})
+	mux.HandleFunc("/runs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.list())
+		case http.MethodPost:
+			req := runRequest{}
+			decoder := json.NewDecoder(r.Body)
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid run: %w", err))
+				return
+			}
+			run, err := s.start(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			writeJSON(w, http.StatusCreated, run)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		}
+	})
+	mux.HandleFunc("/runs/", func(w http.ResponseWriter, r *http.Request) {
+		id, file, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/runs/"), "/")
+		run, ok := s.get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", id))
+			return
+		}
+		switch {
+		case file == "" && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, run)
+		case file == "" && r.Method == http.MethodDelete:
+			if !s.remove(id) {
+				writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", id))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case (file == "output" || file == "log") && r.Method == http.MethodGet:
+			name := batchOutputFile
+			if file == "log" {
+				name = batchLogFile
+			}
+			path := filepath.Join(s.dir, id, name)
+			if _, err := os.Stat(path); err != nil {
+				writeError(w, http.StatusNotFound, fmt.Errorf("run %s has no %s", id, file))
+				return
+			}
+			http.ServeFile(w, r, path)
+		case file == "" || file == "output" || file == "log":
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		default:
+			writeError(w, http.StatusNotFound, fmt.Errorf("%s not found", r.URL.Path))
+		}
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// newRunID returns a new id of a run, the runs sort by the time they were
+// created
+func newRunID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b), nil
+}
+
+// dirSize returns the bytes of the files of the directory
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
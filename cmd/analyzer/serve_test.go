@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestRunRetentionExpired(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	finished := func(id string, age time.Duration, size int64) *serveRun {
+		f := now.Add(-age)
+		return &serveRun{ID: id, Status: batchSucceeded, Finished: &f, Size: size}
+	}
+	runs := []*serveRun{
+		finished("b", 2*time.Hour, 10),
+		finished("a", 3*time.Hour, 10),
+		finished("c", time.Hour, 10),
+		{ID: "running", Status: runRunning, Size: 100},
+		{ID: "queued", Status: runQueued},
+	}
+	tests := []struct {
+		name      string
+		retention runRetention
+		want      []string
+	}{
+		{
+			name: "no limits",
+			want: []string{},
+		},
+		{
+			name:      "most runs",
+			retention: runRetention{MaxRuns: 1},
+			want:      []string{"a", "b"},
+		},
+		{
+			name:      "most age",
+			retention: runRetention{MaxAge: 90 * time.Minute},
+			want:      []string{"a", "b"},
+		},
+		{
+			name:      "most disk of the completed runs",
+			retention: runRetention{MaxDisk: 25},
+			want:      []string{"a"},
+		},
+		{
+			name:      "every limit",
+			retention: runRetention{MaxRuns: 2, MaxAge: 150 * time.Minute, MaxDisk: 30},
+			want:      []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, run := range tt.retention.expired(runs, now) {
+				got = append(got, run.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeServeRun(t *testing.T, dir string, run serveRun, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, run.ID), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files[serveRunFile] = ""
+	for name, content := range files {
+		if name == serveRunFile {
+			b, err := json.Marshal(run)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content = string(b)
+		}
+		if err := os.WriteFile(filepath.Join(dir, run.ID, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunServer(t *testing.T) {
+	dir := t.TempDir()
+	created := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	finished := created.Add(time.Minute)
+	writeServeRun(t, dir, serveRun{ID: "old", Status: batchSucceeded, Created: created, Finished: &finished}, map[string]string{
+		batchOutputFile: "[]\n",
+		batchLogFile:    "done\n",
+	})
+	writeServeRun(t, dir, serveRun{ID: "recent", Status: batchPolicyFailed, Created: created.Add(time.Hour), Finished: &finished}, map[string]string{
+		batchLogFile: "done\n",
+	})
+	// the server stopped during this run
+	writeServeRun(t, dir, serveRun{ID: "interrupted", Status: runRunning, Created: created.Add(2 * time.Hour)}, map[string]string{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &runServer{
+		log:       logr.Discard(),
+		dir:       dir,
+		retention: runRetention{MaxRuns: 2},
+		ctx:       ctx,
+		slots:     make(chan struct{}, 1),
+		runs:      map[string]*serveRun{},
+		cancels:   map[string]context.CancelFunc{},
+		finished:  map[string]chan struct{}{},
+	}
+	if err := s.load(); err != nil {
+		t.Fatal(err)
+	}
+	s.prune()
+	if _, err := os.Stat(filepath.Join(dir, "old")); !os.IsNotExist(err) {
+		t.Errorf("expected the oldest completed run to be pruned, got %v", err)
+	}
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	do := func(method string, path string, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+
+	code, body := do(http.MethodGet, "/runs", "")
+	runs := []serveRun{}
+	if err := json.Unmarshal([]byte(body), &runs); code != http.StatusOK || err != nil {
+		t.Fatalf("GET /runs = %d %s", code, body)
+	}
+	if len(runs) != 2 || runs[0].ID != "recent" || runs[1].ID != "interrupted" {
+		t.Fatalf("GET /runs = %s, expected the recent and interrupted runs", body)
+	}
+	if runs[1].Status != batchFailed || runs[1].Finished == nil {
+		t.Errorf("expected the interrupted run to have failed, got %+v", runs[1])
+	}
+
+	if code, body := do(http.MethodGet, "/runs/recent/log", ""); code != http.StatusOK || body != "done\n" {
+		t.Errorf("GET /runs/recent/log = %d %s", code, body)
+	}
+	if code, _ := do(http.MethodGet, "/runs/recent/output", ""); code != http.StatusNotFound {
+		t.Errorf("GET /runs/recent/output = %d, expected the missing output to be not found", code)
+	}
+	if code, _ := do(http.MethodGet, "/runs/old", ""); code != http.StatusNotFound {
+		t.Errorf("GET /runs/old = %d, expected the pruned run to be not found", code)
+	}
+	if code, body := do(http.MethodPost, "/runs", `{"location": "`+filepath.Join(dir, "missing")+`"}`); code != http.StatusBadRequest {
+		t.Errorf("POST /runs = %d %s, expected a missing location to be rejected", code, body)
+	}
+	if code, body := do(http.MethodPost, "/runs", `{"location": "`+dir+`", "args": ["--mode", "full"]}`); code != http.StatusBadRequest {
+		t.Errorf("POST /runs = %d %s, expected unknown fields to be rejected", code, body)
+	}
+
+	if code, body := do(http.MethodDelete, "/runs/recent", ""); code != http.StatusNoContent {
+		t.Fatalf("DELETE /runs/recent = %d %s", code, body)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "recent")); !os.IsNotExist(err) {
+		t.Errorf("expected the files of the deleted run to be removed, got %v", err)
+	}
+	if code, _ := do(http.MethodDelete, "/runs/recent", ""); code != http.StatusNotFound {
+		t.Errorf("DELETE /runs/recent = %d, expected the deleted run to be not found", code)
+	}
+}
+
+func TestRunServerReloadProviders(t *testing.T) {
+	dir := t.TempDir()
+	settingsPath := filepath.Join(dir, "provider_settings.yaml")
+	writeSettings := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(settingsPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeSettings(`
+- name: builtin
+  initConfig:
+  - location: /tmp
+- name: custom
+  binaryPath: /bin/custom-provider
+  initConfig:
+  - location: /tmp
+`)
+	settings, err := loadProviderSettings(settingsPath, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a run of the first settings uses the shared custom provider
+	customKey, err := sharedProviderKey(map[string]interface{}{"name": "custom", "binaryPath": "/bin/custom-provider"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := false
+	shared := &sharedProviders{log: logr.Discard(), providers: map[string]*sharedProvider{
+		customKey: {name: "custom", address: "localhost:14651", stop: func() { stopped = true }, analyses: 1},
+	}}
+	run := &serveRun{ID: "running", Status: runRunning, ProviderGeneration: 1}
+	s := &runServer{
+		log:      logr.Discard(),
+		dir:      dir,
+		defaults: batchManifest{ProviderSettings: settingsPath},
+		settings: settings,
+		reload:   providerReload{Generation: 1, State: reloadDone},
+		shared:   shared,
+		runs:     map[string]*serveRun{run.ID: run},
+		cancels:  map[string]context.CancelFunc{},
+		finished: map[string]chan struct{}{},
+	}
+	if err := os.MkdirAll(filepath.Join(dir, run.ID), 0755); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+	reload := func(method string) (int, providerReload) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+"/providers/reload", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		status := providerReload{}
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, status
+	}
+
+	writeSettings(`
+- name: custom
+  binaryPath: /opt/custom-provider
+  initConfig:
+  - location: /tmp
+- name: other
+  binaryPath: /bin/generic-provider
+  initConfig:
+  - location: /tmp
+`)
+	code, status := reload(http.MethodPost)
+	if code != http.StatusAccepted {
+		t.Fatalf("POST /providers/reload = %d %+v", code, status)
+	}
+	want := providerReload{
+		Generation: 2,
+		State:      reloadDraining,
+		Added:      []string{"other"},
+		Changed:    []string{"custom"},
+		Removed:    []string{"builtin"},
+		Restarted:  []string{"custom"},
+		Draining:   []string{"running"},
+	}
+	status.Started = time.Time{}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("POST /providers/reload = %+v, want %+v", status, want)
+	}
+	if stopped {
+		t.Errorf("expected the custom provider to run until the run using it is done")
+	}
+
+	shared.release([]string{customKey})
+	s.finish(run, batchAppSummary{Status: batchSucceeded})
+	if !stopped {
+		t.Errorf("expected the custom provider of the previous settings to be stopped")
+	}
+	if code, status := reload(http.MethodGet); code != http.StatusOK || status.State != reloadDone || status.Finished == nil || len(status.Draining) != 0 {
+		t.Errorf("GET /providers/reload = %d %+v, expected the reload to be done", code, status)
+	}
+
+	// invalid settings keep the previous ones
+	writeSettings("name: custom\n")
+	if code, status := reload(http.MethodPost); code != http.StatusBadRequest || status.State != reloadFailed || status.Generation != 2 || status.Error == "" {
+		t.Errorf("POST /providers/reload = %d %+v, expected the invalid settings to fail", code, status)
+	}
+	if s.settings.generation != 2 {
+		t.Errorf("expected the settings of generation 2 to be kept, got %d", s.settings.generation)
+	}
+}
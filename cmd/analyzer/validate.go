@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	logrusr "github.com/bombsimon/logrusr/v3"
+	"github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/lib"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ValidateCmd checks rule files without running an analysis
+func ValidateCmd() *cobra.Command {
+	var (
+		validateRules    []string
+		validateSettings string
+	)
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate rule files and report every problem found with its file and line",
+		Long: "Validate rule files against the rule schema and check that the rules can run: " +
+			"label and template syntax, custom variables and, when provider settings are given, " +
+			"that the providers and capabilities used by the conditions exist.",
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(validateRules) == 0 {
+				return fmt.Errorf("at least one rule file or directory must be given with --rules")
+			}
+			// the errors are about the rules, not the usage of the command
+			c.SilenceUsage = true
+			logrusLog := logrus.New()
+			logrusLog.SetOutput(os.Stderr)
+			logrusLog.SetLevel(logrus.Level(logLevel))
+			log := logrusr.New(logrusLog)
+
+			validator := parser.RuleValidator{}
+			if validateSettings != "" {
+				configs, err := provider.GetConfig(validateSettings)
+				if err != nil {
+					return fmt.Errorf("unable to get configuration: %w", err)
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				validator.Capabilities = map[string][]provider.Capability{}
+				for _, config := range configs {
+					prov, err := lib.GetProviderClient(config, log)
+					if err != nil {
+						return fmt.Errorf("unable to create provider client %s: %w", config.Name, err)
+					}
+					if s, ok := prov.(provider.Startable); ok {
+						if err := s.Start(ctx); err != nil {
+							return fmt.Errorf("unable to start provider %s: %w", config.Name, err)
+						}
+					}
+					validator.Capabilities[config.Name] = prov.Capabilities()
+					prov.Stop()
+				}
+			}
+
+			errors := 0
+			for _, rules := range validateRules {
+				issues, err := validator.Validate(rules)
+				if err != nil {
+					return fmt.Errorf("unable to validate %s: %w", rules, err)
+				}
+				for _, issue := range issues {
+					fmt.Println(issue.String())
+					if !issue.Warning {
+						errors++
+					}
+				}
+			}
+			if errors > 0 {
+				return fmt.Errorf("found %d errors in the rules", errors)
+			}
+			return nil
+		},
+	}
+	validateCmd.Flags().StringArrayVar(&validateRules, "rules", []string{}, "filename or directory containing rule files")
+	validateCmd.Flags().StringVar(&validateSettings, "provider-settings", "", "path to the provider settings, the providers and capabilities used by the rules are only checked when it is given")
+	validateCmd.Flags().IntVar(&logLevel, "verbose", 0, "level for logging output")
+	return validateCmd
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/bombsimon/logrusr/v3"
@@ -24,6 +27,8 @@ var (
 	treeOutput       bool
 	outputFile       string
 	depLabelSelector string
+	depPath          string
+	outputFormat     string
 )
 
 func init() {
@@ -74,6 +79,7 @@ func DependencyCmd() *cobra.Command {
 				errLog.Error(err, "unable to get configuration")
 				os.Exit(1)
 			}
+			name := applicationName(configs)
 
 			for _, config := range configs {
 				prov, err := lib.GetProviderClient(config, log)
@@ -110,13 +116,18 @@ func DependencyCmd() *cobra.Command {
 					continue
 				}
 
-				if treeOutput {
+				if treeOutput || depPath != "" {
 					deps, err := prov.GetDependenciesDAG(ctx)
 					if err != nil {
 						errLog.Error(err, "failed to get list of dependencies for provider", "provider", name)
 						continue
 					}
 					for u, ds := range deps {
+						ds, cycles := provider.BreakDepDAGCycles(ds)
+						for _, cycle := range cycles {
+							log.Info("dependency cycle found", "provider", name, "file", u, "cycle", strings.Join(cycle, " -> "))
+						}
+						provider.SetDepDAGPaths(ds)
 						depsTree = append(depsTree, konveyor.DepsTreeItem{
 							FileURI:      string(u),
 							Provider:     name,
@@ -157,8 +168,22 @@ func DependencyCmd() *cobra.Command {
 				os.Exit(0)
 			}
 
+			if depPath != "" {
+				printDepPaths(depsTree, depPath)
+				return
+			}
+
 			var b []byte
-			if treeOutput {
+			if outputFormat != "yaml" {
+				sbom := konveyor.NewSBOM(name, time.Now())
+				sbom.AddDeps(depsFlat)
+				sbom.AddDepTrees(depsTree)
+				b, err = sbom.Marshal(konveyor.SBOMFormat(outputFormat))
+				if err != nil {
+					errLog.Error(err, "failed to create the SBOM of the dependencies")
+					os.Exit(1)
+				}
+			} else if treeOutput {
 				b, err = yaml.Marshal(depsTree)
 				if err != nil {
 					errLog.Error(err, "failed to marshal dependency data as yaml")
@@ -192,11 +217,51 @@ func DependencyCmd() *cobra.Command {
 	rootCmd.Flags().StringVar(&providerSettings, "provider-settings", "provider_settings.json", "path to the provider settings")
 	rootCmd.Flags().BoolVar(&treeOutput, "tree", false, "output dependencies as a tree")
 	rootCmd.Flags().StringVar(&outputFile, "output-file", "output.yaml", "path to output file")
+	rootCmd.Flags().StringVar(&outputFormat, "output-format", "yaml", fmt.Sprintf("format of the output file, yaml or the SBOM formats %v", konveyor.SBOMFormats))
 	rootCmd.Flags().StringVar(&depLabelSelector, "dep-label-selector", "", "an expression to select dependencies based on labels provided by the provider")
+	rootCmd.Flags().StringVar(&depPath, "dep-path", "", "print why the dependencies with a name containing the value are present, the chain of dependencies from every direct dependency adding them, e.g. log4j@1. for the 1.x versions of log4j")
 	return rootCmd
 
 }
 
+// applicationName names the application in the SBOMs after its location
+func applicationName(configs []provider.Config) string {
+	for _, config := range configs {
+		for _, c := range config.InitConfig {
+			if c.Location != "" {
+				return filepath.Base(c.Location)
+			}
+		}
+	}
+	return "application"
+}
+
+// printDepPaths prints the shortest chain of dependencies to the dependencies
+// matching the query from every direct dependency adding them
+func printDepPaths(depsTree []konveyor.DepsTreeItem, query string) {
+	sort.SliceStable(depsTree, func(i, j int) bool {
+		if depsTree[i].Provider == depsTree[j].Provider {
+			return depsTree[i].FileURI < depsTree[j].FileURI
+		}
+		return depsTree[i].Provider < depsTree[j].Provider
+	})
+	found := false
+	for _, item := range depsTree {
+		paths := provider.FindDepPaths(item.Dependencies, query)
+		if len(paths) == 0 {
+			continue
+		}
+		found = true
+		fmt.Printf("%s %s:\n", item.Provider, item.FileURI)
+		for _, p := range paths {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+	if !found {
+		fmt.Printf("no dependency matching %s found\n", query)
+	}
+}
+
 func main() {
 	if err := DependencyCmd().Execute(); err != nil {
 		os.Exit(1)
@@ -211,6 +276,9 @@ func validateFlags() error {
 	if err != nil {
 		return fmt.Errorf("unable to find provider settings file")
 	}
+	if outputFormat != "yaml" && !slices.Contains(konveyor.SBOMFormats, konveyor.SBOMFormat(outputFormat)) {
+		return fmt.Errorf("output-format must be yaml or one of %v, not %s", konveyor.SBOMFormats, outputFormat)
+	}
 
 	return nil
 }
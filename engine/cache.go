@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"sync"
+)
+
+// ConditionCache memoizes the responses of the conditions evaluated during a
+// run of the engine, so that the conditions repeated by several rules are
+// evaluated once. The engine adds a cache to the context of the conditions
+// of every run with WithConditionCache, conditions find it with
+// ConditionCacheFromContext.
+type ConditionCache struct {
+	mutex   sync.Mutex
+	entries map[string]*conditionCacheEntry
+	hits    int
+	misses  int
+}
+
+type conditionCacheEntry struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func NewConditionCache() *ConditionCache {
+	return &ConditionCache{entries: map[string]*conditionCacheEntry{}}
+}
+
+// Evaluate returns the value cached for the key, or the value evaluated and
+// cached for it, and whether it was cached. The conditions evaluating the
+// key meanwhile wait for its value. Errors are returned to the conditions
+// waiting for them but not cached.
+func (c *ConditionCache) Evaluate(key string, evaluate func() (interface{}, error)) (interface{}, bool, error) {
+	c.mutex.Lock()
+	if entry, ok := c.entries[key]; ok {
+		c.hits++
+		c.mutex.Unlock()
+		<-entry.done
+		return entry.value, true, entry.err
+	}
+	entry := &conditionCacheEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.misses++
+	c.mutex.Unlock()
+
+	entry.value, entry.err = evaluate()
+	if entry.err != nil {
+		c.mutex.Lock()
+		delete(c.entries, key)
+		c.mutex.Unlock()
+	}
+	close(entry.done)
+	return entry.value, false, entry.err
+}
+
+// Stats returns the number of evaluations answered from the cache and of
+// the ones evaluated
+func (c *ConditionCache) Stats() (hits int, misses int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.hits, c.misses
+}
+
+type conditionCacheKey struct{}
+
+// WithConditionCache returns a context caching the responses of the
+// conditions evaluated with it in the cache
+func WithConditionCache(ctx context.Context, cache *ConditionCache) context.Context {
+	if cache == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, conditionCacheKey{}, cache)
+}
+
+// ConditionCacheFromContext returns the cache of the run evaluating the
+// conditions, nil when the responses are not cached
+func ConditionCacheFromContext(ctx context.Context) *ConditionCache {
+	cache, _ := ctx.Value(conditionCacheKey{}).(*ConditionCache)
+	return cache
+}
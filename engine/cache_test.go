@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConditionCache(t *testing.T) {
+	cache := NewConditionCache()
+	var evaluations int32
+	evaluate := func() (interface{}, error) {
+		atomic.AddInt32(&evaluations, 1)
+		return "response", nil
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, _, err := cache.Evaluate("key", evaluate)
+			if err != nil || value != "response" {
+				t.Errorf("Evaluate() = %v %v", value, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if evaluations != 1 {
+		t.Errorf("the key must be evaluated once, got %d evaluations", evaluations)
+	}
+	if hits, misses := cache.Stats(); hits != 9 || misses != 1 {
+		t.Errorf("Stats() = %d %d, want 9 1", hits, misses)
+	}
+
+	failed := errors.New("provider failed")
+	if _, cached, err := cache.Evaluate("error", func() (interface{}, error) { return nil, failed }); cached || err != failed {
+		t.Errorf("Evaluate() = %v %v, want the error evaluated", cached, err)
+	}
+	if value, cached, err := cache.Evaluate("error", evaluate); cached || err != nil || value != "response" {
+		t.Errorf("Evaluate() = %v %v %v, errors must not be cached", value, cached, err)
+	}
+}
+
+func TestConditionCacheFromContext(t *testing.T) {
+	if ConditionCacheFromContext(context.Background()) != nil {
+		t.Error("a context has no cache by default")
+	}
+	if ConditionCacheFromContext(WithConditionCache(context.Background(), nil)) != nil {
+		t.Error("a nil cache does not cache")
+	}
+	cache := NewConditionCache()
+	if ConditionCacheFromContext(WithConditionCache(context.Background(), cache)) != cache {
+		t.Error("the cache of the context must be found")
+	}
+}
@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// CategorySelector selects the rules of the given categories. Rules without
+// a category, the rules only tagging the application, are always selected
+// as the rules of the categories may depend on their tags.
+type CategorySelector struct {
+	categories []konveyor.Category
+}
+
+// NewCategorySelector returns a selector of the rules of a comma separated
+// list of categories, such as mandatory,optional
+func NewCategorySelector(categories string) (*CategorySelector, error) {
+	parsed, err := konveyor.ParseCategories(categories)
+	if err != nil {
+		return nil, err
+	}
+	return &CategorySelector{categories: parsed}, nil
+}
+
+func (s *CategorySelector) Matches(m *RuleMeta) (bool, error) {
+	if m.Category == nil {
+		return true, nil
+	}
+	for _, category := range s.categories {
+		if *m.Category == category {
+			return true, nil
+		}
+	}
+	return false, nil
+}
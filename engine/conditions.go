@@ -3,12 +3,16 @@ package engine
 import (
 	"context"
 	"fmt"
+	"io"
 	"maps"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/textfile"
 	"github.com/konveyor/analyzer-lsp/tracing"
 	"go.lsp.dev/uri"
 )
@@ -22,6 +26,8 @@ type ConditionResponse struct {
 	// keys here, will be used in the message.
 	Incidents       []IncidentContext      `yaml:"incidents"`
 	TemplateContext map[string]interface{} `yaml:",inline"`
+	// Warnings are non-fatal issues found while evaluating the condition
+	Warnings []konveyor.Warning `yaml:"warnings,omitempty"`
 }
 
 type ConditionContext struct {
@@ -40,11 +46,17 @@ func (c *ConditionContext) Copy() ConditionContext {
 	}
 }
 
+// ConditionEntry is a condition of a rule. Within an and/or condition, an
+// entry can name its Branch, its incidents report the branch and get the
+// Message of the entry instead of the one of the rule when it is set.
 type ConditionEntry struct {
 	From                   string
 	As                     string
 	Ignorable              bool
 	Not                    bool
+	FilterBySource         *regexp.Regexp
+	Branch                 string
+	Message                *string
 	ProviderSpecificConfig Conditional
 }
 
@@ -55,6 +67,12 @@ type IncidentContext struct {
 	Variables    map[string]interface{} `yaml:"variables"`
 	Links        []konveyor.Link        `yaml:"externalLink"`
 	CodeLocation *Location              `yaml:"location,omitempty"`
+	// MatchedText is the exact text matched by the condition when the
+	// provider returns it
+	MatchedText string `yaml:"matchedText,omitempty"`
+	// Branch and Message are set by the and/or condition branch the incident was found by
+	Branch  string  `yaml:"branch,omitempty"`
+	Message *string `yaml:"-"`
 }
 
 type Location struct {
@@ -94,14 +112,20 @@ type RuleSet struct {
 	Name        string   `json:"name,omitempty" yaml:"name,omitempty"`
 	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
 	Labels      []string `json:"labels,omitempty" yaml:"labels,omitempty"`
-	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty"`
-	Rules       []Rule   `json:"rules,omitempty" yaml:"rules,omitempty"`
+	// DefaultLabels are added to the rules that don't have a label with the same key
+	DefaultLabels []string `json:"defaultLabels,omitempty" yaml:"defaultLabels,omitempty"`
+	Tags          []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// SourceURL is the URL of the directory of the ruleset in its upstream
+	// repository, the violations link to the rule files under it
+	SourceURL string `json:"sourceURL,omitempty" yaml:"sourceURL,omitempty"`
+	Rules     []Rule `json:"rules,omitempty" yaml:"rules,omitempty"`
 }
 
 type Rule struct {
 	RuleMeta        `yaml:",inline" json:",inline"`
 	Perform         Perform          `yaml:",inline" json:"perform,omitempty"`
 	When            Conditional      `yaml:"when,omitempty" json:"when,omitempty"`
+	Unless          Conditional      `yaml:"unless,omitempty" json:"unless,omitempty"`
 	Snipper         CodeSnip         `yaml:"-" json:"-"`
 	CustomVariables []CustomVariable `yaml:"customVariables,omitempty" json:"customVariables,omitempty"`
 }
@@ -110,8 +134,15 @@ type RuleMeta struct {
 	RuleID      string             `yaml:"ruleID,omitempty" json:"ruleID,omitempty"`
 	Description string             `yaml:"description,omitempty" json:"description,omitempty"`
 	Category    *konveyor.Category `yaml:"category,omitempty" json:"category,omitempty"`
+	Severity    *konveyor.Severity `yaml:"severity,omitempty" json:"severity,omitempty"`
 	Labels      []string           `yaml:"labels,omitempty" json:"labels,omitempty"`
 	Effort      *int               `json:"effort,omitempty"`
+	Task        string             `yaml:"task,omitempty" json:"task,omitempty"`
+	// Cost is a hint of how expensive the rule is to evaluate, the engine
+	// finds it from the conditions of the rule when it's not set
+	Cost *Cost `yaml:"cost,omitempty" json:"cost,omitempty"`
+	// Source is where the rule is defined, set by the parser
+	Source *konveyor.RuleSource `yaml:"source,omitempty" json:"source,omitempty"`
 }
 
 func (r *RuleMeta) GetLabels() []string {
@@ -133,8 +164,43 @@ type CustomVariable struct {
 type Perform struct {
 	Message Message  `yaml:",inline"`
 	Tag     []string `yaml:"tag,omitempty"`
+	Fix     *Fix     `yaml:"fix,omitempty"`
 }
 
+// Fix is the remediation of the incidents of a rule. Its fields are templates
+// of the variables of each incident, the values of the variables are quoted
+// in the Search regex.
+type Fix struct {
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// File is the file the fix edits relative to the directory of the file
+	// of the incident, the file of the incident when it's empty
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+	// Search is a regex replaced with Replace on the line of the incident,
+	// or in the whole file when the incident has no line or File is set
+	Search     string `yaml:"search,omitempty" json:"search,omitempty"`
+	Replace    string `yaml:"replace,omitempty" json:"replace,omitempty"`
+	CodeAction string `yaml:"codeAction,omitempty" json:"codeAction,omitempty"`
+}
+
+func (f *Fix) Validate() error {
+	if f.Search == "" && f.CodeAction == "" {
+		return fmt.Errorf("fix must have a search pattern or a code action")
+	}
+	if f.Search == "" && (f.Replace != "" || f.File != "") {
+		return fmt.Errorf("replace and file of a fix can only be used with search")
+	}
+	if filepath.IsAbs(f.File) {
+		return fmt.Errorf("file of a fix must be relative to the file of the incident")
+	}
+	// the templates are rendered for each incident
+	if _, err := regexp.Compile(fixTemplateRegex.ReplaceAllString(f.Search, "x")); err != nil {
+		return fmt.Errorf("invalid search pattern of fix: %w", err)
+	}
+	return nil
+}
+
+var fixTemplateRegex = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+
 type Message struct {
 	Text  *string         `yaml:"message,omitempty"`
 	Links []konveyor.Link `yaml:"links,omitempty"`
@@ -147,8 +213,14 @@ func (p *Perform) Validate() error {
 	return nil
 }
 
+// AndCondition matches when all its conditions match. When FileScoped is
+// set, the conditions must also match in the same files, the incidents are
+// the ones in the files every condition with incidents has incidents in.
+// Conditions negated with not, ignored or without incidents are not scoped
+// to files.
 type AndCondition struct {
 	Conditions []ConditionEntry `yaml:"and"`
+	FileScoped bool             `yaml:"fileScoped,omitempty"`
 }
 
 func (a AndCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
@@ -164,6 +236,7 @@ func (a AndCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Con
 		Incidents:       []IncidentContext{},
 		TemplateContext: map[string]interface{}{},
 	}
+	scopedIncidents := [][]IncidentContext{}
 	conditions := sortConditionEntries(a.Conditions)
 	for _, c := range conditions {
 		if _, ok := condCtx.Template[c.From]; !ok && c.From != "" {
@@ -176,10 +249,7 @@ func (a AndCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Con
 			return ConditionResponse{}, err
 		}
 		if c.As != "" {
-			condCtx.Template[c.As] = ChainTemplate{
-				Filepaths: incidentsToFilepaths(response.Incidents),
-				Extras:    response.TemplateContext,
-			}
+			condCtx.Template[c.As] = newChainTemplate(response)
 		}
 
 		matched := response.Matched
@@ -191,17 +261,50 @@ func (a AndCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Con
 		}
 
 		if !c.Ignorable {
-			fullResponse.Incidents = append(fullResponse.Incidents, response.Incidents...)
+			incidents := c.branchIncidents(response.Incidents)
+			fullResponse.Incidents = append(fullResponse.Incidents, incidents...)
+			if !c.Not && len(incidents) > 0 {
+				scopedIncidents = append(scopedIncidents, incidents)
+			}
 		}
+		fullResponse.Warnings = append(fullResponse.Warnings, response.Warnings...)
 
 		for k, v := range response.TemplateContext {
 			fullResponse.TemplateContext[k] = v
 		}
 	}
 
+	if a.FileScoped && fullResponse.Matched && len(scopedIncidents) > 0 {
+		fullResponse.Incidents = incidentsInAllFiles(scopedIncidents)
+		fullResponse.Matched = len(fullResponse.Incidents) > 0
+	}
 	return fullResponse, nil
 }
 
+// incidentsInAllFiles returns the incidents of the groups that are in files
+// every group has incidents in.
+func incidentsInAllFiles(groups [][]IncidentContext) []IncidentContext {
+	files := map[uri.URI]int{}
+	for _, incidents := range groups {
+		seen := map[uri.URI]bool{}
+		for _, incident := range incidents {
+			if !seen[incident.FileURI] {
+				seen[incident.FileURI] = true
+				files[incident.FileURI]++
+			}
+		}
+	}
+	inAll := []IncidentContext{}
+	for _, incidents := range groups {
+		for _, incident := range incidents {
+			if files[incident.FileURI] == len(groups) {
+				inAll = append(inAll, incident)
+			}
+		}
+	}
+	return inAll
+}
+
 type OrCondition struct {
 	Conditions []ConditionEntry `yaml:"or"`
 }
@@ -234,10 +337,7 @@ func (o OrCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Cond
 		}
 
 		if c.As != "" {
-			condCtx.Template[c.As] = ChainTemplate{
-				Filepaths: incidentsToFilepaths(response.Incidents),
-				Extras:    response.TemplateContext,
-			}
+			condCtx.Template[c.As] = newChainTemplate(response)
 		}
 
 		matched := response.Matched
@@ -249,8 +349,9 @@ func (o OrCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Cond
 		}
 
 		if !c.Ignorable {
-			fullResponse.Incidents = append(fullResponse.Incidents, response.Incidents...)
+			fullResponse.Incidents = append(fullResponse.Incidents, c.branchIncidents(response.Incidents)...)
 		}
+		fullResponse.Warnings = append(fullResponse.Warnings, response.Warnings...)
 
 		for k, v := range response.TemplateContext {
 			fullResponse.TemplateContext[k] = v
@@ -260,12 +361,41 @@ func (o OrCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Cond
 	return fullResponse, nil
 }
 
+// branchIncidents returns the incidents with the branch and message of the
+// condition. Incidents found by a nested branch keep what it set.
+func (ce ConditionEntry) branchIncidents(incidents []IncidentContext) []IncidentContext {
+	if ce.Branch == "" && ce.Message == nil {
+		return incidents
+	}
+	// copy, the response may be shared with chained conditions
+	branched := make([]IncidentContext, len(incidents))
+	for i, incident := range incidents {
+		if incident.Branch == "" {
+			incident.Branch = ce.Branch
+		}
+		if incident.Message == nil {
+			incident.Message = ce.Message
+		}
+		branched[i] = incident
+	}
+	return branched
+}
+
 func (ce ConditionEntry) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
 	response, err := ce.ProviderSpecificConfig.Evaluate(ctx, log, condCtx)
 	if err != nil {
 		return ConditionResponse{}, err
 	}
 
+	if ce.FilterBySource != nil && len(response.Incidents) > 0 {
+		var warnings []konveyor.Warning
+		response.Incidents, warnings = filterIncidentsBySource(log, response.Incidents, ce.FilterBySource)
+		response.Warnings = append(response.Warnings, warnings...)
+		if len(response.Incidents) == 0 {
+			response.Matched = false
+		}
+	}
+
 	matched := response.Matched
 	if ce.Not {
 		matched = !matched
@@ -275,6 +405,126 @@ func (ce ConditionEntry) Evaluate(ctx context.Context, log logr.Logger, condCtx
 	return response, nil
 }
 
+// filterIncidentsBySource keeps the incidents whose source lines match the pattern.
+// Incidents without a line number or in files that can't be read are kept as
+// there is nothing to filter them on.
+func filterIncidentsBySource(log logr.Logger, incidents []IncidentContext, pattern *regexp.Regexp) ([]IncidentContext, []konveyor.Warning) {
+	// only the lines of the incidents are read from their files
+	wanted := map[uri.URI]map[int]bool{}
+	for _, incident := range incidents {
+		if incident.LineNumber == nil || !strings.HasPrefix(string(incident.FileURI), uri.FileScheme) {
+			continue
+		}
+		if wanted[incident.FileURI] == nil {
+			wanted[incident.FileURI] = map[int]bool{}
+		}
+		start, end := incidentSourceLines(incident)
+		for line := max(start, 0); line <= end; line++ {
+			wanted[incident.FileURI][line] = true
+		}
+	}
+	fileLines := map[uri.URI]*sourceLines{}
+	filtered := []IncidentContext{}
+	warnings := []konveyor.Warning{}
+	for _, incident := range incidents {
+		if incident.LineNumber == nil || !strings.HasPrefix(string(incident.FileURI), uri.FileScheme) {
+			filtered = append(filtered, incident)
+			continue
+		}
+		lines, ok := fileLines[incident.FileURI]
+		if !ok {
+			var err error
+			lines, err = readSourceLines(incident.FileURI.Filename(), wanted[incident.FileURI])
+			if err != nil {
+				log.V(5).Error(err, "unable to read source to filter incident", "file", incident.FileURI)
+				warnings = append(warnings, konveyor.Warning{
+					Message: fmt.Sprintf("unable to read %s to filter incidents by source, its incidents were kept: %v", incident.FileURI, err),
+				})
+			}
+			fileLines[incident.FileURI] = lines
+		}
+		if lines == nil {
+			filtered = append(filtered, incident)
+			continue
+		}
+		start, end := incidentSourceLines(incident)
+		if start < 0 || start >= lines.count {
+			continue
+		}
+		end = min(end, lines.count-1)
+		block := make([]string, 0, end-start+1)
+		for line := start; line <= end; line++ {
+			block = append(block, lines.lines[line])
+		}
+		if pattern.MatchString(strings.Join(block, "\n")) {
+			filtered = append(filtered, incident)
+		}
+	}
+	return filtered, warnings
+}
+
+// incidentSourceLines returns the first and last lines of an incident,
+// starting at 0, incidents spanning multiple lines are matched as a block
+func incidentSourceLines(incident IncidentContext) (int, int) {
+	start := *incident.LineNumber - 1
+	end := start
+	if incident.CodeLocation != nil && incident.CodeLocation.EndPosition.Line > incident.CodeLocation.StartPosition.Line {
+		end += incident.CodeLocation.EndPosition.Line - incident.CodeLocation.StartPosition.Line
+	}
+	return start, end
+}
+
+// sourceLines are the wanted lines of a file and its number of lines
+type sourceLines struct {
+	lines map[int]string
+	count int
+}
+
+// readSourceLines reads the wanted lines of the file, starting at 0, the file
+// is read up to the last one and its lines are counted
+func readSourceLines(file string, wanted map[int]bool) (*sourceLines, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	last := -1
+	for line := range wanted {
+		last = max(last, line)
+	}
+	lines := &sourceLines{lines: map[int]string{}}
+	reader := textfile.NewReader(f)
+	for ; lines.count <= last; lines.count++ {
+		line, err := reader.Line()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if wanted[lines.count] {
+			lines.lines[lines.count] = line
+		}
+	}
+	return lines, nil
+}
+
+// newChainTemplate returns the template the conditions chained to a condition
+// get from its response
+func newChainTemplate(response ConditionResponse) ChainTemplate {
+	variables := []map[string]interface{}{}
+	for _, incident := range response.Incidents {
+		if len(incident.Variables) > 0 {
+			variables = append(variables, incident.Variables)
+		}
+	}
+	return ChainTemplate{
+		Filepaths: incidentsToFilepaths(response.Incidents),
+		Extras:    response.TemplateContext,
+		Variables: variables,
+	}
+}
+
 func incidentsToFilepaths(incident []IncidentContext) []string {
 	filepaths := []string{}
 	for _, ic := range incident {
@@ -314,4 +564,8 @@ type ChainTemplate struct {
 	Filepaths     []string               `yaml:"filepaths,omitempty"`
 	Extras        map[string]interface{} `yaml:"extras,omitempty"`
 	ExcludedPaths []string               `yaml:"excludedPaths,omitempty"`
+	// Variables are the variables of the incidents of the condition, one map
+	// per incident. They are joined with the conditions referring to them
+	// by the engine, providers don't get them.
+	Variables []map[string]interface{} `yaml:"-"`
 }
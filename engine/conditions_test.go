@@ -1,8 +1,15 @@
 package engine
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+
+	"github.com/go-logr/logr"
+	"go.lsp.dev/uri"
 )
 
 func Test_sortConditionEntries(t *testing.T) {
@@ -248,3 +255,83 @@ func Test_sortConditionEntries(t *testing.T) {
 		})
 	}
 }
+
+func Test_filterIncidentsBySource(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "Example.java")
+	err := os.WriteFile(source, []byte(strings.Join([]string{
+		"import javax.ejb.Stateless;",
+		"// import javax.ejb.Remote;",
+		"@Stateless(",
+		"    name = \"orders\")",
+	}, "\n")), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lineNumber := func(i int) *int { return &i }
+	tests := []struct {
+		title     string
+		pattern   string
+		incidents []IncidentContext
+		wantLines []int
+	}{
+		{
+			title:   "drops incidents whose line does not match",
+			pattern: `^import`,
+			incidents: []IncidentContext{
+				{FileURI: uri.File(source), LineNumber: lineNumber(1)},
+				{FileURI: uri.File(source), LineNumber: lineNumber(2)},
+			},
+			wantLines: []int{1},
+		},
+		{
+			title:   "matches incidents spanning multiple lines as a block",
+			pattern: `@Stateless\(\s+name`,
+			incidents: []IncidentContext{
+				{
+					FileURI:    uri.File(source),
+					LineNumber: lineNumber(3),
+					CodeLocation: &Location{
+						StartPosition: Position{Line: 2},
+						EndPosition:   Position{Line: 3},
+					},
+				},
+			},
+			wantLines: []int{3},
+		},
+		{
+			title:   "keeps incidents that cannot be checked",
+			pattern: `^import`,
+			incidents: []IncidentContext{
+				{FileURI: uri.File(source)},
+				{FileURI: uri.File(filepath.Join(dir, "Missing.java")), LineNumber: lineNumber(7)},
+				{FileURI: "konveyor-jdt://contents/Example.class", LineNumber: lineNumber(8)},
+			},
+			wantLines: []int{0, 7, 8},
+		},
+		{
+			title:   "drops incidents past the end of the file",
+			pattern: `.*`,
+			incidents: []IncidentContext{
+				{FileURI: uri.File(source), LineNumber: lineNumber(10)},
+			},
+			wantLines: []int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got, _ := filterIncidentsBySource(logr.Discard(), tt.incidents, regexp.MustCompile(tt.pattern))
+			gotLines := []int{}
+			for _, incident := range got {
+				line := 0
+				if incident.LineNumber != nil {
+					line = *incident.LineNumber
+				}
+				gotLines = append(gotLines, line)
+			}
+			if !reflect.DeepEqual(gotLines, tt.wantLines) {
+				t.Errorf("filterIncidentsBySource() lines = %v, want %v", gotLines, tt.wantLines)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package engine
+
+import "sort"
+
+// Cost is how expensive a rule is to evaluate. The engine evaluates the
+// cheap rules first so that their tags and results are found early instead
+// of waiting behind rules of slow providers such as language servers.
+type Cost string
+
+const (
+	LowCost    Cost = "low"
+	MediumCost Cost = "medium"
+	HighCost   Cost = "high"
+)
+
+// Costs from the cheapest to the most expensive
+var Costs = []Cost{LowCost, MediumCost, HighCost}
+
+var costRank = map[Cost]int{
+	LowCost:    1,
+	MediumCost: 2,
+	HighCost:   3,
+}
+
+// Valid reports whether the cost is one of Costs
+func (c Cost) Valid() bool {
+	_, ok := costRank[c]
+	return ok
+}
+
+// CostConditional is a condition knowing how expensive it is to evaluate,
+// such as the conditions of the builtin provider that only read files. The
+// conditions that are not are expected to be expensive.
+type CostConditional interface {
+	Cost() Cost
+}
+
+// ruleCost returns the cost hint of a rule, or the cost of its most
+// expensive condition when it has none
+func ruleCost(rule Rule) Cost {
+	if rule.Cost != nil && rule.Cost.Valid() {
+		return *rule.Cost
+	}
+	cost := LowCost
+	walkConditions(rule, func(c Conditional) {
+		leaf := HighCost
+		if v, ok := c.(CostConditional); ok && v.Cost().Valid() {
+			leaf = v.Cost()
+		}
+		if costRank[leaf] > costRank[cost] {
+			cost = leaf
+		}
+	})
+	return cost
+}
+
+// sortByCost orders the rules from the cheapest to the most expensive,
+// keeping the order of the rules of the same cost
+func sortByCost(rules []ruleMessage) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return costRank[ruleCost(rules[i].rule)] < costRank[ruleCost(rules[j].rule)]
+	})
+}
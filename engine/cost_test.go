@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testCostConditional struct {
+	testConditional
+	cost Cost
+}
+
+func (t testCostConditional) Cost() Cost {
+	return t.cost
+}
+
+func Test_ruleCost(t *testing.T) {
+	entry := func(cost Cost) ConditionEntry {
+		return ConditionEntry{ProviderSpecificConfig: testCostConditional{cost: cost}}
+	}
+	medium := MediumCost
+	tests := []struct {
+		name string
+		rule Rule
+		want Cost
+	}{
+		{
+			name: "cheap conditions",
+			rule: Rule{When: AndCondition{Conditions: []ConditionEntry{entry(LowCost), entry(LowCost)}}},
+			want: LowCost,
+		},
+		{
+			name: "most expensive condition",
+			rule: Rule{When: OrCondition{Conditions: []ConditionEntry{entry(LowCost), entry(HighCost)}}},
+			want: HighCost,
+		},
+		{
+			name: "expensive unless",
+			rule: Rule{When: entry(LowCost), Unless: entry(HighCost)},
+			want: HighCost,
+		},
+		{
+			name: "condition without cost",
+			rule: Rule{When: AndCondition{Conditions: []ConditionEntry{entry(LowCost), {ProviderSpecificConfig: testConditional{}}}}},
+			want: HighCost,
+		},
+		{
+			name: "cost hint",
+			rule: Rule{RuleMeta: RuleMeta{Cost: &medium}, When: entry(HighCost)},
+			want: MediumCost,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ruleCost(tt.rule); got != tt.want {
+				t.Errorf("expected cost %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_sortByCost(t *testing.T) {
+	message := func(id string, cost Cost) ruleMessage {
+		return ruleMessage{rule: Rule{
+			RuleMeta: RuleMeta{RuleID: id},
+			When:     ConditionEntry{ProviderSpecificConfig: testCostConditional{cost: cost}},
+		}}
+	}
+	rules := []ruleMessage{
+		message("java-1", HighCost), message("builtin-1", LowCost), message("java-2", HighCost),
+		message("yaml-1", MediumCost), message("builtin-2", LowCost),
+	}
+	sortByCost(rules)
+	got := []string{}
+	for _, r := range rules {
+		got = append(got, r.rule.RuleID)
+	}
+	if want := []string{"builtin-1", "builtin-2", "yaml-1", "java-1", "java-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected rules ordered %v, got %v", want, got)
+	}
+}
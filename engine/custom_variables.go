@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.lsp.dev/uri"
+)
+
+// codeSnipLinePattern matches a line of a code snippet, its number and text
+var codeSnipLinePattern = regexp.MustCompile(`^\s*([0-9]+)  (.*)`)
+
+// customVariableLines are the source lines of the incidents of a rule with
+// custom variables, only the lines of the incidents are read from their files
+// and every file is read once.
+type customVariableLines struct {
+	wanted map[uri.URI]map[int]bool
+	files  map[uri.URI]*sourceLines
+}
+
+func newCustomVariableLines(incidents []IncidentContext) *customVariableLines {
+	c := &customVariableLines{
+		wanted: map[uri.URI]map[int]bool{},
+		files:  map[uri.URI]*sourceLines{},
+	}
+	for _, incident := range incidents {
+		if incident.LineNumber == nil || !strings.HasPrefix(string(incident.FileURI), uri.FileScheme) {
+			continue
+		}
+		if c.wanted[incident.FileURI] == nil {
+			c.wanted[incident.FileURI] = map[int]bool{}
+		}
+		c.wanted[incident.FileURI][*incident.LineNumber-1] = true
+	}
+	return c
+}
+
+// line returns the source line of the incident, false when it has no line or
+// its file can't be read
+func (c *customVariableLines) line(incident IncidentContext) (string, bool) {
+	wanted, ok := c.wanted[incident.FileURI]
+	if !ok || incident.LineNumber == nil {
+		return "", false
+	}
+	lines, ok := c.files[incident.FileURI]
+	if !ok {
+		lines, _ = readSourceLines(incident.FileURI.Filename(), wanted)
+		c.files[incident.FileURI] = lines
+	}
+	if lines == nil {
+		return "", false
+	}
+	line, ok := lines.lines[*incident.LineNumber-1]
+	return line, ok
+}
+
+// codeSnipLine returns the text of the line of the code snippet, for the
+// incidents in files that can't be read such as the ones of dependencies
+func codeSnipLine(codeSnip string, lineNumber int) (string, bool) {
+	for _, line := range strings.Split(codeSnip, "\n") {
+		match := codeSnipLinePattern.FindStringSubmatch(line)
+		if match != nil && match[1] == strconv.Itoa(lineNumber) {
+			return match[2], true
+		}
+	}
+	return "", false
+}
+
+// setCustomVariables sets the custom variables of the rule in the variables
+// of an incident. The pattern of every custom variable is matched against the
+// texts in order, the first text matching it gives the value of the variable.
+// Custom variables without a name set a variable for every named capture group
+// of their pattern.
+func setCustomVariables(customVariables []CustomVariable, texts []string, variables map[string]interface{}) {
+	for _, cv := range customVariables {
+		if cv.Pattern == nil {
+			continue
+		}
+		var match []string
+		for _, text := range texts {
+			if match = cv.Pattern.FindStringSubmatch(text); match != nil {
+				break
+			}
+		}
+		if cv.Name == "" {
+			for i, name := range cv.Pattern.SubexpNames() {
+				if name == "" {
+					continue
+				}
+				if match == nil {
+					variables[name] = cv.DefaultValue
+				} else {
+					variables[name] = strings.TrimSpace(match[i])
+				}
+			}
+			continue
+		}
+		switch group := cv.Pattern.SubexpIndex(cv.NameOfCaptureGroup); {
+		case match == nil:
+			variables[cv.Name] = cv.DefaultValue
+		case cv.NameOfCaptureGroup != "" && group >= 0:
+			variables[cv.Name] = strings.TrimSpace(match[group])
+		case len(match) == 1:
+			variables[cv.Name] = strings.TrimSpace(match[0])
+		default:
+			variables[cv.Name] = strings.TrimSpace(match[1])
+		}
+	}
+}
@@ -1,10 +1,10 @@
 package engine
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"net/url"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -20,7 +20,11 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/konveyor/analyzer-lsp/engine/internal"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/feature"
+	"github.com/konveyor/analyzer-lsp/fileuri"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/progress"
+	"github.com/konveyor/analyzer-lsp/textfile"
 	"github.com/konveyor/analyzer-lsp/tracing"
 )
 
@@ -35,6 +39,8 @@ type ruleMessage struct {
 	ruleSetName string
 	ctx         ConditionContext
 	scope       Scope
+	control     *progress.Control
+	cache       *ConditionCache
 	returnChan  chan response
 }
 
@@ -58,8 +64,41 @@ type ruleEngine struct {
 	contextLines     int
 	incidentSelector string
 	locationPrefixes []string
+
+	locationPrefixStrategy LocationPrefixStrategy
+	stripPrefixes          []string
+	locationAliases        map[string]string
+
+	featureFlags feature.Flags
+
+	collapseIncidents bool
+
+	maxWorkers     int
+	providerLimits map[string]int
+
+	conditionCache bool
+
+	// codeSnipMaxFileSize is the most bytes of the files code snippets are
+	// taken from, zero means no limit
+	codeSnipMaxFileSize int64
 }
 
+// LocationPrefixStrategy decides how the file URIs of incidents are
+// rewritten based on the locations that were analyzed.
+type LocationPrefixStrategy string
+
+const (
+	// RelativeToRootStrategy rewrites files under a location that was given
+	// as a relative path to be relative to it, this is the default.
+	RelativeToRootStrategy LocationPrefixStrategy = "relative"
+	// KeepAbsoluteStrategy leaves the file URIs unchanged.
+	KeepAbsoluteStrategy LocationPrefixStrategy = "absolute"
+	// StripPrefixStrategy removes the strip prefixes and the locations from the file URIs.
+	StripPrefixStrategy LocationPrefixStrategy = "strip"
+)
+
+var LocationPrefixStrategies = []LocationPrefixStrategy{RelativeToRootStrategy, KeepAbsoluteStrategy, StripPrefixStrategy}
+
 type Option func(engine *ruleEngine)
 
 func WithIncidentLimit(i int) Option {
@@ -80,6 +119,19 @@ func WithCodeSnipLimit(i int) Option {
 	}
 }
 
+// DefaultCodeSnipMaxFileSize is the code snippet file size limit of the
+// analyzer command
+const DefaultCodeSnipMaxFileSize = 10 << 20
+
+// WithCodeSnipMaxFileSize leaves the code snippets of the incidents of files
+// bigger than size bytes out, the incidents get a codeSnipWarning variable
+// instead. Zero means no limit.
+func WithCodeSnipMaxFileSize(size int64) Option {
+	return func(engine *ruleEngine) {
+		engine.codeSnipMaxFileSize = size
+	}
+}
+
 func WithIncidentSelector(selector string) Option {
 	return func(engine *ruleEngine) {
 		engine.incidentSelector = selector
@@ -92,20 +144,77 @@ func WithLocationPrefixes(location []string) Option {
 	}
 }
 
+func WithLocationPrefixStrategy(strategy LocationPrefixStrategy) Option {
+	return func(engine *ruleEngine) {
+		engine.locationPrefixStrategy = strategy
+	}
+}
+
+// WithStripPrefixes sets additional prefixes to remove when using the StripPrefixStrategy
+func WithStripPrefixes(prefixes []string) Option {
+	return func(engine *ruleEngine) {
+		engine.stripPrefixes = prefixes
+	}
+}
+
+// WithLocationAliases reports the files under the locations, such as the
+// directories archives are extracted to, as under their aliases instead
+func WithLocationAliases(aliases map[string]string) Option {
+	return func(engine *ruleEngine) {
+		engine.locationAliases = aliases
+	}
+}
+
+// WithConditionCaching caches the responses of the conditions during a run,
+// so that the conditions repeated by several rules are evaluated once, it is
+// disabled by default
+func WithConditionCaching(enabled bool) Option {
+	return func(engine *ruleEngine) {
+		engine.conditionCache = enabled
+	}
+}
+
+// WithFeatureFlags enables experimental behaviors of the engine
+func WithFeatureFlags(flags feature.Flags) Option {
+	return func(engine *ruleEngine) {
+		engine.featureFlags = flags
+	}
+}
+
+// WithCollapsedIncidents keeps one of the incidents found on the same line
+// by several rules of the same family, see konveyor.CollapseIncidents
+func WithCollapsedIncidents(collapse bool) Option {
+	return func(engine *ruleEngine) {
+		engine.collapseIncidents = collapse
+	}
+}
+
+// WithMaxWorkers sets the most workers the rules are evaluated with, the
+// workers scale up to it from the number of workers of the engine when rules
+// wait for one
+func WithMaxWorkers(i int) Option {
+	return func(engine *ruleEngine) {
+		engine.maxWorkers = i
+	}
+}
+
+// WithProviderLimits sets the most rules with conditions of a provider that
+// are evaluated at once, by provider name. The other rules are evaluated
+// meanwhile.
+func WithProviderLimits(limits map[string]int) Option {
+	return func(engine *ruleEngine) {
+		engine.providerLimits = limits
+	}
+}
+
 func CreateRuleEngine(ctx context.Context, workers int, log logr.Logger, options ...Option) RuleEngine {
 	// Only allow for 10 rules to be waiting in the buffer at once.
-	// Adding more workers will increase the number of rules running at once.
+	// The scheduler takes them as they come, waiting for the workers itself.
 	ruleProcessor := make(chan ruleMessage, 10)
 
 	ctx, cancelFunc := context.WithCancel(ctx)
 	wg := &sync.WaitGroup{}
 
-	for i := 0; i < workers; i++ {
-		logger := log.WithValues("worker", i)
-		wg.Add(1)
-		go processRuleWorker(ctx, ruleProcessor, logger, wg)
-	}
-
 	r := &ruleEngine{
 		ruleProcessing: ruleProcessor,
 		cancelFunc:     cancelFunc,
@@ -115,6 +224,10 @@ func CreateRuleEngine(ctx context.Context, workers int, log logr.Logger, options
 	for _, o := range options {
 		o(r)
 	}
+
+	s := newScheduler(log, workers, r.maxWorkers, r.providerLimits, processRuleMessage)
+	wg.Add(1)
+	go s.run(ctx, ruleProcessor, wg)
 	return r
 }
 
@@ -124,31 +237,33 @@ func (r *ruleEngine) Stop() {
 	r.wg.Wait()
 }
 
-func processRuleWorker(ctx context.Context, ruleMessages chan ruleMessage, logger logr.Logger, wg *sync.WaitGroup) {
-	for {
-		select {
-		case m := <-ruleMessages:
-			logger.V(5).Info("taking rule", "ruleset", m.ruleSetName, "rule", m.rule.RuleID)
-			newLogger := logger.WithValues("ruleID", m.rule.RuleID)
-			//We createa new rule context for a every rule run, here we need to apply the scope
-			m.ctx.Template = make(map[string]ChainTemplate)
-			if m.scope != nil {
-				m.scope.AddToContext(&m.ctx)
-			}
-
-			bo, err := processRule(ctx, m.rule, m.ctx, newLogger)
-			logger.V(5).Info("finished rule", "found", len(bo.Incidents), "error", err, "rule", m.rule.RuleID)
-			m.returnChan <- response{
-				ConditionResponse: bo,
-				Err:               err,
-				Rule:              m.rule,
-				RuleSetName:       m.ruleSetName,
-			}
-		case <-ctx.Done():
-			logger.V(5).Info("stopping rule worker")
-			wg.Done()
-			return
+// processRuleMessage evaluates a rule and sends its response
+func processRuleMessage(ctx context.Context, logger logr.Logger, m ruleMessage) {
+	newLogger := logger.WithValues("ruleID", m.rule.RuleID)
+	// the rules left wait while the analysis is paused and are skipped once
+	// it is canceled
+	if err := m.control.Wait(ctx); err != nil {
+		m.returnChan <- response{
+			Err:         err,
+			Rule:        m.rule,
+			RuleSetName: m.ruleSetName,
 		}
+		return
+	}
+	ctx = WithConditionCache(ctx, m.cache)
+	//We createa new rule context for a every rule run, here we need to apply the scope
+	m.ctx.Template = make(map[string]ChainTemplate)
+	if m.scope != nil {
+		m.scope.AddToContext(&m.ctx)
+	}
+
+	bo, err := processRule(ctx, m.rule, m.ctx, newLogger)
+	logger.V(5).Info("finished rule", "found", len(bo.Incidents), "error", err, "rule", m.rule.RuleID)
+	m.returnChan <- response{
+		ConditionResponse: bo,
+		Err:               err,
+		Rule:              m.rule,
+		RuleSetName:       m.ruleSetName,
 	}
 }
 
@@ -159,9 +274,12 @@ func (r *ruleEngine) createRuleSet(ruleSet RuleSet) *konveyor.RuleSet {
 		Tags:        []string{},
 		Violations:  map[string]konveyor.Violation{},
 		Insights:    map[string]konveyor.Violation{},
-		Errors:      map[string]string{},
+		Errors:      map[string]konveyor.RuleError{},
+		Warnings:    map[string][]konveyor.Warning{},
+		Suppressed:  map[string][]konveyor.Incident{},
 		Unmatched:   []string{},
 		Skipped:     []string{},
+		Canceled:    []string{},
 	}
 	return rs
 }
@@ -179,6 +297,9 @@ func (r *ruleEngine) RunRulesScoped(ctx context.Context, ruleSets []RuleSet, sco
 		Tags:     make(map[string]interface{}),
 		Template: make(map[string]ChainTemplate),
 	}
+	if len(r.featureFlags) > 0 {
+		r.logger.Info("using feature flags", "flags", r.featureFlags.String())
+	}
 	if scopes != nil {
 		r.logger.Info("using scopes", "scope", scopes.Name())
 		err := scopes.AddToContext(&conditionContext)
@@ -191,8 +312,17 @@ func (r *ruleEngine) RunRulesScoped(ctx context.Context, ruleSets []RuleSet, sco
 		r.logger.Info("added scopes to condition context", "scopes", scopes, "conditionContext", conditionContext)
 	}
 	ctx, cancelFunc := context.WithCancel(ctx)
+	var cache *ConditionCache
+	if r.conditionCache {
+		cache = NewConditionCache()
+		ctx = WithConditionCache(ctx, cache)
+	}
+	ctx = withSuppressions(ctx, newSuppressions())
 
 	taggingRules, otherRules, mapRuleSets := r.filterRules(ruleSets, selectors...)
+	task := progress.FromContext(ctx)
+	control := progress.ControlFromContext(ctx)
+	task.SetTotal(len(taggingRules) + len(otherRules))
 
 	ruleContext := r.runTaggingRules(ctx, taggingRules, mapRuleSets, conditionContext, scopes)
 
@@ -212,19 +342,33 @@ func (r *ruleEngine) RunRulesScoped(ctx context.Context, ruleSets []RuleSet, sco
 				func() {
 					r.logger.Info("rule returned", "ruleID", response.Rule.RuleID)
 					defer wg.Done()
+					if errors.Is(response.Err, progress.ErrCanceled) {
+						r.cancelRules(mapRuleSets, []ruleMessage{{rule: response.Rule, ruleSetName: response.RuleSetName}})
+						return
+					}
+					defer task.Add(1, response.Rule.RuleID)
+					if rs, ok := mapRuleSets[response.RuleSetName]; ok {
+						r.addWarnings(rs, response.Rule.RuleID, response.ConditionResponse)
+					}
 					if response.Err != nil {
 						atomic.AddInt32(&failedRules, 1)
 						r.logger.Error(response.Err, "failed to evaluate rule", "ruleID", response.Rule.RuleID)
 
 						if rs, ok := mapRuleSets[response.RuleSetName]; ok {
-							rs.Errors[response.Rule.RuleID] = response.Err.Error()
+							rs.Errors[response.Rule.RuleID] = ruleError(response.Err)
 						}
 					} else if response.ConditionResponse.Matched && len(response.ConditionResponse.Incidents) > 0 {
-						violation, err := r.createViolation(ctx, response.ConditionResponse, response.Rule, scopes)
+						violation, suppressed, err := r.createViolation(ctx, response.ConditionResponse, response.Rule, scopes)
 						if err != nil {
 							r.logger.Error(err, "unable to create violation from response", "ruleID", response.Rule.RuleID)
 						}
-						if len(violation.Incidents) == 0 {
+						if rs, ok := mapRuleSets[response.RuleSetName]; ok && len(suppressed) > 0 {
+							rs.Suppressed[response.Rule.RuleID] = suppressed
+						}
+						if len(violation.Incidents) == 0 && len(suppressed) > 0 {
+							r.logger.V(5).Info("rule was evaluated and all its incidents were suppressed", "ruleID", response.Rule.RuleID)
+							atomic.AddInt32(&matchedRules, 1)
+						} else if len(violation.Incidents) == 0 {
 							r.logger.V(5).Info("rule was evaluated and incidents were filtered out to make it unmatched", "ruleID", response.Rule.RuleID)
 							atomic.AddInt32(&unmatchedRules, 1)
 							if rs, ok := mapRuleSets[response.RuleSetName]; ok {
@@ -263,11 +407,17 @@ func (r *ruleEngine) RunRulesScoped(ctx context.Context, ruleSets []RuleSet, sco
 		}
 	}()
 
-	for _, rule := range otherRules {
+	for i, rule := range otherRules {
+		if control.State() == progress.StateCanceled {
+			r.cancelRules(mapRuleSets, otherRules[i:])
+			break
+		}
 		wg.Add(1)
 		rule.returnChan = ret
 		rule.ctx = ruleContext
 		rule.scope = scopes
+		rule.control = control
+		rule.cache = cache
 		r.ruleProcessing <- rule
 	}
 	r.logger.V(5).Info("All rules added buffer, waiting for engine to complete", "size", len(otherRules))
@@ -281,7 +431,12 @@ func (r *ruleEngine) RunRulesScoped(ctx context.Context, ruleSets []RuleSet, sco
 	// Wait for all the rules to process
 	select {
 	case <-done:
+		if control.State() == progress.StateCanceled {
+			r.logger.Info("analysis was canceled, the rules evaluated meanwhile were drained", "reason", control.Reason())
+			break
+		}
 		r.logger.V(2).Info("done processing all the rules")
+		task.Done()
 	case <-ctx.Done():
 		r.logger.V(1).Info("processing of rules was canceled")
 	}
@@ -293,9 +448,46 @@ func (r *ruleEngine) RunRulesScoped(ctx context.Context, ruleSets []RuleSet, sco
 	}
 	// Cannel running go-routine
 	cancelFunc()
+	if cache != nil {
+		hits, misses := cache.Stats()
+		r.logger.V(2).Info("evaluated the conditions repeated by several rules once", "evaluated", misses, "cached", hits)
+	}
+	if r.collapseIncidents {
+		removed := konveyor.CollapseIncidents(responses)
+		r.logger.V(2).Info("collapsed the incidents found by several rules", "removed", removed)
+	}
 	return responses
 }
 
+// cancelRules records the rules that were not evaluated because the analysis
+// was canceled in their rulesets
+func (r *ruleEngine) cancelRules(mapRuleSets map[string]*konveyor.RuleSet, rules []ruleMessage) {
+	for _, m := range rules {
+		r.logger.V(5).Info("rule was not evaluated, the analysis was canceled", "ruleID", m.rule.RuleID)
+		if rs, ok := mapRuleSets[m.ruleSetName]; ok {
+			rs.Canceled = append(rs.Canceled, m.rule.RuleID)
+		}
+	}
+}
+
+// addWarnings records the warnings of a rule in its ruleset along with the
+// warnings about the results of the rule being truncated.
+func (r *ruleEngine) addWarnings(rs *konveyor.RuleSet, ruleID string, response ConditionResponse) {
+	warnings := response.Warnings
+	if r.incidentLimit != 0 && len(response.Incidents) > r.incidentLimit {
+		warnings = append(warnings, konveyor.Warning{
+			Message: fmt.Sprintf("found %d incidents, only the first %d are reported because of the incident limit", len(response.Incidents), r.incidentLimit),
+		})
+	}
+	if len(warnings) == 0 {
+		return
+	}
+	for _, w := range warnings {
+		r.logger.V(3).Info("rule returned warning", "ruleID", ruleID, "provider", w.Provider, "warning", w.Message)
+	}
+	rs.Warnings[ruleID] = append(rs.Warnings[ruleID], warnings...)
+}
+
 // filterRules splits rules into tagging and other rules
 func (r *ruleEngine) filterRules(ruleSets []RuleSet, selectors ...RuleSelector) ([]ruleMessage, []ruleMessage, map[string]*konveyor.RuleSet) {
 	// filter rules that generate tags, they run first
@@ -306,8 +498,9 @@ func (r *ruleEngine) filterRules(ruleSets []RuleSet, selectors ...RuleSelector)
 	for _, ruleSet := range ruleSets {
 		mapRuleSets[ruleSet.Name] = r.createRuleSet(ruleSet)
 		for _, rule := range ruleSet.Rules {
-			// labels on ruleset apply to all rules in it
-			rule.Labels = append(rule.Labels, ruleSet.Labels...)
+			// labels on ruleset apply to all rules in it, its default labels
+			// to the rules without a label of the same key
+			rule.Labels = append(labels.MergeLabels(rule.Labels, ruleSet.DefaultLabels), ruleSet.Labels...)
 			// skip rule when doesn't match any selector
 			if !matchesAllSelectors(rule.RuleMeta, selectors...) {
 				mapRuleSets[ruleSet.Name].Skipped = append(mapRuleSets[ruleSet.Name].Skipped, rule.RuleID)
@@ -340,6 +533,9 @@ func (r *ruleEngine) filterRules(ruleSets []RuleSet, selectors ...RuleSelector)
 			}
 		}
 	}
+	// the cheap rules run first, their tags and results are found early
+	sortByCost(taggingRules)
+	sortByCost(otherRules)
 	return taggingRules, otherRules, mapRuleSets
 }
 
@@ -348,13 +544,22 @@ func (r *ruleEngine) filterRules(ruleSets []RuleSet, selectors ...RuleSelector)
 func (r *ruleEngine) runTaggingRules(ctx context.Context, infoRules []ruleMessage, mapRuleSets map[string]*konveyor.RuleSet, context ConditionContext, scope Scope) ConditionContext {
 	// track unique tags per ruleset
 	rulesetTagsCache := map[string]map[string]bool{}
-	for _, ruleMessage := range infoRules {
+	control := progress.ControlFromContext(ctx)
+	for i, ruleMessage := range infoRules {
+		if err := control.Wait(ctx); errors.Is(err, progress.ErrCanceled) {
+			r.cancelRules(mapRuleSets, infoRules[i:])
+			break
+		}
 		rule := ruleMessage.rule
 		response, err := processRule(ctx, rule, context, r.logger)
+		progress.FromContext(ctx).Add(1, rule.RuleID)
+		if rs, ok := mapRuleSets[ruleMessage.ruleSetName]; ok {
+			r.addWarnings(rs, rule.RuleID, response)
+		}
 		if err != nil {
 			r.logger.Error(err, "failed to evaluate rule", "ruleID", rule.RuleID)
 			if rs, ok := mapRuleSets[ruleMessage.ruleSetName]; ok {
-				rs.Errors[rule.RuleID] = err.Error()
+				rs.Errors[rule.RuleID] = ruleError(err)
 			}
 		} else if response.Matched && len(response.Incidents) > 0 {
 			r.logger.V(5).Info("info rule was matched", "ruleID", rule.RuleID)
@@ -407,11 +612,14 @@ func (r *ruleEngine) runTaggingRules(ctx context.Context, infoRules []ruleMessag
 				mapRuleSets[ruleMessage.ruleSetName] = rs
 			}
 			// create an insight for this tag
-			violation, err := r.createViolation(ctx, response, rule, scope)
+			violation, suppressed, err := r.createViolation(ctx, response, rule, scope)
 			if err != nil {
 				r.logger.Error(err, "unable to create violation from response", "ruleID", rule.RuleID)
 			}
 			if rs, ok := mapRuleSets[ruleMessage.ruleSetName]; ok {
+				if len(suppressed) > 0 {
+					rs.Suppressed[rule.RuleID] = suppressed
+				}
 				violation.Effort = nil
 				violation.Category = nil
 				// we need to tie these incidents back to tags that created them
@@ -454,49 +662,190 @@ func processRule(ctx context.Context, rule Rule, ruleCtx ConditionContext, log l
 	newContext.RuleID = rule.RuleID
 	// Here is what a worker should run when getting a rule.
 	// For now, lets not fan out the running of conditions.
-	return rule.When.Evaluate(ctx, log, newContext)
+	response, err := rule.When.Evaluate(ctx, log, newContext)
+	if err != nil || !response.Matched || rule.Unless == nil {
+		return response, err
+	}
+	// the unless condition can use the templates of the when condition,
+	// including the one of a single condition named with as
+	if entry, ok := rule.When.(ConditionEntry); ok && entry.As != "" {
+		if newContext.Template == nil {
+			newContext.Template = map[string]ChainTemplate{}
+		}
+		newContext.Template[entry.As] = newChainTemplate(response)
+	}
+	return evaluateUnless(ctx, log, rule.Unless, newContext, response)
+}
 
+// evaluateUnless removes the incidents of the response in the files the
+// unless condition matched in. When the unless condition, or the response,
+// has no incidents to compare the files of, the response no longer matches.
+func evaluateUnless(ctx context.Context, log logr.Logger, unless Conditional, condCtx ConditionContext, response ConditionResponse) (ConditionResponse, error) {
+	unlessResponse, err := unless.Evaluate(ctx, log, condCtx)
+	if err != nil {
+		return ConditionResponse{}, fmt.Errorf("unable to evaluate unless condition: %w", err)
+	}
+	response.Warnings = append(response.Warnings, unlessResponse.Warnings...)
+	if !unlessResponse.Matched {
+		return response, nil
+	}
+	if len(unlessResponse.Incidents) == 0 || len(response.Incidents) == 0 {
+		log.V(5).Info("rule suppressed by unless condition")
+		response.Matched = false
+		response.Incidents = []IncidentContext{}
+		return response, nil
+	}
+	suppressed := map[uri.URI]bool{}
+	for _, incident := range unlessResponse.Incidents {
+		suppressed[incident.FileURI] = true
+	}
+	incidents := []IncidentContext{}
+	for _, incident := range response.Incidents {
+		if !suppressed[incident.FileURI] {
+			incidents = append(incidents, incident)
+		}
+	}
+	log.V(5).Info("incidents suppressed by unless condition", "suppressed", len(response.Incidents)-len(incidents))
+	response.Incidents = incidents
+	response.Matched = len(incidents) > 0
+	return response, nil
 }
 
 func (r *ruleEngine) getRelativePathForViolation(fileURI uri.URI) (uri.URI, error) {
-	var sourceLocation string
-	if fileURI != "" {
-		u, err := url.ParseRequestURI(string(fileURI))
-		if err != nil || u.Scheme != uri.FileScheme {
+	if fileURI == "" {
+		return fileURI, nil
+	}
+	// the path is decoded, so escaped characters such as spaces
+	// are compared with the prefixes as they are on disk
+	file := fileuri.Path(string(fileURI))
+	if file == "" {
+		return fileURI, nil
+	}
+	locationPrefixes := r.locationPrefixes
+	if len(r.locationAliases) > 0 {
+		file, fileURI, locationPrefixes = r.aliasLocation(file, fileURI)
+	}
+	if r.locationPrefixStrategy == KeepAbsoluteStrategy {
+		return fileURI, nil
+	}
+
+	if r.locationPrefixStrategy == StripPrefixStrategy {
+		prefixes := append(append([]string{}, r.stripPrefixes...), locationPrefixes...)
+		_, rest, ok := matchLocationPrefix(file, prefixes)
+		if !ok {
 			return fileURI, nil
 		}
-		file := fileURI.Filename()
-		// get the correct source
-		for _, locationPrefix := range r.locationPrefixes {
-			if strings.Contains(file, locationPrefix) {
-				sourceLocation = locationPrefix
-				break
-			}
+		return uri.File("/" + rest), nil
+	}
+
+	prefix, rest, ok := matchLocationPrefix(file, locationPrefixes)
+	// locations given as absolute paths are kept absolute
+	if !ok || filepath.IsAbs(prefix) {
+		return fileURI, nil
+	}
+	relPrefix := strings.TrimPrefix(filepath.Clean(prefix), "/")
+	if relPrefix == "." {
+		relPrefix = ""
+	}
+	return uri.File("/" + filepath.Join(relPrefix, rest)), nil
+}
+
+// aliasLocation moves the file under the alias of the location it is in and
+// returns it along with the location prefixes where the aliased locations
+// are replaced by their aliases
+func (r *ruleEngine) aliasLocation(file string, fileURI uri.URI) (string, uri.URI, []string) {
+	locations := make([]string, 0, len(r.locationAliases))
+	for location := range r.locationAliases {
+		locations = append(locations, location)
+	}
+	prefixes := make([]string, 0, len(r.locationPrefixes))
+	for _, prefix := range r.locationPrefixes {
+		if alias, ok := r.locationAliases[prefix]; ok {
+			prefix = alias
 		}
-		absPath, err := filepath.Abs(sourceLocation)
+		prefixes = append(prefixes, prefix)
+	}
+	location, rest, ok := matchLocationPrefix(file, locations)
+	if !ok {
+		return file, fileURI, prefixes
+	}
+	alias, err := filepath.Abs(r.locationAliases[location])
+	if err != nil {
+		return file, fileURI, prefixes
+	}
+	file = filepath.Join(alias, rest)
+	return file, uri.File(file), prefixes
+}
+
+// matchLocationPrefix finds the longest prefix the file is in and returns it along with the
+// path of the file relative to it. Prefixes only match whole path elements, relative prefixes
+// that are not under the working directory, such as in a container with a different root,
+// match where their path elements appear in the file path.
+func matchLocationPrefix(file string, prefixes []string) (string, string, bool) {
+	matched := ""
+	matchedLen := -1
+	rest := ""
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			continue
+		}
+		absPrefix, err := filepath.Abs(prefix)
 		if err != nil {
-			return fileURI, nil
+			continue
+		}
+		if r, ok := trimPathPrefix(file, absPrefix); ok {
+			if len(absPrefix) > matchedLen {
+				matched, matchedLen, rest = prefix, len(absPrefix), r
+			}
+			continue
 		}
-		// given a relative path for source
-		if absPath != sourceLocation {
-			relPath := filepath.Join(sourceLocation, strings.TrimPrefix(file, absPath))
-			newURI := fmt.Sprintf("file:///%s", filepath.Join(strings.TrimPrefix(relPath, "/")))
-			return uri.URI(newURI), nil
+		if filepath.IsAbs(prefix) {
+			continue
 		}
+		cleanPrefix := strings.TrimPrefix(filepath.Clean(prefix), "/")
+		if cleanPrefix == "." || cleanPrefix == ".." || strings.HasPrefix(cleanPrefix, "../") {
+			continue
+		}
+		if i := strings.LastIndex(file+"/", "/"+cleanPrefix+"/"); i != -1 && len(cleanPrefix) > matchedLen {
+			matched, matchedLen = prefix, len(cleanPrefix)
+			rest = strings.TrimPrefix(file[min(len(file), i+len(cleanPrefix)+1):], "/")
+		}
+	}
+	return matched, rest, matchedLen != -1
+}
+
+// trimPathPrefix returns the path of file relative to prefix when file is in prefix
+func trimPathPrefix(file, prefix string) (string, bool) {
+	if file == prefix {
+		return "", true
 	}
-	return fileURI, nil
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if strings.HasPrefix(file, prefix) {
+		return strings.TrimPrefix(file, prefix), true
+	}
+	return "", false
 }
 
-func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse ConditionResponse, rule Rule, scope Scope) (konveyor.Violation, error) {
+// createViolation returns the violation of the incidents of the rule along
+// with the incidents suppressed by konveyor:ignore comments
+func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse ConditionResponse, rule Rule, scope Scope) (konveyor.Violation, []konveyor.Incident, error) {
 	incidents := []konveyor.Incident{}
+	suppressed := []konveyor.Incident{}
+	fileSuppressions := suppressionsFromContext(ctx)
 	fileCodeSnipCount := map[string]int{}
+	var customVariableSources *customVariableLines
+	if len(rule.CustomVariables) > 0 {
+		customVariableSources = newCustomVariableLines(conditionResponse.Incidents)
+	}
 	incidentsSet := map[string]struct{}{} // Set of incidents
 	var incidentSelector *labels.LabelSelector[internal.VariableLabelSelector]
 	var err error
 	if r.incidentSelector != "" {
 		incidentSelector, err = labels.NewLabelSelector[internal.VariableLabelSelector](r.incidentSelector, internal.MatchVariables)
 		if err != nil {
-			return konveyor.Violation{}, err
+			return konveyor.Violation{}, nil, err
 		}
 	}
 	for _, m := range conditionResponse.Incidents {
@@ -511,7 +860,7 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 		}
 		trimmedUri, err := r.getRelativePathForViolation(m.FileURI)
 		if err != nil {
-			return konveyor.Violation{}, err
+			return konveyor.Violation{}, nil, err
 		}
 
 		for val := range m.Variables {
@@ -535,7 +884,15 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 		limitSnip := (r.codeSnipLimit != 0 && fileCodeSnipCount[string(m.FileURI)] == r.codeSnipLimit)
 		if !limitSnip {
 			codeSnip, err := r.getCodeLocation(ctx, m, rule)
-			if err != nil || codeSnip == "" {
+			tooBig := &codeSnipFileTooBigError{}
+			if errors.As(err, &tooBig) {
+				r.logger.V(5).Info("leaving out the code snippet of a big file", "file", m.FileURI, "size", tooBig.size)
+				if m.Variables == nil {
+					m.Variables = map[string]interface{}{}
+					incident.Variables = m.Variables
+				}
+				m.Variables[CodeSnipWarningVariable] = tooBig.Error()
+			} else if err != nil || codeSnip == "" {
 				r.logger.V(6).Error(err, "unable to get code location")
 			} else {
 				incident.CodeSnip = codeSnip
@@ -544,41 +901,32 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 		}
 
 		if len(rule.CustomVariables) > 0 {
-			var originalCodeSnip string
-			re := regexp.MustCompile(`^(\s*[0-9]+  )?(.*)`)
-			scanner := bufio.NewScanner(strings.NewReader(incident.CodeSnip))
-			for scanner.Scan() {
-				if incident.LineNumber != nil && strings.HasPrefix(strings.TrimSpace(scanner.Text()), fmt.Sprintf("%v", *incident.LineNumber)) {
-					originalCodeSnip = strings.TrimSpace(re.ReplaceAllString(scanner.Text(), "$2"))
-					r.logger.V(5).Info("found originalCodeSnip", "lineNuber", incident.LineNumber, "original", originalCodeSnip)
-					break
-				}
+			// the text matched by the provider comes first, then the source
+			// line, read from the file as the code snippet may be left out
+			texts := []string{}
+			if m.MatchedText != "" {
+				texts = append(texts, m.MatchedText)
 			}
-
-			for _, cv := range rule.CustomVariables {
-				match := cv.Pattern.FindStringSubmatch(originalCodeSnip)
-				if cv.NameOfCaptureGroup != "" && cv.Pattern.SubexpIndex(cv.NameOfCaptureGroup) >= 0 &&
-					cv.Pattern.SubexpIndex(cv.NameOfCaptureGroup) < len(match) {
-
-					m.Variables[cv.Name] = strings.TrimSpace(match[cv.Pattern.SubexpIndex(cv.NameOfCaptureGroup)])
-					continue
-
-				} else {
-					switch len(match) {
-					case 0:
-						m.Variables[cv.Name] = cv.DefaultValue
-						continue
-					case 1:
-						m.Variables[cv.Name] = strings.TrimSpace(match[0])
-						continue
-					case 2:
-						m.Variables[cv.Name] = strings.TrimSpace(match[1])
-					}
-				}
+			line, ok := customVariableSources.line(m)
+			if !ok && m.LineNumber != nil {
+				line, ok = codeSnipLine(incident.CodeSnip, *m.LineNumber)
+			}
+			if ok {
+				texts = append(texts, strings.TrimSpace(line))
 			}
+			if m.Variables == nil {
+				m.Variables = map[string]interface{}{}
+				incident.Variables = m.Variables
+			}
+			setCustomVariables(rule.CustomVariables, texts, m.Variables)
 		}
 
-		if rule.Perform.Message.Text != nil {
+		incident.Branch = m.Branch
+		messageText := rule.Perform.Message.Text
+		if m.Message != nil {
+			messageText = m.Message
+		}
+		if messageText != nil {
 			variables := make(map[string]interface{})
 			for key, value := range m.Variables {
 				variables[key] = value
@@ -586,12 +934,21 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 			if m.LineNumber != nil {
 				variables["lineNumber"] = *m.LineNumber
 			}
-			templateString, err := r.createPerformString(*rule.Perform.Message.Text, variables)
+			if m.Branch != "" {
+				variables["branch"] = m.Branch
+			}
+			templateString, err := r.createPerformString(*messageText, variables)
 			if err != nil {
 				r.logger.Error(err, "unable to create template string")
 			}
 			incident.Message = templateString
 		}
+		if rule.Perform.Fix != nil {
+			incident.Fix, err = r.createFix(*rule.Perform.Fix, m, trimmedUri)
+			if err != nil {
+				r.logger.Error(err, "unable to create fix", "ruleID", rule.RuleID)
+			}
+		}
 
 		incidentLineNumber := -1
 		if incident.LineNumber != nil {
@@ -611,27 +968,43 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 			}
 		}
 
-		incidentString := fmt.Sprintf("%s-%s-%d", incident.URI, incident.Message, incidentLineNumber) // Formating a unique string for an incident
+		// Formating a unique string for an incident, providers can report the same file with different URIs
+		incidentString := fmt.Sprintf("%s-%s-%d", fileuri.Key(m.FileURI), incident.Message, incidentLineNumber)
+		if _, isDuplicate := incidentsSet[incidentString]; isDuplicate {
+			continue
+		}
+		incidentsSet[incidentString] = struct{}{}
 
-		// Adding it to list  and set if no duplicates found
-		if _, isDuplicate := incidentsSet[incidentString]; !isDuplicate {
-			incidents = append(incidents, incident)
-			incidentsSet[incidentString] = struct{}{}
+		if fileSuppressions.suppressed(m.FileURI, m.LineNumber, rule.RuleID) {
+			r.logger.V(5).Info("incident suppressed by a konveyor:ignore comment", "ruleID", rule.RuleID, "file", m.FileURI, "line", incidentLineNumber)
+			suppressed = append(suppressed, incident)
+			continue
 		}
+		incidents = append(incidents, incident)
 
 	}
 
 	rule.Labels = deduplicateLabels(rule.Labels)
+	family := konveyor.RuleFamily(rule.RuleID, rule.Labels)
+	for i := range incidents {
+		incidents[i].Fingerprint = konveyor.Fingerprint(family, incidents[i])
+	}
+	for i := range suppressed {
+		suppressed[i].Fingerprint = konveyor.Fingerprint(family, suppressed[i])
+	}
 
 	return konveyor.Violation{
 		Description: rule.Description,
 		Labels:      rule.Labels,
 		Category:    rule.Category,
+		Severity:    rule.Severity,
 		Incidents:   incidents,
 		Extras:      []byte{},
 		Effort:      rule.Effort,
+		Task:        rule.Task,
+		Source:      rule.Source,
 		Links:       rule.Perform.Message.Links,
-	}, nil
+	}, suppressed, nil
 }
 
 func (r *ruleEngine) getCodeLocation(_ context.Context, m IncidentContext, rule Rule) (codeSnip string, err error) {
@@ -640,6 +1013,14 @@ func (r *ruleEngine) getCodeLocation(_ context.Context, m IncidentContext, rule
 		return "", nil
 	}
 
+	// the snippets of big files are left out whether the engine or the
+	// provider reads them
+	if r.codeSnipMaxFileSize > 0 && strings.HasPrefix(string(m.FileURI), uri.FileScheme) {
+		if info, err := os.Stat(m.FileURI.Filename()); err == nil && info.Size() > r.codeSnipMaxFileSize {
+			return "", &codeSnipFileTooBigError{size: info.Size(), limit: r.codeSnipMaxFileSize}
+		}
+	}
+
 	// We need to move this up, because the code only lives in the
 	// provider's
 	if rule.Snipper != nil {
@@ -655,27 +1036,109 @@ func (r *ruleEngine) getCodeLocation(_ context.Context, m IncidentContext, rule
 		}
 		defer readFile.Close()
 
-		scanner := bufio.NewScanner(readFile)
+		// only the lines of the snippet are kept, the file is read up to them
+		reader := textfile.NewReader(readFile)
 		lineNumber := 0
-		codeSnip := ""
+		codeSnip := strings.Builder{}
 		paddingSize := len(strconv.Itoa(m.CodeLocation.EndPosition.Line + r.contextLines))
-		for scanner.Scan() {
+		for {
+			line, err := reader.Line()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
 			if (lineNumber - r.contextLines) == m.CodeLocation.EndPosition.Line {
-				codeSnip = codeSnip + fmt.Sprintf("%*d  %v", paddingSize, lineNumber+1, scanner.Text())
+				codeSnip.WriteString(fmt.Sprintf("%*d  %v", paddingSize, lineNumber+1, line))
 				break
 			}
 			if (lineNumber + r.contextLines) >= m.CodeLocation.StartPosition.Line {
-				codeSnip = codeSnip + fmt.Sprintf("%*d  %v\n", paddingSize, lineNumber+1, scanner.Text())
+				codeSnip.WriteString(fmt.Sprintf("%*d  %v\n", paddingSize, lineNumber+1, line))
 			}
 			lineNumber += 1
 		}
-		return codeSnip, nil
+		return codeSnip.String(), nil
 	}
 
 	// if it is not a file ask the provider
 	return "", nil
 }
 
+// CodeSnipWarningVariable is the variable of the incidents without a code
+// snippet because their file is bigger than the code snippet file size limit
+const CodeSnipWarningVariable = "codeSnipWarning"
+
+type codeSnipFileTooBigError struct {
+	size  int64
+	limit int64
+}
+
+func (e *codeSnipFileTooBigError) Error() string {
+	return fmt.Sprintf("no code snippet, the file of %d bytes is bigger than the limit of %d bytes", e.size, e.limit)
+}
+
+// createFix returns the fix of an incident, the templates of the fix of its
+// rule are rendered with the variables of the incident. The fixes of the files
+// that are not on disk, such as the sources of dependencies, can't be applied
+// and have no path. The other file a fix edits must be relative to the file of
+// the incident and stay in the analyzed locations.
+func (r *ruleEngine) createFix(fix Fix, m IncidentContext, file uri.URI) (*konveyor.Fix, error) {
+	variables := map[string]interface{}{}
+	quoted := map[string]interface{}{}
+	for key, value := range m.Variables {
+		variables[key] = value
+		quoted[key] = regexp.QuoteMeta(fmt.Sprint(value))
+	}
+	if m.LineNumber != nil {
+		variables["lineNumber"] = *m.LineNumber
+		quoted["lineNumber"] = *m.LineNumber
+	}
+	render := func(template string, variables map[string]interface{}) (string, error) {
+		if template == "" {
+			return "", nil
+		}
+		return mustache.RenderRaw(template, true, variables)
+	}
+	incidentFix := &konveyor.Fix{File: file, CodeAction: fix.CodeAction}
+	var err error
+	if incidentFix.Description, err = render(fix.Description, variables); err != nil {
+		return nil, err
+	}
+	if incidentFix.Search, err = render(fix.Search, quoted); err != nil {
+		return nil, err
+	}
+	if incidentFix.Replace, err = render(fix.Replace, variables); err != nil {
+		return nil, err
+	}
+	path := fileuri.Path(string(m.FileURI))
+	if fix.File == "" {
+		incidentFix.Path = path
+		if m.LineNumber != nil && fix.Search != "" {
+			line := *m.LineNumber
+			incidentFix.Line = &line
+		}
+		return incidentFix, nil
+	}
+	target, err := render(fix.File, variables)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.IsAbs(target) {
+		return nil, fmt.Errorf("the file %s of the fix must be relative to the file of the incident", target)
+	}
+	if path != "" {
+		incidentFix.Path = filepath.Join(filepath.Dir(path), target)
+		if !konveyor.InLocations(incidentFix.Path, r.locationPrefixes) {
+			return nil, fmt.Errorf("the file %s of the fix is outside of the analyzed locations", incidentFix.Path)
+		}
+	}
+	if filePath := fileuri.Path(string(file)); filePath != "" {
+		incidentFix.File = uri.File(filepath.Join(filepath.Dir(filePath), target))
+	}
+	return incidentFix, nil
+}
+
 func (r *ruleEngine) createPerformString(messageTemplate string, ctx map[string]interface{}) (string, error) {
 	return mustache.Render(messageTemplate, ctx)
 }
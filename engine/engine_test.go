@@ -2,14 +2,25 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bombsimon/logrusr/v3"
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/progress"
+	"github.com/konveyor/analyzer-lsp/textfile"
 	"github.com/sirupsen/logrus"
+	"go.lsp.dev/uri"
 )
 
 type testConditional struct {
@@ -744,3 +755,731 @@ func Test_parseTagsFromPerformString(t *testing.T) {
 		})
 	}
 }
+
+func Test_getRelativePathForViolation(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		strategy      LocationPrefixStrategy
+		prefixes      []string
+		stripPrefixes []string
+		aliases       map[string]string
+		fileURI       uri.URI
+		want          uri.URI
+	}{
+		{
+			name:     "relative location",
+			prefixes: []string{"examples/java"},
+			fileURI:  uri.File(filepath.Join(cwd, "examples/java/src/App.java")),
+			want:     "file:///examples/java/src/App.java",
+		},
+		{
+			name:     "relative location with dot prefix",
+			prefixes: []string{"./examples/java/"},
+			fileURI:  uri.File(filepath.Join(cwd, "examples/java/src/App.java")),
+			want:     "file:///examples/java/src/App.java",
+		},
+		{
+			name:     "absolute location is kept",
+			prefixes: []string{"/opt/input/source"},
+			fileURI:  "file:///opt/input/source/src/App.java",
+			want:     "file:///opt/input/source/src/App.java",
+		},
+		{
+			name:     "url encoded path with spaces",
+			prefixes: []string{"my app"},
+			fileURI:  uri.URI("file://" + filepath.ToSlash(filepath.Join(cwd, "my%20app/src/App.java"))),
+			want:     "file:///my%20app/src/App.java",
+		},
+		{
+			name:     "prefix only matches whole path elements",
+			prefixes: []string{"source"},
+			fileURI:  "file:///opt/input/source-deps/App.java",
+			want:     "file:///opt/input/source-deps/App.java",
+		},
+		{
+			name:     "relative location in a container with a different root",
+			prefixes: []string{"examples/java"},
+			fileURI:  "file:///analyzer/examples/java/src/App.java",
+			want:     "file:///examples/java/src/App.java",
+		},
+		{
+			name:     "longest prefix wins",
+			prefixes: []string{"examples", "examples/java"},
+			fileURI:  "file:///analyzer/examples/java/src/App.java",
+			want:     "file:///examples/java/src/App.java",
+		},
+		{
+			name:     "file outside of the locations",
+			prefixes: []string{"examples/java"},
+			fileURI:  "file:///usr/lib/App.java",
+			want:     "file:///usr/lib/App.java",
+		},
+		{
+			name:     "non file scheme",
+			prefixes: []string{"examples/java"},
+			fileURI:  "konveyor-jdt://contents/examples/java/App.class",
+			want:     "konveyor-jdt://contents/examples/java/App.class",
+		},
+		{
+			name:     "keep absolute",
+			strategy: KeepAbsoluteStrategy,
+			prefixes: []string{"examples/java"},
+			fileURI:  uri.File(filepath.Join(cwd, "examples/java/src/App.java")),
+			want:     uri.File(filepath.Join(cwd, "examples/java/src/App.java")),
+		},
+		{
+			name:          "strip prefixes",
+			strategy:      StripPrefixStrategy,
+			prefixes:      []string{"/opt/input/source"},
+			stripPrefixes: []string{"/opt/input"},
+			fileURI:       "file:///opt/input/source/src/App.java",
+			want:          "file:///src/App.java",
+		},
+		{
+			name:          "strip prefixes outside of locations",
+			strategy:      StripPrefixStrategy,
+			prefixes:      []string{"/opt/input/source"},
+			stripPrefixes: []string{"/opt/input"},
+			fileURI:       "file:///opt/input/deps/lib/App.java",
+			want:          "file:///deps/lib/App.java",
+		},
+		{
+			name:     "relative archive",
+			prefixes: []string{"/tmp/konveyor-archives-1/1-app"},
+			aliases:  map[string]string{"/tmp/konveyor-archives-1/1-app": "examples/app.zip"},
+			fileURI:  "file:///tmp/konveyor-archives-1/1-app/src/App.java",
+			want:     "file:///examples/app.zip/src/App.java",
+		},
+		{
+			name:     "absolute archive",
+			strategy: KeepAbsoluteStrategy,
+			prefixes: []string{"/tmp/konveyor-archives-1/1-app"},
+			aliases:  map[string]string{"/tmp/konveyor-archives-1/1-app": "/opt/input/app.zip"},
+			fileURI:  "file:///tmp/konveyor-archives-1/1-app/src/App.java",
+			want:     "file:///opt/input/app.zip/src/App.java",
+		},
+		{
+			name:          "strip archive",
+			strategy:      StripPrefixStrategy,
+			prefixes:      []string{"/tmp/konveyor-archives-1/1-app"},
+			aliases:       map[string]string{"/tmp/konveyor-archives-1/1-app": "/opt/input/app.zip"},
+			stripPrefixes: []string{"/opt/input"},
+			fileURI:       "file:///tmp/konveyor-archives-1/1-app/src/App.java",
+			want:          "file:///src/App.java",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ruleEngine{
+				locationPrefixes:       tt.prefixes,
+				locationPrefixStrategy: tt.strategy,
+				stripPrefixes:          tt.stripPrefixes,
+				locationAliases:        tt.aliases,
+			}
+			got, err := r.getRelativePathForViolation(tt.fileURI)
+			if err != nil {
+				t.Fatalf("getRelativePathForViolation() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("getRelativePathForViolation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testWarningConditional struct {
+	incidents int
+	warnings  []konveyor.Warning
+}
+
+func (t testWarningConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	response := ConditionResponse{Matched: true, Warnings: t.warnings}
+	for i := 0; i < t.incidents; i++ {
+		response.Incidents = append(response.Incidents, IncidentContext{
+			FileURI: uri.File(fmt.Sprintf("/test/file%d.java", i)),
+		})
+	}
+	return response, nil
+}
+
+func (t testWarningConditional) Ignorable() bool {
+	return true
+}
+
+func TestRuleEngineWarnings(t *testing.T) {
+	message := "message"
+	effort := 1
+	ruleSets := []RuleSet{
+		{
+			Name: "warnings",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "provider-warning", Effort: &effort},
+					Perform:  Perform{Message: Message{Text: &message}},
+					When: AndCondition{
+						Conditions: []ConditionEntry{
+							{ProviderSpecificConfig: testWarningConditional{
+								incidents: 1,
+								warnings:  []konveyor.Warning{{Provider: "builtin", Message: "unable to parse xml file pom.xml"}},
+							}},
+							{ProviderSpecificConfig: testWarningConditional{incidents: 1}},
+						},
+					},
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "incident-limit", Effort: &effort},
+					Perform:  Perform{Message: Message{Text: &message}},
+					When:     testWarningConditional{incidents: 3},
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "no-warning", Effort: &effort},
+					Perform:  Perform{Message: Message{Text: &message}},
+					When:     testWarningConditional{incidents: 1},
+				},
+			},
+		},
+	}
+	eng := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithIncidentLimit(2))
+	defer eng.Stop()
+	rulesets := eng.RunRules(context.Background(), ruleSets)
+	if len(rulesets) != 1 {
+		t.Fatalf("expected 1 ruleset, got %d", len(rulesets))
+	}
+	want := map[string][]konveyor.Warning{
+		"provider-warning": {{Provider: "builtin", Message: "unable to parse xml file pom.xml"}},
+		"incident-limit":   {{Message: "found 3 incidents, only the first 2 are reported because of the incident limit"}},
+	}
+	if !reflect.DeepEqual(rulesets[0].Warnings, want) {
+		t.Errorf("Warnings = %v, want %v", rulesets[0].Warnings, want)
+	}
+}
+
+func TestRuleEngineBranchMessages(t *testing.T) {
+	message := "found in {{branch}}"
+	javaxMessage := "replace javax with jakarta"
+	effort := 1
+	ruleSets := []RuleSet{
+		{
+			Name: "branches",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "branch-messages", Effort: &effort},
+					Perform:  Perform{Message: Message{Text: &message}},
+					When: OrCondition{
+						Conditions: []ConditionEntry{
+							{Branch: "javax", Message: &javaxMessage, ProviderSpecificConfig: testWarningConditional{incidents: 1}},
+							{Branch: "jakarta", ProviderSpecificConfig: testWarningConditional{incidents: 1}},
+						},
+					},
+				},
+			},
+		},
+	}
+	eng := CreateRuleEngine(context.Background(), 2, logr.Discard())
+	defer eng.Stop()
+	rulesets := eng.RunRules(context.Background(), ruleSets)
+	if len(rulesets) != 1 {
+		t.Fatalf("expected 1 ruleset, got %d", len(rulesets))
+	}
+	violation, ok := rulesets[0].Violations["branch-messages"]
+	if !ok {
+		t.Fatalf("expected a violation for the rule")
+	}
+	got := map[string]string{}
+	for _, incident := range violation.Incidents {
+		got[incident.Branch] = incident.Message
+	}
+	want := map[string]string{
+		"javax":   "replace javax with jakarta",
+		"jakarta": "found in jakarta",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("branch messages = %v, want %v", got, want)
+	}
+}
+
+func TestProcessRuleUnless(t *testing.T) {
+	incidents := []IncidentContext{{FileURI: "file:///a.java"}, {FileURI: "file:///b.java"}}
+	testCases := []struct {
+		Name      string
+		When      Conditional
+		Unless    Conditional
+		IsMatched bool
+		Incidents int
+	}{
+		{
+			Name:      "unless not matched",
+			When:      testIncidentConditional{matched: true, incidents: incidents},
+			Unless:    testIncidentConditional{},
+			IsMatched: true,
+			Incidents: 2,
+		},
+		{
+			Name:      "unless matched in one of the files",
+			When:      testIncidentConditional{matched: true, incidents: incidents},
+			Unless:    testIncidentConditional{matched: true, incidents: incidents[1:]},
+			IsMatched: true,
+			Incidents: 1,
+		},
+		{
+			Name:   "unless matched in all the files",
+			When:   testIncidentConditional{matched: true, incidents: incidents},
+			Unless: testIncidentConditional{matched: true, incidents: incidents},
+		},
+		{
+			Name:   "unless matched without incidents",
+			When:   testIncidentConditional{matched: true, incidents: incidents},
+			Unless: testIncidentConditional{matched: true},
+		},
+		{
+			Name: "unless using the template of the when condition",
+			When: ConditionEntry{
+				As:                     "usages",
+				ProviderSpecificConfig: testChainableConditionalAs{documentedKey: "package", AsValue: "javax.persistence"},
+			},
+			Unless: testChainableConditionalFrom{FromName: "usages", DocumentedKey: "package", FromValue: "javax.persistence"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			rule := Rule{
+				RuleMeta: RuleMeta{RuleID: "unless"},
+				When:     tc.When,
+				Unless:   tc.Unless,
+			}
+			ret, err := processRule(context.TODO(), rule, ConditionContext{
+				Template: make(map[string]ChainTemplate),
+			}, logr.Discard())
+			if err != nil {
+				t.Fatalf("got err: %v, expected no error", err)
+			}
+			if ret.Matched != tc.IsMatched {
+				t.Errorf("expected matched %v, got %v", tc.IsMatched, ret.Matched)
+			}
+			if len(ret.Incidents) != tc.Incidents {
+				t.Errorf("expected %d incidents, got %d", tc.Incidents, len(ret.Incidents))
+			}
+		})
+	}
+}
+
+func TestFileScopedAndCondition(t *testing.T) {
+	a := IncidentContext{FileURI: "file:///a.java"}
+	b := IncidentContext{FileURI: "file:///b.java"}
+	config := IncidentContext{FileURI: "file:///b.properties"}
+	testCases := []struct {
+		Name       string
+		Conditions []ConditionEntry
+		IsMatched  bool
+		Incidents  int
+	}{
+		{
+			Name: "conditions in the same file",
+			Conditions: []ConditionEntry{
+				{ProviderSpecificConfig: testIncidentConditional{matched: true, incidents: []IncidentContext{a, b}}},
+				{ProviderSpecificConfig: testIncidentConditional{matched: true, incidents: []IncidentContext{b, b}}},
+			},
+			IsMatched: true,
+			Incidents: 3,
+		},
+		{
+			Name: "conditions in different files",
+			Conditions: []ConditionEntry{
+				{ProviderSpecificConfig: testIncidentConditional{matched: true, incidents: []IncidentContext{a, b}}},
+				{ProviderSpecificConfig: testIncidentConditional{matched: true, incidents: []IncidentContext{config}}},
+			},
+		},
+		{
+			Name: "ignored conditions are not scoped to files",
+			Conditions: []ConditionEntry{
+				{ProviderSpecificConfig: testIncidentConditional{matched: true, incidents: []IncidentContext{a, b}}},
+				{Ignorable: true, ProviderSpecificConfig: testIncidentConditional{matched: true, incidents: []IncidentContext{config}}},
+			},
+			IsMatched: true,
+			Incidents: 2,
+		},
+		{
+			Name: "negated conditions are not scoped to files",
+			Conditions: []ConditionEntry{
+				{ProviderSpecificConfig: testIncidentConditional{matched: true, incidents: []IncidentContext{a}}},
+				{Not: true, ProviderSpecificConfig: testIncidentConditional{}},
+			},
+			IsMatched: true,
+			Incidents: 1,
+		},
+		{
+			Name: "condition not matched",
+			Conditions: []ConditionEntry{
+				{ProviderSpecificConfig: testIncidentConditional{matched: true, incidents: []IncidentContext{a}}},
+				{ProviderSpecificConfig: testIncidentConditional{}},
+			},
+			Incidents: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			and := AndCondition{Conditions: tc.Conditions, FileScoped: true}
+			ret, err := and.Evaluate(context.TODO(), logr.Discard(), ConditionContext{
+				Template: make(map[string]ChainTemplate),
+			})
+			if err != nil {
+				t.Fatalf("got err: %v, expected no error", err)
+			}
+			if ret.Matched != tc.IsMatched {
+				t.Errorf("expected matched %v, got %v", tc.IsMatched, ret.Matched)
+			}
+			if len(ret.Incidents) != tc.Incidents {
+				t.Errorf("expected %d incidents, got %d", tc.Incidents, len(ret.Incidents))
+			}
+		})
+	}
+}
+
+type testIncidentsConditional struct {
+	file  string
+	lines []int
+	// matchedText is the text matched on the lines
+	matchedText []string
+}
+
+func (t testIncidentsConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	response := ConditionResponse{Matched: true}
+	for i, line := range t.lines {
+		lineNumber := line
+		incident := IncidentContext{
+			FileURI:    uri.File(t.file),
+			LineNumber: &lineNumber,
+		}
+		if i < len(t.matchedText) {
+			incident.MatchedText = t.matchedText[i]
+		}
+		response.Incidents = append(response.Incidents, incident)
+	}
+	return response, nil
+}
+
+func (t testIncidentsConditional) Ignorable() bool {
+	return true
+}
+
+func TestRuleEngineSuppressions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "Main.java")
+	content := `import javax.ejb.Stateless; // konveyor:ignore
+// konveyor:ignore:ejb-00002,ejb-00003
+import javax.ejb.Remote;
+import javax.ejb.Local;
+`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	message := "message"
+	effort := 1
+	ruleSets := []RuleSet{
+		{
+			Name: "suppressions",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "ejb-00001", Effort: &effort},
+					Perform:  Perform{Message: Message{Text: &message}},
+					When:     testIncidentsConditional{file: file, lines: []int{1, 3, 4}},
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "ejb-00002", Effort: &effort},
+					Perform:  Perform{Message: Message{Text: &message}},
+					When:     testIncidentsConditional{file: file, lines: []int{3}},
+				},
+			},
+		},
+	}
+	eng := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithLocationPrefixStrategy(KeepAbsoluteStrategy))
+	defer eng.Stop()
+	rulesets := eng.RunRules(context.Background(), ruleSets)
+	if len(rulesets) != 1 {
+		t.Fatalf("expected 1 ruleset, got %d", len(rulesets))
+	}
+	lines := func(incidents []konveyor.Incident) []int {
+		numbers := []int{}
+		for _, incident := range incidents {
+			numbers = append(numbers, *incident.LineNumber)
+		}
+		return numbers
+	}
+	// the comment on line 1 suppresses the incidents on lines 1 and 2 of every rule
+	if got := lines(rulesets[0].Violations["ejb-00001"].Incidents); !reflect.DeepEqual(got, []int{3, 4}) {
+		t.Errorf("expected the incidents of lines 3 and 4 to be reported, got %v", got)
+	}
+	if got := lines(rulesets[0].Suppressed["ejb-00001"]); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("expected the incident of line 1 to be suppressed, got %v", got)
+	}
+	if _, ok := rulesets[0].Violations["ejb-00002"]; ok {
+		t.Errorf("expected no violation for a rule with every incident suppressed")
+	}
+	if got := lines(rulesets[0].Suppressed["ejb-00002"]); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("expected the incident of line 3 to be suppressed, got %v", got)
+	}
+	if len(rulesets[0].Unmatched) != 0 {
+		t.Errorf("expected rules with suppressed incidents not to be unmatched, got %v", rulesets[0].Unmatched)
+	}
+}
+
+func TestSuppressionsReadOnce(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "Main.java")
+	if err := os.WriteFile(file, []byte("import javax.ejb.Stateless; // konveyor:ignore\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := withSuppressions(context.Background(), newSuppressions())
+	line := 1
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func() {
+			done <- suppressionsFromContext(ctx).suppressed(uri.File(file), &line, "ejb-00001")
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if !<-done {
+			t.Errorf("expected the incident of line 1 to be suppressed")
+		}
+	}
+	// the file is not read again by the other rules of the run
+	if err := os.WriteFile(file, []byte("import javax.ejb.Stateless;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !suppressionsFromContext(ctx).suppressed(uri.File(file), &line, "ejb-00002") {
+		t.Errorf("expected the comments of the file to be read once for the run")
+	}
+	if suppressionsFromContext(context.Background()).suppressed(uri.File(file), &line, "ejb-00002") {
+		t.Errorf("expected another run to read the file again")
+	}
+}
+
+func TestRuleEngineCustomVariables(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "Main.java")
+	content := `import javax.jms.Queue;
+Queue orders = session.createQueue("orders");
+`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	message := "package={{package}} queue={{queue}} topic={{topic}}"
+	effort := 1
+	ruleSets := []RuleSet{
+		{
+			Name: "custom-variables",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "jms-00001", Effort: &effort},
+					Perform:  Perform{Message: Message{Text: &message}},
+					CustomVariables: []CustomVariable{
+						{Pattern: regexp.MustCompile(`import (?P<name>[a-z.]+)\.`), Name: "package", NameOfCaptureGroup: "name"},
+						// without a name, the named capture groups are the variables
+						{Pattern: regexp.MustCompile(`createQueue\("(?P<queue>[^"]+)"\)`)},
+						{Pattern: regexp.MustCompile(`createTopic\("([^"]+)"\)`), Name: "topic", DefaultValue: "none"},
+					},
+					When: testIncidentsConditional{
+						file:        file,
+						lines:       []int{1, 2, 2},
+						matchedText: []string{"", "", `session.createQueue("payments")`},
+					},
+				},
+			},
+		},
+	}
+	// the variables are extracted from the source, not from the code snippets
+	eng := CreateRuleEngine(context.Background(), 2, logr.Discard(), WithLocationPrefixStrategy(KeepAbsoluteStrategy), WithCodeSnipLimit(1))
+	defer eng.Stop()
+	rulesets := eng.RunRules(context.Background(), ruleSets)
+	if len(rulesets) != 1 {
+		t.Fatalf("expected 1 ruleset, got %d", len(rulesets))
+	}
+	messages := []string{}
+	for _, incident := range rulesets[0].Violations["jms-00001"].Incidents {
+		messages = append(messages, incident.Message)
+	}
+	want := []string{
+		"package=javax.jms queue= topic=none",
+		"package= queue=orders topic=none",
+		"package= queue=payments topic=none",
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("expected the messages %q, got %q", want, messages)
+	}
+}
+
+func TestCreateFix(t *testing.T) {
+	line := 3
+	incident := IncidentContext{
+		FileURI:    uri.File("/app/src/Main.java"),
+		LineNumber: &line,
+		Variables:  map[string]interface{}{"name": "javax.ejb.Stateless", "version": "1.0"},
+	}
+	r := &ruleEngine{locationPrefixes: []string{"/app"}}
+	tests := []struct {
+		name    string
+		fix     Fix
+		want    konveyor.Fix
+		wantErr bool
+	}{
+		{
+			name: "search and replace on the line of the incident",
+			fix:  Fix{Description: "Replace {{name}}", Search: "{{name}}", Replace: "jakarta.ejb.Stateless"},
+			want: konveyor.Fix{
+				Description: "Replace javax.ejb.Stateless",
+				File:        uri.File("/src/Main.java"),
+				Search:      `javax\.ejb\.Stateless`,
+				Replace:     "jakarta.ejb.Stateless",
+				Line:        &line,
+				Path:        "/app/src/Main.java",
+			},
+		},
+		{
+			name: "search and replace in another file",
+			fix:  Fix{File: "../pom.xml", Search: "<version>{{version}}</version>", Replace: "<version>2.0</version>"},
+			want: konveyor.Fix{
+				File:    uri.File("/pom.xml"),
+				Search:  `<version>1\.0</version>`,
+				Replace: "<version>2.0</version>",
+				Path:    "/app/pom.xml",
+			},
+		},
+		{
+			name: "code action",
+			fix:  Fix{CodeAction: "quickfix.jakarta"},
+			want: konveyor.Fix{File: uri.File("/src/Main.java"), CodeAction: "quickfix.jakarta", Path: "/app/src/Main.java"},
+		},
+		{
+			name:    "absolute file",
+			fix:     Fix{File: "/etc/passwd", Search: "root", Replace: "toor"},
+			wantErr: true,
+		},
+		{
+			name:    "file outside of the locations",
+			fix:     Fix{File: "../../etc/passwd", Search: "root", Replace: "toor"},
+			wantErr: true,
+		},
+		{
+			name:    "file rendered outside of the locations",
+			fix:     Fix{File: "../{{version}}/../../pom.xml", Search: "root", Replace: "toor"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.createFix(tt.fix, incident, uri.File("/src/Main.java"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createFix() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("createFix() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+type testCancelConditional struct {
+	control *progress.Control
+}
+
+func (t testCancelConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	t.control.Cancel("test")
+	return ConditionResponse{}, nil
+}
+
+func (t testCancelConditional) Ignorable() bool {
+	return true
+}
+
+func TestRuleEngineCancel(t *testing.T) {
+	collector := progress.NewCollector()
+	task := collector.Root().Task(progress.StageRuleExecution, "rules", 1)
+	ruleSets := []RuleSet{
+		{
+			Name: "cancel",
+			Rules: []Rule{
+				{RuleMeta: RuleMeta{RuleID: "cancel"}, When: testCancelConditional{control: collector.Control()}},
+				{RuleMeta: RuleMeta{RuleID: "rule-1"}, When: createTestConditional(true, nil, false)},
+				{RuleMeta: RuleMeta{RuleID: "rule-2"}, When: createTestConditional(true, nil, false)},
+			},
+		},
+	}
+	eng := CreateRuleEngine(context.Background(), 1, logr.Discard())
+	defer eng.Stop()
+	rulesets := eng.RunRules(progress.WithTask(context.Background(), task), ruleSets)
+	if len(rulesets) != 1 {
+		t.Fatalf("expected 1 ruleset, got %d", len(rulesets))
+	}
+	if !reflect.DeepEqual(rulesets[0].Unmatched, []string{"cancel"}) {
+		t.Errorf("expected the rule evaluated before the cancellation to be unmatched, got %v", rulesets[0].Unmatched)
+	}
+	sort.Strings(rulesets[0].Canceled)
+	if !reflect.DeepEqual(rulesets[0].Canceled, []string{"rule-1", "rule-2"}) {
+		t.Errorf("expected the rules left to be canceled, got %v", rulesets[0].Canceled)
+	}
+	if len(rulesets[0].Violations) != 0 || len(rulesets[0].Errors) != 0 {
+		t.Errorf("expected the canceled rules not to be evaluated, got %+v", rulesets[0])
+	}
+}
+
+func TestFilterRulesDefaultLabels(t *testing.T) {
+	message := "message"
+	ruleSets := []RuleSet{
+		{
+			Name:          "defaults",
+			Labels:        []string{"konveyor.io/source=eap7"},
+			DefaultLabels: []string{"konveyor.io/target=eap8"},
+			Rules: []Rule{
+				{RuleMeta: RuleMeta{RuleID: "default-target"}, Perform: Perform{Message: Message{Text: &message}}},
+				{RuleMeta: RuleMeta{RuleID: "quarkus-target", Labels: []string{"konveyor.io/target=quarkus"}}, Perform: Perform{Message: Message{Text: &message}}},
+			},
+		},
+	}
+	selector, err := labels.NewLabelSelector[*RuleMeta]("konveyor.io/target in (eap8) && konveyor.io/source=eap7", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &ruleEngine{logger: logr.Discard()}
+	_, rules, mapRuleSets := r.filterRules(ruleSets, selector)
+	if len(rules) != 1 || rules[0].rule.RuleID != "default-target" {
+		t.Fatalf("filterRules() = %v, want the rule with the default target", rules)
+	}
+	if want := []string{"konveyor.io/target=eap8", "konveyor.io/source=eap7"}; !reflect.DeepEqual(rules[0].rule.Labels, want) {
+		t.Errorf("Labels = %v, want %v", rules[0].rule.Labels, want)
+	}
+	if want := []string{"quarkus-target"}; !reflect.DeepEqual(mapRuleSets["defaults"].Skipped, want) {
+		t.Errorf("Skipped = %v, want %v", mapRuleSets["defaults"].Skipped, want)
+	}
+}
+
+func TestGetCodeLocation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dump.sql")
+	content := "-- dump\nCREATE TABLE t (id int);\nINSERT INTO t VALUES " + strings.Repeat("(1),", 20000) + "(1);\n-- end\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	incident := IncidentContext{
+		FileURI:      uri.File(file),
+		CodeLocation: &Location{StartPosition: Position{Line: 1}, EndPosition: Position{Line: 1}},
+	}
+	r := &ruleEngine{logger: logr.Discard(), contextLines: 1}
+	got, err := r.getCodeLocation(context.TODO(), incident, Rule{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1  -- dump\n2  CREATE TABLE t (id int);\n3  " + ("INSERT INTO t VALUES " + strings.Repeat("(1),", 20000))[:textfile.MaxLineLength]
+	if got != want {
+		t.Errorf("expected the snippet to end with the truncated line 3, got %d bytes ending with %q", len(got), got[max(0, len(got)-20):])
+	}
+
+	r.codeSnipMaxFileSize = 1024
+	_, err = r.getCodeLocation(context.TODO(), incident, Rule{})
+	tooBig := &codeSnipFileTooBigError{}
+	if !errors.As(err, &tooBig) || tooBig.size != int64(len(content)) {
+		t.Errorf("expected the file to be too big for a snippet, got %v", err)
+	}
+}
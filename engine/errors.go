@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"context"
+	"errors"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// ProviderError is an error of a provider evaluating a condition. Its code
+// tells whether the rule or the environment is broken, it's recorded with
+// the errors of the rule in the output.
+type ProviderError struct {
+	Code     konveyor.ErrorCode
+	Provider string
+	Err      error
+}
+
+// NewProviderError classifies an error with a code, the provider is added
+// by the condition evaluating it.
+func NewProviderError(code konveyor.ErrorCode, err error) *ProviderError {
+	return &ProviderError{Code: code, Err: err}
+}
+
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the code of the first ProviderError in the chain of an
+// error, ConditionTimeout when a deadline was exceeded and UnknownError
+// otherwise.
+func ErrorCode(err error) konveyor.ErrorCode {
+	var pe *ProviderError
+	if errors.As(err, &pe) && pe.Code != "" {
+		return pe.Code
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return konveyor.ConditionTimeout
+	}
+	return konveyor.UnknownError
+}
+
+// ruleError returns the error of a rule for the output
+func ruleError(err error) konveyor.RuleError {
+	ruleErr := konveyor.RuleError{
+		Code:    ErrorCode(err),
+		Message: err.Error(),
+	}
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		ruleErr.Provider = pe.Provider
+	}
+	return ruleErr
+}
@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func Test_ruleError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want konveyor.RuleError
+	}{
+		{
+			name: "provider error",
+			err:  &ProviderError{Code: konveyor.CapabilityUnsupported, Provider: "java", Err: fmt.Errorf("capability 'foo' not supported")},
+			want: konveyor.RuleError{Code: konveyor.CapabilityUnsupported, Provider: "java", Message: "capability 'foo' not supported"},
+		},
+		{
+			name: "wrapped provider error",
+			err:  fmt.Errorf("unable to evaluate chained condition: %w", &ProviderError{Code: konveyor.ProviderUnavailable, Provider: "go", Err: fmt.Errorf("connection refused")}),
+			want: konveyor.RuleError{Code: konveyor.ProviderUnavailable, Provider: "go", Message: "unable to evaluate chained condition: connection refused"},
+		},
+		{
+			name: "timeout",
+			err:  fmt.Errorf("evaluating condition: %w", context.DeadlineExceeded),
+			want: konveyor.RuleError{Code: konveyor.ConditionTimeout, Message: "evaluating condition: context deadline exceeded"},
+		},
+		{
+			name: "engine error",
+			err:  fmt.Errorf("unable to find condition"),
+			want: konveyor.RuleError{Code: konveyor.UnknownError, Message: "unable to find condition"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ruleError(tt.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"github.com/google/cel-go/cel"
+	"github.com/konveyor/analyzer-lsp/tracing"
+)
+
+const (
+	exprTagsVariable      = "tags"
+	exprTemplatesVariable = "templates"
+)
+
+// ExprCondition matches when its CEL expression is true. The expression
+// refers to the result of each of its conditions by the name given with as,
+// a bool, and can use the tags found by tagging rules, a list of strings, and
+// the chained template variables, a map of the names to their filepaths,
+// extras and the variables of their incidents:
+//
+//	references && !('Spring' in tags) && size(templates.poms.filepaths) > 0
+//
+// Every condition is evaluated, the incidents are the ones of the conditions
+// that matched.
+type ExprCondition struct {
+	Expr       string           `yaml:"expr"`
+	Conditions []ConditionEntry `yaml:"conditions"`
+
+	program cel.Program
+}
+
+// NewExprCondition compiles the expression, it must be a bool using only the
+// names of the conditions, tags and templates.
+func NewExprCondition(expr string, conditions []ConditionEntry) (*ExprCondition, error) {
+	options := []cel.EnvOption{
+		cel.Variable(exprTagsVariable, cel.ListType(cel.StringType)),
+		cel.Variable(exprTemplatesVariable, cel.MapType(cel.StringType, cel.DynType)),
+	}
+	seen := map[string]bool{}
+	for _, c := range conditions {
+		if c.As == "" {
+			return nil, fmt.Errorf("conditions of an expression must be named with 'as'")
+		}
+		if c.As == exprTagsVariable || c.As == exprTemplatesVariable {
+			return nil, fmt.Errorf("condition can not be named %s, it is a variable of the expression", c.As)
+		}
+		if seen[c.As] {
+			return nil, fmt.Errorf("condition cannot have multiple 'as' fields with the same name")
+		}
+		seen[c.As] = true
+		options = append(options, cel.Variable(c.As, cel.BoolType))
+	}
+	env, err := cel.NewEnv(options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create expression environment: %w", err)
+	}
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must be a bool, not %s", ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	return &ExprCondition{
+		Expr:       expr,
+		Conditions: conditions,
+		program:    program,
+	}, nil
+}
+
+func (e ExprCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	ctx, span := tracing.StartNewSpan(ctx, "expr-condition")
+	defer span.End()
+
+	if e.program == nil {
+		return ConditionResponse{}, fmt.Errorf("expression must be created with NewExprCondition")
+	}
+
+	fullResponse := ConditionResponse{
+		Incidents:       []IncidentContext{},
+		TemplateContext: map[string]interface{}{},
+	}
+	variables := map[string]interface{}{}
+	for _, c := range sortConditionEntries(e.Conditions) {
+		if _, ok := condCtx.Template[c.From]; !ok && c.From != "" {
+			return ConditionResponse{}, fmt.Errorf("unable to find context value: %v", c.From)
+		}
+		response, err := c.ProviderSpecificConfig.Evaluate(ctx, log, condCtx)
+		if err != nil {
+			return ConditionResponse{}, err
+		}
+		condCtx.Template[c.As] = newChainTemplate(response)
+
+		matched := response.Matched
+		if c.Not {
+			matched = !matched
+		}
+		variables[c.As] = matched
+		if matched && !c.Ignorable {
+			fullResponse.Incidents = append(fullResponse.Incidents, c.branchIncidents(response.Incidents)...)
+		}
+		fullResponse.Warnings = append(fullResponse.Warnings, response.Warnings...)
+
+		for k, v := range response.TemplateContext {
+			fullResponse.TemplateContext[k] = v
+		}
+	}
+
+	tags := []string{}
+	for tag := range condCtx.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	variables[exprTagsVariable] = tags
+	templates := map[string]interface{}{}
+	for name, template := range condCtx.Template {
+		filepaths := template.Filepaths
+		if filepaths == nil {
+			filepaths = []string{}
+		}
+		extras := template.Extras
+		if extras == nil {
+			extras = map[string]interface{}{}
+		}
+		incidentVariables := template.Variables
+		if incidentVariables == nil {
+			incidentVariables = []map[string]interface{}{}
+		}
+		templates[name] = map[string]interface{}{
+			"filepaths": filepaths,
+			"extras":    extras,
+			"variables": incidentVariables,
+		}
+	}
+	variables[exprTemplatesVariable] = templates
+
+	out, _, err := e.program.Eval(variables)
+	if err != nil {
+		return ConditionResponse{}, fmt.Errorf("unable to evaluate expression %s: %w", e.Expr, err)
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return ConditionResponse{}, fmt.Errorf("expression %s did not return a bool", e.Expr)
+	}
+	fullResponse.Matched = matched
+	return fullResponse, nil
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+type testIncidentConditional struct {
+	matched   bool
+	incidents []IncidentContext
+}
+
+func (t testIncidentConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	if !t.matched {
+		return ConditionResponse{}, nil
+	}
+	return ConditionResponse{Matched: true, Incidents: t.incidents}, nil
+}
+
+func TestNewExprCondition(t *testing.T) {
+	tests := []struct {
+		title       string
+		expr        string
+		conditions  []ConditionEntry
+		shouldError bool
+	}{
+		{
+			title:      "expression of the named conditions",
+			expr:       "a && !b",
+			conditions: []ConditionEntry{{As: "a"}, {As: "b"}},
+		},
+		{
+			title:      "expression of the tags and templates",
+			expr:       "'Spring' in tags && size(templates.a.filepaths) > 0",
+			conditions: []ConditionEntry{{As: "a"}},
+		},
+		{
+			title:       "conditions must be named",
+			expr:        "a",
+			conditions:  []ConditionEntry{{As: "a"}, {}},
+			shouldError: true,
+		},
+		{
+			title:       "conditions can not be named as a variable",
+			expr:        "tags",
+			conditions:  []ConditionEntry{{As: "tags"}},
+			shouldError: true,
+		},
+		{
+			title:       "conditions must have different names",
+			expr:        "a",
+			conditions:  []ConditionEntry{{As: "a"}, {As: "a"}},
+			shouldError: true,
+		},
+		{
+			title:       "expression of unknown names",
+			expr:        "a || c",
+			conditions:  []ConditionEntry{{As: "a"}, {As: "b"}},
+			shouldError: true,
+		},
+		{
+			title:       "expression must be a bool",
+			expr:        "size(tags)",
+			conditions:  []ConditionEntry{{As: "a"}},
+			shouldError: true,
+		},
+		{
+			title:       "invalid expression",
+			expr:        "a &&",
+			conditions:  []ConditionEntry{{As: "a"}},
+			shouldError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			_, err := NewExprCondition(tt.expr, tt.conditions)
+			if (err != nil) != tt.shouldError {
+				t.Errorf("NewExprCondition() error = %v, shouldError %v", err, tt.shouldError)
+			}
+		})
+	}
+}
+
+func TestExprCondition(t *testing.T) {
+	incidents := []IncidentContext{{FileURI: "file:///a.java", Variables: map[string]interface{}{"package": "com.example"}}}
+	tests := []struct {
+		title     string
+		expr      string
+		a         bool
+		b         bool
+		notB      bool
+		tags      map[string]interface{}
+		matched   bool
+		incidents int
+	}{
+		{
+			title:     "a and not b",
+			expr:      "a && !b",
+			a:         true,
+			matched:   true,
+			incidents: 1,
+		},
+		{
+			title:     "a and not b when b matches",
+			expr:      "a && !b",
+			a:         true,
+			b:         true,
+			incidents: 2,
+		},
+		{
+			title:     "not of the condition is applied before the expression",
+			expr:      "a && b",
+			a:         true,
+			notB:      true,
+			matched:   true,
+			incidents: 1,
+		},
+		{
+			title:     "tag of the application",
+			expr:      "a && 'Spring' in tags",
+			a:         true,
+			tags:      map[string]interface{}{"Spring": true},
+			matched:   true,
+			incidents: 1,
+		},
+		{
+			title:     "missing tag of the application",
+			expr:      "a && tags.exists(t, t == 'Spring')",
+			a:         true,
+			tags:      map[string]interface{}{"Quarkus": true},
+			incidents: 1,
+		},
+		{
+			title:     "template of a condition",
+			expr:      "size(templates.a.filepaths) == 1 && size(templates.b.filepaths) == 0",
+			a:         true,
+			matched:   true,
+			incidents: 1,
+		},
+		{
+			title:     "variables of the incidents of a condition",
+			expr:      "templates.a.variables.exists(v, v.package == 'com.example') && size(templates.b.variables) == 0",
+			a:         true,
+			matched:   true,
+			incidents: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			e, err := NewExprCondition(tt.expr, []ConditionEntry{
+				{As: "a", ProviderSpecificConfig: testIncidentConditional{matched: tt.a, incidents: incidents}},
+				{As: "b", Not: tt.notB, ProviderSpecificConfig: testIncidentConditional{matched: tt.b, incidents: incidents}},
+			})
+			if err != nil {
+				t.Fatalf("unable to create expression: %v", err)
+			}
+			response, err := e.Evaluate(context.TODO(), logr.Discard(), ConditionContext{
+				Tags:     tt.tags,
+				Template: map[string]ChainTemplate{},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.Matched != tt.matched {
+				t.Errorf("expected matched %v, got %v", tt.matched, response.Matched)
+			}
+			if len(response.Incidents) != tt.incidents {
+				t.Errorf("expected %d incidents, got %d", tt.incidents, len(response.Incidents))
+			}
+		})
+	}
+}
@@ -25,6 +25,15 @@ const (
 	exprSplitter = `(` + exprSpecialSymbols + `|[^!` + exprSpecialSymbols + `]+)`
 )
 
+var (
+	// labelInList matches the `key in (val1, val2)` operations, the first
+	// group is what precedes the key
+	labelInList = regexp.MustCompile(`(^|[\s!&|(])([^\s!&|(),=]+)\s+in\s*\(([^()]*)\)`)
+	// labelFunction matches the `exists(key)` and `prefix(key, val)`
+	// functions, the first group is what precedes the function
+	labelFunction = regexp.MustCompile(`(^|[\s!&|(])(exists|prefix)\(\s*([^\s,()]+)\s*(?:,\s*([^,()]*[^\s,()])\s*)?\)`)
+)
+
 type LabelSelector[T Labeled] struct {
 	expr     string
 	language gval.Language
@@ -86,7 +95,11 @@ type MatchAny func(elem string, items []string) bool
 // NewRuleSelector returns a new rule selector that works on rule labels
 // it enables using string expressions to form complex label queries
 // supports "&&", "||" and "!" operators, "(" ")" for grouping, operands
-// are string labels in key=val format, keys can be subdomain prefixed
+// are string labels in key=val format, keys can be subdomain prefixed.
+// Operands can also be "key in (val1, val2)", true when a value of the key
+// matches one of the values, "exists(key)", true when the key is set with
+// any value, and "prefix(key, val)", true when a value of the key starts
+// with val.
 func NewLabelSelector[T Labeled](expr string, match MatchAny) (*LabelSelector[T], error) {
 	language := gval.NewLanguage(
 		gval.Ident(),
@@ -176,6 +189,27 @@ func ParseLabel(label string) (string, string, error) {
 	return key, val, nil
 }
 
+// MergeLabels returns the labels with the default labels whose keys are not
+// in the labels, the labels override the default labels with the same key
+func MergeLabels(labels []string, defaults []string) []string {
+	keys := map[string]bool{}
+	for _, label := range labels {
+		keys[labelKey(label)] = true
+	}
+	merged := append([]string{}, labels...)
+	for _, label := range defaults {
+		if !keys[labelKey(label)] {
+			merged = append(merged, label)
+		}
+	}
+	return merged
+}
+
+func labelKey(label string) string {
+	key, _, _ := strings.Cut(label, "=")
+	return key
+}
+
 func convertToBool(o interface{}) (bool, bool) {
 	if b, ok := o.(bool); ok {
 		return b, true
@@ -195,7 +229,7 @@ func convertToBool(o interface{}) (bool, bool) {
 func getLabelsFromExpression(expr string) (map[string][]string, error) {
 	labelsList := []string{}
 	for _, token := range tokenize(expr) {
-		if token == "" ||
+		if token == "" || token == "true" || token == "false" ||
 			regexp.MustCompile(exprSpecialSymbols).MatchString(token) {
 			continue
 		}
@@ -215,6 +249,7 @@ func getLabelsFromExpression(expr string) (map[string][]string, error) {
 // something like "true && false" as a boolean expression depending on passed labels
 // we wouldn't need this if gval supported writing custom operands
 func getBooleanExpression(expr string, compareLabels map[string][]string, matchAny MatchAny) string {
+	expr = evaluateOperations(expr, compareLabels, matchAny)
 	exprLabels, err := getLabelsFromExpression(expr)
 	if err != nil {
 		return expr
@@ -247,6 +282,75 @@ func getBooleanExpression(expr string, compareLabels map[string][]string, matchA
 	return boolExpr
 }
 
+// evaluateOperations replaces the "in" operations and the functions of the
+// expression with their result, true or false, for the given labels. The
+// operations of invalid keys or values are left as they are so that the
+// expression is invalid.
+func evaluateOperations(expr string, compareLabels map[string][]string, matchAny MatchAny) string {
+	valueRegex := regexp.MustCompile(LabelValueFmt)
+	expr = replaceSubmatches(labelInList, expr, func(groups []string) (bool, bool) {
+		key, list := groups[2], groups[3]
+		if _, _, err := ParseLabel(key); err != nil {
+			return false, false
+		}
+		labelVals, ok := compareLabels[key]
+		matched := false
+		for _, val := range strings.Split(list, ",") {
+			val = strings.TrimSpace(val)
+			if !valueRegex.MatchString(val) {
+				return false, false
+			}
+			if ok && matchAny(val, labelVals) {
+				matched = true
+			}
+		}
+		return matched, true
+	})
+	return replaceSubmatches(labelFunction, expr, func(groups []string) (bool, bool) {
+		function, key, val := groups[2], groups[3], groups[4]
+		if _, _, err := ParseLabel(key); err != nil {
+			return false, false
+		}
+		labelVals, ok := compareLabels[key]
+		switch function {
+		case "exists":
+			return ok, val == ""
+		default:
+			if val == "" || !valueRegex.MatchString(val) {
+				return false, false
+			}
+			for _, labelVal := range labelVals {
+				if strings.HasPrefix(labelVal, val) {
+					return true, true
+				}
+			}
+			return false, true
+		}
+	})
+}
+
+// replaceSubmatches replaces the matches of the regex, but their first group,
+// with the result of the operation they are
+func replaceSubmatches(regex *regexp.Regexp, expr string, evaluate func(groups []string) (result bool, ok bool)) string {
+	replaced := ""
+	last := 0
+	for _, loc := range regex.FindAllStringSubmatchIndex(expr, -1) {
+		groups := make([]string, len(loc)/2)
+		for i := range groups {
+			if loc[2*i] >= 0 {
+				groups[i] = expr[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		result, ok := evaluate(groups)
+		if !ok {
+			continue
+		}
+		replaced += expr[last:loc[0]] + groups[1] + fmt.Sprintf(" %v ", result)
+		last = loc[1]
+	}
+	return replaced + expr[last:]
+}
+
 func tokenize(expr string) []string {
 	tokens := []string{}
 	for _, token := range regexp.MustCompile(exprSplitter).FindAllString(expr, -1) {
@@ -1,6 +1,7 @@
 package labels
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/konveyor/analyzer-lsp/engine/internal"
@@ -277,6 +278,25 @@ func TestNewRuleSelector(t *testing.T) {
 			name: "spaces and dots in label values",
 			expr: "konveyor.io/target=Spring     . Beans",
 		},
+		{
+			name: "in operations and functions",
+			expr: "(konveyor.io/target in (quarkus, eap8+) || prefix(konveyor.io/target, spring)) && !exists(konveyor.io/source)",
+		},
+		{
+			name:    "invalid value in a list",
+			expr:    "konveyor.io/target in (quarkus, eap$)",
+			wantErr: true,
+		},
+		{
+			name:    "prefix without value",
+			expr:    "prefix(konveyor.io/target)",
+			wantErr: true,
+		},
+		{
+			name:    "unknown function",
+			expr:    "suffix(konveyor.io/target, 8)",
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -413,6 +433,73 @@ func Test_ruleSelector_Matches(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "in operation with a matching value",
+			expr: "konveyor.io/target in (quarkus, eap8) && !konveyor.io/source=weblogic",
+			ruleLabels: []string{
+				"konveyor.io/target=eap7+",
+				"konveyor.io/source=eap",
+			},
+			want: true,
+		},
+		{
+			name: "in operation excluded by the source",
+			expr: "konveyor.io/target in (quarkus, eap8) && !konveyor.io/source=weblogic",
+			ruleLabels: []string{
+				"konveyor.io/target=quarkus",
+				"konveyor.io/source=weblogic",
+			},
+			want: false,
+		},
+		{
+			name: "in operation without a matching value",
+			expr: "konveyor.io/target in (quarkus,eap8)",
+			ruleLabels: []string{
+				"konveyor.io/target=openjdk17",
+			},
+			want: false,
+		},
+		{
+			name: "negated in operation",
+			expr: "!konveyor.io/target in (quarkus, eap8)",
+			ruleLabels: []string{
+				"konveyor.io/target=openjdk17",
+			},
+			want: true,
+		},
+		{
+			name: "exists",
+			expr: "exists(konveyor.io/source) && !exists(konveyor.io/include)",
+			ruleLabels: []string{
+				"konveyor.io/source=java-ee",
+			},
+			want: true,
+		},
+		{
+			name: "exists of a missing key",
+			expr: "(exists(konveyor.io/source))",
+			ruleLabels: []string{
+				"konveyor.io/target=quarkus",
+			},
+			want: false,
+		},
+		{
+			name: "prefix",
+			expr: "prefix(konveyor.io/target, spring) || konveyor.io/target=quarkus",
+			ruleLabels: []string{
+				"konveyor.io/target=springboot3",
+			},
+			want: true,
+		},
+		{
+			name: "prefix without a matching value",
+			expr: "prefix(konveyor.io/target, spring)",
+			ruleLabels: []string{
+				"konveyor.io/target=quarkus",
+				"konveyor.io/source=springboot",
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -424,6 +511,17 @@ func Test_ruleSelector_Matches(t *testing.T) {
 	}
 }
 
+func TestMergeLabels(t *testing.T) {
+	got := MergeLabels(
+		[]string{"konveyor.io/target=quarkus", "component"},
+		[]string{"konveyor.io/target=eap8", "konveyor.io/source=eap7", "component=storage"},
+	)
+	want := []string{"konveyor.io/target=quarkus", "component", "konveyor.io/source=eap7"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeLabels() = %v, want %v", got, want)
+	}
+}
+
 func Test_labelValueMatches(t *testing.T) {
 	tests := []struct {
 		name      string
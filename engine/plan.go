@@ -0,0 +1,36 @@
+package engine
+
+import "github.com/konveyor/analyzer-lsp/engine/labels"
+
+// PlannedRule is a rule of the rulesets given to a run, see PlanRules
+type PlannedRule struct {
+	RuleSet string
+	RuleID  string
+	// Providers are the providers of the conditions of the rule
+	Providers []string
+	// Cost is how expensive the rule is to evaluate, rules run from the
+	// cheapest to the most expensive
+	Cost Cost
+	// Selected is false for the rules the selectors leave out of the run
+	Selected bool
+}
+
+// PlanRules returns the rules of the rulesets, telling which ones a run with
+// the selectors evaluates, without evaluating any of them
+func PlanRules(ruleSets []RuleSet, selectors ...RuleSelector) []PlannedRule {
+	planned := []PlannedRule{}
+	for _, ruleSet := range ruleSets {
+		for _, rule := range ruleSet.Rules {
+			// the selectors match the labels of the rule as in filterRules
+			rule.Labels = append(labels.MergeLabels(rule.Labels, ruleSet.DefaultLabels), ruleSet.Labels...)
+			planned = append(planned, PlannedRule{
+				RuleSet:   ruleSet.Name,
+				RuleID:    rule.RuleID,
+				Providers: ruleProviders(rule),
+				Cost:      ruleCost(rule),
+				Selected:  matchesAllSelectors(rule.RuleMeta, selectors...),
+			})
+		}
+	}
+	return planned
+}
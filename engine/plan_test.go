@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+)
+
+type testPlannedConditional struct {
+	testProviderConditional
+	cost Cost
+}
+
+func (t testPlannedConditional) Cost() Cost {
+	return t.cost
+}
+
+func TestPlanRules(t *testing.T) {
+	entry := func(provider string, cost Cost) ConditionEntry {
+		return ConditionEntry{ProviderSpecificConfig: testPlannedConditional{
+			testProviderConditional: testProviderConditional{provider: provider},
+			cost:                    cost,
+		}}
+	}
+	ruleSets := []RuleSet{{
+		Name:          "eap",
+		DefaultLabels: []string{"konveyor.io/target=eap8"},
+		Rules: []Rule{
+			{RuleMeta: RuleMeta{RuleID: "eap-001"}, When: entry("java", HighCost)},
+			{RuleMeta: RuleMeta{RuleID: "eap-002", Labels: []string{"konveyor.io/target=quarkus"}}, When: entry("builtin", LowCost)},
+			{RuleMeta: RuleMeta{RuleID: "eap-003"}, When: AndCondition{Conditions: []ConditionEntry{entry("builtin", LowCost), entry("java", HighCost)}}},
+		},
+	}}
+	selector, err := labels.NewLabelSelector[*RuleMeta]("konveyor.io/target=eap8", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PlannedRule{
+		{RuleSet: "eap", RuleID: "eap-001", Providers: []string{"java"}, Cost: HighCost, Selected: true},
+		{RuleSet: "eap", RuleID: "eap-002", Providers: []string{"builtin"}, Cost: LowCost, Selected: false},
+		{RuleSet: "eap", RuleID: "eap-003", Providers: []string{"builtin", "java"}, Cost: HighCost, Selected: true},
+	}
+	if got := PlanRules(ruleSets, selector); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the plan %#v, got %#v", want, got)
+	}
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+)
+
+// ScopeFactory creates a scope from the arguments given along with its name,
+// the arguments are empty when none were given.
+type ScopeFactory func(log logr.Logger, args string) (Scope, error)
+
+// SelectorFactory creates a rule selector from the arguments given along with its name
+type SelectorFactory func(log logr.Logger, args string) (RuleSelector, error)
+
+var (
+	registryMutex sync.RWMutex
+	scopes        = map[string]ScopeFactory{}
+	selectors     = map[string]SelectorFactory{}
+)
+
+func init() {
+	RegisterScope("included-paths", func(log logr.Logger, args string) (Scope, error) {
+		return IncludedPathsScope(splitArgs(args), log), nil
+	})
+	RegisterScope("excluded-paths", func(log logr.Logger, args string) (Scope, error) {
+		return ExcludedPathsScope(splitArgs(args), log), nil
+	})
+	RegisterScope("changed-files", func(log logr.Logger, args string) (Scope, error) {
+		files := []string{}
+		for _, file := range splitArgs(args) {
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, abs)
+		}
+		return ChangedFilesScope(files, log), nil
+	})
+	RegisterSelector("label", func(log logr.Logger, args string) (RuleSelector, error) {
+		return labels.NewLabelSelector[*RuleMeta](args, nil)
+	})
+	RegisterSelector("category", func(log logr.Logger, args string) (RuleSelector, error) {
+		return NewCategorySelector(args)
+	})
+}
+
+// RegisterScope makes a scope available by name to the analyzer, programs
+// embedding the engine register their own scopes before parsing their flags
+// or configuration. Names can only be registered once.
+func RegisterScope(name string, factory ScopeFactory) error {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := scopes[name]; ok {
+		return fmt.Errorf("scope %s is already registered", name)
+	}
+	scopes[name] = factory
+	return nil
+}
+
+// RegisterSelector makes a rule selector available by name to the analyzer.
+// Names can only be registered once.
+func RegisterSelector(name string, factory SelectorFactory) error {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := selectors[name]; ok {
+		return fmt.Errorf("selector %s is already registered", name)
+	}
+	selectors[name] = factory
+	return nil
+}
+
+// NewRegisteredScope creates a registered scope from a "<name>" or
+// "<name>=<args>" reference.
+func NewRegisteredScope(log logr.Logger, reference string) (Scope, error) {
+	name, args, _ := strings.Cut(reference, "=")
+	registryMutex.RLock()
+	factory, ok := scopes[name]
+	registryMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown scope %s, must be one of %v", name, RegisteredScopes())
+	}
+	scope, err := factory(log, args)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create scope %s: %w", name, err)
+	}
+	return scope, nil
+}
+
+// NewRegisteredSelector creates a registered rule selector from a "<name>" or
+// "<name>=<args>" reference.
+func NewRegisteredSelector(log logr.Logger, reference string) (RuleSelector, error) {
+	name, args, _ := strings.Cut(reference, "=")
+	registryMutex.RLock()
+	factory, ok := selectors[name]
+	registryMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown selector %s, must be one of %v", name, RegisteredSelectors())
+	}
+	selector, err := factory(log, args)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create selector %s: %w", name, err)
+	}
+	return selector, nil
+}
+
+// RegisteredScopes returns the sorted names of the registered scopes
+func RegisteredScopes() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := []string{}
+	for name := range scopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisteredSelectors returns the sorted names of the registered rule selectors
+func RegisteredSelectors() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := []string{}
+	for name := range selectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func splitArgs(args string) []string {
+	values := []string{}
+	for _, value := range strings.Split(args, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
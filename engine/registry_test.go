@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+type testSelector struct {
+	label string
+}
+
+func (s testSelector) Matches(m *RuleMeta) (bool, error) {
+	for _, label := range m.Labels {
+		if label == s.label {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestRegistry(t *testing.T) {
+	err := RegisterSelector("test-has-label", func(log logr.Logger, args string) (RuleSelector, error) {
+		return testSelector{label: args}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error registering selector: %v", err)
+	}
+	if err := RegisterSelector("test-has-label", nil); err == nil {
+		t.Errorf("expected an error registering the same selector twice")
+	}
+
+	selector, err := NewRegisteredSelector(logr.Discard(), "test-has-label=konveyor.io/target=quarkus")
+	if err != nil {
+		t.Fatalf("unexpected error creating selector: %v", err)
+	}
+	if matched, _ := selector.Matches(&RuleMeta{Labels: []string{"konveyor.io/target=quarkus"}}); !matched {
+		t.Errorf("expected the arguments after the first = to be given to the selector")
+	}
+
+	selector, err = NewRegisteredSelector(logr.Discard(), "label=konveyor.io/source=java")
+	if err != nil {
+		t.Fatalf("unexpected error creating label selector: %v", err)
+	}
+	if matched, _ := selector.Matches(&RuleMeta{Labels: []string{"konveyor.io/source=java"}}); !matched {
+		t.Errorf("expected the label selector to match")
+	}
+
+	selector, err = NewRegisteredSelector(logr.Discard(), "category=mandatory")
+	if err != nil {
+		t.Fatalf("unexpected error creating category selector: %v", err)
+	}
+	mandatory, optional := konveyor.Mandatory, konveyor.Optional
+	for _, tc := range []struct {
+		category *konveyor.Category
+		want     bool
+	}{{&mandatory, true}, {&optional, false}, {nil, true}} {
+		if matched, _ := selector.Matches(&RuleMeta{Category: tc.category}); matched != tc.want {
+			t.Errorf("expected the category selector to match %v: %v", tc.category, tc.want)
+		}
+	}
+
+	if _, err := NewRegisteredSelector(logr.Discard(), "unknown"); err == nil || !strings.Contains(err.Error(), "test-has-label") {
+		t.Errorf("expected an error listing the registered selectors, got %v", err)
+	}
+
+	scope, err := NewRegisteredScope(logr.Discard(), "excluded-paths=vendor/, .*_test.go")
+	if err != nil {
+		t.Fatalf("unexpected error creating scope: %v", err)
+	}
+	if !scope.FilterResponse(IncidentContext{FileURI: "file:///app/vendor/lib.go"}) {
+		t.Errorf("expected the scope to exclude vendor")
+	}
+	if scope.FilterResponse(IncidentContext{FileURI: "file:///app/main.go"}) {
+		t.Errorf("expected the scope to keep main.go")
+	}
+	if _, err := NewRegisteredScope(logr.Discard(), "unknown"); err == nil {
+		t.Errorf("expected an error for an unknown scope")
+	}
+}
@@ -0,0 +1,236 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// defaultScaleUpWait is how long a rule that could run waits for a worker
+	// before the scheduler adds one
+	defaultScaleUpWait = 500 * time.Millisecond
+	// defaultScaleDownIdle is how long a worker is not needed before the
+	// scheduler removes one
+	defaultScaleDownIdle = 5 * time.Second
+	// schedulerTick is how often the scheduler checks the wait of the rules
+	schedulerTick = 100 * time.Millisecond
+)
+
+// ProviderConditional is a condition evaluated by providers. The rules with
+// conditions of a provider limiting the rules evaluated at once wait for the
+// ones evaluated to finish, see WithProviderLimits.
+type ProviderConditional interface {
+	Providers() []string
+}
+
+// walkConditions calls visit with the conditions of a rule that are not
+// made of other conditions
+func walkConditions(rule Rule, visit func(c Conditional)) {
+	var walk func(c Conditional)
+	walk = func(c Conditional) {
+		switch v := c.(type) {
+		case nil:
+			return
+		case ConditionEntry:
+			walk(v.ProviderSpecificConfig)
+		case AndCondition:
+			for _, e := range v.Conditions {
+				walk(e)
+			}
+		case OrCondition:
+			for _, e := range v.Conditions {
+				walk(e)
+			}
+		case ExprCondition:
+			for _, e := range v.Conditions {
+				walk(e)
+			}
+		case *ExprCondition:
+			for _, e := range v.Conditions {
+				walk(e)
+			}
+		default:
+			visit(v)
+		}
+	}
+	walk(rule.When)
+	walk(rule.Unless)
+}
+
+// ruleProviders returns the providers of the conditions of a rule
+func ruleProviders(rule Rule) []string {
+	seen := map[string]bool{}
+	providers := []string{}
+	walkConditions(rule, func(c Conditional) {
+		v, ok := c.(ProviderConditional)
+		if !ok {
+			return
+		}
+		for _, p := range v.Providers() {
+			if !seen[p] {
+				seen[p] = true
+				providers = append(providers, p)
+			}
+		}
+	})
+	return providers
+}
+
+// queuedRule is a rule waiting to be evaluated
+type queuedRule struct {
+	message   ruleMessage
+	providers []string
+	cost      Cost
+	queued    time.Time
+}
+
+// scheduler evaluates the rules sent to the engine. Rather than a fixed
+// number of workers evaluating the rules in order, it evaluates the first
+// rules whose providers are under their limit of rules evaluated at once, so
+// that the rules of a slow provider don't hold every worker. The cheap rules
+// are evaluated before the expensive ones received earlier. The number of
+// workers scales from minWorkers up to maxWorkers when rules that could be
+// evaluated wait for one, and back down when they are not needed.
+type scheduler struct {
+	log            logr.Logger
+	minWorkers     int
+	maxWorkers     int
+	providerLimits map[string]int
+	scaleUpWait    time.Duration
+	scaleDownIdle  time.Duration
+
+	// evaluate evaluates a rule and sends its response
+	evaluate func(ctx context.Context, log logr.Logger, m ruleMessage)
+
+	pending  []queuedRule
+	running  int
+	workers  int
+	inFlight map[string]int
+	// lastNeeded is the last time every worker was used
+	lastNeeded time.Time
+}
+
+func newScheduler(log logr.Logger, minWorkers, maxWorkers int, providerLimits map[string]int, evaluate func(ctx context.Context, log logr.Logger, m ruleMessage)) *scheduler {
+	if minWorkers < 1 {
+		minWorkers = 1
+	}
+	if maxWorkers < minWorkers {
+		maxWorkers = minWorkers
+	}
+	return &scheduler{
+		log:            log,
+		minWorkers:     minWorkers,
+		maxWorkers:     maxWorkers,
+		providerLimits: providerLimits,
+		scaleUpWait:    defaultScaleUpWait,
+		scaleDownIdle:  defaultScaleDownIdle,
+		evaluate:       evaluate,
+		workers:        minWorkers,
+		inFlight:       map[string]int{},
+	}
+}
+
+// run evaluates the rules received until the context is canceled
+func (s *scheduler) run(ctx context.Context, rules <-chan ruleMessage, wg *sync.WaitGroup) {
+	defer wg.Done()
+	finished := make(chan []string)
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+	s.lastNeeded = time.Now()
+	for {
+		select {
+		case m := <-rules:
+			s.enqueue(queuedRule{message: m, providers: ruleProviders(m.rule), cost: ruleCost(m.rule), queued: time.Now()})
+		case providers := <-finished:
+			s.running--
+			for _, p := range providers {
+				s.inFlight[p]--
+			}
+		case now := <-ticker.C:
+			s.scale(now)
+		case <-ctx.Done():
+			s.log.V(5).Info("stopping rule scheduler")
+			return
+		}
+		s.dispatch(ctx, finished)
+	}
+}
+
+// enqueue adds a rule to the pending rules after the ones that are not more
+// expensive
+func (s *scheduler) enqueue(q queuedRule) {
+	i := len(s.pending)
+	for i > 0 && costRank[s.pending[i-1].cost] > costRank[q.cost] {
+		i--
+	}
+	s.pending = append(s.pending, queuedRule{})
+	copy(s.pending[i+1:], s.pending[i:])
+	s.pending[i] = q
+}
+
+// dispatch starts the first pending rules whose providers are under their
+// limit while there are free workers
+func (s *scheduler) dispatch(ctx context.Context, finished chan<- []string) {
+	remaining := s.pending[:0]
+	for i, q := range s.pending {
+		if s.running >= s.workers {
+			remaining = append(remaining, s.pending[i:]...)
+			break
+		}
+		if !s.available(q.providers) {
+			remaining = append(remaining, q)
+			continue
+		}
+		s.running++
+		for _, p := range q.providers {
+			s.inFlight[p]++
+		}
+		s.log.V(5).Info("taking rule", "ruleset", q.message.ruleSetName, "rule", q.message.rule.RuleID,
+			"cost", q.cost, "wait", time.Since(q.queued), "workers", s.workers, "running", s.running)
+		go func(q queuedRule) {
+			s.evaluate(ctx, s.log, q.message)
+			select {
+			case finished <- q.providers:
+			case <-ctx.Done():
+			}
+		}(q)
+	}
+	s.pending = remaining
+	if s.running >= s.workers {
+		s.lastNeeded = time.Now()
+	}
+}
+
+// available tells whether the providers are under their limit
+func (s *scheduler) available(providers []string) bool {
+	for _, p := range providers {
+		if limit := s.providerLimits[p]; limit > 0 && s.inFlight[p] >= limit {
+			return false
+		}
+	}
+	return true
+}
+
+// scale adds a worker when a rule that could be evaluated waited for one
+// longer than scaleUpWait, and removes one when they were not all used for
+// scaleDownIdle
+func (s *scheduler) scale(now time.Time) {
+	if s.running >= s.workers && s.workers < s.maxWorkers {
+		for _, q := range s.pending {
+			if s.available(q.providers) && now.Sub(q.queued) > s.scaleUpWait {
+				s.workers++
+				s.lastNeeded = now
+				s.log.V(3).Info("adding a rule worker", "workers", s.workers, "wait", now.Sub(q.queued))
+				return
+			}
+		}
+	}
+	if s.running < s.workers && s.workers > s.minWorkers && now.Sub(s.lastNeeded) > s.scaleDownIdle {
+		s.workers--
+		s.lastNeeded = now
+		s.log.V(3).Info("removing a rule worker", "workers", s.workers)
+	}
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+type testProviderConditional struct {
+	testConditional
+	provider string
+}
+
+func (t testProviderConditional) Providers() []string {
+	return []string{t.provider}
+}
+
+func Test_ruleProviders(t *testing.T) {
+	entry := func(provider string) ConditionEntry {
+		return ConditionEntry{ProviderSpecificConfig: testProviderConditional{provider: provider}}
+	}
+	rule := Rule{
+		When: AndCondition{Conditions: []ConditionEntry{
+			entry("java"),
+			{ProviderSpecificConfig: OrCondition{Conditions: []ConditionEntry{entry("builtin"), entry("java")}}},
+		}},
+		Unless: entry("python"),
+	}
+	if got := ruleProviders(rule); !reflect.DeepEqual(got, []string{"java", "builtin", "python"}) {
+		t.Errorf("unexpected providers %v", got)
+	}
+	if got := ruleProviders(Rule{When: createTestConditional(true, nil, false)}); len(got) != 0 {
+		t.Errorf("expected no providers, got %v", got)
+	}
+}
+
+func TestSchedulerProviderLimits(t *testing.T) {
+	release := make(chan struct{})
+	mutex := sync.Mutex{}
+	running, maxRunning := 0, 0
+	builtinDone := make(chan string, 3)
+	evaluate := func(ctx context.Context, log logr.Logger, m ruleMessage) {
+		if m.ruleSetName == "builtin" {
+			builtinDone <- m.rule.RuleID
+			return
+		}
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		<-release
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newScheduler(logr.Discard(), 2, 2, map[string]int{"python": 1}, evaluate)
+	rules := make(chan ruleMessage)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go s.run(ctx, rules, wg)
+
+	message := func(provider, id string) ruleMessage {
+		return ruleMessage{
+			ruleSetName: provider,
+			rule:        Rule{RuleMeta: RuleMeta{RuleID: id}, When: ConditionEntry{ProviderSpecificConfig: testProviderConditional{provider: provider}}},
+		}
+	}
+	for _, id := range []string{"python-1", "python-2", "python-3"} {
+		rules <- message("python", id)
+	}
+	for _, id := range []string{"builtin-1", "builtin-2", "builtin-3"} {
+		rules <- message("builtin", id)
+	}
+	// the builtin rules are evaluated while the python rules wait
+	for i := 0; i < 3; i++ {
+		select {
+		case <-builtinDone:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("builtin rules were not evaluated while python rules were waiting")
+		}
+	}
+	close(release)
+	cancel()
+	wg.Wait()
+	mutex.Lock()
+	defer mutex.Unlock()
+	if maxRunning != 1 {
+		t.Errorf("expected 1 python rule evaluated at once, got %d", maxRunning)
+	}
+}
+
+func TestSchedulerScale(t *testing.T) {
+	s := newScheduler(logr.Discard(), 1, 2, map[string]int{"python": 1}, nil)
+	now := time.Now()
+	s.running = 1
+	s.inFlight["python"] = 1
+	// a rule waiting for its provider doesn't need another worker
+	s.pending = []queuedRule{{providers: []string{"python"}, queued: now.Add(-time.Minute)}}
+	s.scale(now)
+	if s.workers != 1 {
+		t.Errorf("expected 1 worker for a rule waiting for its provider, got %d", s.workers)
+	}
+	s.pending = append(s.pending, queuedRule{providers: []string{"builtin"}, queued: now.Add(-time.Minute)})
+	s.scale(now)
+	s.scale(now)
+	if s.workers != 2 {
+		t.Errorf("expected 2 workers, got %d", s.workers)
+	}
+	s.running, s.pending = 0, nil
+	s.scale(now.Add(s.scaleDownIdle / 2))
+	if s.workers != 2 {
+		t.Errorf("expected the workers to be kept before they are idle, got %d", s.workers)
+	}
+	s.scale(now.Add(2 * s.scaleDownIdle))
+	s.scale(now.Add(4 * s.scaleDownIdle))
+	if s.workers != 1 {
+		t.Errorf("expected to scale down to 1 worker, got %d", s.workers)
+	}
+}
+
+func TestSchedulerEnqueue(t *testing.T) {
+	s := newScheduler(logr.Discard(), 1, 1, nil, nil)
+	for _, q := range []queuedRule{
+		{message: ruleMessage{rule: Rule{RuleMeta: RuleMeta{RuleID: "java-1"}}}, cost: HighCost},
+		{message: ruleMessage{rule: Rule{RuleMeta: RuleMeta{RuleID: "builtin-1"}}}, cost: LowCost},
+		{message: ruleMessage{rule: Rule{RuleMeta: RuleMeta{RuleID: "java-2"}}}, cost: HighCost},
+		{message: ruleMessage{rule: Rule{RuleMeta: RuleMeta{RuleID: "builtin-2"}}}, cost: LowCost},
+	} {
+		s.enqueue(q)
+	}
+	got := []string{}
+	for _, q := range s.pending {
+		got = append(got, q.message.rule.RuleID)
+	}
+	// the cheap rules are evaluated first, in the order they were received
+	if want := []string{"builtin-1", "builtin-2", "java-1", "java-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected pending rules %v, got %v", want, got)
+	}
+}
@@ -2,11 +2,11 @@ package engine
 
 import (
 	"fmt"
-	"net/url"
 	"regexp"
 
 	"github.com/go-logr/logr"
-	"go.lsp.dev/uri"
+	"github.com/konveyor/analyzer-lsp/fileuri"
+	"github.com/konveyor/analyzer-lsp/pathfilter"
 )
 
 const TemplateContextPathScopeKey = "konveyor.io/path-scope"
@@ -100,7 +100,7 @@ func (i *includedPathScope) FilterResponse(response IncidentContext) bool {
 		return false
 	}
 	for _, path := range i.paths {
-		if string(response.FileURI) != "" && response.FileURI.Filename() == path {
+		if string(response.FileURI) != "" && fileuri.Equal(string(response.FileURI), path) {
 			return false
 		}
 	}
@@ -146,9 +146,8 @@ func (e *excludedPathsScope) FilterResponse(response IncidentContext) bool {
 			e.log.V(5).Error(err, "invalid pattern", "pattern", path)
 			continue
 		}
-		u, err := url.ParseRequestURI(string(response.FileURI))
-		if err == nil && u.Scheme == uri.FileScheme && pattern.MatchString(response.FileURI.Filename()) {
-			e.log.V(5).Info("excluding the file", "file", response.FileURI.Filename(), "pattern", pattern)
+		if file := fileuri.Path(string(response.FileURI)); file != "" && pattern.MatchString(file) {
+			e.log.V(5).Info("excluding the file", "file", file, "pattern", pattern)
 			return true
 		}
 	}
@@ -161,3 +160,79 @@ func ExcludedPathsScope(paths []string, log logr.Logger) Scope {
 		log: log.WithName("excludedPathScope"),
 	}
 }
+
+type changedFilesScope struct {
+	includedPathScope
+}
+
+var _ Scope = &changedFilesScope{}
+
+func (c *changedFilesScope) Name() string {
+	return "ChangedFilesScope"
+}
+
+func (c *changedFilesScope) FilterResponse(response IncidentContext) bool {
+	// unlike included paths, no changed files leaves nothing to report
+	if len(c.paths) == 0 {
+		return true
+	}
+	return c.includedPathScope.FilterResponse(response)
+}
+
+// ChangedFilesScope limits the provider searches and the incidents to the
+// files changed in the application, the files are absolute paths.
+func ChangedFilesScope(files []string, log logr.Logger) Scope {
+	return &changedFilesScope{
+		includedPathScope: includedPathScope{
+			paths: files,
+			log:   log.WithName("changedFilesScope"),
+		},
+	}
+}
+
+type pathFilterScope struct {
+	filters []*pathfilter.Filter
+	log     logr.Logger
+}
+
+var _ Scope = &pathFilterScope{}
+
+func (p *pathFilterScope) Name() string {
+	return "PathFilterScope"
+}
+
+// The providers filter their searches themselves with the included and
+// excluded paths of their settings, nothing is added to the context.
+func (p *pathFilterScope) AddToContext(conditionCTX *ConditionContext) error {
+	return nil
+}
+
+func (p *pathFilterScope) FilterResponse(response IncidentContext) bool {
+	if response.FileURI == "" {
+		return false
+	}
+	contained := false
+	for _, filter := range p.filters {
+		if !filter.Contains(string(response.FileURI)) {
+			continue
+		}
+		contained = true
+		if filter.Match(string(response.FileURI)) {
+			return false
+		}
+	}
+	if contained {
+		p.log.V(5).Info("excluding the file", "file", response.FileURI)
+	}
+	return contained
+}
+
+// PathFilterScope filters the incidents in the files of the locations of the
+// filters that are not included in the analysis or are excluded from it.
+// Incidents outside of the locations, such as in dependencies, are kept.
+func PathFilterScope(filters []*pathfilter.Filter, log logr.Logger) Scope {
+	return &pathFilterScope{
+		filters: filters,
+		log:     log.WithName("pathFilterScope"),
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/pathfilter"
+	"go.lsp.dev/uri"
+)
+
+func TestChangedFilesScope(t *testing.T) {
+	scope := ChangedFilesScope([]string{"/app/src/Main.java"}, logr.Discard())
+	condCtx := ConditionContext{Template: map[string]ChainTemplate{}}
+	if err := scope.AddToContext(&condCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepaths := condCtx.Template[TemplateContextPathScopeKey].Filepaths; len(filepaths) != 1 || filepaths[0] != "/app/src/Main.java" {
+		t.Errorf("expected the provider searches to be limited to the changed files, got %v", filepaths)
+	}
+	if scope.FilterResponse(IncidentContext{FileURI: "file:///app/src/Main.java"}) {
+		t.Errorf("expected the incidents of a changed file to be kept")
+	}
+	if !scope.FilterResponse(IncidentContext{FileURI: "file:///app/src/Other.java"}) {
+		t.Errorf("expected the incidents of an unchanged file to be filtered")
+	}
+	if !scope.FilterResponse(IncidentContext{}) {
+		t.Errorf("expected the incidents without a file to be filtered")
+	}
+
+	scope = ChangedFilesScope([]string{}, logr.Discard())
+	if !scope.FilterResponse(IncidentContext{FileURI: "file:///app/src/Main.java"}) {
+		t.Errorf("expected every incident to be filtered without changed files")
+	}
+}
+
+func TestPathFilterScope(t *testing.T) {
+	filter, err := pathfilter.New("/app", []string{"src"}, []string{"src/test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := PathFilterScope([]*pathfilter.Filter{filter}, logr.Discard())
+	tests := []struct {
+		fileURI  string
+		filtered bool
+	}{
+		{fileURI: "file:///app/src/main/Main.java"},
+		{fileURI: "file:///app/src/test/MainTest.java", filtered: true},
+		{fileURI: "file:///app/pom.xml", filtered: true},
+		{fileURI: "file:///root/.m2/repository/lib.jar"},
+		{fileURI: ""},
+	}
+	for _, tt := range tests {
+		if filtered := scope.FilterResponse(IncidentContext{FileURI: uri.URI(tt.fileURI)}); filtered != tt.filtered {
+			t.Errorf("expected the incident of %q to be filtered %v, got %v", tt.fileURI, tt.filtered, filtered)
+		}
+	}
+}
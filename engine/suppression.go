@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/uri"
+)
+
+// suppressionPattern matches the konveyor:ignore comments of the common
+// languages, `// konveyor:ignore` suppresses the incidents of every rule on its
+// line and the next one, `# konveyor:ignore:rule-1,rule-2` the incidents of
+// the given rules.
+var suppressionPattern = regexp.MustCompile(`(?://|#|/\*|<!--|--|;|')\s*konveyor:ignore(?::([^\s*>]+))?`)
+
+// suppressions are the konveyor:ignore comments of the files of the incidents
+// of a run, by line number, every file is read once for all the rules.
+type suppressions struct {
+	files sync.Map
+}
+
+// suppressionFile are the comments of a file, read by the first rule with an
+// incident in it
+type suppressionFile struct {
+	once     sync.Once
+	comments map[int][]string
+}
+
+func newSuppressions() *suppressions {
+	return &suppressions{}
+}
+
+type suppressionsKey struct{}
+
+// withSuppressions returns a context sharing the suppressions with the rules
+// of the run
+func withSuppressions(ctx context.Context, s *suppressions) context.Context {
+	return context.WithValue(ctx, suppressionsKey{}, s)
+}
+
+// suppressionsFromContext returns the suppressions of the run, new ones when
+// the context has none
+func suppressionsFromContext(ctx context.Context) *suppressions {
+	if s, ok := ctx.Value(suppressionsKey{}).(*suppressions); ok {
+		return s
+	}
+	return newSuppressions()
+}
+
+// suppressed reports whether a konveyor:ignore comment on the line of the
+// incident, or on the line before it, suppresses the rule
+func (s *suppressions) suppressed(fileURI uri.URI, lineNumber *int, ruleID string) bool {
+	if lineNumber == nil || !strings.HasPrefix(string(fileURI), uri.FileScheme) {
+		return false
+	}
+	file := fileURI.Filename()
+	entry, _ := s.files.LoadOrStore(file, &suppressionFile{})
+	f := entry.(*suppressionFile)
+	f.once.Do(func() {
+		f.comments = readSuppressions(file)
+	})
+	comments := f.comments
+	for _, line := range []int{*lineNumber, *lineNumber - 1} {
+		ruleIDs, ok := comments[line]
+		if !ok {
+			continue
+		}
+		if len(ruleIDs) == 0 {
+			return true
+		}
+		for _, id := range ruleIDs {
+			if id == ruleID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readSuppressions returns the rule IDs of the konveyor:ignore comments of the
+// file by line number, starting at 1, no rule IDs meaning every rule.
+func readSuppressions(file string) map[int][]string {
+	comments := map[int][]string{}
+	f, err := os.Open(file)
+	if err != nil {
+		return comments
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if !strings.Contains(line, "konveyor:ignore") {
+			continue
+		}
+		match := suppressionPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		ruleIDs := []string{}
+		for _, id := range strings.Split(match[1], ",") {
+			if id != "" {
+				ruleIDs = append(ruleIDs, id)
+			}
+		}
+		comments[lineNumber] = ruleIDs
+	}
+	return comments
+}
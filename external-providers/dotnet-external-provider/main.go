@@ -13,11 +13,12 @@ import (
 )
 
 var (
-	port      = flag.Int("port", 0, "Port must be set")
-	logLevel  = flag.Int("log-level", 5, "Level to log")
-	certFile  = flag.String("certFile", "", "Path to the cert file")
-	keyFile   = flag.String("keyFile", "", "Path to the key file")
-	secretKey = flag.String("secretKey", "", "Secret Key value")
+	port        = flag.Int("port", 0, "Port must be set unless a socket is")
+	logLevel    = flag.Int("log-level", 5, "Level to log")
+	certFile    = flag.String("certFile", "", "Path to the cert file")
+	keyFile     = flag.String("keyFile", "", "Path to the key file")
+	secretKey   = flag.String("secretKey", "", "Secret Key value")
+	serverFlags = provider.NewServerFlags(flag.CommandLine)
 )
 
 func main() {
@@ -34,8 +35,8 @@ func main() {
 	if logLevel != nil && *logLevel != 5 {
 		logrusLog.SetLevel(logrus.Level(*logLevel))
 	}
-	if port == nil || *port == 0 {
-		log.Error(fmt.Errorf("port unspecified"), "port number must be specified")
+	if (port == nil || *port == 0) && serverFlags.Socket == "" {
+		log.Error(fmt.Errorf("port unspecified"), "port number or socket must be specified")
 		panic(1)
 	}
 
@@ -55,7 +56,7 @@ func main() {
 		secret = *secretKey
 	}
 
-	s := provider.NewServer(client, *port, c, k, secret, log)
+	s := provider.NewServer(client, *port, c, k, secret, log, serverFlags.Options()...)
 	ctx := context.TODO()
 	s.Start(ctx)
 }
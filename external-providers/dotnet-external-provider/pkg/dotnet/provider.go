@@ -6,10 +6,11 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/process"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/swaggest/openapi-go/openapi3"
 
@@ -18,6 +19,15 @@ import (
 	"go.lsp.dev/uri"
 )
 
+const (
+	// solutionFileConfigKey is the solution analyzed, relative to the location,
+	// the one at the root of the location by default
+	solutionFileConfigKey = "solutionFile"
+	// targetFrameworksConfigKey limits the target frameworks the projects are
+	// analyzed for
+	targetFrameworksConfigKey = "targetFrameworks"
+)
+
 type dotnetProvider struct {
 	Log logr.Logger
 }
@@ -85,9 +95,6 @@ func (p *dotnetProvider) Init(ctx context.Context, log logr.Logger, config provi
 
 	ctx, cancelFunc := context.WithCancel(ctx)
 	log = log.WithValues("provider", "dotnet")
-	sentLog := &sent{l: log.WithValues("stdio", "sent")}
-	recvLog := &received{l: log.WithValues("stdio", "recv")}
-	handlerLog := log.WithValues("stdio", "replyHandler")
 
 	lspServerPath, ok := config.ProviderSpecificConfig[provider.LspServerPathConfigKey].(string)
 	if !ok || lspServerPath == "" {
@@ -95,24 +102,90 @@ func (p *dotnetProvider) Init(ctx context.Context, log logr.Logger, config provi
 		return nil, provider.InitConfig{}, fmt.Errorf("invalid lspServerPath provided, unable to init dotnet provider")
 	}
 
-	cmd := exec.CommandContext(ctx, lspServerPath)
-	cmd.Dir = codePath // At a minimum, 'csharp-ls' doesn't respect URI @initialization
-	stdin, err := cmd.StdinPipe()
+	// the projects of the solution are analyzed for each of their target
+	// frameworks, code under #if differs between them
+	solutionFile, _ := config.ProviderSpecificConfig[solutionFileConfigKey].(string)
+	solution, projects, err := findProjects(codePath, solutionFile)
 	if err != nil {
 		cancelFunc()
 		return nil, provider.InitConfig{}, err
 	}
+	configuredTFMs := []string{}
+	if values, ok := config.ProviderSpecificConfig[targetFrameworksConfigKey].([]interface{}); ok {
+		for _, v := range values {
+			if tfm, ok := v.(string); ok {
+				configuredTFMs = append(configuredTFMs, strings.ToLower(tfm))
+			}
+		}
+	}
+	tfms := solutionTargetFrameworks(projects, configuredTFMs)
+	if len(tfms) == 0 {
+		if len(configuredTFMs) > 0 {
+			cancelFunc()
+			return nil, provider.InitConfig{}, fmt.Errorf("no project targets the target frameworks %v", configuredTFMs)
+		}
+		tfms = []string{""}
+	}
+	log.Info("found projects", "solution", solution, "projects", len(projects), "targetFrameworks", tfms)
+
+	servers := []*languageServer{}
+	for _, tfm := range tfms {
+		server, err := startLanguageServer(ctx, log, lspServerPath, codePath, solution, tfm)
+		if err != nil {
+			cancelFunc()
+			for _, s := range servers {
+				s.cmd.Wait()
+			}
+			return nil, provider.InitConfig{}, err
+		}
+		servers = append(servers, server)
+	}
+
+	return &dotnetServiceClient{
+		servers:    servers,
+		projects:   projects,
+		ctx:        ctx,
+		cancelFunc: cancelFunc,
+		log:        log,
+		config:     config,
+	}, provider.InitConfig{}, nil
+}
+
+// startLanguageServer starts a csharp-ls loading the solution, or the projects
+// of the location without one, for a target framework. MSBuild reads the
+// environment as properties, TargetFramework selects the framework of the
+// projects targeting several.
+func startLanguageServer(ctx context.Context, log logr.Logger, lspServerPath, codePath, solution, tfm string) (*languageServer, error) {
+	if tfm != "" {
+		log = log.WithValues("targetFramework", tfm)
+	}
+	sentLog := &sent{l: log.WithValues("stdio", "sent")}
+	recvLog := &received{l: log.WithValues("stdio", "recv")}
+	handlerLog := log.WithValues("stdio", "replyHandler")
+
+	args := []string{}
+	if solution != "" {
+		args = append(args, "--solution", solution)
+	}
+	group := process.Command(ctx, lspServerPath, args...)
+	cmd := group.Cmd
+	cmd.Dir = codePath // At a minimum, 'csharp-ls' doesn't respect URI @initialization
+	if tfm != "" {
+		cmd.Env = append(os.Environ(), "TargetFramework="+tfm)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
 	clientWriter := io.MultiWriter(stdin, sentLog)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		cancelFunc()
-		return nil, provider.InitConfig{}, err
+		return nil, err
 	}
 	clientReader := io.TeeReader(stdout, recvLog)
-	if err := cmd.Start(); err != nil {
+	if err := group.Start(); err != nil {
 		log.Error(err, "failed to start language server process")
-		cancelFunc()
-		return nil, provider.InitConfig{}, err
+		return nil, err
 	}
 	log.V(2).Info("language server started")
 
@@ -178,20 +251,16 @@ func (p *dotnetProvider) Init(ctx context.Context, log logr.Logger, config provi
 
 	if err := conn.Notify(ctx, protocol.MethodInitialized, &protocol.InitializedParams{}); err != nil {
 		log.Error(err, "initialized notification failed")
-		cancelFunc()
-		return nil, provider.InitConfig{}, err
+		return nil, err
 	}
 
 	log.Info("waiting for language server to load the project")
 	<-serverChannel
 	log.Info("project loaded")
 
-	return &dotnetServiceClient{
-		rpc:        conn,
-		ctx:        ctx,
-		cancelFunc: cancelFunc,
-		cmd:        cmd,
-		log:        log,
-		config:     config,
-	}, provider.InitConfig{}, nil
+	return &languageServer{
+		rpc:             conn,
+		cmd:             cmd,
+		targetFramework: tfm,
+	}, nil
 }
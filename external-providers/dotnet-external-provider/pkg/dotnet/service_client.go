@@ -21,22 +21,36 @@ import (
 )
 
 type dotnetServiceClient struct {
-	rpc        jsonrpc2.Conn
+	// servers analyze the projects for each of their target frameworks
+	servers    []*languageServer
+	projects   []dotnetProject
 	ctx        context.Context
 	cancelFunc context.CancelFunc
-	cmd        *exec.Cmd
 	log        logr.Logger
 
 	config provider.InitConfig
 }
 
+// languageServer is a csharp-ls that loaded the projects for a target
+// framework, empty when the projects don't declare any
+type languageServer struct {
+	rpc             jsonrpc2.Conn
+	cmd             *exec.Cmd
+	targetFramework string
+}
+
 var _ provider.ServiceClient = &dotnetServiceClient{}
 
 func (d *dotnetServiceClient) Stop() {
 	d.cancelFunc()
-	d.cmd.Wait()
+	for _, s := range d.servers {
+		s.cmd.Wait()
+	}
 }
 
+// Evaluate finds the incidents of every target framework, an incident found for
+// several of them is reported once with the targetFramework variable listing
+// them.
 func (d *dotnetServiceClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
 	var cond dotnetCondition
 	err := yaml.Unmarshal(conditionInfo, &cond)
@@ -54,11 +68,55 @@ func (d *dotnetServiceClient) Evaluate(ctx context.Context, cap string, conditio
 		return provider.ProviderEvaluateResponse{}, fmt.Errorf("unable to get namespace for query")
 	}
 
-	symbols := d.GetAllSymbols(query)
+	incidents := []provider.IncidentContext{}
+	frameworks := map[string][]string{}
+	for _, server := range d.servers {
+		for _, incident := range d.evaluate(server, namespace, query) {
+			if !d.targets(incident.FileURI, server.targetFramework) {
+				continue
+			}
+			key := fmt.Sprintf("%s:%d", incident.FileURI, *incident.LineNumber)
+			if _, ok := frameworks[key]; !ok {
+				incidents = append(incidents, incident)
+			}
+			if server.targetFramework != "" {
+				frameworks[key] = append(frameworks[key], server.targetFramework)
+			} else {
+				frameworks[key] = []string{}
+			}
+		}
+	}
+	for _, incident := range incidents {
+		if tfms := frameworks[fmt.Sprintf("%s:%d", incident.FileURI, *incident.LineNumber)]; len(tfms) > 0 {
+			incident.Variables["targetFramework"] = strings.Join(tfms, ",")
+		}
+	}
+	return provider.ProviderEvaluateResponse{
+		Matched:   len(incidents) > 0,
+		Incidents: incidents,
+	}, nil
+}
+
+// targets reports whether the project of a file targets the framework, files
+// outside of the projects and projects without target frameworks target all
+// of them
+func (d *dotnetServiceClient) targets(fileURI uri.URI, tfm string) bool {
+	if tfm == "" || !strings.HasPrefix(string(fileURI), uri.FileScheme) {
+		return true
+	}
+	project, ok := projectOf(d.projects, fileURI.Filename())
+	if !ok || len(project.TargetFrameworks) == 0 {
+		return true
+	}
+	return contains(project.TargetFrameworks, tfm)
+}
+
+func (d *dotnetServiceClient) evaluate(server *languageServer, namespace, query string) []provider.IncidentContext {
+	symbols := d.GetAllSymbols(server, query)
 	incidents := []provider.IncidentContext{}
 	for _, s := range symbols {
 		if s.Kind == protocol.SymbolKindMethod {
-			references := d.GetAllReferences(s)
+			references := d.GetAllReferences(server, s)
 			for _, ref := range references {
 				if strings.Contains(ref.URI.Filename(), d.config.Location) {
 					lineNumber := int(ref.Range.Start.Line)
@@ -72,6 +130,7 @@ func (d *dotnetServiceClient) Evaluate(ctx context.Context, cap string, conditio
 							StartPosition: provider.Position{Line: float64(lineNumber)},
 							EndPosition:   provider.Position{Line: float64(lineNumber)},
 						},
+						MatchedText: referenceText(ref),
 					})
 				}
 			}
@@ -84,25 +143,25 @@ func (d *dotnetServiceClient) Evaluate(ctx context.Context, cap string, conditio
 		regex, err := regexp.Compile(query)
 		if err != nil {
 			// Not a valid regex, can't do anything more
-			return provider.ProviderEvaluateResponse{Matched: false}, nil
+			return nil
 		}
 		var positions []interface{}
 		positions, err = parallelWalk(d.config.Location, regex)
 		if err != nil {
 			d.log.Error(err, "failed parallel walk")
-			return provider.ProviderEvaluateResponse{Matched: false}, nil
+			return nil
 		}
 		for _, position := range positions {
 			d.log.V(5).Info("got position", "position", position)
 			res := []protocol.Location{}
 			switch position.(type) {
 			case protocol.ReferenceParams:
-				_, err := d.rpc.Call(d.ctx, protocol.MethodTextDocumentReferences, position, &res)
+				_, err := server.rpc.Call(d.ctx, protocol.MethodTextDocumentReferences, position, &res)
 				if err != nil {
 					d.log.Error(err, "failed to get references")
 				}
 			case protocol.TextDocumentPositionParams:
-				_, err := d.rpc.Call(d.ctx, "textDocument/definition", position, &res)
+				_, err := server.rpc.Call(d.ctx, "textDocument/definition", position, &res)
 				if err != nil {
 					d.log.Error(err, "problem getting definition")
 					continue
@@ -144,10 +203,20 @@ func (d *dotnetServiceClient) Evaluate(ctx context.Context, cap string, conditio
 
 		}
 	}
-	return provider.ProviderEvaluateResponse{
-		Matched:   len(incidents) > 0,
-		Incidents: incidents,
-	}, nil
+	return incidents
+}
+
+// referenceText returns the text of a reference in its file, the matched text
+// of its incident
+func referenceText(ref protocol.Location) string {
+	text, err := provider.LocationText(ref.URI.Filename(), provider.Location{
+		StartPosition: provider.Position{Line: float64(ref.Range.Start.Line), Character: float64(ref.Range.Start.Character)},
+		EndPosition:   provider.Position{Line: float64(ref.Range.End.Line), Character: float64(ref.Range.End.Character)},
+	})
+	if err != nil {
+		return ""
+	}
+	return text
 }
 
 func processFile(path string, regex *regexp.Regexp, positionsChan chan<- interface{}, wg *sync.WaitGroup) {
@@ -231,13 +300,13 @@ func parallelWalk(location string, regex *regexp.Regexp) ([]interface{}, error)
 	return positions, nil
 }
 
-func (d *dotnetServiceClient) GetAllSymbols(query string) []protocol.SymbolInformation {
+func (d *dotnetServiceClient) GetAllSymbols(server *languageServer, query string) []protocol.SymbolInformation {
 	wsp := &protocol.WorkspaceSymbolParams{
 		Query: query,
 	}
 
 	var refs []protocol.SymbolInformation
-	_, err := d.rpc.Call(context.TODO(), protocol.MethodWorkspaceSymbol, wsp, &refs)
+	_, err := server.rpc.Call(context.TODO(), protocol.MethodWorkspaceSymbol, wsp, &refs)
 	if err != nil {
 		d.log.Error(err, "failed to get workspace symbols")
 	}
@@ -245,7 +314,7 @@ func (d *dotnetServiceClient) GetAllSymbols(query string) []protocol.SymbolInfor
 	return refs
 }
 
-func (d *dotnetServiceClient) GetAllReferences(symbol protocol.SymbolInformation) []protocol.Location {
+func (d *dotnetServiceClient) GetAllReferences(server *languageServer, symbol protocol.SymbolInformation) []protocol.Location {
 	params := &protocol.ReferenceParams{
 		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
 			TextDocument: protocol.TextDocumentIdentifier{
@@ -256,7 +325,7 @@ func (d *dotnetServiceClient) GetAllReferences(symbol protocol.SymbolInformation
 	}
 
 	res := []protocol.Location{}
-	_, err := d.rpc.Call(d.ctx, protocol.MethodTextDocumentReferences, params, &res)
+	_, err := server.rpc.Call(d.ctx, protocol.MethodTextDocumentReferences, params, &res)
 	if err != nil {
 		d.log.Error(err, "failed to get references")
 	}
@@ -0,0 +1,225 @@
+package dotnet
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// dotnetProject is a project of the analyzed application with the target
+// frameworks it is built for
+type dotnetProject struct {
+	Name             string
+	Path             string
+	TargetFrameworks []string
+}
+
+// Dir is the directory of the project, the files under it belong to it
+func (p dotnetProject) Dir() string {
+	return filepath.Dir(p.Path)
+}
+
+// solutionProjectRegex matches the projects of a solution such as
+// Project("{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}") = "Web", "src\Web\Web.csproj", "{5B3C1D2E-...}"
+var solutionProjectRegex = regexp.MustCompile(`^Project\("\{[^}]*\}"\)\s*=\s*"([^"]*)"\s*,\s*"([^"]*)"`)
+
+var projectExtensions = []string{".csproj", ".vbproj", ".fsproj"}
+
+func isProjectFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range projectExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// findSolution returns the solution file to analyze, the configured one or
+// else the one at the root of the location. It is empty when there is none.
+func findSolution(location, configured string) (string, error) {
+	if configured != "" {
+		if !filepath.IsAbs(configured) {
+			configured = filepath.Join(location, configured)
+		}
+		if _, err := os.Stat(configured); err != nil {
+			return "", fmt.Errorf("unable to find solution file %s: %w", configured, err)
+		}
+		return configured, nil
+	}
+	solutions, err := filepath.Glob(filepath.Join(location, "*.sln"))
+	if err != nil || len(solutions) == 0 {
+		return "", err
+	}
+	sort.Strings(solutions)
+	return solutions[0], nil
+}
+
+// parseSolution returns the projects of a solution, solution folders and
+// projects that don't exist are left out
+func parseSolution(solution string) ([]dotnetProject, error) {
+	f, err := os.Open(solution)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	projects := []dotnetProject{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		match := solutionProjectRegex.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		// solutions are written on windows, the paths use backslashes
+		path := filepath.Join(filepath.Dir(solution), filepath.FromSlash(strings.ReplaceAll(match[2], `\`, "/")))
+		if !isProjectFile(path) {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		projects = append(projects, dotnetProject{Name: match[1], Path: path})
+	}
+	return projects, scanner.Err()
+}
+
+// discoverProjects returns the projects under the location when there is no
+// solution, build outputs are skipped
+func discoverProjects(location string) ([]dotnetProject, error) {
+	projects := []dotnetProject{}
+	err := filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case "bin", "obj", ".git", "node_modules":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isProjectFile(path) {
+			projects = append(projects, dotnetProject{
+				Name: strings.TrimSuffix(d.Name(), filepath.Ext(d.Name())),
+				Path: path,
+			})
+		}
+		return nil
+	})
+	return projects, err
+}
+
+// findProjects returns the solution and the projects of the location along
+// with their target frameworks
+func findProjects(location, configuredSolution string) (string, []dotnetProject, error) {
+	solution, err := findSolution(location, configuredSolution)
+	if err != nil {
+		return "", nil, err
+	}
+	var projects []dotnetProject
+	if solution != "" {
+		projects, err = parseSolution(solution)
+	} else {
+		projects, err = discoverProjects(location)
+	}
+	if err != nil {
+		return solution, nil, err
+	}
+	for i := range projects {
+		projects[i].TargetFrameworks = projectTargetFrameworks(projects[i].Path)
+	}
+	return solution, projects, nil
+}
+
+type msbuildProject struct {
+	PropertyGroups []struct {
+		TargetFramework        string `xml:"TargetFramework"`
+		TargetFrameworks       string `xml:"TargetFrameworks"`
+		TargetFrameworkVersion string `xml:"TargetFrameworkVersion"`
+	} `xml:"PropertyGroup"`
+}
+
+// projectTargetFrameworks returns the target framework monikers of a
+// project, such as net48 and net8.0 for <TargetFrameworks>net48;net8.0</TargetFrameworks>.
+// The TargetFrameworkVersion v4.8 of the projects of the .NET Framework is
+// net48.
+func projectTargetFrameworks(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	project := msbuildProject{}
+	if err := xml.Unmarshal(content, &project); err != nil {
+		return nil
+	}
+	tfms := []string{}
+	add := func(values string) {
+		for _, tfm := range strings.Split(values, ";") {
+			tfm = strings.ToLower(strings.TrimSpace(tfm))
+			// properties such as $(DefaultTargetFrameworks) can't be evaluated
+			if tfm == "" || strings.Contains(tfm, "$(") {
+				continue
+			}
+			if !contains(tfms, tfm) {
+				tfms = append(tfms, tfm)
+			}
+		}
+	}
+	for _, group := range project.PropertyGroups {
+		add(group.TargetFrameworks)
+		add(group.TargetFramework)
+		if version := strings.TrimPrefix(strings.TrimSpace(group.TargetFrameworkVersion), "v"); version != "" {
+			add("net" + strings.ReplaceAll(version, ".", ""))
+		}
+	}
+	return tfms
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// solutionTargetFrameworks returns the target frameworks of the projects, in
+// the order of the projects, limited to the configured ones if any
+func solutionTargetFrameworks(projects []dotnetProject, configured []string) []string {
+	tfms := []string{}
+	for _, p := range projects {
+		for _, tfm := range p.TargetFrameworks {
+			if len(configured) > 0 && !contains(configured, tfm) {
+				continue
+			}
+			if !contains(tfms, tfm) {
+				tfms = append(tfms, tfm)
+			}
+		}
+	}
+	return tfms
+}
+
+// projectOf returns the project of a file, the one with the deepest directory
+// containing it
+func projectOf(projects []dotnetProject, file string) (dotnetProject, bool) {
+	found := false
+	var project dotnetProject
+	for _, p := range projects {
+		dir := p.Dir()
+		if file != dir && !strings.HasPrefix(file, dir+string(filepath.Separator)) {
+			continue
+		}
+		if !found || len(dir) > len(project.Dir()) {
+			project, found = p, true
+		}
+	}
+	return project, found
+}
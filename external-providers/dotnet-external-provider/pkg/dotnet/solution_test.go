@@ -0,0 +1,54 @@
+package dotnet
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindProjects(t *testing.T) {
+	location, err := filepath.Abs(filepath.Join("testdata", "solution"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solution, projects, err := findProjects(location, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if solution != filepath.Join(location, "App.sln") {
+		t.Errorf("unexpected solution %s", solution)
+	}
+	expected := []dotnetProject{
+		{Name: "Web", Path: filepath.Join(location, "src", "Web", "Web.csproj"), TargetFrameworks: []string{"net48", "net8.0"}},
+		{Name: "Legacy", Path: filepath.Join(location, "src", "Legacy", "Legacy.csproj"), TargetFrameworks: []string{"net48"}},
+	}
+	if !reflect.DeepEqual(projects, expected) {
+		t.Errorf("expected projects %v, got %v", expected, projects)
+	}
+	if tfms := solutionTargetFrameworks(projects, nil); !reflect.DeepEqual(tfms, []string{"net48", "net8.0"}) {
+		t.Errorf("unexpected target frameworks %v", tfms)
+	}
+	if tfms := solutionTargetFrameworks(projects, []string{"net8.0"}); !reflect.DeepEqual(tfms, []string{"net8.0"}) {
+		t.Errorf("unexpected configured target frameworks %v", tfms)
+	}
+	if p, ok := projectOf(projects, filepath.Join(location, "src", "Legacy", "Controllers", "Home.cs")); !ok || p.Name != "Legacy" {
+		t.Errorf("unexpected project %v of a file", p)
+	}
+	if _, ok := projectOf(projects, filepath.Join(location, "src", "WebApi", "Api.cs")); ok {
+		t.Errorf("expected no project for a file outside of the projects")
+	}
+
+	// without a solution the projects are discovered, build outputs are skipped
+	_, projects, err = findProjects(filepath.Join(location, "tests"), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(projects) != 1 || projects[0].Name != "Web.Tests" || !reflect.DeepEqual(projects[0].TargetFrameworks, []string{"net8.0"}) {
+		t.Errorf("unexpected discovered projects %v", projects)
+	}
+
+	if _, _, err := findProjects(location, "Other.sln"); err == nil {
+		t.Errorf("expected an error for a missing solution file")
+	}
+}
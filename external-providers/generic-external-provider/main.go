@@ -13,11 +13,12 @@ import (
 )
 
 var (
-	port          = flag.Int("port", 0, "Port must be set")
+	port          = flag.Int("port", 0, "Port must be set unless a socket is")
 	lspServerName = flag.String("name", "", "lsp server name")
 	certFile      = flag.String("certFile", "", "Path to the cert file")
 	keyFile       = flag.String("keyFile", "", "Path to the key file")
 	secretKey     = flag.String("secretKey", "", "Secret Key value")
+	serverFlags   = provider.NewServerFlags(flag.CommandLine)
 )
 
 func main() {
@@ -52,8 +53,8 @@ func main() {
 
 	client := generic_external_provider.NewGenericProvider(*lspServerName, log)
 
-	if port == nil || *port == 0 {
-		panic(fmt.Errorf("must pass in the port for the external provider"))
+	if (port == nil || *port == 0) && serverFlags.Socket == "" {
+		panic(fmt.Errorf("must pass in the port or the socket for the external provider"))
 	}
 
 	var c string
@@ -72,7 +73,7 @@ func main() {
 		secret = *secretKey
 	}
 
-	s := provider.NewServer(client, *port, c, k, secret, log)
+	s := provider.NewServer(client, *port, c, k, secret, log, serverFlags.Options()...)
 	ctx := context.TODO()
 	s.Start(ctx)
 }
@@ -6,7 +6,9 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/konveyor/analyzer-lsp/external-providers/generic-external-provider/pkg/server_configurations/generic"
 	"github.com/konveyor/analyzer-lsp/external-providers/generic-external-provider/pkg/server_configurations/nodejs"
+	"github.com/konveyor/analyzer-lsp/external-providers/generic-external-provider/pkg/server_configurations/php"
 	"github.com/konveyor/analyzer-lsp/external-providers/generic-external-provider/pkg/server_configurations/pylsp"
+	"github.com/konveyor/analyzer-lsp/external-providers/generic-external-provider/pkg/server_configurations/ruby"
 	yaml "github.com/konveyor/analyzer-lsp/external-providers/generic-external-provider/pkg/server_configurations/yaml_language_server"
 	base "github.com/konveyor/analyzer-lsp/lsp/base_service_client"
 	"github.com/konveyor/analyzer-lsp/provider"
@@ -23,4 +25,6 @@ var SupportedLanguages = map[string]ServiceClientBuilder{
 	"pylsp":                &pylsp.PythonServiceClientBuilder{},
 	"yaml_language_server": &yaml.YamlServiceClientBuilder{},
 	"nodejs":               &nodejs.NodeServiceClientBuilder{},
+	"php":                  &php.PhpServiceClientBuilder{},
+	"ruby":                 &ruby.RubyServiceClientBuilder{},
 }
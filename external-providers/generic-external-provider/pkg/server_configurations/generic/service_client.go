@@ -123,6 +123,15 @@ func (g *GenericServiceClientBuilder) GetGenericServiceClientCapabilities(log lo
 			Fn:         serviceClientFn(base.EvaluateReferenced[*GenericServiceClient]),
 		})
 	}
+	importsCap, err := provider.ToProviderCap(r, log, base.ImportsCondition{}, "imports")
+	if err != nil {
+		log.Error(err, "unable to get imports cap")
+	} else {
+		caps = append(caps, base.LSPServiceClientCapability{
+			Capability: importsCap,
+			Fn:         serviceClientFn(base.EvaluateImports[*GenericServiceClient]),
+		})
+	}
 	depCap, err := provider.ToProviderCap(r, log, base.NoOpCondition{}, "dependency")
 	if err != nil {
 		log.Error(err, "unable to get referenced cap")
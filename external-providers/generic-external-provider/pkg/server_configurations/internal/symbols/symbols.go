@@ -0,0 +1,45 @@
+// Package symbols holds what the symbol search helpers of the languages
+// matching fully qualified names with patterns have in common.
+package symbols
+
+import (
+	"regexp"
+	"sync"
+)
+
+// Patterns turns the patterns of the rules into the query sent to the server
+// and the regex matching the fully qualified names of the symbols it answers.
+type Patterns struct {
+	// TrailingName matches the name ending a pattern in its first group, the
+	// servers fuzzy match it
+	TrailingName *regexp.Regexp
+	// Expr returns the expression of the regex matching the fully qualified
+	// names of the pattern
+	Expr func(pattern string) string
+
+	// regexes caches the regexes of the patterns, every symbol the server
+	// answers is matched
+	regexes sync.Map
+}
+
+// Query returns the query of the workspace symbols of the pattern, empty to
+// ask for every symbol when the name ending it is a regex
+func (p *Patterns) Query(pattern string) string {
+	if m := p.TrailingName.FindStringSubmatch(pattern); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// Regex returns the regex matching the fully qualified names of the pattern
+func (p *Patterns) Regex(pattern string) (*regexp.Regexp, error) {
+	if r, ok := p.regexes.Load(pattern); ok {
+		return r.(*regexp.Regexp), nil
+	}
+	regex, err := regexp.Compile(p.Expr(pattern))
+	if err != nil {
+		return nil, err
+	}
+	p.regexes.Store(pattern, regex)
+	return regex, nil
+}
@@ -111,6 +111,15 @@ func (n *NodeServiceClientBuilder) GetGenericServiceClientCapabilities(log logr.
 			Fn:         serviceClientFn((*NodeServiceClient).EvaluateReferenced),
 		})
 	}
+	importsCap, err := provider.ToProviderCap(r, log, base.ImportsCondition{}, "imports")
+	if err != nil {
+		log.Error(err, "unable to get imports cap")
+	} else {
+		caps = append(caps, base.LSPServiceClientCapability{
+			Capability: importsCap,
+			Fn:         serviceClientFn(base.EvaluateImports[*NodeServiceClient]),
+		})
+	}
 	return caps
 }
 
@@ -156,7 +165,7 @@ func (sc *NodeServiceClient) EvaluateReferenced(ctx context.Context, cap string,
 
 	// time.Sleep(2 * time.Second)
 
-	symbols := sc.GetAllDeclarations(ctx, sc.BaseConfig.WorkspaceFolders, query)
+	symbols, fallback := sc.GetDeclarations(ctx, sc.BaseConfig.WorkspaceFolders, query)
 
 	// fmt.Printf("symbols: %v\n", symbols)
 
@@ -202,7 +211,12 @@ func (sc *NodeServiceClient) EvaluateReferenced(ctx context.Context, cap string,
 				Variables: map[string]interface{}{
 					"file": ref.URI,
 				},
+				MatchedText: base.RangeText(u, ref.Range),
+			}
+			if fallback {
+				incident.Variables[base.TextSearchFallbackVariable] = true
 			}
+			sc.AddCodeActions(ctx, &incident, ref.URI, ref.Range)
 			b, _ := json.Marshal(incident)
 
 			incidentsMap[string(b)] = incident
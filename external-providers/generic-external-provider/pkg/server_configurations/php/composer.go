@@ -0,0 +1,224 @@
+package php
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+const (
+	composerJSON = "composer.json"
+	composerLock = "composer.lock"
+
+	phpDownloadableDepSourceLabel = "downloadable"
+)
+
+// composerManifest is the part of a composer.json listing the packages of
+// the application
+type composerManifest struct {
+	Require    map[string]string `json:"require"`
+	RequireDev map[string]string `json:"require-dev"`
+}
+
+// composerPackage is a package resolved in a composer.lock
+type composerPackage struct {
+	Name    string            `json:"name"`
+	Version string            `json:"version"`
+	Type    string            `json:"type"`
+	License []string          `json:"license"`
+	Require map[string]string `json:"require"`
+	Source  struct {
+		Type      string `json:"type"`
+		URL       string `json:"url"`
+		Reference string `json:"reference"`
+	} `json:"source"`
+	Dist struct {
+		Type      string `json:"type"`
+		URL       string `json:"url"`
+		Reference string `json:"reference"`
+	} `json:"dist"`
+
+	// dev is set for the packages only required for the development
+	dev bool
+}
+
+// composerLockfile is the part of a composer.lock listing the resolved
+// packages
+type composerLockfile struct {
+	Packages    []composerPackage `json:"packages"`
+	PackagesDev []composerPackage `json:"packages-dev"`
+}
+
+// composerProject are the packages of an application: the ones it requires
+// and the ones resolved in its lockfile, by name
+type composerProject struct {
+	required []string
+	packages map[string]*composerPackage
+}
+
+// isPlatformPackage reports whether the package is PHP, an extension or a
+// library of the platform, which composer does not install
+func isPlatformPackage(name string) bool {
+	return !strings.Contains(name, "/")
+}
+
+// loadComposerProject reads the composer.json and the composer.lock of the
+// application in dir, the packages are the required ones with their
+// constraints as version when there is no lockfile. It returns the URI of
+// the manifest the dependencies are reported in.
+func loadComposerProject(dir string) (*composerProject, uri.URI, error) {
+	manifestPath := filepath.Join(dir, composerJSON)
+	content, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("no composer.json in %s: %w", dir, err)
+	}
+	var manifest composerManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, "", fmt.Errorf("unable to parse %s: %w", manifestPath, err)
+	}
+	project := &composerProject{packages: map[string]*composerPackage{}}
+	for _, requires := range []map[string]string{manifest.Require, manifest.RequireDev} {
+		for _, name := range sortedNames(requires) {
+			if !isPlatformPackage(name) {
+				project.required = append(project.required, name)
+			}
+		}
+	}
+
+	lockPath := filepath.Join(dir, composerLock)
+	content, err = os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		for _, name := range project.required {
+			constraint, ok := manifest.Require[name]
+			project.packages[name] = &composerPackage{Name: name, Version: constraint, dev: !ok}
+			if !ok {
+				project.packages[name].Version = manifest.RequireDev[name]
+			}
+		}
+		return project, uri.File(manifestPath), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	var lock composerLockfile
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, "", fmt.Errorf("unable to parse %s: %w", lockPath, err)
+	}
+	for i := range lock.Packages {
+		project.packages[lock.Packages[i].Name] = &lock.Packages[i]
+	}
+	for i := range lock.PackagesDev {
+		lock.PackagesDev[i].dev = true
+		project.packages[lock.PackagesDev[i].Name] = &lock.PackagesDev[i]
+	}
+	return project, uri.File(manifestPath), nil
+}
+
+// dep returns the dependency of a package
+func (p *composerPackage) dep(indirect bool) provider.Dep {
+	d := provider.Dep{
+		Name:     p.Name,
+		Version:  strings.TrimPrefix(p.Version, "v"),
+		Indirect: indirect,
+		Labels: []string{
+			labels.AsString(provider.DepLanguageLabel, "php"),
+		},
+		Extras: map[string]interface{}{},
+	}
+	// packages of a path repository are part of the application
+	if p.Dist.Type != "path" {
+		d.Labels = append(d.Labels, labels.AsString(provider.DepSourceLabel, phpDownloadableDepSourceLabel))
+	}
+	if len(p.License) > 0 {
+		d.Labels = append(d.Labels, provider.DepLicenseLabels(p.License)...)
+	}
+	if p.Source.Reference != "" {
+		d.ResolvedIdentifier = p.Source.Reference
+	} else if p.Dist.Reference != "" {
+		d.ResolvedIdentifier = p.Dist.Reference
+	}
+	if p.Source.URL != "" {
+		d.Extras["source"] = p.Source.URL
+	}
+	if p.Type != "" {
+		d.Extras["type"] = p.Type
+	}
+	if p.dev {
+		d.Extras["dev"] = true
+	}
+	return d
+}
+
+// deps returns the packages, the ones of the composer.json are the direct
+// ones
+func (c *composerProject) deps() []*provider.Dep {
+	direct := map[string]bool{}
+	for _, name := range c.required {
+		direct[name] = true
+	}
+	deps := []*provider.Dep{}
+	for _, name := range sortedPackageNames(c.packages) {
+		d := c.packages[name].dep(!direct[name])
+		deps = append(deps, &d)
+	}
+	return deps
+}
+
+// dag returns the tree of the packages of the composer.json and the
+// packages they require. A package that is one of its own ancestors is not
+// walked again.
+func (c *composerProject) dag() []provider.DepDAGItem {
+	var walk func(name string, indirect bool, ancestors map[string]bool) (provider.DepDAGItem, bool)
+	walk = func(name string, indirect bool, ancestors map[string]bool) (provider.DepDAGItem, bool) {
+		p, ok := c.packages[name]
+		if !ok {
+			// platform packages and packages replaced or provided by others
+			// are not resolved
+			return provider.DepDAGItem{}, false
+		}
+		item := provider.DepDAGItem{Dep: p.dep(indirect)}
+		if ancestors[name] {
+			return item, true
+		}
+		ancestors[name] = true
+		for _, required := range sortedNames(p.Require) {
+			if added, ok := walk(required, true, ancestors); ok {
+				item.AddedDeps = append(item.AddedDeps, added)
+			}
+		}
+		delete(ancestors, name)
+		return item, true
+	}
+	items := []provider.DepDAGItem{}
+	for _, name := range c.required {
+		if item, ok := walk(name, false, map[string]bool{}); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func sortedNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedPackageNames(packages map[string]*composerPackage) []string {
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,90 @@
+package php
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+func Test_loadComposerProject(t *testing.T) {
+	project, manifest, err := loadComposerProject("testdata/app")
+	if err != nil {
+		t.Fatalf("loadComposerProject() error = %v", err)
+	}
+	if manifest != uri.File(filepath.Join("testdata/app", composerJSON)) {
+		t.Errorf("dependencies must be reported in the composer.json, got %s", manifest)
+	}
+	if want := []string{"example/billing", "laravel/framework", "phpunit/phpunit"}; !reflect.DeepEqual(project.required, want) {
+		t.Errorf("loadComposerProject() required = %v, want %v", project.required, want)
+	}
+
+	got := map[string]provider.Dep{}
+	for _, d := range project.deps() {
+		got[d.Name] = *d
+	}
+	if len(got) != 4 {
+		t.Errorf("expected the 4 locked packages, got %v", got)
+	}
+	laravel := got["laravel/framework"]
+	if laravel.Version != "10.13.0" || laravel.Indirect || laravel.ResolvedIdentifier != "7322723585103082758d74917db62980684845cb" {
+		t.Errorf("unexpected package %+v", laravel)
+	}
+	wantLabels := []string{"konveyor.io/language=php", "konveyor.io/dep-source=downloadable", "konveyor.io/dep-license=MIT"}
+	if !reflect.DeepEqual(laravel.Labels, wantLabels) {
+		t.Errorf("laravel/framework labels = %v, want %v", laravel.Labels, wantLabels)
+	}
+	if !got["symfony/console"].Indirect {
+		t.Errorf("packages out of the composer.json must be indirect")
+	}
+	if got["phpunit/phpunit"].Extras["dev"] != true {
+		t.Errorf("packages of require-dev must be dev ones, got %+v", got["phpunit/phpunit"])
+	}
+	if !reflect.DeepEqual(got["example/billing"].Labels, wantLabels[:1]) {
+		t.Errorf("packages of a path repository must not be downloadable, got %v", got["example/billing"].Labels)
+	}
+
+	items := project.dag()
+	if len(items) != 3 {
+		t.Fatalf("expected the 3 required packages in the tree, got %+v", items)
+	}
+	billing := items[0]
+	if billing.Dep.Name != "example/billing" || len(billing.AddedDeps) != 1 {
+		t.Fatalf("unexpected packages added by example/billing %+v", billing)
+	}
+	// platform packages are left out
+	framework := billing.AddedDeps[0]
+	if len(framework.AddedDeps) != 1 || framework.AddedDeps[0].Dep.Name != "symfony/console" {
+		t.Errorf("unexpected packages added by laravel/framework %+v", framework.AddedDeps)
+	}
+}
+
+func Test_loadComposerProjectWithoutLock(t *testing.T) {
+	dir := t.TempDir()
+	content, err := os.ReadFile("testdata/app/composer.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, composerJSON), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, _, err := loadComposerProject(dir)
+	if err != nil {
+		t.Fatalf("loadComposerProject() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, d := range project.deps() {
+		got[d.Name] = d.Version
+	}
+	want := map[string]string{"example/billing": "*", "laravel/framework": "^10.0", "phpunit/phpunit": "^10.1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("packages without a lockfile = %v, want %v", got, want)
+	}
+
+	if _, _, err := loadComposerProject(t.TempDir()); err == nil {
+		t.Errorf("loadComposerProject() of a directory without a composer.json must fail")
+	}
+}
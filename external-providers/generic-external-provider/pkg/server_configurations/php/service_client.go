@@ -0,0 +1,267 @@
+package php
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/fileuri"
+	base "github.com/konveyor/analyzer-lsp/lsp/base_service_client"
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/swaggest/openapi-go/openapi3"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+type PhpServiceClientConfig struct {
+	base.LSPServiceClientConfig `yaml:",inline"`
+}
+
+// Tidy aliases
+type serviceClientFn = base.LSPServiceClientFunc[*PhpServiceClient]
+
+type PhpServiceClient struct {
+	*base.LSPServiceClientBase
+	*base.LSPServiceClientEvaluator[*PhpServiceClient]
+
+	Config PhpServiceClientConfig
+}
+
+type PhpServiceClientBuilder struct{}
+
+// defaultLspServerArgs are the args of the PHP language servers to speak LSP
+// on their stdio
+var defaultLspServerArgs = map[string][]interface{}{
+	"intelephense": {"--stdio"},
+	"phpactor":     {"language-server"},
+}
+
+func (p *PhpServiceClientBuilder) Init(ctx context.Context, log logr.Logger, c provider.InitConfig) (provider.ServiceClient, error) {
+	sc := &PhpServiceClient{}
+
+	// Unmarshal the config
+	b, _ := yaml.Marshal(c.ProviderSpecificConfig)
+	err := yaml.Unmarshal(b, &sc.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Launch intelephense and phpactor with their args unless others are set
+	if _, ok := c.ProviderSpecificConfig["lspServerArgs"]; !ok {
+		server := strings.TrimSuffix(filepath.Base(sc.Config.LspServerPath), filepath.Ext(sc.Config.LspServerPath))
+		if args, ok := defaultLspServerArgs[server]; ok {
+			config := map[string]interface{}{}
+			for k, v := range c.ProviderSpecificConfig {
+				config[k] = v
+			}
+			config["lspServerArgs"] = args
+			c.ProviderSpecificConfig = config
+		}
+	}
+
+	params := protocol.InitializeParams{}
+
+	if c.Location != "" {
+		sc.Config.WorkspaceFolders = []string{c.Location}
+	}
+
+	if len(sc.Config.WorkspaceFolders) == 0 {
+		params.RootURI = ""
+	} else {
+		params.RootURI = sc.Config.WorkspaceFolders[0]
+	}
+
+	params.Capabilities = protocol.ClientCapabilities{}
+
+	var InitializationOptions map[string]any
+	err = json.Unmarshal([]byte(sc.Config.LspServerInitializationOptions), &InitializationOptions)
+	if err != nil {
+		params.InitializationOptions = map[string]any{}
+	} else {
+		params.InitializationOptions = InitializationOptions
+	}
+
+	// Initialize the base client
+	scBase, err := base.NewLSPServiceClientBase(
+		ctx, log, c,
+		base.LogHandler(log),
+		params,
+	)
+	if err != nil {
+		return nil, err
+	}
+	sc.LSPServiceClientBase = scBase
+	sc.BaseConfig.WorkspaceFolders = sc.Config.WorkspaceFolders
+
+	// The servers index the packages composer installs in the application,
+	// their references are not the application's
+	if len(sc.BaseConfig.WorkspaceFolders) > 0 && len(sc.BaseConfig.DependencyFolders) == 0 {
+		sc.BaseConfig.DependencyFolders = []string{
+			strings.TrimSuffix(sc.BaseConfig.WorkspaceFolders[0], "/") + "/vendor",
+		}
+	}
+
+	// Initialize the fancy evaluator (dynamic dispatch ftw)
+	eval, err := base.NewLspServiceClientEvaluator[*PhpServiceClient](sc, p.GetGenericServiceClientCapabilities(log))
+	if err != nil {
+		return nil, err
+	}
+	sc.LSPServiceClientEvaluator = eval
+
+	return sc, nil
+}
+
+func (p *PhpServiceClientBuilder) GetGenericServiceClientCapabilities(log logr.Logger) []base.LSPServiceClientCapability {
+	caps := []base.LSPServiceClientCapability{}
+	r := openapi3.NewReflector()
+	refCap, err := provider.ToProviderCap(r, log, base.ReferencedCondition{}, "referenced")
+	if err != nil {
+		log.Error(err, "unable to get referenced cap")
+	} else {
+		caps = append(caps, base.LSPServiceClientCapability{
+			Capability: refCap,
+			Fn:         serviceClientFn((*PhpServiceClient).EvaluateReferenced),
+		})
+	}
+	importsCap, err := provider.ToProviderCap(r, log, base.ImportsCondition{}, "imports")
+	if err != nil {
+		log.Error(err, "unable to get imports cap")
+	} else {
+		caps = append(caps, base.LSPServiceClientCapability{
+			Capability: importsCap,
+			Fn:         serviceClientFn(base.EvaluateImports[*PhpServiceClient]),
+		})
+	}
+	depCap, err := provider.ToProviderCap(r, log, base.NoOpCondition{}, "dependency")
+	if err != nil {
+		log.Error(err, "unable to get dependency capability")
+	} else {
+		caps = append(caps, base.LSPServiceClientCapability{
+			Capability: depCap,
+			Fn:         serviceClientFn(base.EvaluateNoOp[*PhpServiceClient]),
+		})
+	}
+	return caps
+}
+
+type resp = provider.ProviderEvaluateResponse
+
+// EvaluateReferenced finds the references in the application to the
+// declarations matching the fully qualified name of the pattern, see
+// SymbolSearchHelper. The servers index the packages in the vendor directory
+// too, so the classes of the dependencies are found.
+func (sc *PhpServiceClient) EvaluateReferenced(ctx context.Context, cap string, info []byte) (provider.ProviderEvaluateResponse, error) {
+	var cond base.ReferencedCondition
+	err := yaml.Unmarshal(info, &cond)
+	if err != nil {
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("error unmarshaling query info"))
+	}
+	pattern := cond.Referenced.Pattern
+	if pattern == "" {
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("unable to get query info"))
+	}
+	if _, err := patterns.Regex(pattern); err != nil {
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("invalid pattern %s: %w", pattern, err))
+	}
+	if len(sc.BaseConfig.WorkspaceFolders) == 0 {
+		return resp{}, fmt.Errorf("no workspace folder to search")
+	}
+
+	incidentsMap := map[string]provider.IncidentContext{} // Remove duplicates
+	for _, s := range sc.GetMatchingDeclarations(ctx, SymbolSearchHelper{}, pattern) {
+		for _, ref := range sc.GetAllReferences(ctx, s.Location.Value.(protocol.Location)) {
+			if !sc.inApplication(ref.URI) {
+				continue
+			}
+			u, err := uri.Parse(ref.URI)
+			if err != nil {
+				return resp{}, err
+			}
+			// ranges are 0 indexed
+			lineNumber := int(ref.Range.Start.Line) + 1
+			incident := provider.IncidentContext{
+				FileURI:    u,
+				LineNumber: &lineNumber,
+				Variables: map[string]interface{}{
+					"file": ref.URI,
+				},
+				CodeLocation: &provider.Location{
+					StartPosition: provider.Position{
+						Line:      float64(ref.Range.Start.Line) + 1,
+						Character: float64(ref.Range.Start.Character) + 1,
+					},
+					EndPosition: provider.Position{
+						Line:      float64(ref.Range.End.Line) + 1,
+						Character: float64(ref.Range.End.Character) + 1,
+					},
+				},
+				MatchedText: base.RangeText(u, ref.Range),
+			}
+			sc.AddCodeActions(ctx, &incident, ref.URI, ref.Range)
+			b, _ := json.Marshal(incident)
+			incidentsMap[string(b)] = incident
+		}
+	}
+
+	incidents := []provider.IncidentContext{}
+	for _, incident := range incidentsMap {
+		incidents = append(incidents, incident)
+	}
+	// No results were found.
+	if len(incidents) == 0 {
+		return resp{Matched: false}, nil
+	}
+	return resp{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
+
+// inApplication reports whether a reference is in the workspace and not in
+// its dependency folders
+func (sc *PhpServiceClient) inApplication(ref string) bool {
+	if !strings.Contains(ref, sc.BaseConfig.WorkspaceFolders[0]) {
+		return false
+	}
+	for _, substr := range sc.BaseConfig.DependencyFolders {
+		if substr != "" && strings.Contains(ref, substr) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetDependencies returns the packages of the composer.lock of the
+// application, or the ones printed by the dependency provider when it is set
+func (sc *PhpServiceClient) GetDependencies(ctx context.Context) (map[uri.URI][]*provider.Dep, error) {
+	if sc.BaseConfig.DependencyProviderPath != "" {
+		return sc.LSPServiceClientBase.GetDependencies(ctx)
+	}
+	if len(sc.BaseConfig.WorkspaceFolders) == 0 {
+		return nil, nil
+	}
+	project, manifest, err := loadComposerProject(fileuri.Path(sc.BaseConfig.WorkspaceFolders[0]))
+	if err != nil {
+		return nil, err
+	}
+	return map[uri.URI][]*provider.Dep{manifest: project.deps()}, nil
+}
+
+// GetDependenciesDAG returns the packages of the composer.json and the
+// packages they require as resolved in the composer.lock
+func (sc *PhpServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]provider.DepDAGItem, error) {
+	if sc.BaseConfig.DependencyProviderPath != "" || len(sc.BaseConfig.WorkspaceFolders) == 0 {
+		return nil, nil
+	}
+	project, manifest, err := loadComposerProject(fileuri.Path(sc.BaseConfig.WorkspaceFolders[0]))
+	if err != nil {
+		return nil, err
+	}
+	return map[uri.URI][]provider.DepDAGItem{manifest: project.dag()}, nil
+}
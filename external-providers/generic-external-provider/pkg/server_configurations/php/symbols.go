@@ -0,0 +1,63 @@
+package php
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/external-providers/generic-external-provider/pkg/server_configurations/internal/symbols"
+	base "github.com/konveyor/analyzer-lsp/lsp/base_service_client"
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+// SymbolSearchHelper matches the symbols answered by intelephense and
+// phpactor with PHP fully qualified names: `App\Models\User` for classes,
+// interfaces, traits and functions, `App\Models\User::save` for methods and
+// `App\Models\User::$table` for properties. The leading `\` of the names is
+// optional. Patterns are regular expressions where `\` is the namespace
+// separator rather than an escape, and are matched ignoring the case as
+// PHP does for the names of classes and functions.
+type SymbolSearchHelper struct{}
+
+var _ base.SymbolSearchHelper = SymbolSearchHelper{}
+
+var patterns = &symbols.Patterns{
+	TrailingName: regexp.MustCompile(`(?:^|\\|::\$?)([A-Za-z_][A-Za-z0-9_]*)$`),
+	Expr: func(pattern string) string {
+		expr := strings.TrimPrefix(pattern, `\`)
+		expr = strings.ReplaceAll(expr, `\`, `\\`)
+		expr = strings.ReplaceAll(expr, `$`, `\$`)
+		return `(?i)^(?:` + expr + `)$`
+	},
+}
+
+func (SymbolSearchHelper) GetQuery(pattern string) string {
+	return patterns.Query(pattern)
+}
+
+func (SymbolSearchHelper) MatchSymbol(pattern string, symbol protocol.WorkspaceSymbol) bool {
+	regex, err := patterns.Regex(pattern)
+	if err != nil {
+		return false
+	}
+	return regex.MatchString(symbolName(symbol))
+}
+
+// symbolName returns the fully qualified name of a symbol, without its
+// leading `\`. phpactor names the classes with their fully qualified name
+// while intelephense gives their name in their namespace.
+func symbolName(symbol protocol.WorkspaceSymbol) string {
+	name := strings.TrimPrefix(symbol.Name, `\`)
+	container := strings.TrimPrefix(symbol.ContainerName, `\`)
+	if container == "" || strings.HasPrefix(strings.ToLower(name), strings.ToLower(container)) {
+		return name
+	}
+	switch symbol.Kind {
+	case protocol.Method, protocol.Property, protocol.Field, protocol.Constant, protocol.EnumMember:
+		// properties are named with their $ by some servers only
+		if symbol.Kind == protocol.Property && !strings.HasPrefix(name, "$") {
+			name = "$" + name
+		}
+		return container + "::" + name
+	}
+	return container + `\` + name
+}
@@ -0,0 +1,55 @@
+package php
+
+import (
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+func symbol(name, container string, kind protocol.SymbolKind) protocol.WorkspaceSymbol {
+	return protocol.WorkspaceSymbol{
+		BaseSymbolInformation: protocol.BaseSymbolInformation{Name: name, ContainerName: container, Kind: kind},
+	}
+}
+
+func TestSymbolSearchHelper_GetQuery(t *testing.T) {
+	tests := map[string]string{
+		`Illuminate\Database\Eloquent\Model`: "Model",
+		`\App\Models\User`:                   "User",
+		`App\Models\User::save`:              "save",
+		`App\Models\User::$table`:            "table",
+		`Illuminate\Support\.*`:              "",
+	}
+	for pattern, want := range tests {
+		if got := (SymbolSearchHelper{}).GetQuery(pattern); got != want {
+			t.Errorf("GetQuery(%s) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestSymbolSearchHelper_MatchSymbol(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		symbol  protocol.WorkspaceSymbol
+		want    bool
+	}{
+		{name: "intelephense class", pattern: `Illuminate\Database\Eloquent\Model`, symbol: symbol("Model", `Illuminate\Database\Eloquent`, protocol.Class), want: true},
+		{name: "phpactor class", pattern: `Illuminate\Database\Eloquent\Model`, symbol: symbol(`Illuminate\Database\Eloquent\Model`, "", protocol.Class), want: true},
+		{name: "leading backslash", pattern: `\App\Models\User`, symbol: symbol("User", `\App\Models`, protocol.Class), want: true},
+		{name: "case insensitive", pattern: `app\models\user`, symbol: symbol("User", `App\Models`, protocol.Class), want: true},
+		{name: "other namespace", pattern: `App\Models\User`, symbol: symbol("User", `Tests\Models`, protocol.Class), want: false},
+		{name: "short name", pattern: `User`, symbol: symbol("User", `App\Models`, protocol.Class), want: false},
+		{name: "regex", pattern: `Illuminate\Support\Facades\.*`, symbol: symbol("DB", `Illuminate\Support\Facades`, protocol.Class), want: true},
+		{name: "method", pattern: `App\Models\User::save`, symbol: symbol("save", `App\Models\User`, protocol.Method), want: true},
+		{name: "property", pattern: `App\Models\User::$table`, symbol: symbol("table", `App\Models\User`, protocol.Property), want: true},
+		{name: "function", pattern: `App\Support\helper`, symbol: symbol("helper", `App\Support`, protocol.Function), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (SymbolSearchHelper{}).MatchSymbol(tt.pattern, tt.symbol); got != tt.want {
+				t.Errorf("MatchSymbol(%s, %s) = %v, want %v", tt.pattern, symbolName(tt.symbol), got, tt.want)
+			}
+		})
+	}
+}
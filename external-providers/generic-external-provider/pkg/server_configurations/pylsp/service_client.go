@@ -102,5 +102,14 @@ func (p *PythonServiceClientBuilder) GetGenericServiceClientCapabilities(log log
 			Fn:         serviceClientFn(base.EvaluateReferenced[*PythonServiceClient]),
 		})
 	}
+	importsCap, err := provider.ToProviderCap(r, log, base.ImportsCondition{}, "imports")
+	if err != nil {
+		log.Error(err, "unable to get imports cap")
+	} else {
+		caps = append(caps, base.LSPServiceClientCapability{
+			Capability: importsCap,
+			Fn:         serviceClientFn(base.EvaluateImports[*PythonServiceClient]),
+		})
+	}
 	return caps
 }
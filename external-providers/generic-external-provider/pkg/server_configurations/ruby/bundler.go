@@ -0,0 +1,235 @@
+package ruby
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+const (
+	gemfile     = "Gemfile"
+	gemfileLock = "Gemfile.lock"
+
+	rubyDownloadableDepSourceLabel = "downloadable"
+)
+
+// gemSpec is a gem resolved in a Gemfile.lock
+type gemSpec struct {
+	name     string
+	version  string
+	platform string
+	// source is the section the gem is resolved in, GEM, GIT or PATH
+	source   string
+	remote   string
+	revision string
+	// dependencies are the names of the gems the gem depends on
+	dependencies []string
+}
+
+// gemfileLockfile is a parsed Gemfile.lock
+type gemfileLockfile struct {
+	// specs are the resolved gems by name, the first platform of a gem is
+	// kept when it is resolved for several
+	specs map[string]*gemSpec
+	// dependencies are the names of the gems of the Gemfile
+	dependencies []string
+}
+
+// parseGemfileLock parses the sections of a Gemfile.lock listing the
+// resolved gems and the gems of the Gemfile, the others are skipped
+func parseGemfileLock(r io.Reader) (*gemfileLockfile, error) {
+	lock := &gemfileLockfile{specs: map[string]*gemSpec{}}
+	var section, remote, revision string
+	var spec *gemSpec
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if line == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		text := strings.TrimSpace(line)
+		if indent == 0 {
+			section, remote, revision, spec = text, "", "", nil
+			continue
+		}
+		switch section {
+		case "GEM", "GIT", "PATH":
+			switch {
+			case indent == 2 && strings.HasPrefix(text, "remote:"):
+				remote = strings.TrimSpace(strings.TrimPrefix(text, "remote:"))
+			case indent == 2 && strings.HasPrefix(text, "revision:"):
+				revision = strings.TrimSpace(strings.TrimPrefix(text, "revision:"))
+			case indent == 4:
+				name, version := splitGemLine(text)
+				if version == "" {
+					return nil, fmt.Errorf("gem %s of the %s section has no version", name, section)
+				}
+				spec = &gemSpec{name: name, version: version, source: section, remote: remote, revision: revision}
+				if i := strings.Index(version, "-"); i > 0 {
+					spec.version, spec.platform = version[:i], version[i+1:]
+				}
+				if _, ok := lock.specs[name]; !ok {
+					lock.specs[name] = spec
+				}
+			case indent == 6 && spec != nil:
+				name, _ := splitGemLine(text)
+				spec.dependencies = append(spec.dependencies, name)
+			}
+		case "DEPENDENCIES":
+			if indent == 2 {
+				name, _ := splitGemLine(text)
+				lock.dependencies = append(lock.dependencies, strings.TrimSuffix(name, "!"))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// splitGemLine splits `name (version)` in its name and version or
+// requirement
+func splitGemLine(text string) (string, string) {
+	name, version, ok := strings.Cut(text, " (")
+	if !ok {
+		return text, ""
+	}
+	return name, strings.TrimSuffix(version, ")")
+}
+
+// dep returns the dependency of a resolved gem
+func (s *gemSpec) dep(indirect bool) provider.Dep {
+	d := provider.Dep{
+		Name:     s.name,
+		Version:  s.version,
+		Indirect: indirect,
+		Labels: []string{
+			labels.AsString(provider.DepLanguageLabel, "ruby"),
+		},
+		Extras: map[string]interface{}{
+			"source": s.source,
+		},
+	}
+	// gems of a PATH are part of the application
+	if s.source != "PATH" {
+		d.Labels = append(d.Labels, labels.AsString(provider.DepSourceLabel, rubyDownloadableDepSourceLabel))
+	}
+	if s.remote != "" {
+		d.Extras["remote"] = s.remote
+	}
+	if s.revision != "" {
+		d.Extras["revision"] = s.revision
+		d.ResolvedIdentifier = s.revision
+	}
+	if s.platform != "" {
+		d.Extras["platform"] = s.platform
+	}
+	return d
+}
+
+// deps returns the resolved gems, the gems of the Gemfile are the direct
+// ones
+func (l *gemfileLockfile) deps() []*provider.Dep {
+	direct := map[string]bool{}
+	for _, name := range l.dependencies {
+		direct[name] = true
+	}
+	deps := []*provider.Dep{}
+	for _, name := range sortedSpecNames(l.specs) {
+		d := l.specs[name].dep(!direct[name])
+		deps = append(deps, &d)
+	}
+	return deps
+}
+
+// dag returns the tree of the gems of the Gemfile and the gems they depend
+// on. A gem that is one of its own ancestors is not walked again.
+func (l *gemfileLockfile) dag() []provider.DepDAGItem {
+	var walk func(name string, indirect bool, ancestors map[string]bool) (provider.DepDAGItem, bool)
+	walk = func(name string, indirect bool, ancestors map[string]bool) (provider.DepDAGItem, bool) {
+		spec, ok := l.specs[name]
+		if !ok {
+			// bundler itself and the gems of other platforms are not resolved
+			return provider.DepDAGItem{}, false
+		}
+		item := provider.DepDAGItem{Dep: spec.dep(indirect)}
+		if ancestors[name] {
+			return item, true
+		}
+		ancestors[name] = true
+		for _, dependency := range spec.dependencies {
+			if added, ok := walk(dependency, true, ancestors); ok {
+				item.AddedDeps = append(item.AddedDeps, added)
+			}
+		}
+		delete(ancestors, name)
+		return item, true
+	}
+	items := []provider.DepDAGItem{}
+	for _, name := range l.dependencies {
+		if item, ok := walk(name, false, map[string]bool{}); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func sortedSpecNames(specs map[string]*gemSpec) []string {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadGemfileLock reads the Gemfile.lock of the application in dir. When the
+// application has a Gemfile without a lockfile, bundler resolves its gems in
+// a lockfile of a temporary directory, the application is not changed. It
+// returns the URI of the manifest the dependencies are reported in.
+func loadGemfileLock(ctx context.Context, bundlerPath, dir string) (*gemfileLockfile, uri.URI, error) {
+	manifest := filepath.Join(dir, gemfile)
+	if _, err := os.Stat(manifest); err != nil {
+		manifest = filepath.Join(dir, gemfileLock)
+	}
+	lockPath := filepath.Join(dir, gemfileLock)
+	if _, err := os.Stat(lockPath); err != nil {
+		if _, err := os.Stat(filepath.Join(dir, gemfile)); err != nil {
+			return nil, "", fmt.Errorf("no Gemfile or Gemfile.lock in %s", dir)
+		}
+		tmp, err := os.MkdirTemp("", "bundler")
+		if err != nil {
+			return nil, "", err
+		}
+		defer os.RemoveAll(tmp)
+		lockPath = filepath.Join(tmp, gemfileLock)
+		cmd := exec.CommandContext(ctx, bundlerPath, "lock", "--lockfile", lockPath)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "BUNDLE_GEMFILE="+filepath.Join(dir, gemfile))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, "", fmt.Errorf("bundle lock failed: %w: %s", err, out)
+		}
+	}
+	f, err := os.Open(lockPath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	lock, err := parseGemfileLock(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to parse %s: %w", lockPath, err)
+	}
+	return lock, uri.File(manifest), nil
+}
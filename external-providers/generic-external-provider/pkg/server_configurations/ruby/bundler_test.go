@@ -0,0 +1,103 @@
+package ruby
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+func Test_parseGemfileLock(t *testing.T) {
+	f, err := os.Open("testdata/Gemfile.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lock, err := parseGemfileLock(f)
+	if err != nil {
+		t.Fatalf("parseGemfileLock() error = %v", err)
+	}
+	if want := []string{"audited", "billing", "nokogiri", "rails"}; !reflect.DeepEqual(lock.dependencies, want) {
+		t.Errorf("parseGemfileLock() dependencies = %v, want %v", lock.dependencies, want)
+	}
+
+	got := map[string]provider.Dep{}
+	for _, d := range lock.deps() {
+		got[d.Name] = *d
+	}
+	if len(got) != 9 {
+		t.Errorf("expected the 9 resolved gems, got %v", got)
+	}
+	nokogiri := got["nokogiri"]
+	if nokogiri.Version != "1.13.10" || nokogiri.Indirect || nokogiri.Extras["platform"] != "x86_64-linux" {
+		t.Errorf("unexpected gem of a platform %+v", nokogiri)
+	}
+	audited := got["audited"]
+	if audited.Extras["source"] != "GIT" || audited.ResolvedIdentifier != "3b1a5c4d2e" || audited.Extras["remote"] != "https://github.com/example/audited.git" {
+		t.Errorf("unexpected gem of a git repository %+v", audited)
+	}
+	if !got["racc"].Indirect {
+		t.Errorf("gems out of the Gemfile must be indirect")
+	}
+	wantLabels := []string{"konveyor.io/language=ruby", "konveyor.io/dep-source=downloadable"}
+	if !reflect.DeepEqual(got["rails"].Labels, wantLabels) {
+		t.Errorf("rails labels = %v, want %v", got["rails"].Labels, wantLabels)
+	}
+	if !reflect.DeepEqual(got["billing"].Labels, wantLabels[:1]) {
+		t.Errorf("gems of a path of the application must not be downloadable, got %v", got["billing"].Labels)
+	}
+}
+
+func Test_gemfileLockfileDAG(t *testing.T) {
+	lock := &gemfileLockfile{
+		specs: map[string]*gemSpec{
+			"a": {name: "a", version: "1.0", dependencies: []string{"b", "bundler"}},
+			"b": {name: "b", version: "2.0", dependencies: []string{"a"}},
+		},
+		dependencies: []string{"a"},
+	}
+	items := lock.dag()
+	if len(items) != 1 || items[0].Dep.Name != "a" || items[0].Dep.Indirect {
+		t.Fatalf("unexpected direct gems %+v", items)
+	}
+	if len(items[0].AddedDeps) != 1 || items[0].AddedDeps[0].Dep.Name != "b" || !items[0].AddedDeps[0].Dep.Indirect {
+		t.Fatalf("unexpected gems added by a %+v", items[0].AddedDeps)
+	}
+	// a depends on itself through b, it is not walked again
+	cycle := items[0].AddedDeps[0].AddedDeps
+	if len(cycle) != 1 || cycle[0].Dep.Name != "a" || len(cycle[0].AddedDeps) != 0 {
+		t.Errorf("unexpected gems added by b %+v", cycle)
+	}
+}
+
+func Test_loadGemfileLock(t *testing.T) {
+	dir := t.TempDir()
+	content, err := os.ReadFile("testdata/Gemfile.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, gemfileLock), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, gemfile), []byte("source 'https://rubygems.org'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lock, manifest, err := loadGemfileLock(context.TODO(), "bundle", dir)
+	if err != nil {
+		t.Fatalf("loadGemfileLock() error = %v", err)
+	}
+	if manifest != uri.File(filepath.Join(dir, gemfile)) {
+		t.Errorf("dependencies must be reported in the Gemfile, got %s", manifest)
+	}
+	if len(lock.specs) != 9 {
+		t.Errorf("expected the 9 resolved gems, got %d", len(lock.specs))
+	}
+
+	if _, _, err := loadGemfileLock(context.TODO(), "bundle", t.TempDir()); err == nil {
+		t.Errorf("loadGemfileLock() of a directory without a Gemfile must fail")
+	}
+}
@@ -0,0 +1,408 @@
+package ruby
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/fileuri"
+	base "github.com/konveyor/analyzer-lsp/lsp/base_service_client"
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/swaggest/openapi-go/openapi3"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+type RubyServiceClientConfig struct {
+	base.LSPServiceClientConfig `yaml:",inline"`
+
+	// Path to the bundler binary resolving the gems of applications without a
+	// Gemfile.lock. Defaults to `bundle`.
+	BundlerPath string `yaml:"bundlerPath,omitempty"`
+}
+
+// Tidy aliases
+type serviceClientFn = base.LSPServiceClientFunc[*RubyServiceClient]
+
+type RubyServiceClient struct {
+	*base.LSPServiceClientBase
+	*base.LSPServiceClientEvaluator[*RubyServiceClient]
+
+	Config RubyServiceClientConfig
+
+	// documents opened in the server, ruby-lsp only answers requests about
+	// opened documents
+	opened sync.Map
+}
+
+type RubyServiceClientBuilder struct{}
+
+// defaultLspServerArgs are the args of the Ruby language servers speaking
+// LSP on their stdio, solargraph needs to be told
+var defaultLspServerArgs = map[string][]interface{}{
+	"solargraph": {"stdio"},
+	"ruby-lsp":   {},
+}
+
+func (r *RubyServiceClientBuilder) Init(ctx context.Context, log logr.Logger, c provider.InitConfig) (provider.ServiceClient, error) {
+	sc := &RubyServiceClient{}
+
+	// Unmarshal the config
+	b, _ := yaml.Marshal(c.ProviderSpecificConfig)
+	err := yaml.Unmarshal(b, &sc.Config)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Config.BundlerPath == "" {
+		sc.Config.BundlerPath = "bundle"
+	}
+
+	// Launch solargraph and ruby-lsp with their args unless others are set
+	if _, ok := c.ProviderSpecificConfig["lspServerArgs"]; !ok {
+		server := strings.TrimSuffix(filepath.Base(sc.Config.LspServerPath), filepath.Ext(sc.Config.LspServerPath))
+		if args, ok := defaultLspServerArgs[server]; ok {
+			config := map[string]interface{}{}
+			for k, v := range c.ProviderSpecificConfig {
+				config[k] = v
+			}
+			config["lspServerArgs"] = args
+			c.ProviderSpecificConfig = config
+		}
+	}
+
+	params := protocol.InitializeParams{}
+
+	if c.Location != "" {
+		sc.Config.WorkspaceFolders = []string{c.Location}
+	}
+
+	if len(sc.Config.WorkspaceFolders) == 0 {
+		params.RootURI = ""
+	} else {
+		params.RootURI = sc.Config.WorkspaceFolders[0]
+	}
+
+	params.Capabilities = protocol.ClientCapabilities{}
+
+	var InitializationOptions map[string]any
+	err = json.Unmarshal([]byte(sc.Config.LspServerInitializationOptions), &InitializationOptions)
+	if err != nil {
+		params.InitializationOptions = map[string]any{}
+	} else {
+		params.InitializationOptions = InitializationOptions
+	}
+
+	// Initialize the base client
+	scBase, err := base.NewLSPServiceClientBase(
+		ctx, log, c,
+		base.LogHandler(log),
+		params,
+	)
+	if err != nil {
+		return nil, err
+	}
+	sc.LSPServiceClientBase = scBase
+	sc.BaseConfig.WorkspaceFolders = sc.Config.WorkspaceFolders
+
+	// The gems bundler installs in the application are dependencies
+	if len(sc.BaseConfig.WorkspaceFolders) > 0 && len(sc.BaseConfig.DependencyFolders) == 0 {
+		sc.BaseConfig.DependencyFolders = []string{
+			strings.TrimSuffix(sc.BaseConfig.WorkspaceFolders[0], "/") + "/vendor/bundle",
+		}
+	}
+
+	// Initialize the fancy evaluator (dynamic dispatch ftw)
+	eval, err := base.NewLspServiceClientEvaluator[*RubyServiceClient](sc, r.GetGenericServiceClientCapabilities(log))
+	if err != nil {
+		return nil, err
+	}
+	sc.LSPServiceClientEvaluator = eval
+
+	return sc, nil
+}
+
+func (r *RubyServiceClientBuilder) GetGenericServiceClientCapabilities(log logr.Logger) []base.LSPServiceClientCapability {
+	caps := []base.LSPServiceClientCapability{}
+	reflector := openapi3.NewReflector()
+	refCap, err := provider.ToProviderCap(reflector, log, base.ReferencedCondition{}, "referenced")
+	if err != nil {
+		log.Error(err, "unable to get referenced cap")
+	} else {
+		caps = append(caps, base.LSPServiceClientCapability{
+			Capability: refCap,
+			Fn:         serviceClientFn((*RubyServiceClient).EvaluateReferenced),
+		})
+	}
+	importsCap, err := provider.ToProviderCap(reflector, log, base.ImportsCondition{}, "imports")
+	if err != nil {
+		log.Error(err, "unable to get imports cap")
+	} else {
+		caps = append(caps, base.LSPServiceClientCapability{
+			Capability: importsCap,
+			Fn:         serviceClientFn(base.EvaluateImports[*RubyServiceClient]),
+		})
+	}
+	depCap, err := provider.ToProviderCap(reflector, log, base.NoOpCondition{}, "dependency")
+	if err != nil {
+		log.Error(err, "unable to get dependency capability")
+	} else {
+		caps = append(caps, base.LSPServiceClientCapability{
+			Capability: depCap,
+			Fn:         serviceClientFn(base.EvaluateNoOp[*RubyServiceClient]),
+		})
+	}
+	return caps
+}
+
+type resp = provider.ProviderEvaluateResponse
+
+// EvaluateReferenced finds the references to the declarations matching the
+// fully qualified name of the pattern, see SymbolSearchHelper. Constants
+// declared outside of the application, such as the ones of gems like
+// `ActiveRecord::Base`, are unknown to the server and searched in the Ruby
+// sources instead, unless strictMatches is set.
+func (sc *RubyServiceClient) EvaluateReferenced(ctx context.Context, cap string, info []byte) (provider.ProviderEvaluateResponse, error) {
+	var cond base.ReferencedCondition
+	err := yaml.Unmarshal(info, &cond)
+	if err != nil {
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("error unmarshaling query info"))
+	}
+	pattern := cond.Referenced.Pattern
+	if pattern == "" {
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("unable to get query info"))
+	}
+	if _, err := patterns.Regex(pattern); err != nil {
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("invalid pattern %s: %w", pattern, err))
+	}
+	if len(sc.BaseConfig.WorkspaceFolders) == 0 {
+		return resp{}, fmt.Errorf("no workspace folder to search")
+	}
+
+	incidentsMap := map[string]provider.IncidentContext{} // Remove duplicates
+	symbols := sc.GetMatchingDeclarations(ctx, SymbolSearchHelper{}, pattern)
+	for _, s := range symbols {
+		location := s.Location.Value.(protocol.Location)
+		sc.openDocument(ctx, location.URI)
+		for _, ref := range sc.GetAllReferences(ctx, location) {
+			if !sc.inApplication(ref.URI) {
+				continue
+			}
+			incident, err := sc.incident(ctx, ref.URI, ref.Range)
+			if err != nil {
+				return resp{}, err
+			}
+			b, _ := json.Marshal(incident)
+			incidentsMap[string(b)] = incident
+		}
+	}
+
+	if len(symbols) == 0 && !isMethodPattern(pattern) && !sc.BaseConfig.StrictMatches {
+		refs, err := sc.searchConstant(ctx, pattern)
+		if err != nil {
+			return resp{}, err
+		}
+		for _, ref := range refs {
+			incident, err := sc.incident(ctx, ref.URI, ref.Range)
+			if err != nil {
+				return resp{}, err
+			}
+			incident.Variables[base.TextSearchFallbackVariable] = true
+			b, _ := json.Marshal(incident)
+			incidentsMap[string(b)] = incident
+		}
+	}
+
+	incidents := []provider.IncidentContext{}
+	for _, incident := range incidentsMap {
+		incidents = append(incidents, incident)
+	}
+	// No results were found.
+	if len(incidents) == 0 {
+		return resp{Matched: false}, nil
+	}
+	return resp{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
+
+// inApplication reports whether a reference is in the workspace and not in
+// its dependency folders
+func (sc *RubyServiceClient) inApplication(ref string) bool {
+	if !strings.Contains(ref, sc.BaseConfig.WorkspaceFolders[0]) {
+		return false
+	}
+	for _, substr := range sc.BaseConfig.DependencyFolders {
+		if substr != "" && strings.Contains(ref, substr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (sc *RubyServiceClient) incident(ctx context.Context, ref string, r protocol.Range) (provider.IncidentContext, error) {
+	u, err := uri.Parse(ref)
+	if err != nil {
+		return provider.IncidentContext{}, err
+	}
+	// ranges are 0 indexed
+	lineNumber := int(r.Start.Line) + 1
+	incident := provider.IncidentContext{
+		FileURI:    u,
+		LineNumber: &lineNumber,
+		Variables: map[string]interface{}{
+			"file": ref,
+		},
+		CodeLocation: &provider.Location{
+			StartPosition: provider.Position{
+				Line:      float64(r.Start.Line) + 1,
+				Character: float64(r.Start.Character) + 1,
+			},
+			EndPosition: provider.Position{
+				Line:      float64(r.End.Line) + 1,
+				Character: float64(r.End.Character) + 1,
+			},
+		},
+		MatchedText: base.RangeText(u, r),
+	}
+	sc.AddCodeActions(ctx, &incident, ref, r)
+	return incident, nil
+}
+
+// openDocument opens a Ruby source in the server, once
+func (sc *RubyServiceClient) openDocument(ctx context.Context, documentURI string) {
+	path := fileuri.Path(documentURI)
+	languageID := LanguageID(path)
+	if languageID == "" {
+		return
+	}
+	if _, loaded := sc.opened.LoadOrStore(documentURI, true); loaded {
+		return
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		sc.Log.V(5).Info("unable to read document", "uri", documentURI, "error", err)
+		return
+	}
+	err = sc.Conn.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:        documentURI,
+			LanguageID: languageID,
+			Text:       string(content),
+		},
+	})
+	if err != nil {
+		sc.Log.V(5).Info("unable to open document", "uri", documentURI, "error", err)
+	}
+}
+
+// searchConstant finds the references to a constant in the Ruby sources of
+// the workspace, out of comments, without resolving them
+func (sc *RubyServiceClient) searchConstant(ctx context.Context, pattern string) ([]protocol.Location, error) {
+	regex, err := constantRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+	root := fileuri.Path(sc.BaseConfig.WorkspaceFolders[0])
+	locations := []protocol.Location{}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		u := string(uri.File(path))
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || sc.PathFilter.SkipDir(path) || !sc.inApplication(u+"/")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if LanguageID(path) == "" || !sc.PathFilter.Match(path) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		locations = append(locations, constantReferences(regex, u, content)...)
+		return nil
+	})
+	return locations, err
+}
+
+// constantRegex matches the references to the constants of the pattern in
+// Ruby sources, its first group is the reference. Patterns not starting with
+// `::` also match constants referenced in a namespace, and the constants of
+// a namespace are references to it.
+func constantRegex(pattern string) (*regexp.Regexp, error) {
+	prefix := `(?:::)?(?:[A-Z][A-Za-z0-9_]*::)*`
+	if strings.HasPrefix(pattern, "::") {
+		pattern, prefix = pattern[2:], `(?:::)?`
+	}
+	return regexp.Compile(`(?:^|[^A-Za-z0-9_:@$])(` + prefix + `(?:` + pattern + `))(?:[^A-Za-z0-9_]|$)`)
+}
+
+func constantReferences(regex *regexp.Regexp, documentURI string, content []byte) []protocol.Location {
+	locations := []protocol.Location{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for line := 0; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(text), "#") {
+			continue
+		}
+		for _, m := range regex.FindAllStringSubmatchIndex(text, -1) {
+			locations = append(locations, protocol.Location{
+				URI: documentURI,
+				Range: protocol.Range{
+					Start: protocol.Position{Line: uint32(line), Character: uint32(m[2])},
+					End:   protocol.Position{Line: uint32(line), Character: uint32(m[3])},
+				},
+			})
+		}
+	}
+	return locations
+}
+
+// GetDependencies returns the gems of the Gemfile.lock of the application,
+// or the ones printed by the dependency provider when it is set
+func (sc *RubyServiceClient) GetDependencies(ctx context.Context) (map[uri.URI][]*provider.Dep, error) {
+	if sc.BaseConfig.DependencyProviderPath != "" {
+		return sc.LSPServiceClientBase.GetDependencies(ctx)
+	}
+	if len(sc.BaseConfig.WorkspaceFolders) == 0 {
+		return nil, nil
+	}
+	lock, manifest, err := loadGemfileLock(ctx, sc.Config.BundlerPath, fileuri.Path(sc.BaseConfig.WorkspaceFolders[0]))
+	if err != nil {
+		return nil, err
+	}
+	return map[uri.URI][]*provider.Dep{manifest: lock.deps()}, nil
+}
+
+// GetDependenciesDAG returns the gems of the Gemfile and the gems they depend
+// on as resolved in the Gemfile.lock
+func (sc *RubyServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]provider.DepDAGItem, error) {
+	if sc.BaseConfig.DependencyProviderPath != "" || len(sc.BaseConfig.WorkspaceFolders) == 0 {
+		return nil, nil
+	}
+	lock, manifest, err := loadGemfileLock(ctx, sc.Config.BundlerPath, fileuri.Path(sc.BaseConfig.WorkspaceFolders[0]))
+	if err != nil {
+		return nil, err
+	}
+	return map[uri.URI][]provider.DepDAGItem{manifest: lock.dag()}, nil
+}
@@ -0,0 +1,102 @@
+package ruby
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/external-providers/generic-external-provider/pkg/server_configurations/internal/symbols"
+	base "github.com/konveyor/analyzer-lsp/lsp/base_service_client"
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+// SymbolSearchHelper matches the symbols answered by solargraph and ruby-lsp
+// with Ruby fully qualified names: `ActiveRecord::Base` for constants,
+// `Foo::Bar#baz` for instance methods and `Foo::Bar.baz` for class methods.
+// Patterns are regular expressions. As Ruby looks constants up from the
+// namespace they are referenced in, a pattern matches the symbols nested in
+// any namespace, `Base` matches `ActiveRecord::Base`, unless it starts with
+// `::`.
+type SymbolSearchHelper struct{}
+
+var _ base.SymbolSearchHelper = SymbolSearchHelper{}
+
+// trailingMethod is the method ending a pattern with the separator from its
+// receiver, the `.` and the `?` it may have are not regex operators
+var trailingMethod = regexp.MustCompile(`[A-Za-z0-9_](?:\.|#)[a-z_][A-Za-z0-9_]*[?!=]?$`)
+
+// singletonClass is how ruby-lsp names the owner of class methods, such as
+// `Foo::Bar::<Class:Bar>`
+var singletonClass = regexp.MustCompile(`(?:^|::)<Class:([^>]*)>$`)
+
+var patterns = &symbols.Patterns{
+	TrailingName: regexp.MustCompile(`(?:^|::|#|\.)([A-Za-z_][A-Za-z0-9_]*[?!=]?)$`),
+	Expr: func(pattern string) string {
+		expr := trailingMethod.ReplaceAllStringFunc(pattern, regexp.QuoteMeta)
+		if strings.HasPrefix(expr, "::") {
+			return "^(?:" + expr[2:] + ")$"
+		}
+		return "^(?:.*::)?(?:" + expr + ")$"
+	},
+}
+
+func (SymbolSearchHelper) GetQuery(pattern string) string {
+	return patterns.Query(pattern)
+}
+
+func (SymbolSearchHelper) MatchSymbol(pattern string, symbol protocol.WorkspaceSymbol) bool {
+	regex, err := patterns.Regex(pattern)
+	if err != nil {
+		return false
+	}
+	return regex.MatchString(symbolName(symbol))
+}
+
+// isMethodPattern reports whether the pattern is a method rather than a
+// constant
+func isMethodPattern(pattern string) bool {
+	return trailingMethod.MatchString(pattern)
+}
+
+// symbolName returns the fully qualified name of a symbol. solargraph names
+// the symbols with their path while ruby-lsp gives their name in their
+// container.
+func symbolName(symbol protocol.WorkspaceSymbol) string {
+	name, container := symbol.Name, symbol.ContainerName
+	if container == "" || strings.HasPrefix(name, container) {
+		return name
+	}
+	singleton := false
+	if m := singletonClass.FindStringSubmatchIndex(container); m != nil {
+		singleton = true
+		if m[0] == 0 {
+			container = container[m[2]:m[3]]
+		} else {
+			container = container[:m[0]]
+		}
+	}
+	switch symbol.Kind {
+	case protocol.Method, protocol.Function, protocol.Constructor:
+		if singleton {
+			return container + "." + name
+		}
+		return container + "#" + name
+	}
+	return container + "::" + name
+}
+
+// LanguageID returns the language ID of the documents of Ruby sources sent
+// to the server, empty for the other files
+func LanguageID(path string) string {
+	switch filepath.Base(path) {
+	case "Gemfile", "Rakefile", "Guardfile", "Capfile", "config.ru":
+		return "ruby"
+	}
+	switch filepath.Ext(path) {
+	case ".rb", ".rake", ".gemspec", ".ru", ".rbw", ".jbuilder":
+		return "ruby"
+	case ".erb":
+		return "erb"
+	}
+	return ""
+}
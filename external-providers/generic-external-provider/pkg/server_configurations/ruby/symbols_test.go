@@ -0,0 +1,114 @@
+package ruby
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+func symbol(name, container string, kind protocol.SymbolKind) protocol.WorkspaceSymbol {
+	return protocol.WorkspaceSymbol{
+		BaseSymbolInformation: protocol.BaseSymbolInformation{Name: name, ContainerName: container, Kind: kind},
+	}
+}
+
+func TestSymbolSearchHelper_GetQuery(t *testing.T) {
+	tests := map[string]string{
+		"ActiveRecord::Base":    "Base",
+		"::User":                "User",
+		"Foo::Bar#valid?":       "valid?",
+		"Foo::Bar.find_by_name": "find_by_name",
+		"ActiveRecord::.*":      "",
+		"Foo::(Bar|Baz)":        "",
+	}
+	for pattern, want := range tests {
+		if got := (SymbolSearchHelper{}).GetQuery(pattern); got != want {
+			t.Errorf("GetQuery(%s) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestSymbolSearchHelper_MatchSymbol(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		symbol  protocol.WorkspaceSymbol
+		want    bool
+	}{
+		{name: "solargraph constant", pattern: "Billing::Invoice", symbol: symbol("Billing::Invoice", "Billing", protocol.Class), want: true},
+		{name: "ruby-lsp constant", pattern: "Billing::Invoice", symbol: symbol("Invoice", "Billing", protocol.Class), want: true},
+		{name: "nested in another namespace", pattern: "Invoice", symbol: symbol("Billing::Invoice", "Billing", protocol.Class), want: true},
+		{name: "top level constant", pattern: "::Invoice", symbol: symbol("Billing::Invoice", "Billing", protocol.Class), want: false},
+		{name: "longer constant", pattern: "Billing::Invoice", symbol: symbol("Billing::InvoiceItem", "Billing", protocol.Class), want: false},
+		{name: "regex", pattern: "Billing::.*", symbol: symbol("Payment", "Billing", protocol.Module), want: true},
+		{name: "solargraph instance method", pattern: "Billing::Invoice#total", symbol: symbol("Billing::Invoice#total", "Billing::Invoice", protocol.Method), want: true},
+		{name: "ruby-lsp instance method", pattern: "Billing::Invoice#total", symbol: symbol("total", "Billing::Invoice", protocol.Method), want: true},
+		{name: "ruby-lsp class method", pattern: "Billing::Invoice.due", symbol: symbol("due", "Billing::Invoice::<Class:Invoice>", protocol.Method), want: true},
+		{name: "class method is not an instance method", pattern: "Billing::Invoice.due", symbol: symbol("due", "Billing::Invoice", protocol.Method), want: false},
+		{name: "method with a question mark", pattern: "Invoice#paid?", symbol: symbol("paid?", "Invoice", protocol.Method), want: true},
+		{name: "method without the question mark", pattern: "Invoice#paid?", symbol: symbol("paid", "Invoice", protocol.Method), want: false},
+		{name: "top level class method", pattern: "Invoice.due", symbol: symbol("due", "<Class:Invoice>", protocol.Method), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (SymbolSearchHelper{}).MatchSymbol(tt.pattern, tt.symbol); got != tt.want {
+				t.Errorf("MatchSymbol(%s, %s) = %v, want %v", tt.pattern, symbolName(tt.symbol), got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_constantReferences(t *testing.T) {
+	content := []byte(`# ActiveRecord::Base is deprecated here
+class User < ActiveRecord::Base
+  has_many :posts
+end
+
+class Post < ::ActiveRecord::Base
+  belongs_to :user
+  validates_with ActiveRecord::BaseValidator
+end
+`)
+	lines := func(pattern string) []uint32 {
+		regex, err := constantRegex(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []uint32{}
+		for _, l := range constantReferences(regex, "file:///app/models.rb", content) {
+			got = append(got, l.Range.Start.Line)
+		}
+		return got
+	}
+	if got := lines("ActiveRecord::Base"); !reflect.DeepEqual(got, []uint32{1, 5}) {
+		t.Errorf("references to ActiveRecord::Base on lines %v", got)
+	}
+	if got := lines("Base"); !reflect.DeepEqual(got, []uint32{1, 5}) {
+		t.Errorf("references to Base on lines %v", got)
+	}
+	if got := lines("::Base"); !reflect.DeepEqual(got, []uint32{}) {
+		t.Errorf("references to the top level Base on lines %v", got)
+	}
+
+	regex, _ := constantRegex("ActiveRecord::Base")
+	l := constantReferences(regex, "file:///app/models.rb", content)[0]
+	if l.Range.Start.Character != 13 || l.Range.End.Character != 31 {
+		t.Errorf("unexpected range of the reference %+v", l.Range)
+	}
+}
+
+func TestLanguageID(t *testing.T) {
+	tests := map[string]string{
+		"app/models/user.rb":            "ruby",
+		"lib/tasks/db.rake":             "ruby",
+		"Gemfile":                       "ruby",
+		"app/views/users/show.html.erb": "erb",
+		"app/assets/app.js":             "",
+	}
+	for path, want := range tests {
+		if got := LanguageID(path); got != want {
+			t.Errorf("LanguageID(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
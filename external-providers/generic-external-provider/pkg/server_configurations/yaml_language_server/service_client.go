@@ -6,12 +6,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	jsonrpc2 "github.com/konveyor/analyzer-lsp/jsonrpc2_v2"
 	base "github.com/konveyor/analyzer-lsp/lsp/base_service_client"
+	"github.com/konveyor/analyzer-lsp/fileuri"
 	"github.com/konveyor/analyzer-lsp/lsp/protocol"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/swaggest/openapi-go/openapi3"
@@ -225,7 +225,7 @@ func (sc *YamlServiceClient) EvaluateReferenced(ctx context.Context, cap string,
 	}
 
 	// Get all yaml files
-	folder := strings.TrimPrefix(sc.Config.WorkspaceFolders[0], "file://")
+	folder := fileuri.Path(sc.Config.WorkspaceFolders[0])
 	var yamlFiles []string
 	err = filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -292,7 +292,7 @@ func (sc *YamlServiceClient) EvaluateReferenced(ctx context.Context, cap string,
 
 		// This function gets the diagnostics
 		var res json.RawMessage
-		err = sc.Conn.Call(ctx, "yaml/get/jsonSchema", yamlFiles[batchLeft]).Await(ctx, &res)
+		err = sc.Call(ctx, "yaml/get/jsonSchema", yamlFiles[batchLeft], &res)
 		if err != nil {
 			return provider.ProviderEvaluateResponse{}, err
 		}
@@ -308,8 +308,9 @@ func (sc *YamlServiceClient) EvaluateReferenced(ctx context.Context, cap string,
 			for _, diagnostic := range diagnostics {
 				lineNumber := int(diagnostic.Range.Start.Line)
 				incidents = append(incidents, provider.IncidentContext{
-					FileURI:    uri.URI(yamlFiles[i]),
-					LineNumber: &lineNumber,
+					FileURI:     uri.URI(yamlFiles[i]),
+					LineNumber:  &lineNumber,
+					MatchedText: base.RangeText(uri.URI(yamlFiles[i]), diagnostic.Range),
 				})
 			}
 		}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"unicode"
 
 	"github.com/konveyor/analyzer-lsp/engine/labels"
 	"github.com/konveyor/analyzer-lsp/provider"
@@ -19,6 +23,10 @@ const (
 	golangDownloadableDepSourceLabel = "downloadable"
 )
 
+// modCacheDir is the module cache the licenses of the dependencies are read
+// from, they are left unknown without it
+var modCacheDir string
+
 // TODO implement this for real
 func main() {
 	ll, err := GetDependenciesDAG()
@@ -64,6 +72,9 @@ func GetDependenciesDAG() (map[uri.URI][]provider.DepDAGItem, error) {
 	file := uri.File(path)
 
 	moddir := filepath.Dir(path)
+	if out, err := exec.Command("go", "env", "GOMODCACHE").Output(); err == nil {
+		modCacheDir = strings.TrimSpace(string(out))
+	}
 	// get the graph output
 	buf := bytes.Buffer{}
 	cmd := exec.Command("go", "mod", "graph")
@@ -103,9 +114,71 @@ func parseGoDepString(dep string) (provider.Dep, error) {
 		labels.AsString(provider.DepSourceLabel, golangDownloadableDepSourceLabel),
 		labels.AsString(provider.DepLanguageLabel, "go"),
 	}
+	if license := moduleLicense(modCacheDir, d.Name, d.Version); license != "" {
+		d.Labels = append(d.Labels, provider.DepLicenseLabels([]string{license})...)
+	}
 	return d, nil
 }
 
+// moduleLicense detects the license of a module from its LICENSE file in the
+// module cache, in the extracted module or else in its zip
+func moduleLicense(cacheDir, name, version string) string {
+	if cacheDir == "" {
+		return ""
+	}
+	escapedName, escapedVersion := escapeModulePath(name), escapeModulePath(version)
+	dir := filepath.Join(cacheDir, escapedName+"@"+escapedVersion)
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			if e.Type().IsRegular() && provider.IsLicenseFile(e.Name()) {
+				if text, err := os.ReadFile(filepath.Join(dir, e.Name())); err == nil {
+					return provider.DetectLicense(text)
+				}
+			}
+		}
+		return ""
+	}
+	r, err := zip.OpenReader(filepath.Join(cacheDir, "cache", "download", escapedName, "@v", escapedVersion+".zip"))
+	if err != nil {
+		return ""
+	}
+	defer r.Close()
+	prefix := name + "@" + version + "/"
+	for _, f := range r.File {
+		file, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || strings.Contains(file, "/") || !provider.IsLicenseFile(file) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return ""
+		}
+		text, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return ""
+		}
+		return provider.DetectLicense(text)
+	}
+	return ""
+}
+
+// escapeModulePath escapes the upper case letters of a module path or version
+// as the module cache does, github.com/Masterminds becomes
+// github.com/!masterminds
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteRune('!')
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
 // parseGoDepLines parses go mod graph output
 func parseGoDepLines(lines []string) ([]provider.DepDAGItem, error) {
 	depsListed := map[string][]string{}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"archive/zip"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -122,3 +125,47 @@ github.com/antchfx/xmlquery@v1.3.12 github.com/golang/groupcache@v0.0.0-20200121
 		})
 	}
 }
+
+func Test_moduleLicense(t *testing.T) {
+	cacheDir := t.TempDir()
+	mit := "Permission is hereby granted, free of charge, to any person obtaining a copy"
+	extracted := filepath.Join(cacheDir, "github.com", "!burnt!sushi", "toml@v1.3.2")
+	if err := os.MkdirAll(extracted, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(extracted, "COPYING"), []byte(mit), 0644); err != nil {
+		t.Fatal(err)
+	}
+	zipDir := filepath.Join(cacheDir, "cache", "download", "golang.org", "x", "text", "@v")
+	if err := os.MkdirAll(zipDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(zipDir, "v0.14.0.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	license, err := w.Create("golang.org/x/text@v0.14.0/LICENSE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	license.Write([]byte("Redistribution and use in source and binary forms, with or without modification, " +
+		"are permitted provided that... Neither the name of Google Inc. nor the names of its contributors"))
+	w.Close()
+	f.Close()
+
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{name: "github.com/BurntSushi/toml", version: "v1.3.2", want: "MIT"},
+		{name: "golang.org/x/text", version: "v0.14.0", want: "BSD-3-Clause"},
+		{name: "golang.org/x/mod", version: "v0.14.0", want: ""},
+	}
+	for _, tt := range tests {
+		if got := moduleLicense(cacheDir, tt.name, tt.version); got != tt.want {
+			t.Errorf("moduleLicense(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ require (
 	go.opentelemetry.io/otel v1.11.2
 	google.golang.org/grpc v1.62.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -13,13 +13,14 @@ import (
 )
 
 var (
-	port          = flag.Int("port", 0, "Port must be set")
+	port          = flag.Int("port", 0, "Port must be set unless a socket is")
 	logLevel      = flag.Int("log-level", 5, "Level to log")
 	lspServerName = flag.String("name", "java", "name of the lsp to be used in rules")
 	contextLines  = flag.Int("contxtLines", 10, "lines of context for the code snippet")
 	certFile      = flag.String("certFile", "", "Path to the cert file")
 	keyFile       = flag.String("keyFile", "", "Path to the key file")
 	secretKey     = flag.String("secretKey", "", "Secret Key value")
+	serverFlags   = provider.NewServerFlags(flag.CommandLine)
 )
 
 func main() {
@@ -37,8 +38,8 @@ func main() {
 	if logLevel != nil && *logLevel != 5 {
 		logrusLog.SetLevel(logrus.Level(*logLevel))
 	}
-	if port == nil || *port == 0 {
-		log.Error(fmt.Errorf("port unspecified"), "port number must be specified")
+	if (port == nil || *port == 0) && serverFlags.Socket == "" {
+		log.Error(fmt.Errorf("port unspecified"), "port number or socket must be specified")
 		panic(1)
 	}
 	var c string
@@ -57,7 +58,7 @@ func main() {
 		secret = *secretKey
 	}
 
-	s := provider.NewServer(client, *port, c, k, secret, log)
+	s := provider.NewServer(client, *port, c, k, secret, log, serverFlags.Options()...)
 	ctx := context.TODO()
 	s.Start(ctx)
 }
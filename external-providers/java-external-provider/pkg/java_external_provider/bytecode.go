@@ -0,0 +1,470 @@
+package java
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/tracing"
+	"go.lsp.dev/uri"
+)
+
+// classReference is a declaration or a use of a type or a member found in a
+// class file, location is the location of the referenced conditions it is
+// matched by
+type classReference struct {
+	location string
+	name     string
+	line     int
+	// signature is the signature of the called and declared methods
+	signature *methodSignature
+}
+
+// bytecodeClass is a class file of a binary analyzed without decompiling it
+type bytecodeClass struct {
+	path       string
+	name       string
+	superName  string
+	interfaces []string
+	sourceFile string
+	references []classReference
+}
+
+// bytecodeIndex answers the referenced conditions with the class files of the
+// application and of the jars that are not known libraries, the binary is
+// neither decompiled nor loaded in the language server. The class files are
+// parsed the first time a condition is evaluated.
+type bytecodeIndex struct {
+	log        logr.Logger
+	classFiles []string
+
+	once    sync.Once
+	classes []bytecodeClass
+}
+
+func newBytecodeIndex(log logr.Logger, classFiles []string) *bytecodeIndex {
+	sort.Strings(classFiles)
+	return &bytecodeIndex{log: log, classFiles: classFiles}
+}
+
+func (b *bytecodeIndex) load() []bytecodeClass {
+	b.once.Do(func() {
+		for _, path := range b.classFiles {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				b.log.V(5).Error(err, "unable to read class file", "file", path)
+				continue
+			}
+			c, err := parseClassFile(content)
+			if err != nil {
+				b.log.V(5).Error(err, "unable to parse class file", "file", path)
+				continue
+			}
+			b.classes = append(b.classes, bytecodeClass{
+				path:       path,
+				name:       c.name,
+				superName:  c.superName,
+				interfaces: c.interfaces,
+				sourceFile: c.sourceFile,
+				references: c.references(),
+			})
+		}
+		b.log.V(3).Info("parsed class files", "classes", len(b.classes))
+	})
+	return b.classes
+}
+
+// subtypes returns the classes extending or implementing the type, directly
+// or through another subtype
+func (b *bytecodeIndex) subtypes(fqn string) []string {
+	children := map[string][]string{}
+	for _, c := range b.load() {
+		for _, parent := range append([]string{c.superName}, c.interfaces...) {
+			children[parent] = append(children[parent], c.name)
+		}
+	}
+	seen := map[string]bool{fqn: true}
+	subtypes := []string{}
+	for queue := []string{fqn}; len(queue) > 0; queue = queue[1:] {
+		for _, child := range children[queue[0]] {
+			if !seen[child] {
+				seen[child] = true
+				subtypes = append(subtypes, child)
+				queue = append(queue, child)
+			}
+		}
+	}
+	return subtypes
+}
+
+// references returns the declarations of the class and the references to
+// types and members of its declarations and code
+func (c *classFile) references() []classReference {
+	refs := []classReference{}
+	seen := map[classReference]bool{}
+	add := func(location, name string, line int, signature *methodSignature) {
+		if name == "" {
+			return
+		}
+		ref := classReference{location: location, name: name, line: line}
+		if signature == nil && seen[ref] {
+			return
+		}
+		seen[ref] = true
+		ref.signature = signature
+		refs = append(refs, ref)
+	}
+	addType := func(t string, line int) {
+		t = strings.TrimRight(t, "[]")
+		if !primitiveTypes[t] {
+			add("type", t, line, nil)
+		}
+	}
+	addDescriptor := func(descriptor string, line int) {
+		if parameters, returnType, ok := parseMethodDescriptor(descriptor); ok {
+			for _, t := range append(parameters, returnType) {
+				addType(t, line)
+			}
+		} else if t, _ := parseFieldDescriptor(descriptor, 0); t != "" {
+			addType(t, line)
+		}
+	}
+
+	classLine := 0
+	for _, m := range c.methods {
+		if first := m.firstLine(); first != 0 && (classLine == 0 || first < classLine) {
+			classLine = first
+		}
+	}
+
+	add("class", c.name, classLine, nil)
+	if c.superName != "java.lang.Object" {
+		add("inheritance", c.superName, classLine, nil)
+		addType(c.superName, classLine)
+	}
+	for _, i := range c.interfaces {
+		add("inheritance", i, classLine, nil)
+		add("implements_type", i, classLine, nil)
+		addType(i, classLine)
+	}
+	for _, a := range c.annotations {
+		add("annotation", a, classLine, nil)
+		addType(a, classLine)
+	}
+
+	for _, f := range c.fields {
+		t, _ := parseFieldDescriptor(f.descriptor, 0)
+		add("field", strings.TrimRight(t, "[]"), classLine, nil)
+		addType(t, classLine)
+		for _, a := range f.annotations {
+			add("annotation", a, classLine, nil)
+			addType(a, classLine)
+		}
+	}
+
+	for _, m := range c.methods {
+		line := m.firstLine()
+		if line == 0 {
+			line = classLine
+		}
+		parameters, returnType, ok := parseMethodDescriptor(m.descriptor)
+		if !ok {
+			continue
+		}
+		if !strings.HasPrefix(m.name, "<") {
+			add("method", c.name+"."+m.name, line, &methodSignature{name: m.name, parameterTypes: parameters, returnType: returnType})
+			add("return_type", strings.TrimRight(returnType, "[]"), line, nil)
+		}
+		addDescriptor(m.descriptor, line)
+		for _, a := range m.annotations {
+			add("annotation", a, line, nil)
+			addType(a, line)
+		}
+		for _, local := range m.locals {
+			if local.name == "this" {
+				continue
+			}
+			t, _ := parseFieldDescriptor(local.descriptor, 0)
+			// the scope of a variable starts after the instruction storing
+			// its initial value
+			localLine := 0
+			if local.pc > 0 {
+				localLine = m.line(local.pc - 1)
+			}
+			if localLine == 0 {
+				localLine = line
+			}
+			add("variable_declaration", strings.TrimRight(t, "[]"), localLine, nil)
+			addType(t, localLine)
+		}
+		for _, ins := range instructions(m.code) {
+			insLine := m.line(ins.pc)
+			if insLine == 0 {
+				insLine = line
+			}
+			switch ins.opcode {
+			case opNew:
+				add("constructor_call", c.className(ins.index), insLine, nil)
+				addType(c.className(ins.index), insLine)
+			case opANewArray, opCheckCast, opInstanceOf, opMultiANewArray:
+				addType(c.className(ins.index), insLine)
+			case opLdc, opLdcW:
+				if c.entry(ins.index).tag == constantClass {
+					addType(c.className(ins.index), insLine)
+				}
+			case opInvokeVirtual, opInvokeSpecial, opInvokeStatic, opInvokeInterface:
+				owner, name, descriptor := c.memberRef(ins.index)
+				addType(owner, insLine)
+				addDescriptor(descriptor, insLine)
+				// constructors are found with the new instructions
+				if name == "" || name == "<init>" {
+					continue
+				}
+				parameters, returnType, _ := parseMethodDescriptor(descriptor)
+				add("method_call", owner+"."+name, insLine, &methodSignature{name: name, parameterTypes: parameters, returnType: returnType})
+			case opGetStatic, opPutStatic, opGetField, opPutField:
+				owner, name, descriptor := c.memberRef(ins.index)
+				addType(owner, insLine)
+				addDescriptor(descriptor, insLine)
+				// the constants of an enum are static fields of its type
+				if t, _ := parseFieldDescriptor(descriptor, 0); ins.opcode == opGetStatic && t == owner {
+					add("enum", owner+"."+name, insLine, nil)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+var primitiveTypes = map[string]bool{
+	"byte":    true,
+	"char":    true,
+	"double":  true,
+	"float":   true,
+	"int":     true,
+	"long":    true,
+	"short":   true,
+	"boolean": true,
+	"void":    true,
+}
+
+// evaluateBytecode answers a referenced condition with the class files of
+// the binary
+func (p *javaServiceClient) evaluateBytecode(ctx context.Context, cond referenceCondition) (provider.ProviderEvaluateResponse, error) {
+	if cond.Annotated.Pattern != "" {
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("annotated is not supported by the bytecode analysis of binaries")
+	}
+	location := strings.ToLower(cond.Location)
+	if _, ok := locationToCode[location]; !ok {
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("unknown location '%s'", cond.Location)
+	}
+	if location == "" {
+		location = "type"
+	}
+	incidents, err := p.bytecodeIncidents(cond, location, cond.Pattern)
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	if cond.IncludeSubtypes {
+		if strings.ContainsAny(cond.Pattern, "*()[]{}|?+\\^$") {
+			return provider.ProviderEvaluateResponse{}, fmt.Errorf("includeSubtypes requires a fully qualified type name, got pattern '%s'", cond.Pattern)
+		}
+		for _, subtype := range p.bytecode.subtypes(cond.Pattern) {
+			subtypeIncidents, err := p.bytecodeIncidents(cond, location, subtype)
+			if err != nil {
+				return provider.ProviderEvaluateResponse{}, err
+			}
+			for i := range subtypeIncidents {
+				subtypeIncidents[i].Variables["subtype"] = subtype
+			}
+			incidents = append(incidents, subtypeIncidents...)
+		}
+	}
+	if len(incidents) == 0 {
+		return provider.ProviderEvaluateResponse{Matched: false}, nil
+	}
+	return provider.ProviderEvaluateResponse{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
+
+func (p *javaServiceClient) bytecodeIncidents(cond referenceCondition, location, pattern string) ([]provider.IncidentContext, error) {
+	regex, err := referencePatternRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var signature *signatureMatcher
+	if location == "method_call" && cond.hasSignature() {
+		if signature, err = newSignatureMatcher(cond); err != nil {
+			return nil, err
+		}
+	}
+	// the type references answer the default location, packages are the
+	// ones of the referenced types
+	refLocation := location
+	switch location {
+	case "package", "import":
+		refLocation = "type"
+	}
+
+	incidents := []provider.IncidentContext{}
+	for _, c := range p.bytecode.load() {
+		if !matchesFilepaths(c.path, cond.Filepaths) {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, ref := range c.references {
+			if ref.location != refLocation {
+				continue
+			}
+			name := ref.name
+			if location == "package" {
+				name = name[:max(strings.LastIndex(name, "."), 0)]
+			}
+			if name == "" || !regex.MatchString(name) {
+				continue
+			}
+			if signature != nil && (ref.signature == nil || !signature.matches(*ref.signature)) {
+				continue
+			}
+			key := fmt.Sprintf("%s:%d", name, ref.line)
+			if location == "import" {
+				// a type is imported once
+				key = name
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			incidents = append(incidents, bytecodeIncident(c, location, name, ref.line))
+		}
+	}
+	return incidents, nil
+}
+
+func matchesFilepaths(path string, filepaths []string) bool {
+	if filepaths == nil {
+		return true
+	}
+	for _, fp := range filepaths {
+		if strings.HasSuffix(path, fp) {
+			return true
+		}
+	}
+	return false
+}
+
+// bytecodeIncident returns the incident of a reference found in a class
+// file, the line is the one of the source the class was compiled from
+func bytecodeIncident(c bytecodeClass, location, name string, line int) provider.IncidentContext {
+	u := uri.File(c.path)
+	incident := provider.IncidentContext{
+		FileURI: u,
+		Variables: map[string]interface{}{
+			KIND_EXTRA_KEY:  location,
+			SYMBOL_NAME_KEY: name,
+			FILE_KEY:        string(u),
+			"package":       c.name[:max(strings.LastIndex(c.name, "."), 0)],
+			"class":         c.name,
+		},
+	}
+	if c.sourceFile != "" {
+		incident.Variables["sourceFile"] = c.sourceFile
+	}
+	if line > 0 {
+		incident.LineNumber = &line
+		incident.CodeLocation = &provider.Location{
+			StartPosition: provider.Position{Line: float64(line - 1)},
+			EndPosition:   provider.Position{Line: float64(line - 1)},
+		}
+	}
+	return incident
+}
+
+// methodParametersRegex matches the parameters of the method of a pattern,
+// e.g. the (*) of java.util.List.add(*)
+var methodParametersRegex = regexp.MustCompile(`\(([^()|]*)\)$`)
+
+// referencePatternRegex turns the pattern of a referenced condition into a
+// regex matching the names of the references: * matches any characters, (|)
+// are alternatives and the parameters of the methods are left out, as in the
+// search patterns of the language server
+func referencePatternRegex(pattern string) (*regexp.Regexp, error) {
+	pattern = methodParametersRegex.ReplaceAllString(pattern, "")
+	var b strings.Builder
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '(', ')', '|':
+			b.WriteRune(r)
+		case '$':
+			// nested classes are named with dots
+			b.WriteString(`\.`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	regex, err := regexp.Compile("^(?:" + b.String() + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile pattern '%s': %w", pattern, err)
+	}
+	return regex, nil
+}
+
+// explodeBytecode unpacks the archive at archivePath like decompileJava, but
+// rather than decompiling them it returns the class files of the application
+// and of the jars that are not known libraries. The known libraries are only
+// dependencies, as when the binary is decompiled. It returns the path to the
+// exploded archive, the path to the java project the other files of the
+// archive are moved to and the class files.
+func explodeBytecode(ctx context.Context, log logr.Logger, archivePath, m2RepoPath string, index *mavenIndex) (explodedPath, projectPath string, classFiles []string, err error) {
+	ctx, span := tracing.StartNewSpan(ctx, "explode-bytecode")
+	defer span.End()
+
+	projectPath = filepath.Join(filepath.Dir(archivePath), "java-project")
+	explodedPath, jobs, deps, err := explode(ctx, log, archivePath, projectPath, m2RepoPath, index)
+	if err != nil {
+		log.Error(err, "failed to explode archive", "path", archivePath)
+		return "", "", nil, err
+	}
+	err = createJavaProject(ctx, projectPath, deduplicateJavaArtifacts(deps))
+	if err != nil {
+		log.Error(err, "failed to create java project", "path", projectPath)
+		return "", "", nil, err
+	}
+
+	seen := map[string]bool{}
+	for len(jobs) > 0 {
+		job := jobs[0]
+		jobs = jobs[1:]
+		if seen[job.inputPath] {
+			continue
+		}
+		seen[job.inputPath] = true
+		switch job.artifact.packaging {
+		case ClassFile:
+			classFiles = append(classFiles, job.inputPath)
+		case JavaArchive:
+			// jars that are not known libraries are part of the application
+			_, nestedJobs, _, err := explode(ctx, log, job.inputPath, projectPath, m2RepoPath, index)
+			if err != nil {
+				log.V(5).Error(err, "failed to explode jar", "path", job.inputPath)
+				continue
+			}
+			jobs = append(jobs, nestedJobs...)
+		}
+	}
+	log.V(5).Info("exploded archive for bytecode analysis", "path", archivePath, "classes", len(classFiles))
+	return explodedPath, projectPath, classFiles, nil
+}
@@ -0,0 +1,295 @@
+package java
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+)
+
+var bytecodeTestClasses = []string{
+	"testdata/bytecode/com/example/apps/App.class",
+	"testdata/bytecode/com/example/apps/GenericClass.class",
+}
+
+func Test_parseClassFile(t *testing.T) {
+	content, err := os.ReadFile(bytecodeTestClasses[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := parseClassFile(content)
+	if err != nil {
+		t.Fatalf("parseClassFile() error = %v", err)
+	}
+	if c.name != "com.example.apps.App" || c.superName != "java.lang.Object" || c.sourceFile != "App.java" {
+		t.Errorf("parseClassFile() = %s extends %s in %s", c.name, c.superName, c.sourceFile)
+	}
+	methods := []string{}
+	for _, m := range c.methods {
+		methods = append(methods, m.name+m.descriptor)
+	}
+	if want := []string{"<init>()V", "main([Ljava/lang/String;)V"}; !reflect.DeepEqual(methods, want) {
+		t.Errorf("parseClassFile() methods = %v, want %v", methods, want)
+	}
+
+	if _, err := parseClassFile(content[:100]); err == nil {
+		t.Errorf("parseClassFile() of a truncated class file must fail")
+	}
+}
+
+func Test_parseMethodDescriptor(t *testing.T) {
+	parameters, returnType, ok := parseMethodDescriptor("(I[[Ljava/lang/String;Ljava/util/Map$Entry;J)[B")
+	if !ok {
+		t.Fatalf("parseMethodDescriptor() failed")
+	}
+	if want := []string{"int", "java.lang.String[][]", "java.util.Map.Entry", "long"}; !reflect.DeepEqual(parameters, want) {
+		t.Errorf("parseMethodDescriptor() parameters = %v, want %v", parameters, want)
+	}
+	if returnType != "byte[]" {
+		t.Errorf("parseMethodDescriptor() return type = %s, want byte[]", returnType)
+	}
+	if _, _, ok := parseMethodDescriptor("(Ljava/lang/String"); ok {
+		t.Errorf("parseMethodDescriptor() of an invalid descriptor must fail")
+	}
+}
+
+func Test_referencePatternRegex(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "io.fabric8.kubernetes.*", name: "io.fabric8.kubernetes.api.model.Pod", want: true},
+		{pattern: "io.fabric8.kubernetes.*", name: "io.fabric8.openshift.Route", want: false},
+		{pattern: "java.io.PrintStream.println(*)", name: "java.io.PrintStream.println", want: true},
+		{pattern: "java.util.(List|Map)", name: "java.util.Map", want: true},
+		{pattern: "java.util.Map$Entry", name: "java.util.Map.Entry", want: true},
+		{pattern: "java.util.List", name: "java.util.ListIterator", want: false},
+	}
+	for _, tt := range tests {
+		regex, err := referencePatternRegex(tt.pattern)
+		if err != nil {
+			t.Fatalf("referencePatternRegex(%s) error = %v", tt.pattern, err)
+		}
+		if got := regex.MatchString(tt.name); got != tt.want {
+			t.Errorf("referencePatternRegex(%s) matches %s = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_evaluateBytecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition referenceCondition
+		want      []lineIncidentWant
+		wantErr   bool
+	}{
+		{
+			name:      "type references",
+			condition: referenceCondition{Pattern: "io.fabric8.kubernetes.*"},
+			want: []lineIncidentWant{
+				{file: "App.class", line: 10, variables: bytecodeVariables("type", "io.fabric8.kubernetes.api.model.apiextensions.v1beta1.CustomResourceDefinition", "App")},
+			},
+		},
+		{
+			name:      "constructor calls",
+			condition: referenceCondition{Pattern: "com.example.apps.*", Location: "CONSTRUCTOR_CALL"},
+			want: []lineIncidentWant{
+				{file: "App.class", line: 13, variables: bytecodeVariables("constructor_call", "com.example.apps.GenericClass", "App")},
+			},
+		},
+		{
+			name:      "method calls with their parameters",
+			condition: referenceCondition{Pattern: "java.io.PrintStream.println(*)", Location: "METHOD_CALL", ParameterTypes: []string{"Object"}},
+			want: []lineIncidentWant{
+				{file: "App.class", line: 11, variables: bytecodeVariables("method_call", "java.io.PrintStream.println", "App")},
+			},
+		},
+		{
+			name:      "method calls with other parameters",
+			condition: referenceCondition{Pattern: "java.io.PrintStream.println", Location: "METHOD_CALL", ParameterTypes: []string{"java.lang.String"}},
+			want:      []lineIncidentWant{},
+		},
+		{
+			name:      "method declarations",
+			condition: referenceCondition{Pattern: "com.example.apps.*.get", Location: "METHOD"},
+			want: []lineIncidentWant{
+				{file: "GenericClass.class", line: 12, variables: bytecodeVariables("method", "com.example.apps.GenericClass.get", "GenericClass")},
+			},
+		},
+		{
+			name:      "variable declarations",
+			condition: referenceCondition{Pattern: "com.example.apps.GenericClass", Location: "VARIABLE_DECLARATION"},
+			want: []lineIncidentWant{
+				{file: "App.class", line: 13, variables: bytecodeVariables("variable_declaration", "com.example.apps.GenericClass", "App")},
+			},
+		},
+		{
+			name:      "packages",
+			condition: referenceCondition{Pattern: "io.fabric8.kubernetes.api.model.*", Location: "PACKAGE"},
+			want: []lineIncidentWant{
+				{file: "App.class", line: 10, variables: bytecodeVariables("package", "io.fabric8.kubernetes.api.model.apiextensions.v1beta1", "App")},
+			},
+		},
+		{
+			name:      "imports",
+			condition: referenceCondition{Pattern: "java.lang.System", Location: "IMPORT"},
+			want: []lineIncidentWant{
+				{file: "App.class", line: 11, variables: bytecodeVariables("import", "java.lang.System", "App")},
+			},
+		},
+		{
+			name:      "subtypes",
+			condition: referenceCondition{Pattern: "java.lang.Object", Location: "CLASS", IncludeSubtypes: true},
+			want: []lineIncidentWant{
+				{file: "App.class", line: 5, variables: withSubtype(bytecodeVariables("class", "com.example.apps.App", "App"), "com.example.apps.App")},
+				{file: "GenericClass.class", line: 7, variables: withSubtype(bytecodeVariables("class", "com.example.apps.GenericClass", "GenericClass"), "com.example.apps.GenericClass")},
+			},
+		},
+		{
+			name:      "annotated",
+			condition: referenceCondition{Pattern: "com.example.apps.App", Annotated: annotated{Pattern: "javax.ejb.Stateless"}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &javaServiceClient{
+				log:      testr.New(t),
+				bytecode: newBytecodeIndex(testr.New(t), append([]string{}, bytecodeTestClasses...)),
+			}
+			got, err := p.evaluateBytecode(context.TODO(), tt.condition)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateBytecode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			incidents := lineIncidents(got)
+			for i := range incidents {
+				delete(incidents[i].variables, FILE_KEY)
+			}
+			if !reflect.DeepEqual(incidents, tt.want) {
+				t.Errorf("evaluateBytecode() incidents = %+v, want %+v", incidents, tt.want)
+			}
+		})
+	}
+}
+
+func bytecodeVariables(kind, name, class string) map[string]interface{} {
+	return map[string]interface{}{
+		KIND_EXTRA_KEY:  kind,
+		SYMBOL_NAME_KEY: name,
+		"package":       "com.example.apps",
+		"class":         "com.example.apps." + class,
+		"sourceFile":    class + ".java",
+	}
+}
+
+func withSubtype(variables map[string]interface{}, subtype string) map[string]interface{} {
+	variables["subtype"] = subtype
+	return variables
+}
+
+func Test_explodeBytecode(t *testing.T) {
+	dir := t.TempDir()
+	// a known library, identified with the maven index, and a jar of the
+	// application that is not
+	known := filepath.Join(dir, "known.jar")
+	writeZip(t, known, map[string]string{"com/example/apps/GenericClass.class": bytecodeTestClasses[1]})
+	sha1, err := fileSHA1(known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexPath := filepath.Join(dir, "maven-index.txt")
+	if err := os.WriteFile(indexPath, []byte(sha1+" com.example:known:1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index, err := loadMavenIndex(indexPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	war := filepath.Join(dir, "app.war")
+	writeZip(t, war, map[string]string{
+		"WEB-INF/classes/com/example/apps/App.class": bytecodeTestClasses[0],
+		"WEB-INF/lib/app.jar":                        "testdata/bytecode/app.jar",
+		"WEB-INF/lib/known.jar":                      known,
+	})
+
+	explodedPath, projectPath, classFiles, err := explodeBytecode(context.TODO(), testr.New(t), war, filepath.Join(dir, "m2"), index)
+	if err != nil {
+		t.Fatalf("explodeBytecode() error = %v", err)
+	}
+	if explodedPath != filepath.Join(dir, "app-war-exploded") || projectPath != filepath.Join(dir, "java-project") {
+		t.Errorf("explodeBytecode() paths = %s, %s", explodedPath, projectPath)
+	}
+	got := []string{}
+	for _, f := range classFiles {
+		rel, _ := filepath.Rel(dir, f)
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+	want := []string{
+		"app-war-exploded/WEB-INF/classes/com/example/apps/App.class",
+		"app-war-exploded/WEB-INF/lib/app-jar-exploded/com/example/apps/App.class",
+		"app-war-exploded/WEB-INF/lib/app-jar-exploded/com/example/apps/GenericClass.class",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("explodeBytecode() class files = %v, want %v", got, want)
+	}
+
+	p := &javaServiceClient{
+		log:      testr.New(t),
+		bytecode: newBytecodeIndex(testr.New(t), classFiles),
+	}
+	response, err := p.evaluateBytecode(context.TODO(), referenceCondition{Pattern: "com.example.apps.GenericClass", Location: "CONSTRUCTOR_CALL"})
+	if err != nil {
+		t.Fatalf("evaluateBytecode() error = %v", err)
+	}
+	if len(response.Incidents) != 2 {
+		t.Errorf("expected the constructor calls of both copies of the class, got %+v", response.Incidents)
+	}
+	for _, incident := range response.Incidents {
+		if incident.IsDependencyIncident || incident.FileURI == "" {
+			t.Errorf("unexpected incident %+v", incident)
+		}
+	}
+}
+
+// writeZip writes a zip archive with the content of files of the test
+func writeZip(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		src, err := os.Open(files[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst, err := w.Create(name)
+		if err == nil {
+			_, err = io.Copy(dst, src)
+		}
+		src.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
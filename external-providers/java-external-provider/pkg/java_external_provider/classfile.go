@@ -0,0 +1,564 @@
+package java
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tags of the entries of the constant pool of a class file
+const (
+	constantUtf8               = 1
+	constantInteger            = 3
+	constantFloat              = 4
+	constantLong               = 5
+	constantDouble             = 6
+	constantClass              = 7
+	constantString             = 8
+	constantFieldref           = 9
+	constantMethodref          = 10
+	constantInterfaceMethodref = 11
+	constantNameAndType        = 12
+	constantMethodHandle       = 15
+	constantMethodType         = 16
+	constantDynamic            = 17
+	constantInvokeDynamic      = 18
+	constantModule             = 19
+	constantPackage            = 20
+)
+
+// Opcodes of the instructions referencing the constant pool the analysis of
+// binaries looks at
+const (
+	opLdc             = 0x12
+	opLdcW            = 0x13
+	opGetStatic       = 0xb2
+	opPutStatic       = 0xb3
+	opGetField        = 0xb4
+	opPutField        = 0xb5
+	opInvokeVirtual   = 0xb6
+	opInvokeSpecial   = 0xb7
+	opInvokeStatic    = 0xb8
+	opInvokeInterface = 0xb9
+	opNew             = 0xbb
+	opANewArray       = 0xbd
+	opCheckCast       = 0xc0
+	opInstanceOf      = 0xc1
+	opWide            = 0xc4
+	opMultiANewArray  = 0xc5
+	opTableSwitch     = 0xaa
+	opLookupSwitch    = 0xab
+)
+
+const classFileMagic = 0xCAFEBABE
+
+var errTruncatedClassFile = errors.New("truncated class file")
+
+// constantPoolEntry is an entry of the constant pool, a and b are the indexes
+// or values the entry is made of
+type constantPoolEntry struct {
+	tag  byte
+	a, b uint16
+	utf8 string
+}
+
+// classFile is what the analysis of binaries needs of a class file, the
+// names of the types are the fully qualified java names
+type classFile struct {
+	name        string
+	superName   string
+	interfaces  []string
+	sourceFile  string
+	annotations []string
+	fields      []classMember
+	methods     []classMember
+	pool        []constantPoolEntry
+}
+
+type classMember struct {
+	name        string
+	descriptor  string
+	annotations []string
+	// code is the bytecode of a method, empty for fields and abstract methods
+	code []byte
+	// lines maps the offsets of the code to the lines of the source
+	lines []lineNumber
+	// locals are the local variables, when the class was compiled with them
+	locals []localVariable
+}
+
+type lineNumber struct {
+	pc   int
+	line int
+}
+
+type localVariable struct {
+	pc         int
+	name       string
+	descriptor string
+}
+
+// classReader reads the big endian values of a class file
+type classReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *classReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.err = errTruncatedClassFile
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *classReader) u1() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *classReader) u2() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *classReader) u4() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// parseClassFile parses the constant pool, the declarations and the code of
+// the methods of a class file
+func parseClassFile(data []byte) (*classFile, error) {
+	r := &classReader{data: data}
+	if r.u4() != classFileMagic {
+		return nil, fmt.Errorf("not a class file")
+	}
+	// minor and major versions
+	r.u2()
+	r.u2()
+
+	c := &classFile{}
+	count := int(r.u2())
+	c.pool = make([]constantPoolEntry, count)
+	for i := 1; i < count && r.err == nil; i++ {
+		entry := constantPoolEntry{tag: r.u1()}
+		switch entry.tag {
+		case constantUtf8:
+			entry.utf8 = decodeModifiedUTF8(r.bytes(int(r.u2())))
+		case constantClass, constantString, constantMethodType, constantModule, constantPackage:
+			entry.a = r.u2()
+		case constantFieldref, constantMethodref, constantInterfaceMethodref, constantNameAndType,
+			constantDynamic, constantInvokeDynamic:
+			entry.a, entry.b = r.u2(), r.u2()
+		case constantInteger, constantFloat:
+			r.u4()
+		case constantLong, constantDouble:
+			r.bytes(8)
+		case constantMethodHandle:
+			entry.a, entry.b = uint16(r.u1()), r.u2()
+		default:
+			return nil, fmt.Errorf("unknown constant pool tag %d at index %d", entry.tag, i)
+		}
+		c.pool[i] = entry
+		// longs and doubles take two entries
+		if entry.tag == constantLong || entry.tag == constantDouble {
+			i++
+		}
+	}
+
+	// access flags
+	r.u2()
+	c.name = c.className(r.u2())
+	c.superName = c.className(r.u2())
+	for i := int(r.u2()); i > 0 && r.err == nil; i-- {
+		c.interfaces = append(c.interfaces, c.className(r.u2()))
+	}
+	c.fields = c.parseMembers(r)
+	c.methods = c.parseMembers(r)
+	c.parseAttributes(r, func(name string, attr *classReader) {
+		switch name {
+		case "SourceFile":
+			c.sourceFile = c.utf8(attr.u2())
+		case "RuntimeVisibleAnnotations", "RuntimeInvisibleAnnotations":
+			c.annotations = append(c.annotations, c.parseAnnotations(attr)...)
+		}
+	})
+	if r.err != nil {
+		return nil, r.err
+	}
+	return c, nil
+}
+
+func (c *classFile) parseMembers(r *classReader) []classMember {
+	members := []classMember{}
+	for i := int(r.u2()); i > 0 && r.err == nil; i-- {
+		// access flags
+		r.u2()
+		m := classMember{name: c.utf8(r.u2()), descriptor: c.utf8(r.u2())}
+		c.parseAttributes(r, func(name string, attr *classReader) {
+			switch name {
+			case "Code":
+				c.parseCode(attr, &m)
+			case "RuntimeVisibleAnnotations", "RuntimeInvisibleAnnotations":
+				m.annotations = append(m.annotations, c.parseAnnotations(attr)...)
+			}
+		})
+		members = append(members, m)
+	}
+	return members
+}
+
+// parseAttributes calls parse with a reader of the content of every
+// attribute, an error parsing the content of an attribute is ignored as the
+// rest of the class file can still be read
+func (c *classFile) parseAttributes(r *classReader, parse func(name string, attr *classReader)) {
+	for i := int(r.u2()); i > 0 && r.err == nil; i-- {
+		name := c.utf8(r.u2())
+		content := r.bytes(int(r.u4()))
+		if r.err == nil {
+			parse(name, &classReader{data: content})
+		}
+	}
+}
+
+func (c *classFile) parseCode(r *classReader, m *classMember) {
+	// max stack and max locals
+	r.u2()
+	r.u2()
+	m.code = r.bytes(int(r.u4()))
+	// exception table
+	r.bytes(int(r.u2()) * 8)
+	c.parseAttributes(r, func(name string, attr *classReader) {
+		switch name {
+		case "LineNumberTable":
+			for i := int(attr.u2()); i > 0 && attr.err == nil; i-- {
+				m.lines = append(m.lines, lineNumber{pc: int(attr.u2()), line: int(attr.u2())})
+			}
+		case "LocalVariableTable":
+			for i := int(attr.u2()); i > 0 && attr.err == nil; i-- {
+				pc := int(attr.u2())
+				// length
+				attr.u2()
+				m.locals = append(m.locals, localVariable{pc: pc, name: c.utf8(attr.u2()), descriptor: c.utf8(attr.u2())})
+				// index
+				attr.u2()
+			}
+		}
+	})
+}
+
+// parseAnnotations returns the types of the annotations of an annotations
+// attribute, the values of their elements are skipped
+func (c *classFile) parseAnnotations(r *classReader) []string {
+	annotations := []string{}
+	for i := int(r.u2()); i > 0 && r.err == nil; i-- {
+		if t := c.parseAnnotation(r); t != "" {
+			annotations = append(annotations, t)
+		}
+	}
+	return annotations
+}
+
+func (c *classFile) parseAnnotation(r *classReader) string {
+	t, _ := parseFieldDescriptor(c.utf8(r.u2()), 0)
+	for i := int(r.u2()); i > 0 && r.err == nil; i-- {
+		// element name
+		r.u2()
+		c.skipElementValue(r)
+	}
+	return t
+}
+
+func (c *classFile) skipElementValue(r *classReader) {
+	switch r.u1() {
+	case 'e':
+		r.u2()
+		r.u2()
+	case '@':
+		c.parseAnnotation(r)
+	case '[':
+		for i := int(r.u2()); i > 0 && r.err == nil; i-- {
+			c.skipElementValue(r)
+		}
+	default:
+		// constants and classes
+		r.u2()
+	}
+}
+
+func (c *classFile) entry(index uint16) constantPoolEntry {
+	if int(index) >= len(c.pool) {
+		return constantPoolEntry{}
+	}
+	return c.pool[index]
+}
+
+func (c *classFile) utf8(index uint16) string {
+	return c.entry(index).utf8
+}
+
+// className returns the java name of the class of a Class entry, arrays
+// are named after the type of their elements
+func (c *classFile) className(index uint16) string {
+	entry := c.entry(index)
+	if entry.tag != constantClass {
+		return ""
+	}
+	name := c.utf8(entry.a)
+	if strings.HasPrefix(name, "[") {
+		t, _ := parseFieldDescriptor(strings.TrimLeft(name, "["), 0)
+		return t
+	}
+	return javaClassName(name)
+}
+
+// memberRef returns the class, the name and the descriptor of a Fieldref,
+// Methodref or InterfaceMethodref entry
+func (c *classFile) memberRef(index uint16) (owner, name, descriptor string) {
+	entry := c.entry(index)
+	switch entry.tag {
+	case constantFieldref, constantMethodref, constantInterfaceMethodref:
+	default:
+		return "", "", ""
+	}
+	nameAndType := c.entry(entry.b)
+	return c.className(entry.a), c.utf8(nameAndType.a), c.utf8(nameAndType.b)
+}
+
+// instruction is an instruction of the code of a method referencing the
+// constant pool
+type instruction struct {
+	pc     int
+	opcode byte
+	index  uint16
+}
+
+// instructions returns the instructions of the code referencing the
+// constant pool. Decoding stops at an unknown opcode.
+func instructions(code []byte) []instruction {
+	found := []instruction{}
+	for pc := 0; pc < len(code); {
+		opcode := code[pc]
+		switch opcode {
+		case opLdc:
+			if pc+1 < len(code) {
+				found = append(found, instruction{pc: pc, opcode: opcode, index: uint16(code[pc+1])})
+			}
+		case opLdcW, opGetStatic, opPutStatic, opGetField, opPutField, opInvokeVirtual, opInvokeSpecial,
+			opInvokeStatic, opInvokeInterface, opNew, opANewArray, opCheckCast, opInstanceOf, opMultiANewArray:
+			if pc+2 < len(code) {
+				found = append(found, instruction{pc: pc, opcode: opcode, index: binary.BigEndian.Uint16(code[pc+1:])})
+			}
+		}
+		length := instructionLength(code, pc)
+		if length <= 0 {
+			break
+		}
+		pc += length
+	}
+	return found
+}
+
+// instructionLength returns the length of the instruction at pc, 0 when the
+// opcode is unknown
+func instructionLength(code []byte, pc int) int {
+	opcode := code[pc]
+	switch {
+	case opcode <= 0x0f:
+		return 1
+	case opcode == 0x10, opcode == opLdc:
+		return 2
+	case opcode == 0x11, opcode == opLdcW, opcode == 0x14:
+		return 3
+	case opcode >= 0x15 && opcode <= 0x19, opcode >= 0x36 && opcode <= 0x3a, opcode == 0xa9, opcode == 0xbc:
+		// loads, stores, ret and newarray
+		return 2
+	case opcode >= 0x1a && opcode <= 0x35, opcode >= 0x3b && opcode <= 0x83, opcode >= 0x85 && opcode <= 0x98:
+		return 1
+	case opcode == 0x84:
+		// iinc
+		return 3
+	case opcode >= 0x99 && opcode <= 0xa8, opcode == 0xc6, opcode == 0xc7:
+		// branches
+		return 3
+	case opcode == opTableSwitch, opcode == opLookupSwitch:
+		// the operands are aligned on 4 bytes from the start of the code
+		operands := pc + 1 + (4-(pc+1)%4)%4
+		if operands+12 > len(code) {
+			return 0
+		}
+		if opcode == opTableSwitch {
+			low := int32(binary.BigEndian.Uint32(code[operands+4:]))
+			high := int32(binary.BigEndian.Uint32(code[operands+8:]))
+			return operands + 12 + int(high-low+1)*4 - pc
+		}
+		pairs := int32(binary.BigEndian.Uint32(code[operands+4:]))
+		return operands + 8 + int(pairs)*8 - pc
+	case opcode >= 0xac && opcode <= 0xb1, opcode == 0xbe, opcode == 0xbf, opcode == 0xc2, opcode == 0xc3:
+		// returns, arraylength, athrow and monitors
+		return 1
+	case opcode >= opGetStatic && opcode <= opInvokeStatic, opcode == opNew, opcode == opANewArray,
+		opcode == opCheckCast, opcode == opInstanceOf:
+		return 3
+	case opcode == opInvokeInterface, opcode == 0xba, opcode == 0xc8, opcode == 0xc9:
+		// invokeinterface, invokedynamic, goto_w and jsr_w
+		return 5
+	case opcode == opMultiANewArray:
+		return 4
+	case opcode == opWide:
+		if pc+1 < len(code) && code[pc+1] == 0x84 {
+			return 6
+		}
+		return 4
+	}
+	return 0
+}
+
+// line returns the line of the source of the instruction at pc, 0 when the
+// class was compiled without line numbers
+func (m classMember) line(pc int) int {
+	line, start := 0, -1
+	for _, l := range m.lines {
+		if l.pc <= pc && l.pc > start {
+			line, start = l.line, l.pc
+		}
+	}
+	return line
+}
+
+// firstLine returns the first line of the code of a method
+func (m classMember) firstLine() int {
+	first := 0
+	for _, l := range m.lines {
+		if first == 0 || l.line < first {
+			first = l.line
+		}
+	}
+	return first
+}
+
+// javaClassName turns the internal name of a class into its java name, the
+// names of nested classes are separated with dots as in the source
+func javaClassName(internal string) string {
+	return strings.NewReplacer("/", ".", "$", ".").Replace(internal)
+}
+
+var primitiveDescriptors = map[byte]string{
+	'B': "byte",
+	'C': "char",
+	'D': "double",
+	'F': "float",
+	'I': "int",
+	'J': "long",
+	'S': "short",
+	'Z': "boolean",
+	'V': "void",
+}
+
+// parseFieldDescriptor returns the java type of the descriptor starting at
+// start and the offset following it, arrays end with []
+func parseFieldDescriptor(descriptor string, start int) (string, int) {
+	dimensions := 0
+	i := start
+	for i < len(descriptor) && descriptor[i] == '[' {
+		dimensions++
+		i++
+	}
+	if i >= len(descriptor) {
+		return "", len(descriptor)
+	}
+	var t string
+	if descriptor[i] == 'L' {
+		end := strings.IndexByte(descriptor[i:], ';')
+		if end < 0 {
+			return "", len(descriptor)
+		}
+		t = javaClassName(descriptor[i+1 : i+end])
+		i += end + 1
+	} else {
+		t = primitiveDescriptors[descriptor[i]]
+		i++
+	}
+	return t + strings.Repeat("[]", dimensions), i
+}
+
+// parseMethodDescriptor returns the parameter and return types of a method
+// descriptor
+func parseMethodDescriptor(descriptor string) ([]string, string, bool) {
+	if !strings.HasPrefix(descriptor, "(") {
+		return nil, "", false
+	}
+	parameters := []string{}
+	i := 1
+	for i < len(descriptor) && descriptor[i] != ')' {
+		t, next := parseFieldDescriptor(descriptor, i)
+		if t == "" {
+			return nil, "", false
+		}
+		parameters = append(parameters, t)
+		i = next
+	}
+	if i >= len(descriptor) {
+		return nil, "", false
+	}
+	returnType, _ := parseFieldDescriptor(descriptor, i+1)
+	return parameters, returnType, returnType != ""
+}
+
+// decodeModifiedUTF8 decodes the modified UTF-8 of the class files, in which
+// the null character and the supplementary characters are encoded
+// differently from UTF-8
+func decodeModifiedUTF8(b []byte) string {
+	ascii := true
+	for _, c := range b {
+		if c == 0 || c >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return string(b)
+	}
+	units := []uint16{}
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			units = append(units, uint16(c))
+			i++
+		case c&0xe0 == 0xc0 && i+1 < len(b):
+			units = append(units, uint16(c&0x1f)<<6|uint16(b[i+1]&0x3f))
+			i += 2
+		case c&0xf0 == 0xe0 && i+2 < len(b):
+			units = append(units, uint16(c&0x0f)<<12|uint16(b[i+1]&0x3f)<<6|uint16(b[i+2]&0x3f))
+			i += 3
+		default:
+			units = append(units, 0xfffd)
+			i++
+		}
+	}
+	var s strings.Builder
+	for i := 0; i < len(units); i++ {
+		u := rune(units[i])
+		if u >= 0xd800 && u < 0xdc00 && i+1 < len(units) && units[i+1] >= 0xdc00 && units[i+1] < 0xe000 {
+			u = (u-0xd800)<<10 + (rune(units[i+1]) - 0xdc00) + 0x10000
+			i++
+		}
+		s.WriteRune(u)
+	}
+	return s.String()
+}
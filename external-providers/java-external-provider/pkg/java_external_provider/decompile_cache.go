@@ -0,0 +1,88 @@
+package java
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// decompileCache keeps what fernflower produced for a file by the sha1 of the
+// file, so that archives analyzed again, in this or a later run, are not
+// decompiled again.
+type decompileCache struct {
+	dir string
+}
+
+// newDecompileCache returns nil, disabling the cache, when no directory is given
+func newDecompileCache(dir string) (*decompileCache, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create decompile cache directory %s: %w", dir, err)
+	}
+	return &decompileCache{dir: dir}, nil
+}
+
+// entry returns the path of the cached output for the input file
+func (c *decompileCache) entry(inputPath, outputPath string) (string, error) {
+	sum, err := fileSHA1(inputPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.dir, sum[:2], sum, filepath.Base(outputPath)), nil
+}
+
+// restore copies the cached output of the input file to the output path, it
+// returns false when the file was never decompiled along with the cache entry
+// to store the output in once it is decompiled.
+func (c *decompileCache) restore(inputPath, outputPath string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	entry, err := c.entry(inputPath, outputPath)
+	if err != nil {
+		return "", false
+	}
+	if _, err := os.Stat(entry); err != nil {
+		return entry, false
+	}
+	return entry, CopyFile(entry, outputPath) == nil
+}
+
+// store adds the output of fernflower to the cache entry
+func (c *decompileCache) store(entry, outputPath string) error {
+	if c == nil || entry == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+		return err
+	}
+	// copy to a temporary file first, concurrent workers and runs may share the cache
+	tmp, err := os.CreateTemp(filepath.Dir(entry), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	if err := CopyFile(outputPath, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), entry)
+}
+
+func fileSHA1(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha1.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,63 @@
+package java
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+)
+
+func Test_decompileCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := newDecompileCache(filepath.Join(dir, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := filepath.Join(dir, "app", "lib.jar")
+	if err := os.MkdirAll(filepath.Dir(input), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(input, []byte("compiled"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "app", "lib-decompiled", "lib.jar")
+	entry, ok := cache.restore(input, output)
+	if ok {
+		t.Fatalf("expected an empty cache")
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(output, []byte("decompiled"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.store(entry, output); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same archive analyzed from another location is restored without running fernflower
+	other := filepath.Join(dir, "other", "lib.jar")
+	if err := CopyFile(input, other); err != nil {
+		t.Fatal(err)
+	}
+	job := decompileJob{
+		inputPath:  other,
+		outputPath: filepath.Join(dir, "other", "lib-decompiled", "lib.jar"),
+		artifact:   javaArtifact{packaging: JavaArchive},
+	}
+	err = decompile(context.TODO(), testr.New(t), alwaysDecompileFilter(true), 1,
+		[]decompileJob{job}, filepath.Join(dir, "missing-fernflower.jar"), "", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(job.outputPath)
+	if err != nil {
+		t.Fatalf("expected decompiled file to be restored from the cache: %v", err)
+	}
+	if string(content) != "decompiled" {
+		t.Errorf("expected cached content, got %q", content)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -74,23 +75,48 @@ func (p *javaServiceClient) findPom() string {
 	return f
 }
 
+// findGradleBuild returns the build file of the root project, either a Groovy or
+// a Kotlin DSL build file. Multi-project builds may only have a settings file
+// at their root, in which case that one is returned.
 func (p *javaServiceClient) findGradleBuild() string {
 	if p.config.Location != "" {
-		path := filepath.Join(p.config.Location, "build.gradle")
-		_, err := os.Stat(path)
-		if err != nil {
-			return ""
-		}
-		f, err := filepath.Abs(path)
-		if err != nil {
-			return ""
+		if f := findGradleFile(p.config.Location, gradleBuildFiles); f != "" {
+			return f
 		}
-		return f
+		return findGradleFile(p.config.Location, gradleSettingsFiles)
 	}
 	return ""
 }
 
+// preparedDependenciesFile keeps the dependencies resolved when preparing
+const preparedDependenciesFile = "dependencies.json"
+
 func (p *javaServiceClient) GetDependencies(ctx context.Context) (map[uri.URI][]*provider.Dep, error) {
+	if p.preparedDir == "" {
+		return p.resolveDependencies(ctx)
+	}
+	path := filepath.Join(p.preparedDir, preparedDependenciesFile)
+	if content, err := os.ReadFile(path); err == nil {
+		m := map[uri.URI][]*provider.Dep{}
+		if err := json.Unmarshal(content, &m); err == nil {
+			p.log.V(4).Info("using prepared dependencies", "file", path)
+			return m, nil
+		}
+		p.log.Info("unable to read prepared dependencies, resolving them again", "file", path)
+	}
+	m, err := p.resolveDependencies(ctx)
+	if err != nil {
+		return m, err
+	}
+	if content, err := json.Marshal(m); err == nil {
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			p.log.Error(err, "unable to keep prepared dependencies", "file", path)
+		}
+	}
+	return m, nil
+}
+
+func (p *javaServiceClient) resolveDependencies(ctx context.Context) (map[uri.URI][]*provider.Dep, error) {
 	p.log.V(4).Info("running dependency analysis")
 
 	if p.GetBuildTool() == gradle {
@@ -339,17 +365,26 @@ func (p *javaServiceClient) getDependenciesForMaven(_ context.Context) (map[uri.
 	lines := strings.Split(string(mvnOutput), "\n")
 	submoduleTrees := extractSubmoduleTrees(lines)
 
-	var pomDeps []provider.DepDAGItem
+	// in a reactor build every module gets its own dependency list, keyed by
+	// the pom.xml of the module so that incidents point to the right module.
+	modules := findMavenModules(path)
+	m := map[uri.URI][]provider.DepDAGItem{}
 	for _, tree := range submoduleTrees {
-		submoduleDeps, err := p.parseMavenDepLines(tree, localRepoPath, path)
+		pomPath, ok := modules[tree.module]
+		if !ok {
+			p.log.V(5).Info("unable to find pom of maven module, using root pom", "module", tree.module)
+			pomPath = path
+		}
+		submoduleDeps, err := p.parseMavenDepLines(tree.lines, localRepoPath, pomPath)
 		if err != nil {
 			return nil, err
 		}
-		pomDeps = append(pomDeps, submoduleDeps...)
+		pomFile := uri.File(pomPath)
+		m[pomFile] = append(m[pomFile], submoduleDeps...)
+	}
+	if len(submoduleTrees) == 0 {
+		m[file] = nil
 	}
-
-	m := map[uri.URI][]provider.DepDAGItem{}
-	m[file] = pomDeps
 
 	if len(m) == 0 {
 		// grab the embedded deps
@@ -362,6 +397,11 @@ func (p *javaServiceClient) getDependenciesForMaven(_ context.Context) (map[uri.
 // getDependenciesForGradle invokes the Gradle wrapper to get the dependency tree and returns all project dependencies
 // TODO: what if no wrapper?
 func (p *javaServiceClient) getDependenciesForGradle(_ context.Context) (map[uri.URI][]provider.DepDAGItem, error) {
+	if _, err := os.Stat(filepath.Join(p.config.Location, "gradlew")); errors.Is(err, os.ErrNotExist) {
+		p.log.V(2).Info("no gradle wrapper found - reading dependencies declared in the build files")
+		return p.getDependenciesFromGradleBuildFiles()
+	}
+
 	subprojects, err := p.getGradleSubprojects()
 	if err != nil {
 		return nil, err
@@ -524,9 +564,16 @@ func parseGradleDependencyString(s string) provider.DepDAGItem {
 	return provider.DepDAGItem{Dep: dep, AddedDeps: []provider.DepDAGItem{}}
 }
 
+// mavenModuleTree is the dependency tree of a single module of a reactor build
+type mavenModuleTree struct {
+	// module is the groupId:artifactId of the module
+	module string
+	lines  []string
+}
+
 // extractSubmoduleTrees creates an array of lines for each submodule tree found in the mvn dependency:tree output
-func extractSubmoduleTrees(lines []string) [][]string {
-	submoduleTrees := [][]string{}
+func extractSubmoduleTrees(lines []string) []mavenModuleTree {
+	submoduleTrees := []mavenModuleTree{}
 
 	beginRegex := regexp.MustCompile(`(maven-)*dependency(-plugin)*:[\d\.]+:tree`)
 	endRegex := regexp.MustCompile(`\[INFO\] -*$`)
@@ -536,7 +583,7 @@ func extractSubmoduleTrees(lines []string) [][]string {
 	for _, line := range lines {
 		if beginRegex.Find([]byte(line)) != nil {
 			gather = true
-			submoduleTrees = append(submoduleTrees, []string{})
+			submoduleTrees = append(submoduleTrees, mavenModuleTree{})
 			continue
 		}
 
@@ -546,26 +593,68 @@ func extractSubmoduleTrees(lines []string) [][]string {
 				submod++
 				continue
 			}
-			if skipmod { // we ignore the first module (base module)
-				skipmod = false
-				continue
-			}
 
 			line = strings.TrimPrefix(line, "[INFO] ")
 			line = strings.Trim(line, " ")
 
+			if skipmod { // the first line holds the coordinates of the module itself
+				skipmod = false
+				if parts := strings.Split(line, ":"); len(parts) > 1 {
+					submoduleTrees[submod].module = parts[0] + ":" + parts[1]
+				}
+				continue
+			}
+
 			// output contains progress report lines that are not deps, skip those
 			if !(strings.HasPrefix(line, "+") || strings.HasPrefix(line, "|") || strings.HasPrefix(line, "\\")) {
 				continue
 			}
 
-			submoduleTrees[submod] = append(submoduleTrees[submod], line)
+			submoduleTrees[submod].lines = append(submoduleTrees[submod].lines, line)
 		}
 	}
 
 	return submoduleTrees
 }
 
+// findMavenModules walks the modules of a reactor build starting at the root
+// pom and returns the path to the pom.xml of each module by groupId:artifactId
+func findMavenModules(rootPom string) map[string]string {
+	modules := map[string]string{}
+	seen := map[string]bool{}
+	pending := []string{rootPom}
+	for len(pending) > 0 {
+		path := pending[0]
+		pending = pending[1:]
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		pom, err := gopom.Parse(path)
+		if err != nil {
+			continue
+		}
+		groupId := pom.GroupID
+		if groupId == nil && pom.Parent != nil {
+			groupId = pom.Parent.GroupID
+		}
+		if groupId != nil && pom.ArtifactID != nil {
+			modules[fmt.Sprintf("%s:%s", *groupId, *pom.ArtifactID)] = path
+		}
+		if pom.Modules == nil {
+			continue
+		}
+		for _, module := range *pom.Modules {
+			modulePath := filepath.Join(filepath.Dir(path), strings.TrimSpace(module))
+			if !strings.HasSuffix(modulePath, ".xml") {
+				modulePath = filepath.Join(modulePath, "pom.xml")
+			}
+			pending = append(pending, modulePath)
+		}
+	}
+	return modules
+}
+
 // discoverDepsFromJars walks given path to discover dependencies embedded as JARs
 func (p *javaServiceClient) discoverDepsFromJars(path string, ll map[uri.URI][]konveyor.DepDAGItem) {
 	// for binaries we only find JARs embedded in archive
@@ -573,6 +662,7 @@ func (p *javaServiceClient) discoverDepsFromJars(path string, ll map[uri.URI][]k
 		deps:        ll,
 		depToLabels: p.depToLabels,
 		m2RepoPath:  getMavenLocalRepoPath(p.mvnSettingsFile),
+		mavenIndex:  p.mavenIndex,
 		seen:        map[string]bool{},
 		initialPath: path,
 	}
@@ -583,6 +673,7 @@ type walker struct {
 	deps        map[uri.URI][]provider.DepDAGItem
 	depToLabels map[string]*depLabelItem
 	m2RepoPath  string
+	mavenIndex  *mavenIndex
 	initialPath string
 	seen        map[string]bool
 	pomPaths    []string
@@ -604,11 +695,14 @@ func (w *walker) walkDirForJar(path string, info fs.DirEntry, err error) error {
 		d := provider.Dep{
 			Name: info.Name(),
 		}
-		artifact, _ := toDependency(context.TODO(), path)
+		artifact, _ := toDependency(context.TODO(), path, w.mavenIndex)
 		if (artifact != javaArtifact{}) {
 			d.Name = fmt.Sprintf("%s.%s", artifact.GroupId, artifact.ArtifactId)
 			d.Version = artifact.Version
 			d.Labels = addDepLabels(w.depToLabels, d.Name)
+			if licenses := pomLicenses(w.m2RepoPath, artifact.GroupId, artifact.ArtifactId, artifact.Version); len(licenses) > 0 {
+				d.Labels = append(d.Labels, provider.DepLicenseLabels(licenses)...)
+			}
 			d.ResolvedIdentifier = artifact.sha1
 			// when we can successfully get javaArtifact from a jar
 			// we added it to the pom and it should be in m2Repo path
@@ -722,6 +816,9 @@ func (p *javaServiceClient) parseDepString(dep, localRepoPath, pomPath string) (
 	fp := resolveDepFilepath(&d, p, group, artifact, localRepoPath)
 
 	d.Labels = addDepLabels(p.depToLabels, d.Name)
+	if licenses := pomLicenses(localRepoPath, group, artifact, d.Version); len(licenses) > 0 {
+		d.Labels = append(d.Labels, provider.DepLicenseLabels(licenses)...)
+	}
 	d.FileURIPrefix = fmt.Sprintf("file://%v", filepath.Dir(fp))
 
 	d.Extras = map[string]interface{}{
@@ -769,6 +866,47 @@ func resolveDepFilepath(d *provider.Dep, p *javaServiceClient, group string, art
 	return fp
 }
 
+// maxPomParents limits the parent poms looked up for the licenses of a dependency
+const maxPomParents = 5
+
+// pomLicenses returns the SPDX identifiers of the licenses of the pom of a
+// dependency in the local repository. Poms without licenses inherit the ones of
+// their parent. Licenses that aren't recognized are left out, the dependency is
+// labeled with an unknown license when none is.
+func pomLicenses(localRepoPath, group, artifact, version string) []string {
+	if localRepoPath == "" {
+		return nil
+	}
+	for i := 0; i <= maxPomParents; i++ {
+		pom, err := gopom.Parse(filepath.Join(localRepoPath, strings.Replace(group, ".", "/", -1),
+			artifact, version, fmt.Sprintf("%s-%s.pom", artifact, version)))
+		if err != nil {
+			return nil
+		}
+		if pom.Licenses != nil && len(*pom.Licenses) > 0 {
+			licenses := []string{}
+			for _, l := range *pom.Licenses {
+				spdx := ""
+				if l.Name != nil {
+					spdx = provider.NormalizeLicense(*l.Name)
+				}
+				if spdx == "" && l.URL != nil {
+					spdx = provider.NormalizeLicense(*l.URL)
+				}
+				if spdx != "" {
+					licenses = append(licenses, spdx)
+				}
+			}
+			return licenses
+		}
+		if pom.Parent == nil || pom.Parent.GroupID == nil || pom.Parent.ArtifactID == nil || pom.Parent.Version == nil {
+			return nil
+		}
+		group, artifact, version = *pom.Parent.GroupID, *pom.Parent.ArtifactID, *pom.Parent.Version
+	}
+	return nil
+}
+
 func addDepLabels(depToLabels map[string]*depLabelItem, depName string) []string {
 	m := map[string]interface{}{}
 	for _, d := range depToLabels {
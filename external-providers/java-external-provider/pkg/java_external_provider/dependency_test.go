@@ -1,6 +1,10 @@
 package java
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -8,6 +12,7 @@ import (
 	"github.com/go-logr/logr/testr"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
 	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
 )
 
 func Test_parseMavenDepLines(t *testing.T) {
@@ -561,6 +566,61 @@ func Test_parseMavenDepLines(t *testing.T) {
 	}
 }
 
+func Test_extractSubmoduleTrees(t *testing.T) {
+	output := `[INFO] Reactor Build Order:
+[INFO]
+[INFO] reactor                                                            [pom]
+[INFO] core                                                               [jar]
+[INFO] api                                                                [jar]
+[INFO]
+[INFO] --- maven-dependency-plugin:3.6.0:tree (default-cli) @ reactor ---
+[INFO] io.konveyor.demo:reactor:pom:1.0.0
+[INFO] ------------------------------------------------------------------------
+[INFO]
+[INFO] --- maven-dependency-plugin:3.6.0:tree (default-cli) @ core ---
+[INFO] io.konveyor.demo:core:jar:1.0.0
+[INFO] \- junit:junit:jar:4.11:test
+[INFO]    \- org.hamcrest:hamcrest-core:jar:1.3:test
+[INFO] ------------------------------------------------------------------------
+[INFO]
+[INFO] --- maven-dependency-plugin:3.6.0:tree (default-cli) @ api ---
+[INFO] io.konveyor.demo.services:api:jar:1.0.0
+[INFO] \- io.fabric8:kubernetes-client:jar:6.0.0:compile
+[INFO] ------------------------------------------------------------------------`
+
+	want := []mavenModuleTree{
+		{module: "io.konveyor.demo:reactor"},
+		{module: "io.konveyor.demo:core", lines: []string{
+			"\\- junit:junit:jar:4.11:test",
+			"\\- org.hamcrest:hamcrest-core:jar:1.3:test",
+		}},
+		{module: "io.konveyor.demo.services:api", lines: []string{
+			"\\- io.fabric8:kubernetes-client:jar:6.0.0:compile",
+		}},
+	}
+	got := extractSubmoduleTrees(strings.Split(output, "\n"))
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("extractSubmoduleTrees() = %#v, want %#v", got, want)
+	}
+}
+
+func Test_findMavenModules(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("testdata", "maven-reactor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"io.konveyor.demo:reactor":           filepath.Join(root, "pom.xml"),
+		"io.konveyor.demo:core":              filepath.Join(root, "core", "pom.xml"),
+		"io.konveyor.demo.services:services": filepath.Join(root, "services", "pom.xml"),
+		"io.konveyor.demo.services:api":      filepath.Join(root, "services", "api", "pom.xml"),
+	}
+	got := findMavenModules(filepath.Join(root, "pom.xml"))
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("findMavenModules() = %v, want %v", got, want)
+	}
+}
+
 func Test_parseGradleDependencyOutput(t *testing.T) {
 	gradleOutput := `
 Starting a Gradle Daemon, 1 incompatible Daemon could not be reused, use --status for details
@@ -795,3 +855,50 @@ BUILD SUCCESSFUL in 4s
 	}
 
 }
+
+func Test_preparedDependencies(t *testing.T) {
+	dir := t.TempDir()
+	want := map[uri.URI][]*provider.Dep{
+		uri.File("/app/pom.xml"): {
+			{Name: "junit.junit", Version: "4.11", Labels: []string{"konveyor.io/dep-source=open-source"}},
+		},
+	}
+	content, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, preparedDependenciesFile), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := javaServiceClient{
+		log:         testr.New(t),
+		preparedDir: dir,
+	}
+	got, err := p.GetDependencies(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("GetDependencies() = %v, want %v", got, want)
+	}
+}
+
+func Test_pomLicenses(t *testing.T) {
+	localRepoPath := filepath.Join("testdata", "licenses")
+	tests := []struct {
+		artifact string
+		want     []string
+	}{
+		{artifact: "acme-web", want: []string{"EPL-2.0", "GPL-2.0-with-classpath-exception"}},
+		{artifact: "acme-core", want: []string{"Apache-2.0"}},
+		{artifact: "acme-missing", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.artifact, func(t *testing.T) {
+			got := pomLicenses(localRepoPath, "org.acme", tt.artifact, "1.0")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pomLicenses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
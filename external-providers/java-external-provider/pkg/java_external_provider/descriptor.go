@@ -0,0 +1,297 @@
+package java
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+// defaultDescriptors are the deployment descriptors searched when the
+// condition does not list any.
+var defaultDescriptors = []string{
+	"web.xml",
+	"web-fragment.xml",
+	"ejb-jar.xml",
+	"faces-config.xml",
+}
+
+// descriptorClassElements are the descriptor elements whose text content
+// is a fully qualified class name.
+var descriptorClassElements = map[string]bool{
+	"servlet-class":      true,
+	"filter-class":       true,
+	"listener-class":     true,
+	"ejb-class":          true,
+	"home":               true,
+	"remote":             true,
+	"local-home":         true,
+	"local":              true,
+	"business-local":     true,
+	"business-remote":    true,
+	"service-endpoint":   true,
+	"interceptor-class":  true,
+	"managed-bean-class": true,
+}
+
+var javaPackageRegex = regexp.MustCompile(`^\s*package\s+([\w.]+)\s*;`)
+
+type descriptorCondition struct {
+	// Descriptors are the file names of the descriptors to check, defaults to
+	// web.xml, web-fragment.xml, ejb-jar.xml and faces-config.xml.
+	Descriptors []string `yaml:"descriptors,omitempty" json:"descriptors,omitempty"`
+	// Pattern optionally limits the referenced class names that are checked.
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+}
+
+// descriptorReference is a class referenced from a deployment descriptor
+type descriptorReference struct {
+	file      string
+	element   string
+	className string
+	line      int
+}
+
+// evaluateDescriptor creates an incident for every class referenced in a
+// deployment descriptor that cannot be found in the workspace.
+func (p *javaServiceClient) evaluateDescriptor(ctx context.Context, cond descriptorCondition, condCtx *provider.ProviderContext) (provider.ProviderEvaluateResponse, error) {
+	var pattern *regexp.Regexp
+	if cond.Pattern != "" {
+		var err error
+		pattern, err = regexp.Compile(cond.Pattern)
+		if err != nil {
+			return provider.ProviderEvaluateResponse{}, fmt.Errorf("unable to compile descriptor pattern '%s': %w", cond.Pattern, err)
+		}
+	}
+	descriptorNames := cond.Descriptors
+	if len(descriptorNames) == 0 {
+		descriptorNames = defaultDescriptors
+	}
+
+	descriptors, classes, err := p.indexDescriptorWorkspace(descriptorNames)
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+
+	incidents := []provider.IncidentContext{}
+	for _, descriptor := range descriptors {
+		refs, err := parseDescriptorReferences(descriptor)
+		if err != nil {
+			p.log.V(5).Error(err, "unable to parse descriptor", "file", descriptor)
+			continue
+		}
+		for _, ref := range refs {
+			if pattern != nil && !pattern.MatchString(ref.className) {
+				continue
+			}
+			if _, ok := classes[ref.className]; ok {
+				continue
+			}
+			if p.classExistsInDependencies(ctx, ref.className, condCtx) {
+				continue
+			}
+			lineNumber := ref.line
+			incident := provider.IncidentContext{
+				FileURI:    uri.File(ref.file),
+				LineNumber: &lineNumber,
+				Variables: map[string]interface{}{
+					"descriptor": filepath.Base(ref.file),
+					"element":    ref.element,
+					"className":  ref.className,
+				},
+				CodeLocation: &provider.Location{
+					StartPosition: provider.Position{Line: float64(lineNumber)},
+					EndPosition:   provider.Position{Line: float64(lineNumber)},
+				},
+			}
+			// a class with the same simple name in another package is most
+			// likely the class the descriptor entry was meant to point to.
+			if candidates := renamedClassCandidates(classes, ref.className); len(candidates) > 0 {
+				incident.Variables["candidates"] = candidates
+			}
+			incidents = append(incidents, incident)
+		}
+	}
+	if len(incidents) == 0 {
+		return provider.ProviderEvaluateResponse{Matched: false}, nil
+	}
+	return provider.ProviderEvaluateResponse{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
+
+// indexDescriptorWorkspace walks the location once and returns the descriptor
+// files found along with the fully qualified names of the classes in the workspace.
+func (p *javaServiceClient) indexDescriptorWorkspace(descriptorNames []string) ([]string, map[string]string, error) {
+	names := map[string]bool{}
+	for _, name := range descriptorNames {
+		names[name] = true
+	}
+	location, err := filepath.Abs(p.config.Location)
+	if err != nil {
+		return nil, nil, err
+	}
+	descriptors := []string{}
+	classes := map[string]string{}
+	err = filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != location && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch {
+		case names[d.Name()]:
+			if p.isPathIncluded(path) {
+				descriptors = append(descriptors, path)
+			}
+		case strings.HasSuffix(path, JavaFile):
+			if className := javaSourceClassName(path); className != "" {
+				classes[className] = path
+			}
+		case strings.HasSuffix(path, ClassFile):
+			if className := compiledClassName(path); className != "" {
+				classes[className] = path
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to index workspace for descriptors: %w", err)
+	}
+	return descriptors, classes, nil
+}
+
+func (p *javaServiceClient) isPathIncluded(path string) bool {
+	if !p.pathFilter.Match(path) {
+		return false
+	}
+	if len(p.includedPaths) == 0 {
+		return true
+	}
+	for _, included := range p.includedPaths {
+		if strings.HasPrefix(path, included) {
+			return true
+		}
+	}
+	return false
+}
+
+// classExistsInDependencies asks the language server for the class, this finds
+// classes that come from dependencies rather than the application itself.
+func (p *javaServiceClient) classExistsInDependencies(ctx context.Context, className string, condCtx *provider.ProviderContext) bool {
+	if p.rpc == nil {
+		return false
+	}
+	symbols, err := p.GetAllSymbols(ctx, javaCondition{
+		Referenced: referenceCondition{
+			Pattern:  className,
+			Location: "class",
+		},
+	}, condCtx)
+	if err != nil {
+		p.log.V(5).Error(err, "unable to look up class referenced in descriptor", "class", className)
+		return false
+	}
+	return len(symbols) > 0
+}
+
+// javaSourceClassName returns the fully qualified name of the top level class
+// of a java source file based on its package declaration.
+func javaSourceClassName(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	className := strings.TrimSuffix(filepath.Base(path), JavaFile)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if match := javaPackageRegex.FindStringSubmatch(scanner.Text()); match != nil {
+			return match[1] + "." + className
+		}
+	}
+	return className
+}
+
+// compiledClassName returns the fully qualified name of a class file found
+// under a classes directory such as WEB-INF/classes or target/classes.
+func compiledClassName(path string) string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] == "classes" {
+			// inner classes keep their binary name, which is also how descriptors reference them
+			return strings.TrimSuffix(strings.Join(parts[i+1:], "."), ClassFile)
+		}
+	}
+	return ""
+}
+
+func renamedClassCandidates(classes map[string]string, className string) []string {
+	simpleName := className[strings.LastIndex(className, ".")+1:]
+	candidates := []string{}
+	for name := range classes {
+		if name != className && name[strings.LastIndex(name, ".")+1:] == simpleName {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// parseDescriptorReferences returns the class names referenced in a descriptor
+// along with the line they are declared on.
+func parseDescriptorReferences(path string) ([]descriptorReference, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	decoder := xml.NewDecoder(f)
+	decoder.Strict = false
+	refs := []descriptorReference{}
+	var current *descriptorReference
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return refs, nil
+		}
+		if err != nil {
+			return refs, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			current = nil
+			if descriptorClassElements[t.Name.Local] {
+				line, _ := decoder.InputPos()
+				current = &descriptorReference{file: path, element: t.Name.Local, line: line}
+			}
+		case xml.CharData:
+			if current != nil {
+				current.className += string(t)
+			}
+		case xml.EndElement:
+			if current != nil && t.Name.Local == current.element {
+				current.className = strings.TrimSpace(current.className)
+				if current.className != "" {
+					refs = append(refs, *current)
+				}
+			}
+			current = nil
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package java
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func Test_evaluateDescriptor(t *testing.T) {
+	tests := []struct {
+		name        string
+		condition   descriptorCondition
+		wantLines   []int
+		wantVars    []map[string]interface{}
+		wantErr     bool
+		wantNoMatch bool
+	}{
+		{
+			name:      "all default descriptors",
+			condition: descriptorCondition{},
+			wantLines: []int{6, 9},
+			wantVars: []map[string]interface{}{
+				{
+					"descriptor": "ejb-jar.xml",
+					"element":    "ejb-class",
+					"className":  "com.example.ejb.OrderBean",
+				},
+				{
+					"descriptor": "web.xml",
+					"element":    "servlet-class",
+					"className":  "com.example.web.ReportServlet",
+					"candidates": []string{"com.example.legacy.ReportServlet"},
+				},
+			},
+		},
+		{
+			name:      "only web.xml",
+			condition: descriptorCondition{Descriptors: []string{"web.xml"}},
+			wantLines: []int{9},
+		},
+		{
+			name:        "pattern excludes missing classes",
+			condition:   descriptorCondition{Pattern: `\.HelloServlet$`},
+			wantNoMatch: true,
+		},
+		{
+			name:      "invalid pattern",
+			condition: descriptorCondition{Pattern: "("},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &javaServiceClient{
+				config: provider.InitConfig{Location: "testdata/descriptor"},
+				log:    testr.New(t),
+			}
+			got, err := p.evaluateDescriptor(context.TODO(), tt.condition, &provider.ProviderContext{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateDescriptor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Matched == tt.wantNoMatch {
+				t.Fatalf("evaluateDescriptor() matched = %v, want %v", got.Matched, !tt.wantNoMatch)
+			}
+			if len(got.Incidents) != len(tt.wantLines) {
+				t.Fatalf("evaluateDescriptor() got %d incidents, want %d", len(got.Incidents), len(tt.wantLines))
+			}
+			for i, line := range tt.wantLines {
+				if *got.Incidents[i].LineNumber != line {
+					t.Errorf("evaluateDescriptor() incident %d line = %d, want %d", i, *got.Incidents[i].LineNumber, line)
+				}
+			}
+			for i, vars := range tt.wantVars {
+				if !reflect.DeepEqual(got.Incidents[i].Variables, vars) {
+					t.Errorf("evaluateDescriptor() incident %d variables = %v, want %v", i, got.Incidents[i].Variables, vars)
+				}
+			}
+		})
+	}
+}
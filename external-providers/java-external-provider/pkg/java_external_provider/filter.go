@@ -142,6 +142,11 @@ func (p *javaServiceClient) convertToIncidentContext(symbol protocol.WorkspaceSy
 			Character: float64(locationRange.End.Character),
 		},
 	}
+	if strings.HasPrefix(string(u), uri.FileScheme) {
+		if text, err := provider.LocationText(u.Filename(), *incident.CodeLocation); err == nil {
+			incident.MatchedText = text
+		}
+	}
 	return incident, nil
 }
 
@@ -0,0 +1,386 @@
+package java
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+// gradleBuildFiles and gradleSettingsFiles are the file names of Groovy and Kotlin DSL builds
+var (
+	gradleBuildFiles    = []string{"build.gradle", "build.gradle.kts"}
+	gradleSettingsFiles = []string{"settings.gradle", "settings.gradle.kts"}
+)
+
+var (
+	// matches dependency declarations in both DSLs, e.g.
+	// implementation("org.slf4j:slf4j-api:2.0.9") or compileOnly 'javax.servlet:servlet-api:2.5'
+	gradleDeclarationRegex = regexp.MustCompile(`^\s*(api|implementation|compileOnly|runtimeOnly|compileOnlyApi|annotationProcessor|kapt|compile|runtime|providedCompile|providedRuntime|testImplementation|testCompileOnly|testRuntimeOnly|testCompile|testRuntime)\b\s*\(?(.*)$`)
+	gradleStringRegex      = regexp.MustCompile(`^["']([^"']+)["']`)
+	gradleCatalogRegex     = regexp.MustCompile(`^(\w+)\.([\w.]+)`)
+	gradleNamedArgRegex    = regexp.MustCompile(`(group|name|version)\s*[=:]\s*["']([^"']*)["']`)
+	gradlePlatformRegex    = regexp.MustCompile(`^(?:enforcedPlatform|platform)\s*\(\s*`)
+	gradleIncludeRegex     = regexp.MustCompile(`^\s*include\s*\(?(.*)$`)
+	gradleProjectPathRegex = regexp.MustCompile(`["']:?([^"']+)["']`)
+	tomlTableRegex         = regexp.MustCompile(`^\[\s*([\w.-]+)\s*\]$`)
+	tomlKeyValueRegex      = regexp.MustCompile(`^([\w.-]+|"[^"]+")\s*=\s*(.*)$`)
+	tomlInlineStringRegex  = regexp.MustCompile(`([\w.]+)\s*=\s*"([^"]*)"`)
+	tomlStringRegex        = regexp.MustCompile(`"([^"]*)"`)
+)
+
+// versionCatalog is the content of a gradle version catalog (gradle/libs.versions.toml)
+type versionCatalog struct {
+	versions  map[string]string
+	libraries map[string]provider.Dep
+	bundles   map[string][]string
+}
+
+// findGradleFile returns the absolute path of the first of the files found in dir
+func findGradleFile(dir string, names []string) string {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		f, err := filepath.Abs(path)
+		if err != nil {
+			return ""
+		}
+		return f
+	}
+	return ""
+}
+
+// getDependenciesFromGradleBuildFiles reads the dependencies declared in the
+// build files of the root project and its subprojects, it is used when there
+// is no gradle wrapper to resolve the dependency tree. Only the declared
+// dependencies can be found this way, not the transitive ones.
+func (p *javaServiceClient) getDependenciesFromGradleBuildFiles() (map[uri.URI][]provider.DepDAGItem, error) {
+	location, err := filepath.Abs(p.config.Location)
+	if err != nil {
+		return nil, err
+	}
+	catalogs := map[string]*versionCatalog{}
+	if catalog, err := parseVersionCatalog(filepath.Join(location, "gradle", "libs.versions.toml")); err == nil {
+		catalogs["libs"] = catalog
+	} else if !os.IsNotExist(err) {
+		p.log.V(5).Error(err, "unable to parse version catalog")
+	}
+
+	projectDirs := []string{location}
+	if settings := findGradleFile(location, gradleSettingsFiles); settings != "" {
+		subprojects, err := parseGradleSettings(settings)
+		if err != nil {
+			p.log.V(5).Error(err, "unable to parse gradle settings", "file", settings)
+		}
+		for _, subproject := range subprojects {
+			projectDirs = append(projectDirs, filepath.Join(location, filepath.FromSlash(subproject)))
+		}
+	}
+
+	m := map[uri.URI][]provider.DepDAGItem{}
+	for _, dir := range projectDirs {
+		build := findGradleFile(dir, gradleBuildFiles)
+		if build == "" {
+			continue
+		}
+		deps, err := parseGradleBuildFile(build, catalogs)
+		if err != nil {
+			p.log.V(5).Error(err, "unable to parse gradle build file", "file", build)
+			continue
+		}
+		if len(deps) > 0 {
+			m[uri.File(build)] = deps
+		}
+	}
+	return m, nil
+}
+
+// parseGradleSettings returns the paths of the projects included in a settings file
+func parseGradleSettings(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	projects := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		match := gradleIncludeRegex.FindStringSubmatch(stripGradleComment(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		for _, project := range gradleProjectPathRegex.FindAllStringSubmatch(match[1], -1) {
+			// project paths use : as separator, e.g. services:api lives in services/api
+			projects = append(projects, strings.ReplaceAll(project[1], ":", "/"))
+		}
+	}
+	return projects, scanner.Err()
+}
+
+// parseGradleBuildFile returns the dependencies declared in a Groovy or Kotlin DSL build file
+func parseGradleBuildFile(path string, catalogs map[string]*versionCatalog) ([]provider.DepDAGItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	deps := []provider.DepDAGItem{}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		match := gradleDeclarationRegex.FindStringSubmatch(stripGradleComment(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		for _, dep := range parseGradleDeclaration(strings.TrimSpace(match[2]), catalogs) {
+			key := dep.Name + "@" + dep.Version
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			deps = append(deps, provider.DepDAGItem{Dep: dep, AddedDeps: []provider.DepDAGItem{}})
+		}
+	}
+	return deps, scanner.Err()
+}
+
+// parseGradleDeclaration parses the argument of a dependency declaration, which
+// can be a string notation, named arguments or a version catalog accessor.
+func parseGradleDeclaration(s string, catalogs map[string]*versionCatalog) []provider.Dep {
+	s = gradlePlatformRegex.ReplaceAllString(s, "")
+	if match := gradleStringRegex.FindStringSubmatch(s); match != nil {
+		if dep, ok := parseGradleCoordinates(match[1]); ok {
+			return []provider.Dep{dep}
+		}
+		return nil
+	}
+	if named := gradleNamedArgRegex.FindAllStringSubmatch(s, -1); named != nil {
+		args := map[string]string{}
+		for _, arg := range named {
+			args[arg[1]] = arg[2]
+		}
+		if args["group"] == "" || args["name"] == "" {
+			return nil
+		}
+		return []provider.Dep{{Name: args["group"] + "." + args["name"], Version: args["version"]}}
+	}
+	if match := gradleCatalogRegex.FindStringSubmatch(s); match != nil {
+		if catalog, ok := catalogs[match[1]]; ok {
+			return catalog.resolve(match[2])
+		}
+	}
+	return nil
+}
+
+// parseGradleCoordinates parses group:artifact[:version[:classifier]] coordinates
+func parseGradleCoordinates(s string) (provider.Dep, bool) {
+	parts := strings.Split(strings.SplitN(s, "@", 2)[0], ":")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return provider.Dep{}, false
+	}
+	dep := provider.Dep{Name: parts[0] + "." + parts[1]}
+	if len(parts) > 2 {
+		dep.Version = parts[2]
+	}
+	return dep, true
+}
+
+func stripGradleComment(line string) string {
+	if i := strings.Index(line, "//"); i != -1 && !strings.Contains(line[:i], "\"") && !strings.Contains(line[:i], "'") {
+		return line[:i]
+	}
+	return line
+}
+
+// resolve returns the dependencies for a catalog accessor such as
+// spring.boot.starter or bundles.jackson
+func (c *versionCatalog) resolve(accessor string) []provider.Dep {
+	if bundle, ok := strings.CutPrefix(accessor, "bundles."); ok {
+		deps := []provider.Dep{}
+		for alias, libraries := range c.bundles {
+			if catalogAccessor(alias) != bundle {
+				continue
+			}
+			for _, library := range libraries {
+				if dep, ok := c.library(catalogAccessor(library)); ok {
+					deps = append(deps, dep)
+				}
+			}
+		}
+		return deps
+	}
+	if dep, ok := c.library(accessor); ok {
+		return []provider.Dep{dep}
+	}
+	return nil
+}
+
+func (c *versionCatalog) library(accessor string) (provider.Dep, bool) {
+	for alias, dep := range c.libraries {
+		if catalogAccessor(alias) == accessor {
+			return dep, true
+		}
+	}
+	return provider.Dep{}, false
+}
+
+// catalogAccessor returns the accessor gradle generates for a catalog alias,
+// the separators -, _ and . are all turned into .
+func catalogAccessor(alias string) string {
+	return strings.NewReplacer("-", ".", "_", ".").Replace(alias)
+}
+
+// parseVersionCatalog reads the versions, libraries and bundles of a version catalog
+func parseVersionCatalog(path string) (*versionCatalog, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	catalog := &versionCatalog{
+		versions:  map[string]string{},
+		libraries: map[string]provider.Dep{},
+		bundles:   map[string][]string{},
+	}
+	type library struct {
+		alias string
+		value string
+	}
+	libraries := []library{}
+	table := ""
+	// arrays can span lines, they are accumulated until they are closed
+	pendingKey, pendingValue := "", ""
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(stripTOMLComment(line))
+		if line == "" {
+			continue
+		}
+		if pendingKey != "" {
+			pendingValue += " " + line
+			if strings.Contains(line, "]") {
+				catalog.bundles[pendingKey] = tomlStrings(pendingValue)
+				pendingKey, pendingValue = "", ""
+			}
+			continue
+		}
+		if match := tomlTableRegex.FindStringSubmatch(line); match != nil {
+			table = match[1]
+			continue
+		}
+		match := tomlKeyValueRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		key, value := strings.Trim(match[1], `"`), strings.TrimSpace(match[2])
+		switch table {
+		case "versions":
+			if strings.HasPrefix(value, "{") {
+				value = tomlVersion(tomlInlineTable(value))
+			} else {
+				value = strings.Trim(value, `"`)
+			}
+			catalog.versions[key] = value
+		case "libraries":
+			libraries = append(libraries, library{alias: key, value: value})
+		case "bundles":
+			if strings.HasPrefix(value, "[") && !strings.Contains(value, "]") {
+				pendingKey, pendingValue = key, value
+				continue
+			}
+			catalog.bundles[key] = tomlStrings(value)
+		}
+	}
+	if pendingKey != "" {
+		return nil, fmt.Errorf("unterminated array for bundle %s in %s", pendingKey, path)
+	}
+
+	// libraries can reference versions declared after them, resolve them once all versions are known
+	for _, l := range libraries {
+		if !strings.HasPrefix(l.value, "{") {
+			if dep, ok := parseGradleCoordinates(strings.Trim(l.value, `"`)); ok {
+				catalog.libraries[l.alias] = dep
+			}
+			continue
+		}
+		fields := tomlInlineTable(l.value)
+		module := fields["module"]
+		if module == "" && fields["group"] != "" && fields["name"] != "" {
+			module = fields["group"] + ":" + fields["name"]
+		}
+		dep, ok := parseGradleCoordinates(module)
+		if !ok {
+			continue
+		}
+		if ref := fields["version.ref"]; ref != "" {
+			dep.Version = catalog.versions[ref]
+		} else if v := tomlVersion(fields); v != "" {
+			dep.Version = v
+		}
+		catalog.libraries[l.alias] = dep
+	}
+	return catalog, nil
+}
+
+// tomlInlineTable returns the string values of an inline table, nested tables
+// such as version = { strictly = "1.0" } are flattened to version.strictly
+func tomlInlineTable(value string) map[string]string {
+	fields := map[string]string{}
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "{"), "}")
+	prefix := ""
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if nested := strings.Index(part, "{"); nested != -1 {
+			// start of a nested table, e.g. version = { strictly = "1.0"
+			prefix = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part[:nested]), "=")) + "."
+			part = part[nested+1:]
+		}
+		for _, kv := range tomlInlineStringRegex.FindAllStringSubmatch(part, -1) {
+			fields[prefix+kv[1]] = kv[2]
+		}
+		if strings.Contains(part, "}") {
+			prefix = ""
+		}
+	}
+	return fields
+}
+
+// tomlVersion returns the version of a library or version entry, rich versions
+// use the strictly, require or prefer version in that order.
+func tomlVersion(fields map[string]string) string {
+	for _, key := range []string{"version", "version.strictly", "version.require", "version.prefer", "strictly", "require", "prefer"} {
+		if v := fields[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func tomlStrings(value string) []string {
+	values := []string{}
+	for _, match := range tomlStringRegex.FindAllStringSubmatch(value, -1) {
+		values = append(values, match[1])
+	}
+	return values
+}
+
+func stripTOMLComment(line string) string {
+	inString := false
+	for i, c := range line {
+		switch c {
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
@@ -0,0 +1,57 @@
+package java
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+func Test_getDependenciesFromGradleBuildFiles(t *testing.T) {
+	location, err := filepath.Abs(filepath.Join("testdata", "gradle-kts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := javaServiceClient{
+		log:    testr.New(t),
+		config: provider.InitConfig{Location: location},
+	}
+	if tool := p.GetBuildTool(); tool != gradle {
+		t.Errorf("GetBuildTool() = %s, want %s", tool, gradle)
+	}
+
+	got, err := p.getDependenciesFromGradleBuildFiles()
+	if err != nil {
+		t.Fatalf("getDependenciesFromGradleBuildFiles() error = %v", err)
+	}
+	want := map[uri.URI][]provider.Dep{
+		uri.File(filepath.Join(location, "build.gradle.kts")): {
+			{Name: "org.springframework.boot.spring-boot-dependencies", Version: "3.1.5"},
+			{Name: "com.google.guava.guava", Version: "32.1.3-jre"},
+			{Name: "com.fasterxml.jackson.core.jackson-databind", Version: "2.15.3"},
+			{Name: "com.fasterxml.jackson.core.jackson-annotations", Version: "2.15.3"},
+			{Name: "org.apache.commons.commons-lang3", Version: "3.13.0"},
+			{Name: "jakarta.servlet.jakarta.servlet-api", Version: "6.0.0"},
+			{Name: "org.junit.jupiter.junit-jupiter", Version: "5.10.0"},
+		},
+		uri.File(filepath.Join(location, "services", "api", "build.gradle.kts")): {
+			{Name: "org.slf4j.slf4j-api", Version: "2.0.9"},
+			{Name: "com.google.guava.guava", Version: "32.1.3-jre"},
+		},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got dependencies for %d files, want %d", len(got), len(want))
+	}
+	for file, wantDeps := range want {
+		gotDeps := []provider.Dep{}
+		for _, dep := range got[file] {
+			gotDeps = append(gotDeps, dep.Dep)
+		}
+		if !reflect.DeepEqual(gotDeps, wantDeps) {
+			t.Errorf("dependencies of %s = %v, want %v", file, gotDeps, wantDeps)
+		}
+	}
+}
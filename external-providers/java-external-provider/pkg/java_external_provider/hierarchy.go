@@ -0,0 +1,95 @@
+package java
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/jsonrpc2"
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+// findSubtypes returns the fully qualified names of every class that extends
+// or implements the given type, directly or through another subtype.
+func (p *javaServiceClient) findSubtypes(ctx context.Context, fqn string) ([]string, error) {
+	if strings.ContainsAny(fqn, "*()[]{}|?+\\^$") {
+		return nil, fmt.Errorf("includeSubtypes requires a fully qualified type name, got pattern '%s'", fqn)
+	}
+	packageName, simpleName := splitTypeName(fqn)
+
+	symbols := []protocol.SymbolInformation{}
+	err := p.rpc.Call(ctx, "workspace/symbol", &protocol.WorkspaceSymbolParams{Query: simpleName}, &symbols)
+	if err != nil {
+		return nil, p.hierarchyRPCError(err, "workspace/symbol")
+	}
+
+	seen := map[string]bool{fqn: true}
+	subtypes := []string{}
+	for _, symbol := range symbols {
+		if symbol.Name != simpleName || symbol.ContainerName != packageName {
+			continue
+		}
+		items := []protocol.TypeHierarchyItem{}
+		err := p.rpc.Call(ctx, "textDocument/prepareTypeHierarchy", &protocol.TypeHierarchyPrepareParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: symbol.Location.URI},
+				Position:     symbol.Location.Range.Start,
+			},
+		}, &items)
+		if err != nil {
+			return nil, p.hierarchyRPCError(err, "textDocument/prepareTypeHierarchy")
+		}
+		// walk the hierarchy breadth first, interfaces can be reached through
+		// more than one path so every type is only expanded once.
+		for len(items) > 0 {
+			item := items[0]
+			items = items[1:]
+			children := []protocol.TypeHierarchyItem{}
+			err := p.rpc.Call(ctx, "typeHierarchy/subtypes", &protocol.TypeHierarchySubtypesParams{Item: item}, &children)
+			if err != nil {
+				return nil, p.hierarchyRPCError(err, "typeHierarchy/subtypes")
+			}
+			for _, child := range children {
+				name := typeHierarchyItemName(child)
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				subtypes = append(subtypes, name)
+				items = append(items, child)
+			}
+		}
+	}
+	return subtypes, nil
+}
+
+func (p *javaServiceClient) hierarchyRPCError(err error, method string) error {
+	if jsonrpc2.IsRPCClosed(err) {
+		p.log.Error(err, "connection to the language server is closed, language server is not running")
+		return fmt.Errorf("connection to the language server is closed, language server is not running")
+	}
+	p.log.Error(err, "unable to resolve type hierarchy", "method", method)
+	return fmt.Errorf("unable to resolve type hierarchy: %w", err)
+}
+
+// splitTypeName splits a fully qualified name into its package and simple name
+func splitTypeName(fqn string) (string, string) {
+	i := strings.LastIndex(fqn, ".")
+	if i == -1 {
+		return "", fqn
+	}
+	return fqn[:i], fqn[i+1:]
+}
+
+// typeHierarchyItemName returns the fully qualified name of a type hierarchy
+// item, jdtls puts the package in the detail of the item.
+func typeHierarchyItemName(item protocol.TypeHierarchyItem) string {
+	switch {
+	case item.Detail == "":
+		return item.Name
+	case item.Detail == item.Name || strings.HasSuffix(item.Detail, "."+item.Name):
+		return item.Detail
+	default:
+		return item.Detail + "." + item.Name
+	}
+}
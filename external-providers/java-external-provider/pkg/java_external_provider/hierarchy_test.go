@@ -0,0 +1,48 @@
+package java
+
+import (
+	"context"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+func Test_typeHierarchyItemName(t *testing.T) {
+	tests := []struct {
+		name string
+		item protocol.TypeHierarchyItem
+		want string
+	}{
+		{
+			name: "package in detail",
+			item: protocol.TypeHierarchyItem{Name: "HttpServlet", Detail: "javax.servlet.http"},
+			want: "javax.servlet.http.HttpServlet",
+		},
+		{
+			name: "fully qualified name in detail",
+			item: protocol.TypeHierarchyItem{Name: "HttpServlet", Detail: "javax.servlet.http.HttpServlet"},
+			want: "javax.servlet.http.HttpServlet",
+		},
+		{
+			name: "default package",
+			item: protocol.TypeHierarchyItem{Name: "MyServlet"},
+			want: "MyServlet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := typeHierarchyItemName(tt.item); got != tt.want {
+				t.Errorf("typeHierarchyItemName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findSubtypesRejectsPatterns(t *testing.T) {
+	p := &javaServiceClient{}
+	for _, pattern := range []string{"javax.servlet.*", "javax.servlet.(Generic|Http)Servlet"} {
+		if _, err := p.findSubtypes(context.TODO(), pattern); err == nil {
+			t.Errorf("expected error for pattern %s", pattern)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package java
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// mavenIndex identifies jars by their sha1 checksum without network access so
+// that binaries can be analyzed in air-gapped environments.
+type mavenIndex struct {
+	artifacts map[string]javaArtifact
+	// offline disables the lookups on search.maven.org for jars missing from the index
+	offline bool
+}
+
+// loadMavenIndex reads a checksum database with one "<sha1> <groupId>:<artifactId>:<version>"
+// entry per line, blank lines and lines starting with # are ignored. The path
+// is optional, an index without entries only keeps the lookups offline.
+func loadMavenIndex(path string, offline bool) (*mavenIndex, error) {
+	index := &mavenIndex{
+		artifacts: map[string]javaArtifact{},
+		offline:   offline,
+	}
+	if path == "" {
+		return index, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open maven index %s: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid maven index entry at %s:%d, expected '<sha1> <groupId>:<artifactId>:<version>'", path, lineNumber)
+		}
+		coordinates := strings.Split(fields[1], ":")
+		if len(coordinates) != 3 {
+			return nil, fmt.Errorf("invalid maven coordinates at %s:%d, expected '<groupId>:<artifactId>:<version>'", path, lineNumber)
+		}
+		sha1 := strings.ToLower(fields[0])
+		index.artifacts[sha1] = javaArtifact{
+			GroupId:    coordinates[0],
+			ArtifactId: coordinates[1],
+			Version:    coordinates[2],
+			sha1:       sha1,
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read maven index %s: %w", path, err)
+	}
+	return index, nil
+}
+
+func (m *mavenIndex) lookup(sha1 string) (javaArtifact, bool) {
+	if m == nil {
+		return javaArtifact{}, false
+	}
+	artifact, ok := m.artifacts[strings.ToLower(sha1)]
+	return artifact, ok
+}
+
+func (m *mavenIndex) isOffline() bool {
+	return m != nil && m.offline
+}
@@ -0,0 +1,90 @@
+package java
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_loadMavenIndex(t *testing.T) {
+	index, err := loadMavenIndex(filepath.Join("testdata", "maven-index.txt"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := javaArtifact{
+		GroupId:    "org.hamcrest",
+		ArtifactId: "hamcrest-core",
+		Version:    "1.3",
+		sha1:       "42a25dc3219429f0e5d060061f71acb49bf010a0",
+	}
+	if got, ok := index.lookup("42a25dc3219429f0e5d060061f71acb49bf010a0"); !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("lookup() = %v, %v, want %v", got, ok, want)
+	}
+	if _, ok := index.lookup("0000000000000000000000000000000000000000"); ok {
+		t.Errorf("expected unknown checksum not to be found")
+	}
+
+	invalid := filepath.Join(t.TempDir(), "index.txt")
+	if err := os.WriteFile(invalid, []byte("4e031bb61df09069aeb2bffb4019e7a5034a4ee0 junit:junit\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadMavenIndex(invalid, false); err == nil {
+		t.Errorf("expected an error for an invalid index entry")
+	}
+}
+
+func Test_toDependencyOffline(t *testing.T) {
+	jarFile := filepath.Join(t.TempDir(), "embedded.jar")
+	f, err := os.Create(jarFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	properties, err := w.Create("META-INF/maven/io.konveyor/embedded/pom.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	properties.Write([]byte("groupId=io.konveyor\nartifactId=embedded\nversion=1.0.0\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	content, err := os.ReadFile(jarFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum(content)
+	sha1sum := hex.EncodeToString(sum[:])
+
+	// jars in the index are identified by their checksum
+	index := &mavenIndex{
+		artifacts: map[string]javaArtifact{
+			sha1sum: {GroupId: "io.konveyor.demo", ArtifactId: "embedded-lib", Version: "2.0.0", sha1: sha1sum},
+		},
+		offline: true,
+	}
+	got, err := toDependency(context.TODO(), jarFile, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := javaArtifact{GroupId: "io.konveyor.demo", ArtifactId: "embedded-lib", Version: "2.0.0", sha1: sha1sum, foundOnline: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toDependency() = %v, want %v", got, want)
+	}
+
+	// other jars fall back to their pom properties without looking them up online
+	got, err = toDependency(context.TODO(), jarFile, &mavenIndex{artifacts: map[string]javaArtifact{}, offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = javaArtifact{GroupId: "io.konveyor", ArtifactId: "embedded", Version: "1.0.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toDependency() = %v, want %v", got, want)
+	}
+}
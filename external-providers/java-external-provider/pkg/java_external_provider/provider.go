@@ -21,6 +21,7 @@ import (
 	"github.com/konveyor/analyzer-lsp/jsonrpc2"
 	"github.com/konveyor/analyzer-lsp/lsp/protocol"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/process"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/konveyor/analyzer-lsp/tracing"
 	"github.com/nxadm/tail"
@@ -46,6 +47,12 @@ const (
 	MVN_INSECURE_SETTING          = "mavenInsecure"
 	JVM_MAX_MEM_INIT_OPTION       = "jvmMaxMem"
 	FERN_FLOWER_INIT_OPTION       = "fernFlowerPath"
+	MVN_INDEX_PATH_INIT_OPTION    = "mavenIndexPath"
+	MVN_OFFLINE_SETTING           = "mavenOffline"
+	DECOMPILE_CACHE_INIT_OPTION   = "decompileCacheDir"
+	BYTECODE_ANALYSIS_INIT_OPTION = "bytecodeAnalysis"
+	JVM_OPTIONS_INIT_OPTION       = "jvmOptions"
+	WORKSPACE_CACHE_INIT_OPTION   = "workspaceCacheDir"
 )
 
 // Rule Location to location that the bundle understands
@@ -92,7 +99,11 @@ var _ provider.InternalProviderClient = &javaProvider{}
 var _ provider.DependencyLocationResolver = &javaProvider{}
 
 type javaCondition struct {
-	Referenced referenceCondition `yaml:"referenced"`
+	Referenced     referenceCondition      `yaml:"referenced"`
+	Descriptor     descriptorCondition     `yaml:"descriptor"`
+	SpringBean     springBeanCondition     `yaml:"springBean"`
+	SpringProperty springPropertyCondition `yaml:"springProperty"`
+	WebPage        webPageCondition        `yaml:"webPage"`
 }
 
 type referenceCondition struct {
@@ -100,6 +111,12 @@ type referenceCondition struct {
 	Location  string    `yaml:"location"`
 	Annotated annotated `yaml:"annotated,omitempty" json:"annotated,omitempty"`
 	Filepaths []string  `yaml:"filepaths"`
+	// IncludeSubtypes also matches the classes extending or implementing the type in the pattern
+	IncludeSubtypes bool `yaml:"includeSubtypes,omitempty" json:"includeSubtypes,omitempty"`
+	// ParameterTypes and ReturnType narrow down METHOD_CALL matches to the calls
+	// of methods with this signature
+	ParameterTypes []string `yaml:"parameterTypes,omitempty" json:"parameterTypes,omitempty"`
+	ReturnType     string   `yaml:"returnType,omitempty" json:"returnType,omitempty"`
 }
 
 type annotated struct {
@@ -144,6 +161,20 @@ func (p *javaProvider) Capabilities() []provider.Capability {
 	} else {
 		caps = append(caps, refCap)
 	}
+	descriptorCap, err := provider.ToProviderCap(r, p.Log, javaCondition{}, "descriptor")
+	if err != nil {
+		p.Log.Error(err, "unable to get descriptor capability")
+	} else {
+		caps = append(caps, descriptorCap)
+	}
+	for _, name := range []string{"springBean", "springProperty", "webPage"} {
+		fileCap, err := provider.ToProviderCap(r, p.Log, javaCondition{}, name)
+		if err != nil {
+			p.Log.Error(err, "unable to get capability", "capability", name)
+		} else {
+			caps = append(caps, fileCap)
+		}
+	}
 	if p.hasMaven {
 		depCap, err := provider.ToProviderCap(r, p.Log, provider.DependencyConditionCap{}, "dependency")
 		if err != nil {
@@ -227,6 +258,11 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 	}
 	log = log.WithValues("provider", "java")
 
+	pathFilter, err := provider.GetPathFilterFromConfig(config)
+	if err != nil {
+		return nil, additionalBuiltinConfig, err
+	}
+
 	// read provider settings
 	bundlesString, ok := config.ProviderSpecificConfig[BUNDLES_INIT_OPTION].(string)
 	if !ok {
@@ -234,10 +270,18 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 	}
 	bundles := strings.Split(bundlesString, ",")
 
+	// artifacts of a previous preparation are reused, the workspace keeps the
+	// symbol index of jdtls and the maven repository the resolved and
+	// decompiled dependencies
+	preparedDir, _ := config.ProviderSpecificConfig[provider.PreparedDirConfigKey].(string)
+
 	workspace, ok := config.ProviderSpecificConfig[WORKSPACE_INIT_OPTION].(string)
 	if !ok {
 		workspace = ""
 	}
+	if workspace == "" && preparedDir != "" {
+		workspace = filepath.Join(preparedDir, "workspace")
+	}
 
 	mavenSettingsFile, ok := config.ProviderSpecificConfig[MVN_SETTINGS_FILE_INIT_OPTION].(string)
 	if !ok {
@@ -246,6 +290,9 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 	var globalSettingsFile string
 	var returnError error
 	globalM2, ok := config.ProviderSpecificConfig[GLOBAL_SETTINGS_INIT_OPTION].(string)
+	if !ok && preparedDir != "" {
+		globalM2, ok = filepath.Join(preparedDir, "m2"), true
+	}
 	if !ok {
 		globalM2 = ""
 	} else {
@@ -271,6 +318,27 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		fernflower = "/bin/fernflower.jar"
 	}
 
+	mavenIndexPath, _ := config.ProviderSpecificConfig[MVN_INDEX_PATH_INIT_OPTION].(string)
+	mavenOffline, _ := config.ProviderSpecificConfig[MVN_OFFLINE_SETTING].(bool)
+	if mavenOffline {
+		log.Info("maven offline setting enabled, jars are only identified with the maven index")
+	}
+	mavenIndex, err := loadMavenIndex(mavenIndexPath, mavenOffline)
+	if err != nil {
+		return nil, additionalBuiltinConfig, err
+	}
+
+	decompileCacheDir, _ := config.ProviderSpecificConfig[DECOMPILE_CACHE_INIT_OPTION].(string)
+	if decompileCacheDir == "" && preparedDir != "" {
+		decompileCacheDir = filepath.Join(preparedDir, "decompiled")
+	}
+	decompileCache, err := newDecompileCache(decompileCacheDir)
+	if err != nil {
+		return nil, additionalBuiltinConfig, err
+	}
+
+	bytecodeAnalysis, _ := config.ProviderSpecificConfig[BYTECODE_ANALYSIS_INIT_OPTION].(bool)
+
 	isBinary := false
 	var returnErr error
 	// each service client should have their own context
@@ -336,10 +404,19 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 	}
 
 	extension := strings.ToLower(path.Ext(config.Location))
+	var bytecode *bytecodeIndex
 	switch extension {
 	case JavaArchive, WebArchive, EnterpriseArchive:
-		depLocation, sourceLocation, err := decompileJava(ctx, log, fernflower,
-			config.Location, getMavenLocalRepoPath(mavenSettingsFile))
+		var depLocation, sourceLocation string
+		if bytecodeAnalysis {
+			var classFiles []string
+			depLocation, sourceLocation, classFiles, err = explodeBytecode(ctx, log,
+				config.Location, getMavenLocalRepoPath(mavenSettingsFile), mavenIndex)
+			bytecode = newBytecodeIndex(log, classFiles)
+		} else {
+			depLocation, sourceLocation, err = decompileJava(ctx, log, fernflower,
+				config.Location, getMavenLocalRepoPath(mavenSettingsFile), mavenIndex, decompileCache)
+		}
 		if err != nil {
 			cancelFunc()
 			return nil, additionalBuiltinConfig, err
@@ -348,6 +425,10 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		// for binaries, we fallback to looking at .jar files only for deps
 		config.DependencyPath = depLocation
 		isBinary = true
+	default:
+		if bytecodeAnalysis {
+			log.Info("bytecode analysis only applies to binaries, the location is analyzed with the language server")
+		}
 	}
 	additionalBuiltinConfig.Location = config.Location
 	additionalBuiltinConfig.DependencyPath = config.DependencyPath
@@ -359,6 +440,33 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		}
 	}
 
+	if bytecode != nil {
+		// the class files answer the referenced conditions, the language
+		// server is not started
+		svcClient := &javaServiceClient{
+			cancelFunc:        cancelFunc,
+			config:            config,
+			log:               log,
+			depToLabels:       map[string]*depLabelItem{},
+			isLocationBinary:  true,
+			mvnInsecure:       mavenInsecure,
+			mvnSettingsFile:   mavenSettingsFile,
+			mavenIndex:        mavenIndex,
+			decompileCache:    decompileCache,
+			globalSettings:    globalSettingsFile,
+			preparedDir:       preparedDir,
+			depsLocationCache: make(map[string]int),
+			includedPaths:     provider.GetIncludedPathsFromConfig(config, false),
+			pathFilter:        pathFilter,
+			bytecode:          bytecode,
+		}
+		if err := svcClient.depInit(); err != nil {
+			cancelFunc()
+			return nil, provider.InitConfig{}, err
+		}
+		return svcClient, additionalBuiltinConfig, nil
+	}
+
 	jdtlsBasePath, err := filepath.Abs(filepath.Dir(filepath.Dir(lspServerPath)))
 	if err != nil {
 		cancelFunc()
@@ -383,7 +491,24 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		return nil, additionalBuiltinConfig, fmt.Errorf("failed getting java executable - %v", err)
 	}
 
-	jdtlsArgs := []string{
+	if workspace == "" {
+		if cacheDir, _ := config.ProviderSpecificConfig[WORKSPACE_CACHE_INIT_OPTION].(string); cacheDir != "" {
+			// the index of the workspace of a previous analysis of the same
+			// project is reused, saving jdtls from indexing it again
+			hash, err := projectHash(config.Location, cacheDir, bundles...)
+			if err != nil {
+				cancelFunc()
+				return nil, additionalBuiltinConfig, fmt.Errorf("failed to hash project for workspace cache - %w", err)
+			}
+			workspace, err = cachedWorkspace(log, cacheDir, config.Location, hash)
+			if err != nil {
+				cancelFunc()
+				return nil, additionalBuiltinConfig, fmt.Errorf("failed to prepare cached workspace - %w", err)
+			}
+		}
+	}
+
+	jvmArgs := []string{
 		"-Declipse.application=org.eclipse.jdt.ls.core.id1",
 		"-Dosgi.bundles.defaultStartLevel=4",
 		"-Declipse.product=org.eclipse.jdt.ls.core.product",
@@ -397,16 +522,22 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		"--add-modules=ALL-SYSTEM",
 		"--add-opens", "java.base/java.util=ALL-UNNAMED",
 		"--add-opens", "java.base/java.lang=ALL-UNNAMED",
+	}
+	// options of the JVM must come before the jar, the last one of an option
+	// given twice wins over the defaults above
+	if val, ok := config.ProviderSpecificConfig[JVM_MAX_MEM_INIT_OPTION].(string); ok && val != "" {
+		jvmArgs = append(jvmArgs, fmt.Sprintf("-Xmx%s", val))
+	}
+	jvmArgs = append(jvmArgs, stringList(config.ProviderSpecificConfig[JVM_OPTIONS_INIT_OPTION])...)
+	jdtlsArgs := append(jvmArgs,
 		"-jar", jarPath,
 		"-Djava.net.useSystemProxies=true",
 		"-configuration", "./",
 		"-data", workspace,
-	}
-
-	if val, ok := config.ProviderSpecificConfig[JVM_MAX_MEM_INIT_OPTION].(string); ok && val != "" {
-		jdtlsArgs = append(jdtlsArgs, fmt.Sprintf("-Xmx%s", val))
-	}
-	cmd := exec.CommandContext(ctx, javaExec, jdtlsArgs...)
+	)
+	// gradle and maven daemons started by the language server are stopped along with it
+	group := process.Command(ctx, javaExec, jdtlsArgs...)
+	cmd := group.Cmd
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		cancelFunc()
@@ -422,7 +553,7 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 	go func() {
-		err := cmd.Start()
+		err := group.Start()
 		wg.Done()
 		if err != nil {
 			cancelFunc()
@@ -480,9 +611,13 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		isLocationBinary:  isBinary,
 		mvnInsecure:       mavenInsecure,
 		mvnSettingsFile:   mavenSettingsFile,
+		mavenIndex:        mavenIndex,
+		decompileCache:    decompileCache,
 		globalSettings:    globalSettingsFile,
+		preparedDir:       preparedDir,
 		depsLocationCache: make(map[string]int),
 		includedPaths:     provider.GetIncludedPathsFromConfig(config, false),
+		pathFilter:        pathFilter,
 	}
 
 	if mode == provider.FullAnalysisMode {
@@ -490,7 +625,7 @@ func (p *javaProvider) Init(ctx context.Context, log logr.Logger, config provide
 		// we need to do this for jdtls to correctly recognize source attachment for dep
 		switch svcClient.GetBuildTool() {
 		case maven:
-			err := resolveSourcesJarsForMaven(ctx, log, fernflower, config.Location, mavenSettingsFile, mavenInsecure)
+			err := resolveSourcesJarsForMaven(ctx, log, fernflower, config.Location, mavenSettingsFile, mavenInsecure, decompileCache)
 			if err != nil {
 				// TODO (pgaikwad): should we ignore this failure?
 				log.Error(err, "failed to resolve maven sources jar for location", "location", config.Location)
@@ -542,7 +677,7 @@ func resolveSourcesJarsForGradle(ctx context.Context, log logr.Logger, fernflowe
 
 	log.V(5).Info("resolving dependency sources for gradle")
 
-	gb := svc.findGradleBuild()
+	gb := findGradleFile(location, gradleBuildFiles)
 	if gb == "" {
 		return fmt.Errorf("could not find gradle build file for project")
 	}
@@ -557,7 +692,12 @@ func resolveSourcesJarsForGradle(ctx context.Context, log logr.Logger, fernflowe
 
 	// append downloader task
 	taskfile := "/root/.gradle/task.gradle"
-	err = AppendToFile(taskfile, taskgb)
+	if strings.HasSuffix(gb, ".kts") {
+		// the task is written in groovy, kotlin builds can apply it as a script plugin
+		err = appendLine(taskgb, fmt.Sprintf("apply(from = %q)", taskfile))
+	} else {
+		err = AppendToFile(taskfile, taskgb)
+	}
 	if err != nil {
 		return fmt.Errorf("error appending file %s to %s", taskfile, taskgb)
 	}
@@ -635,7 +775,7 @@ func resolveSourcesJarsForGradle(ctx context.Context, log logr.Logger, fernflowe
 				outputPath: filepath.Join(filepath.Dir(artifactPath), "decompiled", jarName),
 			})
 		}
-		err = decompile(ctx, log, alwaysDecompileFilter(true), 10, decompileJobs, fernflower, "")
+		err = decompile(ctx, log, alwaysDecompileFilter(true), 10, decompileJobs, fernflower, "", svc.decompileCache)
 		if err != nil {
 			return err
 		}
@@ -702,8 +842,9 @@ func findGradleArtifact(root string, artifactId string) (string, error) {
 func (j *javaProvider) GetLocation(ctx context.Context, dep konveyor.Dep, file string) (engine.Location, error) {
 	location := engine.Location{StartPosition: engine.Position{}, EndPosition: engine.Position{}}
 
-	cacheKey := fmt.Sprintf("%s-%s-%s-%v",
-		dep.Name, dep.Version, dep.ResolvedIdentifier, dep.Indirect)
+	// the same dependency can be declared by more than one module of a reactor
+	cacheKey := fmt.Sprintf("%s-%s-%s-%v-%s",
+		dep.Name, dep.Version, dep.ResolvedIdentifier, dep.Indirect, file)
 	j.depsMutex.RLock()
 	val, exists := j.depsLocationCache[cacheKey]
 	j.depsMutex.RUnlock()
@@ -766,7 +907,7 @@ func (j *javaProvider) GetLocation(ctx context.Context, dep konveyor.Dep, file s
 
 // resolveSourcesJarsForMaven for a given source code location, runs maven to find
 // deps that don't have sources attached and decompiles them
-func resolveSourcesJarsForMaven(ctx context.Context, log logr.Logger, fernflower, location, mavenSettings string, mvnInsecure bool) error {
+func resolveSourcesJarsForMaven(ctx context.Context, log logr.Logger, fernflower, location, mavenSettings string, mvnInsecure bool, cache *decompileCache) error {
 	// TODO (pgaikwad): when we move to external provider, inherit context from parent
 	ctx, span := tracing.StartNewSpan(ctx, "resolve-sources")
 	defer span.End()
@@ -818,7 +959,7 @@ func resolveSourcesJarsForMaven(ctx context.Context, log logr.Logger, fernflower
 				m2Repo, groupDirs, artifactDirs, artifact.Version, "decompiled", jarName),
 		})
 	}
-	err = decompile(ctx, log, alwaysDecompileFilter(true), 10, decompileJobs, fernflower, "")
+	err = decompile(ctx, log, alwaysDecompileFilter(true), 10, decompileJobs, fernflower, "", cache)
 	if err != nil {
 		return err
 	}
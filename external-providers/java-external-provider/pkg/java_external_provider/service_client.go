@@ -16,6 +16,7 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/konveyor/analyzer-lsp/jsonrpc2"
 	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/pathfilter"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"go.lsp.dev/uri"
 	"gopkg.in/yaml.v2"
@@ -33,11 +34,18 @@ type javaServiceClient struct {
 	isLocationBinary  bool
 	mvnInsecure       bool
 	mvnSettingsFile   string
+	mavenIndex        *mavenIndex
+	decompileCache    *decompileCache
 	globalSettings    string
 	depsMutex         sync.RWMutex
 	depsCache         map[uri.URI][]*provider.Dep
 	depsLocationCache map[string]int
 	includedPaths     []string
+	pathFilter        *pathfilter.Filter
+	preparedDir       string
+	// bytecode answers the referenced conditions of binaries analyzed
+	// without the language server, nil otherwise
+	bytecode *bytecodeIndex
 }
 
 type depLabelItem struct {
@@ -65,9 +73,23 @@ func (p *javaServiceClient) Evaluate(ctx context.Context, cap string, conditionI
 		return provider.ProviderEvaluateResponse{}, fmt.Errorf("unable to get condition context info: %v", err)
 	}
 
+	switch cap {
+	case "descriptor":
+		return p.evaluateDescriptor(ctx, cond.Descriptor, condCtx)
+	case "springBean":
+		return p.evaluateSpringBean(ctx, cond.SpringBean)
+	case "springProperty":
+		return p.evaluateSpringProperty(ctx, cond.SpringProperty)
+	case "webPage":
+		return p.evaluateWebPage(ctx, cond.WebPage)
+	}
+
 	if cond.Referenced.Pattern == "" {
 		return provider.ProviderEvaluateResponse{}, fmt.Errorf("provided query pattern empty")
 	}
+	if p.bytecode != nil {
+		return p.evaluateBytecode(ctx, cond.Referenced)
+	}
 	symbols, err := p.GetAllSymbols(ctx, *cond, condCtx)
 	if err != nil {
 		p.log.Error(err, "unable to get symbols", "symbols", symbols, "cap", cap, "conditionInfo", cond)
@@ -75,29 +97,38 @@ func (p *javaServiceClient) Evaluate(ctx context.Context, cap string, conditionI
 	}
 	p.log.Info("Symbols retrieved", "symbols", len(symbols), "cap", cap, "conditionInfo", cond)
 
-	incidents := []provider.IncidentContext{}
-	switch locationToCode[strings.ToLower(cond.Referenced.Location)] {
-	case 0, 3, 4, 6, 10, 11, 12, 13, 14:
-		// Filter handle for type, find all the referneces to this type.
-		incidents, err = p.filterDefault(symbols)
-	case 1, 5:
-		incidents, err = p.filterTypesInheritance(symbols)
-	case 2:
-		incidents, err = p.filterMethodSymbols(symbols)
-	case 7:
-		incidents, err = p.filterMethodSymbols(symbols)
-	case 8:
-		incidents, err = p.filterModulesImports(symbols)
-	case 9:
-		incidents, err = p.filterVariableDeclaration(symbols)
-	default:
-
-	}
-	// push error up for easier printing.
+	incidents, err := p.filterSymbols(ctx, cond.Referenced, symbols)
 	if err != nil {
 		return provider.ProviderEvaluateResponse{}, err
 	}
 
+	if cond.Referenced.IncludeSubtypes {
+		subtypes, err := p.findSubtypes(ctx, cond.Referenced.Pattern)
+		if err != nil {
+			return provider.ProviderEvaluateResponse{}, err
+		}
+		p.log.V(5).Info("subtypes found", "type", cond.Referenced.Pattern, "subtypes", subtypes)
+		for _, subtype := range subtypes {
+			subtypeCond := *cond
+			subtypeCond.Referenced.Pattern = subtype
+			subtypeSymbols, err := p.GetAllSymbols(ctx, subtypeCond, condCtx)
+			if err != nil {
+				return provider.ProviderEvaluateResponse{}, err
+			}
+			subtypeIncidents, err := p.filterSymbols(ctx, cond.Referenced, subtypeSymbols)
+			if err != nil {
+				return provider.ProviderEvaluateResponse{}, err
+			}
+			for i := range subtypeIncidents {
+				if subtypeIncidents[i].Variables == nil {
+					subtypeIncidents[i].Variables = map[string]interface{}{}
+				}
+				subtypeIncidents[i].Variables["subtype"] = subtype
+			}
+			incidents = append(incidents, subtypeIncidents...)
+		}
+	}
+
 	if len(incidents) == 0 {
 		return provider.ProviderEvaluateResponse{
 			Matched: false,
@@ -109,6 +140,29 @@ func (p *javaServiceClient) Evaluate(ctx context.Context, cap string, conditionI
 	}, nil
 }
 
+func (p *javaServiceClient) filterSymbols(ctx context.Context, cond referenceCondition, symbols []protocol.WorkspaceSymbol) ([]provider.IncidentContext, error) {
+	switch locationToCode[strings.ToLower(cond.Location)] {
+	case 0, 3, 4, 6, 10, 11, 12, 13, 14:
+		// Filter handle for type, find all the referneces to this type.
+		return p.filterDefault(symbols)
+	case 1, 5:
+		return p.filterTypesInheritance(symbols)
+	case 2:
+		if cond.hasSignature() {
+			return p.filterMethodSignatures(ctx, cond, symbols)
+		}
+		return p.filterMethodSymbols(symbols)
+	case 7:
+		return p.filterMethodSymbols(symbols)
+	case 8:
+		return p.filterModulesImports(symbols)
+	case 9:
+		return p.filterVariableDeclaration(symbols)
+	default:
+		return []provider.IncidentContext{}, nil
+	}
+}
+
 func (p *javaServiceClient) GetAllSymbols(ctx context.Context, c javaCondition, condCTX *provider.ProviderContext) ([]protocol.WorkspaceSymbol, error) {
 	// This command will run the added bundle to the language server. The command over the wire needs too look like this.
 	// in this case the project is hardcoded in the init of the Langauge Server above
@@ -156,6 +210,19 @@ func (p *javaServiceClient) GetAllSymbols(ctx context.Context, c javaCondition,
 		}
 	}
 
+	if !p.pathFilter.Empty() {
+		// globs of included and excluded paths can't be pushed down to the
+		// language server, the files of the location are filtered here
+		var filteredRefs []protocol.WorkspaceSymbol
+		for _, ref := range refs {
+			location, ok := ref.Location.Value.(protocol.Location)
+			if !ok || !p.pathFilter.Contains(location.URI) || p.pathFilter.Match(location.URI) {
+				filteredRefs = append(filteredRefs, ref)
+			}
+		}
+		refs = filteredRefs
+	}
+
 	if c.Referenced.Filepaths != nil {
 		// filter according to the given filepaths
 		var filteredRefs []protocol.WorkspaceSymbol
@@ -218,6 +285,10 @@ func (p *javaServiceClient) GetAllReferences(ctx context.Context, symbol protoco
 
 func (p *javaServiceClient) Stop() {
 	p.cancelFunc()
+	if p.cmd == nil {
+		// the language server is not started for the bytecode analysis
+		return
+	}
 	err := p.cmd.Wait()
 	if err != nil {
 		p.log.Info("stopping java provider", "error", err)
@@ -0,0 +1,274 @@
+package java
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// methodSignatureRegex matches the signature jdtls shows when hovering a method,
+// e.g. "void com.example.Repository.save(java.lang.Object entity)"
+var methodSignatureRegex = regexp.MustCompile(`^(?:<.*>\s+)?(.+?)\s+([\w$.]+)\((.*)\)`)
+
+type methodSignature struct {
+	returnType     string
+	name           string
+	parameterTypes []string
+}
+
+// signatureMatcher holds the compiled parameter and return type patterns of a
+// METHOD_CALL condition.
+type signatureMatcher struct {
+	parameterTypes []*regexp.Regexp
+	// matchParameters is false when the condition does not constrain the parameters
+	matchParameters bool
+	returnType      *regexp.Regexp
+}
+
+func (c referenceCondition) hasSignature() bool {
+	return c.ParameterTypes != nil || c.ReturnType != ""
+}
+
+func newSignatureMatcher(c referenceCondition) (*signatureMatcher, error) {
+	m := &signatureMatcher{matchParameters: c.ParameterTypes != nil}
+	for _, parameterType := range c.ParameterTypes {
+		r, err := compileTypePattern(parameterType)
+		if err != nil {
+			return nil, err
+		}
+		m.parameterTypes = append(m.parameterTypes, r)
+	}
+	if c.ReturnType != "" {
+		r, err := compileTypePattern(c.ReturnType)
+		if err != nil {
+			return nil, err
+		}
+		m.returnType = r
+	}
+	return m, nil
+}
+
+func compileTypePattern(pattern string) (*regexp.Regexp, error) {
+	r, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile type pattern '%s': %w", pattern, err)
+	}
+	return r, nil
+}
+
+// matches checks the signature against the condition, types match either with
+// their fully qualified or simple name, with or without type arguments.
+func (m *signatureMatcher) matches(s methodSignature) bool {
+	if m.returnType != nil && !matchesType(m.returnType, s.returnType) {
+		return false
+	}
+	if !m.matchParameters {
+		return true
+	}
+	if len(m.parameterTypes) != len(s.parameterTypes) {
+		return false
+	}
+	for i, r := range m.parameterTypes {
+		if !matchesType(r, s.parameterTypes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesType(r *regexp.Regexp, t string) bool {
+	erased := eraseTypeArguments(t)
+	base := strings.TrimSuffix(erased, "...")
+	simple := base[strings.LastIndex(base, ".")+1:] + erased[len(base):]
+	return r.MatchString(t) || r.MatchString(erased) || r.MatchString(simple)
+}
+
+func eraseTypeArguments(t string) string {
+	var b strings.Builder
+	depth := 0
+	for _, c := range t {
+		switch {
+		case c == '<':
+			depth++
+		case c == '>':
+			depth--
+		case depth == 0:
+			b.WriteRune(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// parseMethodSignature parses the signature of a method as shown by jdtls
+func parseMethodSignature(signature string) (methodSignature, bool) {
+	match := methodSignatureRegex.FindStringSubmatch(strings.TrimSpace(signature))
+	if match == nil {
+		return methodSignature{}, false
+	}
+	s := methodSignature{
+		returnType:     strings.TrimSpace(match[1]),
+		name:           match[2],
+		parameterTypes: []string{},
+	}
+	for _, parameter := range splitTopLevel(match[3]) {
+		if t := parameterType(parameter); t != "" {
+			s.parameterTypes = append(s.parameterTypes, t)
+		}
+	}
+	return s, true
+}
+
+// splitTopLevel splits the parameter list on the commas outside of type arguments
+func splitTopLevel(parameters string) []string {
+	parts := []string{}
+	depth, start := 0, 0
+	for i, c := range parameters {
+		switch c {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, parameters[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if strings.TrimSpace(parameters[start:]) != "" {
+		parts = append(parts, parameters[start:])
+	}
+	return parts
+}
+
+// parameterType drops the annotations, modifiers and name of a parameter
+func parameterType(parameter string) string {
+	fields := []string{}
+	depth := 0
+	current := ""
+	for _, c := range strings.TrimSpace(parameter) {
+		switch {
+		case c == '<':
+			depth++
+		case c == '>':
+			depth--
+		}
+		if c == ' ' && depth == 0 {
+			if current != "" {
+				fields = append(fields, current)
+			}
+			current = ""
+			continue
+		}
+		current += string(c)
+	}
+	if current != "" {
+		fields = append(fields, current)
+	}
+	kept := []string{}
+	for _, f := range fields {
+		if strings.HasPrefix(f, "@") || f == "final" {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	switch len(kept) {
+	case 0:
+		return ""
+	case 1:
+		return kept[0]
+	default:
+		// the last field is the name of the parameter
+		return strings.Join(kept[:len(kept)-1], " ")
+	}
+}
+
+// filterMethodSignatures keeps the method calls whose signature matches the
+// condition and adds the signature to the variables of the incidents.
+func (p *javaServiceClient) filterMethodSignatures(ctx context.Context, cond referenceCondition, symbols []protocol.WorkspaceSymbol) ([]provider.IncidentContext, error) {
+	matcher, err := newSignatureMatcher(cond)
+	if err != nil {
+		return nil, err
+	}
+	incidents := []provider.IncidentContext{}
+	for _, symbol := range symbols {
+		location, ok := symbol.Location.Value.(protocol.Location)
+		if !ok {
+			continue
+		}
+		signature, ok := p.hoverMethodSignature(ctx, location)
+		if !ok {
+			p.log.V(5).Info("unable to resolve method signature", "symbol", symbol.Name, "uri", location.URI)
+			continue
+		}
+		if !matcher.matches(signature) {
+			continue
+		}
+		incident, err := p.convertToIncidentContext(symbol)
+		if err != nil {
+			return nil, err
+		}
+		incident.Variables["returnType"] = signature.returnType
+		incident.Variables["parameterTypes"] = signature.parameterTypes
+		incidents = append(incidents, incident)
+	}
+	return incidents, nil
+}
+
+// hoverMethodSignature asks the language server for the signature of the
+// method referenced at the location.
+func (p *javaServiceClient) hoverMethodSignature(ctx context.Context, location protocol.Location) (methodSignature, bool) {
+	params := &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: location.URI},
+			Position:     location.Range.Start,
+		},
+	}
+	var hover struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if err := p.rpc.Call(ctx, "textDocument/hover", params, &hover); err != nil {
+		p.log.V(5).Error(err, "unable to get hover information", "uri", location.URI)
+		return methodSignature{}, false
+	}
+	for _, content := range hoverContents(hover.Contents) {
+		if signature, ok := parseMethodSignature(content); ok {
+			return signature, true
+		}
+	}
+	return methodSignature{}, false
+}
+
+// hoverContents flattens the contents of a hover response, which can be a
+// string, a marked string, a markup content or a list of marked strings.
+func hoverContents(raw json.RawMessage) []string {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		contents := []string{}
+		for _, item := range list {
+			contents = append(contents, hoverContents(item)...)
+		}
+		return contents
+	}
+	var value struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &value.Value); err != nil {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil
+		}
+	}
+	contents := []string{}
+	// markdown content wraps the signature in a code block
+	for _, line := range strings.Split(value.Value, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "```") {
+			contents = append(contents, line)
+		}
+	}
+	return contents
+}
@@ -0,0 +1,151 @@
+package java
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_parseMethodSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature string
+		want      methodSignature
+		wantOk    bool
+	}{
+		{
+			name:      "simple method",
+			signature: "void com.example.Repository.save(java.lang.Object entity)",
+			want: methodSignature{
+				returnType:     "void",
+				name:           "com.example.Repository.save",
+				parameterTypes: []string{"java.lang.Object"},
+			},
+			wantOk: true,
+		},
+		{
+			name:      "generics, annotations and varargs",
+			signature: "<T> java.util.Map<java.lang.String, T> com.example.Util.index(@NotNull final java.util.List<T> items, java.lang.String... keys)",
+			want: methodSignature{
+				returnType:     "java.util.Map<java.lang.String, T>",
+				name:           "com.example.Util.index",
+				parameterTypes: []string{"java.util.List<T>", "java.lang.String..."},
+			},
+			wantOk: true,
+		},
+		{
+			name:      "no parameters",
+			signature: "int com.example.Counter.get()",
+			want: methodSignature{
+				returnType:     "int",
+				name:           "com.example.Counter.get",
+				parameterTypes: []string{},
+			},
+			wantOk: true,
+		},
+		{
+			name:      "not a method",
+			signature: "com.example.Repository repository",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseMethodSignature(tt.signature)
+			if ok != tt.wantOk {
+				t.Fatalf("parseMethodSignature() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMethodSignature() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_signatureMatcher(t *testing.T) {
+	saveObject := methodSignature{returnType: "void", parameterTypes: []string{"java.lang.Object"}}
+	saveString := methodSignature{returnType: "void", parameterTypes: []string{"java.lang.String"}}
+	findAll := methodSignature{returnType: "java.util.List<com.example.Entity>", parameterTypes: []string{}}
+	tests := []struct {
+		name      string
+		condition referenceCondition
+		signature methodSignature
+		want      bool
+	}{
+		{
+			name:      "fully qualified parameter type",
+			condition: referenceCondition{ParameterTypes: []string{"java.lang.Object"}},
+			signature: saveObject,
+			want:      true,
+		},
+		{
+			name:      "different parameter type",
+			condition: referenceCondition{ParameterTypes: []string{"java.lang.Object"}},
+			signature: saveString,
+		},
+		{
+			name:      "simple parameter type",
+			condition: referenceCondition{ParameterTypes: []string{"String"}},
+			signature: saveString,
+			want:      true,
+		},
+		{
+			name:      "different number of parameters",
+			condition: referenceCondition{ParameterTypes: []string{"String", "int"}},
+			signature: saveString,
+		},
+		{
+			name:      "no parameters",
+			condition: referenceCondition{ParameterTypes: []string{}},
+			signature: findAll,
+			want:      true,
+		},
+		{
+			name:      "erased return type",
+			condition: referenceCondition{ReturnType: "java.util.List"},
+			signature: findAll,
+			want:      true,
+		},
+		{
+			name:      "return type regex",
+			condition: referenceCondition{ReturnType: "java.util.(Set|Map)"},
+			signature: findAll,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newSignatureMatcher(tt.condition)
+			if err != nil {
+				t.Fatalf("newSignatureMatcher() error = %v", err)
+			}
+			if got := m.matches(tt.signature); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_hoverContents(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{
+			name: "marked strings",
+			raw:  `[{"language":"java","value":"void com.example.Repository.save(java.lang.Object entity)"},"Saves the entity"]`,
+			want: []string{"void com.example.Repository.save(java.lang.Object entity)", "Saves the entity"},
+		},
+		{
+			name: "markdown",
+			raw:  `{"kind":"markdown","value":"` + "```java\\nvoid com.example.Repository.save(java.lang.Object entity)\\n```" + `"}`,
+			want: []string{"void com.example.Repository.save(java.lang.Object entity)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hoverContents(json.RawMessage(tt.raw)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hoverContents() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,667 @@
+package java
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v3"
+)
+
+const springBeansNamespace = "http://www.springframework.org/schema/beans"
+
+// Sources of spring beans
+const (
+	springXMLSource           = "xml"
+	springConfigurationSource = "configuration"
+	springComponentSource     = "component"
+)
+
+// springStereotypes are the annotations of the classes spring registers as
+// beans when it scans for components
+var springStereotypes = map[string]bool{
+	"Component":      true,
+	"Service":        true,
+	"Repository":     true,
+	"Controller":     true,
+	"RestController": true,
+	"Configuration":  true,
+	// @SpringBootApplication is a @Configuration too
+	"SpringBootApplication": true,
+}
+
+// Keys of spring property documents activated for a profile
+var springProfileKeys = []string{"spring.config.activate.on-profile", "spring.profiles"}
+
+var (
+	springPropertiesFileRegex = regexp.MustCompile(`^(application|bootstrap)(-([\w.-]+))?\.(properties|ya?ml)$`)
+	springPlaceholderRegex    = regexp.MustCompile(`\$\{([^${}]+)\}`)
+	javaImportRegex           = regexp.MustCompile(`^\s*import\s+([\w.]+)\s*;`)
+	javaClassRegex            = regexp.MustCompile(`\b(?:class|interface|record)\s+(\w+)`)
+	javaAnnotationRegex       = regexp.MustCompile(`^\s*@(\w+)\s*(\((.*)\))?`)
+	javaMethodRegex           = regexp.MustCompile(`^\s*(?:(?:public|protected|private|static|final|synchronized)\s+)*([\w.$]+)(?:<.*>)?(?:\[\])*\s+(\w+)\s*\(`)
+	javaStringLiteralRegex    = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+	javaNamedArgumentRegex    = regexp.MustCompile(`\b(name|value)\s*=\s*\{?\s*"((?:[^"\\]|\\.)*)"`)
+)
+
+type springBeanCondition struct {
+	// ClassPattern is a regex pattern the fully qualified class of the beans
+	// must match
+	ClassPattern string `yaml:"classPattern,omitempty" json:"classPattern,omitempty"`
+	// NamePattern is a regex pattern the names of the beans must match
+	NamePattern string `yaml:"namePattern,omitempty" json:"namePattern,omitempty"`
+}
+
+type springPropertyCondition struct {
+	// KeyPattern is a regex pattern the keys of the properties must match
+	KeyPattern string `yaml:"keyPattern" json:"keyPattern"`
+	// ValuePattern is a regex pattern the values of the properties, with
+	// their placeholders resolved, must match
+	ValuePattern string `yaml:"valuePattern,omitempty" json:"valuePattern,omitempty"`
+	// Profile is a regex pattern the profile of the properties must match,
+	// the properties of no profile don't match it
+	Profile string `yaml:"profile,omitempty" json:"profile,omitempty"`
+}
+
+// springBean is a bean defined in a spring XML file, by a @Bean method of a
+// @Configuration class or by a component class
+type springBean struct {
+	name      string
+	className string
+	source    string
+	// configuration is the class of the @Bean method defining the bean
+	configuration string
+	file          string
+	line          int
+}
+
+// springProperty is a key of an application properties or YAML file
+type springProperty struct {
+	key     string
+	value   string
+	profile string
+	file    string
+	line    int
+}
+
+// springWorkspace are the files of the application spring reads beans and
+// properties from
+type springWorkspace struct {
+	xmlFiles        []string
+	javaFiles       []string
+	propertiesFiles []string
+}
+
+// evaluateSpringBean creates an incident for every spring bean whose class
+// and name match the condition, wherever the bean is defined.
+func (p *javaServiceClient) evaluateSpringBean(ctx context.Context, cond springBeanCondition) (provider.ProviderEvaluateResponse, error) {
+	regexes, err := compilePatterns(map[string]string{"classPattern": cond.ClassPattern, "namePattern": cond.NamePattern})
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	workspace, err := p.indexSpringWorkspace()
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	properties := resolvableProperties(p.springProperties(workspace), "")
+
+	beans := []springBean{}
+	for _, file := range workspace.xmlFiles {
+		xmlBeans, err := parseSpringXMLBeans(file)
+		if err != nil {
+			p.log.V(5).Error(err, "unable to parse spring xml file", "file", file)
+		}
+		beans = append(beans, xmlBeans...)
+	}
+	for _, file := range workspace.javaFiles {
+		javaBeans, err := parseSpringJavaBeans(file)
+		if err != nil {
+			p.log.V(5).Error(err, "unable to read java file", "file", file)
+		}
+		beans = append(beans, javaBeans...)
+	}
+
+	incidents := []provider.IncidentContext{}
+	for _, bean := range beans {
+		className := resolvePlaceholders(bean.className, properties)
+		if regex, ok := regexes["classPattern"]; ok && !regex.MatchString(className) {
+			continue
+		}
+		if regex, ok := regexes["namePattern"]; ok && !regex.MatchString(bean.name) {
+			continue
+		}
+		variables := map[string]interface{}{
+			"name":   bean.name,
+			"class":  className,
+			"source": bean.source,
+		}
+		if bean.configuration != "" {
+			variables["configuration"] = bean.configuration
+		}
+		incidents = append(incidents, lineIncident(bean.file, bean.line, variables))
+	}
+	if len(incidents) == 0 {
+		return provider.ProviderEvaluateResponse{Matched: false}, nil
+	}
+	return provider.ProviderEvaluateResponse{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
+
+// evaluateSpringProperty creates an incident for every key of the
+// application properties and YAML files matching the condition, the
+// placeholders of the values are resolved with the other keys.
+func (p *javaServiceClient) evaluateSpringProperty(ctx context.Context, cond springPropertyCondition) (provider.ProviderEvaluateResponse, error) {
+	if cond.KeyPattern == "" {
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("keyPattern must be given for spring properties")
+	}
+	regexes, err := compilePatterns(map[string]string{"keyPattern": cond.KeyPattern, "valuePattern": cond.ValuePattern, "profile": cond.Profile})
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	workspace, err := p.indexSpringWorkspace()
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	properties := p.springProperties(workspace)
+
+	incidents := []provider.IncidentContext{}
+	for _, property := range properties {
+		if !regexes["keyPattern"].MatchString(property.key) {
+			continue
+		}
+		if regex, ok := regexes["profile"]; ok && (property.profile == "" || !regex.MatchString(property.profile)) {
+			continue
+		}
+		value := resolvePlaceholders(property.value, resolvableProperties(properties, property.profile))
+		if regex, ok := regexes["valuePattern"]; ok && !regex.MatchString(value) {
+			continue
+		}
+		variables := map[string]interface{}{
+			"key":   property.key,
+			"value": value,
+		}
+		if value != property.value {
+			variables["rawValue"] = property.value
+		}
+		if property.profile != "" {
+			variables["profile"] = property.profile
+		}
+		incidents = append(incidents, lineIncident(property.file, property.line, variables))
+	}
+	if len(incidents) == 0 {
+		return provider.ProviderEvaluateResponse{Matched: false}, nil
+	}
+	return provider.ProviderEvaluateResponse{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
+
+// compilePatterns compiles the regex patterns of the fields of a condition
+// that are given
+func compilePatterns(patterns map[string]string) (map[string]*regexp.Regexp, error) {
+	regexes := map[string]*regexp.Regexp{}
+	for field, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compile %s '%s': %w", field, pattern, err)
+		}
+		regexes[field] = regex
+	}
+	return regexes, nil
+}
+
+// lineIncident returns the incident of a line of a file
+func lineIncident(file string, line int, variables map[string]interface{}) provider.IncidentContext {
+	return provider.IncidentContext{
+		FileURI:    uri.File(file),
+		LineNumber: &line,
+		Variables:  variables,
+		CodeLocation: &provider.Location{
+			StartPosition: provider.Position{Line: float64(line)},
+			EndPosition:   provider.Position{Line: float64(line)},
+		},
+	}
+}
+
+// indexSpringWorkspace walks the location once and returns the XML, java
+// and application properties files found.
+func (p *javaServiceClient) indexSpringWorkspace() (springWorkspace, error) {
+	workspace := springWorkspace{}
+	location, err := filepath.Abs(p.config.Location)
+	if err != nil {
+		return workspace, err
+	}
+	err = filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != location && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !p.isPathIncluded(path) {
+			return nil
+		}
+		switch {
+		case springPropertiesFileRegex.MatchString(d.Name()):
+			workspace.propertiesFiles = append(workspace.propertiesFiles, path)
+		case strings.HasSuffix(path, ".xml"):
+			workspace.xmlFiles = append(workspace.xmlFiles, path)
+		case strings.HasSuffix(path, JavaFile):
+			workspace.javaFiles = append(workspace.javaFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return workspace, fmt.Errorf("unable to index workspace for spring: %w", err)
+	}
+	return workspace, nil
+}
+
+// springProperties returns the keys of the application properties files in
+// the order they are declared, the keys before an error in a file are kept
+func (p *javaServiceClient) springProperties(workspace springWorkspace) []springProperty {
+	properties := []springProperty{}
+	for _, file := range workspace.propertiesFiles {
+		fileProperties, err := parseSpringProperties(file)
+		if err != nil {
+			p.log.V(5).Error(err, "unable to parse spring properties file", "file", file)
+		}
+		properties = append(properties, fileProperties...)
+	}
+	return properties
+}
+
+// resolvableProperties returns the values placeholders are resolved with
+// for a profile, the keys of the profile override the ones of no profile
+func resolvableProperties(properties []springProperty, profile string) map[string]string {
+	values := map[string]string{}
+	for _, property := range properties {
+		if property.profile == "" {
+			values[property.key] = property.value
+		}
+	}
+	if profile == "" {
+		return values
+	}
+	for _, property := range properties {
+		if property.profile == profile {
+			values[property.key] = property.value
+		}
+	}
+	return values
+}
+
+// resolvePlaceholders replaces the ${key} and ${key:default} placeholders of
+// a value with the values of the keys, resolved in turn. Placeholders of
+// unknown keys without a default are left as they are.
+func resolvePlaceholders(value string, properties map[string]string) string {
+	// placeholders resolving to themselves stop after a few rounds
+	for i := 0; i < 10 && strings.Contains(value, "${"); i++ {
+		resolved := springPlaceholderRegex.ReplaceAllStringFunc(value, func(placeholder string) string {
+			key, defaultValue, hasDefault := strings.Cut(placeholder[2:len(placeholder)-1], ":")
+			if v, ok := properties[strings.TrimSpace(key)]; ok {
+				return v
+			}
+			if hasDefault {
+				return defaultValue
+			}
+			return placeholder
+		})
+		if resolved == value {
+			break
+		}
+		value = resolved
+	}
+	return value
+}
+
+// parseSpringProperties parses an application properties or YAML file, the
+// profile of its keys is the one of its name, such as prod for
+// application-prod.yml, or the one its document is activated on
+func parseSpringProperties(file string) ([]springProperty, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var properties []springProperty
+	if strings.HasSuffix(file, ".properties") {
+		properties = parsePropertiesDocuments(string(content))
+	} else {
+		properties, err = parseYAMLDocuments(content)
+	}
+	fileProfile := ""
+	if match := springPropertiesFileRegex.FindStringSubmatch(filepath.Base(file)); match != nil {
+		fileProfile = match[3]
+	}
+	for i := range properties {
+		properties[i].file = file
+		if properties[i].profile == "" {
+			properties[i].profile = fileProfile
+		}
+	}
+	return properties, err
+}
+
+// setDocumentProfile sets the profile of the properties of a document to
+// the one it is activated on
+func setDocumentProfile(document []springProperty) {
+	for _, property := range document {
+		for _, key := range springProfileKeys {
+			if property.key != key {
+				continue
+			}
+			for i := range document {
+				document[i].profile = property.value
+			}
+			return
+		}
+	}
+}
+
+// parsePropertiesDocuments parses a java properties file, lines ending with
+// a backslash continue on the next one and #--- separates the documents of
+// the file
+func parsePropertiesDocuments(content string) []springProperty {
+	properties := []springProperty{}
+	documentStart := 0
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimLeft(strings.TrimRight(lines[i], "\r"), " \t\f")
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' || line[0] == '!' {
+			if strings.TrimSpace(line[1:]) == "---" {
+				setDocumentProfile(properties[documentStart:])
+				documentStart = len(properties)
+			}
+			continue
+		}
+		start := i
+		for strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(strings.TrimRight(lines[i], "\r"), " \t\f")
+		}
+		end := strings.IndexAny(line, "=: \t")
+		if end < 0 {
+			end = len(line)
+		}
+		value := strings.TrimLeft(line[end:], " \t\f")
+		if value != "" && (value[0] == '=' || value[0] == ':') {
+			value = strings.TrimLeft(value[1:], " \t\f")
+		}
+		properties = append(properties, springProperty{
+			key:   line[:end],
+			value: strings.TrimRight(value, " \t"),
+			line:  start + 1,
+		})
+	}
+	setDocumentProfile(properties[documentStart:])
+	return properties
+}
+
+// parseYAMLDocuments parses the documents of a YAML file, the keys of nested
+// mappings are dotted, as in spring.datasource.url, and the items of
+// sequences have their index, as in servers[0]
+func parseYAMLDocuments(content []byte) ([]springProperty, error) {
+	properties := []springProperty{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(content)))
+	for {
+		node := &yaml.Node{}
+		err := decoder.Decode(node)
+		if errors.Is(err, io.EOF) {
+			return properties, nil
+		}
+		if err != nil {
+			return properties, err
+		}
+		document := flattenYAML(node, "", node.Line, nil)
+		setDocumentProfile(document)
+		properties = append(properties, document...)
+	}
+}
+
+func flattenYAML(node *yaml.Node, key string, line int, properties []springProperty) []springProperty {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			properties = flattenYAML(child, key, child.Line, properties)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childKey := node.Content[i].Value
+			if key != "" {
+				childKey = key + "." + childKey
+			}
+			properties = flattenYAML(node.Content[i+1], childKey, node.Content[i].Line, properties)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			properties = flattenYAML(child, fmt.Sprintf("%s[%d]", key, i), child.Line, properties)
+		}
+	case yaml.ScalarNode:
+		if key != "" {
+			properties = append(properties, springProperty{key: key, value: node.Value, line: line})
+		}
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			properties = flattenYAML(node.Alias, key, line, properties)
+		}
+	}
+	return properties
+}
+
+// parseSpringXMLBeans returns the beans of a spring XML file, files of other
+// namespaces have none
+func parseSpringXMLBeans(path string) ([]springBean, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	decoder := xml.NewDecoder(f)
+	decoder.Strict = false
+	beans := []springBean{}
+	root := ""
+	generated := map[string]int{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return beans, nil
+		}
+		if err != nil {
+			return beans, err
+		}
+		t, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root = t.Name.Local
+		}
+		if t.Name.Local != "bean" || (t.Name.Space != springBeansNamespace && (t.Name.Space != "" || root != "beans")) {
+			continue
+		}
+		line, _ := decoder.InputPos()
+		bean := springBean{source: springXMLSource, file: path, line: line}
+		for _, attr := range t.Attr {
+			switch attr.Name.Local {
+			case "id":
+				bean.name = attr.Value
+			case "name":
+				// names are aliases separated by commas, semicolons or spaces
+				if aliases := strings.FieldsFunc(attr.Value, func(r rune) bool { return r == ',' || r == ';' || unicode.IsSpace(r) }); bean.name == "" && len(aliases) > 0 {
+					bean.name = aliases[0]
+				}
+			case "class":
+				bean.className = strings.TrimSpace(attr.Value)
+			}
+		}
+		// spring names the beans without a name after their class
+		if bean.name == "" && bean.className != "" {
+			bean.name = fmt.Sprintf("%s#%d", bean.className, generated[bean.className])
+			generated[bean.className]++
+		}
+		beans = append(beans, bean)
+	}
+}
+
+// parseSpringJavaBeans returns the beans of a java source file, the class
+// itself when it is a component and the beans of its @Bean methods when it
+// is a @Configuration class. The types are resolved with the imports and
+// the package of the file.
+func parseSpringJavaBeans(path string) ([]springBean, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	pkg := ""
+	imports := map[string]string{}
+	className := ""
+	configuration := false
+	// annotation is the last annotation and its line, a @Bean annotation
+	// waits for the method it annotates
+	annotation, annotationArgs, annotationLine := "", "", 0
+	beans := []springBean{}
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "*") {
+			continue
+		}
+		if match := javaPackageRegex.FindStringSubmatch(line); match != nil {
+			pkg = match[1]
+			continue
+		}
+		if match := javaImportRegex.FindStringSubmatch(line); match != nil {
+			imports[match[1][strings.LastIndex(match[1], ".")+1:]] = match[1]
+			continue
+		}
+		if match := javaAnnotationRegex.FindStringSubmatch(line); match != nil {
+			if className == "" && springStereotypes[match[1]] {
+				configuration = configuration || match[1] == "Configuration" || match[1] == "SpringBootApplication"
+				annotation, annotationArgs, annotationLine = match[1], match[3], lineNumber
+			} else if className != "" && match[1] == "Bean" {
+				annotation, annotationArgs, annotationLine = match[1], match[3], lineNumber
+			}
+			// annotations can be followed by the declaration on the same line
+			line = line[len(match[0]):]
+		}
+		if className == "" {
+			match := javaClassRegex.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			className = match[1]
+			if springStereotypes[annotation] {
+				name := annotationName(annotationArgs)
+				if name == "" {
+					name = decapitalize(className)
+				}
+				beans = append(beans, springBean{
+					name:      name,
+					className: qualifiedName(pkg, className),
+					source:    springComponentSource,
+					file:      path,
+					line:      annotationLine,
+				})
+			}
+			annotation = ""
+			continue
+		}
+		if annotation != "Bean" || !configuration {
+			continue
+		}
+		match := javaMethodRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		name := annotationName(annotationArgs)
+		if name == "" {
+			name = match[2]
+		}
+		beans = append(beans, springBean{
+			name:          name,
+			className:     resolveJavaType(match[1], pkg, imports),
+			source:        springConfigurationSource,
+			configuration: qualifiedName(pkg, className),
+			file:          path,
+			line:          annotationLine,
+		})
+		annotation = ""
+	}
+	return beans, scanner.Err()
+}
+
+// annotationName returns the name of a bean given in the arguments of its
+// annotation, as in @Bean("name"), @Bean(name = {"name", "alias"}) or
+// @Service(value = "name"), the other arguments such as initMethod are not
+// names
+func annotationName(args string) string {
+	if match := javaNamedArgumentRegex.FindStringSubmatch(args); match != nil {
+		return match[2]
+	}
+	if strings.Contains(args, "=") {
+		return ""
+	}
+	if match := javaStringLiteralRegex.FindStringSubmatch(args); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// resolveJavaType returns the fully qualified name of a type used in a java
+// file, from its imports or its package
+func resolveJavaType(name, pkg string, imports map[string]string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+	if qualified, ok := imports[name]; ok {
+		return qualified
+	}
+	if unicode.IsLower(rune(name[0])) {
+		// primitive types
+		return name
+	}
+	return qualifiedName(pkg, name)
+}
+
+func qualifiedName(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// decapitalize returns the name spring gives to the bean of a class, the
+// class name with its first letter lowercase unless its first two letters
+// are uppercase, like java.beans.Introspector.decapitalize
+func decapitalize(name string) string {
+	runes := []rune(name)
+	if len(runes) == 0 || (len(runes) > 1 && unicode.IsUpper(runes[0]) && unicode.IsUpper(runes[1])) {
+		return name
+	}
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
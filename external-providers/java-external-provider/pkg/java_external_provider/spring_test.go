@@ -0,0 +1,173 @@
+package java
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// lineIncidentWant is an incident of a line of a file, its file name,
+// line and variables
+type lineIncidentWant struct {
+	file      string
+	line      int
+	variables map[string]interface{}
+}
+
+func lineIncidents(response provider.ProviderEvaluateResponse) []lineIncidentWant {
+	got := []lineIncidentWant{}
+	for _, incident := range response.Incidents {
+		got = append(got, lineIncidentWant{
+			file:      filepath.Base(incident.FileURI.Filename()),
+			line:      *incident.LineNumber,
+			variables: incident.Variables,
+		})
+	}
+	return got
+}
+
+func Test_evaluateSpringBean(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition springBeanCondition
+		want      []lineIncidentWant
+		wantErr   bool
+	}{
+		{
+			name:      "xml and configuration beans of a class",
+			condition: springBeanCondition{ClassPattern: `DataSource$`},
+			want: []lineIncidentWant{
+				{file: "applicationContext.xml", line: 5, variables: map[string]interface{}{"name": "dataSource", "class": "org.apache.commons.dbcp.BasicDataSource", "source": "xml"}},
+				{file: "AppConfig.java", line: 17, variables: map[string]interface{}{"name": "dataSource", "class": "javax.sql.DataSource", "source": "configuration", "configuration": "com.example.config.AppConfig"}},
+			},
+		},
+		{
+			name:      "class placeholder resolved with the properties",
+			condition: springBeanCondition{ClassPattern: `JdbcOrderDao`},
+			want: []lineIncidentWant{
+				{file: "applicationContext.xml", line: 8, variables: map[string]interface{}{"name": "orderDao", "class": "com.example.JdbcOrderDao", "source": "xml"}},
+			},
+		},
+		{
+			name:      "names given to the beans",
+			condition: springBeanCondition{NamePattern: `^(orders|clock|URLParser|orderService|com\.example\.AuditListener#0)$`},
+			want: []lineIncidentWant{
+				{file: "applicationContext.xml", line: 9, variables: map[string]interface{}{"name": "com.example.AuditListener#0", "class": "com.example.AuditListener", "source": "xml"}},
+				{file: "OrderService.java", line: 5, variables: map[string]interface{}{"name": "orderService", "class": "com.example.OrderService", "source": "component"}},
+				{file: "URLParser.java", line: 5, variables: map[string]interface{}{"name": "URLParser", "class": "com.example.URLParser", "source": "component"}},
+				{file: "AppConfig.java", line: 22, variables: map[string]interface{}{"name": "orders", "class": "com.example.OrderService", "source": "configuration", "configuration": "com.example.config.AppConfig"}},
+				{file: "AppConfig.java", line: 28, variables: map[string]interface{}{"name": "clock", "class": "com.example.config.Clock", "source": "configuration", "configuration": "com.example.config.AppConfig"}},
+			},
+		},
+		{
+			name:      "beans of other xml files are ignored",
+			condition: springBeanCondition{ClassPattern: `logback`},
+		},
+		{
+			name:      "invalid pattern",
+			condition: springBeanCondition{NamePattern: "("},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &javaServiceClient{
+				config: provider.InitConfig{Location: "testdata/spring"},
+				log:    testr.New(t),
+			}
+			got, err := p.evaluateSpringBean(context.TODO(), tt.condition)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateSpringBean() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Matched != (len(tt.want) > 0) {
+				t.Fatalf("evaluateSpringBean() matched = %v, want %v", got.Matched, len(tt.want) > 0)
+			}
+			if incidents := lineIncidents(got); len(tt.want) > 0 && !reflect.DeepEqual(incidents, tt.want) {
+				t.Errorf("evaluateSpringBean() incidents = %+v, want %+v", incidents, tt.want)
+			}
+		})
+	}
+}
+
+func Test_evaluateSpringProperty(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition springPropertyCondition
+		want      []lineIncidentWant
+		wantErr   bool
+	}{
+		{
+			name:      "placeholders resolved for the profile of the key",
+			condition: springPropertyCondition{KeyPattern: `^(db|spring\.datasource)\.url$`},
+			want: []lineIncidentWant{
+				{file: "application-prod.yml", line: 5, variables: map[string]interface{}{"key": "spring.datasource.url", "value": "jdbc:postgresql://prod-db:5432/orders", "rawValue": "${db.url}", "profile": "prod"}},
+				{file: "application.properties", line: 4, variables: map[string]interface{}{"key": "db.url", "value": "jdbc:postgresql://localhost:5432/orders", "rawValue": "jdbc:postgresql://${db.host}:${db.port:5432}/orders"}},
+			},
+		},
+		{
+			name:      "profile of a properties document",
+			condition: springPropertyCondition{KeyPattern: `^db\.host$`, Profile: `^dev$`},
+			want: []lineIncidentWant{
+				{file: "application.properties", line: 8, variables: map[string]interface{}{"key": "db.host", "value": "dev-db", "profile": "dev"}},
+			},
+		},
+		{
+			name:      "value of a sequence item",
+			condition: springPropertyCondition{KeyPattern: `^management\.endpoints\.web\.exposure\.include`, ValuePattern: `^info$`},
+			want: []lineIncidentWant{
+				{file: "application-prod.yml", line: 14, variables: map[string]interface{}{"key": "management.endpoints.web.exposure.include[1]", "value": "info", "profile": "prod"}},
+			},
+		},
+		{
+			name:      "default of a placeholder",
+			condition: springPropertyCondition{KeyPattern: `^server\.port$`, ValuePattern: `^8080$`},
+			want: []lineIncidentWant{
+				{file: "application.properties", line: 5, variables: map[string]interface{}{"key": "server.port", "value": "8080", "rawValue": "${PORT:8080}"}},
+			},
+		},
+		{
+			name:      "no key pattern",
+			condition: springPropertyCondition{ValuePattern: "orders"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &javaServiceClient{
+				config: provider.InitConfig{Location: "testdata/spring"},
+				log:    testr.New(t),
+			}
+			got, err := p.evaluateSpringProperty(context.TODO(), tt.condition)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateSpringProperty() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if incidents := lineIncidents(got); !reflect.DeepEqual(incidents, tt.want) {
+				t.Errorf("evaluateSpringProperty() incidents = %+v, want %+v", incidents, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resolvePlaceholders(t *testing.T) {
+	properties := map[string]string{"a": "${b}", "b": "value", "loop": "${loop}"}
+	for value, want := range map[string]string{
+		"${a}-${c:default}": "value-default",
+		"${unknown}":        "${unknown}",
+		"${loop}":           "${loop}",
+		"plain":             "plain",
+	} {
+		if got := resolvePlaceholders(value, properties); got != want {
+			t.Errorf("resolvePlaceholders(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
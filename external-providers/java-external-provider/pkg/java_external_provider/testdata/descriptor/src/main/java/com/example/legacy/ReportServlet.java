@@ -0,0 +1,4 @@
+package com.example.legacy;
+
+public class ReportServlet {
+}
@@ -0,0 +1,14 @@
+plugins {
+    java
+    alias(libs.plugins.spring.boot)
+}
+
+dependencies {
+    implementation(platform("org.springframework.boot:spring-boot-dependencies:3.1.5"))
+    implementation(libs.guava)
+    implementation(libs.bundles.jackson)
+    implementation(group = "org.apache.commons", name = "commons-lang3", version = "3.13.0")
+    compileOnly("jakarta.servlet:jakarta.servlet-api:6.0.0") // provided by the container
+    runtimeOnly(project(":services:api"))
+    testImplementation(libs.junit.jupiter)
+}
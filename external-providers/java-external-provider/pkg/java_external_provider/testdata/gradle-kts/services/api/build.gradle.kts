@@ -0,0 +1,4 @@
+dependencies {
+    api("org.slf4j:slf4j-api:2.0.9")
+    implementation(libs.guava)
+}
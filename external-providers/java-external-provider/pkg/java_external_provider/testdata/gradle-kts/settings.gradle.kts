@@ -0,0 +1,3 @@
+rootProject.name = "kotlin-dsl-example"
+
+include(":services:api")
@@ -0,0 +1,10 @@
+package com.example;
+
+import org.springframework.stereotype.Service;
+
+@Service
+public class OrderService {
+
+    public void init() {
+    }
+}
@@ -0,0 +1,7 @@
+package com.example;
+
+import org.springframework.stereotype.Component;
+
+@Component
+public class URLParser {
+}
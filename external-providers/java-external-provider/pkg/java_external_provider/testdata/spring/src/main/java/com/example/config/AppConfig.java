@@ -0,0 +1,32 @@
+package com.example.config;
+
+import javax.sql.DataSource;
+
+import org.springframework.context.annotation.Bean;
+import org.springframework.context.annotation.Configuration;
+import org.springframework.context.annotation.Scope;
+
+import com.example.OrderService;
+
+/**
+ * The beans of the application, this class is not a component
+ */
+@Configuration
+public class AppConfig {
+
+    @Bean
+    public DataSource dataSource() {
+        return null;
+    }
+
+    @Bean(name = "orders", initMethod = "init")
+    @Scope("prototype")
+    public OrderService orderService() {
+        return new OrderService();
+    }
+
+    @Bean("clock")
+    Clock clock() {
+        return new Clock();
+    }
+}
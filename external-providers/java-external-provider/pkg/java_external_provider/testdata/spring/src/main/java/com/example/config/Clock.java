@@ -0,0 +1,4 @@
+package com.example.config;
+
+public class Clock {
+}
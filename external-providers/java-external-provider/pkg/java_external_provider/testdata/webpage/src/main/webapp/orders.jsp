@@ -0,0 +1,15 @@
+<%@ page contentType="text/html;charset=UTF-8" %>
+<%@ taglib prefix="c" uri="http://java.sun.com/jsp/jstl/core" %>
+<%@ taglib prefix="s" uri="/struts-tags" %>
+<%-- <% legacyCall(); %> is commented out --%>
+<%! private int count = 0; %>
+<html>
+<body>
+<% java.util.List orders = (java.util.List) request.getAttribute("orders"); %>
+<c:forEach items="${orders}" var="order">
+    <s:property value="order.id"/> <%= order.getTotal() %>
+</c:forEach>
+<jsp:include page="footer.jspf"/>
+\${not.an.expression}
+</body>
+</html>
@@ -4,8 +4,6 @@ import (
 	"archive/zip"
 	"bufio"
 	"context"
-	"crypto/sha1"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -79,12 +77,13 @@ type decompileJob struct {
 	outputPath string
 	artifact   javaArtifact
 	m2RepoPath string
+	mavenIndex *mavenIndex
 }
 
 // decompile decompiles files submitted via a list of decompileJob concurrently
 // if a .class file is encountered, it will be decompiled to output path right away
 // if a .jar file is encountered, it will be decompiled as a whole, then exploded to project path
-func decompile(ctx context.Context, log logr.Logger, filter decompileFilter, workerCount int, jobs []decompileJob, fernflower, projectPath string) error {
+func decompile(ctx context.Context, log logr.Logger, filter decompileFilter, workerCount int, jobs []decompileJob, fernflower, projectPath string, cache *decompileCache) error {
 	wg := &sync.WaitGroup{}
 	jobChan := make(chan decompileJob)
 
@@ -115,21 +114,28 @@ func decompile(ctx context.Context, log logr.Logger, filter decompileFilter, wor
 						"failed to create directories for decompiled file", "path", outputPathDir)
 					continue
 				}
-				// multiple java versions may be installed - chose $JAVA_HOME one
-				java := filepath.Join(os.Getenv("JAVA_HOME"), "bin", "java")
-				// -mpm (max processing method) is required to keep decomp time low
-				cmd := exec.CommandContext(
-					jobCtx, java, "-jar", fernflower, "-mpm=30", job.inputPath, outputPathDir)
-				err := cmd.Run()
-				if err != nil {
-					log.V(5).Error(err, "failed to decompile file", "file", job.inputPath, job.outputPath)
+				if entry, ok := cache.restore(job.inputPath, job.outputPath); ok {
+					log.V(5).Info("restored decompiled file from cache", "source", job.inputPath, "dest", job.outputPath)
 				} else {
-					log.V(5).Info("decompiled file", "source", job.inputPath, "dest", job.outputPath)
+					// multiple java versions may be installed - chose $JAVA_HOME one
+					java := filepath.Join(os.Getenv("JAVA_HOME"), "bin", "java")
+					// -mpm (max processing method) is required to keep decomp time low
+					cmd := exec.CommandContext(
+						jobCtx, java, "-jar", fernflower, "-mpm=30", job.inputPath, outputPathDir)
+					err := cmd.Run()
+					if err != nil {
+						log.V(5).Error(err, "failed to decompile file", "file", job.inputPath, job.outputPath)
+					} else {
+						log.V(5).Info("decompiled file", "source", job.inputPath, "dest", job.outputPath)
+						if err := cache.store(entry, job.outputPath); err != nil {
+							log.V(5).Error(err, "failed to cache decompiled file", "file", job.outputPath)
+						}
+					}
 				}
 				// if we just decompiled a java archive, we need to
 				// explode it further and copy files to project
 				if job.artifact.packaging == JavaArchive && projectPath != "" {
-					_, _, _, err = explode(jobCtx, log, job.outputPath, projectPath, job.m2RepoPath)
+					_, _, _, err := explode(jobCtx, log, job.outputPath, projectPath, job.m2RepoPath, job.mavenIndex)
 					if err != nil {
 						log.V(5).Error(err, "failed to explode decompiled jar", "path", job.inputPath)
 					}
@@ -159,7 +165,7 @@ func decompile(ctx context.Context, log logr.Logger, filter decompileFilter, wor
 // decompileJava unpacks archive at archivePath, decompiles all .class files in it
 // creates new java project and puts the java files in the tree of the project
 // returns path to exploded archive, path to java project, and an error when encountered
-func decompileJava(ctx context.Context, log logr.Logger, fernflower, archivePath string, m2RepoPath string) (explodedPath, projectPath string, err error) {
+func decompileJava(ctx context.Context, log logr.Logger, fernflower, archivePath string, m2RepoPath string, index *mavenIndex, cache *decompileCache) (explodedPath, projectPath string, err error) {
 	ctx, span := tracing.StartNewSpan(ctx, "decompile")
 	defer span.End()
 
@@ -167,7 +173,7 @@ func decompileJava(ctx context.Context, log logr.Logger, fernflower, archivePath
 
 	decompFilter := alwaysDecompileFilter(true)
 
-	explodedPath, decompJobs, deps, err := explode(ctx, log, archivePath, projectPath, m2RepoPath)
+	explodedPath, decompJobs, deps, err := explode(ctx, log, archivePath, projectPath, m2RepoPath, index)
 	if err != nil {
 		log.Error(err, "failed to decompile archive", "path", archivePath)
 		return "", "", err
@@ -180,7 +186,7 @@ func decompileJava(ctx context.Context, log logr.Logger, fernflower, archivePath
 	}
 	log.V(5).Info("created java project", "path", projectPath)
 
-	err = decompile(ctx, log, decompFilter, 10, decompJobs, fernflower, projectPath)
+	err = decompile(ctx, log, decompFilter, 10, decompJobs, fernflower, projectPath, cache)
 	if err != nil {
 		log.Error(err, "failed to decompile", "path", archivePath)
 		return "", "", err
@@ -205,8 +211,8 @@ func deduplicateJavaArtifacts(artifacts []javaArtifact) []javaArtifact {
 
 // explode explodes the given JAR, WAR or EAR archive, generates javaArtifact struct for given archive
 // and identifies all .class found recursively. returns output path, a list of decompileJob for .class files
-// it also returns a list of any javaArtifact we could interpret from jars
-func explode(ctx context.Context, log logr.Logger, archivePath, projectPath string, m2Repo string) (string, []decompileJob, []javaArtifact, error) {
+// it also returns a list of any javaArtifact we could interpret from jars, jars are looked up in the index first
+func explode(ctx context.Context, log logr.Logger, archivePath, projectPath string, m2Repo string, index *mavenIndex) (string, []decompileJob, []javaArtifact, error) {
 	var dependencies []javaArtifact
 	fileInfo, err := os.Stat(archivePath)
 	if err != nil {
@@ -338,7 +344,7 @@ func explode(ctx context.Context, log logr.Logger, archivePath, projectPath stri
 		// decompile web archives
 		case strings.HasSuffix(f.Name, WebArchive):
 			// TODO(djzager): Should we add these deps to the pom?
-			_, nestedJobs, deps, err := explode(ctx, log, filePath, projectPath, m2Repo)
+			_, nestedJobs, deps, err := explode(ctx, log, filePath, projectPath, m2Repo, index)
 			if err != nil {
 				log.Error(err, "failed to decompile file", "file", filePath)
 			}
@@ -346,7 +352,7 @@ func explode(ctx context.Context, log logr.Logger, archivePath, projectPath stri
 			dependencies = append(dependencies, deps...)
 		// attempt to add nested jars as dependency before decompiling
 		case strings.HasSuffix(f.Name, JavaArchive):
-			dep, err := toDependency(ctx, filePath)
+			dep, err := toDependency(ctx, filePath, index)
 			if err != nil {
 				log.V(3).Error(err, "failed to add dep", "file", filePath)
 				// when we fail to identify a dep we will fallback to
@@ -363,6 +369,7 @@ func explode(ctx context.Context, log logr.Logger, archivePath, projectPath stri
 							GroupId:    dep.GroupId,
 							ArtifactId: dep.ArtifactId,
 						},
+						mavenIndex: index,
 					})
 				}
 			}
@@ -392,6 +399,7 @@ func explode(ctx context.Context, log logr.Logger, archivePath, projectPath stri
 							GroupId:    dep.GroupId,
 							ArtifactId: dep.ArtifactId,
 						},
+						mavenIndex: index,
 					})
 				}
 			}
@@ -493,10 +501,24 @@ func AppendToFile(src string, dst string) error {
 	return nil
 }
 
+// appendLine appends a line to the end of a file
+func appendLine(path string, line string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening destination file: %s", err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "\n%s\n", line)
+	if err != nil {
+		return fmt.Errorf("error apending to destination file: %s", err)
+	}
+	return nil
+}
+
 // toDependency returns javaArtifact constructed for a jar
-func toDependency(_ context.Context, jarFile string) (javaArtifact, error) {
-	// attempt to lookup java artifact in maven
-	dep, err := constructArtifactFromSHA(jarFile)
+func toDependency(_ context.Context, jarFile string, index *mavenIndex) (javaArtifact, error) {
+	// attempt to lookup java artifact in the index or in maven
+	dep, err := constructArtifactFromSHA(jarFile, index)
 	if err == nil {
 		return dep, nil
 	}
@@ -549,22 +571,22 @@ func constructArtifactFromPom(jarFile string) (javaArtifact, error) {
 	return dep, fmt.Errorf("failed to construct artifact from pom properties")
 }
 
-func constructArtifactFromSHA(jarFile string) (javaArtifact, error) {
+func constructArtifactFromSHA(jarFile string, index *mavenIndex) (javaArtifact, error) {
 	dep := javaArtifact{}
 	// we look up the jar in maven
-	file, err := os.Open(jarFile)
+	sha1sum, err := fileSHA1(jarFile)
 	if err != nil {
 		return dep, err
 	}
-	defer file.Close()
 
-	hash := sha1.New()
-	_, err = io.Copy(hash, file)
-	if err != nil {
-		return dep, err
+	// jars in the index are known maven artifacts, same as the ones found online
+	if artifact, ok := index.lookup(sha1sum); ok {
+		artifact.foundOnline = true
+		return artifact, nil
+	}
+	if index.isOffline() {
+		return dep, fmt.Errorf("jar %s not found in the maven index, skipping maven lookup in offline mode", filepath.Base(jarFile))
 	}
-
-	sha1sum := hex.EncodeToString(hash.Sum(nil))
 
 	// Make an HTTPS request to search.maven.org
 	searchURL := fmt.Sprintf("https://search.maven.org/solrsearch/select?q=1:%s&rows=20&wt=json", sha1sum)
@@ -0,0 +1,322 @@
+package java
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// Kinds of the elements of web pages a webPage condition searches
+const (
+	webPageTaglib    = "taglib"
+	webPageTag       = "tag"
+	webPageEL        = "el"
+	webPageScriptlet = "scriptlet"
+)
+
+var webPageKinds = []string{webPageTaglib, webPageTag, webPageEL, webPageScriptlet}
+
+// webPageExtensions are the extensions of the JSP, JSF and facelets pages,
+// fragments and tag files
+var webPageExtensions = map[string]bool{
+	".jsp":   true,
+	".jspx":  true,
+	".jspf":  true,
+	".tag":   true,
+	".tagx":  true,
+	".xhtml": true,
+}
+
+// jspPageURI is the URI of the jsp: standard actions, they are declared in
+// no page
+const jspPageURI = "http://java.sun.com/JSP/Page"
+
+var (
+	jspCommentRegex        = regexp.MustCompile(`(?s)<%--.*?--%>`)
+	jspTaglibRegex         = regexp.MustCompile(`(?s)<%@\s*taglib\b(.*?)%>`)
+	jspAttributeRegex      = regexp.MustCompile(`(\w+)\s*=\s*["']([^"']*)["']`)
+	xmlNamespaceRegex      = regexp.MustCompile(`\bxmlns:([\w.-]+)\s*=\s*["']([^"']*)["']`)
+	prefixedTagRegex       = regexp.MustCompile(`<([\w.-]+):([\w.-]+)`)
+	jspScriptletRegex      = regexp.MustCompile(`(?s)<%([!=]?)(.*?)%>`)
+	jspxScriptletRegex     = regexp.MustCompile(`(?s)<jsp:(scriptlet|expression|declaration)\s*>(.*?)</jsp:(?:scriptlet|expression|declaration)\s*>`)
+	elExpressionStartRegex = regexp.MustCompile(`[$#]\{`)
+)
+
+type webPageCondition struct {
+	// Kind is what is searched in the pages, one of taglib, tag, el or
+	// scriptlet
+	Kind string `yaml:"kind" json:"kind"`
+	// Pattern is a regex pattern the URI of the tag libraries, the tags with
+	// their prefix, the EL expressions or the code of the scriptlets must
+	// match
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	// Library is a regex pattern the URI of the tag library of the tags must
+	// match
+	Library string `yaml:"library,omitempty" json:"library,omitempty"`
+}
+
+// webPageElement is a tag library declaration, a tag, an EL expression or a
+// scriptlet of a page
+type webPageElement struct {
+	// offset is the offset of the element in the page
+	offset int
+	// text is what the pattern is matched against
+	text      string
+	variables map[string]interface{}
+}
+
+// evaluateWebPage creates an incident for every element of the kind of the
+// condition found in the JSP, JSF and facelets pages of the application.
+func (p *javaServiceClient) evaluateWebPage(ctx context.Context, cond webPageCondition) (provider.ProviderEvaluateResponse, error) {
+	if !slices.Contains(webPageKinds, cond.Kind) {
+		return provider.ProviderEvaluateResponse{}, fmt.Errorf("kind of web page condition must be one of %v, not '%s'", webPageKinds, cond.Kind)
+	}
+	regexes, err := compilePatterns(map[string]string{"pattern": cond.Pattern, "library": cond.Library})
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+	pages, err := p.indexWebPages()
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, err
+	}
+
+	incidents := []provider.IncidentContext{}
+	for _, page := range pages {
+		content, err := os.ReadFile(page)
+		if err != nil {
+			p.log.V(5).Error(err, "unable to read web page", "file", page)
+			continue
+		}
+		elements := parseWebPage(string(content), cond.Kind)
+		lines := newLineIndex(string(content))
+		for _, element := range elements {
+			if regex, ok := regexes["pattern"]; ok && !regex.MatchString(element.text) {
+				continue
+			}
+			if regex, ok := regexes["library"]; ok {
+				uri, _ := element.variables["uri"].(string)
+				if !regex.MatchString(uri) {
+					continue
+				}
+			}
+			element.variables["kind"] = cond.Kind
+			incident := lineIncident(page, lines.line(element.offset), element.variables)
+			incident.MatchedText = element.text
+			incidents = append(incidents, incident)
+		}
+	}
+	if len(incidents) == 0 {
+		return provider.ProviderEvaluateResponse{Matched: false}, nil
+	}
+	return provider.ProviderEvaluateResponse{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
+
+// indexWebPages walks the location and returns the web pages found
+func (p *javaServiceClient) indexWebPages() ([]string, error) {
+	location, err := filepath.Abs(p.config.Location)
+	if err != nil {
+		return nil, err
+	}
+	pages := []string{}
+	err = filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != location && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if webPageExtensions[strings.ToLower(filepath.Ext(path))] && p.isPathIncluded(path) {
+			pages = append(pages, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to index workspace for web pages: %w", err)
+	}
+	return pages, nil
+}
+
+// parseWebPage returns the elements of a kind of a page in the order they
+// appear, the JSP comments are left out
+func parseWebPage(content string, kind string) []webPageElement {
+	// comments are blanked out rather than removed to keep the offsets
+	content = jspCommentRegex.ReplaceAllStringFunc(content, func(comment string) string {
+		return strings.Map(func(r rune) rune {
+			if r == '\n' {
+				return r
+			}
+			return ' '
+		}, comment)
+	})
+	libraries := webPageTaglibs(content)
+	elements := []webPageElement{}
+	switch kind {
+	case webPageTaglib:
+		elements = libraries
+	case webPageTag:
+		uris := map[string]string{"jsp": jspPageURI}
+		for _, library := range libraries {
+			uris[library.variables["prefix"].(string)] = library.text
+		}
+		for _, match := range prefixedTagRegex.FindAllStringSubmatchIndex(content, -1) {
+			prefix, name := content[match[2]:match[3]], content[match[4]:match[5]]
+			variables := map[string]interface{}{
+				"tag":    prefix + ":" + name,
+				"prefix": prefix,
+				"name":   name,
+			}
+			if uri, ok := uris[prefix]; ok {
+				variables["uri"] = uri
+			}
+			elements = append(elements, webPageElement{offset: match[0], text: prefix + ":" + name, variables: variables})
+		}
+	case webPageEL:
+		for _, match := range elExpressionStartRegex.FindAllStringIndex(content, -1) {
+			// escaped expressions are text
+			if match[0] > 0 && content[match[0]-1] == '\\' {
+				continue
+			}
+			end := elExpressionEnd(content, match[1])
+			if end < 0 {
+				continue
+			}
+			expression := strings.TrimSpace(content[match[1]:end])
+			elements = append(elements, webPageElement{
+				offset: match[0],
+				text:   expression,
+				variables: map[string]interface{}{
+					"expression": expression,
+					"deferred":   content[match[0]] == '#',
+				},
+			})
+		}
+	case webPageScriptlet:
+		scriptletTypes := map[string]string{"": "scriptlet", "=": "expression", "!": "declaration"}
+		for _, match := range jspScriptletRegex.FindAllStringSubmatchIndex(content, -1) {
+			code := content[match[4]:match[5]]
+			// directives are not scriptlets
+			if strings.HasPrefix(code, "@") {
+				continue
+			}
+			elements = append(elements, scriptletElement(match[0], scriptletTypes[content[match[2]:match[3]]], code))
+		}
+		for _, match := range jspxScriptletRegex.FindAllStringSubmatchIndex(content, -1) {
+			elements = append(elements, scriptletElement(match[0], content[match[2]:match[3]], content[match[4]:match[5]]))
+		}
+	}
+	sort.SliceStable(elements, func(i, j int) bool { return elements[i].offset < elements[j].offset })
+	return elements
+}
+
+func scriptletElement(offset int, scriptletType, code string) webPageElement {
+	code = strings.TrimSpace(code)
+	return webPageElement{
+		offset: offset,
+		text:   code,
+		variables: map[string]interface{}{
+			"code":          code,
+			"scriptletType": scriptletType,
+		},
+	}
+}
+
+// webPageTaglibs returns the tag libraries declared by the taglib directives
+// of a JSP and the namespaces of a JSP document or facelet, the text of the
+// elements is the URI of the libraries
+func webPageTaglibs(content string) []webPageElement {
+	libraries := []webPageElement{}
+	for _, match := range jspTaglibRegex.FindAllStringSubmatchIndex(content, -1) {
+		attributes := map[string]string{}
+		for _, attr := range jspAttributeRegex.FindAllStringSubmatch(content[match[2]:match[3]], -1) {
+			attributes[attr[1]] = attr[2]
+		}
+		uri := attributes["uri"]
+		if uri == "" {
+			uri = attributes["tagdir"]
+		}
+		libraries = append(libraries, webPageElement{
+			offset:    match[0],
+			text:      uri,
+			variables: map[string]interface{}{"prefix": attributes["prefix"], "uri": uri},
+		})
+	}
+	for _, match := range xmlNamespaceRegex.FindAllStringSubmatchIndex(content, -1) {
+		uri := content[match[4]:match[5]]
+		libraries = append(libraries, webPageElement{
+			offset:    match[0],
+			text:      uri,
+			variables: map[string]interface{}{"prefix": content[match[2]:match[3]], "uri": uri},
+		})
+	}
+	return libraries
+}
+
+// elExpressionEnd returns the offset of the brace closing the EL expression
+// starting at start, -1 when it is not closed. The braces of nested
+// expressions and of strings are skipped.
+func elExpressionEnd(content string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// lineIndex finds the line of offsets of a content
+type lineIndex []int
+
+func newLineIndex(content string) lineIndex {
+	starts := lineIndex{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// line returns the line of an offset, starting at 1
+func (l lineIndex) line(offset int) int {
+	low, high := 0, len(l)
+	for high-low > 1 {
+		mid := (low + high) / 2
+		if l[mid] <= offset {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low + 1
+}
@@ -0,0 +1,82 @@
+package java
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func Test_evaluateWebPage(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition webPageCondition
+		want      []lineIncidentWant
+		wantErr   bool
+	}{
+		{
+			name:      "tag libraries of jsp and facelets",
+			condition: webPageCondition{Kind: "taglib", Pattern: `java\.sun\.com/(jsp/jstl|jsf)/`},
+			want: []lineIncidentWant{
+				{file: "index.xhtml", line: 3, variables: map[string]interface{}{"kind": "taglib", "prefix": "h", "uri": "http://java.sun.com/jsf/html"}},
+				{file: "index.xhtml", line: 4, variables: map[string]interface{}{"kind": "taglib", "prefix": "f", "uri": "http://java.sun.com/jsf/core"}},
+				{file: "orders.jsp", line: 2, variables: map[string]interface{}{"kind": "taglib", "prefix": "c", "uri": "http://java.sun.com/jsp/jstl/core"}},
+			},
+		},
+		{
+			name:      "tags of a library",
+			condition: webPageCondition{Kind: "tag", Library: `^/struts-tags$|^http://java\.sun\.com/JSP/Page$`},
+			want: []lineIncidentWant{
+				{file: "price.tagx", line: 1, variables: map[string]interface{}{"kind": "tag", "tag": "jsp:root", "prefix": "jsp", "name": "root", "uri": "http://java.sun.com/JSP/Page"}},
+				{file: "price.tagx", line: 2, variables: map[string]interface{}{"kind": "tag", "tag": "jsp:scriptlet", "prefix": "jsp", "name": "scriptlet", "uri": "http://java.sun.com/JSP/Page"}},
+				{file: "orders.jsp", line: 10, variables: map[string]interface{}{"kind": "tag", "tag": "s:property", "prefix": "s", "name": "property", "uri": "/struts-tags"}},
+				{file: "orders.jsp", line: 12, variables: map[string]interface{}{"kind": "tag", "tag": "jsp:include", "prefix": "jsp", "name": "include", "uri": "http://java.sun.com/JSP/Page"}},
+			},
+		},
+		{
+			name:      "el expressions",
+			condition: webPageCondition{Kind: "el"},
+			want: []lineIncidentWant{
+				{file: "index.xhtml", line: 7, variables: map[string]interface{}{"kind": "el", "expression": "msgs['order.submit']", "deferred": true}},
+				{file: "index.xhtml", line: 7, variables: map[string]interface{}{"kind": "el", "expression": "orderBean.submit", "deferred": true}},
+				{file: "index.xhtml", line: 8, variables: map[string]interface{}{"kind": "el", "expression": "orderBean.items.stream().filter(i -> i.active).toList()", "deferred": true}},
+				{file: "orders.jsp", line: 9, variables: map[string]interface{}{"kind": "el", "expression": "orders", "deferred": false}},
+			},
+		},
+		{
+			name:      "scriptlets but not the commented ones",
+			condition: webPageCondition{Kind: "scriptlet"},
+			want: []lineIncidentWant{
+				{file: "price.tagx", line: 2, variables: map[string]interface{}{"kind": "scriptlet", "code": "out.print(price);", "scriptletType": "scriptlet"}},
+				{file: "orders.jsp", line: 5, variables: map[string]interface{}{"kind": "scriptlet", "code": "private int count = 0;", "scriptletType": "declaration"}},
+				{file: "orders.jsp", line: 8, variables: map[string]interface{}{"kind": "scriptlet", "code": `java.util.List orders = (java.util.List) request.getAttribute("orders");`, "scriptletType": "scriptlet"}},
+				{file: "orders.jsp", line: 10, variables: map[string]interface{}{"kind": "scriptlet", "code": "order.getTotal()", "scriptletType": "expression"}},
+			},
+		},
+		{
+			name:      "unknown kind",
+			condition: webPageCondition{Kind: "directive"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &javaServiceClient{
+				config: provider.InitConfig{Location: "testdata/webpage"},
+				log:    testr.New(t),
+			}
+			got, err := p.evaluateWebPage(context.TODO(), tt.condition)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateWebPage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if incidents := lineIncidents(got); !reflect.DeepEqual(incidents, tt.want) {
+				t.Errorf("evaluateWebPage() incidents = %+v, want %+v", incidents, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package java
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// workspaceHashFile keeps the hash of the project a cached workspace indexed
+const workspaceHashFile = ".konveyor-project-hash"
+
+// projectHash fingerprints the project at location with the path, size and
+// modification time of its files, and the settings changing how jdtls
+// indexes it. Hidden files and directories and the cache of the workspaces,
+// when it is in the project, are left out.
+func projectHash(location, cacheDir string, settings ...string) (string, error) {
+	location, err := filepath.Abs(location)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err = filepath.Abs(cacheDir)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, setting := range settings {
+		fmt.Fprintf(h, "setting %s\n", setting)
+	}
+	err = filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != location && (strings.HasPrefix(d.Name(), ".") || path == cacheDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// jdtls writes the .project and .classpath files of the projects
+		if strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(location, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s %d %d\n", filepath.ToSlash(rel), info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cachedWorkspace returns the workspace of the project at location in the
+// cache directory. The workspace is reused when it indexed a project with
+// the same hash, it is emptied for jdtls to index the project again
+// otherwise, so a project has a single workspace in the cache.
+func cachedWorkspace(log logr.Logger, cacheDir, location, hash string) (string, error) {
+	location, err := filepath.Abs(location)
+	if err != nil {
+		return "", err
+	}
+	key := sha256.Sum256([]byte(location))
+	workspace, err := filepath.Abs(filepath.Join(cacheDir, hex.EncodeToString(key[:])[:16]))
+	if err != nil {
+		return "", err
+	}
+	hashFile := filepath.Join(workspace, workspaceHashFile)
+	if content, err := os.ReadFile(hashFile); err == nil && strings.TrimSpace(string(content)) == hash {
+		log.Info("reusing the workspace of a previous analysis of the project", "workspace", workspace)
+		return workspace, nil
+	}
+	if err := os.RemoveAll(workspace); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(hashFile, []byte(hash+"\n"), 0644); err != nil {
+		return "", err
+	}
+	log.Info("project changed or not analyzed before, indexing it in a new workspace", "workspace", workspace)
+	return workspace, nil
+}
+
+// stringList returns the strings of a provider setting given as a list,
+// which is a []interface{} once decoded, or as a string separated by
+// whitespace
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []interface{}:
+		values := []string{}
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package java
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+)
+
+func Test_cachedWorkspace(t *testing.T) {
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "pom.xml"), []byte("<project/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := filepath.Join(project, ".workspaces")
+
+	hash, err := projectHash(project, cacheDir, "bundle.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace, err := cachedWorkspace(testr.New(t), cacheDir, project, hash)
+	if err != nil {
+		t.Fatalf("cachedWorkspace() error = %v", err)
+	}
+	// jdtls writes its index to the workspace and its files to the project
+	index := filepath.Join(workspace, "index")
+	if err := os.WriteFile(index, []byte("index"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, ".classpath"), []byte("<classpath/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sameHash, err := projectHash(project, cacheDir, "bundle.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sameHash != hash {
+		t.Errorf("expected the hash of the unchanged project not to change")
+	}
+	if reused, err := cachedWorkspace(testr.New(t), cacheDir, project, sameHash); err != nil || reused != workspace {
+		t.Fatalf("cachedWorkspace() = %s, %v, want %s", reused, err, workspace)
+	}
+	if _, err := os.Stat(index); err != nil {
+		t.Errorf("expected the index of the workspace to be reused")
+	}
+
+	if otherBundles, _ := projectHash(project, cacheDir); otherBundles == hash {
+		t.Errorf("expected the bundles to change the hash")
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(project, "pom.xml"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	changedHash, err := projectHash(project, cacheDir, "bundle.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changedHash == hash {
+		t.Fatalf("expected the hash of the changed project to change")
+	}
+	if emptied, err := cachedWorkspace(testr.New(t), cacheDir, project, changedHash); err != nil || emptied != workspace {
+		t.Fatalf("cachedWorkspace() = %s, %v, want %s", emptied, err, workspace)
+	}
+	if _, err := os.Stat(index); !os.IsNotExist(err) {
+		t.Errorf("expected the workspace of the changed project to be emptied")
+	}
+}
+
+func Test_stringList(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  []string
+	}{
+		{value: "-Xms2g  -XX:+UseG1GC", want: []string{"-Xms2g", "-XX:+UseG1GC"}},
+		{value: []interface{}{"-Xms2g", "-Xss4m"}, want: []string{"-Xms2g", "-Xss4m"}},
+		{value: []string{"-Xms2g"}, want: []string{"-Xms2g"}},
+		{value: nil, want: nil},
+	}
+	for _, tt := range tests {
+		if got := stringList(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("stringList(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -13,11 +13,12 @@ import (
 )
 
 var (
-	port      = flag.Int("port", 0, "Port must be set")
-	name      = flag.String("name", "yaml", "Port must be set")
-	certFile  = flag.String("certFile", "", "Path to the cert file")
-	keyFile   = flag.String("keyFile", "", "Path to the key file")
-	secretKey = flag.String("secretKey", "", "Secret Key value")
+	port        = flag.Int("port", 0, "Port must be set unless a socket is")
+	name        = flag.String("name", "yaml", "Port must be set")
+	certFile    = flag.String("certFile", "", "Path to the cert file")
+	keyFile     = flag.String("keyFile", "", "Path to the key file")
+	secretKey   = flag.String("secretKey", "", "Secret Key value")
+	serverFlags = provider.NewServerFlags(flag.CommandLine)
 )
 
 func main() {
@@ -32,8 +33,8 @@ func main() {
 
 	client := yq_provider.NewYqProvider()
 
-	if port == nil || *port == 0 {
-		panic(fmt.Errorf("must pass in the port for the external provider"))
+	if (port == nil || *port == 0) && serverFlags.Socket == "" {
+		panic(fmt.Errorf("must pass in the port or the socket for the external provider"))
 	}
 	var c string
 	var k string
@@ -51,7 +52,7 @@ func main() {
 		secret = *secretKey
 	}
 
-	s := provider.NewServer(client, *port, c, k, secret, log)
+	s := provider.NewServer(client, *port, c, k, secret, log, serverFlags.Options()...)
 	ctx := context.TODO()
 	s.Start(ctx)
 }
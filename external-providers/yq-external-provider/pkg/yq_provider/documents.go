@@ -0,0 +1,132 @@
+package yq_provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// TemplatesConfigKey is how the documents with go-template actions, such
+	// as the templates of Helm charts, are handled
+	TemplatesConfigKey = "templates"
+	// HelmPathConfigKey is the helm binary rendering the charts
+	HelmPathConfigKey = "helmPath"
+
+	// TolerateTemplates replaces the template actions so that the documents
+	// can be parsed, the default
+	TolerateTemplates = "tolerate"
+	// RenderTemplates renders the Helm charts with helm template, the other
+	// templates are tolerated
+	RenderTemplates = "render"
+	// NoTemplates leaves the template actions as is, the documents with any
+	// are skipped as they can't be parsed
+	NoTemplates = "none"
+)
+
+// templatePlaceholder is the scalar the template actions are replaced with
+const templatePlaceholder = "_template_"
+
+var templateActionRegex = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+
+// yamlDocument is a document of a YAML stream
+type yamlDocument struct {
+	// Index is the position of the document in the stream, from 1
+	Index int
+	// StartLine is the line of the stream the document starts at, from 1
+	StartLine int
+	// Separated is whether a --- marker is before the document, it always is
+	// but for the first one
+	Separated bool
+	Lines     []string
+}
+
+func (d yamlDocument) Content() string {
+	return strings.Join(d.Lines, "\n")
+}
+
+// isDocumentSeparator reports whether a line is a --- marker starting a
+// document, such as "---" or "--- # comment"
+func isDocumentSeparator(line string) bool {
+	if !strings.HasPrefix(line, "---") {
+		return false
+	}
+	rest := strings.TrimRight(line[3:], "\r")
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// splitDocuments splits a YAML stream into its documents, the separators are
+// in none of them
+func splitDocuments(content string) []yamlDocument {
+	docs := []yamlDocument{}
+	current := yamlDocument{Index: 1, StartLine: 1}
+	for i, line := range strings.Split(content, "\n") {
+		if isDocumentSeparator(line) {
+			if i > 0 {
+				docs = append(docs, current)
+			}
+			current = yamlDocument{Index: len(docs) + 1, StartLine: i + 2, Separated: true}
+			continue
+		}
+		current.Lines = append(current.Lines, line)
+	}
+	return append(docs, current)
+}
+
+func hasTemplateActions(content string) bool {
+	return strings.Contains(content, "{{")
+}
+
+// tolerateTemplates replaces the go-template actions of a document so that
+// it can be parsed while keeping its lines where they are. The lines with
+// only actions, such as {{- if .Values.enabled }}, are emptied, the other
+// actions are replaced by a placeholder, image: {{ .Values.image }} becomes
+// image: _template_.
+func tolerateTemplates(lines []string) []string {
+	// the actions are replaced by a marker first, an action may span lines
+	const marker = "\x00"
+	replaced := templateActionRegex.ReplaceAllStringFunc(strings.Join(lines, "\n"), func(action string) string {
+		return marker + strings.Repeat("\n"+marker, strings.Count(action, "\n"))
+	})
+	tolerated := strings.Split(replaced, "\n")
+	for i, line := range tolerated {
+		if !strings.Contains(line, marker) {
+			continue
+		}
+		if strings.TrimSpace(strings.ReplaceAll(line, marker, "")) == "" {
+			tolerated[i] = ""
+			continue
+		}
+		tolerated[i] = strings.ReplaceAll(line, marker, templatePlaceholder)
+	}
+	return tolerated
+}
+
+// prepareStream returns the stream given to yq for the content of a file, the
+// template actions are handled as configured and the documents that can't be
+// parsed are emptied, they would fail the whole stream. The lines are kept
+// where they are, the line numbers of yq are the ones of the file. There is a
+// warning per emptied document.
+func prepareStream(file, content, templates string) (string, []string) {
+	warnings := []string{}
+	docs := splitDocuments(content)
+	lines := []string{}
+	for _, doc := range docs {
+		if doc.Separated {
+			lines = append(lines, "---")
+		}
+		docLines := doc.Lines
+		if templates != NoTemplates && hasTemplateActions(doc.Content()) {
+			docLines = tolerateTemplates(docLines)
+		}
+		var parsed interface{}
+		if err := yaml.Unmarshal([]byte(strings.Join(docLines, "\n")), &parsed); err != nil {
+			warnings = append(warnings, fmt.Sprintf("unable to parse document %d of yaml file %s at line %d, skipped it: %v", doc.Index, file, doc.StartLine, err))
+			docLines = make([]string, len(doc.Lines))
+		}
+		lines = append(lines, docLines...)
+	}
+	return strings.Join(lines, "\n"), warnings
+}
@@ -0,0 +1,116 @@
+package yq_provider
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const chartTemplate = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "app.fullname" . }}
+  labels:
+    {{- include "app.labels" . | nindent 4 }}
+spec:
+  {{- if not .Values.autoscaling.enabled }}
+  replicas: {{ .Values.replicaCount }}
+  {{- end }}
+  template:
+    spec:
+      containers:
+        - image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+---
+{{/*
+Service of the deployment
+*/}}
+apiVersion: v1
+kind: Service
+---
+apiVersion: batch/v1beta1
+kind: CronJob
+spec: [unclosed
+`
+
+func TestPrepareStream(t *testing.T) {
+	stream, warnings := prepareStream("deployment.yaml", chartTemplate, TolerateTemplates)
+	lines := strings.Split(stream, "\n")
+	if len(lines) != len(strings.Split(chartTemplate, "\n")) {
+		t.Fatalf("expected the lines of the file to be kept, got\n%s", stream)
+	}
+	expected := map[int]string{
+		4:  "  name: _template_",
+		6:  "",
+		8:  "",
+		9:  "  replicas: _template_",
+		14: `        - image: "_template_:_template_"`,
+		15: "---",
+		16: "",
+		18: "",
+		19: "apiVersion: v1",
+		21: "---",
+		22: "",
+		24: "",
+	}
+	for line, content := range expected {
+		if lines[line-1] != content {
+			t.Errorf("expected %q at line %d, got %q", content, line, lines[line-1])
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "document 3 of yaml file deployment.yaml at line 22") {
+		t.Errorf("expected a warning for the third document, got %v", warnings)
+	}
+
+	_, warnings = prepareStream("deployment.yaml", chartTemplate, NoTemplates)
+	if len(warnings) != 3 {
+		t.Errorf("expected a warning per document without tolerating templates, got %v", warnings)
+	}
+
+	stream, warnings = prepareStream("list.yaml", "---\napiVersion: v1\nkind: ConfigMap\n--- # second\nkind: Secret\n", TolerateTemplates)
+	if stream != "---\napiVersion: v1\nkind: ConfigMap\n---\nkind: Secret\n" || len(warnings) != 0 {
+		t.Errorf("unexpected stream %q with warnings %v", stream, warnings)
+	}
+}
+
+func TestRenderedDocuments(t *testing.T) {
+	output := `---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+---
+# Source: app/charts/db/templates/statefulset.yaml
+apiVersion: apps/v1
+kind: StatefulSet
+---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: headless
+`
+	rendered := renderedDocuments(filepath.Join("charts", "app"), output)
+	counts := map[string]int{}
+	for file, docs := range rendered {
+		counts[file] = len(docs)
+	}
+	expected := map[string]int{
+		filepath.Join("charts", "app", "templates", "service.yaml"):                     2,
+		filepath.Join("charts", "app", "charts", "db", "templates", "statefulset.yaml"): 1,
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected documents %v, got %v", expected, counts)
+	}
+	chart := helmChart{Dir: filepath.Join("charts", "app")}
+	if !chart.owns(filepath.Join("charts", "app", "templates", "service.yaml")) || chart.owns(filepath.Join("charts", "app", "values.yaml")) {
+		t.Errorf("expected the chart to own its templates only")
+	}
+
+	template := strings.Split(chartTemplate, "\n")
+	if line := templateLine(template, "Service"); line != 20 {
+		t.Errorf("expected the line of the kind, got %d", line)
+	}
+	if line := templateLine(template, "Ingress"); line != 1 {
+		t.Errorf("expected the line of the first apiVersion, got %d", line)
+	}
+}
@@ -0,0 +1,122 @@
+package yq_provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// helmChart is a chart rendered with helm template, the files of its
+// templates are analyzed from the rendered documents
+type helmChart struct {
+	Dir string
+	// Rendered are the documents rendered from each template file
+	Rendered map[string][]yamlDocument
+}
+
+// owns reports whether a file is rendered by the chart, the templates of the
+// chart and of its subcharts are
+func (c helmChart) owns(file string) bool {
+	for _, dir := range []string{filepath.Join(c.Dir, "templates"), filepath.Join(c.Dir, "charts")} {
+		if strings.HasPrefix(file, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// findCharts returns the directories of the Helm charts under the location,
+// the subcharts under a charts directory are rendered with their parent
+func findCharts(location string) ([]string, error) {
+	charts := []string{}
+	err := filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "Chart.yaml" {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		for _, chart := range charts {
+			if strings.HasPrefix(dir, filepath.Join(chart, "charts")+string(filepath.Separator)) {
+				return nil
+			}
+		}
+		charts = append(charts, dir)
+		return nil
+	})
+	return charts, err
+}
+
+// renderChart renders a chart with the default values of the chart
+func renderChart(ctx context.Context, helmPath, dir string) (helmChart, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, helmPath, "template", "release", dir)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return helmChart{}, fmt.Errorf("unable to render chart %s: %w, %s", dir, err, strings.TrimSpace(stderr.String()))
+	}
+	return helmChart{Dir: dir, Rendered: renderedDocuments(dir, stdout.String())}, nil
+}
+
+// renderedDocuments groups the documents of the output of helm template by the
+// template they are rendered from. Each document starts with a comment such as
+// "# Source: mychart/templates/deployment.yaml", the path starts with the name
+// of the chart.
+func renderedDocuments(dir, output string) map[string][]yamlDocument {
+	rendered := map[string][]yamlDocument{}
+	for _, doc := range splitDocuments(output) {
+		for _, line := range doc.Lines {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "# Source: ") {
+				continue
+			}
+			_, path, found := strings.Cut(strings.TrimPrefix(line, "# Source: "), "/")
+			if !found {
+				break
+			}
+			file := filepath.Join(dir, filepath.FromSlash(path))
+			rendered[file] = append(rendered[file], doc)
+			break
+		}
+	}
+	return rendered
+}
+
+// renderedStream returns the stream of the documents rendered from a
+// template, the line numbers of yq are the ones of the stream
+func renderedStream(docs []yamlDocument) string {
+	contents := []string{}
+	for _, doc := range docs {
+		contents = append(contents, doc.Content())
+	}
+	return strings.Join(contents, "\n---\n")
+}
+
+// templateLine returns the line of a template declaring a resource of a kind,
+// the first apiVersion key when the kind is templated too. The rendered
+// documents don't keep the lines of the template, it is a best effort.
+func templateLine(template []string, kind string) int {
+	for i, line := range template {
+		if strings.TrimSpace(line) == "kind: "+kind {
+			return i + 1
+		}
+	}
+	for i, line := range template {
+		if strings.HasPrefix(strings.TrimSpace(line), "apiVersion:") {
+			return i + 1
+		}
+	}
+	return 1
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/process"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/swaggest/openapi-go/openapi3"
 )
@@ -83,6 +83,20 @@ func (p *yqProvider) Init(ctx context.Context, log logr.Logger, c provider.InitC
 		return nil, provider.InitConfig{}, fmt.Errorf("invalid lspServerPath provided, unable to init yq provider")
 	}
 
+	templates := TolerateTemplates
+	if t, ok := c.ProviderSpecificConfig[TemplatesConfigKey].(string); ok && t != "" {
+		switch t {
+		case TolerateTemplates, RenderTemplates, NoTemplates:
+			templates = t
+		default:
+			return nil, provider.InitConfig{}, fmt.Errorf("invalid %s %s, must be one of %s, %s or %s", TemplatesConfigKey, t, TolerateTemplates, RenderTemplates, NoTemplates)
+		}
+	}
+	helmPath, ok := c.ProviderSpecificConfig[HelmPathConfigKey].(string)
+	if !ok || helmPath == "" {
+		helmPath = "helm"
+	}
+
 	ctx, cancelFunc := context.WithCancel(ctx)
 	log = log.WithValues("provider", c.ProviderSpecificConfig["name"])
 	var args []string
@@ -101,10 +115,14 @@ func (p *yqProvider) Init(ctx context.Context, log logr.Logger, c provider.InitC
 			}
 		}
 	}
-	cmd := exec.CommandContext(ctx, lspServerPath, args...)
+	group := process.Command(ctx, lspServerPath, args...)
+	cmd := group.Cmd
 
 	go func() {
-		err := cmd.Run()
+		err := group.Start()
+		if err == nil {
+			err = cmd.Wait()
+		}
 		if err != nil {
 			log.Error(err, "failed to start LSP server")
 			// TODO: Probably should cancel the ctx here, to shut everything down
@@ -117,6 +135,8 @@ func (p *yqProvider) Init(ctx context.Context, log logr.Logger, c provider.InitC
 		log:        log,
 		cmd:        cmd,
 		config:     c,
+		templates:  templates,
+		helmPath:   helmPath,
 	}
 
 	return &svcClient, provider.InitConfig{}, nil
@@ -33,6 +33,14 @@ type yqServiceClient struct {
 
 	config       provider.InitConfig
 	capabilities protocol.ServerCapabilities
+
+	// templates is how the go-template actions of the documents are handled
+	templates string
+	helmPath  string
+	// the charts are rendered once, at the first evaluation
+	chartsOnce    sync.Once
+	charts        []helmChart
+	chartWarnings []string
 }
 
 type Release struct {
@@ -76,7 +84,7 @@ func (p *yqServiceClient) Evaluate(ctx context.Context, cap string, conditionInf
 
 	query := []string{APIVERSION, KIND}
 
-	values, err := p.GetAllValuesForKey(ctx, query)
+	values, warnings, err := p.GetAllValuesForKey(ctx, query)
 	if err != nil {
 		return provider.ProviderEvaluateResponse{}, fmt.Errorf("can't find any value for query: %v, error=%v", query, err)
 	}
@@ -124,15 +132,18 @@ func (p *yqServiceClient) Evaluate(ctx context.Context, cap string, conditionInf
 
 	if len(incidents) == 0 {
 		// No results were found.
-		return provider.ProviderEvaluateResponse{Matched: false}, nil
+		return provider.ProviderEvaluateResponse{Matched: false, Warnings: warnings}, nil
 	}
 	return provider.ProviderEvaluateResponse{
 		Matched:   true,
 		Incidents: incidents,
+		Warnings:  warnings,
 	}, nil
 }
 
-func (p *yqServiceClient) GetAllValuesForKey(ctx context.Context, query []string) ([]k8sOutput, error) {
+// GetAllValuesForKey returns the values of the keys of the documents of the
+// YAML files, along with warnings about the documents that were skipped
+func (p *yqServiceClient) GetAllValuesForKey(ctx context.Context, query []string) ([]k8sOutput, []string, error) {
 	var results []k8sOutput
 	var wg sync.WaitGroup
 	var mu sync.Mutex
@@ -140,16 +151,23 @@ func (p *yqServiceClient) GetAllValuesForKey(ctx context.Context, query []string
 	matchingYAMLFiles, err := provider.FindFilesMatchingPattern(p.config.Location, "*.yaml")
 	if err != nil {
 		p.log.Error(err, "unable to find any YML files")
-		return results, err
+		return results, nil, err
 	}
 	matchingYMLFiles, err := provider.FindFilesMatchingPattern(p.config.Location, "*.yml")
 	if err != nil {
 		p.log.Error(err, "unable to find any YML files")
-		return results, err
+		return results, nil, err
 	}
 	matchingYAMLFiles = append(matchingYAMLFiles, matchingYMLFiles...)
 
+	charts, chartWarnings := p.renderCharts(ctx)
+	warnings := append([]string{}, chartWarnings...)
+
 	for _, file := range matchingYAMLFiles {
+		if absPath, err := filepath.Abs(file); err == nil && ownedByChart(charts, absPath) {
+			// analyzed from the rendered documents
+			continue
+		}
 		wg.Add(1)
 		go func(file string) {
 			defer wg.Done()
@@ -160,48 +178,138 @@ func (p *yqServiceClient) GetAllValuesForKey(ctx context.Context, query []string
 				return
 			}
 
-			cmd := p.ConstructYQCommand(query)
-			result, err := p.ExecuteCmd(cmd, string(data))
+			stream, streamWarnings := prepareStream(file, string(data), p.templates)
+			outputs, err := p.queryStream(query, file, stream)
+
+			mu.Lock()
+			defer mu.Unlock()
+			warnings = append(warnings, streamWarnings...)
 			if err != nil {
 				p.log.V(5).Error(err, "Error running 'yq' command")
+				warnings = append(warnings, fmt.Sprintf("unable to query yaml file %s: %v", file, err))
 				return
 			}
+			results = append(results, outputs...)
+		}(file)
+	}
 
-			mu.Lock()
-			defer mu.Unlock()
-
-			for _, output := range result {
-				var currentResult k8sOutput
-
-				result := strings.Split(strings.TrimSpace(output), "\n")
-				currentResult.ApiVersion = k8skey{
-					Value:      result[0],
-					LineNumber: result[1],
+	for _, chart := range charts {
+		for file, docs := range chart.Rendered {
+			wg.Add(1)
+			go func(file string, docs []yamlDocument) {
+				defer wg.Done()
+
+				stream, streamWarnings := prepareStream(file, renderedStream(docs), NoTemplates)
+				outputs, err := p.queryStream(query, file, stream)
+				var template []string
+				if data, readErr := os.ReadFile(file); readErr == nil {
+					template = strings.Split(string(data), "\n")
 				}
-
-				currentResult.Kind = k8skey{
-					Value:      result[2],
-					LineNumber: result[3],
+				// the lines are the ones of the rendered documents, not of
+				// the template
+				for i := range outputs {
+					line := strconv.Itoa(templateLine(template, outputs[i].Kind.Value))
+					outputs[i].ApiVersion.LineNumber = line
+					outputs[i].Kind.LineNumber = line
 				}
-				absPath, err := filepath.Abs(file)
+
+				mu.Lock()
+				defer mu.Unlock()
+				warnings = append(warnings, streamWarnings...)
 				if err != nil {
-					p.log.V(5).Error(err, "error getting abs path of yaml file")
-				}
-				fileURL := url.URL{
-					Scheme: "file",
-					Path:   absPath,
+					p.log.V(5).Error(err, "Error running 'yq' command")
+					warnings = append(warnings, fmt.Sprintf("unable to query the rendered documents of yaml file %s: %v", file, err))
+					return
 				}
+				results = append(results, outputs...)
+			}(file, docs)
+		}
+	}
 
-				fileURI := fileURL.String()
-				currentResult.URI = fileURI
+	wg.Wait()
+	sort.Strings(warnings)
+	return results, warnings, nil
+}
 
-				results = append(results, currentResult)
-			}
-		}(file)
+// queryStream runs the query on the documents of a YAML stream of a file
+func (p *yqServiceClient) queryStream(query []string, file, stream string) ([]k8sOutput, error) {
+	cmd := p.ConstructYQCommand(query)
+	result, err := p.ExecuteCmd(cmd, stream)
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		p.log.V(5).Error(err, "error getting abs path of yaml file")
+	}
+	fileURL := url.URL{
+		Scheme: "file",
+		Path:   absPath,
 	}
 
-	wg.Wait()
-	return results, nil
+	outputs := []k8sOutput{}
+	for _, output := range result {
+		var currentResult k8sOutput
+
+		result := strings.Split(strings.TrimSpace(output), "\n")
+		// emptied documents have no values
+		if len(result) < 4 {
+			continue
+		}
+		currentResult.ApiVersion = k8skey{
+			Value:      result[0],
+			LineNumber: result[1],
+		}
+
+		currentResult.Kind = k8skey{
+			Value:      result[2],
+			LineNumber: result[3],
+		}
+		currentResult.URI = fileURL.String()
+
+		outputs = append(outputs, currentResult)
+	}
+	return outputs, nil
+}
+
+// renderCharts renders the Helm charts of the location with helm template
+// when configured to. The charts that can't be rendered are left to the
+// analysis of their files, with a warning.
+func (p *yqServiceClient) renderCharts(ctx context.Context) ([]helmChart, []string) {
+	p.chartsOnce.Do(func() {
+		if p.templates != RenderTemplates {
+			return
+		}
+		location, err := filepath.Abs(p.config.Location)
+		if err != nil {
+			p.chartWarnings = append(p.chartWarnings, fmt.Sprintf("unable to find the helm charts: %v", err))
+			return
+		}
+		dirs, err := findCharts(location)
+		if err != nil {
+			p.chartWarnings = append(p.chartWarnings, fmt.Sprintf("unable to find the helm charts: %v", err))
+			return
+		}
+		for _, dir := range dirs {
+			chart, err := renderChart(ctx, p.helmPath, dir)
+			if err != nil {
+				p.log.Error(err, "unable to render helm chart, its templates are tolerated", "chart", dir)
+				p.chartWarnings = append(p.chartWarnings, err.Error())
+				continue
+			}
+			p.charts = append(p.charts, chart)
+		}
+	})
+	return p.charts, p.chartWarnings
+}
+
+func ownedByChart(charts []helmChart, file string) bool {
+	for _, chart := range charts {
+		if chart.owns(file) {
+			return true
+		}
+	}
+	return false
 }
 
 func (p *yqServiceClient) ExecuteCmd(cmd *exec.Cmd, input string) ([]string, error) {
@@ -0,0 +1,85 @@
+// Package feature implements the flags used to opt in to experimental
+// behaviors of the engine and the providers at runtime.
+package feature
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvVar is the environment variable holding a comma separated list of flags,
+// a flag is either a name, which enables it, or name=<bool>.
+const EnvVar = "KONVEYOR_FEATURE_FLAGS"
+
+// Flags maps the name of a feature to whether it is enabled.
+type Flags map[string]bool
+
+// Load reads the flags from the given YAML file, a map of flag names to
+// booleans, and applies the flags set in the environment on top of them.
+// An empty path only reads the environment.
+func Load(path string) (Flags, error) {
+	flags := Flags{}
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read feature flags file: %w", err)
+		}
+		if err := yaml.Unmarshal(content, &flags); err != nil {
+			return nil, fmt.Errorf("unable to parse feature flags file %s: %w", path, err)
+		}
+	}
+	env, err := Parse(os.Getenv(EnvVar))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", EnvVar, err)
+	}
+	for name, enabled := range env {
+		flags[name] = enabled
+	}
+	return flags, nil
+}
+
+// Parse parses flags in the format of the environment variable.
+func Parse(s string) (Flags, error) {
+	flags := Flags{}
+	for _, flag := range strings.Split(s, ",") {
+		flag = strings.TrimSpace(flag)
+		if flag == "" {
+			continue
+		}
+		name, value, found := strings.Cut(flag, "=")
+		enabled := true
+		if found {
+			var err error
+			enabled, err = strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for feature flag %s: %w", name, err)
+			}
+		}
+		flags[strings.TrimSpace(name)] = enabled
+	}
+	return flags, nil
+}
+
+// Enabled returns whether the feature is enabled, unknown features are disabled.
+func (f Flags) Enabled(name string) bool {
+	return f[name]
+}
+
+// String returns the flags in the format of the environment variable, sorted by name.
+func (f Flags) String() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	flags := make([]string, 0, len(names))
+	for _, name := range names {
+		flags = append(flags, fmt.Sprintf("%s=%t", name, f[name]))
+	}
+	return strings.Join(flags, ",")
+}
@@ -0,0 +1,36 @@
+package feature
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	if err := os.WriteFile(path, []byte("conditionCache: true\nstreaming: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvVar, "streaming=false, newMatcher")
+	flags, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := Flags{"conditionCache": true, "streaming": false, "newMatcher": true}
+	if !reflect.DeepEqual(flags, want) {
+		t.Errorf("Load() = %v, want %v", flags, want)
+	}
+	if flags.Enabled("streaming") || !flags.Enabled("newMatcher") || flags.Enabled("unknown") {
+		t.Errorf("unexpected enabled flags %v", flags)
+	}
+	if got := flags.String(); got != "conditionCache=true,newMatcher=true,streaming=false" {
+		t.Errorf("String() = %v", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse("conditionCache=maybe"); err == nil {
+		t.Errorf("expected error for invalid flag value")
+	}
+}
@@ -0,0 +1,122 @@
+// Package fileuri canonicalizes the file URIs and paths exchanged between the
+// engine, the providers and the scopes so that the same file is always
+// represented, and compared, the same way.
+package fileuri
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/uri"
+)
+
+// resolved caches symlink resolution, the same files are looked up for every incident
+var resolved sync.Map
+
+// Path returns the file system path of a file URI or of a path, percent-encoded
+// characters are decoded and the path is cleaned. It returns an empty string
+// for URIs with another scheme or that are not on the local host.
+func Path(location string) string {
+	if location == "" {
+		return ""
+	}
+	if !strings.Contains(location, "://") {
+		return filepath.Clean(location)
+	}
+	u, err := url.Parse(location)
+	// URIs with a host are not local files
+	if err != nil || u.Scheme != uri.FileScheme || (u.Host != "" && u.Host != "localhost") {
+		return ""
+	}
+	p := u.Path
+	// windows paths are written file:///C:/dir
+	if runtime.GOOS == "windows" && len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(p))
+}
+
+// Canonical returns the canonical URI of a file: the path is absolute, cleaned,
+// has its symlinks resolved and is percent-encoded consistently. URIs with
+// another scheme, such as jar files, are returned unchanged.
+func Canonical(u uri.URI) uri.URI {
+	p := Path(string(u))
+	if p == "" {
+		return u
+	}
+	return uri.File(ResolvePath(p))
+}
+
+// FromPath returns the canonical URI of a file path
+func FromPath(path string) uri.URI {
+	if path == "" {
+		return ""
+	}
+	return uri.File(ResolvePath(filepath.Clean(path)))
+}
+
+// ResolvePath makes the path absolute and resolves its symlinks. When the file
+// does not exist the symlinks of its closest existing parent are resolved.
+func ResolvePath(path string) string {
+	if v, ok := resolved.Load(path); ok {
+		return v.(string)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	result := abs
+	rest := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if r, err := filepath.EvalSymlinks(dir); err == nil {
+			result = filepath.Join(r, rest)
+			break
+		} else if !os.IsNotExist(err) {
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+	resolved.Store(path, result)
+	return result
+}
+
+// Key returns the string to compare or deduplicate files with, two locations
+// have the same key when they point to the same file. Paths are compared case
+// insensitively on platforms whose file systems usually are.
+func Key(u uri.URI) string {
+	p := Path(string(u))
+	if p == "" {
+		return string(u)
+	}
+	p = ResolvePath(p)
+	if caseInsensitive() {
+		p = strings.ToLower(p)
+	}
+	return p
+}
+
+// Equal reports whether two URIs or paths refer to the same file
+func Equal(a, b string) bool {
+	return Key(uri.URI(a)) == Key(uri.URI(b))
+}
+
+// HasPrefix reports whether the file is the prefix or is in the prefix
+// directory. Locations that are not files are compared as strings.
+func HasPrefix(location, prefix string) bool {
+	if Path(location) == "" || Path(prefix) == "" {
+		return strings.HasPrefix(location, prefix)
+	}
+	key, prefixKey := Key(uri.URI(location)), Key(uri.URI(prefix))
+	return key == prefixKey || strings.HasPrefix(key, strings.TrimSuffix(prefixKey, string(filepath.Separator))+string(filepath.Separator))
+}
+
+func caseInsensitive() bool {
+	return runtime.GOOS == "windows" || runtime.GOOS == "darwin"
+}
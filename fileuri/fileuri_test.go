@@ -0,0 +1,75 @@
+package fileuri
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{location: "file:///opt/input/source/src/Main.java", want: "/opt/input/source/src/Main.java"},
+		{location: "file:///opt/input/my%20app/./src/../pom.xml", want: "/opt/input/my app/pom.xml"},
+		{location: "/opt/input/source/", want: "/opt/input/source"},
+		{location: "jar:file:///opt/lib.jar!/Main.class", want: ""},
+		{location: "konveyor-jdt://contents/lib.jar", want: ""},
+		{location: "file://localhost/opt/input/source", want: "/opt/input/source"},
+		{location: "file://server/share/source", want: ""},
+		{location: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := Path(tt.location); got != tt.want {
+			t.Errorf("Path(%q) = %q, want %q", tt.location, got, tt.want)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	dir := ResolvePath(t.TempDir())
+	if err := os.MkdirAll(filepath.Join(dir, "source code"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "source code"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	want := uri.File(filepath.Join(dir, "source code", "Main.java"))
+
+	for _, u := range []uri.URI{
+		uri.URI("file://" + filepath.Join(dir, "source%20code", "Main.java")),
+		uri.File(filepath.Join(dir, "link", "Main.java")),
+		uri.File(filepath.Join(dir, "link", "..", "source code", "Main.java")),
+	} {
+		if got := Canonical(u); got != want {
+			t.Errorf("Canonical(%q) = %q, want %q", u, got, want)
+		}
+		if !Equal(string(u), string(want)) {
+			t.Errorf("expected %q to be equal to %q", u, want)
+		}
+	}
+	if got := Canonical("jar:file:///opt/lib.jar!/Main.class"); got != "jar:file:///opt/lib.jar!/Main.class" {
+		t.Errorf("expected URIs that are not files to be unchanged, got %q", got)
+	}
+}
+
+func TestHasPrefix(t *testing.T) {
+	tests := []struct {
+		location string
+		prefix   string
+		want     bool
+	}{
+		{location: "file:///root/.m2/repository/io/fabric8/client/6.0.0/A.java", prefix: "file:///root/.m2/repository/io/fabric8/client/6.0.0", want: true},
+		{location: "file:///root/.m2/repository/io/fabric8/client/6.0.0-beta/A.java", prefix: "file:///root/.m2/repository/io/fabric8/client/6.0.0", want: false},
+		{location: "file:///root/my%20app/A.java", prefix: "/root/my app", want: true},
+		{location: "konveyor-jdt://contents/io/A.class", prefix: "konveyor-jdt://contents/io", want: true},
+	}
+	for _, tt := range tests {
+		if got := HasPrefix(tt.location, tt.prefix); got != tt.want {
+			t.Errorf("HasPrefix(%q, %q) = %v, want %v", tt.location, tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"go.lsp.dev/uri"
 	"gopkg.in/yaml.v2"
@@ -26,6 +28,11 @@ func EvaluateNoOp[T base](t T, ctx ctx, cap string, info []byte) (resp, error) {
 	return resp{}, nil
 }
 
+// TextSearchFallbackVariable is true for the incidents found by searching the
+// text of the files because the server could not find the declarations of
+// the pattern, see GetDeclarations
+const TextSearchFallbackVariable = "textSearchFallback"
+
 // Generic referenced condition
 type ReferencedCondition struct {
 	Referenced struct {
@@ -44,15 +51,15 @@ func EvaluateReferenced[T base](t T, ctx ctx, cap string, info []byte) (resp, er
 	var cond ReferencedCondition
 	err := yaml.Unmarshal(info, &cond)
 	if err != nil {
-		return resp{}, fmt.Errorf("error unmarshaling query info")
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("error unmarshaling query info"))
 	}
 
 	query := cond.Referenced.Pattern
 	if query == "" {
-		return resp{}, fmt.Errorf("unable to get query info")
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("unable to get query info"))
 	}
 
-	symbols := sc.GetAllDeclarations(ctx, sc.BaseConfig.WorkspaceFolders, query)
+	symbols, fallback := sc.GetDeclarations(ctx, sc.BaseConfig.WorkspaceFolders, query)
 
 	incidents := []provider.IncidentContext{}
 	incidentsMap := make(map[string]provider.IncidentContext) // Remove duplicates
@@ -106,6 +113,11 @@ func EvaluateReferenced[T base](t T, ctx ctx, cap string, info []byte) (resp, er
 					},
 				},
 			}
+			if fallback {
+				incident.Variables[TextSearchFallbackVariable] = true
+			}
+			incident.MatchedText = RangeText(u, ref.Range)
+			sc.AddCodeActions(ctx, &incident, ref.URI, ref.Range)
 			b, _ := json.Marshal(incident)
 
 			incidentsMap[string(b)] = incident
@@ -125,3 +137,19 @@ func EvaluateReferenced[T base](t T, ctx ctx, cap string, info []byte) (resp, er
 		Incidents: incidents,
 	}, nil
 }
+
+// RangeText returns the text of the range in the document, the matched text
+// of an incident, or an empty string when the document can't be read
+func RangeText(documentURI uri.URI, rng protocol.Range) string {
+	if !strings.HasPrefix(string(documentURI), uri.FileScheme) {
+		return ""
+	}
+	text, err := provider.LocationText(documentURI.Filename(), provider.Location{
+		StartPosition: provider.Position{Line: float64(rng.Start.Line), Character: float64(rng.Start.Character)},
+		EndPosition:   provider.Position{Line: float64(rng.End.Line), Character: float64(rng.End.Character)},
+	})
+	if err != nil {
+		return ""
+	}
+	return text
+}
@@ -0,0 +1,166 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/fileuri"
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// GetCodeActions returns the quick fixes of the server for a range of a
+// document. Servers that don't support code actions, and the ones disabled in
+// the config, have none.
+func (sc *LSPServiceClientBase) GetCodeActions(ctx context.Context, documentURI string, rng protocol.Range) []protocol.CodeAction {
+	if sc.BaseConfig.DisableCodeActions || !sc.ServerCapabilities.Supports("textDocument/codeAction") {
+		return nil
+	}
+	params := protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: documentURI},
+		Range:        rng,
+		Context: protocol.CodeActionContext{
+			Diagnostics: []protocol.Diagnostic{},
+			Only:        []protocol.CodeActionKind{protocol.QuickFix},
+		},
+	}
+	res := []json.RawMessage{}
+	err := sc.Call(ctx, "textDocument/codeAction", params, &res)
+	if err != nil {
+		sc.Log.V(5).Error(err, "unable to get code actions", "uri", documentURI)
+		return nil
+	}
+	return parseCodeActions(res)
+}
+
+// AddCodeActions adds the quick fixes of the server at the range of an
+// incident to its codeActions variable
+func (sc *LSPServiceClientBase) AddCodeActions(ctx context.Context, incident *provider.IncidentContext, documentURI string, rng protocol.Range) {
+	actions := sc.GetCodeActions(ctx, documentURI, rng)
+	if len(actions) == 0 {
+		return
+	}
+	if incident.Variables == nil {
+		incident.Variables = map[string]interface{}{}
+	}
+	root := ""
+	if len(sc.BaseConfig.WorkspaceFolders) > 0 {
+		root = sc.BaseConfig.WorkspaceFolders[0]
+	}
+	incident.Variables["codeActions"] = codeActionVariables(actions, root)
+}
+
+// parseCodeActions returns the code actions of a textDocument/codeAction
+// response, the commands it can have instead are code actions with only a
+// command. Disabled code actions and the ones that are not quick fixes are
+// left out.
+func parseCodeActions(res []json.RawMessage) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	for _, raw := range res {
+		var literal struct {
+			Command json.RawMessage `json:"command"`
+		}
+		if err := json.Unmarshal(raw, &literal); err != nil {
+			continue
+		}
+		// the command of a code action is an object, a command has its
+		// identifier
+		if strings.HasPrefix(strings.TrimSpace(string(literal.Command)), `"`) {
+			command := protocol.Command{}
+			if err := json.Unmarshal(raw, &command); err != nil {
+				continue
+			}
+			actions = append(actions, protocol.CodeAction{Title: command.Title, Command: &command})
+			continue
+		}
+		action := protocol.CodeAction{}
+		if err := json.Unmarshal(raw, &action); err != nil {
+			continue
+		}
+		if action.Disabled != nil {
+			continue
+		}
+		if action.Kind != nil && !strings.HasPrefix(string(*action.Kind), string(protocol.QuickFix)) {
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// codeActionVariables returns the title, kind and a summary of the edits of
+// code actions, the files edited are relative to root when they are in it
+func codeActionVariables(actions []protocol.CodeAction, root string) []interface{} {
+	variables := []interface{}{}
+	for _, action := range actions {
+		v := map[string]interface{}{
+			"title": action.Title,
+		}
+		if action.Kind != nil {
+			v["kind"] = string(*action.Kind)
+		}
+		if action.IsPreferred {
+			v["preferred"] = true
+		}
+		if action.Edit != nil {
+			v["edit"] = editSummary(*action.Edit, root)
+		}
+		if action.Command != nil {
+			v["command"] = action.Command.Command
+		}
+		variables = append(variables, v)
+	}
+	return variables
+}
+
+// editSummary describes a workspace edit, such as 2 edits in src/app.py and
+// the files it renames
+func editSummary(edit protocol.WorkspaceEdit, root string) string {
+	edits := map[string]int{}
+	renames := []string{}
+	for documentURI, textEdits := range edit.Changes {
+		edits[relativeFile(string(documentURI), root)] += len(textEdits)
+	}
+	for _, change := range edit.DocumentChanges {
+		switch {
+		case change.TextDocumentEdit != nil:
+			edits[relativeFile(string(change.TextDocumentEdit.TextDocument.URI), root)] += len(change.TextDocumentEdit.Edits)
+		case change.RenameFile != nil:
+			renames = append(renames, fmt.Sprintf("rename %s to %s",
+				relativeFile(string(change.RenameFile.OldURI), root), relativeFile(string(change.RenameFile.NewURI), root)))
+		}
+	}
+	files := make([]string, 0, len(edits))
+	for file := range edits {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	parts := []string{}
+	for _, file := range files {
+		noun := "edits"
+		if edits[file] == 1 {
+			noun = "edit"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s in %s", edits[file], noun, file))
+	}
+	return strings.Join(append(parts, renames...), ", ")
+}
+
+func relativeFile(documentURI, root string) string {
+	path := fileuri.Path(documentURI)
+	if path == "" {
+		return documentURI
+	}
+	rootPath := fileuri.Path(root)
+	if rootPath == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(rootPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
@@ -0,0 +1,46 @@
+package base
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_parseCodeActions(t *testing.T) {
+	res := []json.RawMessage{
+		json.RawMessage(`{"title": "Import 'jakarta.persistence.Entity'", "kind": "quickfix", "isPreferred": true,
+			"edit": {"changes": {"file:///app/src/Entity.py": [{"range": {"start": {"line": 0, "character": 0}, "end": {"line": 0, "character": 6}}, "newText": "import"}]}}}`),
+		json.RawMessage(`{"title": "Organize imports", "command": "python.organizeImports", "arguments": ["file:///app/src/Entity.py"]}`),
+		json.RawMessage(`{"title": "Extract method", "kind": "refactor.extract", "edit": {"changes": {}}}`),
+		json.RawMessage(`{"title": "Remove unused", "kind": "quickfix", "disabled": {"reason": "nothing to remove"}}`),
+		json.RawMessage(`{"title": "Move to module", "kind": "quickfix.move", "command": {"title": "Move", "command": "python.move"},
+			"edit": {"documentChanges": [
+				{"textDocument": {"uri": "file:///app/src/Entity.py", "version": null}, "edits": [
+					{"range": {"start": {"line": 1, "character": 0}, "end": {"line": 1, "character": 0}}, "newText": "a"},
+					{"range": {"start": {"line": 2, "character": 0}, "end": {"line": 2, "character": 0}}, "newText": "b"}]},
+				{"kind": "rename", "oldUri": "file:///app/src/old.py", "newUri": "file:///app/lib/new.py"}]}}`),
+	}
+	actions := parseCodeActions(res)
+	got := codeActionVariables(actions, "file:///app")
+	expected := []interface{}{
+		map[string]interface{}{
+			"title":     "Import 'jakarta.persistence.Entity'",
+			"kind":      "quickfix",
+			"preferred": true,
+			"edit":      "1 edit in src/Entity.py",
+		},
+		map[string]interface{}{
+			"title":   "Organize imports",
+			"command": "python.organizeImports",
+		},
+		map[string]interface{}{
+			"title":   "Move to module",
+			"kind":    "quickfix.move",
+			"edit":    "2 edits in src/Entity.py, rename src/old.py to lib/new.py",
+			"command": "python.move",
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected code actions\n%v\ngot\n%v", expected, got)
+	}
+}
@@ -0,0 +1,285 @@
+package base
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/fileuri"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+// ImportsCondition matches the import, include, require and use statements of
+// the source files without asking the language server.
+type ImportsCondition struct {
+	Imports struct {
+		Pattern   string   `yaml:"pattern"`
+		Languages []string `yaml:"languages,omitempty"`
+	} `yaml:"imports"`
+}
+
+// importParser extracts the imported names from the lines of a language, the
+// first group of a pattern is the imported name. Languages that import
+// several names at once, such as Go, use a block.
+type importParser struct {
+	language   string
+	extensions []string
+	patterns   []*regexp.Regexp
+	blockStart *regexp.Regexp
+	blockEnd   *regexp.Regexp
+	blockEntry *regexp.Regexp
+}
+
+var importParsers = []importParser{
+	{
+		language:   "go",
+		extensions: []string{".go"},
+		patterns:   []*regexp.Regexp{regexp.MustCompile(`^\s*import\s+(?:[\w.]+\s+)?"([^"]+)"`)},
+		blockStart: regexp.MustCompile(`^\s*import\s*\(\s*$`),
+		blockEnd:   regexp.MustCompile(`^\s*\)`),
+		blockEntry: regexp.MustCompile(`^\s*(?:[\w.]+\s+)?"([^"]+)"`),
+	},
+	{
+		language:   "python",
+		extensions: []string{".py"},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^\s*from\s+([\w.]+)\s+import\s`),
+			regexp.MustCompile(`^\s*import\s+([\w.]+(?:\s+as\s+\w+)?(?:\s*,\s*[\w.]+(?:\s+as\s+\w+)?)*)`),
+		},
+	},
+	{
+		language:   "javascript",
+		extensions: []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx"},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^\s*(?:import|export)\s[^'"]*?\bfrom\s+['"]([^'"]+)['"]`),
+			// closing line of an import spanning several lines
+			regexp.MustCompile(`^\s*}\s*from\s+['"]([^'"]+)['"]`),
+			regexp.MustCompile(`^\s*import\s+['"]([^'"]+)['"]`),
+			regexp.MustCompile(`\brequire\s*\(\s*['"]([^'"]+)['"]\s*\)`),
+			regexp.MustCompile(`\bimport\s*\(\s*['"]([^'"]+)['"]\s*\)`),
+		},
+	},
+	{
+		language:   "java",
+		extensions: []string{".java", ".kt", ".kts", ".groovy", ".scala"},
+		patterns:   []*regexp.Regexp{regexp.MustCompile(`^\s*import\s+(?:static\s+)?([\w.*]+)`)},
+	},
+	{
+		language:   "c",
+		extensions: []string{".c", ".h", ".cc", ".cpp", ".cxx", ".hpp", ".hh"},
+		patterns:   []*regexp.Regexp{regexp.MustCompile(`^\s*#\s*include\s*[<"]([^>"]+)[>"]`)},
+	},
+	{
+		language:   "csharp",
+		extensions: []string{".cs"},
+		patterns:   []*regexp.Regexp{regexp.MustCompile(`^\s*(?:global\s+)?using\s+(?:static\s+)?(?:\w+\s*=\s*)?([\w.]+)\s*;`)},
+	},
+	{
+		language:   "ruby",
+		extensions: []string{".rb"},
+		patterns:   []*regexp.Regexp{regexp.MustCompile(`^\s*require(?:_relative)?\s*\(?\s*['"]([^'"]+)['"]`)},
+	},
+	{
+		language:   "php",
+		extensions: []string{".php"},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^\s*use\s+(?:function\s+|const\s+)?\\?([\w\\]+)`),
+			regexp.MustCompile(`\b(?:require|include)(?:_once)?\s*\(?\s*['"]([^'"]+)['"]`),
+		},
+	},
+	{
+		language:   "rust",
+		extensions: []string{".rs"},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^\s*(?:pub\s+)?use\s+([\w:]+)`),
+			regexp.MustCompile(`^\s*extern\s+crate\s+(\w+)`),
+		},
+	},
+}
+
+var pythonAlias = regexp.MustCompile(`\s+as\s+\w+$`)
+
+type importStatement struct {
+	// lineNumber starts at 1
+	lineNumber int
+	name       string
+}
+
+// imports returns the names imported by the file in order
+func (p importParser) imports(path string) ([]importStatement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	found := []importStatement{}
+	inBlock := false
+	lineNumber := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if inBlock {
+			if p.blockEnd.MatchString(line) {
+				inBlock = false
+			} else if match := p.blockEntry.FindStringSubmatch(line); match != nil {
+				found = append(found, importStatement{lineNumber: lineNumber, name: match[1]})
+			}
+			continue
+		}
+		if p.blockStart != nil && p.blockStart.MatchString(line) {
+			inBlock = true
+			continue
+		}
+		for _, pattern := range p.patterns {
+			for _, match := range pattern.FindAllStringSubmatch(line, -1) {
+				for _, name := range p.names(match[1]) {
+					found = append(found, importStatement{lineNumber: lineNumber, name: name})
+				}
+			}
+		}
+	}
+	return found, scanner.Err()
+}
+
+// names splits the statements that import several modules at once
+func (p importParser) names(imported string) []string {
+	if p.language != "python" {
+		return []string{imported}
+	}
+	names := []string{}
+	for _, name := range strings.Split(imported, ",") {
+		names = append(names, pythonAlias.ReplaceAllString(strings.TrimSpace(name), ""))
+	}
+	return names
+}
+
+func importParserFor(path string, languages []string) (importParser, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, p := range importParsers {
+		if len(languages) > 0 && !containsString(languages, p.language) {
+			continue
+		}
+		if containsString(p.extensions, ext) {
+			return p, true
+		}
+	}
+	return importParser{}, false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// EvaluateImports finds the imports matching the pattern in the source files
+// of the workspace. Files in the dependency folders are skipped.
+func EvaluateImports[T base](t T, ctx ctx, cap string, info []byte) (resp, error) {
+	sc := t.GetLSPServiceClientBase()
+
+	var cond ImportsCondition
+	err := yaml.Unmarshal(info, &cond)
+	if err != nil {
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("error unmarshaling query info"))
+	}
+	if cond.Imports.Pattern == "" {
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("unable to get query info, imports pattern is required"))
+	}
+	pattern, err := regexp.Compile(cond.Imports.Pattern)
+	if err != nil {
+		return resp{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("unable to compile imports pattern '%s': %w", cond.Imports.Pattern, err))
+	}
+	for _, language := range cond.Imports.Languages {
+		if !knownImportLanguage(language) {
+			return resp{}, engine.NewProviderError(konveyor.CapabilityUnsupported, fmt.Errorf("imports are not supported for language '%s'", language))
+		}
+	}
+	if len(sc.BaseConfig.WorkspaceFolders) == 0 {
+		return resp{}, fmt.Errorf("no workspace folder to search imports in")
+	}
+
+	incidents := []provider.IncidentContext{}
+	root := fileuri.Path(sc.BaseConfig.WorkspaceFolders[0])
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			for _, dependencyFolder := range sc.BaseConfig.DependencyFolders {
+				if dependencyFolder != "" && strings.Contains(path, dependencyFolder) {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		parser, ok := importParserFor(path, cond.Imports.Languages)
+		if !ok {
+			return nil
+		}
+		imports, err := parser.imports(path)
+		if err != nil {
+			sc.Log.V(5).Error(err, "unable to read imports", "file", path)
+			return nil
+		}
+		for _, i := range imports {
+			if !pattern.MatchString(i.name) {
+				continue
+			}
+			lineNumber := i.lineNumber
+			incidents = append(incidents, provider.IncidentContext{
+				FileURI:    uri.File(path),
+				LineNumber: &lineNumber,
+				Variables: map[string]interface{}{
+					"file":     string(uri.File(path)),
+					"import":   i.name,
+					"language": parser.language,
+				},
+				CodeLocation: &provider.Location{
+					StartPosition: provider.Position{Line: float64(lineNumber)},
+					EndPosition:   provider.Position{Line: float64(lineNumber)},
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return resp{}, err
+	}
+
+	if len(incidents) == 0 {
+		return resp{Matched: false}, nil
+	}
+	return resp{
+		Matched:   true,
+		Incidents: incidents,
+	}, nil
+}
+
+func knownImportLanguage(language string) bool {
+	for _, p := range importParsers {
+		if p.language == language {
+			return true
+		}
+	}
+	return false
+}
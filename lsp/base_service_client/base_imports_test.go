@@ -0,0 +1,160 @@
+package base
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func Test_importParsers(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		want    []importStatement
+	}{
+		{
+			file: "main.go",
+			content: `package main
+
+import "fmt"
+import (
+	"os"
+	log "github.com/sirupsen/logrus"
+	_ "embed"
+)
+`,
+			want: []importStatement{{3, "fmt"}, {5, "os"}, {6, "github.com/sirupsen/logrus"}, {7, "embed"}},
+		},
+		{
+			file: "app.py",
+			content: `import os, sys as system
+from flask import Flask
+    import numpy.linalg as la
+`,
+			want: []importStatement{{1, "os"}, {1, "sys"}, {2, "flask"}, {3, "numpy.linalg"}},
+		},
+		{
+			file: "index.ts",
+			content: `import express from 'express';
+import {
+  Router,
+} from "express-router";
+import './styles.css';
+const lodash = require('lodash');
+export * from "./api";
+`,
+			want: []importStatement{{1, "express"}, {4, "express-router"}, {5, "./styles.css"}, {6, "lodash"}, {7, "./api"}},
+		},
+		{
+			file:    "Main.java",
+			content: "import java.util.List;\nimport static org.junit.Assert.*;\n",
+			want:    []importStatement{{1, "java.util.List"}, {2, "org.junit.Assert.*"}},
+		},
+		{
+			file:    "main.c",
+			content: "#include <stdio.h>\n#  include \"config.h\"\n",
+			want:    []importStatement{{1, "stdio.h"}, {2, "config.h"}},
+		},
+		{
+			file:    "Program.cs",
+			content: "using System.Web;\nusing (var stream = File.Open(path)) {}\nglobal using Json = Newtonsoft.Json;\n",
+			want:    []importStatement{{1, "System.Web"}, {3, "Newtonsoft.Json"}},
+		},
+		{
+			file:    "app.rb",
+			content: "require 'rails'\nrequire_relative \"lib/helper\"\n",
+			want:    []importStatement{{1, "rails"}, {2, "lib/helper"}},
+		},
+		{
+			file:    "index.php",
+			content: "<?php\nuse Symfony\\Component\\HttpFoundation\\Request;\nrequire_once 'vendor/autoload.php';\n",
+			want:    []importStatement{{2, "Symfony\\Component\\HttpFoundation\\Request"}, {3, "vendor/autoload.php"}},
+		},
+		{
+			file:    "main.rs",
+			content: "use std::collections::HashMap;\nextern crate serde;\n",
+			want:    []importStatement{{1, "std::collections::HashMap"}, {2, "serde"}},
+		},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			parser, ok := importParserFor(path, nil)
+			if !ok {
+				t.Fatalf("no import parser for %s", tt.file)
+			}
+			got, err := parser.imports(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("imports() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_EvaluateImports(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":                  "package main\n\nimport \"github.com/gin-gonic/gin\"\n",
+		"vendor/lib/lib.go":        "package lib\n\nimport \"github.com/gin-gonic/gin\"\n",
+		"web/app.js":               "const gin = require('github.com/gin-gonic/gin');\n",
+		"docs/README.md":           "import \"github.com/gin-gonic/gin\"\n",
+		".git/hooks/pre-commit.go": "import \"github.com/gin-gonic/gin\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sc := &LSPServiceClientBase{
+		Log: logr.Discard(),
+		BaseConfig: LSPServiceClientConfig{
+			WorkspaceFolders:  []string{"file://" + dir},
+			DependencyFolders: []string{filepath.Join(dir, "vendor")},
+		},
+	}
+
+	response, err := EvaluateImports(sc, context.TODO(), "imports", []byte("imports:\n  pattern: ^github.com/gin-gonic/\n  languages: [go]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Matched || len(response.Incidents) != 1 {
+		t.Fatalf("expected a single incident, got %#v", response)
+	}
+	incident := response.Incidents[0]
+	if incident.Variables["import"] != "github.com/gin-gonic/gin" || incident.Variables["language"] != "go" || *incident.LineNumber != 3 {
+		t.Errorf("unexpected incident %#v", incident)
+	}
+
+	response, err = EvaluateImports(sc, context.TODO(), "imports", []byte("imports:\n  pattern: gin-gonic\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	languages := []string{}
+	for _, incident := range response.Incidents {
+		languages = append(languages, incident.Variables["language"].(string))
+	}
+	sort.Strings(languages)
+	if !reflect.DeepEqual(languages, []string{"go", "javascript"}) {
+		t.Errorf("expected imports of go and javascript files, got %v", languages)
+	}
+
+	if _, err := EvaluateImports(sc, context.TODO(), "imports", []byte("imports:\n  pattern: gin\n  languages: [cobol]\n")); err == nil {
+		t.Errorf("expected an error for an unsupported language")
+	}
+}
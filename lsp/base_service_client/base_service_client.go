@@ -9,13 +9,18 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/fileuri"
 	jsonrpc2 "github.com/konveyor/analyzer-lsp/jsonrpc2_v2"
 	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/pathfilter"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"go.lsp.dev/uri"
 	"gopkg.in/yaml.v2"
@@ -33,31 +38,13 @@ type LSPServiceClientCapability struct {
 	Fn interface{}
 }
 
-// The base service client configs that all subsequent configs must embed
-type LSPServiceClientConfig struct {
-	// The name of the server. Think `yaml_language_server` not `yaml`
-	LspServerName string `yaml:"lspServerName,omitempty"`
+// The base service client configs that all subsequent configs must embed, it
+// is the schema of the providerSpecificConfig of the generic providers.
+type LSPServiceClientConfig = provider.LSPServiceClientConfig
 
-	// Where the binary of the server is. Not a URI. Passed to exec.CommandContext
-	LspServerPath string `yaml:"lspServerPath,omitempty"`
-
-	// The args of the lsp server. Passed to exec.CommandContext.
-	LspServerArgs []string `yaml:"lspServerArgs,omitempty"`
-
-	// JSON string that can get sent to the initialize request instead of the
-	// computed options in the service client. Each service client can implement
-	// this differently. Must be a string due to grpc not allowing nested structs.
-	LspServerInitializationOptions string `yaml:"lspServerInitializationOptions,omitempty"`
-
-	// Full URI of the workspace folders under consideration
-	WorkspaceFolders []string `yaml:"workspaceFolders,omitempty"`
-	// Full URI of the dependency folders under consideration. Used for ignoring
-	// results from things like `referenced`
-	DependencyFolders []string `yaml:"dependencyFolders,omitempty"`
-
-	// Path to a simple binary that lists the dependencies for a given language.
-	DependencyProviderPath string `yaml:"dependencyProviderPath,omitempty"`
-}
+const (
+	defaultLspMaxConcurrentRequests = 10
+)
 
 // Provides a generic `Evaluate` method, that calls the associated method found
 // in the struct's FuncMap field. T should be a service client pointer
@@ -101,7 +88,7 @@ func (sc *LSPServiceClientEvaluator[T]) Evaluate(ctx context.Context, cap string
 		return fn(sc.Parent, ctx, cap, conditionInfo)
 	}
 
-	return provider.ProviderEvaluateResponse{}, fmt.Errorf("capability '%s' not supported", cap)
+	return provider.ProviderEvaluateResponse{}, engine.NewProviderError(konveyor.CapabilityUnsupported, fmt.Errorf("capability '%s' not supported", cap))
 }
 
 // For the generic methods we must convert the T to a *LSPServiceClientBase[T],
@@ -145,6 +132,13 @@ type LSPServiceClientBase struct {
 	ServerInfo         *protocol.PServerInfoMsg_initialize
 
 	TempDir string
+
+	// Filters the files of the workspace with the included and excluded paths
+	// of the provider settings
+	PathFilter *pathfilter.Filter
+
+	// Limits the requests in flight to LspMaxConcurrentRequests, see Call
+	requests chan struct{}
 }
 
 func NewLSPServiceClientBase(
@@ -170,6 +164,19 @@ func NewLSPServiceClientBase(
 		sc.BaseConfig.LspServerName = "generic"
 	}
 
+	if sc.BaseConfig.LspMaxConcurrentRequests <= 0 {
+		sc.BaseConfig.LspMaxConcurrentRequests = defaultLspMaxConcurrentRequests
+	}
+	if sc.BaseConfig.FileSearchWorkers <= 0 {
+		sc.BaseConfig.FileSearchWorkers = runtime.NumCPU()
+	}
+	sc.requests = make(chan struct{}, sc.BaseConfig.LspMaxConcurrentRequests)
+
+	sc.PathFilter, err = provider.GetPathFilterFromConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
 	if initializeParams.RootURI == "" && len(initializeParams.WorkspaceFolders) == 0 {
 		TempDir, err := os.MkdirTemp("", "tmp")
 		if err != nil {
@@ -184,6 +191,23 @@ func NewLSPServiceClientBase(
 		initializeParams.RootURI = "file://" + initializeParams.RootURI
 	}
 
+	// Servers only answer code actions with their edits, rather than with
+	// commands, to clients supporting code action literals
+	if initializeParams.Capabilities.TextDocument == nil {
+		initializeParams.Capabilities.TextDocument = &protocol.TextDocumentClientCapabilities{}
+	}
+	if initializeParams.Capabilities.TextDocument.CodeAction == nil {
+		initializeParams.Capabilities.TextDocument.CodeAction = &protocol.CodeActionClientCapabilities{
+			CodeActionLiteralSupport: &protocol.PCodeActionLiteralSupportPCodeAction{
+				CodeActionKind: protocol.FCodeActionKindPCodeActionLiteralSupport{
+					ValueSet: []protocol.CodeActionKind{protocol.QuickFix},
+				},
+			},
+			IsPreferredSupport: true,
+			DisabledSupport:    true,
+		}
+	}
+
 	if initializeParams.ProcessID == 0 {
 		initializeParams.ProcessID = int32(os.Getpid())
 	}
@@ -244,6 +268,20 @@ func NewLSPServiceClientBase(
 	return &sc, nil
 }
 
+// Call sends a request to the server and waits for its result, at most
+// LspMaxConcurrentRequests requests are in flight at the same time.
+func (sc *LSPServiceClientBase) Call(ctx context.Context, method string, params, result interface{}) error {
+	if sc.requests != nil {
+		select {
+		case sc.requests <- struct{}{}:
+			defer func() { <-sc.requests }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return sc.Conn.Call(ctx, method, params).Await(ctx, result)
+}
+
 // Method exists so that we can do generic capabilities. See
 // `base_capabilities.go` for examples
 func (sc *LSPServiceClientBase) GetLSPServiceClientBase() *LSPServiceClientBase {
@@ -328,6 +366,14 @@ func (sc *LSPServiceClientBase) Handle(ctx context.Context, req *jsonrpc2.Reques
 //
 // [^1]: https://github.com/golang/tools/blob/ecbfa885b278478686e8b8efb52535e934c53ec5/gopls/internal/lsp/cache/symbols.go#L72
 func (sc *LSPServiceClientBase) GetAllDeclarations(ctx context.Context, workspaceFolders []string, query string) []protocol.WorkspaceSymbol {
+	symbols, _ := sc.GetDeclarations(ctx, workspaceFolders, query)
+	return symbols
+}
+
+// GetDeclarations returns the declarations of GetAllDeclarations and whether
+// they were found by searching the text of the files for the query, when the
+// server found none itself. The text search is skipped with strictMatches.
+func (sc *LSPServiceClientBase) GetDeclarations(ctx context.Context, workspaceFolders []string, query string) ([]protocol.WorkspaceSymbol, bool) {
 	// TODO(jsussman) Should we change protocol.WorkspaceSymbol to
 	// protocol.SymbolInformation?
 
@@ -343,7 +389,7 @@ func (sc *LSPServiceClientBase) GetAllDeclarations(ctx context.Context, workspac
 			Query: query,
 		}
 
-		err := sc.Conn.Call(ctx, "workspace/symbol", params).Await(ctx, &symbols)
+		err := sc.Call(ctx, "workspace/symbol", params, &symbols)
 		if err != nil {
 			fmt.Printf("error: %v\n", err)
 		}
@@ -351,10 +397,12 @@ func (sc *LSPServiceClientBase) GetAllDeclarations(ctx context.Context, workspac
 
 	if regexErr != nil {
 		// Not a valid regex, can't do anything more
-		return symbols
+		return symbols, false
 	}
 
-	if sc.ServerCapabilities.Supports("textDocument/definition") && len(symbols) == 0 {
+	fallback := false
+	if sc.ServerCapabilities.Supports("textDocument/definition") && len(symbols) == 0 && !sc.BaseConfig.StrictMatches {
+		fallback = true
 		// if p.capabilities.Supports("textDocument/declaration") && len(symbols) == 0 {
 		var positions []protocol.TextDocumentPositionParams
 		symbolMap := make(map[string]protocol.WorkspaceSymbol) // To avoid repeats
@@ -365,13 +413,15 @@ func (sc *LSPServiceClientBase) GetAllDeclarations(ctx context.Context, workspac
 		// Lambda function to support switch to workspace folders
 		walkFiles := func(locations []string) error {
 			for _, location := range locations {
-				location = strings.TrimPrefix(location, "file://")
+				if strings.HasPrefix(location, "file://") {
+					location = fileuri.Path(location)
+				}
 
 				if location == "" {
 					continue
 				}
 
-				result, err := parallelWalk(location, regex)
+				result, err := parallelWalk(location, regex, sc.BaseConfig.FileSearchWorkers, sc.PathFilter)
 				if err != nil {
 					return fmt.Errorf("error: %v", err)
 				}
@@ -385,7 +435,7 @@ func (sc *LSPServiceClientBase) GetAllDeclarations(ctx context.Context, workspac
 		err := walkFiles(workspaceFolders)
 		if err != nil {
 			fmt.Printf("%s\n", err.Error())
-			return nil
+			return nil, false
 		}
 
 		// Leaving this in here until we determine whether we can use workspace
@@ -402,30 +452,40 @@ func (sc *LSPServiceClientBase) GetAllDeclarations(ctx context.Context, workspac
 		// 	return nil
 		// }
 
+		// Call limits how many of the requests are sent at once
+		mutex := sync.Mutex{}
+		wg := sync.WaitGroup{}
 		for _, position := range positions {
-			res := []protocol.Location{}
-			err := sc.Conn.Call(ctx, "textDocument/definition", position).Await(ctx, &res)
-			// err := p.rpc.Call(ctx, "textDocument/declaration", position, &res)
-			if err != nil {
-				fmt.Printf("Error rpc: %v", err)
-			}
+			wg.Add(1)
+			go func(position protocol.TextDocumentPositionParams) {
+				defer wg.Done()
+				res := []protocol.Location{}
+				err := sc.Call(ctx, "textDocument/definition", position, &res)
+				// err := p.rpc.Call(ctx, "textDocument/declaration", position, &res)
+				if err != nil {
+					fmt.Printf("Error rpc: %v", err)
+				}
 
-			for _, r := range res {
-				out, _ := json.Marshal(r)
-				symbolMap[string(out)] = protocol.WorkspaceSymbol{
-					Location: protocol.OrPLocation_workspace_symbol{
-						Value: r,
-					},
+				mutex.Lock()
+				defer mutex.Unlock()
+				for _, r := range res {
+					out, _ := json.Marshal(r)
+					symbolMap[string(out)] = protocol.WorkspaceSymbol{
+						Location: protocol.OrPLocation_workspace_symbol{
+							Value: r,
+						},
+					}
 				}
-			}
+			}(position)
 		}
+		wg.Wait()
 
 		for _, ws := range symbolMap {
 			symbols = append(symbols, ws)
 		}
 	}
 
-	return symbols
+	return symbols, fallback && len(symbols) > 0
 }
 
 func (sc *LSPServiceClientBase) GetAllReferences(ctx context.Context, location protocol.Location) []protocol.Location {
@@ -442,19 +502,23 @@ func (sc *LSPServiceClientBase) GetAllReferences(ctx context.Context, location p
 	}
 
 	res := []protocol.Location{}
-	err := sc.Conn.Call(ctx, "textDocument/references", params).Await(ctx, &res)
+	err := sc.Call(ctx, "textDocument/references", params, &res)
 	if err != nil {
 		fmt.Printf("Error rpc: %v", err)
 	}
 
-	return res
+	references := []protocol.Location{}
+	for _, ref := range res {
+		if sc.PathFilter.Match(string(ref.URI)) {
+			references = append(references, ref)
+		}
+	}
+	return references
 }
 
 // ---
 
-func processFile(path string, regex *regexp.Regexp, positionsChan chan<- protocol.TextDocumentPositionParams, wg *sync.WaitGroup) {
-	defer wg.Done()
-
+func processFile(path string, regex *regexp.Regexp, positionsChan chan<- protocol.TextDocumentPositionParams) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return
@@ -485,28 +549,41 @@ func processFile(path string, regex *regexp.Regexp, positionsChan chan<- protoco
 	}
 }
 
-func parallelWalk(location string, regex *regexp.Regexp) ([]protocol.TextDocumentPositionParams, error) {
+func parallelWalk(location string, regex *regexp.Regexp, workers int, filter *pathfilter.Filter) ([]protocol.TextDocumentPositionParams, error) {
 	var positions []protocol.TextDocumentPositionParams
 	positionsChan := make(chan protocol.TextDocumentPositionParams)
+	paths := make(chan string)
 	wg := &sync.WaitGroup{}
 
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				processFile(path, regex, positionsChan)
+			}
+		}()
+	}
+
 	go func() {
-		err := filepath.Walk(location, func(path string, f os.FileInfo, err error) error {
+		filepath.Walk(location, func(path string, f os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
 
-			if f.Mode().IsRegular() {
-				wg.Add(1)
-				go processFile(path, regex, positionsChan, wg)
+			if f.IsDir() && path != location && filter.SkipDir(path) {
+				return filepath.SkipDir
+			}
+			if f.Mode().IsRegular() && filter.Match(path) {
+				paths <- path
 			}
 
 			return nil
 		})
-
-		if err != nil {
-			return
-		}
+		close(paths)
 
 		wg.Wait()
 		close(positionsChan)
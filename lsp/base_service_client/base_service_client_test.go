@@ -0,0 +1,44 @@
+package base
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/pathfilter"
+)
+
+func Test_parallelWalk(t *testing.T) {
+	dir := t.TempDir()
+	for i, content := range []string{"foo\nbar foo\n", "bar\n", "foo\n"} {
+		sub := filepath.Join(dir, "dir", string(rune('a'+i)))
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(sub, "file.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, workers := range []int{0, 1, 4} {
+		positions, err := parallelWalk(dir, regexp.MustCompile("foo"), workers, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(positions) != 3 {
+			t.Errorf("expected 3 positions with %d workers, got %d", workers, len(positions))
+		}
+	}
+
+	filter, err := pathfilter.New(dir, nil, []string{"dir/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions, err := parallelWalk(dir, regexp.MustCompile("foo"), 2, filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(positions) != 1 {
+		t.Errorf("expected 1 position out of the excluded directory, got %d", len(positions))
+	}
+}
@@ -0,0 +1,57 @@
+package base
+
+import (
+	"context"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+// SymbolSearchHelper adapts the search of the declarations of a pattern to
+// how a language names its symbols. Servers answer `workspace/symbol` with
+// fuzzy matches of a short name, the helper turns the pattern into that
+// query and keeps the symbols whose fully qualified name is the pattern.
+type SymbolSearchHelper interface {
+	// GetQuery returns the query of the `workspace/symbol` request searching
+	// the declarations of the pattern, empty to ask for every symbol
+	GetQuery(pattern string) string
+	// MatchSymbol reports whether the symbol answered by the server is a
+	// declaration of the pattern
+	MatchSymbol(pattern string, symbol protocol.WorkspaceSymbol) bool
+}
+
+// GetMatchingDeclarations returns the declarations of the pattern the server
+// knows, as told by the helper. Unlike GetAllDeclarations, the files are not
+// searched for the pattern when the server does not support
+// `workspace/symbol`.
+func (sc *LSPServiceClientBase) GetMatchingDeclarations(ctx context.Context, helper SymbolSearchHelper, pattern string) []protocol.WorkspaceSymbol {
+	if !sc.ServerCapabilities.Supports("workspace/symbol") {
+		return nil
+	}
+	var symbols []protocol.WorkspaceSymbol
+	params := protocol.WorkspaceSymbolParams{
+		Query: helper.GetQuery(pattern),
+	}
+	err := sc.Call(ctx, "workspace/symbol", params, &symbols)
+	if err != nil {
+		sc.Log.Error(err, "workspace/symbol request failed", "pattern", pattern)
+		return nil
+	}
+	return matchingSymbols(helper, pattern, symbols)
+}
+
+// matchingSymbols keeps the symbols with a location the helper matches with
+// the pattern
+func matchingSymbols(helper SymbolSearchHelper, pattern string, symbols []protocol.WorkspaceSymbol) []protocol.WorkspaceSymbol {
+	matches := []protocol.WorkspaceSymbol{}
+	for _, symbol := range symbols {
+		// servers may answer locations without a range, references can't be
+		// asked for those
+		if _, ok := symbol.Location.Value.(protocol.Location); !ok {
+			continue
+		}
+		if helper.MatchSymbol(pattern, symbol) {
+			matches = append(matches, symbol)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,38 @@
+package base
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+)
+
+// suffixHelper matches the symbols with a name ending with the pattern
+type suffixHelper struct{}
+
+func (suffixHelper) GetQuery(pattern string) string {
+	return pattern
+}
+
+func (suffixHelper) MatchSymbol(pattern string, symbol protocol.WorkspaceSymbol) bool {
+	return strings.HasSuffix(symbol.Name, pattern)
+}
+
+func Test_matchingSymbols(t *testing.T) {
+	located := func(name string) protocol.WorkspaceSymbol {
+		return protocol.WorkspaceSymbol{
+			Location:              protocol.OrPLocation_workspace_symbol{Value: protocol.Location{URI: "file:///app/" + name}},
+			BaseSymbolInformation: protocol.BaseSymbolInformation{Name: name},
+		}
+	}
+	withoutRange := protocol.WorkspaceSymbol{
+		Location:              protocol.OrPLocation_workspace_symbol{Value: protocol.PLocationMsg_workspace_symbol{URI: "file:///app/Foo::Bar"}},
+		BaseSymbolInformation: protocol.BaseSymbolInformation{Name: "Foo::Bar"},
+	}
+	symbols := []protocol.WorkspaceSymbol{located("Foo::Bar"), located("Foo::Baz"), withoutRange}
+
+	got := matchingSymbols(suffixHelper{}, "Bar", symbols)
+	if len(got) != 1 || got[0].Name != "Foo::Bar" {
+		t.Errorf("expected the located Foo::Bar, got %+v", got)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+
+	"github.com/konveyor/analyzer-lsp/process"
 )
 
 // Dialers in jsonrpc2_v2 return a ReadWriteCloser that sends and receives
@@ -12,20 +14,22 @@ import (
 // to the spawned process and as a Dialer that returns itself.
 //
 // NOTE: Dial should only be called once. This is because closing CmdDialer also
-// kills the underlying process
+// kills the underlying process, along with the processes it spawned.
 type CmdDialer struct {
 	Cmd    *exec.Cmd
 	Stdin  io.WriteCloser
 	Stdout io.ReadCloser
 
-	err error
+	group *process.Group
+	err   error
 }
 
 // Create a new CmdDialer
 func NewCmdDialer(ctx context.Context, name string, arg ...string) (*CmdDialer, error) {
 	cmdDialer := CmdDialer{}
 
-	Cmd := exec.CommandContext(ctx, name, arg...)
+	group := process.Command(ctx, name, arg...)
+	Cmd := group.Cmd
 
 	Stdin, err := Cmd.StdinPipe()
 	if err != nil {
@@ -38,7 +42,7 @@ func NewCmdDialer(ctx context.Context, name string, arg ...string) (*CmdDialer,
 	}
 
 	go func() {
-		err := Cmd.Start()
+		err := group.Start()
 		// fmt.Printf("pid: %d\n", cmd.Process.Pid)
 
 		if err != nil {
@@ -48,6 +52,7 @@ func NewCmdDialer(ctx context.Context, name string, arg ...string) (*CmdDialer,
 	}()
 
 	cmdDialer.Cmd = Cmd
+	cmdDialer.group = group
 	cmdDialer.Stdin = Stdin
 	cmdDialer.Stdout = Stdout
 
@@ -69,7 +74,7 @@ func (rwc *CmdDialer) Write(p []byte) (int, error) {
 }
 
 func (rwc *CmdDialer) Close() error {
-	err := rwc.Cmd.Process.Kill()
+	err := rwc.group.Stop()
 	if err != nil {
 		return err
 	}
@@ -28,7 +28,16 @@ func (c *ServerCapabilities) Supports(method string) bool {
 	// case "notebookDocument/didSave":
 	// case "shutdown":
 	// case "telemetry/event":
-	// case "textDocument/codeAction":
+	case "textDocument/codeAction":
+		switch x := c.CodeActionProvider.(type) {
+		case bool:
+			return x
+		case nil:
+			return false
+		default:
+			// CodeActionOptions
+			return true
+		}
 	// case "textDocument/codeLens":
 	// case "textDocument/colorPresentation":
 	// case "textDocument/completion":
@@ -0,0 +1,124 @@
+// Package convert reads the outputs of the analyzer of every API version,
+// up-converting the older ones, and writes outputs with the API version their
+// consumers expect. The structures of the output can evolve in a new version
+// while the consumers of the older ones keep reading them.
+package convert
+
+import (
+	"fmt"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// V1 is a list of rulesets.
+	V1 = "v1"
+	// V2 is a document with the apiVersion, the metadata of the analysis and
+	// the rulesets.
+	V2 = "v2"
+
+	// Latest is the API version outputs are read as.
+	Latest = V2
+)
+
+// Versions are the API versions outputs can be written with
+var Versions = []string{V1, V2}
+
+// upgrade converts a document of a version to the next one
+type upgrade struct {
+	to      string
+	convert func(document interface{}) (interface{}, error)
+}
+
+// upgrades are the conversions of every version but the latest to the next
+// one, older documents go through them in turn
+var upgrades = map[string]upgrade{
+	V1: {to: V2, convert: v1ToV2},
+}
+
+// Read reads an output of any API version as the latest one.
+func Read(content []byte) (konveyor.Output, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return konveyor.Output{}, err
+	}
+	version := Version(document)
+	for version != Latest {
+		u, ok := upgrades[version]
+		if !ok {
+			return konveyor.Output{}, fmt.Errorf("unknown output apiVersion %s, it must be one of %v", version, Versions)
+		}
+		var err error
+		if document, err = u.convert(document); err != nil {
+			return konveyor.Output{}, fmt.Errorf("unable to convert output from %s to %s: %w", version, u.to, err)
+		}
+		version = u.to
+	}
+	b, err := yaml.Marshal(document)
+	if err != nil {
+		return konveyor.Output{}, err
+	}
+	output := konveyor.Output{}
+	if err := yaml.Unmarshal(b, &output); err != nil {
+		return konveyor.Output{}, err
+	}
+	output.APIVersion = Latest
+	return output, nil
+}
+
+// Version returns the API version of a document. Lists of rulesets are V1,
+// documents written with their metadata before the apiVersion was added are
+// V2.
+func Version(document interface{}) string {
+	switch d := document.(type) {
+	case nil, []interface{}:
+		return V1
+	case map[interface{}]interface{}:
+		if version, ok := d["apiVersion"].(string); ok {
+			return version
+		}
+	}
+	return V2
+}
+
+// Marshal writes an output with the API version.
+func Marshal(output konveyor.Output, version string) ([]byte, error) {
+	switch version {
+	case V1:
+		return yaml.Marshal(output.RuleSets)
+	case V2:
+		output.APIVersion = V2
+		return yaml.Marshal(output)
+	default:
+		return nil, fmt.Errorf("unknown output apiVersion %s, it must be one of %v", version, Versions)
+	}
+}
+
+// v1ToV2 puts the rulesets in a document, the errors of rules that are only
+// messages become unknown errors
+func v1ToV2(document interface{}) (interface{}, error) {
+	ruleSets, _ := document.([]interface{})
+	for _, rs := range ruleSets {
+		ruleSet, ok := rs.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("ruleset must be a map, not %T", rs)
+		}
+		errors, _ := ruleSet["errors"].(map[interface{}]interface{})
+		for ruleID, e := range errors {
+			if message, ok := e.(string); ok {
+				errors[ruleID] = map[interface{}]interface{}{
+					"code":    string(konveyor.UnknownError),
+					"message": message,
+				}
+			}
+		}
+	}
+	if ruleSets == nil {
+		ruleSets = []interface{}{}
+	}
+	return map[interface{}]interface{}{
+		"apiVersion": V2,
+		"rulesets":   ruleSets,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package convert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestRead(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	metadata := &konveyor.Metadata{
+		AnalyzerVersion: "v0.6.0",
+		Providers:       []konveyor.ProviderMetadata{{Name: "builtin", ConfigHash: "sha256:0a"}},
+		StartTime:       start,
+		EndTime:         start.Add(time.Minute),
+		Host:            konveyor.HostMetadata{OS: "linux", Arch: "amd64", CPUs: 4},
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    konveyor.Output
+		wantErr string
+	}{
+		{
+			name: "v1 with errors written as messages",
+			content: `
+- name: rules
+  errors:
+    rule-001: unable to get query info
+  unmatched:
+  - rule-002
+`,
+			want: konveyor.Output{APIVersion: V2, RuleSets: []konveyor.RuleSet{{
+				Name:      "rules",
+				Errors:    map[string]konveyor.RuleError{"rule-001": {Code: konveyor.UnknownError, Message: "unable to get query info"}},
+				Unmatched: []string{"rule-002"},
+			}}},
+		},
+		{
+			name: "v1 with classified errors",
+			content: `
+- name: rules
+  errors:
+    rule-001:
+      code: ParseError
+      provider: builtin
+      message: unable to get query info
+`,
+			want: konveyor.Output{APIVersion: V2, RuleSets: []konveyor.RuleSet{{
+				Name:   "rules",
+				Errors: map[string]konveyor.RuleError{"rule-001": {Code: konveyor.ParseError, Provider: "builtin", Message: "unable to get query info"}},
+			}}},
+		},
+		{
+			name:    "empty v1",
+			content: "[]",
+			want:    konveyor.Output{APIVersion: V2, RuleSets: []konveyor.RuleSet{}},
+		},
+		{
+			name: "metadata without apiVersion",
+			content: `
+metadata:
+  analyzerVersion: v0.6.0
+  providers:
+  - name: builtin
+    configHash: sha256:0a
+  startTime: 2024-05-01T10:00:00Z
+  endTime: 2024-05-01T10:01:00Z
+  host:
+    os: linux
+    arch: amd64
+    cpus: 4
+rulesets:
+- name: rules
+`,
+			want: konveyor.Output{APIVersion: V2, Metadata: metadata, RuleSets: []konveyor.RuleSet{{Name: "rules"}}},
+		},
+		{
+			name: "v2",
+			content: `
+apiVersion: v2
+rulesets:
+- name: rules
+  skipped:
+  - rule-001
+`,
+			want: konveyor.Output{APIVersion: V2, RuleSets: []konveyor.RuleSet{{Name: "rules", Skipped: []string{"rule-001"}}}},
+		},
+		{
+			name:    "unknown version",
+			content: "apiVersion: v9\nrulesets: []\n",
+			wantErr: "unknown output apiVersion v9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read([]byte(tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	output := konveyor.Output{
+		Metadata: &konveyor.Metadata{AnalyzerVersion: "v0.6.0"},
+		RuleSets: []konveyor.RuleSet{{Name: "rules", Unmatched: []string{"rule-001"}}},
+	}
+	for _, version := range Versions {
+		b, err := Marshal(output, version)
+		if err != nil {
+			t.Fatalf("unexpected error writing %s: %v", version, err)
+		}
+		read, err := Read(b)
+		if err != nil {
+			t.Fatalf("unexpected error reading %s: %v", version, err)
+		}
+		if !reflect.DeepEqual(read.RuleSets, output.RuleSets) {
+			t.Errorf("expected the rulesets written with %s to be read back, got %+v", version, read.RuleSets)
+		}
+	}
+	b, _ := Marshal(output, V1)
+	if !strings.HasPrefix(string(b), "- name: rules") {
+		t.Errorf("expected v1 to be a list of rulesets, got\n%s", b)
+	}
+	b, _ = Marshal(output, V2)
+	if !strings.HasPrefix(string(b), "apiVersion: v2\nmetadata:") {
+		t.Errorf("expected v2 to be a document with the apiVersion and metadata, got\n%s", b)
+	}
+	if _, err := Marshal(output, "v3"); err == nil {
+		t.Errorf("expected an error writing an unknown version")
+	}
+}
@@ -0,0 +1,305 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+// Format is the encoding outputs are written with
+type Format string
+
+const (
+	YAML Format = "yaml"
+	JSON Format = "json"
+)
+
+// Formats are the encodings outputs can be written with, both are read by Read
+var Formats = []Format{YAML, JSON}
+
+// WriteOptions tell how an output is written
+type WriteOptions struct {
+	// Version is the API version of the output
+	Version string
+	// Format is the encoding of the output, YAML by default
+	Format Format
+	// Gzip compresses the output
+	Gzip bool
+	// ChunkByRuleSet writes every ruleset to a file of its own, in the
+	// directory of the output, see WriteFile
+	ChunkByRuleSet bool
+}
+
+func (o WriteOptions) validate() error {
+	if !slices.Contains(Versions, o.Version) {
+		return fmt.Errorf("unknown output apiVersion %s, it must be one of %v", o.Version, Versions)
+	}
+	if o.Format != "" && o.Format != YAML && o.Format != JSON {
+		return fmt.Errorf("unknown output format %s, it must be one of %v", o.Format, Formats)
+	}
+	return nil
+}
+
+// Write streams the output to w one ruleset at a time, unlike Marshal the
+// whole document is never held in memory. The YAML written is the one of
+// Marshal.
+func Write(w io.Writer, output konveyor.Output, opts WriteOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if opts.Gzip {
+		gz := gzip.NewWriter(w)
+		if err := write(gz, output, opts); err != nil {
+			gz.Close()
+			return err
+		}
+		return gz.Close()
+	}
+	return write(w, output, opts)
+}
+
+func write(w io.Writer, output konveyor.Output, opts WriteOptions) error {
+	buffered := bufio.NewWriter(w)
+	var err error
+	if opts.Format == JSON {
+		err = writeJSON(buffered, output, opts.Version)
+	} else {
+		err = writeYAML(buffered, output, opts.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+func writeYAML(w io.Writer, output konveyor.Output, version string) error {
+	if version == V2 {
+		header := yaml.MapSlice{{Key: "apiVersion", Value: V2}}
+		if output.Metadata != nil {
+			header = append(header, yaml.MapItem{Key: "metadata", Value: output.Metadata})
+		}
+		if err := encodeYAML(w, header); err != nil {
+			return err
+		}
+		if len(output.RuleSets) == 0 {
+			_, err := io.WriteString(w, "rulesets: []\n")
+			return err
+		}
+		if _, err := io.WriteString(w, "rulesets:\n"); err != nil {
+			return err
+		}
+	} else if len(output.RuleSets) == 0 {
+		_, err := io.WriteString(w, "[]\n")
+		return err
+	}
+	for _, ruleSet := range output.RuleSets {
+		// a list of one ruleset is an item of the list of rulesets
+		if err := encodeYAML(w, []konveyor.RuleSet{ruleSet}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeYAML encodes the value as a document of its own, an encoder writes
+// a document separator before every document but the first one
+func encodeYAML(w io.Writer, v interface{}) error {
+	encoder := yaml.NewEncoder(w)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func writeJSON(w io.Writer, output konveyor.Output, version string) error {
+	if version == V2 {
+		if _, err := io.WriteString(w, `{"apiVersion":"v2",`); err != nil {
+			return err
+		}
+		if output.Metadata != nil {
+			b, err := json.Marshal(output.Metadata)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, `"metadata":%s,`, b); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, `"rulesets":`); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, ruleSet := range output.RuleSets {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		b, err := json.Marshal(ruleSet)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	end := "]\n"
+	if version == V2 {
+		end = "]}\n"
+	}
+	_, err := io.WriteString(w, end)
+	return err
+}
+
+var (
+	invalidChunkNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+	chunkName             = regexp.MustCompile(`^[0-9]{4}-.*\.(yaml|json)(\.gz)?$`)
+)
+
+// WriteFile writes the output to the file at path. Outputs chunked by ruleset
+// are written to the directory at path instead, every ruleset to an output
+// file of its own numbered in the order of the rulesets, such as
+// 0001-<ruleset>.yaml, along with the metadata in 0000-metadata.yaml.
+// ReadFile reads both back.
+func WriteFile(path string, output konveyor.Output, opts WriteOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if !opts.ChunkByRuleSet {
+		return writeFile(path, output, opts)
+	}
+	if err := removeChunks(path); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	ext := "." + string(YAML)
+	if opts.Format == JSON {
+		ext = "." + string(JSON)
+	}
+	if opts.Gzip {
+		ext += ".gz"
+	}
+	if output.Metadata != nil && opts.Version != V1 {
+		metadata := konveyor.Output{Metadata: output.Metadata}
+		if err := writeFile(filepath.Join(path, "0000-metadata"+ext), metadata, opts); err != nil {
+			return err
+		}
+	}
+	for i, ruleSet := range output.RuleSets {
+		chunk := konveyor.Output{RuleSets: []konveyor.RuleSet{ruleSet}}
+		name := fmt.Sprintf("%04d-%s%s", i+1, invalidChunkNameChars.ReplaceAllString(ruleSet.Name, "_"), ext)
+		if err := writeFile(filepath.Join(path, name), chunk, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeChunks removes the output file, or the chunks of the output written
+// to the directory before, the other files of the directory are left alone
+func removeChunks(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return os.Remove(path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && chunkName.MatchString(entry.Name()) {
+			if err := os.Remove(filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, output konveyor.Output, opts WriteOptions) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, output, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadFile reads an output of any API version written by WriteFile, the
+// gzip compressed outputs and the directories of outputs chunked by ruleset
+// too, as the latest version.
+func ReadFile(path string) (konveyor.Output, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return konveyor.Output{}, err
+	}
+	if !info.IsDir() {
+		return readFile(path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return konveyor.Output{}, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && chunkName.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	output := konveyor.Output{APIVersion: Latest, RuleSets: []konveyor.RuleSet{}}
+	for _, name := range names {
+		chunk, err := readFile(filepath.Join(path, name))
+		if err != nil {
+			return konveyor.Output{}, fmt.Errorf("unable to read output chunk %s: %w", name, err)
+		}
+		if output.Metadata == nil {
+			output.Metadata = chunk.Metadata
+		}
+		output.RuleSets = append(output.RuleSets, chunk.RuleSets...)
+	}
+	return output, nil
+}
+
+func readFile(path string) (konveyor.Output, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return konveyor.Output{}, err
+	}
+	// the magic number of gzip
+	if bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return konveyor.Output{}, err
+		}
+		defer gz.Close()
+		if content, err = io.ReadAll(gz); err != nil {
+			return konveyor.Output{}, err
+		}
+	}
+	return Read(content)
+}
@@ -0,0 +1,97 @@
+package convert
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestWrite(t *testing.T) {
+	line := 12
+	mandatory := konveyor.Mandatory
+	outputs := []konveyor.Output{
+		{
+			Metadata: &konveyor.Metadata{AnalyzerVersion: "v0.6.0", LabelSelector: "konveyor.io/target=quarkus", FeatureFlags: map[string]bool{"someFeature": true}},
+			RuleSets: []konveyor.RuleSet{
+				{
+					Name: "eap",
+					Violations: map[string]konveyor.Violation{"eap-1": {
+						Category:  &mandatory,
+						Incidents: []konveyor.Incident{{URI: "file:///app/Main.java", LineNumber: &line, Message: "replace: it"}},
+					}},
+				},
+				{Name: "quarkus", Unmatched: []string{"quarkus-1"}},
+			},
+		},
+		{Metadata: &konveyor.Metadata{AnalyzerVersion: "v0.6.0"}},
+	}
+	for _, output := range outputs {
+		for _, version := range Versions {
+			want, _ := Marshal(output, version)
+			buf := &bytes.Buffer{}
+			if err := Write(buf, output, WriteOptions{Version: version}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if buf.String() != string(want) {
+				t.Errorf("Write() of %s =\n%s\nwant the output of Marshal\n%s", version, buf, want)
+			}
+
+			for _, opts := range []WriteOptions{
+				{Version: version, Format: JSON},
+				{Version: version, Gzip: true},
+				{Version: version, Format: JSON, Gzip: true, ChunkByRuleSet: true},
+			} {
+				path := filepath.Join(t.TempDir(), "output")
+				if err := WriteFile(path, output, opts); err != nil {
+					t.Fatalf("WriteFile() with %+v error = %v", opts, err)
+				}
+				read, err := ReadFile(path)
+				if err != nil {
+					t.Fatalf("ReadFile() with %+v error = %v", opts, err)
+				}
+				wantRuleSets := output.RuleSets
+				if wantRuleSets == nil {
+					wantRuleSets = []konveyor.RuleSet{}
+				}
+				if !reflect.DeepEqual(read.RuleSets, wantRuleSets) {
+					t.Errorf("ReadFile() with %+v = %+v, want %+v", opts, read.RuleSets, wantRuleSets)
+				}
+				if version == V2 && !reflect.DeepEqual(read.Metadata, output.Metadata) {
+					t.Errorf("ReadFile() with %+v metadata = %+v, want %+v", opts, read.Metadata, output.Metadata)
+				}
+			}
+		}
+	}
+	if err := Write(&bytes.Buffer{}, konveyor.Output{}, WriteOptions{Version: V1, Format: "xml"}); err == nil {
+		t.Errorf("expected an error writing an unknown format")
+	}
+}
+
+func TestWriteFileChunks(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(other, []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := WriteOptions{Version: V1, ChunkByRuleSet: true}
+	output := konveyor.Output{RuleSets: []konveyor.RuleSet{{Name: "eap/rules"}, {Name: "quarkus"}}}
+	if err := WriteFile(dir, output, opts); err != nil {
+		t.Fatal(err)
+	}
+	output.RuleSets = output.RuleSets[1:]
+	if err := WriteFile(dir, output, opts); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"0001-quarkus.yaml", "notes.txt"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("WriteFile() wrote %v, want the chunks of the previous output replaced and the other files kept %v", names, want)
+	}
+}
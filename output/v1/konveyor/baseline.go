@@ -0,0 +1,119 @@
+package konveyor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Baseline are the fingerprints of the incidents of a previous output, the
+// incidents of a new output with the same fingerprints are not new.
+type Baseline struct {
+	// fingerprints counts the incidents of every fingerprint, a file with the
+	// same incident twice in the baseline and three times now has a new one
+	fingerprints map[string]int
+}
+
+// NewBaseline creates the baseline of the violations and insights of the
+// rulesets of an output.
+func NewBaseline(ruleSets []RuleSet) Baseline {
+	b := Baseline{fingerprints: map[string]int{}}
+	for _, ruleSet := range ruleSets {
+		for _, violations := range []map[string]Violation{ruleSet.Violations, ruleSet.Insights} {
+			for id, violation := range violations {
+				for _, incident := range violation.Incidents {
+					b.fingerprints[IncidentFingerprint(ruleSet.Name, id, incident)]++
+				}
+			}
+		}
+	}
+	return b
+}
+
+// Apply marks the incidents of the rulesets that are in the baseline. With
+// onlyNew they are removed instead, along with the violations left without
+// incidents. It returns the number of incidents found in the baseline.
+func (b Baseline) Apply(ruleSets []RuleSet, onlyNew bool) int {
+	remaining := map[string]int{}
+	for k, v := range b.fingerprints {
+		remaining[k] = v
+	}
+	found := 0
+	for _, ruleSet := range ruleSets {
+		for _, violations := range []map[string]Violation{ruleSet.Violations, ruleSet.Insights} {
+			// incidents are matched in a stable order, which of the same
+			// incidents are new does not depend on the order of the providers
+			ids := []string{}
+			for id := range violations {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			for _, id := range ids {
+				violation := violations[id]
+				violation.sortFields()
+				incidents := []Incident{}
+				for _, incident := range violation.Incidents {
+					fingerprint := IncidentFingerprint(ruleSet.Name, id, incident)
+					if remaining[fingerprint] > 0 {
+						remaining[fingerprint]--
+						found++
+						if onlyNew {
+							continue
+						}
+						incident.Baseline = true
+					}
+					incidents = append(incidents, incident)
+				}
+				if len(incidents) == 0 {
+					delete(violations, id)
+					continue
+				}
+				violation.Incidents = incidents
+				violations[id] = violation
+			}
+		}
+	}
+	return found
+}
+
+// IncidentFingerprint identifies an incident of a rule across analyses of
+// different versions of an application. The line of code matched is part of
+// it rather than the line number, the incident keeps its fingerprint when
+// lines are added or removed above it. The line number is used when there is
+// no code snip.
+func IncidentFingerprint(ruleSetName, ruleID string, incident Incident) string {
+	location := ""
+	if line, ok := matchedLine(incident); ok {
+		location = "code:" + strings.TrimSpace(line)
+	} else if incident.LineNumber != nil {
+		location = fmt.Sprintf("line:%d", *incident.LineNumber)
+	}
+	h := sha256.New()
+	for _, part := range []string{ruleSetName, ruleID, string(incident.URI), incident.Message, location} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// matchedLine returns the line of the incident in its code snip, the lines
+// of code snips start with their line number
+func matchedLine(incident Incident) (string, bool) {
+	if incident.LineNumber == nil || incident.CodeSnip == "" {
+		return "", false
+	}
+	for _, line := range strings.Split(incident.CodeSnip, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		number, code, ok := strings.Cut(trimmed, "  ")
+		if !ok {
+			number, code = trimmed, ""
+		}
+		if n, err := strconv.Atoi(number); err == nil && n == *incident.LineNumber {
+			return code, true
+		}
+	}
+	return "", false
+}
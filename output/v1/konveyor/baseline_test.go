@@ -0,0 +1,70 @@
+package konveyor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	line := func(n int) *int { return &n }
+	previous := []RuleSet{{
+		Name: "rules",
+		Violations: map[string]Violation{
+			"ejb-00001": {Incidents: []Incident{
+				{URI: "file:///app/Main.java", Message: "EJB", LineNumber: line(3), CodeSnip: " 2  package app;\n 3  import javax.ejb.Stateless;\n 4  "},
+				{URI: "file:///app/pom.xml", Message: "EJB"},
+			}},
+		},
+	}}
+	current := func() []RuleSet {
+		return []RuleSet{{
+			Name: "rules",
+			Violations: map[string]Violation{
+				"ejb-00001": {Incidents: []Incident{
+					// moved down a line
+					{URI: "file:///app/Main.java", Message: "EJB", LineNumber: line(4), CodeSnip: " 3  \n 4  import javax.ejb.Stateless;\n 5  "},
+					{URI: "file:///app/Main.java", Message: "EJB", LineNumber: line(5), CodeSnip: " 5  import javax.ejb.Remote;"},
+					{URI: "file:///app/pom.xml", Message: "EJB"},
+				}},
+				"ejb-00002": {Incidents: []Incident{
+					{URI: "file:///app/pom.xml", Message: "EJB"},
+				}},
+			},
+		}}
+	}
+	baseline := NewBaseline(previous)
+
+	marked := current()
+	if found := baseline.Apply(marked, false); found != 2 {
+		t.Errorf("expected 2 incidents in the baseline, got %d", found)
+	}
+	got := map[string]bool{}
+	for _, incident := range marked[0].Violations["ejb-00001"].Incidents {
+		got[string(incident.URI)+":"+incident.CodeSnip] = incident.Baseline
+	}
+	want := map[string]bool{
+		"file:///app/Main.java: 3  \n 4  import javax.ejb.Stateless;\n 5  ": true,
+		"file:///app/Main.java: 5  import javax.ejb.Remote;":                false,
+		"file:///app/pom.xml:": true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected baseline incidents %v, got %v", want, got)
+	}
+	if marked[0].Violations["ejb-00002"].Incidents[0].Baseline {
+		t.Errorf("expected the incidents of another rule not to be in the baseline")
+	}
+
+	onlyNew := current()
+	baseline.Apply(onlyNew, true)
+	if incidents := onlyNew[0].Violations["ejb-00001"].Incidents; len(incidents) != 1 || *incidents[0].LineNumber != 5 {
+		t.Errorf("expected only the new incident, got %v", incidents)
+	}
+
+	onlyNew = []RuleSet{{Name: "rules", Violations: map[string]Violation{
+		"ejb-00001": {Incidents: []Incident{{URI: "file:///app/pom.xml", Message: "EJB"}}},
+	}}}
+	baseline.Apply(onlyNew, true)
+	if _, ok := onlyNew[0].Violations["ejb-00001"]; ok {
+		t.Errorf("expected the violation without new incidents to be removed")
+	}
+}
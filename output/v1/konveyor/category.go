@@ -0,0 +1,75 @@
+package konveyor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Categories of the violations, from the one that must be fixed for the
+// migration to the ones that may not need to be
+var Categories = []Category{Mandatory, Optional, Potential}
+
+// Valid reports whether the category is one of Categories
+func (c Category) Valid() bool {
+	for _, category := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCategories parses a comma separated list of categories, the
+// categories are not case sensitive
+func ParseCategories(text string) ([]Category, error) {
+	categories := []Category{}
+	for _, value := range strings.Split(text, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		c := Category(strings.ToLower(value))
+		if !c.Valid() {
+			return nil, fmt.Errorf("category must be one of %v, not %s", Categories, value)
+		}
+		categories = append(categories, c)
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("no category in %q, must be a comma separated list of %v", text, Categories)
+	}
+	return categories, nil
+}
+
+// PartitionByCategory returns the rulesets with the violations of every
+// category, in rulesets of the same names and descriptions. A category
+// without violations has no rulesets, the other results of the rules are
+// left out.
+func PartitionByCategory(ruleSets []RuleSet) map[Category][]RuleSet {
+	partitions := map[Category][]RuleSet{}
+	for _, category := range Categories {
+		partitions[category] = []RuleSet{}
+	}
+	for _, ruleSet := range ruleSets {
+		violations := map[Category]map[string]Violation{}
+		for id, violation := range ruleSet.Violations {
+			if violation.Category == nil {
+				continue
+			}
+			if violations[*violation.Category] == nil {
+				violations[*violation.Category] = map[string]Violation{}
+			}
+			violations[*violation.Category][id] = violation
+		}
+		for _, category := range Categories {
+			if len(violations[category]) == 0 {
+				continue
+			}
+			partitions[category] = append(partitions[category], RuleSet{
+				Name:        ruleSet.Name,
+				Description: ruleSet.Description,
+				Violations:  violations[category],
+			})
+		}
+	}
+	return partitions
+}
@@ -0,0 +1,50 @@
+package konveyor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCategories(t *testing.T) {
+	got, err := ParseCategories("Mandatory, optional")
+	if err != nil || !reflect.DeepEqual(got, []Category{Mandatory, Optional}) {
+		t.Errorf("ParseCategories() = %v %v, want mandatory and optional", got, err)
+	}
+	for _, text := range []string{"required", " , "} {
+		if _, err := ParseCategories(text); err == nil {
+			t.Errorf("ParseCategories(%q) must fail", text)
+		}
+	}
+}
+
+func TestPartitionByCategory(t *testing.T) {
+	mandatory, potential := Mandatory, Potential
+	ruleSets := []RuleSet{
+		{
+			Name:        "eap",
+			Description: "EAP rules",
+			Violations: map[string]Violation{
+				"eap-1": {Category: &mandatory},
+				"eap-2": {Category: &potential},
+				"eap-3": {},
+			},
+			Insights:  map[string]Violation{"eap-tag": {}},
+			Unmatched: []string{"eap-4"},
+		},
+		{
+			Name:       "quarkus",
+			Violations: map[string]Violation{"quarkus-1": {Category: &mandatory}},
+		},
+	}
+	want := map[Category][]RuleSet{
+		Mandatory: {
+			{Name: "eap", Description: "EAP rules", Violations: map[string]Violation{"eap-1": {Category: &mandatory}}},
+			{Name: "quarkus", Violations: map[string]Violation{"quarkus-1": {Category: &mandatory}}},
+		},
+		Optional:  {},
+		Potential: {{Name: "eap", Description: "EAP rules", Violations: map[string]Violation{"eap-2": {Category: &potential}}}},
+	}
+	if got := PartitionByCategory(ruleSets); !reflect.DeepEqual(got, want) {
+		t.Errorf("PartitionByCategory() = %v, want %v", got, want)
+	}
+}
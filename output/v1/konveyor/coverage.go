@@ -0,0 +1,89 @@
+package konveyor
+
+import "sort"
+
+// CoverageReport lists the rules that did not contribute to the analysis, to
+// find the rules that are obsolete or can't run in the rulesets.
+type CoverageReport struct {
+	// Runs is the number of analyses the report was created from, the
+	// current one and the previous outputs given
+	Runs int `yaml:"runs" json:"runs"`
+	// Skipped are the rules not selected by the selectors of the current run
+	Skipped []CoverageRule `yaml:"skipped,omitempty" json:"skipped,omitempty"`
+	// UnavailableProviders are the rules that were not loaded as they use
+	// providers or capabilities that were not configured
+	UnavailableProviders []CoverageRule `yaml:"unavailableProviders,omitempty" json:"unavailableProviders,omitempty"`
+	// NeverMatched are the rules evaluated in the current run that matched in
+	// none of the runs
+	NeverMatched []CoverageRule `yaml:"neverMatched,omitempty" json:"neverMatched,omitempty"`
+}
+
+// CoverageRule is a rule of the coverage report
+type CoverageRule struct {
+	RuleSet string `yaml:"ruleSet,omitempty" json:"ruleSet,omitempty"`
+	RuleID  string `yaml:"ruleID" json:"ruleID"`
+	// File of the rule, for the rules that were not loaded
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+	// Providers are the provider conditions that can't be evaluated, as
+	// <provider>.<capability>
+	Providers []string `yaml:"providers,omitempty" json:"providers,omitempty"`
+	// UnmatchedRuns is the number of runs the rule was evaluated in without
+	// matching
+	UnmatchedRuns int `yaml:"unmatchedRuns,omitempty" json:"unmatchedRuns,omitempty"`
+}
+
+// NewCoverageReport creates the coverage report of the current output from
+// the outputs of previous runs of the same rules, a rule matching in any of
+// them is not reported as never matched. Matched tagging rules are not part
+// of the outputs, a tagging rule matching in a previous run without being
+// evaluated in the others can't be told apart from one that never ran there.
+func NewCoverageReport(current []RuleSet, previous ...[]RuleSet) CoverageReport {
+	report := CoverageReport{Runs: 1 + len(previous)}
+
+	matched := map[string]bool{}
+	unmatchedRuns := map[string]int{}
+	for _, run := range append([][]RuleSet{current}, previous...) {
+		for _, ruleSet := range run {
+			for id := range ruleSet.Violations {
+				matched[id] = true
+			}
+			for id := range ruleSet.Insights {
+				matched[id] = true
+			}
+			for id := range ruleSet.Suppressed {
+				matched[id] = true
+			}
+			for _, id := range ruleSet.Unmatched {
+				unmatchedRuns[id]++
+			}
+		}
+	}
+
+	for _, ruleSet := range current {
+		for _, id := range ruleSet.Skipped {
+			report.Skipped = append(report.Skipped, CoverageRule{RuleSet: ruleSet.Name, RuleID: id})
+		}
+		for _, id := range ruleSet.Unmatched {
+			if matched[id] {
+				continue
+			}
+			report.NeverMatched = append(report.NeverMatched, CoverageRule{
+				RuleSet:       ruleSet.Name,
+				RuleID:        id,
+				UnmatchedRuns: unmatchedRuns[id],
+			})
+		}
+	}
+	sortCoverageRules(report.Skipped)
+	sortCoverageRules(report.NeverMatched)
+	return report
+}
+
+func sortCoverageRules(rules []CoverageRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].RuleSet != rules[j].RuleSet {
+			return rules[i].RuleSet < rules[j].RuleSet
+		}
+		return rules[i].RuleID < rules[j].RuleID
+	})
+}
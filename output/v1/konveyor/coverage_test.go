@@ -0,0 +1,31 @@
+package konveyor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewCoverageReport(t *testing.T) {
+	current := []RuleSet{{
+		Name:       "rules",
+		Violations: map[string]Violation{"matched-001": {}},
+		Unmatched:  []string{"unmatched-002", "unmatched-001", "matched-before-001"},
+		Skipped:    []string{"skipped-001"},
+	}}
+	previous := []RuleSet{{
+		Name:      "rules",
+		Insights:  map[string]Violation{"matched-before-001": {}},
+		Unmatched: []string{"unmatched-001"},
+	}}
+	want := CoverageReport{
+		Runs:    2,
+		Skipped: []CoverageRule{{RuleSet: "rules", RuleID: "skipped-001"}},
+		NeverMatched: []CoverageRule{
+			{RuleSet: "rules", RuleID: "unmatched-001", UnmatchedRuns: 2},
+			{RuleSet: "rules", RuleID: "unmatched-002", UnmatchedRuns: 1},
+		},
+	}
+	if got := NewCoverageReport(current, previous); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,59 @@
+package konveyor
+
+import "math"
+
+// EffortModel is the curve used to scale the effort of a violation with the
+// number of its incidents.
+type EffortModel string
+
+const (
+	// LinearEffortModel counts the full effort for every incident, this is the raw effort.
+	LinearEffortModel EffortModel = "linear"
+	// LogEffortModel grows logarithmically, effort * (1 + ln(incidents)),
+	// fixing the same issue many times gets cheaper after the first ones.
+	LogEffortModel EffortModel = "log"
+	// SqrtEffortModel grows with the square root, effort * sqrt(incidents).
+	SqrtEffortModel EffortModel = "sqrt"
+)
+
+var EffortModels = []EffortModel{
+	LinearEffortModel,
+	LogEffortModel,
+	SqrtEffortModel,
+}
+
+// Weigh returns the effort of a violation with the given number of incidents
+func (m EffortModel) Weigh(effort, incidents int) float64 {
+	if incidents <= 0 || effort <= 0 {
+		return 0
+	}
+	var weighted float64
+	switch m {
+	case LogEffortModel:
+		weighted = float64(effort) * (1 + math.Log(float64(incidents)))
+	case SqrtEffortModel:
+		weighted = float64(effort) * math.Sqrt(float64(incidents))
+	default:
+		weighted = float64(effort * incidents)
+	}
+	// keep the output stable and readable
+	return math.Round(weighted*100) / 100
+}
+
+// ApplyEffortModel sets the weighted effort of every violation with an effort.
+// The linear model is the raw effort, it leaves the violations unchanged.
+func ApplyEffortModel(ruleSets []RuleSet, model EffortModel) {
+	if model == "" || model == LinearEffortModel {
+		return
+	}
+	for _, ruleSet := range ruleSets {
+		for id, violation := range ruleSet.Violations {
+			if violation.Effort == nil {
+				continue
+			}
+			weighted := model.Weigh(*violation.Effort, len(violation.Incidents))
+			violation.WeightedEffort = &weighted
+			ruleSet.Violations[id] = violation
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package konveyor
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v2"
+)
+
+// WeightedEffortCalculator is the effort calculator used when the effort
+// config names none
+const WeightedEffortCalculator = "weighted"
+
+// Variables of the effort expressions
+const (
+	effortExprRuleSet   = "ruleSet"
+	effortExprRuleID    = "ruleID"
+	effortExprCategory  = "category"
+	effortExprLabels    = "labels"
+	effortExprEffort    = "effort"
+	effortExprIncidents = "incidents"
+)
+
+// EffortConfig calibrates the effort score of an application, read from the
+// file given with --effort-config.
+type EffortConfig struct {
+	// Calculator is the name of the effort calculator, weighted or one
+	// registered by a program embedding the analyzer
+	Calculator string `yaml:"calculator,omitempty"`
+	// CategoryWeights multiply the effort of the violations of the category,
+	// the violations of the other categories and without a category weigh 1
+	CategoryWeights map[Category]float64 `yaml:"categoryWeights,omitempty"`
+	// IncidentCap is the most incidents of a violation counted in its effort,
+	// zero means no cap
+	IncidentCap int `yaml:"incidentCap,omitempty"`
+	// Expression is a CEL expression computing the effort of a violation, a
+	// double, from its ruleSet, ruleID, category, labels, effort and
+	// incidents, the number of incidents after the cap
+	Expression string `yaml:"expression,omitempty"`
+	// Options configure the registered calculators
+	Options map[string]interface{} `yaml:"options,omitempty"`
+
+	// Model scales the effort of the violations when there is no expression,
+	// the effort model of the analysis
+	Model EffortModel `yaml:"-"`
+}
+
+// EffortScore is the effort of an application computed by an effort
+// calculator
+type EffortScore struct {
+	Calculator string  `yaml:"calculator" json:"calculator"`
+	Score      float64 `yaml:"score" json:"score"`
+}
+
+// EffortCalculator computes the effort score of the rulesets of an
+// application
+type EffortCalculator interface {
+	Score(ruleSets []RuleSet) (float64, error)
+}
+
+// EffortCalculatorFactory creates an effort calculator from the effort config
+type EffortCalculatorFactory func(config EffortConfig) (EffortCalculator, error)
+
+var (
+	effortCalculatorsMutex sync.RWMutex
+	effortCalculators      = map[string]EffortCalculatorFactory{
+		WeightedEffortCalculator: newWeightedEffortCalculator,
+	}
+)
+
+// RegisterEffortCalculator makes an effort calculator available by name to
+// the effort configs. Names can only be registered once.
+func RegisterEffortCalculator(name string, factory EffortCalculatorFactory) error {
+	effortCalculatorsMutex.Lock()
+	defer effortCalculatorsMutex.Unlock()
+	if _, ok := effortCalculators[name]; ok {
+		return fmt.Errorf("effort calculator %s is already registered", name)
+	}
+	effortCalculators[name] = factory
+	return nil
+}
+
+// RegisteredEffortCalculators returns the sorted names of the registered
+// effort calculators
+func RegisteredEffortCalculators() []string {
+	effortCalculatorsMutex.RLock()
+	defer effortCalculatorsMutex.RUnlock()
+	names := []string{}
+	for name := range effortCalculators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadEffortConfig reads the effort config file
+func LoadEffortConfig(path string) (EffortConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return EffortConfig{}, err
+	}
+	config := EffortConfig{}
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return EffortConfig{}, fmt.Errorf("unable to read the effort config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// NewEffortCalculator creates the calculator of the config
+func NewEffortCalculator(config EffortConfig) (EffortCalculator, string, error) {
+	name := config.Calculator
+	if name == "" {
+		name = WeightedEffortCalculator
+	}
+	for category, weight := range config.CategoryWeights {
+		if !category.Valid() {
+			return nil, "", fmt.Errorf("unknown category %s in the category weights, must be one of %v", category, Categories)
+		}
+		if weight < 0 {
+			return nil, "", fmt.Errorf("the weight of category %s must not be negative", category)
+		}
+	}
+	if config.IncidentCap < 0 {
+		return nil, "", fmt.Errorf("the incident cap must not be negative")
+	}
+	effortCalculatorsMutex.RLock()
+	factory, ok := effortCalculators[name]
+	effortCalculatorsMutex.RUnlock()
+	if !ok {
+		return nil, "", fmt.Errorf("unknown effort calculator %s, must be one of %v", name, RegisteredEffortCalculators())
+	}
+	calculator, err := factory(config)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to create effort calculator %s: %w", name, err)
+	}
+	return calculator, name, nil
+}
+
+// NewEffortScore computes the effort score of the rulesets
+func NewEffortScore(ruleSets []RuleSet, config EffortConfig) (*EffortScore, error) {
+	calculator, name, err := NewEffortCalculator(config)
+	if err != nil {
+		return nil, err
+	}
+	score, err := calculator.Score(ruleSets)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compute the effort with calculator %s: %w", name, err)
+	}
+	return &EffortScore{Calculator: name, Score: math.Round(score*100) / 100}, nil
+}
+
+// weightedEffortCalculator sums the effort of the violations, weighted by
+// their category, computed by the expression or the effort model from the
+// incidents under the cap
+type weightedEffortCalculator struct {
+	config  EffortConfig
+	program cel.Program
+}
+
+func newWeightedEffortCalculator(config EffortConfig) (EffortCalculator, error) {
+	calculator := &weightedEffortCalculator{config: config}
+	if config.Expression == "" {
+		return calculator, nil
+	}
+	env, err := cel.NewEnv(
+		cel.Variable(effortExprRuleSet, cel.StringType),
+		cel.Variable(effortExprRuleID, cel.StringType),
+		cel.Variable(effortExprCategory, cel.StringType),
+		cel.Variable(effortExprLabels, cel.ListType(cel.StringType)),
+		cel.Variable(effortExprEffort, cel.IntType),
+		cel.Variable(effortExprIncidents, cel.IntType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create expression environment: %w", err)
+	}
+	ast, issues := env.Compile(config.Expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.DoubleType {
+		return nil, fmt.Errorf("expression must be a double, not %s", ast.OutputType())
+	}
+	if calculator.program, err = env.Program(ast); err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	return calculator, nil
+}
+
+func (w *weightedEffortCalculator) Score(ruleSets []RuleSet) (float64, error) {
+	score := 0.0
+	for _, ruleSet := range ruleSets {
+		for id, violation := range ruleSet.Violations {
+			incidents := len(violation.Incidents)
+			if w.config.IncidentCap > 0 && incidents > w.config.IncidentCap {
+				incidents = w.config.IncidentCap
+			}
+			effort := 0
+			if violation.Effort != nil {
+				effort = *violation.Effort
+			}
+			var weighed float64
+			if w.program == nil {
+				weighed = w.config.Model.Weigh(effort, incidents)
+			} else {
+				category := ""
+				if violation.Category != nil {
+					category = string(*violation.Category)
+				}
+				labels := violation.Labels
+				if labels == nil {
+					labels = []string{}
+				}
+				out, _, err := w.program.Eval(map[string]interface{}{
+					effortExprRuleSet:   ruleSet.Name,
+					effortExprRuleID:    id,
+					effortExprCategory:  category,
+					effortExprLabels:    labels,
+					effortExprEffort:    effort,
+					effortExprIncidents: incidents,
+				})
+				if err != nil {
+					return 0, fmt.Errorf("unable to evaluate expression for %s/%s: %w", ruleSet.Name, id, err)
+				}
+				value, ok := out.Value().(float64)
+				if !ok {
+					return 0, fmt.Errorf("expression did not return a double for %s/%s", ruleSet.Name, id)
+				}
+				weighed = value
+			}
+			if violation.Category != nil {
+				if weight, ok := w.config.CategoryWeights[*violation.Category]; ok {
+					weighed *= weight
+				}
+			}
+			score += weighed
+		}
+	}
+	return score, nil
+}
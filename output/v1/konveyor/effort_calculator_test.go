@@ -0,0 +1,107 @@
+package konveyor
+
+import "testing"
+
+type fixedEffortCalculator float64
+
+func (f fixedEffortCalculator) Score([]RuleSet) (float64, error) {
+	return float64(f), nil
+}
+
+func TestNewEffortScore(t *testing.T) {
+	effort := 2
+	ruleSets := []RuleSet{{
+		Name: "eap8",
+		Violations: map[string]Violation{
+			"jms-00001": {
+				Category:  &Mandatory,
+				Effort:    &effort,
+				Labels:    []string{"konveyor.io/target=eap8"},
+				Incidents: []Incident{{}, {}, {}, {}},
+			},
+			"ejb-00001": {
+				Category:  &Optional,
+				Effort:    &effort,
+				Incidents: []Incident{{}},
+			},
+			"cdi-00001": {
+				Incidents: []Incident{{}},
+			},
+		},
+	}}
+	if err := RegisterEffortCalculator("fixed", func(config EffortConfig) (EffortCalculator, error) {
+		return fixedEffortCalculator(config.Options["score"].(int)), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterEffortCalculator(WeightedEffortCalculator, nil); err == nil {
+		t.Error("expected an error registering a calculator twice")
+	}
+
+	for _, tt := range []struct {
+		name    string
+		config  EffortConfig
+		want    EffortScore
+		wantErr bool
+	}{
+		{
+			name:   "sum of the efforts",
+			config: EffortConfig{},
+			want:   EffortScore{Calculator: WeightedEffortCalculator, Score: 10},
+		},
+		{
+			name:   "category weights and incident cap",
+			config: EffortConfig{CategoryWeights: map[Category]float64{Mandatory: 1.5, Optional: 0.25}, IncidentCap: 2},
+			want:   EffortScore{Calculator: WeightedEffortCalculator, Score: 6.5},
+		},
+		{
+			name:   "effort model",
+			config: EffortConfig{Model: SqrtEffortModel},
+			want:   EffortScore{Calculator: WeightedEffortCalculator, Score: 6},
+		},
+		{
+			name: "expression",
+			config: EffortConfig{
+				CategoryWeights: map[Category]float64{Mandatory: 2},
+				Expression:      "'konveyor.io/target=eap8' in labels ? double(effort) : double(incidents) / 2.0",
+			},
+			want: EffortScore{Calculator: WeightedEffortCalculator, Score: 5},
+		},
+		{
+			name:   "registered calculator",
+			config: EffortConfig{Calculator: "fixed", Options: map[string]interface{}{"score": 42}},
+			want:   EffortScore{Calculator: "fixed", Score: 42},
+		},
+		{
+			name:    "expression that is not a double",
+			config:  EffortConfig{Expression: "effort * incidents"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown category",
+			config:  EffortConfig{CategoryWeights: map[Category]float64{"urgent": 2}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown calculator",
+			config:  EffortConfig{Calculator: "story-points"},
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewEffortScore(ruleSets, tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, *got)
+			}
+		})
+	}
+}
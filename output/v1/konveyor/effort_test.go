@@ -0,0 +1,43 @@
+package konveyor
+
+import "testing"
+
+func TestEffortModelWeigh(t *testing.T) {
+	tests := []struct {
+		model     EffortModel
+		effort    int
+		incidents int
+		want      float64
+	}{
+		{model: LinearEffortModel, effort: 3, incidents: 100, want: 300},
+		{model: LogEffortModel, effort: 3, incidents: 1, want: 3},
+		{model: LogEffortModel, effort: 3, incidents: 100, want: 16.82},
+		{model: SqrtEffortModel, effort: 3, incidents: 100, want: 30},
+		{model: SqrtEffortModel, effort: 3, incidents: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := tt.model.Weigh(tt.effort, tt.incidents); got != tt.want {
+			t.Errorf("%s.Weigh(%d, %d) = %v, want %v", tt.model, tt.effort, tt.incidents, got, tt.want)
+		}
+	}
+}
+
+func TestApplyEffortModel(t *testing.T) {
+	effort := 5
+	ruleSets := []RuleSet{{
+		Violations: map[string]Violation{
+			"with-effort":    {Effort: &effort, Incidents: []Incident{{}, {}, {}, {}}},
+			"without-effort": {Incidents: []Incident{{}}},
+		},
+	}}
+	ApplyEffortModel(ruleSets, SqrtEffortModel)
+	if got := ruleSets[0].Violations["with-effort"].WeightedEffort; got == nil || *got != 10 {
+		t.Errorf("expected weighted effort of 10, got %v", got)
+	}
+	if got := ruleSets[0].Violations["without-effort"].WeightedEffort; got != nil {
+		t.Errorf("expected no weighted effort without an effort, got %v", *got)
+	}
+	if *ruleSets[0].Violations["with-effort"].Effort != 5 {
+		t.Errorf("expected the raw effort to be kept")
+	}
+}
@@ -0,0 +1,170 @@
+package konveyor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RuleFamilyLabel groups the rules reporting the same issues, such as the
+// rules ported from several windup rulesets
+const RuleFamilyLabel = "konveyor.io/family"
+
+// ruleNumberRegex matches the number at the end of a rule ID, the rules
+// of a family are numbered, such as javax-to-jakarta-00010
+var ruleNumberRegex = regexp.MustCompile(`[-_.]?[0-9]+$`)
+
+// IncidentMatch is a rule that found an incident
+type IncidentMatch struct {
+	RuleSet string `yaml:"ruleSet" json:"ruleSet"`
+	RuleID  string `yaml:"ruleID" json:"ruleID"`
+}
+
+// RuleFamily returns the family of a rule, the value of its
+// konveyor.io/family label or its ID without its number
+func RuleFamily(ruleID string, labels []string) string {
+	for _, label := range labels {
+		if value, ok := strings.CutPrefix(label, RuleFamilyLabel+"="); ok && value != "" {
+			return value
+		}
+	}
+	if family := ruleNumberRegex.ReplaceAllString(ruleID, ""); family != "" {
+		return family
+	}
+	return ruleID
+}
+
+// Fingerprint identifies the location of an incident for a family of rules,
+// the incidents of rules of the same family on the same line have the same
+// fingerprint. Unlike IncidentFingerprint it doesn't depend on the message of
+// the incident.
+func Fingerprint(family string, incident Incident) string {
+	location := "file"
+	if incident.LineNumber != nil {
+		location = fmt.Sprintf("line:%d", *incident.LineNumber)
+	}
+	h := sha256.New()
+	for _, part := range []string{family, string(incident.URI), location} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CollapseIncidents keeps one of the incidents with the same fingerprint
+// found by several rules, the other rules are listed in its AlsoMatchedBy.
+// The incident kept is the one of a violation rather than an insight, then of
+// the rule with the highest category and effort, then of the first rule by
+// ruleset name and ID. The violations left without incidents are removed. It
+// returns the number of incidents removed.
+func CollapseIncidents(ruleSets []RuleSet) int {
+	type candidate struct {
+		match   IncidentMatch
+		insight bool
+		rank    int
+		effort  int
+	}
+	candidates := map[string][]candidate{}
+	for _, ruleSet := range ruleSets {
+		for _, insight := range []bool{false, true} {
+			violations := ruleSet.Violations
+			if insight {
+				violations = ruleSet.Insights
+			}
+			for id, violation := range violations {
+				c := candidate{
+					match:   IncidentMatch{RuleSet: ruleSet.Name, RuleID: id},
+					insight: insight,
+					rank:    categoryRank(violation.Category),
+				}
+				if violation.Effort != nil {
+					c.effort = *violation.Effort
+				}
+				seen := map[string]bool{}
+				for _, incident := range violation.Incidents {
+					if incident.Fingerprint == "" || seen[incident.Fingerprint] {
+						continue
+					}
+					seen[incident.Fingerprint] = true
+					candidates[incident.Fingerprint] = append(candidates[incident.Fingerprint], c)
+				}
+			}
+		}
+	}
+	canonical := map[string]IncidentMatch{}
+	alsoMatchedBy := map[string][]IncidentMatch{}
+	for fingerprint, matches := range candidates {
+		if len(matches) < 2 {
+			continue
+		}
+		sort.Slice(matches, func(i, j int) bool {
+			a, b := matches[i], matches[j]
+			if a.insight != b.insight {
+				return !a.insight
+			}
+			if a.rank != b.rank {
+				return a.rank > b.rank
+			}
+			if a.effort != b.effort {
+				return a.effort > b.effort
+			}
+			if a.match.RuleSet != b.match.RuleSet {
+				return a.match.RuleSet < b.match.RuleSet
+			}
+			return a.match.RuleID < b.match.RuleID
+		})
+		canonical[fingerprint] = matches[0].match
+		for _, m := range matches[1:] {
+			alsoMatchedBy[fingerprint] = append(alsoMatchedBy[fingerprint], m.match)
+		}
+	}
+	if len(canonical) == 0 {
+		return 0
+	}
+	removed := 0
+	for _, ruleSet := range ruleSets {
+		for _, violations := range []map[string]Violation{ruleSet.Violations, ruleSet.Insights} {
+			for id, violation := range violations {
+				match := IncidentMatch{RuleSet: ruleSet.Name, RuleID: id}
+				incidents := []Incident{}
+				for _, incident := range violation.Incidents {
+					kept, ok := canonical[incident.Fingerprint]
+					if ok && kept != match {
+						removed++
+						continue
+					}
+					if ok {
+						incident.AlsoMatchedBy = alsoMatchedBy[incident.Fingerprint]
+					}
+					incidents = append(incidents, incident)
+				}
+				if len(incidents) == 0 {
+					delete(violations, id)
+					continue
+				}
+				violation.Incidents = incidents
+				violations[id] = violation
+			}
+		}
+	}
+	return removed
+}
+
+// categoryRank orders the categories, mandatory first
+func categoryRank(category *Category) int {
+	if category == nil {
+		return 0
+	}
+	switch *category {
+	case Mandatory:
+		return 3
+	case Optional:
+		return 2
+	case Potential:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package konveyor
+
+import (
+	"reflect"
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+func TestRuleFamily(t *testing.T) {
+	tests := []struct {
+		ruleID string
+		labels []string
+		want   string
+	}{
+		{ruleID: "javax-to-jakarta-00010", want: "javax-to-jakarta"},
+		{ruleID: "jakarta_import.2", want: "jakarta_import"},
+		{ruleID: "lang-ref", want: "lang-ref"},
+		{ruleID: "00010", want: "00010"},
+		{ruleID: "persistence-00001", labels: []string{"konveyor.io/source=java-ee", "konveyor.io/family=jakarta-persistence"}, want: "jakarta-persistence"},
+	}
+	for _, tt := range tests {
+		if got := RuleFamily(tt.ruleID, tt.labels); got != tt.want {
+			t.Errorf("RuleFamily(%s, %v) = %s, want %s", tt.ruleID, tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestCollapseIncidents(t *testing.T) {
+	line := func(l int) *int { return &l }
+	effort := func(e int) *int { return &e }
+	mandatory, optional := Mandatory, Optional
+	incident := func(ruleID, file string, l int) Incident {
+		i := Incident{URI: uri.File("/src/" + file), Message: ruleID, LineNumber: line(l)}
+		i.Fingerprint = Fingerprint(RuleFamily(ruleID, nil), i)
+		return i
+	}
+	ruleSets := []RuleSet{
+		{
+			Name: "eap7",
+			Violations: map[string]Violation{
+				"jakarta-00001": {Category: &optional, Effort: effort(1), Incidents: []Incident{
+					incident("jakarta-00001", "Entity.java", 3),
+					incident("jakarta-00001", "Entity.java", 4),
+				}},
+			},
+			Insights: map[string]Violation{
+				"jakarta-00003": {Incidents: []Incident{incident("jakarta-00003", "Entity.java", 3)}},
+			},
+		},
+		{
+			Name: "eap8",
+			Violations: map[string]Violation{
+				"jakarta-00002": {Category: &mandatory, Effort: effort(1), Incidents: []Incident{
+					incident("jakarta-00002", "Entity.java", 3),
+				}},
+				// another family on the same line
+				"hibernate-00001": {Category: &optional, Effort: effort(1), Incidents: []Incident{
+					incident("hibernate-00001", "Entity.java", 3),
+				}},
+			},
+		},
+	}
+	if removed := CollapseIncidents(ruleSets); removed != 2 {
+		t.Errorf("expected 2 incidents removed, got %d", removed)
+	}
+	kept := ruleSets[1].Violations["jakarta-00002"].Incidents
+	expected := []IncidentMatch{{RuleSet: "eap7", RuleID: "jakarta-00001"}, {RuleSet: "eap7", RuleID: "jakarta-00003"}}
+	if len(kept) != 1 || !reflect.DeepEqual(kept[0].AlsoMatchedBy, expected) {
+		t.Errorf("expected the incident of the mandatory rule to be kept, got %+v", kept)
+	}
+	if incidents := ruleSets[0].Violations["jakarta-00001"].Incidents; len(incidents) != 1 || *incidents[0].LineNumber != 4 {
+		t.Errorf("expected the incident on line 4 to be left, got %+v", incidents)
+	}
+	if len(ruleSets[0].Insights) != 0 {
+		t.Errorf("expected the insight left without incidents to be removed, got %+v", ruleSets[0].Insights)
+	}
+	if incidents := ruleSets[1].Violations["hibernate-00001"].Incidents; incidents[0].AlsoMatchedBy != nil {
+		t.Errorf("expected no other rules for an incident found once, got %v", incidents[0].AlsoMatchedBy)
+	}
+}
@@ -0,0 +1,252 @@
+package konveyor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/atomicfile"
+	"github.com/pmezard/go-difflib/difflib"
+	"go.lsp.dev/uri"
+)
+
+// Fix is the remediation of an incident. A fix with Search is a text
+// substitution the analyzer can apply, one with CodeAction refers to the code
+// action of a language server fixing the incident.
+type Fix struct {
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// File is the file the fix edits, the one of the incident unless the
+	// rule targets another file
+	File uri.URI `yaml:"file,omitempty" json:"file,omitempty"`
+	// Search is a regex replaced with Replace, on the line of the incident
+	// when the fix edits its file and the incident has one, or in the whole
+	// file otherwise
+	Search  string `yaml:"search,omitempty" json:"search,omitempty"`
+	Replace string `yaml:"replace,omitempty" json:"replace,omitempty"`
+	// Line is the line of the incident Search is replaced on
+	Line       *int   `yaml:"line,omitempty" json:"line,omitempty"`
+	CodeAction string `yaml:"codeAction,omitempty" json:"codeAction,omitempty"`
+
+	// Path is where File is on disk, the file of the incidents can be
+	// relative to the locations in the output
+	Path string `yaml:"-" json:"-"`
+}
+
+// FixMode is how the fixes of an analysis are applied
+type FixMode string
+
+const (
+	// DryRunFixes prints the changes the fixes make without writing them
+	DryRunFixes FixMode = "dry-run"
+	// WriteFixes writes the changes the fixes make to the files
+	WriteFixes FixMode = "write"
+)
+
+var FixModes = []FixMode{DryRunFixes, WriteFixes}
+
+// FixedFile is a file changed by the fixes of an analysis
+type FixedFile struct {
+	File uri.URI
+	Path string
+	// Fixes is the number of fixes that changed the file
+	Fixes int
+	// Diff is the unified diff of the changes
+	Diff string
+}
+
+// InLocations returns whether the path, once cleaned and its symlinks
+// resolved, is in one of the locations, so that the fixes of the rules can't
+// edit files outside of the analyzed locations, even through a symlink.
+func InLocations(path string, locations []string) bool {
+	path, err := realPath(path)
+	if err != nil {
+		return false
+	}
+	for _, location := range locations {
+		if location == "" {
+			continue
+		}
+		location, err := realPath(location)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(location, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// realPath returns the absolute path with its symlinks resolved, the part of
+// it that does not exist yet is kept as is
+func realPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return real, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	real, err = realPath(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(real, filepath.Base(path)), nil
+}
+
+// ApplyFixes applies the text substitutions of the fixes of the incidents of
+// the rulesets, writing the changed files in WriteFixes mode. The fixes of a
+// file are applied from its last line to its first one, so that a
+// substitution changing the number of lines doesn't move the lines of the
+// others, and the fixes of the whole file last. The same fix found by
+// several rules is applied once. No file is changed when a fix edits a file
+// outside of the locations or a fix can't be applied, the fixes of every file
+// are applied before any is written. On an error writing them, the files
+// written before it are returned.
+func ApplyFixes(ruleSets []RuleSet, mode FixMode, locations []string) ([]FixedFile, error) {
+	fixes := map[string][]Fix{}
+	seen := map[string]bool{}
+	for _, ruleSet := range ruleSets {
+		for _, violations := range []map[string]Violation{ruleSet.Violations, ruleSet.Insights} {
+			for _, violation := range violations {
+				for _, incident := range violation.Incidents {
+					fix := incident.Fix
+					if fix == nil || fix.Search == "" || fix.Path == "" {
+						continue
+					}
+					if !InLocations(fix.Path, locations) {
+						return nil, fmt.Errorf("unable to fix file %s outside of the analyzed locations", fix.Path)
+					}
+					line := 0
+					if fix.Line != nil {
+						line = *fix.Line
+					}
+					key := fmt.Sprintf("%s:%d:%s:%s", fix.Path, line, fix.Search, fix.Replace)
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					fixes[fix.Path] = append(fixes[fix.Path], *fix)
+				}
+			}
+		}
+	}
+	paths := []string{}
+	for path := range fixes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fixed := []FixedFile{}
+	contents := [][]byte{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file %s to fix: %w", path, err)
+		}
+		fileFixes := fixes[path]
+		sort.Slice(fileFixes, func(i, j int) bool {
+			a, b := fileFixes[i], fileFixes[j]
+			if fixLine(a) != fixLine(b) {
+				return fixLine(a) > fixLine(b)
+			}
+			if a.Search != b.Search {
+				return a.Search < b.Search
+			}
+			return a.Replace < b.Replace
+		})
+		before := string(content)
+		after := before
+		applied := 0
+		for _, fix := range fileFixes {
+			changed, err := applyFix(after, fix)
+			if err != nil {
+				return nil, fmt.Errorf("unable to fix file %s: %w", path, err)
+			}
+			if changed != after {
+				applied++
+				after = changed
+			}
+		}
+		if after == before {
+			continue
+		}
+		file := fileFixes[0].File
+		name := strings.TrimPrefix(strings.TrimPrefix(string(file), uri.FileScheme+"://"), "/")
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        diffLines(before),
+			B:        diffLines(after),
+			FromFile: "a/" + name,
+			ToFile:   "b/" + name,
+			Context:  3,
+		})
+		if err != nil {
+			return nil, err
+		}
+		fixed = append(fixed, FixedFile{File: file, Path: path, Fixes: applied, Diff: diff})
+		contents = append(contents, []byte(after))
+	}
+	if mode != WriteFixes {
+		return fixed, nil
+	}
+	for i, file := range fixed {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return fixed[:i], err
+		}
+		// a file is either fixed or left as it was, never partially written
+		if err := atomicfile.WriteFile(file.Path, contents[i], info.Mode().Perm()); err != nil {
+			return fixed[:i], fmt.Errorf("unable to write fixed file %s: %w", file.Path, err)
+		}
+	}
+	return fixed, nil
+}
+
+// diffLines splits content in lines keeping their line breaks, without the
+// empty line after the last line break
+func diffLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// fixLine is the line of a fix, the fixes of the whole file are last
+func fixLine(fix Fix) int {
+	if fix.Line == nil {
+		return 0
+	}
+	return *fix.Line
+}
+
+// applyFix replaces the search regex of the fix on its line, or in the whole
+// content
+func applyFix(content string, fix Fix) (string, error) {
+	search, err := regexp.Compile(fix.Search)
+	if err != nil {
+		return content, fmt.Errorf("invalid search pattern %s: %w", fix.Search, err)
+	}
+	if fix.Line == nil {
+		return search.ReplaceAllString(content, fix.Replace), nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	i := *fix.Line - 1
+	if i < 0 || i >= len(lines) {
+		return content, nil
+	}
+	lines[i] = search.ReplaceAllString(lines[i], fix.Replace)
+	return strings.Join(lines, ""), nil
+}
@@ -0,0 +1,186 @@
+package konveyor
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+func TestApplyFixes(t *testing.T) {
+	line := func(l int) *int { return &l }
+	content := "import javax.persistence.Entity;\nimport javax.persistence.Id;\n// javax.persistence is replaced\n"
+	want := "import jakarta.persistence.Entity;\nimport jakarta.persistence.Id;\n// javax.persistence is replaced\n"
+	for _, mode := range FixModes {
+		t.Run(string(mode), func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "Entity.java")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			fix := func(l *int, search string) Fix {
+				return Fix{File: uri.File("/src/Entity.java"), Path: path, Line: l, Search: search, Replace: "jakarta.persistence"}
+			}
+			first, second, code := fix(line(1), `javax\.persistence`), fix(line(2), `javax\.persistence`), Fix{CodeAction: "quickfix"}
+			ruleSets := []RuleSet{{
+				Violations: map[string]Violation{
+					"persistence-001": {Incidents: []Incident{{Fix: &first}, {Fix: &second}, {Fix: &code}, {}}},
+					// the same fix found by another rule
+					"persistence-002": {Incidents: []Incident{{Fix: &first}}},
+				},
+				Insights: map[string]Violation{
+					"unrelated-001": {Incidents: []Incident{{Fix: &Fix{Path: path, Search: "no match"}}}},
+				},
+			}}
+			fixed, err := ApplyFixes(ruleSets, mode, []string{dir})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(fixed) != 1 || fixed[0].Fixes != 2 {
+				t.Fatalf("expected a file fixed twice, got %+v", fixed)
+			}
+			if !strings.Contains(fixed[0].Diff, "--- a/src/Entity.java") ||
+				!strings.Contains(fixed[0].Diff, "-import javax.persistence.Entity;") ||
+				!strings.Contains(fixed[0].Diff, "+import jakarta.persistence.Entity;") {
+				t.Errorf("unexpected diff\n%s", fixed[0].Diff)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := content
+			if mode == WriteFixes {
+				expected = want
+			}
+			if string(got) != expected {
+				t.Errorf("expected the file to be\n%s\ngot\n%s", expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyFixesInvalidSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pom.xml")
+	if err := os.WriteFile(path, []byte("<project/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ruleSets := []RuleSet{{
+		Violations: map[string]Violation{
+			"pom-001": {Incidents: []Incident{{Fix: &Fix{Path: path, Search: "(project"}}}},
+		},
+	}}
+	if _, err := ApplyFixes(ruleSets, DryRunFixes, []string{filepath.Dir(path)}); err == nil {
+		t.Errorf("expected an error for an invalid search pattern")
+	}
+}
+
+func TestApplyFixesOutsideOfLocations(t *testing.T) {
+	dir := t.TempDir()
+	location := filepath.Join(dir, "app")
+	if err := os.Mkdir(location, 0755); err != nil {
+		t.Fatal(err)
+	}
+	inside := filepath.Join(location, "pom.xml")
+	outside := filepath.Join(dir, "passwd")
+	for _, path := range []string{inside, outside} {
+		if err := os.WriteFile(path, []byte("root"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, path := range map[string]string{
+		"absolute path": outside,
+		"relative path": filepath.Join(location, "..", "passwd"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ruleSets := []RuleSet{{
+				Violations: map[string]Violation{
+					"fix-001": {Incidents: []Incident{
+						{Fix: &Fix{Path: inside, Search: "root", Replace: "toor"}},
+						{Fix: &Fix{Path: path, Search: "root", Replace: "toor"}},
+					}},
+				},
+			}}
+			if _, err := ApplyFixes(ruleSets, WriteFixes, []string{location}); err == nil {
+				t.Errorf("expected an error fixing a file outside of the locations")
+			}
+			for _, file := range []string{inside, outside} {
+				if got, _ := os.ReadFile(file); string(got) != "root" {
+					t.Errorf("expected %s to be left alone, got %s", file, got)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyFixesSymlinkOutsideOfLocations(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks needs privileges on windows")
+	}
+	dir := t.TempDir()
+	location := filepath.Join(dir, "app")
+	if err := os.Mkdir(location, 0755); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(dir, "passwd")
+	if err := os.WriteFile(outside, []byte("root"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(location, "passwd")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+	if InLocations(link, []string{location}) {
+		t.Errorf("expected a symlink to a file outside of the location not to be in it")
+	}
+	// the location itself can be a symlink
+	linkedLocation := filepath.Join(dir, "linked")
+	if err := os.Symlink(location, linkedLocation); err != nil {
+		t.Fatal(err)
+	}
+	if !InLocations(filepath.Join(location, "pom.xml"), []string{linkedLocation}) {
+		t.Errorf("expected a file of the location to be in the symlink to it")
+	}
+	ruleSets := []RuleSet{{
+		Violations: map[string]Violation{
+			"fix-001": {Incidents: []Incident{{Fix: &Fix{Path: link, Search: "root", Replace: "toor"}}}},
+		},
+	}}
+	if _, err := ApplyFixes(ruleSets, WriteFixes, []string{location}); err == nil {
+		t.Errorf("expected an error fixing a file outside of the locations through a symlink")
+	}
+	if got, _ := os.ReadFile(outside); string(got) != "root" {
+		t.Errorf("expected %s to be left alone, got %s", outside, got)
+	}
+}
+
+func TestApplyFixesNothingWrittenOnError(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a.xml")
+	second := filepath.Join(dir, "b.xml")
+	for _, path := range []string{first, second} {
+		if err := os.WriteFile(path, []byte("<project/>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ruleSets := []RuleSet{{
+		Violations: map[string]Violation{
+			"pom-001": {Incidents: []Incident{
+				{Fix: &Fix{Path: first, Search: "project", Replace: "module"}},
+				{Fix: &Fix{Path: second, Search: "(project"}},
+			}},
+		},
+	}}
+	fixed, err := ApplyFixes(ruleSets, WriteFixes, []string{dir})
+	if err == nil {
+		t.Errorf("expected an error for an invalid search pattern")
+	}
+	if len(fixed) != 0 {
+		t.Errorf("expected no file to be fixed, got %+v", fixed)
+	}
+	if got, _ := os.ReadFile(first); string(got) != "<project/>" {
+		t.Errorf("expected %s to be left alone, got %s", first, got)
+	}
+}
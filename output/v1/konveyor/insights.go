@@ -0,0 +1,61 @@
+package konveyor
+
+import "sort"
+
+// InsightLimits caps the insights of the output, the tagging rules of big
+// applications find many more incidents than the output can hold. Zero means
+// no limit.
+type InsightLimits struct {
+	// IncidentsPerInsight is the most incidents kept in an insight
+	IncidentsPerInsight int
+	// InsightsPerRuleSet is the most insights kept in a ruleset
+	InsightsPerRuleSet int
+}
+
+// LimitInsights removes the insights and the incidents of insights over the
+// limits from the rulesets, the insights with the smallest rule IDs and
+// their first incidents are kept. The number of incidents left out of an
+// insight is set in its omittedIncidents, the number of insights left out of
+// a ruleset in its omittedInsights. It returns the rulesets with the
+// incidents and insights left out, the overflow of the output.
+func LimitInsights(ruleSets []RuleSet, limits InsightLimits) []RuleSet {
+	overflow := []RuleSet{}
+	for i := range ruleSets {
+		ruleSet := &ruleSets[i]
+		left := map[string]Violation{}
+		ids := make([]string, 0, len(ruleSet.Insights))
+		for id := range ruleSet.Insights {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for n, id := range ids {
+			insight := ruleSet.Insights[id]
+			if limits.InsightsPerRuleSet > 0 && n >= limits.InsightsPerRuleSet {
+				left[id] = insight
+				delete(ruleSet.Insights, id)
+				ruleSet.OmittedInsights++
+				continue
+			}
+			if limits.IncidentsPerInsight <= 0 || len(insight.Incidents) <= limits.IncidentsPerInsight {
+				continue
+			}
+			sort.SliceStable(insight.Incidents, func(i, j int) bool {
+				return insight.Incidents[i].cmpLess(&insight.Incidents[j])
+			})
+			leftInsight := insight
+			leftInsight.Incidents = append([]Incident{}, insight.Incidents[limits.IncidentsPerInsight:]...)
+			left[id] = leftInsight
+			insight.Incidents = insight.Incidents[:limits.IncidentsPerInsight]
+			insight.OmittedIncidents += len(leftInsight.Incidents)
+			ruleSet.Insights[id] = insight
+		}
+		if len(left) > 0 {
+			overflow = append(overflow, RuleSet{
+				Name:        ruleSet.Name,
+				Description: ruleSet.Description,
+				Insights:    left,
+			})
+		}
+	}
+	return overflow
+}
@@ -0,0 +1,64 @@
+package konveyor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLimitInsights(t *testing.T) {
+	line := func(n int) *int { return &n }
+	incidents := func(lines ...int) []Incident {
+		list := []Incident{}
+		for _, l := range lines {
+			list = append(list, Incident{URI: "file:///app/Main.java", LineNumber: line(l)})
+		}
+		return list
+	}
+	ruleSets := []RuleSet{
+		{
+			Name:       "tags",
+			Violations: map[string]Violation{"violation": {Incidents: incidents(1, 2, 3)}},
+			Insights: map[string]Violation{
+				"tag-b": {Incidents: incidents(3, 1, 2)},
+				"tag-a": {Incidents: incidents(1)},
+				"tag-c": {Incidents: incidents(4)},
+			},
+		},
+		{
+			Name:     "small",
+			Insights: map[string]Violation{"tag": {Incidents: incidents(1)}},
+		},
+	}
+	overflow := LimitInsights(ruleSets, InsightLimits{IncidentsPerInsight: 2, InsightsPerRuleSet: 2})
+
+	wantRuleSets := []RuleSet{
+		{
+			Name:       "tags",
+			Violations: map[string]Violation{"violation": {Incidents: incidents(1, 2, 3)}},
+			Insights: map[string]Violation{
+				"tag-a": {Incidents: incidents(1)},
+				"tag-b": {Incidents: incidents(1, 2), OmittedIncidents: 1},
+			},
+			OmittedInsights: 1,
+		},
+		{
+			Name:     "small",
+			Insights: map[string]Violation{"tag": {Incidents: incidents(1)}},
+		},
+	}
+	if !reflect.DeepEqual(ruleSets, wantRuleSets) {
+		t.Errorf("LimitInsights() left %v, want %v", ruleSets, wantRuleSets)
+	}
+	wantOverflow := []RuleSet{
+		{
+			Name: "tags",
+			Insights: map[string]Violation{
+				"tag-b": {Incidents: incidents(3)},
+				"tag-c": {Incidents: incidents(4)},
+			},
+		},
+	}
+	if !reflect.DeepEqual(overflow, wantOverflow) {
+		t.Errorf("LimitInsights() = %v, want %v", overflow, wantOverflow)
+	}
+}
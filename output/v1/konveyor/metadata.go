@@ -0,0 +1,74 @@
+package konveyor
+
+import "time"
+
+// Output is the output of an analysis along with the metadata of what
+// produced it, see the convert package for reading and writing the outputs
+// of every API version.
+type Output struct {
+	APIVersion string    `yaml:"apiVersion" json:"apiVersion"`
+	Metadata   *Metadata `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+	RuleSets   []RuleSet `yaml:"rulesets" json:"rulesets"`
+}
+
+// Metadata tells what produced an output, so that the analysis can be
+// reproduced and its results audited.
+type Metadata struct {
+	// AnalyzerVersion is the version of the analyzer, (devel) when it was
+	// not built from a tagged module
+	AnalyzerVersion string `yaml:"analyzerVersion" json:"analyzerVersion"`
+	// AnalyzerRevision is the commit the analyzer was built from, with a
+	// -dirty suffix when it had uncommitted changes
+	AnalyzerRevision string `yaml:"analyzerRevision,omitempty" json:"analyzerRevision,omitempty"`
+
+	// Providers are the providers the rules were evaluated with
+	Providers []ProviderMetadata `yaml:"providers,omitempty" json:"providers,omitempty"`
+
+	// RulesDigest is the sha256 digest of the names and contents of the rule
+	// files, the same rules have the same digest wherever they are
+	RulesDigest string `yaml:"rulesDigest,omitempty" json:"rulesDigest,omitempty"`
+
+	LabelSelector    string `yaml:"labelSelector,omitempty" json:"labelSelector,omitempty"`
+	DepLabelSelector string `yaml:"depLabelSelector,omitempty" json:"depLabelSelector,omitempty"`
+	IncidentSelector string `yaml:"incidentSelector,omitempty" json:"incidentSelector,omitempty"`
+	CategorySelector string `yaml:"categorySelector,omitempty" json:"categorySelector,omitempty"`
+
+	// RuleOverrides are the overrides of the rules given to the analysis
+	RuleOverrides []RuleOverride `yaml:"ruleOverrides,omitempty" json:"ruleOverrides,omitempty"`
+
+	// FeatureFlags are the experimental features enabled or disabled for
+	// the analysis, they change how the rules are evaluated
+	FeatureFlags map[string]bool `yaml:"featureFlags,omitempty" json:"featureFlags,omitempty"`
+
+	// Effort is the effort score of the application computed with the
+	// effort config of the analysis
+	Effort *EffortScore `yaml:"effort,omitempty" json:"effort,omitempty"`
+
+	StartTime time.Time `yaml:"startTime" json:"startTime"`
+	EndTime   time.Time `yaml:"endTime" json:"endTime"`
+	// CancelReason is why the analysis was canceled before every rule was
+	// evaluated, the rulesets list the rules that were not
+	CancelReason string `yaml:"cancelReason,omitempty" json:"cancelReason,omitempty"`
+
+	Host HostMetadata `yaml:"host" json:"host"`
+}
+
+// ProviderMetadata is a provider an analysis was run with
+type ProviderMetadata struct {
+	Name string `yaml:"name" json:"name"`
+	// ProtocolVersion is the version of the gRPC protocol the provider
+	// advertised, empty for the providers run by the analyzer itself and
+	// the ones built before the protocol was versioned
+	ProtocolVersion int32 `yaml:"protocolVersion,omitempty" json:"protocolVersion,omitempty"`
+	// ConfigHash is the sha256 digest of the configuration of the provider,
+	// the configuration itself is left out as it may hold credentials
+	ConfigHash string `yaml:"configHash" json:"configHash"`
+}
+
+// HostMetadata is the host an analysis was run on
+type HostMetadata struct {
+	Hostname string `yaml:"hostname,omitempty" json:"hostname,omitempty"`
+	OS       string `yaml:"os" json:"os"`
+	Arch     string `yaml:"arch" json:"arch"`
+	CPUs     int    `yaml:"cpus" json:"cpus"`
+}
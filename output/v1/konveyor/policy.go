@@ -0,0 +1,146 @@
+package konveyor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FailurePolicy decides whether an analysis fails from its output, for
+// instance to gate CI. It is written as comma separated terms, such as
+// category=mandatory,severity>=high,count>10: the category and severity
+// terms select the violations, the count term compares the number of their
+// incidents. The analysis fails when more than zero incidents are selected
+// if there is no count term. Incidents found in the baseline are not
+// counted.
+type FailurePolicy struct {
+	text       string
+	categories []Category
+	severities []severityTerm
+	count      countTerm
+}
+
+type severityTerm struct {
+	op       string
+	severity Severity
+}
+
+type countTerm struct {
+	op    string
+	value int
+}
+
+var policyOps = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseFailurePolicy parses the text of a failure policy.
+func ParseFailurePolicy(text string) (FailurePolicy, error) {
+	p := FailurePolicy{text: text, count: countTerm{op: ">", value: 0}}
+	if strings.TrimSpace(text) == "" {
+		return p, fmt.Errorf("empty failure policy")
+	}
+	for _, term := range strings.Split(text, ",") {
+		key, op, value, err := splitPolicyTerm(strings.TrimSpace(term))
+		if err != nil {
+			return p, err
+		}
+		switch key {
+		case "category":
+			c := Category(strings.ToLower(value))
+			if !c.Valid() {
+				return p, fmt.Errorf("category must be one of %s, %s or %s, not %s", Mandatory, Optional, Potential, value)
+			}
+			if op != "=" {
+				return p, fmt.Errorf("category can only be compared with =, not %s", op)
+			}
+			p.categories = append(p.categories, c)
+		case "severity":
+			s := Severity(strings.ToLower(value))
+			if !s.Valid() {
+				return p, fmt.Errorf("severity must be one of %v, not %s", Severities, value)
+			}
+			p.severities = append(p.severities, severityTerm{op: op, severity: s})
+		case "count":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return p, fmt.Errorf("count must be compared to a positive integer, not %s", value)
+			}
+			p.count = countTerm{op: op, value: n}
+		default:
+			return p, fmt.Errorf("unknown failure policy term %s, must be one of category, severity or count", key)
+		}
+	}
+	return p, nil
+}
+
+func splitPolicyTerm(term string) (string, string, string, error) {
+	for _, op := range policyOps {
+		if key, value, ok := strings.Cut(term, op); ok {
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if key == "" || value == "" {
+				break
+			}
+			return key, op, value, nil
+		}
+	}
+	return "", "", "", fmt.Errorf("invalid failure policy term %q, must be <key><op><value> with one of the operators %v", term, policyOps)
+}
+
+func (p FailurePolicy) String() string {
+	return p.text
+}
+
+// Evaluate returns the number of incidents selected by the policy and
+// whether the analysis fails.
+func (p FailurePolicy) Evaluate(ruleSets []RuleSet) (int, bool) {
+	count := 0
+	for _, ruleSet := range ruleSets {
+		for _, violation := range ruleSet.Violations {
+			if !p.selects(violation) {
+				continue
+			}
+			for _, incident := range violation.Incidents {
+				if !incident.Baseline {
+					count++
+				}
+			}
+		}
+	}
+	return count, compareInts(count, p.count.op, p.count.value)
+}
+
+func (p FailurePolicy) selects(v Violation) bool {
+	if len(p.categories) > 0 {
+		found := false
+		for _, c := range p.categories {
+			if v.Category != nil && *v.Category == c {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, term := range p.severities {
+		if v.Severity == nil || !compareInts(severityRank[*v.Severity], term.op, severityRank[term.severity]) {
+			return false
+		}
+	}
+	return true
+}
+
+func compareInts(a int, op string, b int) bool {
+	switch op {
+	case ">=":
+		return a >= b
+	case "<=":
+		return a <= b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case "<":
+		return a < b
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,59 @@
+package konveyor
+
+import "testing"
+
+func TestFailurePolicy(t *testing.T) {
+	category := func(c Category) *Category { return &c }
+	severity := func(s Severity) *Severity { return &s }
+	incidents := func(n int, baseline int) []Incident {
+		list := []Incident{}
+		for i := 0; i < n; i++ {
+			list = append(list, Incident{Baseline: i < baseline})
+		}
+		return list
+	}
+	ruleSets := []RuleSet{{
+		Name: "rules",
+		Violations: map[string]Violation{
+			"mandatory-high-001": {Category: category(Mandatory), Severity: severity(High), Incidents: incidents(8, 0)},
+			"mandatory-low-001":  {Category: category(Mandatory), Severity: severity(Low), Incidents: incidents(5, 0)},
+			"optional-001":       {Category: category(Optional), Incidents: incidents(4, 3)},
+		},
+	}}
+	tests := []struct {
+		policy string
+		count  int
+		failed bool
+		errMsg string
+	}{
+		{policy: "count>0", count: 14, failed: true},
+		{policy: "category=mandatory", count: 13, failed: true},
+		{policy: "category=mandatory,severity>=high,count>10", count: 8},
+		{policy: "category=mandatory,count>10", count: 13, failed: true},
+		{policy: "category=optional,category=mandatory,count>=18", count: 14},
+		{policy: "severity<high", count: 5, failed: true},
+		{policy: "severity=critical", count: 0},
+		{policy: "colour=red", errMsg: "unknown failure policy term colour, must be one of category, severity or count"},
+		{policy: "severity>=urgent", errMsg: "severity must be one of [critical high medium low], not urgent"},
+		{policy: "category>mandatory", errMsg: "category can only be compared with =, not >"},
+		{policy: "count", errMsg: `invalid failure policy term "count", must be <key><op><value> with one of the operators [>= <= != > < =]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			p, err := ParseFailurePolicy(tt.policy)
+			if tt.errMsg != "" {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Fatalf("expected error %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			count, failed := p.Evaluate(ruleSets)
+			if count != tt.count || failed != tt.failed {
+				t.Errorf("expected %d incidents and failed %v, got %d and %v", tt.count, tt.failed, count, failed)
+			}
+		})
+	}
+}
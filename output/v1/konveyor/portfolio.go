@@ -0,0 +1,126 @@
+package konveyor
+
+import "sort"
+
+// Application is the output of the analysis of an application of a
+// portfolio
+type Application struct {
+	Name     string
+	RuleSets []RuleSet
+}
+
+// PortfolioSummary rolls up the outputs of the analyses of several
+// applications.
+type PortfolioSummary struct {
+	// Incidents is the number of incidents of the violations of all the
+	// applications
+	Incidents int `yaml:"incidents" json:"incidents"`
+	// Effort is the effort of all the applications
+	Effort       float64              `yaml:"effort" json:"effort"`
+	Applications []ApplicationSummary `yaml:"applications" json:"applications"`
+	// TopRules are the rules with violations in the most applications, by
+	// incidents after that
+	TopRules []PortfolioRule `yaml:"topRules" json:"topRules"`
+	// Tags tell the applications tagged with every tag, sorted by tag
+	Tags []PortfolioTag `yaml:"tags" json:"tags"`
+}
+
+type ApplicationSummary struct {
+	Name       string `yaml:"name" json:"name"`
+	Violations int    `yaml:"violations" json:"violations"`
+	Incidents  int    `yaml:"incidents" json:"incidents"`
+	// Effort is the effort of the violations, the weighted effort is used for
+	// the violations that have one
+	Effort float64 `yaml:"effort" json:"effort"`
+	// Categories are the number of incidents of the violations by category
+	Categories map[Category]int `yaml:"categories,omitempty" json:"categories,omitempty"`
+}
+
+type PortfolioRule struct {
+	// Rule is <ruleSet>/<ruleID>
+	Rule         string    `yaml:"rule" json:"rule"`
+	Description  string    `yaml:"description,omitempty" json:"description,omitempty"`
+	Category     *Category `yaml:"category,omitempty" json:"category,omitempty"`
+	Applications int       `yaml:"applications" json:"applications"`
+	Incidents    int       `yaml:"incidents" json:"incidents"`
+	Effort       float64   `yaml:"effort" json:"effort"`
+}
+
+type PortfolioTag struct {
+	Tag          string   `yaml:"tag" json:"tag"`
+	Applications []string `yaml:"applications" json:"applications"`
+}
+
+// NewPortfolioSummary rolls up the outputs of the applications, in their
+// order, keeping the topRules rules with violations in the most
+// applications, all of them when topRules is zero.
+func NewPortfolioSummary(apps []Application, topRules int) PortfolioSummary {
+	summary := PortfolioSummary{
+		Applications: []ApplicationSummary{},
+		TopRules:     []PortfolioRule{},
+		Tags:         []PortfolioTag{},
+	}
+	rules := map[string]*PortfolioRule{}
+	tags := map[string][]string{}
+	for _, app := range apps {
+		appSummary := ApplicationSummary{Name: app.Name}
+		appTags := map[string]bool{}
+		for _, ruleSet := range app.RuleSets {
+			for _, tag := range ruleSet.Tags {
+				appTags[tag] = true
+			}
+			for id, violation := range ruleSet.Violations {
+				incidents := len(violation.Incidents)
+				effort := violationEffort(violation)
+				appSummary.Violations++
+				appSummary.Incidents += incidents
+				appSummary.Effort += effort
+				if violation.Category != nil {
+					if appSummary.Categories == nil {
+						appSummary.Categories = map[Category]int{}
+					}
+					appSummary.Categories[*violation.Category] += incidents
+				}
+				name := ruleSet.Name + "/" + id
+				rule, ok := rules[name]
+				if !ok {
+					rule = &PortfolioRule{Rule: name, Description: violation.Description, Category: violation.Category}
+					rules[name] = rule
+				}
+				rule.Applications++
+				rule.Incidents += incidents
+				rule.Effort += effort
+			}
+		}
+		for tag := range appTags {
+			tags[tag] = append(tags[tag], app.Name)
+		}
+		summary.Incidents += appSummary.Incidents
+		summary.Effort += appSummary.Effort
+		summary.Applications = append(summary.Applications, appSummary)
+	}
+
+	for _, rule := range rules {
+		summary.TopRules = append(summary.TopRules, *rule)
+	}
+	sort.Slice(summary.TopRules, func(i, j int) bool {
+		a, b := summary.TopRules[i], summary.TopRules[j]
+		if a.Applications != b.Applications {
+			return a.Applications > b.Applications
+		}
+		if a.Incidents != b.Incidents {
+			return a.Incidents > b.Incidents
+		}
+		return a.Rule < b.Rule
+	})
+	if topRules > 0 && len(summary.TopRules) > topRules {
+		summary.TopRules = summary.TopRules[:topRules]
+	}
+	for tag, apps := range tags {
+		summary.Tags = append(summary.Tags, PortfolioTag{Tag: tag, Applications: apps})
+	}
+	sort.Slice(summary.Tags, func(i, j int) bool {
+		return summary.Tags[i].Tag < summary.Tags[j].Tag
+	})
+	return summary
+}
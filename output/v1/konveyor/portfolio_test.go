@@ -0,0 +1,77 @@
+package konveyor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewPortfolioSummary(t *testing.T) {
+	effort := 2
+	weighted := 4.5
+	apps := []Application{
+		{
+			Name: "orders",
+			RuleSets: []RuleSet{{
+				Name: "eap8",
+				Tags: []string{"Java EE", "JMS"},
+				Violations: map[string]Violation{
+					"jms-00001": {
+						Description: "JMS",
+						Category:    &Mandatory,
+						Effort:      &effort,
+						Incidents:   []Incident{{}, {}},
+					},
+					"ejb-00001": {
+						Category:  &Optional,
+						Incidents: []Incident{{}},
+					},
+				},
+			}},
+		},
+		{
+			Name: "inventory",
+			RuleSets: []RuleSet{
+				{
+					Name: "eap8",
+					Tags: []string{"Java EE"},
+					Violations: map[string]Violation{
+						"jms-00001": {
+							Description:    "JMS",
+							Category:       &Mandatory,
+							Effort:         &effort,
+							WeightedEffort: &weighted,
+							Incidents:      []Incident{{}, {}, {}},
+						},
+					},
+				},
+				{
+					Name: "quarkus",
+					Tags: []string{"Java EE"},
+				},
+			},
+		},
+		{Name: "empty"},
+	}
+	want := PortfolioSummary{
+		Incidents: 6,
+		Effort:    8.5,
+		Applications: []ApplicationSummary{
+			{Name: "orders", Violations: 2, Incidents: 3, Effort: 4, Categories: map[Category]int{Mandatory: 2, Optional: 1}},
+			{Name: "inventory", Violations: 1, Incidents: 3, Effort: 4.5, Categories: map[Category]int{Mandatory: 3}},
+			{Name: "empty"},
+		},
+		TopRules: []PortfolioRule{
+			{Rule: "eap8/jms-00001", Description: "JMS", Category: &Mandatory, Applications: 2, Incidents: 5, Effort: 8.5},
+		},
+		Tags: []PortfolioTag{
+			{Tag: "JMS", Applications: []string{"orders"}},
+			{Tag: "Java EE", Applications: []string{"orders", "inventory"}},
+		},
+	}
+	if got := NewPortfolioSummary(apps, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got := NewPortfolioSummary(apps, 0); len(got.TopRules) != 2 || got.TopRules[1].Rule != "eap8/ejb-00001" {
+		t.Errorf("expected every rule without a limit, got %+v", got.TopRules)
+	}
+}
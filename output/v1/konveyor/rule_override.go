@@ -0,0 +1,74 @@
+package konveyor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RuleOverride changes the fields of the rule with the ID, or leaves the rule
+// out when it is disabled. The overrides of the ruleset.yaml of a ruleset
+// apply to its rules, the ones of the file given with --rule-overrides to
+// every rule and are recorded in the metadata of the output.
+type RuleOverride struct {
+	RuleID   string `yaml:"ruleID" json:"ruleID"`
+	Disabled bool   `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	// Labels replace the labels of the rule with the same key and are added
+	// to the others
+	Labels      []string  `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Effort      *int      `yaml:"effort,omitempty" json:"effort,omitempty"`
+	Category    *Category `yaml:"category,omitempty" json:"category,omitempty"`
+	Severity    *Severity `yaml:"severity,omitempty" json:"severity,omitempty"`
+	Description string    `yaml:"description,omitempty" json:"description,omitempty"`
+	// Message replaces the message of the rule, MessageSuffix is appended to
+	// it, such as a link to the migration guide of the organization
+	Message       string `yaml:"message,omitempty" json:"message,omitempty"`
+	MessageSuffix string `yaml:"messageSuffix,omitempty" json:"messageSuffix,omitempty"`
+}
+
+// Validate checks the values of the override
+func (o RuleOverride) Validate() error {
+	if o.RuleID == "" {
+		return fmt.Errorf("override without a ruleID")
+	}
+	if o.Effort != nil && *o.Effort < 0 {
+		return fmt.Errorf("effort of the override of %s must not be negative", o.RuleID)
+	}
+	if o.Category != nil && !o.Category.Valid() {
+		return fmt.Errorf("unknown category %s in the override of %s, must be one of %v", *o.Category, o.RuleID, Categories)
+	}
+	if o.Severity != nil && !o.Severity.Valid() {
+		return fmt.Errorf("unknown severity %s in the override of %s, must be one of %v", *o.Severity, o.RuleID, Severities)
+	}
+	return nil
+}
+
+// LoadRuleOverrides reads a file mapping rule IDs to their overrides, the
+// overrides are sorted by rule ID
+func LoadRuleOverrides(path string) ([]RuleOverride, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]RuleOverride{}
+	if err := yaml.UnmarshalStrict(content, &byID); err != nil {
+		return nil, fmt.Errorf("unable to read the rule overrides %s: %w", path, err)
+	}
+	overrides := []RuleOverride{}
+	for id, o := range byID {
+		if o.RuleID != "" && o.RuleID != id {
+			return nil, fmt.Errorf("override of %s has the ruleID %s", id, o.RuleID)
+		}
+		o.RuleID = id
+		if err := o.Validate(); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].RuleID < overrides[j].RuleID
+	})
+	return overrides, nil
+}
@@ -0,0 +1,73 @@
+package konveyor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadRuleOverrides(t *testing.T) {
+	effort := 5
+	mandatory := Mandatory
+	tests := []struct {
+		name      string
+		content   string
+		overrides []RuleOverride
+		errMsg    string
+	}{
+		{
+			name: "sorted by rule ID",
+			content: `rule-002:
+  disabled: true
+rule-001:
+  effort: 5
+  category: mandatory
+  labels:
+  - konveyor.io/target=acme
+  messageSuffix: " See the guide."
+`,
+			overrides: []RuleOverride{
+				{RuleID: "rule-001", Effort: &effort, Category: &mandatory, Labels: []string{"konveyor.io/target=acme"}, MessageSuffix: " See the guide."},
+				{RuleID: "rule-002", Disabled: true},
+			},
+		},
+		{
+			name:    "unknown category",
+			content: "rule-001:\n  category: urgent\n",
+			errMsg:  "unknown category urgent in the override of rule-001",
+		},
+		{
+			name:    "other rule ID",
+			content: "rule-001:\n  ruleID: rule-002\n",
+			errMsg:  "override of rule-001 has the ruleID rule-002",
+		},
+		{
+			name:    "unknown field",
+			content: "rule-001:\n  colour: red\n",
+			errMsg:  "field colour not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "overrides.yaml")
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			overrides, err := LoadRuleOverrides(file)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(overrides, tt.overrides) {
+				t.Errorf("expected %+v, got %+v", tt.overrides, overrides)
+			}
+		})
+	}
+}
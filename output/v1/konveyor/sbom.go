@@ -0,0 +1,443 @@
+package konveyor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SBOMFormat is the format of a software bill of materials
+type SBOMFormat string
+
+const (
+	// CycloneDXFormat is a CycloneDX 1.5 JSON document
+	CycloneDXFormat SBOMFormat = "cyclonedx"
+	// SPDXFormat is an SPDX 2.3 JSON document
+	SPDXFormat SBOMFormat = "spdx"
+)
+
+var SBOMFormats = []SBOMFormat{CycloneDXFormat, SPDXFormat}
+
+const (
+	sbomTool = "konveyor-analyzer"
+	// the labels are added to the components as properties named after their key
+	depLicenseLabel  = "konveyor.io/dep-license"
+	depLanguageLabel = "konveyor.io/language"
+)
+
+var sha1Regex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// SBOM is a software bill of materials of the dependencies of an application
+// found by the providers. The dependencies found in several files, or by
+// several providers, are a single component.
+type SBOM struct {
+	name       string
+	created    time.Time
+	components map[string]*sbomComponent
+	// direct are the refs of the dependencies of the application itself
+	direct map[string]bool
+	// dependsOn are the refs of the dependencies added by a dependency, they
+	// are only known from dependency trees
+	dependsOn map[string]map[string]bool
+}
+
+type sbomComponent struct {
+	ref      string
+	dep      Dep
+	files    map[string]bool
+	labels   map[string]bool
+	licenses []string
+}
+
+// NewSBOM creates the bill of materials of an application
+func NewSBOM(name string, created time.Time) *SBOM {
+	return &SBOM{
+		name:       name,
+		created:    created.UTC(),
+		components: map[string]*sbomComponent{},
+		direct:     map[string]bool{},
+		dependsOn:  map[string]map[string]bool{},
+	}
+}
+
+// AddDeps adds the dependencies of a flat list
+func (s *SBOM) AddDeps(items []DepsFlatItem) {
+	for _, item := range items {
+		for _, d := range item.Dependencies {
+			ref := s.add(item.Provider, item.FileURI, *d)
+			if !d.Indirect {
+				s.direct[ref] = true
+			}
+		}
+	}
+}
+
+// AddDepTrees adds the dependencies of trees, along with which dependency
+// adds which
+func (s *SBOM) AddDepTrees(items []DepsTreeItem) {
+	var walk func(provider, fileURI, parent string, items []DepDAGItem)
+	walk = func(provider, fileURI, parent string, items []DepDAGItem) {
+		for _, item := range items {
+			ref := s.add(provider, fileURI, item.Dep)
+			if parent == "" {
+				s.direct[ref] = true
+			} else {
+				if s.dependsOn[parent] == nil {
+					s.dependsOn[parent] = map[string]bool{}
+				}
+				s.dependsOn[parent][ref] = true
+			}
+			walk(provider, fileURI, ref, item.AddedDeps)
+		}
+	}
+	for _, item := range items {
+		walk(item.Provider, item.FileURI, "", item.Dependencies)
+	}
+}
+
+func (s *SBOM) add(provider, fileURI string, d Dep) string {
+	ref := packageURL(d)
+	c, ok := s.components[ref]
+	if !ok {
+		c = &sbomComponent{ref: ref, dep: d, files: map[string]bool{}, labels: map[string]bool{}}
+		s.components[ref] = c
+	}
+	c.files[fileURI] = true
+	for _, label := range d.Labels {
+		c.labels[label] = true
+		if key, value, _ := strings.Cut(label, "="); key == depLicenseLabel && value != "unknown" && !contains(c.licenses, value) {
+			c.licenses = append(c.licenses, value)
+		}
+	}
+	if provider != "" {
+		c.labels["konveyor.io/provider="+provider] = true
+	}
+	return ref
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// packageURL returns the package URL of a dependency from its language, see
+// https://github.com/package-url/purl-spec
+func packageURL(d Dep) string {
+	language := ""
+	for _, label := range d.Labels {
+		if key, value, _ := strings.Cut(label, "="); key == depLanguageLabel {
+			language = value
+		}
+	}
+	version := ""
+	if d.Version != "" {
+		version = "@" + url.PathEscape(d.Version)
+	}
+	switch language {
+	case "java":
+		group, _ := d.Extras["groupId"].(string)
+		artifact, _ := d.Extras["artifactId"].(string)
+		if group == "" || artifact == "" {
+			// the name of java dependencies is <groupId>.<artifactId>
+			if i := strings.LastIndex(d.Name, "."); i > 0 {
+				group, artifact = d.Name[:i], d.Name[i+1:]
+			} else {
+				artifact = d.Name
+			}
+		}
+		return fmt.Sprintf("pkg:maven/%s/%s%s", url.PathEscape(group), url.PathEscape(artifact), version)
+	case "go":
+		return fmt.Sprintf("pkg:golang/%s%s", escapePath(d.Name), version)
+	case "python":
+		return fmt.Sprintf("pkg:pypi/%s%s", url.PathEscape(strings.ToLower(d.Name)), version)
+	case "nodejs", "javascript", "typescript":
+		return fmt.Sprintf("pkg:npm/%s%s", escapePath(d.Name), version)
+	case "dotnet", "csharp":
+		return fmt.Sprintf("pkg:nuget/%s%s", url.PathEscape(d.Name), version)
+	}
+	return fmt.Sprintf("pkg:generic/%s%s", escapePath(d.Name), version)
+}
+
+func escapePath(path string) string {
+	parts := strings.Split(path, "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	return strings.Join(parts, "/")
+}
+
+// sortedComponents returns the components ordered by their ref, the documents
+// are the same for the same dependencies
+func (s *SBOM) sortedComponents() []*sbomComponent {
+	components := []*sbomComponent{}
+	for _, c := range s.components {
+		components = append(components, c)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].ref < components[j].ref
+	})
+	return components
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// digest identifies the dependencies of the SBOM, it names the documents
+func (s *SBOM) digest() string {
+	h := sha256.New()
+	h.Write([]byte(s.name))
+	for _, c := range s.sortedComponents() {
+		h.Write([]byte{0})
+		h.Write([]byte(c.ref))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Marshal returns the SBOM as a document of the format
+func (s *SBOM) Marshal(format SBOMFormat) ([]byte, error) {
+	switch format {
+	case CycloneDXFormat:
+		return s.CycloneDX()
+	case SPDXFormat:
+		return s.SPDX()
+	}
+	return nil, fmt.Errorf("unknown SBOM format %s, must be one of %v", format, SBOMFormats)
+}
+
+type cdxDocument struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cdxComponent `json:"components"`
+	} `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	Scope      string        `json:"scope,omitempty"`
+	Hashes     []cdxHash     `json:"hashes,omitempty"`
+	Licenses   []cdxLicense  `json:"licenses,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxLicense struct {
+	License struct {
+		ID string `json:"id"`
+	} `json:"license"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// CycloneDX returns the SBOM as a CycloneDX 1.5 JSON document. The labels of
+// the dependencies are properties named after their key, the files declaring
+// them konveyor:file properties.
+func (s *SBOM) CycloneDX() ([]byte, error) {
+	digest := s.digest()
+	appRef := "application"
+	doc := cdxDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		// the serial number is a UUID derived from the dependencies
+		SerialNumber: fmt.Sprintf("urn:uuid:%s-%s-5%s-8%s-%s", digest[0:8], digest[8:12], digest[13:16], digest[17:20], digest[20:32]),
+		Version:      1,
+		Components:   []cdxComponent{},
+		Dependencies: []cdxDependency{},
+	}
+	doc.Metadata.Timestamp = s.created.Format(time.RFC3339)
+	doc.Metadata.Tools.Components = []cdxComponent{{Type: "application", Name: sbomTool}}
+	doc.Metadata.Component = cdxComponent{Type: "application", BOMRef: appRef, Name: s.name}
+
+	for _, c := range s.sortedComponents() {
+		component := cdxComponent{
+			Type:    "library",
+			BOMRef:  c.ref,
+			Name:    c.dep.Name,
+			Version: c.dep.Version,
+			PURL:    c.ref,
+		}
+		if c.dep.Type == "test" {
+			component.Scope = "excluded"
+		}
+		if sha1Regex.MatchString(c.dep.ResolvedIdentifier) {
+			component.Hashes = []cdxHash{{Alg: "SHA-1", Content: c.dep.ResolvedIdentifier}}
+		}
+		for _, license := range c.licenses {
+			l := cdxLicense{}
+			l.License.ID = license
+			component.Licenses = append(component.Licenses, l)
+		}
+		for _, label := range sortedKeys(c.labels) {
+			key, value, _ := strings.Cut(label, "=")
+			component.Properties = append(component.Properties, cdxProperty{Name: key, Value: value})
+		}
+		for _, file := range sortedKeys(c.files) {
+			component.Properties = append(component.Properties, cdxProperty{Name: "konveyor:file", Value: file})
+		}
+		doc.Components = append(doc.Components, component)
+		doc.Dependencies = append(doc.Dependencies, cdxDependency{Ref: c.ref, DependsOn: sortedKeys(s.dependsOn[c.ref])})
+	}
+	doc.Dependencies = append([]cdxDependency{{Ref: appRef, DependsOn: sortedKeys(s.direct)}}, doc.Dependencies...)
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	Annotations      []spdxAnnotation  `json:"annotations,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxAnnotation struct {
+	AnnotationType string `json:"annotationType"`
+	Annotator      string `json:"annotator"`
+	AnnotationDate string `json:"annotationDate"`
+	Comment        string `json:"comment"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// SPDX returns the SBOM as an SPDX 2.3 JSON document. The labels of the
+// dependencies, and the files declaring them, are annotations of their
+// packages.
+func (s *SBOM) SPDX() ([]byte, error) {
+	created := s.created.Format(time.RFC3339)
+	tool := "Tool: " + sbomTool
+	appID := "SPDXRef-Application"
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              s.name,
+		DocumentNamespace: fmt.Sprintf("https://konveyor.io/spdx/%s-%s", url.PathEscape(s.name), s.digest()),
+		CreationInfo:      spdxCreationInfo{Created: created, Creators: []string{tool}},
+		Packages: []spdxPackage{{
+			Name:             s.name,
+			SPDXID:           appID,
+			DownloadLocation: "NOASSERTION",
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  "NOASSERTION",
+			CopyrightText:    "NOASSERTION",
+		}},
+		Relationships: []spdxRelationship{{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: appID}},
+	}
+	ids := map[string]string{}
+	components := s.sortedComponents()
+	for i, c := range components {
+		ids[c.ref] = fmt.Sprintf("SPDXRef-Package-%d", i+1)
+	}
+	for _, c := range components {
+		pkg := spdxPackage{
+			Name:             c.dep.Name,
+			SPDXID:           ids[c.ref],
+			VersionInfo:      c.dep.Version,
+			DownloadLocation: "NOASSERTION",
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  "NOASSERTION",
+			CopyrightText:    "NOASSERTION",
+			ExternalRefs:     []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: c.ref}},
+		}
+		if len(c.licenses) == 1 {
+			pkg.LicenseDeclared = c.licenses[0]
+		} else if len(c.licenses) > 1 {
+			pkg.LicenseDeclared = "(" + strings.Join(c.licenses, " AND ") + ")"
+		}
+		if sha1Regex.MatchString(c.dep.ResolvedIdentifier) {
+			pkg.Checksums = []spdxChecksum{{Algorithm: "SHA1", ChecksumValue: c.dep.ResolvedIdentifier}}
+		}
+		for _, label := range sortedKeys(c.labels) {
+			pkg.Annotations = append(pkg.Annotations, spdxAnnotation{AnnotationType: "OTHER", Annotator: tool, AnnotationDate: created, Comment: label})
+		}
+		for _, file := range sortedKeys(c.files) {
+			pkg.Annotations = append(pkg.Annotations, spdxAnnotation{AnnotationType: "OTHER", Annotator: tool, AnnotationDate: created, Comment: "konveyor:file=" + file})
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		for _, ref := range sortedKeys(s.dependsOn[c.ref]) {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: ids[c.ref], RelationshipType: "DEPENDS_ON", RelatedSPDXElement: ids[ref]})
+		}
+	}
+	for _, ref := range sortedKeys(s.direct) {
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: appID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: ids[ref]})
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
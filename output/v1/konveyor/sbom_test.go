@@ -0,0 +1,130 @@
+package konveyor
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testSBOM() *SBOM {
+	log4j := Dep{
+		Name:               "log4j.log4j",
+		Version:            "1.2.17",
+		ResolvedIdentifier: "5af35056b4d257e4b64b9e8069c0746e8b08629f",
+		Labels:             []string{"konveyor.io/language=java", "konveyor.io/dep-source=open-source", "konveyor.io/dep-license=Apache-2.0"},
+		Extras:             map[string]interface{}{"groupId": "log4j", "artifactId": "log4j"},
+	}
+	core := Dep{
+		Name:    "com.example.app-core",
+		Version: "1.0",
+		Labels:  []string{"konveyor.io/language=java", "konveyor.io/dep-license=unknown"},
+	}
+	text := Dep{
+		Name:    "golang.org/x/text",
+		Version: "v0.14.0",
+		Labels:  []string{"konveyor.io/language=go", "konveyor.io/dep-license=BSD-3-Clause", "konveyor.io/dep-license=MIT"},
+	}
+	s := NewSBOM("app", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	s.AddDepTrees([]DepsTreeItem{{
+		FileURI:      "file:///app/pom.xml",
+		Provider:     "java",
+		Dependencies: []DepDAGItem{{Dep: core, AddedDeps: []DepDAGItem{{Dep: log4j}}}},
+	}})
+	s.AddDeps([]DepsFlatItem{{
+		FileURI:      "file:///app/go.mod",
+		Provider:     "go",
+		Dependencies: []*Dep{&text},
+	}})
+	return s
+}
+
+func TestSBOMCycloneDX(t *testing.T) {
+	b, err := testSBOM().Marshal(CycloneDXFormat)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc := cdxDocument{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("unable to read the document: %v", err)
+	}
+	if doc.SpecVersion != "1.5" || doc.Metadata.Timestamp != "2024-05-01T12:00:00Z" || doc.Metadata.Component.Name != "app" {
+		t.Errorf("unexpected document %s %s %s", doc.SpecVersion, doc.Metadata.Timestamp, doc.Metadata.Component.Name)
+	}
+	refs := []string{}
+	for _, c := range doc.Components {
+		refs = append(refs, c.PURL)
+	}
+	expectedRefs := []string{"pkg:golang/golang.org/x/text@v0.14.0", "pkg:maven/com.example/app-core@1.0", "pkg:maven/log4j/log4j@1.2.17"}
+	if !reflect.DeepEqual(refs, expectedRefs) {
+		t.Fatalf("expected components %v, got %v", expectedRefs, refs)
+	}
+	log4j := doc.Components[2]
+	if len(log4j.Licenses) != 1 || log4j.Licenses[0].License.ID != "Apache-2.0" {
+		t.Errorf("unexpected licenses %v", log4j.Licenses)
+	}
+	if len(log4j.Hashes) != 1 || log4j.Hashes[0].Alg != "SHA-1" {
+		t.Errorf("unexpected hashes %v", log4j.Hashes)
+	}
+	expectedProperties := []cdxProperty{
+		{Name: "konveyor.io/dep-license", Value: "Apache-2.0"},
+		{Name: "konveyor.io/dep-source", Value: "open-source"},
+		{Name: "konveyor.io/language", Value: "java"},
+		{Name: "konveyor.io/provider", Value: "java"},
+		{Name: "konveyor:file", Value: "file:///app/pom.xml"},
+	}
+	if !reflect.DeepEqual(log4j.Properties, expectedProperties) {
+		t.Errorf("expected properties %v, got %v", expectedProperties, log4j.Properties)
+	}
+	if len(doc.Components[1].Licenses) != 0 {
+		t.Errorf("expected no license for an unknown license, got %v", doc.Components[1].Licenses)
+	}
+	expectedDependencies := []cdxDependency{
+		{Ref: "application", DependsOn: []string{"pkg:golang/golang.org/x/text@v0.14.0", "pkg:maven/com.example/app-core@1.0"}},
+		{Ref: "pkg:golang/golang.org/x/text@v0.14.0", DependsOn: []string{}},
+		{Ref: "pkg:maven/com.example/app-core@1.0", DependsOn: []string{"pkg:maven/log4j/log4j@1.2.17"}},
+		{Ref: "pkg:maven/log4j/log4j@1.2.17", DependsOn: []string{}},
+	}
+	if !reflect.DeepEqual(doc.Dependencies, expectedDependencies) {
+		t.Errorf("expected dependencies %v, got %v", expectedDependencies, doc.Dependencies)
+	}
+
+	again, _ := testSBOM().CycloneDX()
+	if string(again) != string(b) {
+		t.Errorf("expected the same document for the same dependencies")
+	}
+}
+
+func TestSBOMSPDX(t *testing.T) {
+	b, err := testSBOM().Marshal(SPDXFormat)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc := spdxDocument{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("unable to read the document: %v", err)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || len(doc.Packages) != 4 {
+		t.Fatalf("unexpected document %s with %d packages", doc.SPDXVersion, len(doc.Packages))
+	}
+	text := doc.Packages[1]
+	if text.Name != "golang.org/x/text" || text.LicenseDeclared != "(BSD-3-Clause AND MIT)" {
+		t.Errorf("unexpected package %s with license %s", text.Name, text.LicenseDeclared)
+	}
+	if doc.Packages[2].LicenseDeclared != "NOASSERTION" {
+		t.Errorf("expected no assertion for an unknown license, got %s", doc.Packages[2].LicenseDeclared)
+	}
+	expectedRelationships := []spdxRelationship{
+		{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-Application"},
+		{SPDXElementID: "SPDXRef-Package-2", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-Package-3"},
+		{SPDXElementID: "SPDXRef-Application", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-Package-1"},
+		{SPDXElementID: "SPDXRef-Application", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-Package-2"},
+	}
+	if !reflect.DeepEqual(doc.Relationships, expectedRelationships) {
+		t.Errorf("expected relationships %v, got %v", expectedRelationships, doc.Relationships)
+	}
+
+	if _, err := testSBOM().Marshal("swid"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,53 @@
+package konveyor
+
+// Severity is how important it is to fix the violations of a rule, unlike
+// the category it says nothing about the migration and unlike the effort
+// nothing about the cost of the fix.
+type Severity string
+
+const (
+	Critical Severity = "critical"
+	High     Severity = "high"
+	Medium   Severity = "medium"
+	Low      Severity = "low"
+)
+
+// Severities from the most to the least severe
+var Severities = []Severity{Critical, High, Medium, Low}
+
+var severityRank = map[Severity]int{
+	Low:      1,
+	Medium:   2,
+	High:     3,
+	Critical: 4,
+}
+
+// Valid reports whether the severity is one of Severities
+func (s Severity) Valid() bool {
+	_, ok := severityRank[s]
+	return ok
+}
+
+// AtLeast reports whether the severity is the threshold or more severe
+func (s Severity) AtLeast(threshold Severity) bool {
+	return severityRank[s] >= severityRank[threshold]
+}
+
+// ApplySeverityThreshold removes the violations below the threshold from the
+// rulesets, violations without a severity are below every threshold. It
+// returns the number of violations removed.
+func ApplySeverityThreshold(ruleSets []RuleSet, threshold Severity) int {
+	if threshold == "" {
+		return 0
+	}
+	removed := 0
+	for _, ruleSet := range ruleSets {
+		for id, violation := range ruleSet.Violations {
+			if violation.Severity == nil || !violation.Severity.AtLeast(threshold) {
+				delete(ruleSet.Violations, id)
+				removed++
+			}
+		}
+	}
+	return removed
+}
@@ -0,0 +1,34 @@
+package konveyor
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestApplySeverityThreshold(t *testing.T) {
+	severity := func(s Severity) *Severity { return &s }
+	ruleSets := []RuleSet{{
+		Name: "rules",
+		Violations: map[string]Violation{
+			"critical-001": {Severity: severity(Critical)},
+			"high-001":     {Severity: severity(High)},
+			"low-001":      {Severity: severity(Low)},
+			"none-001":     {},
+		},
+	}}
+	if removed := ApplySeverityThreshold(ruleSets, High); removed != 2 {
+		t.Errorf("expected 2 violations to be removed, got %d", removed)
+	}
+	got := []string{}
+	for id := range ruleSets[0].Violations {
+		got = append(got, id)
+	}
+	sort.Strings(got)
+	if want := []string{"critical-001", "high-001"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected violations %v, got %v", want, got)
+	}
+	if removed := ApplySeverityThreshold(ruleSets, ""); removed != 0 {
+		t.Errorf("expected no violations to be removed without a threshold, got %d", removed)
+	}
+}
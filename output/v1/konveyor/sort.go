@@ -0,0 +1,88 @@
+package konveyor
+
+import "sort"
+
+// The outputs are sorted so that analyses of the same application with the
+// same rules write the same outputs, whatever the order the rules were
+// evaluated in. Strings are compared byte by byte, in the order of their
+// Unicode code points with upper case before lower case, and numbers
+// numerically.
+//
+//   - rulesets by name
+//   - tags, labels and the rule IDs of unmatched, skipped and canceled rules
+//   - violations, insights and errors by rule ID, they are maps
+//   - incidents by URI, line, message, code snippet, branch, fingerprint and
+//     variables, a missing line number is line 0
+//   - warnings by provider and message, links by title and URL
+//   - dependencies by provider and file, and then by name, version, type,
+//     classifier, indirect after direct, resolved identifier, labels and
+//     file prefix
+
+// SortRuleSets sorts the rulesets and every field of the rulesets. YAML
+// sorts the fields of the rulesets when they are marshaled, JSON does not.
+func SortRuleSets(ruleSets []RuleSet) {
+	sort.SliceStable(ruleSets, func(i, j int) bool {
+		return ruleSets[i].Name < ruleSets[j].Name
+	})
+	for i := range ruleSets {
+		ruleSets[i].sortFields()
+		for _, violations := range []map[string]Violation{ruleSets[i].Violations, ruleSets[i].Insights} {
+			for id, violation := range violations {
+				violation.sortFields()
+				violations[id] = violation
+			}
+		}
+	}
+}
+
+// SortDepsFlat sorts the dependency lists by provider and file and the
+// dependencies of every list
+func SortDepsFlat(items []DepsFlatItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return cmpItem(items[i].Provider, items[i].FileURI, items[j].Provider, items[j].FileURI)
+	})
+	for i := range items {
+		for _, dep := range items[i].Dependencies {
+			dep.sortFields()
+		}
+		items[i].sortFields()
+	}
+}
+
+// SortDepsTree sorts the dependency trees by provider and file and the
+// dependencies of every level of the trees
+func SortDepsTree(items []DepsTreeItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return cmpItem(items[i].Provider, items[i].FileURI, items[j].Provider, items[j].FileURI)
+	})
+	for i := range items {
+		for j := range items[i].Dependencies {
+			sortDAGItem(&items[i].Dependencies[j])
+		}
+		items[i].sortFields()
+	}
+}
+
+func sortDAGItem(item *DepDAGItem) {
+	item.Dep.sortFields()
+	for i := range item.AddedDeps {
+		sortDAGItem(&item.AddedDeps[i])
+	}
+	item.sortFields()
+}
+
+func cmpItem(provider, fileURI, otherProvider, otherFileURI string) bool {
+	if provider != otherProvider {
+		return provider < otherProvider
+	}
+	return fileURI < otherFileURI
+}
+
+func sortMatches(matches []IncidentMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].RuleSet != matches[j].RuleSet {
+			return matches[i].RuleSet < matches[j].RuleSet
+		}
+		return matches[i].RuleID < matches[j].RuleID
+	})
+}
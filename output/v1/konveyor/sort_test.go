@@ -0,0 +1,86 @@
+package konveyor
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSortRuleSets(t *testing.T) {
+	one, two := 1, 2
+	newRuleSets := func(reversed bool) []RuleSet {
+		incidents := []Incident{
+			{URI: "file:///app/a.java", LineNumber: &two, Message: "a"},
+			{URI: "file:///app/a.java", Message: "b"},
+			{URI: "file:///app/a.java", LineNumber: &one, Message: "c", Variables: map[string]interface{}{"name": "y"}},
+			{URI: "file:///app/a.java", LineNumber: &one, Message: "c", Variables: map[string]interface{}{"name": "x"}},
+			{URI: "file:///app/B.java", LineNumber: &two, Message: "a"},
+		}
+		suppressed := []Incident{incidents[1], incidents[0]}
+		if reversed {
+			suppressed[0], suppressed[1] = suppressed[1], suppressed[0]
+		}
+		ruleSets := []RuleSet{
+			{Name: "quarkus", Tags: []string{"b", "a"}},
+			{
+				Name: "eap",
+				Violations: map[string]Violation{"eap-1": {
+					Labels:    []string{"konveyor.io/target=quarkus", "konveyor.io/source=eap"},
+					Incidents: incidents,
+				}},
+				Suppressed: map[string][]Incident{"eap-2": suppressed},
+			},
+		}
+		if reversed {
+			for i, j := 0, len(incidents)-1; i < j; i, j = i+1, j-1 {
+				incidents[i], incidents[j] = incidents[j], incidents[i]
+			}
+			ruleSets[0], ruleSets[1] = ruleSets[1], ruleSets[0]
+		}
+		return ruleSets
+	}
+	sorted, reversed := newRuleSets(false), newRuleSets(true)
+	SortRuleSets(sorted)
+	SortRuleSets(reversed)
+	b, _ := json.Marshal(sorted)
+	reversedB, _ := json.Marshal(reversed)
+	if string(b) != string(reversedB) {
+		t.Errorf("SortRuleSets() of the same rulesets in another order =\n%s\nwant\n%s", reversedB, b)
+	}
+
+	if names := []string{sorted[0].Name, sorted[1].Name}; !reflect.DeepEqual(names, []string{"eap", "quarkus"}) {
+		t.Errorf("rulesets sorted as %v, want by name", names)
+	}
+	got := []string{}
+	for _, incident := range sorted[0].Violations["eap-1"].Incidents {
+		got = append(got, string(incident.URI)+" "+incident.Message)
+		if incident.Message == "b" && incident.LineNumber != nil {
+			t.Errorf("a missing line number must be left missing")
+		}
+	}
+	want := []string{"file:///app/B.java a", "file:///app/a.java b", "file:///app/a.java c", "file:///app/a.java c", "file:///app/a.java a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incidents sorted as %v, want by URI, line and message %v", got, want)
+	}
+	if name := sorted[0].Violations["eap-1"].Incidents[2].Variables["name"]; name != "x" {
+		t.Errorf("incidents of the same line and message must be sorted by variables, got %v first", name)
+	}
+}
+
+func TestSortDepsFlat(t *testing.T) {
+	items := []DepsFlatItem{
+		{Provider: "java", FileURI: "file:///app/pom.xml", Dependencies: []*Dep{{Name: "b"}, {Name: "a", Classifier: "tests"}, {Name: "a", Labels: []string{"y", "x"}}}},
+		{Provider: "go", FileURI: "file:///app/go.mod"},
+	}
+	SortDepsFlat(items)
+	if items[0].Provider != "go" {
+		t.Errorf("dependency lists must be sorted by provider, got %s first", items[0].Provider)
+	}
+	deps := items[1].Dependencies
+	if deps[0].Classifier != "" || deps[1].Classifier != "tests" || deps[2].Name != "b" {
+		t.Errorf("dependencies sorted as %v %v %v, want by name and classifier", *deps[0], *deps[1], *deps[2])
+	}
+	if !reflect.DeepEqual(deps[0].Labels, []string{"x", "y"}) {
+		t.Errorf("labels of dependencies must be sorted, got %v", deps[0].Labels)
+	}
+}
@@ -0,0 +1,74 @@
+package konveyor
+
+import "sort"
+
+// TaskSummary rolls up the violations of the rules of a migration task, the
+// related rules fixed together as one work item.
+type TaskSummary struct {
+	// Name of the task given with task in the rules
+	Name string `yaml:"name" json:"name"`
+	// Rules are the IDs of the rules of the task that have violations, as
+	// <ruleSet>/<ruleID>
+	Rules []string `yaml:"rules" json:"rules"`
+	// Incidents is the number of incidents of all the violations
+	Incidents int `yaml:"incidents" json:"incidents"`
+	// Effort is the effort of all the violations, the weighted effort is
+	// used for the violations that have one
+	Effort float64 `yaml:"effort" json:"effort"`
+	// Category is the most severe category of the violations
+	Category *Category `yaml:"category,omitempty" json:"category,omitempty"`
+}
+
+var categorySeverity = map[Category]int{
+	Optional:  1,
+	Potential: 2,
+	Mandatory: 3,
+}
+
+// NewTaskSummaries rolls up the violations of the rulesets by task, sorted
+// by name. Violations of rules without a task are not part of any summary.
+func NewTaskSummaries(ruleSets []RuleSet) []TaskSummary {
+	tasks := map[string]*TaskSummary{}
+	for _, ruleSet := range ruleSets {
+		for id, violation := range ruleSet.Violations {
+			if violation.Task == "" {
+				continue
+			}
+			task, ok := tasks[violation.Task]
+			if !ok {
+				task = &TaskSummary{Name: violation.Task, Rules: []string{}}
+				tasks[violation.Task] = task
+			}
+			task.Rules = append(task.Rules, ruleSet.Name+"/"+id)
+			task.Incidents += len(violation.Incidents)
+			task.Effort += violationEffort(violation)
+			if violation.Category != nil &&
+				(task.Category == nil || categorySeverity[*violation.Category] > categorySeverity[*task.Category]) {
+				c := *violation.Category
+				task.Category = &c
+			}
+		}
+	}
+
+	summaries := []TaskSummary{}
+	for _, task := range tasks {
+		sort.Strings(task.Rules)
+		summaries = append(summaries, *task)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
+
+// violationEffort is the weighted effort of the violation, or its effort for
+// every incident when it has none
+func violationEffort(violation Violation) float64 {
+	if violation.WeightedEffort != nil {
+		return *violation.WeightedEffort
+	}
+	if violation.Effort != nil {
+		return LinearEffortModel.Weigh(*violation.Effort, len(violation.Incidents))
+	}
+	return 0
+}
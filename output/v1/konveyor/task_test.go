@@ -0,0 +1,65 @@
+package konveyor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewTaskSummaries(t *testing.T) {
+	effort := 3
+	weighted := 4.5
+	ruleSets := []RuleSet{
+		{
+			Name: "eap8",
+			Violations: map[string]Violation{
+				"jms-00002": {
+					Task:      "jms",
+					Category:  &Mandatory,
+					Effort:    &effort,
+					Incidents: []Incident{{}, {}},
+				},
+				"jms-00001": {
+					Task:      "jms",
+					Category:  &Optional,
+					Effort:    &effort,
+					Incidents: []Incident{{}},
+				},
+				"ejb-00001": {
+					Incidents: []Incident{{}},
+				},
+			},
+		},
+		{
+			Name: "quarkus",
+			Violations: map[string]Violation{
+				"jms-00001": {
+					Task:           "jms",
+					Effort:         &effort,
+					WeightedEffort: &weighted,
+					Incidents:      []Incident{{}, {}, {}},
+				},
+				"cdi-00001": {
+					Task:      "cdi",
+					Incidents: []Incident{{}},
+				},
+			},
+		},
+	}
+	want := []TaskSummary{
+		{
+			Name:      "cdi",
+			Rules:     []string{"quarkus/cdi-00001"},
+			Incidents: 1,
+		},
+		{
+			Name:      "jms",
+			Rules:     []string{"eap8/jms-00001", "eap8/jms-00002", "quarkus/jms-00001"},
+			Incidents: 6,
+			Effort:    13.5,
+			Category:  &Mandatory,
+		},
+	}
+	if got := NewTaskSummaries(ruleSets); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
@@ -33,23 +33,99 @@ type RuleSet struct {
 	// additional information about a tag.
 	Insights map[string]Violation `yaml:"insights,omitempty" json:"insights,omitempty"`
 
+	// OmittedInsights is the number of insights left out of the ruleset
+	// because of the limit of insights per ruleset.
+	OmittedInsights int `yaml:"omittedInsights,omitempty" json:"omittedInsights,omitempty"`
+
 	// Errors is a map containing errors generated during evaluation
 	// of rules in this ruleset. Keys are rule IDs, values are
 	// their respective generated errors.
-	Errors map[string]string `yaml:"errors,omitempty" json:"errors,omitempty"`
+	Errors map[string]RuleError `yaml:"errors,omitempty" json:"errors,omitempty"`
+
+	// Warnings is a map containing non-fatal issues found during evaluation
+	// of rules in this ruleset, such as files that could not be read. The
+	// results of these rules may be incomplete. Keys are rule IDs.
+	Warnings map[string][]Warning `yaml:"warnings,omitempty" json:"warnings,omitempty"`
+
+	// Suppressed is a map containing the incidents dropped because of a
+	// konveyor:ignore comment on their line or the line before it. Keys are
+	// rule IDs, values are the suppressed incidents of the rule.
+	Suppressed map[string][]Incident `yaml:"suppressed,omitempty" json:"suppressed,omitempty"`
 
 	// Unmatched is a list of rule IDs of the rules that weren't matched.
 	Unmatched []string `yaml:"unmatched,omitempty" json:"unmatched,omitempty"`
 
 	// Skipped is a list of rule IDs that were skipped
 	Skipped []string `yaml:"skipped,omitempty" json:"skipped,omitempty"`
+
+	// Canceled is a list of rule IDs that were not evaluated because the
+	// analysis was canceled, the results of the ruleset are partial.
+	Canceled []string `yaml:"canceled,omitempty" json:"canceled,omitempty"`
+}
+
+// Warning is a non-fatal issue found while evaluating a rule.
+type Warning struct {
+	// Provider is the name of the provider that reported the warning,
+	// empty when it was reported by the engine.
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+
+	// Message describes the issue.
+	Message string `yaml:"message" json:"message"`
+}
+
+// RuleError is an error found while evaluating a rule.
+type RuleError struct {
+	// Code classifies the error, it tells a broken rule from a broken
+	// environment, see ErrorCode.
+	Code ErrorCode `yaml:"code" json:"code"`
+
+	// Provider is the name of the provider that returned the error, empty
+	// when it was returned by the engine.
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+
+	// Message describes the error.
+	Message string `yaml:"message" json:"message"`
 }
 
+// ErrorCode classifies the errors of rules.
+type ErrorCode string
+
+const (
+	// ProviderUnavailable is a provider that could not be reached or was
+	// stopped, the environment is broken.
+	ProviderUnavailable ErrorCode = "ProviderUnavailable"
+	// ConditionTimeout is a condition that took too long to evaluate, the
+	// environment is likely overloaded.
+	ConditionTimeout ErrorCode = "ConditionTimeout"
+	// CapabilityUnsupported is a condition using a capability the provider
+	// doesn't have, the rule is broken or written for another provider.
+	CapabilityUnsupported ErrorCode = "CapabilityUnsupported"
+	// ParseError is a condition that could not be parsed, the rule is
+	// broken.
+	ParseError ErrorCode = "ParseError"
+	// UnknownError is any other error.
+	UnknownError ErrorCode = "Unknown"
+)
+
 // Sorts all fields in a canonical way on a RuleSet
 func (r *RuleSet) sortFields() {
 	sort.Strings(r.Tags)
 	sort.Strings(r.Unmatched)
 	sort.Strings(r.Skipped)
+	sort.Strings(r.Canceled)
+	for _, warnings := range r.Warnings {
+		sort.SliceStable(warnings, func(i, j int) bool {
+			if warnings[i].Provider != warnings[j].Provider {
+				return warnings[i].Provider < warnings[j].Provider
+			}
+			return warnings[i].Message < warnings[j].Message
+		})
+	}
+	for _, incidents := range r.Suppressed {
+		sort.SliceStable(incidents, func(i, j int) bool {
+			return incidents[i].cmpLess(&incidents[j])
+		})
+	}
 }
 
 func (r RuleSet) MarshalYAML() (interface{}, error) {
@@ -85,11 +161,26 @@ type Violation struct {
 	// TODO: add this to rules
 	Category *Category `yaml:"category,omitempty" json:"category,omitempty"`
 
+	// Severity is how important it is to fix the violation, one of critical,
+	// high, medium or low
+	Severity *Severity `yaml:"severity,omitempty" json:"severity,omitempty"`
+
 	Labels []string `yaml:"labels,omitempty" json:"labels,omitempty"`
 
+	// Task is the migration task the rule is part of, related rules share a
+	// task to be fixed as one work item
+	Task string `yaml:"task,omitempty" json:"task,omitempty"`
+
+	// Source is where the rule of the violation is defined
+	Source *RuleSource `yaml:"source,omitempty" json:"source,omitempty"`
+
 	// Incidents list of instances of violation found
 	Incidents []Incident `yaml:"incidents" json:"incidents"`
 
+	// OmittedIncidents is the number of incidents left out of the output
+	// because of the limit of incidents per insight.
+	OmittedIncidents int `yaml:"omittedIncidents,omitempty" json:"omittedIncidents,omitempty"`
+
 	// ExternalLinks hyperlinks to external sources of docs, fixes
 	Links []Link `yaml:"links,omitempty" json:"links,omitempty"`
 
@@ -98,6 +189,24 @@ type Violation struct {
 
 	// Effort defines expected story points for this incident
 	Effort *int `yaml:"effort,omitempty" json:"effort,omitempty"`
+
+	// WeightedEffort is the effort of all the incidents of the violation
+	// scaled with the selected effort model, it is only set when a model
+	// other than linear is used.
+	WeightedEffort *float64 `yaml:"weightedEffort,omitempty" json:"weightedEffort,omitempty"`
+}
+
+// RuleSource is where a rule is defined, for tools to link a violation back
+// to the definition of its rule
+type RuleSource struct {
+	// File is the rule file, as the path of the rules given to the analyzer,
+	// with forward slashes
+	File string `yaml:"file" json:"file"`
+	// Line is the line of the rule in the file
+	Line int `yaml:"line,omitempty" json:"line,omitempty"`
+	// URL is the URL of the rule file in the upstream repository of its
+	// ruleset, when the ruleset has a sourceURL
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
 }
 
 // Sorts all fields in a canonical way on a Violation
@@ -111,6 +220,10 @@ func (v *Violation) sortFields() {
 	sort.SliceStable(v.Links, func(i, j int) bool {
 		return v.Links[i].cmpLess(&v.Links[j])
 	})
+
+	for i := range v.Incidents {
+		sortMatches(v.Incidents[i].AlsoMatchedBy)
+	}
 }
 
 func (v Violation) MarshalYAML() (interface{}, error) {
@@ -131,14 +244,38 @@ type Incident struct {
 	// Extras json.RawMessage
 	LineNumber *int                   `yaml:"lineNumber,omitempty" json:"lineNumber,omitempty"`
 	Variables  map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
+
+	// Branch is the name of the and/or condition branch that found the incident
+	Branch string `yaml:"branch,omitempty" json:"branch,omitempty"`
+
+	// Baseline is set when the incident was already found in the baseline
+	// the analysis was compared to
+	Baseline bool `yaml:"baseline,omitempty" json:"baseline,omitempty"`
+
+	// Fix is the remediation of the incident given by its rule
+	Fix *Fix `yaml:"fix,omitempty" json:"fix,omitempty"`
+
+	// Fingerprint identifies the location of the incident for the family
+	// of its rule, see Fingerprint
+	Fingerprint string `yaml:"fingerprint,omitempty" json:"fingerprint,omitempty"`
+
+	// AlsoMatchedBy are the other rules that found the incident when the
+	// incidents with the same fingerprint are collapsed
+	AlsoMatchedBy []IncidentMatch `yaml:"alsoMatchedBy,omitempty" json:"alsoMatchedBy,omitempty"`
 }
 
-// Lexicographically compares two Incidents
+// Lexicographically compares two Incidents, by URI, line, message and code
+// snippet and then by the other fields so that no two different incidents
+// are equal. A missing line number is line 0.
 func (i *Incident) cmpLess(other *Incident) bool {
 	if i.URI != other.URI {
 		return i.URI < other.URI
 	}
 
+	if line, otherLine := lineNumber(i.LineNumber), lineNumber(other.LineNumber); line != otherLine {
+		return line < otherLine
+	}
+
 	if i.Message != other.Message {
 		return i.Message < other.Message
 	}
@@ -147,21 +284,25 @@ func (i *Incident) cmpLess(other *Incident) bool {
 		return i.CodeSnip < other.CodeSnip
 	}
 
-	if i.LineNumber == nil {
-		x := 0
-		i.LineNumber = &x
+	if i.Branch != other.Branch {
+		return i.Branch < other.Branch
 	}
 
-	if other.LineNumber == nil {
-		x := 0
-		other.LineNumber = &x
+	if i.Fingerprint != other.Fingerprint {
+		return i.Fingerprint < other.Fingerprint
 	}
 
-	if *(*i).LineNumber != *(*other).LineNumber {
-		return *(*i).LineNumber < *(*other).LineNumber
-	}
+	// the keys of the maps are sorted in JSON
+	variables, _ := json.Marshal(i.Variables)
+	otherVariables, _ := json.Marshal(other.Variables)
+	return string(variables) < string(otherVariables)
+}
 
-	return false
+func lineNumber(line *int) int {
+	if line == nil {
+		return 0
+	}
+	return *line
 }
 
 // Link defines an external hyperlink
@@ -196,6 +337,13 @@ type Dep struct {
 	Extras             map[string]interface{} `json:"extras,omitempty" yaml:"extras,omitempty"`
 	Labels             []string               `json:"labels,omitempty" yaml:"labels,omitempty"`
 	FileURIPrefix      string                 `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// Depth is 1 for the direct dependencies, 2 for the dependencies they add
+	// and so on. It is set on the dependencies of trees, 0 when unknown.
+	Depth int `json:"depth,omitempty" yaml:"depth,omitempty"`
+	// Path is the shortest chain of dependencies from a direct dependency to
+	// the one adding this dependency, as name@version, empty for the direct
+	// dependencies.
+	Path []string `json:"path,omitempty" yaml:"path,omitempty"`
 }
 
 // Sorts all fields in a canonical way on a Dep
@@ -217,6 +365,10 @@ func (d Dep) cmpLess(other Dep) bool {
 		return d.Type < other.Type
 	}
 
+	if d.Classifier != other.Classifier {
+		return d.Classifier < other.Classifier
+	}
+
 	if d.Indirect != other.Indirect {
 		return !d.Indirect && other.Indirect
 	}
@@ -250,6 +402,9 @@ func (d *Dep) GetLabels() []string {
 type DepDAGItem struct {
 	Dep       Dep          `yaml:"dep,omitempty" json:"dep,omitempty"`
 	AddedDeps []DepDAGItem `yaml:"addedDep,omitempty" json:"addedDep,omitempty"`
+	// Cycle is set when the dependency is one of its own ancestors, it lists the
+	// dependencies of the cycle and the added deps of this item are omitted.
+	Cycle []string `yaml:"cycle,omitempty" json:"cycle,omitempty"`
 }
 
 // Sorts all fields in a canonical way on a DepDAGItem
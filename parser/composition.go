@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	path "path/filepath"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"gopkg.in/yaml.v2"
+)
+
+// ruleSetComposition are the directives of a ruleset.yaml composing the
+// ruleset of the rules of other rulesets
+type ruleSetComposition struct {
+	// Include are the rule files or ruleset directories, relative to the
+	// ruleset, whose rules are added to the ruleset. The rules of the
+	// ruleset replace the included rules with the same ID.
+	Include []string `yaml:"include,omitempty"`
+	// Override change the rules of the ruleset, the included ones too
+	Override []konveyor.RuleOverride `yaml:"override,omitempty"`
+}
+
+// applyOverride changes the rule with the fields of the override
+func applyOverride(o konveyor.RuleOverride, rule *engine.Rule) {
+	if len(o.Labels) > 0 {
+		rule.Labels = labels.MergeLabels(o.Labels, rule.Labels)
+	}
+	if o.Effort != nil {
+		effort := *o.Effort
+		rule.Effort = &effort
+	}
+	if o.Severity != nil {
+		severity := *o.Severity
+		rule.Severity = &severity
+	}
+	if o.Description != "" {
+		rule.Description = o.Description
+	}
+	message := ""
+	if rule.Perform.Message.Text != nil {
+		message = *rule.Perform.Message.Text
+	}
+	if o.Message != "" {
+		message = o.Message
+	}
+	// only the rules with a message, violations, have their message suffixed
+	if message != "" && (o.Message != "" || o.MessageSuffix != "") {
+		message += o.MessageSuffix
+		rule.Perform.Message.Text = &message
+		// the rule may now be a violation, which always has a category
+		if rule.Category == nil {
+			rule.Category = &konveyor.Potential
+		}
+	}
+	if o.Category != nil {
+		category := *o.Category
+		rule.Category = &category
+	}
+}
+
+// OverrideRules applies the overrides to the rules and leaves the disabled
+// rules out, it returns the IDs of the overrides that matched no rule
+func OverrideRules(rules []engine.Rule, overrides []konveyor.RuleOverride) ([]engine.Rule, []string) {
+	byID := map[string][]konveyor.RuleOverride{}
+	for _, o := range overrides {
+		byID[o.RuleID] = append(byID[o.RuleID], o)
+	}
+	applied := map[string]bool{}
+	overridden := []engine.Rule{}
+	for _, rule := range rules {
+		disabled := false
+		for _, o := range byID[rule.RuleID] {
+			applied[o.RuleID] = true
+			disabled = disabled || o.Disabled
+			applyOverride(o, &rule)
+		}
+		if !disabled {
+			overridden = append(overridden, rule)
+		}
+	}
+	unmatched := []string{}
+	for _, o := range overrides {
+		if !applied[o.RuleID] {
+			unmatched = append(unmatched, o.RuleID)
+			applied[o.RuleID] = true
+		}
+	}
+	return overridden, unmatched
+}
+
+func loadRuleSetComposition(dir string) (ruleSetComposition, error) {
+	composition := ruleSetComposition{}
+	content, err := os.ReadFile(path.Join(dir, RULE_SET_GOLDEN_FILE_NAME))
+	if err != nil {
+		return composition, err
+	}
+	if err := yaml.Unmarshal(content, &composition); err != nil {
+		return composition, fmt.Errorf("unable to read the includes and overrides of %s: %w", dir, err)
+	}
+	for _, o := range composition.Override {
+		if err := o.Validate(); err != nil {
+			return composition, fmt.Errorf("invalid override in %s: %w", dir, err)
+		}
+	}
+	return composition, nil
+}
+
+// composeRules adds the included rules to the rules of the ruleset in the
+// directory and applies its overrides
+func (r *RuleParser) composeRules(dir string, rules []engine.Rule, clientMap map[string]provider.InternalProviderClient) ([]engine.Rule, error) {
+	composition, err := loadRuleSetComposition(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(composition.Include) == 0 && len(composition.Override) == 0 {
+		return rules, nil
+	}
+	abs, err := path.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if r.including == nil {
+		r.including = map[string]bool{}
+	}
+	if r.including[abs] {
+		return nil, fmt.Errorf("ruleset %s includes itself", dir)
+	}
+	r.including[abs] = true
+	defer delete(r.including, abs)
+
+	own := map[string]bool{}
+	for _, rule := range rules {
+		own[rule.RuleID] = true
+	}
+	composed := append([]engine.Rule{}, rules...)
+	for _, include := range composition.Include {
+		if !path.IsAbs(include) {
+			include = path.Join(dir, include)
+		}
+		ruleSets, m, err := r.LoadRules(include)
+		if err != nil {
+			return nil, fmt.Errorf("unable to include %s in the ruleset %s: %w", include, dir, err)
+		}
+		for k, v := range m {
+			clientMap[k] = v
+		}
+		for _, ruleSet := range ruleSets {
+			for _, rule := range ruleSet.Rules {
+				if own[rule.RuleID] {
+					r.Log.V(5).Info("included rule replaced by the rule of the ruleset", "ruleID", rule.RuleID, "ruleset", dir)
+					continue
+				}
+				// the included rules keep the labels of their ruleset
+				rule.Labels = append(labels.MergeLabels(rule.Labels, ruleSet.DefaultLabels), ruleSet.Labels...)
+				composed = append(composed, rule)
+			}
+		}
+	}
+	composed, unmatched := OverrideRules(composed, composition.Override)
+	if len(unmatched) > 0 {
+		r.Log.Info("overrides of rules not in the ruleset", "ruleset", dir, "ruleIDs", unmatched)
+	}
+	return composed, nil
+}
@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"os"
+	path "path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"gopkg.in/yaml.v2"
+)
+
+// UnavailableProviderRules returns the rules of the file or directory using
+// providers, or capabilities of them, that are not in the providers of the
+// parser. Those rules can't be loaded, LoadRules fails the files they are in.
+func (r *RuleParser) UnavailableProviderRules(filepath string) ([]konveyor.CoverageRule, error) {
+	rules := []konveyor.CoverageRule{}
+	err := r.walkRuleFiles(filepath, func(rule konveyor.CoverageRule, ruleMap map[interface{}]interface{}) {
+		if providers := r.ruleUnavailableProviders(ruleMap); len(providers) > 0 {
+			rule.Providers = providers
+			rules = append(rules, rule)
+		}
+	})
+	return rules, err
+}
+
+// Reasons the parser leaves rules out of the rulesets, see LeftOutRules
+const (
+	// UnavailableProviderReason is the reason of the rules using providers
+	// that are not in the providers of the parser
+	UnavailableProviderReason = "unavailable-provider"
+	// MissingCapabilityReason is the reason of the rules using capabilities
+	// the providers of the parser don't have
+	MissingCapabilityReason = "missing-capability"
+	// NoDependencyRulesReason is the reason of the rules left without
+	// conditions as the parser drops the dependency conditions
+	NoDependencyRulesReason = "no-dependency-rules"
+	// NotLoadedReason is the reason of the other rules, invalid or in a file
+	// with an invalid rule, the logs of the parser tell what is invalid
+	NotLoadedReason = "not-loaded"
+)
+
+// LeftOutRule is a rule of the rule files that is not in the rulesets
+// loaded from them
+type LeftOutRule struct {
+	konveyor.CoverageRule
+	Reason string
+}
+
+// LeftOutRules returns the rules of the file or directory that are not in
+// the rulesets loaded from it, with the reason they are left out
+func (r *RuleParser) LeftOutRules(filepath string, ruleSets []engine.RuleSet) ([]LeftOutRule, error) {
+	loaded := map[string]bool{}
+	for _, ruleSet := range ruleSets {
+		for _, rule := range ruleSet.Rules {
+			loaded[rule.RuleID] = true
+		}
+	}
+	rules := []LeftOutRule{}
+	err := r.walkRuleFiles(filepath, func(rule konveyor.CoverageRule, ruleMap map[interface{}]interface{}) {
+		if loaded[rule.RuleID] {
+			return
+		}
+		leftOut := LeftOutRule{CoverageRule: rule, Reason: NotLoadedReason}
+		if providers := r.ruleUnavailableProviders(ruleMap); len(providers) > 0 {
+			leftOut.Providers = providers
+			leftOut.Reason = MissingCapabilityReason
+			for _, p := range providers {
+				providerName, _, _ := strings.Cut(p, ".")
+				if _, ok := r.ProviderNameToClient[providerName]; !ok {
+					leftOut.Reason = UnavailableProviderReason
+				}
+			}
+		} else if providers := ruleDependencyConditions(ruleMap); r.NoDependencyRules && len(providers) > 0 {
+			leftOut.Providers = providers
+			leftOut.Reason = NoDependencyRulesReason
+		}
+		rules = append(rules, leftOut)
+	})
+	return rules, err
+}
+
+// walkRuleFiles calls visit with the rules of the rule files of the file or
+// directory, the rules have their ruleset, ID and file
+func (r *RuleParser) walkRuleFiles(filepath string, visit func(rule konveyor.CoverageRule, ruleMap map[interface{}]interface{})) error {
+	return path.WalkDir(filepath, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() == RULE_SET_GOLDEN_FILE_NAME {
+			return nil
+		}
+		if ext := path.Ext(d.Name()); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".test.yaml") || strings.HasSuffix(d.Name(), ".test.yml") {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		ruleMaps := []map[interface{}]interface{}{}
+		if err := yaml.Unmarshal(content, &ruleMaps); err != nil {
+			// not a rule file
+			return nil
+		}
+		ruleSetName := ""
+		if ruleSet := r.loadRuleSet(path.Dir(p)); ruleSet != nil {
+			ruleSetName = ruleSet.Name
+		}
+		for _, ruleMap := range ruleMaps {
+			ruleID, _ := ruleMap["ruleID"].(string)
+			if ruleID == "" {
+				continue
+			}
+			visit(konveyor.CoverageRule{RuleSet: ruleSetName, RuleID: ruleID, File: p}, ruleMap)
+		}
+		return nil
+	})
+}
+
+// ruleUnavailableProviders returns the sorted provider conditions of the
+// rule that can't be evaluated by the providers of the parser
+func (r *RuleParser) ruleUnavailableProviders(ruleMap map[interface{}]interface{}) []string {
+	when, _ := ruleMap["when"].(map[interface{}]interface{})
+	unless, _ := ruleMap["unless"].(map[interface{}]interface{})
+	unavailable := map[string]bool{}
+	r.unavailableProviders(when, unavailable)
+	r.unavailableProviders(unless, unavailable)
+	providers := []string{}
+	for p := range unavailable {
+		providers = append(providers, p)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// ruleDependencyConditions returns the sorted dependency conditions of the
+// rule
+func ruleDependencyConditions(ruleMap map[interface{}]interface{}) []string {
+	dependencies := map[string]bool{}
+	for _, k := range []string{"when", "unless"} {
+		condition, _ := ruleMap[k].(map[interface{}]interface{})
+		walkProviderConditions(condition, func(key, _, capability string) {
+			if capability == "dependency" {
+				dependencies[key] = true
+			}
+		})
+	}
+	providers := []string{}
+	for p := range dependencies {
+		providers = append(providers, p)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+func (r *RuleParser) unavailableProviders(condition map[interface{}]interface{}, unavailable map[string]bool) {
+	walkProviderConditions(condition, func(key, providerName, capability string) {
+		client, ok := r.ProviderNameToClient[providerName]
+		if !ok || !provider.HasCapability(client.Capabilities(), capability) {
+			unavailable[key] = true
+		}
+	})
+}
+
+// walkProviderConditions calls visit with the provider conditions of the
+// condition, as <provider>.<capability>
+func walkProviderConditions(condition map[interface{}]interface{}, visit func(key, providerName, capability string)) {
+	for k, v := range condition {
+		key, _ := k.(string)
+		switch key {
+		case "and", "or", "conditions":
+			if m, ok := v.(map[interface{}]interface{}); ok {
+				// and with a scope
+				walkProviderConditions(m, visit)
+				continue
+			}
+			conditions, _ := v.([]interface{})
+			for _, c := range conditions {
+				if m, ok := c.(map[interface{}]interface{}); ok {
+					walkProviderConditions(m, visit)
+				}
+			}
+		default:
+			providerName, capability, ok := strings.Cut(key, ".")
+			if !ok {
+				continue
+			}
+			visit(key, providerName, capability)
+		}
+	}
+}
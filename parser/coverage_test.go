@@ -0,0 +1,127 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	ruleparser "github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func TestUnavailableProviderRules(t *testing.T) {
+	file := filepath.Join("testdata", "rule-or.yaml")
+	testCases := []struct {
+		name      string
+		providers map[string]provider.InternalProviderClient
+		want      []konveyor.CoverageRule
+	}{
+		{
+			name: "all providers available",
+			providers: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{caps: []provider.Capability{{Name: "file"}}},
+			},
+			want: []konveyor.CoverageRule{},
+		},
+		{
+			name: "capability unavailable",
+			providers: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{caps: []provider.Capability{{Name: "xml"}}},
+			},
+			want: []konveyor.CoverageRule{{RuleID: "file-001", File: file, Providers: []string{"builtin.file"}}},
+		},
+		{
+			name:      "provider unavailable",
+			providers: map[string]provider.InternalProviderClient{},
+			want:      []konveyor.CoverageRule{{RuleID: "file-001", File: file, Providers: []string{"builtin.file"}}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := ruleparser.RuleParser{ProviderNameToClient: tc.providers, Log: logr.Discard()}
+			got, err := parser.UnavailableProviderRules(file)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLeftOutRules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ruleset.yaml": "name: test\n",
+		"a.yaml": `- ruleID: file-001
+  message: go files
+  when:
+    builtin.file: "*.go"
+- ruleID: dep-001
+  message: old dependency
+  when:
+    java.dependency:
+      name: junit.junit
+      upperbound: 4.12.0
+`,
+		"b.yaml": `- ruleID: xml-001
+  message: xml files
+  when:
+    builtin.xml:
+      xpath: //beans
+- ruleID: file-002
+  message: json files
+  when:
+    builtin.file: "*.json"
+`,
+		"c.yaml": `- ruleID: go-001
+  message: go references
+  when:
+    go.referenced:
+      pattern: fmt.Println
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parser := ruleparser.RuleParser{
+		ProviderNameToClient: map[string]provider.InternalProviderClient{
+			"builtin": testProvider{caps: []provider.Capability{{Name: "file"}}},
+			"java":    testProvider{caps: []provider.Capability{{Name: "dependency"}}},
+		},
+		Log:               logr.Discard(),
+		NoDependencyRules: true,
+	}
+	ruleSets, _, _ := parser.LoadRules(dir)
+	got, err := parser.LeftOutRules(dir, ruleSets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ruleparser.LeftOutRule{
+		{
+			CoverageRule: konveyor.CoverageRule{RuleSet: "test", RuleID: "dep-001", File: filepath.Join(dir, "a.yaml"), Providers: []string{"java.dependency"}},
+			Reason:       ruleparser.NoDependencyRulesReason,
+		},
+		{
+			CoverageRule: konveyor.CoverageRule{RuleSet: "test", RuleID: "xml-001", File: filepath.Join(dir, "b.yaml"), Providers: []string{"builtin.xml"}},
+			Reason:       ruleparser.MissingCapabilityReason,
+		},
+		{
+			CoverageRule: konveyor.CoverageRule{RuleSet: "test", RuleID: "file-002", File: filepath.Join(dir, "b.yaml")},
+			Reason:       ruleparser.NotLoadedReason,
+		},
+		{
+			CoverageRule: konveyor.CoverageRule{RuleSet: "test", RuleID: "go-001", File: filepath.Join(dir, "c.yaml"), Providers: []string{"go.referenced"}},
+			Reason:       ruleparser.UnavailableProviderReason,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
@@ -39,6 +39,11 @@ func CreateSchema() (openapi3.ComponentsSchemas, error) {
 						Type: &provider.SchemaTypeNumber,
 					},
 				},
+				"task": {
+					Schema: &openapi3.Schema{
+						Type: &provider.SchemaTypeString,
+					},
+				},
 				"category": {
 					Schema: &openapi3.Schema{
 						Type: &provider.SchemaTypeString,
@@ -55,6 +60,39 @@ func CreateSchema() (openapi3.ComponentsSchemas, error) {
 						},
 					},
 				},
+				"severity": {
+					Schema: &openapi3.Schema{
+						Type: &provider.SchemaTypeString,
+						OneOf: []openapi3.SchemaOrRef{
+							{
+								Schema: &openapi3.Schema{
+									Enum: []interface{}{
+										"critical",
+										"high",
+										"medium",
+										"low",
+									},
+								},
+							},
+						},
+					},
+				},
+				"cost": {
+					Schema: &openapi3.Schema{
+						Type: &provider.SchemaTypeString,
+						OneOf: []openapi3.SchemaOrRef{
+							{
+								Schema: &openapi3.Schema{
+									Enum: []interface{}{
+										"low",
+										"medium",
+										"high",
+									},
+								},
+							},
+						},
+					},
+				},
 				"customVariable": {
 					Schema: &openapi3.Schema{
 						Type: &provider.SchemaTypeArray,
@@ -13,6 +13,7 @@ import (
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 const (
@@ -43,6 +44,13 @@ type RuleParser struct {
 	Log                  logr.Logger
 	NoDependencyRules    bool
 	DepLabelSelector     *labels.LabelSelector[*provider.Dep]
+	// DependencyCache is shared by every dependency condition when set
+	DependencyCache *provider.DependencyConditionCache
+	// ConditionResultCache is shared by every provider condition when set
+	ConditionResultCache *provider.ConditionResultCache
+
+	// including are the rulesets whose includes are being loaded
+	including map[string]bool
 }
 
 func (r *RuleParser) loadRuleSet(dir string) *engine.RuleSet {
@@ -95,9 +103,16 @@ func (r *RuleParser) LoadRules(filepath string) ([]engine.RuleSet, map[string]pr
 		}
 
 		ruleSet := r.loadRuleSet(path.Dir(filepath))
-		// if nil, use the default rule set
+		// if nil, use a copy of the default rule set
 		if ruleSet == nil {
-			ruleSet = defaultRuleSet
+			defaultCopy := *defaultRuleSet
+			ruleSet = &defaultCopy
+		} else {
+			withSourceURL(rules, ruleSet.SourceURL)
+			rules, err = r.composeRules(path.Dir(filepath), rules, m)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
 		ruleSet.Rules = rules
 
@@ -159,8 +174,14 @@ func (r *RuleParser) LoadRules(filepath string) ([]engine.RuleSet, map[string]pr
 	}
 
 	if ruleSet != nil {
-		ruleSet.Rules = rules
-		ruleSets = append(ruleSets, *ruleSet)
+		withSourceURL(rules, ruleSet.SourceURL)
+		rules, err = r.composeRules(filepath, rules, clientMap)
+		if err != nil {
+			parserErr.errs = append(parserErr.errs, err)
+		} else {
+			ruleSet.Rules = rules
+			ruleSets = append(ruleSets, *ruleSet)
+		}
 	}
 	// Return nil if there are no captured errors
 	if len(parserErr.errs) == 0 {
@@ -193,7 +214,8 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 	ruleIDMap := map[string]*struct{}{}
 	providers := map[string]provider.InternalProviderClient{}
 	rulesParsed := 0
-	for _, ruleMap := range ruleMap {
+	lines := ruleLines(content)
+	for i, ruleMap := range ruleMap {
 		ruleID, ok := ruleMap["ruleID"].(string)
 		if !ok {
 			r.Log.V(8).Info("ruleID not found", "file", filepath)
@@ -267,6 +289,15 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 			}
 		}
 
+		if fixRaw, ok := ruleMap["fix"]; ok {
+			fix, err := parseFix(fixRaw)
+			if err != nil {
+				r.Log.V(8).Error(err, "failed parsing fix", "ruleID", ruleID, "file", filepath)
+				return nil, nil, err
+			}
+			perform.Fix = fix
+		}
+
 		if err := perform.Validate(); err != nil {
 			r.Log.V(8).Error(err, "failed validating perform", "ruleID", ruleID, "file", filepath)
 			return nil, nil, err
@@ -280,6 +311,10 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 		}
 
 		r.addRuleFields(&rule, ruleMap)
+		rule.Source = &konveyor.RuleSource{File: path.ToSlash(filepath)}
+		if i < len(lines) {
+			rule.Source.Line = lines[i]
+		}
 
 		whenMap, ok := ruleMap["when"].(map[interface{}]interface{})
 		if !ok {
@@ -291,6 +326,7 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 		var as string
 		var ignorable bool
 		var not bool
+		var filterBySource *regexp.Regexp
 		fromRaw, ok := whenMap["from"]
 		if ok {
 			delete(whenMap, "from")
@@ -330,6 +366,25 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 				return nil, nil, fmt.Errorf("not must be a boolean, not %v", notKeywordRaw)
 			}
 		}
+		filterBySourceRaw, ok := whenMap["filterBySource"]
+		if ok {
+			delete(whenMap, "filterBySource")
+			filterBySource, err = parseFilterBySource(filterBySourceRaw)
+			if err != nil {
+				r.Log.V(8).Info("filterBySource must be a valid regex", "ruleID", ruleID, "file", filepath)
+				return nil, nil, err
+			}
+		}
+
+		// conditions are the named conditions of an expression, parsed with it.
+		exprConditionsRaw, ok := whenMap["conditions"]
+		if ok {
+			delete(whenMap, "conditions")
+			if _, ok := whenMap["expr"]; !ok {
+				r.Log.V(8).Info("conditions must be used with expr", "ruleID", ruleID, "file", filepath)
+				return nil, nil, fmt.Errorf("conditions must be used with expr")
+			}
+		}
 
 		noConditions := false
 		for k, value := range whenMap {
@@ -370,10 +425,10 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 				}
 			case "and":
 				//Handle when clause
-				m, ok := value.([]interface{})
-				if !ok {
-					r.Log.V(8).Info("invalid type for and clause, must be an array", "ruleID", ruleID, "file", filepath)
-					return nil, nil, fmt.Errorf("invalid type for and clause, must be an array")
+				m, fileScoped, err := getAndConditions(value)
+				if err != nil {
+					r.Log.V(8).Info(err.Error(), "ruleID", ruleID, "file", filepath)
+					return nil, nil, err
 				}
 				conditions, provs, err := r.getConditions(m)
 				if err != nil {
@@ -383,7 +438,30 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 				if len(conditions) == 0 {
 					noConditions = true
 				}
-				rule.When = engine.AndCondition{Conditions: conditions}
+				rule.When = engine.AndCondition{Conditions: conditions, FileScoped: fileScoped}
+				snippers := []engine.CodeSnip{}
+				for k, prov := range provs {
+					if snip, ok := prov.(engine.CodeSnip); ok {
+						snippers = append(snippers, snip)
+					}
+					providers[k] = prov
+				}
+				if len(snippers) > 0 {
+					rule.Snipper = provider.CodeSnipProvider{
+						Providers: snippers,
+					}
+				}
+			case "expr":
+				condition, provs, err := r.getExprCondition(value, exprConditionsRaw)
+				if err != nil {
+					r.Log.V(8).Error(err, "failed parsing expression", "ruleID", ruleID, "file", filepath)
+					return nil, nil, err
+				}
+				if condition == nil {
+					noConditions = true
+					continue
+				}
+				rule.When = *condition
 				snippers := []engine.CodeSnip{}
 				for k, prov := range provs {
 					if snip, ok := prov.(engine.CodeSnip); ok {
@@ -424,6 +502,7 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 					ProviderSpecificConfig: condition,
 					Ignorable:              ignorable,
 					Not:                    not,
+					FilterBySource:         filterBySource,
 				}
 				rule.When = c
 				if snipper, ok := provider.(engine.CodeSnip); ok {
@@ -432,6 +511,38 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 				providers[providerKey] = provider
 			}
 		}
+		// filterBySource of an and, or or expr applies to their combined incidents
+		if _, ok := rule.When.(engine.ConditionEntry); !ok && rule.When != nil && filterBySource != nil {
+			rule.When = engine.ConditionEntry{
+				ProviderSpecificConfig: rule.When,
+				FilterBySource:         filterBySource,
+			}
+		}
+		if unlessRaw, ok := ruleMap["unless"]; ok {
+			unlessMap, ok := unlessRaw.(map[interface{}]interface{})
+			if !ok {
+				r.Log.V(8).Info("unless must be a single condition", "ruleID", ruleID, "file", filepath)
+				return nil, nil, fmt.Errorf("unless must be a single condition")
+			}
+			conditions, provs, err := r.getConditions([]interface{}{unlessMap})
+			if err != nil {
+				r.Log.V(8).Error(err, "failed parsing unless condition", "ruleID", ruleID, "file", filepath)
+				return nil, nil, err
+			}
+			switch len(conditions) {
+			case 0:
+				// without its unless condition the rule would match where it should not
+				noConditions = true
+			case 1:
+				rule.Unless = conditions[0]
+			default:
+				r.Log.V(8).Info("unless must be a single condition", "ruleID", ruleID, "file", filepath)
+				return nil, nil, fmt.Errorf("unless must be a single condition")
+			}
+			for k, prov := range provs {
+				providers[k] = prov
+			}
+		}
 		if noConditions || rule.When == nil {
 			r.Log.V(5).Info("skipping rule no conditions found", "rule", rule.RuleID)
 			continue
@@ -450,6 +561,33 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 	return append(infoRules, rules...), providers, nil
 }
 
+// ruleLines returns the lines the rules of the rule file start at, in the
+// order of the rules
+func ruleLines(content []byte) []int {
+	doc := yamlv3.Node{}
+	if err := yamlv3.Unmarshal(content, &doc); err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yamlv3.SequenceNode {
+		return nil
+	}
+	lines := []int{}
+	for _, n := range doc.Content[0].Content {
+		lines = append(lines, n.Line)
+	}
+	return lines
+}
+
+// withSourceURL sets the URLs of the rules of a ruleset, the URLs of their
+// files under the source URL of the ruleset
+func withSourceURL(rules []engine.Rule, sourceURL string) {
+	if sourceURL == "" {
+		return
+	}
+	for _, rule := range rules {
+		if rule.Source != nil {
+			rule.Source.URL = strings.TrimSuffix(sourceURL, "/") + "/" + path.Base(rule.Source.File)
+		}
+	}
+}
+
 func validateRuleID(ruleID string) (string, bool) {
 	if strings.Contains(ruleID, "\n") {
 		return "rule id can not contain string", false
@@ -497,6 +635,24 @@ func (r *RuleParser) addRuleFields(rule *engine.Rule, ruleMap map[string]interfa
 		}
 	}
 
+	if severity, ok := ruleMap["severity"].(string); ok {
+		s := konveyor.Severity(strings.ToLower(severity))
+		if s.Valid() {
+			rule.Severity = &s
+		} else {
+			r.Log.V(8).WithValues("ruleID", rule.RuleID).Info(fmt.Sprintf("unknown severity: %v, ignoring it", severity))
+		}
+	}
+
+	if cost, ok := ruleMap["cost"].(string); ok {
+		c := engine.Cost(strings.ToLower(cost))
+		if c.Valid() {
+			rule.Cost = &c
+		} else {
+			r.Log.V(8).WithValues("ruleID", rule.RuleID).Info(fmt.Sprintf("unknown cost: %v, ignoring it", cost))
+		}
+	}
+
 	effort, ok := ruleMap["effort"].(int)
 	if !ok {
 		r.Log.V(8).WithValues("ruleID", rule.RuleID).Info("unable to find effort")
@@ -505,6 +661,12 @@ func (r *RuleParser) addRuleFields(rule *engine.Rule, ruleMap map[string]interfa
 		rule.Effort = &effort
 	}
 
+	task, ok := ruleMap["task"].(string)
+	if !ok {
+		r.Log.V(8).WithValues("ruleID", rule.RuleID).Info("unable to find task")
+	}
+	rule.Task = task
+
 	if customVars, ok := ruleMap["customVariables"]; ok {
 		var customVarsList []interface{}
 		var ok bool
@@ -531,6 +693,39 @@ func (r *RuleParser) addRuleFields(rule *engine.Rule, ruleMap map[string]interfa
 	}
 }
 
+// parseFix parses the fix block of a rule
+func parseFix(fixRaw interface{}) (*engine.Fix, error) {
+	fixMap, ok := fixRaw.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("fix must be a map")
+	}
+	fix := engine.Fix{}
+	for key, value := range fixMap {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v of fix must be a string", key)
+		}
+		switch key {
+		case "description":
+			fix.Description = s
+		case "file":
+			fix.File = s
+		case "search":
+			fix.Search = s
+		case "replace":
+			fix.Replace = s
+		case "codeAction":
+			fix.CodeAction = s
+		default:
+			return nil, fmt.Errorf("%v is not a valid field of a fix", key)
+		}
+	}
+	if err := fix.Validate(); err != nil {
+		return nil, err
+	}
+	return &fix, nil
+}
+
 func (r *RuleParser) addCustomVarFields(m map[interface{}]interface{}, customVar *engine.CustomVariable) error {
 	if name, ok := m["name"]; ok {
 		nameString, ok := name.(string)
@@ -586,6 +781,9 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 		var as string
 		var ignorable bool
 		var not bool
+		var filterBySource *regexp.Regexp
+		var branch string
+		var message *string
 		fromRaw, ok := conditionMap["from"]
 		if ok {
 			delete(conditionMap, "from")
@@ -618,6 +816,39 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 				return nil, nil, fmt.Errorf("not must be a boolean, not %v", notKeywordRaw)
 			}
 		}
+		filterBySourceRaw, ok := conditionMap["filterBySource"]
+		if ok {
+			delete(conditionMap, "filterBySource")
+			var err error
+			filterBySource, err = parseFilterBySource(filterBySourceRaw)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		branchRaw, ok := conditionMap["branch"]
+		if ok {
+			delete(conditionMap, "branch")
+			branch, ok = branchRaw.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("branch must be a string literal, not %v", branchRaw)
+			}
+		}
+		messageRaw, ok := conditionMap["message"]
+		if ok {
+			delete(conditionMap, "message")
+			m, ok := messageRaw.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("message must be a string literal, not %v", messageRaw)
+			}
+			message = &m
+		}
+		exprConditionsRaw, ok := conditionMap["conditions"]
+		if ok {
+			delete(conditionMap, "conditions")
+			if _, ok := conditionMap["expr"]; !ok {
+				return nil, nil, fmt.Errorf("conditions must be used with expr")
+			}
+		}
 		for k, v := range conditionMap {
 			key, ok := k.(string)
 			if !ok {
@@ -626,9 +857,9 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 			var ce engine.ConditionEntry
 			switch key {
 			case "and":
-				iConditions, ok := v.([]interface{})
-				if !ok {
-					return nil, nil, fmt.Errorf("inner condition for and is not array")
+				iConditions, fileScoped, err := getAndConditions(v)
+				if err != nil {
+					return nil, nil, err
 				}
 				conds, provs, err := r.getConditions(iConditions)
 				if err != nil {
@@ -640,12 +871,14 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 					return []engine.ConditionEntry{}, nil, nil
 				}
 				ce = engine.ConditionEntry{
-					From:      from,
-					As:        as,
-					Ignorable: ignorable,
-					Not:       not,
+					From:           from,
+					As:             as,
+					Ignorable:      ignorable,
+					Not:            not,
+					FilterBySource: filterBySource,
 					ProviderSpecificConfig: engine.AndCondition{
 						Conditions: conds,
+						FileScoped: fileScoped,
 					},
 				}
 				for k, prov := range provs {
@@ -666,10 +899,11 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 					return []engine.ConditionEntry{}, nil, nil
 				}
 				ce = engine.ConditionEntry{
-					From:      from,
-					As:        as,
-					Ignorable: ignorable,
-					Not:       not,
+					From:           from,
+					As:             as,
+					Ignorable:      ignorable,
+					Not:            not,
+					FilterBySource: filterBySource,
 					ProviderSpecificConfig: engine.OrCondition{
 						Conditions: conds,
 					},
@@ -677,6 +911,27 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 				for k, prov := range provs {
 					providers[k] = prov
 				}
+			case "expr":
+				condition, provs, err := r.getExprCondition(v, exprConditionsRaw)
+				if err != nil {
+					return nil, nil, err
+				}
+				// The conditions of the expression were filtered
+				// Return early to prevent constructing an empty rule
+				if condition == nil {
+					return []engine.ConditionEntry{}, nil, nil
+				}
+				ce = engine.ConditionEntry{
+					From:                   from,
+					As:                     as,
+					Ignorable:              ignorable,
+					Not:                    not,
+					FilterBySource:         filterBySource,
+					ProviderSpecificConfig: *condition,
+				}
+				for k, prov := range provs {
+					providers[k] = prov
+				}
 			case "":
 				return nil, nil, fmt.Errorf("must have at least one condition")
 			default:
@@ -702,9 +957,12 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 					ProviderSpecificConfig: condition,
 					Ignorable:              ignorable,
 					Not:                    not,
+					FilterBySource:         filterBySource,
 				}
 				providers[providerKey] = provider
 			}
+			ce.Branch = branch
+			ce.Message = message
 			if ce.From != "" && ce.As != "" && ce.From == ce.As {
 				return nil, nil, fmt.Errorf("condition cannot have the same value for fields 'from' and 'as'")
 			} else if ce.As != "" {
@@ -736,6 +994,76 @@ func (r *RuleParser) getConditions(conditionsInterface []interface{}) ([]engine.
 	return conditions, providers, nil
 }
 
+// getAndConditions returns the conditions of an and condition, given as an
+// array or as an object with the conditions and the scope they must match in,
+// the rule or each file.
+func getAndConditions(value interface{}) ([]interface{}, bool, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, false, nil
+	case map[interface{}]interface{}:
+		for k := range v {
+			if k != "scope" && k != "conditions" {
+				return nil, false, fmt.Errorf("invalid field %v for and clause, must be scope or conditions", k)
+			}
+		}
+		conditions, ok := v["conditions"].([]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("invalid type for conditions of and clause, must be an array")
+		}
+		fileScoped := false
+		if scope, ok := v["scope"]; ok {
+			switch scope {
+			case "file":
+				fileScoped = true
+			case "rule":
+			default:
+				return nil, false, fmt.Errorf("scope of and clause must be file or rule, not %v", scope)
+			}
+		}
+		return conditions, fileScoped, nil
+	}
+	return nil, false, fmt.Errorf("invalid type for and clause, must be an array")
+}
+
+// getExprCondition parses an expression and its named conditions. No
+// condition is returned when some of the conditions were filtered as their
+// providers are not available, the expression can't be evaluated without them.
+func (r *RuleParser) getExprCondition(exprRaw, conditionsRaw interface{}) (*engine.ExprCondition, map[string]provider.InternalProviderClient, error) {
+	expr, ok := exprRaw.(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("expr must be a string literal, not %v", exprRaw)
+	}
+	iConditions, ok := conditionsRaw.([]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("conditions of an expression must be an array")
+	}
+	conds, provs, err := r.getConditions(iConditions)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(conds) != len(iConditions) {
+		return nil, nil, nil
+	}
+	condition, err := engine.NewExprCondition(expr, conds)
+	if err != nil {
+		return nil, nil, err
+	}
+	return condition, provs, nil
+}
+
+func parseFilterBySource(filterBySourceRaw interface{}) (*regexp.Regexp, error) {
+	pattern, ok := filterBySourceRaw.(string)
+	if !ok {
+		return nil, fmt.Errorf("filterBySource must be a string literal, not %v", filterBySourceRaw)
+	}
+	filterBySource, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("filterBySource must be a valid regex: %w", err)
+	}
+	return filterBySource, nil
+}
+
 func (r *RuleParser) getConditionForProvider(langProvider, capability string, value interface{}) (engine.Conditional, provider.InternalProviderClient, error) {
 	// Here there can only be a single provider.
 	client, ok := r.ProviderNameToClient[langProvider]
@@ -758,7 +1086,9 @@ func (r *RuleParser) getConditionForProvider(langProvider, capability string, va
 
 	if capability == "dependency" && !r.NoDependencyRules {
 		depCondition := provider.DependencyCondition{
-			Client: client,
+			Client:       client,
+			ProviderName: langProvider,
+			Cache:        r.DependencyCache,
 		}
 
 		fullCondition, ok := value.(map[interface{}]interface{})
@@ -813,9 +1143,11 @@ func (r *RuleParser) getConditionForProvider(langProvider, capability string, va
 
 	return provider.ProviderCondition{
 		Client:           client,
+		ProviderName:     langProvider,
 		Capability:       capability,
 		ConditionInfo:    value,
 		Ignore:           ignorable,
 		DepLabelSelector: selector,
+		ResultCache:      r.ConditionResultCache,
 	}, client, nil
 }
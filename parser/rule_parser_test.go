@@ -2,6 +2,7 @@ package parser_test
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
@@ -51,7 +52,11 @@ var _ provider.InternalProviderClient = &testProvider{}
 func TestLoadRules(t *testing.T) {
 	allGoFiles := "all go files"
 	allGoOrJsonFiles := "all go or json files"
+	goFileMessage := "go file {{file}}"
 	allGoAndJsonFiles := "all go and json files"
+	goWithoutJsonFiles := "go files without json files"
+	goWithoutLicense := "go files without a license header"
+	replaceJavaxPersistence := "Replace javax.persistence with jakarta.persistence"
 	effort := 3
 	testCases := []struct {
 		Name               string
@@ -271,6 +276,54 @@ func TestLoadRules(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:         "test-and-rule-file-scope",
+			testFileName: "rule-and-file-scope.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &allGoAndJsonFiles, Links: []konveyor.Link{}}},
+							When: engine.AndCondition{
+								Conditions: []engine.ConditionEntry{{}, {}},
+								FileScoped: true,
+							},
+						},
+					},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+		},
+		{
+			Name:         "test-and-rule-invalid-scope",
+			testFileName: "invalid-and-scope.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "scope of and clause must be file or rule, not directory",
+		},
 		{
 			Name:         "test-or-rule",
 			testFileName: "rule-or.yaml",
@@ -309,6 +362,199 @@ func TestLoadRules(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:         "test-expr-rule",
+			testFileName: "rule-expr.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &goWithoutJsonFiles, Links: []konveyor.Link{}}},
+							When: engine.ExprCondition{
+								Expr: "goFiles && !jsonFiles",
+								Conditions: []engine.ConditionEntry{
+									{As: "jsonFiles"},
+									{As: "goFiles"},
+								},
+							},
+						},
+					},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+		},
+		{
+			Name:         "test-expr-rule-unnamed-condition",
+			testFileName: "invalid-expr.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "conditions of an expression must be named with 'as'",
+		},
+		{
+			Name:         "test-unless-rule",
+			testFileName: "rule-unless.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}, {
+						Name: "filecontent",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &goWithoutLicense, Links: []konveyor.Link{}}},
+							When:    engine.ConditionEntry{As: "goFiles"},
+							Unless:  engine.ConditionEntry{From: "goFiles"},
+						},
+					},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}, {
+						Name: "filecontent",
+					}},
+				},
+			},
+		},
+		{
+			Name:         "test-unless-rule-multiple-conditions",
+			testFileName: "invalid-unless.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}, {
+						Name: "filecontent",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "unless must be a single condition",
+		},
+		{
+			Name:         "test-fix-rule",
+			testFileName: "rule-fix.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "filecontent",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "fix-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{
+								Message: engine.Message{Text: &replaceJavaxPersistence, Links: []konveyor.Link{}},
+								Fix: &engine.Fix{
+									Description: "Import {{name}} from jakarta.persistence",
+									Search:      `javax\.persistence`,
+									Replace:     "jakarta.persistence",
+								},
+							},
+							When: engine.ConditionEntry{},
+						},
+					},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "filecontent",
+					}},
+				},
+			},
+		},
+		{
+			Name:         "test-fix-without-search",
+			testFileName: "invalid-fix.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "filecontent",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "fix must have a search pattern or a code action",
+		},
+		{
+			Name:         "test-or-rule-branches",
+			testFileName: "rule-or-branches.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:      "file-001",
+								Description: "",
+								Category:    &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &allGoOrJsonFiles, Links: []konveyor.Link{}}},
+							When: engine.OrCondition{
+								Conditions: []engine.ConditionEntry{
+									{Branch: "go", Message: &goFileMessage},
+									{Branch: "json"},
+								},
+							},
+						},
+					},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+		},
 		{
 			Name:         "test-or-rule",
 			testFileName: "rule-chain.yaml",
@@ -442,6 +688,19 @@ func TestLoadRules(t *testing.T) {
 			ShouldErr:    true,
 			ErrorMessage: "duplicated rule id: file-001",
 		},
+		{
+			Name:         "rule invalid filterBySource",
+			testFileName: "invalid-filter-by-source.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "filterBySource must be a valid regex: error parsing regexp: missing closing ): `(`",
+		},
 		{
 			Name:         "rule or/and/chain layer",
 			testFileName: "or-and-chain-layer.yaml",
@@ -866,6 +1125,7 @@ func TestLoadRules(t *testing.T) {
 							}
 						}
 						compareWhens(expectedRule.When, rule.When, t)
+						compareWhens(expectedRule.Unless, rule.Unless, t)
 					}
 					if !foundRule {
 						t.Errorf("not have matching rule go: %#v, expected rules: %#v", rule, expectedSet.Rules)
@@ -886,6 +1146,9 @@ func compareWhens(w1 engine.Conditional, w2 engine.Conditional, t *testing.T) {
 		if !ok {
 			t.Errorf("rulesets did not have matching when field")
 		}
+		if and1.FileScoped != and2.FileScoped {
+			t.Errorf("rulesets did not have the same and scope")
+		}
 		compareConditions(and1.Conditions, and2.Conditions, t)
 	} else if or1, ok := w1.(engine.OrCondition); ok {
 		or2, ok := w2.(engine.OrCondition)
@@ -893,6 +1156,15 @@ func compareWhens(w1 engine.Conditional, w2 engine.Conditional, t *testing.T) {
 			t.Errorf("rulesets did not have matching when field")
 		}
 		compareConditions(or1.Conditions, or2.Conditions, t)
+	} else if expr1, ok := w1.(engine.ExprCondition); ok {
+		expr2, ok := w2.(engine.ExprCondition)
+		if !ok {
+			t.Errorf("rulesets did not have matching when field")
+		}
+		if expr1.Expr != expr2.Expr {
+			t.Errorf("rulesets did not have the same expression")
+		}
+		compareConditions(expr1.Conditions, expr2.Conditions, t)
 	}
 
 }
@@ -916,5 +1188,230 @@ func compareConditions(cs1 []engine.ConditionEntry, cs2 []engine.ConditionEntry,
 		if c1.Not != c2.Not {
 			t.Errorf("rulesets did not have the same Not field")
 		}
+		if c1.Branch != c2.Branch {
+			t.Errorf("rulesets did not have the same Branch field")
+		}
+		if !reflect.DeepEqual(c1.Message, c2.Message) {
+			t.Errorf("rulesets did not have the same Message field")
+		}
+	}
+}
+
+func TestLoadRulesSource(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ruleset.yaml": "name: test\nsourceURL: https://github.com/konveyor/rulesets/tree/main/default/generated/test/\n",
+		"rules.yaml": `# rules of the test
+- ruleID: file-001
+  message: go files
+  when:
+    builtin.file: "*.go"
+
+- message: json files
+  ruleID: file-002
+  when:
+    builtin.file: "*.json"
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ruleParser := ruleparser.RuleParser{
+		ProviderNameToClient: map[string]provider.InternalProviderClient{
+			"builtin": testProvider{caps: []provider.Capability{{Name: "file"}}},
+		},
+		Log: logr.Discard(),
+	}
+	ruleSets, _, err := ruleParser.LoadRules(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ruleSets) != 1 || len(ruleSets[0].Rules) != 2 {
+		t.Fatalf("expected a ruleset of 2 rules, got %+v", ruleSets)
+	}
+	file := filepath.ToSlash(filepath.Join(dir, "rules.yaml"))
+	url := "https://github.com/konveyor/rulesets/tree/main/default/generated/test/rules.yaml"
+	want := []konveyor.RuleSource{
+		{File: file, Line: 2, URL: url},
+		{File: file, Line: 7, URL: url},
+	}
+	for i, rule := range ruleSets[0].Rules {
+		if rule.Source == nil || *rule.Source != want[i] {
+			t.Errorf("expected the source %+v of rule %s, got %+v", want[i], rule.RuleID, rule.Source)
+		}
+	}
+}
+
+func TestLoadRulesFilterBySource(t *testing.T) {
+	dir := t.TempDir()
+	rules := `- ruleID: file-001
+  message: go or json files
+  when:
+    or:
+    - builtin.file: "*.go"
+    - builtin.file: "*.json"
+    filterBySource: ^package\s
+- ruleID: file-002
+  message: go and json files
+  when:
+    and:
+    - builtin.file: "*.go"
+    - builtin.file: "*.json"
+    filterBySource: ^package\s
+`
+	if err := os.WriteFile(filepath.Join(dir, "ruleset.yaml"), []byte("name: test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "rules.yaml"), []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ruleParser := ruleparser.RuleParser{
+		ProviderNameToClient: map[string]provider.InternalProviderClient{
+			"builtin": testProvider{caps: []provider.Capability{{Name: "file"}}},
+		},
+		Log: logr.Discard(),
+	}
+	ruleSets, _, err := ruleParser.LoadRules(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ruleSets) != 1 || len(ruleSets[0].Rules) != 2 {
+		t.Fatalf("expected a ruleset of 2 rules, got %+v", ruleSets)
+	}
+	for _, rule := range ruleSets[0].Rules {
+		entry, ok := rule.When.(engine.ConditionEntry)
+		if !ok || entry.FilterBySource == nil || entry.FilterBySource.String() != `^package\s` {
+			t.Errorf("expected the filterBySource of rule %s to apply to its conditions, got %#v", rule.RuleID, rule.When)
+			continue
+		}
+		switch entry.ProviderSpecificConfig.(type) {
+		case engine.AndCondition, engine.OrCondition:
+		default:
+			t.Errorf("expected the and or or condition of rule %s, got %#v", rule.RuleID, entry.ProviderSpecificConfig)
+		}
+	}
+}
+
+func TestLoadRulesComposition(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"upstream/ruleset.yaml": "name: upstream\nlabels:\n- konveyor.io/source=upstream\n",
+		"upstream/rules.yaml": `- ruleID: jms-001
+  message: jms
+  effort: 1
+  labels:
+  - konveyor.io/target=eap8
+  when:
+    builtin.file: "*.java"
+- ruleID: jms-002
+  message: jms topics
+  when:
+    builtin.file: "*.java"
+- ruleID: jms-003
+  message: jms queues
+  when:
+    builtin.file: "*.java"
+`,
+		"org/ruleset.yaml": `name: org
+include:
+- ../upstream
+override:
+- ruleID: jms-001
+  effort: 5
+  category: mandatory
+  message: replace jms with kafka
+  labels:
+  - konveyor.io/target=quarkus
+- ruleID: jms-002
+  disabled: true
+- ruleID: jms-404
+  effort: 1
+`,
+		"org/rules.yaml": `- ruleID: jms-003
+  message: org jms queues
+  when:
+    builtin.file: "*.java"
+`,
+		"cycle-a/ruleset.yaml": "name: a\ninclude:\n- ../cycle-b\n",
+		"cycle-b/ruleset.yaml": "name: b\ninclude:\n- ../cycle-a\n",
+		"invalid/ruleset.yaml": "name: invalid\noverride:\n- ruleID: jms-001\n  category: urgent\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ruleParser := ruleparser.RuleParser{
+		ProviderNameToClient: map[string]provider.InternalProviderClient{
+			"builtin": testProvider{caps: []provider.Capability{{Name: "file"}}},
+		},
+		Log: logr.Discard(),
+	}
+	ruleSets, _, err := ruleParser.LoadRules(filepath.Join(dir, "org"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ruleSets) != 1 || ruleSets[0].Name != "org" {
+		t.Fatalf("expected the org ruleset, got %+v", ruleSets)
+	}
+	rules := map[string]engine.Rule{}
+	for _, rule := range ruleSets[0].Rules {
+		rules[rule.RuleID] = rule
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected the rules jms-001 and jms-003, got %v", rules)
+	}
+	jms1 := rules["jms-001"]
+	if *jms1.Effort != 5 || *jms1.Category != konveyor.Mandatory || *jms1.Perform.Message.Text != "replace jms with kafka" {
+		t.Errorf("expected the effort, category and message of jms-001 to be overridden, got %+v", jms1)
+	}
+	if want := []string{"konveyor.io/target=quarkus", "konveyor.io/source=upstream"}; !reflect.DeepEqual(jms1.Labels, want) {
+		t.Errorf("expected the labels %v, got %v", want, jms1.Labels)
+	}
+	if jms3 := rules["jms-003"]; *jms3.Perform.Message.Text != "org jms queues" {
+		t.Errorf("expected the rule of the ruleset to replace the included rule, got %+v", jms3)
+	}
+
+	for _, invalid := range []string{"cycle-a", "invalid"} {
+		if _, _, err := ruleParser.LoadRules(filepath.Join(dir, invalid)); err == nil {
+			t.Errorf("expected an error loading %s", invalid)
+		}
+	}
+}
+
+func TestOverrideRules(t *testing.T) {
+	message := "jms"
+	effort := 3
+	rules := []engine.Rule{
+		{RuleMeta: engine.RuleMeta{RuleID: "jms-001"}, Perform: engine.Perform{Message: engine.Message{Text: &message}}},
+		{RuleMeta: engine.RuleMeta{RuleID: "jms-002"}},
+		{RuleMeta: engine.RuleMeta{RuleID: "jms-003"}, Perform: engine.Perform{Message: engine.Message{Text: &message}}},
+	}
+	overrides := []konveyor.RuleOverride{
+		{RuleID: "jms-001", Effort: &effort, MessageSuffix: " See the guide."},
+		{RuleID: "jms-002", MessageSuffix: " See the guide."},
+		{RuleID: "jms-003", Disabled: true},
+		{RuleID: "jms-404", Disabled: true},
+	}
+	overridden, unmatched := ruleparser.OverrideRules(rules, overrides)
+	if len(overridden) != 2 {
+		t.Fatalf("expected the rules jms-001 and jms-002, got %+v", overridden)
+	}
+	if *overridden[0].Perform.Message.Text != "jms See the guide." || *overridden[0].Effort != 3 {
+		t.Errorf("expected the message suffix and effort of jms-001, got %+v", overridden[0])
+	}
+	if overridden[1].Perform.Message.Text != nil {
+		t.Errorf("expected jms-002 to stay without a message, got %q", *overridden[1].Perform.Message.Text)
+	}
+	if message != "jms" {
+		t.Errorf("expected the message of the rule not to be changed in place, got %q", message)
+	}
+	if !reflect.DeepEqual(unmatched, []string{"jms-404"}) {
+		t.Errorf("expected the unmatched override jms-404, got %v", unmatched)
 	}
 }
@@ -0,0 +1,635 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	path "path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cbroglie/mustache"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	ruleKeys = map[string]bool{
+		"ruleID": true, "description": true, "category": true, "labels": true, "effort": true,
+		"message": true, "tag": true, "links": true, "when": true, "customVariables": true,
+		"task": true, "unless": true, "severity": true, "fix": true,
+		"cost": true,
+	}
+	// keys of a condition that are not the condition itself
+	conditionKeys = map[string]bool{
+		"from": true, "as": true, "ignore": true, "not": true, "filterBySource": true,
+		"branch": true, "message": true,
+	}
+	dependencyConditionKeys = map[string]bool{
+		"name": true, "upperbound": true, "lowerbound": true, "nameregex": true,
+	}
+	// variables the engine sets on every incident
+	engineVariables = map[string]bool{"file": true, "lineNumber": true, "branch": true}
+	yamlErrorLine   = regexp.MustCompile(`line (\d+)`)
+)
+
+// ValidationIssue is a problem found in a rule file. Line is the line of the
+// rule, or of the field when it can be found, 0 when it is unknown.
+type ValidationIssue struct {
+	File    string
+	Line    int
+	RuleID  string
+	Message string
+	// Warning issues are suspicious but do not prevent the rule from running
+	Warning bool
+}
+
+func (i ValidationIssue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location = fmt.Sprintf("%s:%d", i.File, i.Line)
+	}
+	level := "error"
+	if i.Warning {
+		level = "warning"
+	}
+	if i.RuleID != "" {
+		return fmt.Sprintf("%s: %s: %s: %s", location, level, i.RuleID, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, level, i.Message)
+}
+
+// RuleValidator checks rule files without running them and reports every
+// problem found instead of stopping at the first one or skipping rules.
+type RuleValidator struct {
+	// Capabilities of the providers by provider name, the providers and the
+	// inputs of the conditions are only checked when it is set.
+	Capabilities map[string][]provider.Capability
+}
+
+// Validate checks the rule file, or the rule files of the directory and its
+// sub directories.
+func (v *RuleValidator) Validate(filepath string) ([]ValidationIssue, error) {
+	info, err := os.Stat(filepath)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().IsRegular() {
+		return v.validateFile(filepath)
+	}
+	issues := []ValidationIssue{}
+	err = path.WalkDir(filepath, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if d.Name() == RULE_SET_GOLDEN_FILE_NAME {
+			issues = append(issues, validateRuleSetComposition(path.Dir(p))...)
+			return nil
+		}
+		if ext := path.Ext(d.Name()); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".test.yaml") || strings.HasSuffix(d.Name(), ".test.yml") {
+			return nil
+		}
+		fileIssues, err := v.validateFile(p)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, fileIssues...)
+		return nil
+	})
+	return issues, err
+}
+
+// validateRuleSetComposition checks the overrides of the ruleset in the
+// directory and that its includes exist
+func validateRuleSetComposition(dir string) []ValidationIssue {
+	file := path.Join(dir, RULE_SET_GOLDEN_FILE_NAME)
+	composition, err := loadRuleSetComposition(dir)
+	if err != nil {
+		return []ValidationIssue{{File: file, Message: err.Error()}}
+	}
+	issues := []ValidationIssue{}
+	for _, include := range composition.Include {
+		if !path.IsAbs(include) {
+			include = path.Join(dir, include)
+		}
+		if _, err := os.Stat(include); err != nil {
+			issues = append(issues, ValidationIssue{File: file, Message: fmt.Sprintf("unable to include %s: %v", include, err)})
+		}
+	}
+	return issues
+}
+
+// ruleFile locates the rules and their fields in the lines of a file
+type ruleFile struct {
+	path   string
+	lines  []string
+	issues []ValidationIssue
+}
+
+func (f *ruleFile) add(r *ruleContext, line int, warning bool, format string, args ...interface{}) {
+	issue := ValidationIssue{
+		File:    f.path,
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+		Warning: warning,
+	}
+	if r != nil {
+		issue.RuleID = r.id
+	}
+	f.issues = append(f.issues, issue)
+}
+
+// ruleContext is the rule being validated and the lines it spans
+type ruleContext struct {
+	file  *ruleFile
+	id    string
+	start int
+	end   int
+}
+
+// line returns the first line of the rule containing the text, the line of the
+// rule when there is none
+func (r *ruleContext) line(text string) int {
+	for i := r.start; i <= r.end && i <= len(r.file.lines); i++ {
+		if strings.Contains(r.file.lines[i-1], text) {
+			return i
+		}
+	}
+	return r.start
+}
+
+func (r *ruleContext) errorf(text string, format string, args ...interface{}) {
+	r.file.add(r, r.line(text), false, format, args...)
+}
+
+func (r *ruleContext) warnf(text string, format string, args ...interface{}) {
+	r.file.add(r, r.line(text), true, format, args...)
+}
+
+func (v *RuleValidator) validateFile(filepath string) ([]ValidationIssue, error) {
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	f := &ruleFile{
+		path:  filepath,
+		lines: strings.Split(string(content), "\n"),
+	}
+
+	rules := []map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(content, &rules); err != nil {
+		line := 0
+		if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+			fmt.Sscanf(match[1], "%d", &line)
+		}
+		f.add(nil, line, false, "invalid rule file, it must be a list of rules: %v", err)
+		return f.issues, nil
+	}
+
+	starts := f.ruleStarts(len(rules))
+	seen := map[string]int{}
+	for i, rule := range rules {
+		r := &ruleContext{file: f, start: starts[i], end: len(f.lines)}
+		if i+1 < len(starts) {
+			r.end = starts[i+1] - 1
+		}
+		if id, ok := rule["ruleID"].(string); ok {
+			r.id = id
+			if line, ok := seen[id]; ok {
+				r.errorf("ruleID", "duplicated rule id, first defined at line %d", line)
+			}
+			seen[id] = r.start
+		}
+		v.validateRule(r, rule)
+	}
+	// fields of a rule are checked in no particular order
+	sort.SliceStable(f.issues, func(i, j int) bool {
+		return f.issues[i].Line < f.issues[j].Line
+	})
+	return f.issues, nil
+}
+
+// ruleStarts returns the line of each rule, found by the list items at the
+// top level of the file
+func (f *ruleFile) ruleStarts(count int) []int {
+	starts := []int{}
+	for i, line := range f.lines {
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			starts = append(starts, i+1)
+		}
+	}
+	if len(starts) != count {
+		// rules are not at the top level of the lines, only report the file
+		starts = make([]int, count)
+	}
+	return starts
+}
+
+func (v *RuleValidator) validateRule(r *ruleContext, rule map[interface{}]interface{}) {
+	for k := range rule {
+		key, ok := k.(string)
+		if !ok || !ruleKeys[key] {
+			r.errorf(fmt.Sprintf("%v:", k), "unknown field %v", k)
+		}
+	}
+
+	ruleID, ok := rule["ruleID"]
+	if !ok {
+		r.errorf("", "ruleID is required")
+	} else if id, ok := ruleID.(string); !ok || id == "" {
+		r.errorf("ruleID", "ruleID must be a non empty string")
+	} else if reason, ok := validateRuleID(id); !ok {
+		r.errorf("ruleID", "invalid rule id, %s", reason)
+	}
+
+	message, hasMessage := rule["message"]
+	tags, hasTags := rule["tag"]
+	if !hasMessage && !hasTags {
+		r.errorf("ruleID", "either message or tag must be set")
+	}
+	if hasMessage {
+		if text, ok := message.(string); !ok {
+			r.errorf("message:", "message must be a string")
+		} else {
+			v.validateTemplate(r, "message:", text, customVariableNames(rule))
+		}
+	}
+	if hasTags {
+		tagList, ok := tags.([]interface{})
+		if !ok {
+			r.errorf("tag:", "tag must be a list of strings")
+		}
+		for _, tag := range tagList {
+			if _, ok := tag.(string); !ok {
+				r.errorf("tag:", "tag value %v must be a string", tag)
+			}
+		}
+	}
+
+	if category, ok := rule["category"]; ok {
+		c, _ := category.(string)
+		switch konveyor.Category(strings.ToLower(c)) {
+		case konveyor.Potential, konveyor.Mandatory, konveyor.Optional:
+		default:
+			r.errorf("category:", "category must be one of %s, %s or %s, not %v",
+				konveyor.Mandatory, konveyor.Optional, konveyor.Potential, category)
+		}
+	}
+	if severity, ok := rule["severity"]; ok {
+		s, _ := severity.(string)
+		if !konveyor.Severity(strings.ToLower(s)).Valid() {
+			r.errorf("severity:", "severity must be one of %v, not %v", konveyor.Severities, severity)
+		}
+	}
+	if cost, ok := rule["cost"]; ok {
+		c, _ := cost.(string)
+		if !engine.Cost(strings.ToLower(c)).Valid() {
+			r.errorf("cost:", "cost must be one of %v, not %v", engine.Costs, cost)
+		}
+	}
+	if effort, ok := rule["effort"]; ok {
+		if _, ok := effort.(int); !ok {
+			r.errorf("effort:", "effort must be an integer, not %v", effort)
+		}
+	}
+	if task, ok := rule["task"]; ok {
+		if _, ok := task.(string); !ok {
+			r.errorf("task:", "task must be a string, not %v", task)
+		}
+	}
+	if ls, ok := rule["labels"]; ok {
+		list, ok := ls.([]interface{})
+		if !ok {
+			r.errorf("labels:", "labels must be a list of strings")
+		}
+		for _, l := range list {
+			label, ok := l.(string)
+			if !ok {
+				r.errorf("labels:", "label %v must be a string", l)
+				continue
+			}
+			if _, _, err := labels.ParseLabel(label); err != nil {
+				r.errorf(label, "invalid label %s: %v", label, err)
+			}
+		}
+	}
+	if fix, ok := rule["fix"]; ok {
+		if _, err := parseFix(fix); err != nil {
+			r.errorf("fix:", "%v", err)
+		}
+	}
+	v.validateCustomVariables(r, rule["customVariables"])
+
+	when, ok := rule["when"]
+	if !ok {
+		r.errorf("", "when is required, a rule must have a single condition")
+		return
+	}
+	whenMap, ok := when.(map[interface{}]interface{})
+	if !ok {
+		r.errorf("when:", "when must be a single condition")
+		return
+	}
+	v.validateCondition(r, whenMap, false)
+
+	if unless, ok := rule["unless"]; ok {
+		unlessMap, ok := unless.(map[interface{}]interface{})
+		if !ok {
+			r.errorf("unless:", "unless must be a single condition")
+			return
+		}
+		v.validateCondition(r, unlessMap, false)
+	}
+}
+
+func customVariableNames(rule map[interface{}]interface{}) map[string]bool {
+	names := map[string]bool{}
+	list, _ := rule["customVariables"].([]interface{})
+	for _, cv := range list {
+		if m, ok := cv.(map[interface{}]interface{}); ok {
+			if name, ok := m["name"].(string); ok && name != "" {
+				names[name] = true
+				continue
+			}
+			// custom variables without a name set their named capture groups
+			pattern, _ := m["pattern"].(string)
+			if re, err := regexp.Compile(pattern); err == nil {
+				for _, group := range re.SubexpNames() {
+					if group != "" {
+						names[group] = true
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+func (v *RuleValidator) validateCustomVariables(r *ruleContext, customVariables interface{}) {
+	if customVariables == nil {
+		return
+	}
+	list, ok := customVariables.([]interface{})
+	if !ok {
+		r.errorf("customVariables:", "customVariables must be a list")
+		return
+	}
+	for _, cv := range list {
+		m, ok := cv.(map[interface{}]interface{})
+		if !ok {
+			r.errorf("customVariables:", "custom variable must be an object")
+			continue
+		}
+		name, _ := m["name"].(string)
+		pattern, ok := m["pattern"].(string)
+		if !ok {
+			r.errorf("customVariables:", "custom variable %s must have a pattern", name)
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			r.errorf("pattern:", "invalid pattern for custom variable %s: %v", name, err)
+			continue
+		}
+		if name == "" && !hasNamedCaptureGroup(re) {
+			r.errorf("customVariables:", "custom variable must have a name or a pattern with named capture groups")
+		}
+		if group, ok := m["nameOfCaptureGroup"].(string); ok && re.SubexpIndex(group) < 0 {
+			r.errorf(group, "pattern of custom variable %s has no capture group named %s", name, group)
+		}
+	}
+}
+
+func hasNamedCaptureGroup(re *regexp.Regexp) bool {
+	for _, group := range re.SubexpNames() {
+		if group != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *RuleValidator) validateTemplate(r *ruleContext, field, text string, customVariables map[string]bool) {
+	template, err := mustache.ParseString(text)
+	if err != nil {
+		r.errorf(field, "invalid message template: %v", err)
+		return
+	}
+	// variables set by the providers can't be known, only report the ones of
+	// rules with custom variables, that are likely misspelled
+	if len(customVariables) == 0 {
+		return
+	}
+	for _, tag := range template.Tags() {
+		if tag.Type() != mustache.Variable {
+			continue
+		}
+		if !customVariables[tag.Name()] && !engineVariables[tag.Name()] {
+			r.warnf(field, "message uses {{%s}} which is not a custom variable of the rule, it must be set by the provider", tag.Name())
+		}
+	}
+}
+
+func (v *RuleValidator) validateCondition(r *ruleContext, condition map[interface{}]interface{}, nested bool) {
+	if pattern, ok := condition["filterBySource"]; ok {
+		if _, err := parseFilterBySource(pattern); err != nil {
+			r.errorf("filterBySource:", "%v", err)
+		}
+	}
+	if message, ok := condition["message"]; ok {
+		if !nested {
+			r.errorf("message:", "message can only be set on the conditions of an and/or condition")
+		} else if text, ok := message.(string); !ok {
+			r.errorf("message:", "message of a branch must be a string")
+		} else {
+			v.validateTemplate(r, "message:", text, nil)
+		}
+	}
+	for _, key := range []string{"from", "as", "branch"} {
+		if value, ok := condition[key]; ok {
+			if _, ok := value.(string); !ok {
+				r.errorf(key+":", "%s must be a string, not %v", key, value)
+			}
+		}
+	}
+	for _, key := range []string{"ignore", "not"} {
+		if value, ok := condition[key]; ok {
+			if _, ok := value.(bool); !ok {
+				r.errorf(key+":", "%s must be a boolean, not %v", key, value)
+			}
+		}
+	}
+
+	found := []string{}
+	for k, value := range condition {
+		key, ok := k.(string)
+		if !ok {
+			r.errorf("when:", "condition key %v must be a string", k)
+			continue
+		}
+		if conditionKeys[key] {
+			continue
+		}
+		if key == "conditions" {
+			if _, ok := condition["expr"]; !ok {
+				r.errorf(key+":", "conditions must be used with expr")
+			}
+			continue
+		}
+		found = append(found, key)
+		switch key {
+		case "and", "or":
+			if m, ok := value.(map[interface{}]interface{}); ok && key == "and" {
+				for k := range m {
+					if k != "scope" && k != "conditions" {
+						r.errorf(fmt.Sprintf("%v:", k), "unknown field %v of and, must be scope or conditions", k)
+					}
+				}
+				if scope, ok := m["scope"]; ok && scope != "file" && scope != "rule" {
+					r.errorf("scope:", "scope of and must be file or rule, not %v", scope)
+				}
+				value = m["conditions"]
+			}
+			list, ok := value.([]interface{})
+			if !ok || len(list) == 0 {
+				r.errorf(key+":", "%s must be a non empty list of conditions", key)
+				continue
+			}
+			for _, c := range list {
+				m, ok := c.(map[interface{}]interface{})
+				if !ok {
+					r.errorf(key+":", "conditions of %s must be objects", key)
+					continue
+				}
+				v.validateCondition(r, m, true)
+			}
+		case "expr":
+			v.validateExprCondition(r, value, condition["conditions"])
+		default:
+			v.validateProviderCondition(r, key, value)
+		}
+	}
+	sort.Strings(found)
+	switch {
+	case len(found) == 0:
+		r.errorf("when:", "must have at least one condition")
+	case len(found) > 1:
+		r.errorf(found[1]+":", "a condition must have a single condition, found %s", strings.Join(found, ", "))
+	}
+}
+
+func (v *RuleValidator) validateExprCondition(r *ruleContext, value interface{}, conditionsRaw interface{}) {
+	expr, ok := value.(string)
+	if !ok {
+		r.errorf("expr:", "expr must be a string, not %v", value)
+		return
+	}
+	list, ok := conditionsRaw.([]interface{})
+	if !ok || len(list) == 0 {
+		r.errorf("expr:", "expr must have a non empty list of conditions")
+		return
+	}
+	named := []engine.ConditionEntry{}
+	for _, c := range list {
+		m, ok := c.(map[interface{}]interface{})
+		if !ok {
+			r.errorf("conditions:", "conditions of expr must be objects")
+			return
+		}
+		v.validateCondition(r, m, true)
+		as, _ := m["as"].(string)
+		named = append(named, engine.ConditionEntry{As: as})
+	}
+	if _, err := engine.NewExprCondition(expr, named); err != nil {
+		r.errorf("expr:", "%s", err)
+	}
+}
+
+func (v *RuleValidator) validateProviderCondition(r *ruleContext, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	if len(parts) != 2 {
+		r.errorf(key+":", "condition %s must be of the form {provider}.{capability}", key)
+		return
+	}
+	providerName, capabilityName := parts[0], parts[1]
+
+	if capabilityName == "dependency" {
+		m, ok := value.(map[interface{}]interface{})
+		if !ok {
+			r.errorf(key+":", "dependency condition must be an object")
+			return
+		}
+		for k := range m {
+			if name, _ := k.(string); !dependencyConditionKeys[name] {
+				r.errorf(fmt.Sprintf("%v:", k), "%v is not a valid argument for a dependency condition", k)
+			}
+		}
+		if _, ok := m["nameregex"]; !ok {
+			if _, ok := m["name"]; !ok {
+				r.errorf(key+":", "dependency condition requires a name or a nameregex")
+			} else if _, ok := m["upperbound"]; !ok {
+				if _, ok := m["lowerbound"]; !ok {
+					r.errorf(key+":", "dependency condition requires an upperbound or a lowerbound")
+				}
+			}
+		}
+	}
+
+	if v.Capabilities == nil {
+		return
+	}
+	capabilities, ok := v.Capabilities[providerName]
+	if !ok {
+		r.errorf(key+":", "unknown provider %s, must be one of %v", providerName, sortedKeys(v.Capabilities))
+		return
+	}
+	var capability *provider.Capability
+	names := []string{}
+	for i := range capabilities {
+		names = append(names, capabilities[i].Name)
+		if capabilities[i].Name == capabilityName {
+			capability = &capabilities[i]
+		}
+	}
+	if capability == nil {
+		sort.Strings(names)
+		r.errorf(key+":", "provider %s has no capability %s, must be one of %v", providerName, capabilityName, names)
+		return
+	}
+
+	// check the fields of the condition against the input schema of the capability
+	input, ok := value.(map[interface{}]interface{})
+	schema := capability.Input.Schema
+	if !ok || schema == nil || len(schema.Properties) == 0 {
+		return
+	}
+	for k := range input {
+		name, _ := k.(string)
+		if _, ok := schema.Properties[name]; !ok {
+			r.errorf(fmt.Sprintf("%v:", k), "unknown field %v for %s, must be one of %v", k, key, sortedKeys(schema.Properties))
+		}
+	}
+	for _, required := range schema.Required {
+		if _, ok := input[required]; !ok {
+			r.errorf(key+":", "%s requires the field %s", key, required)
+		}
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
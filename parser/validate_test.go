@@ -0,0 +1,114 @@
+package parser_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	ruleparser "github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+type patternCondition struct {
+	Pattern string `json:"pattern"`
+}
+
+func TestRuleValidator(t *testing.T) {
+	r := openapi3.Reflector{}
+	capabilities := map[string][]provider.Capability{}
+	for _, c := range []struct{ provider, name string }{
+		{"builtin", "file"}, {"builtin", "filecontent"}, {"builtin", "xml"}, {"go", "referenced"},
+	} {
+		capability, err := provider.ToProviderCap(&r, logr.Discard(), patternCondition{}, c.name)
+		if err != nil {
+			t.Fatalf("unable to create capability: %v", err)
+		}
+		capabilities[c.provider] = append(capabilities[c.provider], capability)
+	}
+
+	type issue struct {
+		Line    int
+		RuleID  string
+		Warning bool
+		Message string
+	}
+	testCases := []struct {
+		name         string
+		file         string
+		capabilities map[string][]provider.Capability
+		want         []issue
+	}{
+		{
+			name:         "invalid rules",
+			file:         "invalid-rules.yaml",
+			capabilities: capabilities,
+			want: []issue{
+				{Line: 9, RuleID: "unknown-capability-001", Message: "provider builtin has no capability files"},
+				{Line: 15, RuleID: "unknown-field-001", Message: "unknown field patern"},
+				{Line: 19, RuleID: "bad-label-001", Message: "invalid label"},
+				{Line: 24, RuleID: "undefined-variable-001", Warning: true, Message: "{{vesion}}"},
+				{Line: 38, RuleID: "two-conditions-001", Message: "a condition must have a single condition"},
+				{Line: 40, RuleID: "valid-001", Message: "duplicated rule id, first defined at line 1"},
+				{Line: 51, RuleID: "undeclared-expr-001", Message: "undeclared reference to 'xmlFiles'"},
+				{Line: 58, RuleID: "bad-severity-001", Message: "severity must be one of [critical high medium low], not blocker"},
+				{Line: 64, RuleID: "bad-cost-001", Message: "cost must be one of [low medium high], not cheap"},
+				{Line: 70, RuleID: "unnamed-variable-001", Message: "custom variable must have a name or a pattern with named capture groups"},
+			},
+		},
+		{
+			name: "providers are not checked without capabilities",
+			file: "invalid-rules.yaml",
+			want: []issue{
+				{Line: 19, RuleID: "bad-label-001", Message: "invalid label"},
+				{Line: 24, RuleID: "undefined-variable-001", Warning: true, Message: "{{vesion}}"},
+				{Line: 38, RuleID: "two-conditions-001", Message: "a condition must have a single condition"},
+				{Line: 40, RuleID: "valid-001", Message: "duplicated rule id, first defined at line 1"},
+				{Line: 51, RuleID: "undeclared-expr-001", Message: "undeclared reference to 'xmlFiles'"},
+				{Line: 58, RuleID: "bad-severity-001", Message: "severity must be one of [critical high medium low], not blocker"},
+				{Line: 64, RuleID: "bad-cost-001", Message: "cost must be one of [low medium high], not cheap"},
+				{Line: 70, RuleID: "unnamed-variable-001", Message: "custom variable must have a name or a pattern with named capture groups"},
+			},
+		},
+		{
+			name: "invalid ruleset",
+			file: "invalid-ruleset",
+			want: []issue{
+				{Message: "unknown severity blocker in the override of jms-001"},
+			},
+		},
+		{
+			name: "syntax error",
+			file: "invalid-syntax.yaml",
+			want: []issue{
+				{Line: 5, Message: "did not find expected key"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := ruleparser.RuleValidator{Capabilities: tc.capabilities}
+			issues, err := validator.Validate(filepath.Join("testdata", "validate", tc.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := []issue{}
+			for i, is := range issues {
+				want := issue{}
+				if i < len(tc.want) {
+					want = tc.want[i]
+				}
+				message := is.Message
+				if want.Message != "" && strings.Contains(is.Message, want.Message) {
+					message = want.Message
+				}
+				got = append(got, issue{Line: is.Line, RuleID: is.RuleID, Warning: is.Warning, Message: message})
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected issues\n%v\ngot\n%v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Package pathfilter matches the files of a location against the globs of the
+// paths included in and excluded from an analysis, so that the engine and
+// every provider agree on which files are analyzed.
+package pathfilter
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/fileuri"
+)
+
+// Filter matches paths against globs of included and excluded paths. Globs
+// relative to the root of the filter are matched against the paths relative
+// to it, absolute globs against absolute paths. `*` and `?` match within a
+// path segment, `**` matches any number of segments. A glob matching a
+// directory matches everything in it, so plain paths are globs too.
+//
+// A nil Filter matches every path.
+type Filter struct {
+	root     string
+	included []glob
+	excluded []glob
+}
+
+type glob struct {
+	absolute bool
+	regex    *regexp.Regexp
+}
+
+// New creates a filter of the files of the root location.
+func New(root string, included, excluded []string) (*Filter, error) {
+	f := &Filter{}
+	if root != "" {
+		if abs, err := filepath.Abs(fileuri.Path(root)); err == nil {
+			f.root = abs
+		}
+	}
+	for _, pattern := range included {
+		g, err := compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.included = append(f.included, g)
+	}
+	for _, pattern := range excluded {
+		g, err := compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.excluded = append(f.excluded, g)
+	}
+	return f, nil
+}
+
+// Empty reports whether the filter matches every path
+func (f *Filter) Empty() bool {
+	return f == nil || (len(f.included) == 0 && len(f.excluded) == 0)
+}
+
+// Root is the location the relative globs are matched in
+func (f *Filter) Root() string {
+	if f == nil {
+		return ""
+	}
+	return f.root
+}
+
+// Contains reports whether the path is in the root of the filter
+func (f *Filter) Contains(path string) bool {
+	if f == nil || f.root == "" {
+		return false
+	}
+	return fileuri.HasPrefix(path, f.root)
+}
+
+// Match reports whether the file, a path or a file URI, is analyzed: it is
+// included, or there are no included globs, and it is not excluded.
+func (f *Filter) Match(path string) bool {
+	if f.Empty() {
+		return true
+	}
+	abs, rel := f.paths(path)
+	if matchAny(f.excluded, abs, rel) {
+		return false
+	}
+	return len(f.included) == 0 || matchAny(f.included, abs, rel)
+}
+
+// SkipDir reports whether nothing in the directory is analyzed because it is
+// excluded, walks of the location do not need to go into it.
+func (f *Filter) SkipDir(path string) bool {
+	if f.Empty() {
+		return false
+	}
+	abs, rel := f.paths(path)
+	return matchAny(f.excluded, abs, rel)
+}
+
+// IsGlob reports whether the pattern is a glob rather than a plain path
+func IsGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+func (f *Filter) paths(path string) (string, string) {
+	abs := fileuri.Path(path)
+	if !filepath.IsAbs(abs) && f.root != "" {
+		abs = filepath.Join(f.root, abs)
+	}
+	rel := ""
+	if f.root != "" {
+		if r, err := filepath.Rel(f.root, abs); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			rel = filepath.ToSlash(r)
+		}
+	}
+	return filepath.ToSlash(abs), rel
+}
+
+func matchAny(globs []glob, abs, rel string) bool {
+	for _, g := range globs {
+		if g.absolute {
+			if g.regex.MatchString(abs) {
+				return true
+			}
+		} else if rel != "" && g.regex.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func compile(pattern string) (glob, error) {
+	p := filepath.ToSlash(strings.TrimSpace(pattern))
+	absolute := filepath.IsAbs(pattern) || strings.HasPrefix(p, "/")
+	if absolute {
+		p = filepath.ToSlash(filepath.Clean(pattern))
+	} else {
+		p = strings.TrimPrefix(p, "./")
+	}
+	p = strings.TrimSuffix(p, "/")
+	if p == "" || p == "." {
+		return glob{}, fmt.Errorf("empty path glob %q", pattern)
+	}
+
+	b := strings.Builder{}
+	b.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				i++
+				if i+1 < len(p) && p[i+1] == '/' {
+					// **/ matches any number of directories, none included
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	// a glob of a directory matches what is in it
+	b.WriteString("(?:/.*)?$")
+	regex, err := regexp.Compile(b.String())
+	if err != nil {
+		return glob{}, fmt.Errorf("invalid path glob %q: %w", pattern, err)
+	}
+	return glob{absolute: absolute, regex: regex}, nil
+}
@@ -0,0 +1,111 @@
+package pathfilter
+
+import "testing"
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		title    string
+		included []string
+		excluded []string
+		path     string
+		matched  bool
+		skipDir  bool
+	}{
+		{
+			title:   "no globs",
+			path:    "/app/src/Main.java",
+			matched: true,
+		},
+		{
+			title:    "excluded directory",
+			excluded: []string{"target"},
+			path:     "/app/target/classes/Main.java",
+		},
+		{
+			title:    "excluded directory is anchored at the root",
+			excluded: []string{"target"},
+			path:     "/app/module/target/Main.java",
+			matched:  true,
+		},
+		{
+			title:    "excluded directory at any depth",
+			excluded: []string{"**/node_modules"},
+			path:     "/app/web/node_modules",
+			skipDir:  true,
+		},
+		{
+			title:    "excluded file names",
+			excluded: []string{"**/*Test.java"},
+			path:     "/app/src/test/java/MainTest.java",
+		},
+		{
+			title:    "star does not match separators",
+			excluded: []string{"src/*.java"},
+			path:     "/app/src/main/Main.java",
+			matched:  true,
+		},
+		{
+			title:    "included glob",
+			included: []string{"src/main/**/*.java"},
+			path:     "/app/src/main/java/io/Main.java",
+			matched:  true,
+		},
+		{
+			title:    "not included",
+			included: []string{"src/main/**/*.java"},
+			path:     "/app/src/main/resources/app.properties",
+		},
+		{
+			title:    "excluded wins over included",
+			included: []string{"src"},
+			excluded: []string{"src/test"},
+			path:     "/app/src/test/MainTest.java",
+			skipDir:  true,
+		},
+		{
+			title:    "absolute glob",
+			included: []string{"////app/src//"},
+			path:     "file:///app/src/Main.java",
+			matched:  true,
+		},
+		{
+			title:    "path relative to the root",
+			excluded: []string{"vendor"},
+			path:     "vendor/lib.go",
+			skipDir:  true,
+		},
+		{
+			title:    "character class",
+			excluded: []string{"build[!s]"},
+			path:     "/app/builds/Main.java",
+			matched:  true,
+		},
+		{
+			title:    "relative globs do not match outside of the root",
+			included: []string{"src"},
+			path:     "/m2/repository/lib.jar",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			f, err := New("/app", tt.included, tt.excluded)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matched := f.Match(tt.path); matched != tt.matched {
+				t.Errorf("expected match %v, got %v", tt.matched, matched)
+			}
+			if skipDir := f.SkipDir(tt.path); tt.skipDir && !skipDir {
+				t.Errorf("expected the directory to be skipped")
+			}
+		})
+	}
+
+	if _, err := New("/app", []string{""}, nil); err == nil {
+		t.Errorf("expected an error for an empty glob")
+	}
+	var f *Filter
+	if !f.Match("/app/src") || !f.Empty() {
+		t.Errorf("expected a nil filter to match every path")
+	}
+}
@@ -0,0 +1,208 @@
+// Package process runs the language servers and providers spawned by the
+// analyzer in their own process group, a job object on Windows, so that
+// stopping them also stops the processes they spawned, such as build tool
+// daemons, instead of leaving them running after the analysis.
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// RecordDirEnvVar overrides the directory the started groups are recorded in
+// to find the ones left behind by an analyzer that did not stop them.
+const RecordDirEnvVar = "KONVEYOR_PROCESS_DIR"
+
+const (
+	maxReapPasses = 3
+	reapPassDelay = 200 * time.Millisecond
+)
+
+// Group is a command and the processes it spawns
+type Group struct {
+	// Cmd is configured by the caller before Start, its process attributes
+	// and Cancel must not be changed.
+	Cmd *exec.Cmd
+
+	mutex   sync.Mutex
+	started bool
+	stopped bool
+	record  string
+	handle  groupHandle
+}
+
+// record is what is known of a started group to stop it when the analyzer
+// that started it exits without doing so. The start times tell a process
+// apart from a later one that was given the same pid.
+type record struct {
+	Owner        int    `json:"owner"`
+	OwnerStarted string `json:"ownerStarted,omitempty"`
+	Pid          int    `json:"pid"`
+	Started      string `json:"started,omitempty"`
+	Command      string `json:"command"`
+}
+
+// Command returns a group running the command, stopped when the context is
+// done or Stop is called.
+func Command(ctx context.Context, name string, arg ...string) *Group {
+	g := &Group{Cmd: exec.CommandContext(ctx, name, arg...)}
+	setGroupAttributes(g.Cmd)
+	g.Cmd.Cancel = g.Stop
+	return g
+}
+
+// Start starts the command in a new group and records it
+func (g *Group) Start() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := g.Cmd.Start(); err != nil {
+		return err
+	}
+	g.started = true
+	handle, err := newGroupHandle(g.Cmd.Process)
+	if err != nil {
+		g.Cmd.Process.Kill()
+		return fmt.Errorf("unable to create process group: %w", err)
+	}
+	g.handle = handle
+	g.record = writeRecord(record{
+		Owner:        os.Getpid(),
+		OwnerStarted: startTime(os.Getpid()),
+		Pid:          g.Cmd.Process.Pid,
+		Started:      startTime(g.Cmd.Process.Pid),
+		Command:      strings.Join(g.Cmd.Args, " "),
+	})
+	return nil
+}
+
+// Stop kills the command and every process of its group. It is safe to call
+// more than once and before Start, the command is waited for by the caller.
+func (g *Group) Stop() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if !g.started || g.stopped {
+		return nil
+	}
+	g.stopped = true
+	if g.record != "" {
+		os.Remove(g.record)
+	}
+	return g.handle.kill()
+}
+
+// recordDir is per user, an analyzer only stops the groups started by the
+// analyzers of the same user.
+func recordDir() string {
+	if dir := os.Getenv(RecordDirEnvVar); dir != "" {
+		return dir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "konveyor-analyzer", "processes")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("konveyor-analyzer-processes-%d", os.Getuid()))
+}
+
+// writeRecord returns the file of the record, empty when it could not be
+// written, orphans are then not detected but the group still works.
+func writeRecord(r record) string {
+	dir := recordDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return ""
+	}
+	content, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(dir, strconv.Itoa(r.Pid)+".json")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return ""
+	}
+	return path
+}
+
+// ReapOrphans stops the groups recorded by analyzers that are no longer
+// running, left behind when they crashed or were killed, and returns how
+// many were stopped. It is called on startup before spawning processes.
+func ReapOrphans(log logr.Logger) int {
+	reaped := 0
+	// a provider stopped in a pass orphans the language servers it started,
+	// in groups of their own, which are stopped in the next one
+	for pass := 0; pass < maxReapPasses; pass++ {
+		stopped := reapOrphans(log)
+		if stopped == 0 {
+			break
+		}
+		reaped += stopped
+		time.Sleep(reapPassDelay)
+	}
+	return reaped
+}
+
+func reapOrphans(log logr.Logger) int {
+	dir := recordDir()
+	if !ownedDir(dir) {
+		return 0
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	reaped := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		r := record{}
+		if err := json.Unmarshal(content, &r); err != nil || r.Pid <= 0 {
+			os.Remove(path)
+			continue
+		}
+		if running(r.Owner, r.OwnerStarted) {
+			continue
+		}
+		if r.Started == "" || !running(r.Pid, r.Started) {
+			// the group exited, or the pid was reused by another process
+			// since, or its start time is not known and it can't be told
+			// apart from one that reused the pid
+			if processAlive(r.Pid) {
+				log.V(3).Info("not stopping process that is not the recorded one", "pid", r.Pid, "command", r.Command)
+			}
+		} else {
+			killed, err := killOrphan(r.Pid)
+			if err != nil {
+				log.V(3).Error(err, "unable to stop orphaned process group", "pid", r.Pid, "command", r.Command)
+				continue
+			}
+			if killed {
+				log.Info("stopped orphaned process group", "pid", r.Pid, "command", r.Command)
+				reaped++
+			}
+		}
+		os.Remove(path)
+	}
+	return reaped
+}
+
+// running tells whether the process is alive and started at the recorded
+// time, a record without a start time only checks it is alive.
+func running(pid int, started string) bool {
+	if !processAlive(pid) {
+		return false
+	}
+	return started == "" || startTime(pid) == started
+}
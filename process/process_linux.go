@@ -0,0 +1,27 @@
+package process
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// startTime returns the start time of the process in clock ticks since boot,
+// field 22 of /proc/<pid>/stat, empty when it is unknown.
+func startTime(pid int) string {
+	content, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return ""
+	}
+	// the command in field 2 is in parentheses and may contain spaces
+	end := strings.LastIndexByte(string(content), ')')
+	if end < 0 {
+		return ""
+	}
+	fields := strings.Fields(string(content[end+1:]))
+	// the fields after the command start at field 3
+	if len(fields) < 20 {
+		return ""
+	}
+	return fields[19]
+}
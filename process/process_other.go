@@ -0,0 +1,19 @@
+//go:build !linux && !windows
+
+package process
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// startTime returns the start time of the process as reported by ps, empty
+// when it is unknown.
+func startTime(pid int) string {
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
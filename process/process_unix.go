@@ -0,0 +1,58 @@
+//go:build !windows
+
+package process
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// groupHandle is the id of the process group, the pid of its leader
+type groupHandle int
+
+func setGroupAttributes(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func newGroupHandle(p *os.Process) (groupHandle, error) {
+	return groupHandle(p.Pid), nil
+}
+
+func (h groupHandle) kill() error {
+	err := syscall.Kill(-int(h), syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		// every process of the group already exited
+		return nil
+	}
+	return err
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+func killOrphan(pid int) (bool, error) {
+	// the pid may have been reused since, only kill it when it still leads a group
+	pgid, err := syscall.Getpgid(pid)
+	if err != nil || pgid != pid {
+		return false, nil
+	}
+	return true, groupHandle(pid).kill()
+}
+
+// ownedDir tells whether the directory is owned by the user, the records of
+// a directory another user could write to are not trusted.
+func ownedDir(dir string) bool {
+	info, err := os.Lstat(dir)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
@@ -0,0 +1,143 @@
+//go:build !windows
+
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// startGroup starts a shell spawning a grandchild and returns the pid of the grandchild
+func startGroup(t *testing.T, ctx context.Context) (*Group, int) {
+	t.Helper()
+	t.Setenv(RecordDirEnvVar, t.TempDir())
+	g := Command(ctx, "/bin/sh", "-c", "sleep 60 & echo $!; wait")
+	out, err := g.Cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Start(); err != nil {
+		t.Fatalf("unable to start: %v", err)
+	}
+	line := make([]byte, 32)
+	n, err := out.Read(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(line[:n])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g, pid
+}
+
+func waitExited(t *testing.T, pid int) {
+	t.Helper()
+	for i := 0; i < 50; i++ {
+		if !processAlive(pid) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Errorf("expected process %d to be stopped", pid)
+}
+
+func TestGroupStop(t *testing.T) {
+	g, grandchild := startGroup(t, context.Background())
+	if _, err := os.Stat(g.record); err != nil {
+		t.Errorf("expected the group to be recorded: %v", err)
+	}
+	if err := g.Stop(); err != nil {
+		t.Fatalf("unable to stop: %v", err)
+	}
+	g.Cmd.Wait()
+	waitExited(t, grandchild)
+	if _, err := os.Stat(g.record); !os.IsNotExist(err) {
+		t.Errorf("expected the record to be removed")
+	}
+	if err := g.Stop(); err != nil {
+		t.Errorf("expected stopping twice to succeed, got %v", err)
+	}
+}
+
+func TestGroupContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g, grandchild := startGroup(t, ctx)
+	cancel()
+	g.Cmd.Wait()
+	waitExited(t, grandchild)
+}
+
+func TestReapOrphans(t *testing.T) {
+	g, grandchild := startGroup(t, context.Background())
+	defer g.Cmd.Wait()
+	// record the group as started by an analyzer that exited
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := json.Marshal(record{Owner: exited.Process.Pid, Pid: g.Cmd.Process.Pid, Started: startTime(g.Cmd.Process.Pid)})
+	if err := os.WriteFile(g.record, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	alive := filepath.Join(recordDir(), "1.json")
+	content, _ = json.Marshal(record{Owner: os.Getpid(), Pid: 1})
+	if err := os.WriteFile(alive, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if reaped := ReapOrphans(logr.Discard()); reaped != 1 {
+		t.Errorf("expected 1 orphaned group to be stopped, got %d", reaped)
+	}
+	waitExited(t, grandchild)
+	if _, err := os.Stat(g.record); !os.IsNotExist(err) {
+		t.Errorf("expected the record of the orphan to be removed")
+	}
+	if _, err := os.Stat(alive); err != nil {
+		t.Errorf("expected the record of a running analyzer to be kept")
+	}
+}
+
+func TestReapOrphansReusedPid(t *testing.T) {
+	g, grandchild := startGroup(t, context.Background())
+	defer func() {
+		g.Stop()
+		g.Cmd.Wait()
+	}()
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	// the recorded group exited and its pid was given to another process
+	content, _ := json.Marshal(record{Owner: exited.Process.Pid, Pid: g.Cmd.Process.Pid, Started: "1"})
+	if err := os.WriteFile(g.record, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	unknown := filepath.Join(recordDir(), strconv.Itoa(grandchild)+".json")
+	content, _ = json.Marshal(record{Owner: exited.Process.Pid, Pid: grandchild})
+	if err := os.WriteFile(unknown, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if reaped := ReapOrphans(logr.Discard()); reaped != 0 {
+		t.Errorf("expected no group to be stopped, got %d", reaped)
+	}
+	if !processAlive(g.Cmd.Process.Pid) || !processAlive(grandchild) {
+		t.Errorf("expected the processes that are not the recorded ones to be kept")
+	}
+	if _, err := os.Stat(g.record); !os.IsNotExist(err) {
+		t.Errorf("expected the record of the exited group to be removed")
+	}
+	if _, err := os.Stat(unknown); !os.IsNotExist(err) {
+		t.Errorf("expected the record without a start time to be removed")
+	}
+}
@@ -0,0 +1,180 @@
+package progress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// State is the state of an analysis
+type State string
+
+const (
+	StateRunning  State = "running"
+	StatePaused   State = "paused"
+	StateCanceled State = "canceled"
+)
+
+// ErrCanceled is the error of the work not done because the analysis was
+// canceled with its control
+var ErrCanceled = errors.New("analysis was canceled")
+
+// Control pauses, resumes and cancels the analysis of a collector from
+// outside of it, such as an application embedding the analyzer. The work of
+// the analysis calls Wait before every item it does, so that pausing it
+// lets the items being done finish and canceling it skips the ones left,
+// the analysis returning the results of the ones done. The changes of the
+// state are reported as events of the task of the analysis.
+//
+// The methods of a nil Control do nothing, the analysis is always running.
+type Control struct {
+	collector *Collector
+	state     State
+	reason    string
+	// resumed is closed when the analysis is resumed or canceled, it is
+	// created when the analysis is paused
+	resumed chan struct{}
+	// canceled is closed when the analysis is canceled
+	canceled chan struct{}
+}
+
+func newControl(c *Collector) *Control {
+	return &Control{
+		collector: c,
+		state:     StateRunning,
+		canceled:  make(chan struct{}),
+	}
+}
+
+// Control returns the control of the analysis of the collector
+func (c *Collector) Control() *Control {
+	if c == nil {
+		return nil
+	}
+	return c.control
+}
+
+// ControlFromContext returns the control of the analysis the task of the
+// context is part of, nil when progress is not collected.
+func ControlFromContext(ctx context.Context) *Control {
+	t := FromContext(ctx)
+	if t == nil {
+		return nil
+	}
+	return t.collector.control
+}
+
+// State returns the state of the analysis
+func (c *Control) State() State {
+	if c == nil {
+		return StateRunning
+	}
+	c.collector.mutex.Lock()
+	defer c.collector.mutex.Unlock()
+	return c.state
+}
+
+// Reason returns why the analysis was canceled, empty until it is
+func (c *Control) Reason() string {
+	if c == nil {
+		return ""
+	}
+	c.collector.mutex.Lock()
+	defer c.collector.mutex.Unlock()
+	return c.reason
+}
+
+// Canceled returns a channel closed once the analysis is canceled
+func (c *Control) Canceled() <-chan struct{} {
+	if c == nil {
+		return nil
+	}
+	return c.canceled
+}
+
+// Pause pauses the analysis, the items being done finish and the next ones
+// wait for it to be resumed. Canceled analyses can't be paused.
+func (c *Control) Pause() error {
+	if c == nil {
+		return fmt.Errorf("progress is not collected, the analysis can't be paused")
+	}
+	c.collector.mutex.Lock()
+	defer c.collector.mutex.Unlock()
+	switch c.state {
+	case StateCanceled:
+		return ErrCanceled
+	case StatePaused:
+		return nil
+	}
+	c.state = StatePaused
+	c.resumed = make(chan struct{})
+	c.report("paused")
+	return nil
+}
+
+// Resume resumes the paused analysis
+func (c *Control) Resume() {
+	if c == nil {
+		return
+	}
+	c.collector.mutex.Lock()
+	defer c.collector.mutex.Unlock()
+	if c.state != StatePaused {
+		return
+	}
+	c.state = StateRunning
+	close(c.resumed)
+	c.report("resumed")
+}
+
+// Cancel cancels the analysis for the reason, the items being done finish
+// and the ones left are skipped.
+func (c *Control) Cancel(reason string) {
+	if c == nil {
+		return
+	}
+	c.collector.mutex.Lock()
+	defer c.collector.mutex.Unlock()
+	if c.state == StateCanceled {
+		return
+	}
+	if c.state == StatePaused {
+		close(c.resumed)
+	}
+	c.state = StateCanceled
+	c.reason = reason
+	close(c.canceled)
+	c.report("canceled: " + reason)
+}
+
+// Wait waits while the analysis is paused. It returns an error wrapping
+// ErrCanceled once the analysis is canceled, and the error of the context
+// when it is done.
+func (c *Control) Wait(ctx context.Context) error {
+	if c == nil {
+		return ctx.Err()
+	}
+	for {
+		c.collector.mutex.Lock()
+		state, reason, resumed := c.state, c.reason, c.resumed
+		c.collector.mutex.Unlock()
+		switch state {
+		case StateCanceled:
+			return fmt.Errorf("%w: %s", ErrCanceled, reason)
+		case StateRunning:
+			return ctx.Err()
+		}
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// report reports the change of the state as an event of the task of the
+// analysis, the mutex of the collector must be held
+func (c *Control) report(message string) {
+	c.collector.root.message = message
+	c.collector.report(c.collector.root)
+}
@@ -0,0 +1,69 @@
+package progress
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestControl(t *testing.T) {
+	recorder := &recordingReporter{}
+	c := NewCollector(WithReporter(recorder))
+	control := c.Control()
+	ctx := WithTask(context.Background(), c.Root())
+	if ControlFromContext(ctx) != control {
+		t.Fatalf("expected the control of the collector of the task of the context")
+	}
+	if err := control.Wait(ctx); err != nil {
+		t.Fatalf("unexpected error waiting for a running analysis: %v", err)
+	}
+
+	if err := control.Pause(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waited := make(chan error)
+	go func() { waited <- control.Wait(ctx) }()
+	select {
+	case err := <-waited:
+		t.Fatalf("expected to wait while the analysis is paused, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	control.Resume()
+	if err := <-waited; err != nil {
+		t.Errorf("unexpected error once the analysis is resumed: %v", err)
+	}
+
+	control.Pause()
+	go func() { waited <- control.Wait(ctx) }()
+	control.Cancel("test")
+	if err := <-waited; !errors.Is(err, ErrCanceled) {
+		t.Errorf("expected the analysis to be canceled, got %v", err)
+	}
+	select {
+	case <-control.Canceled():
+	default:
+		t.Errorf("expected the canceled channel to be closed")
+	}
+	if err := control.Pause(); !errors.Is(err, ErrCanceled) {
+		t.Errorf("expected a canceled analysis not to be paused, got %v", err)
+	}
+	if control.State() != StateCanceled || control.Reason() != "test" {
+		t.Errorf("unexpected state %s with reason %s", control.State(), control.Reason())
+	}
+
+	want := []string{"paused " + string(StatePaused), "resumed " + string(StateRunning), "paused " + string(StatePaused), "canceled: test " + string(StateCanceled)}
+	if len(recorder.events) != len(want) {
+		t.Fatalf("expected the events %v, got %+v", want, recorder.events)
+	}
+	for i, e := range recorder.events {
+		if e.ID != c.Root().id || e.Message+" "+string(e.State) != want[i] {
+			t.Errorf("expected the event %s of the analysis, got %+v", want[i], e)
+		}
+	}
+
+	var nilControl *Control
+	if nilControl.State() != StateRunning || nilControl.Wait(ctx) != nil {
+		t.Errorf("expected a nil control to always run")
+	}
+}
@@ -0,0 +1,77 @@
+package progress
+
+import (
+	"context"
+
+	"github.com/konveyor/analyzer-lsp/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithOTelReporter records the tasks as spans of the tracing setup, children
+// of the span of the context for the tasks of the analysis, see otelReporter.
+func WithOTelReporter(ctx context.Context) Option {
+	return func(c *Collector) {
+		c.reporters = append(c.reporters, newOTelReporter(ctx, c.root.id))
+	}
+}
+
+type taskSpan struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+// otelReporter records every task as a span, a child of the span of its
+// parent task, from its first event to the event of it being done. The
+// starts and ends of the tasks are recorded as span events of the span of
+// the analysis along with its progress, the items done and to do and the
+// throughput of a task as attributes of its span. The spans of the tasks
+// never done are left unfinished and not exported.
+type otelReporter struct {
+	rootID string
+	spans  map[string]taskSpan
+}
+
+func newOTelReporter(ctx context.Context, rootID string) *otelReporter {
+	return &otelReporter{
+		rootID: rootID,
+		spans: map[string]taskSpan{
+			rootID: {ctx: ctx, span: trace.SpanFromContext(ctx)},
+		},
+	}
+}
+
+func (r *otelReporter) Report(event ProgressEvent) {
+	root := r.spans[r.rootID].span
+	s, ok := r.spans[event.ID]
+	if !ok {
+		parent, ok := r.spans[event.ParentID]
+		if !ok {
+			parent = r.spans[r.rootID]
+		}
+		ctx, span := tracing.StartNewSpan(parent.ctx, "progress "+event.Name,
+			attribute.Key("progress.id").String(event.ID),
+			attribute.Key("progress.stage").String(string(event.Stage)))
+		s = taskSpan{ctx: ctx, span: span}
+		r.spans[event.ID] = s
+		root.AddEvent("task started", trace.WithTimestamp(event.Timestamp), trace.WithAttributes(
+			attribute.Key("progress.id").String(event.ID),
+			attribute.Key("progress.stage").String(string(event.Stage)),
+			attribute.Key("progress.overall").Float64(event.Overall)))
+	}
+	if !event.Done {
+		return
+	}
+	s.span.SetAttributes(
+		attribute.Key("progress.current").Int(event.Current),
+		attribute.Key("progress.total").Int(event.Total),
+		attribute.Key("progress.throughput").Float64(event.Throughput))
+	root.AddEvent("task done", trace.WithTimestamp(event.Timestamp), trace.WithAttributes(
+		attribute.Key("progress.id").String(event.ID),
+		attribute.Key("progress.stage").String(string(event.Stage)),
+		attribute.Key("progress.overall").Float64(event.Overall)))
+	// the span of the analysis is ended by whoever started it
+	if event.ID != r.rootID {
+		s.span.End(trace.WithTimestamp(event.Timestamp))
+	}
+}
@@ -0,0 +1,76 @@
+package progress
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOTelReporter(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(previous)
+
+	ctx, mainSpan := tp.Tracer("").Start(context.Background(), "main")
+	c := NewCollector(WithOTelReporter(ctx))
+	providers := c.Root().Task(StageProviderInit, "providers", 1)
+	java := providers.Task(StageProviderInit, "java", 1)
+	java.SetTotal(2)
+	java.Add(1, "indexing")
+	java.Done()
+	providers.Done()
+	c.Root().Task(StageRuleExecution, "rules", 1)
+	c.Root().Done()
+	mainSpan.End()
+
+	spans := map[string]tracesdk.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	if len(spans) != 3 {
+		t.Fatalf("expected the spans of the done tasks and the main span, got %v", spans)
+	}
+	if spans["progress java"].Parent().SpanID() != spans["progress providers"].SpanContext().SpanID() {
+		t.Errorf("expected the span of a task to be a child of the span of its parent task")
+	}
+	if spans["progress providers"].Parent().SpanID() != spans["main"].SpanContext().SpanID() {
+		t.Errorf("expected the span of a stage to be a child of the span of the analysis")
+	}
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, a := range spans["progress java"].Attributes() {
+		attributes[a.Key] = a.Value
+	}
+	if attributes["progress.current"].AsInt64() != 2 || attributes["progress.total"].AsInt64() != 2 || attributes["progress.stage"].AsString() != string(StageProviderInit) {
+		t.Errorf("unexpected attributes of the span of a task %v", attributes)
+	}
+	events := []string{}
+	for _, e := range spans["main"].Events() {
+		for _, a := range e.Attributes {
+			if a.Key == "progress.id" {
+				events = append(events, e.Name+" "+a.Value.AsString())
+			}
+		}
+	}
+	want := []string{
+		"task started analysis/providers",
+		"task started analysis/providers/java",
+		"task done analysis/providers/java",
+		"task done analysis/providers",
+		"task started analysis/rules",
+		"task done analysis",
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected the events %v, got %v", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("expected the events %v, got %v", want, events)
+		}
+	}
+}
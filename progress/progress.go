@@ -0,0 +1,298 @@
+// Package progress reports the progress of an analysis as a tree of tasks, the
+// stages of the analysis, the providers being initialized, the locations they
+// prepare, so that the tasks run concurrently are reported along with the
+// progress of the whole analysis weighted over them.
+package progress
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stage is the stage of the analysis a task is part of
+type Stage string
+
+const (
+	StageAnalysis        Stage = "analysis"
+	StageProviderInit    Stage = "providerInit"
+	StageProviderPrepare Stage = "providerPrepare"
+	StageRuleParsing     Stage = "ruleParsing"
+	StageRuleExecution   Stage = "ruleExecution"
+)
+
+// ProgressEvent is a change of the progress of a task
+type ProgressEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	// ID identifies the task, it is the ID of its parent and its name
+	// joined with a slash
+	ID       string `json:"id"`
+	ParentID string `json:"parentId,omitempty"`
+	Stage    Stage  `json:"stage"`
+	Name     string `json:"name"`
+	Message  string `json:"message,omitempty"`
+	// Weight is the weight of the task among the sub-tasks of its parent
+	Weight float64 `json:"weight"`
+	// Current and Total are the items of the task done and to do, Total is 0
+	// until it is known
+	Current int `json:"current"`
+	Total   int `json:"total"`
+	// Percent is the progress of the task, of its items or weighted over its
+	// sub-tasks when it has any, from 0 to 100
+	Percent float64 `json:"percent"`
+	// Overall is the progress of the whole analysis from 0 to 100
+	Overall float64 `json:"overall"`
+	// Throughput is the items of the task done per second over the last
+	// rateWindow, 0 until it is known
+	Throughput float64 `json:"throughput,omitempty"`
+	// ETASeconds is the estimated time remaining of the whole analysis in
+	// seconds, from how fast it progressed over the last rateWindow, 0 until
+	// it is known
+	ETASeconds float64 `json:"etaSeconds,omitempty"`
+	Done       bool    `json:"done,omitempty"`
+	// State is the state of the analysis, see Control
+	State State `json:"state,omitempty"`
+}
+
+// ETA returns the estimated time remaining of the analysis
+func (e ProgressEvent) ETA() time.Duration {
+	return time.Duration(e.ETASeconds * float64(time.Second)).Round(time.Second)
+}
+
+// Reporter receives the progress events of a collector, one at a time in the
+// order of the changes.
+type Reporter interface {
+	Report(event ProgressEvent)
+}
+
+// Collector keeps the tree of tasks of an analysis and reports their changes
+type Collector struct {
+	mutex     sync.Mutex
+	reporters []Reporter
+	root      *Task
+	control   *Control
+	// rate is the rate of the progress of the analysis
+	rate rate
+	now  func() time.Time
+}
+
+type Option func(c *Collector)
+
+// WithReporter adds a reporter the events are reported to
+func WithReporter(r Reporter) Option {
+	return func(c *Collector) {
+		c.reporters = append(c.reporters, r)
+	}
+}
+
+// NewCollector returns a collector, its root task is the whole analysis.
+func NewCollector(options ...Option) *Collector {
+	c := &Collector{
+		now: time.Now,
+	}
+	c.root = &Task{collector: c, id: string(StageAnalysis), name: string(StageAnalysis), stage: StageAnalysis, weight: 1}
+	c.control = newControl(c)
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// Root returns the task of the whole analysis
+func (c *Collector) Root() *Task {
+	if c == nil {
+		return nil
+	}
+	return c.root
+}
+
+// Task is a task of an analysis. Tasks with sub-tasks progress as the
+// weighted average of their sub-tasks, the others as the items they did out
+// of their total.
+//
+// The methods of a nil Task do nothing, so that the code reporting progress
+// doesn't need to check whether progress is collected.
+type Task struct {
+	collector *Collector
+	parent    *Task
+	children  []*Task
+
+	id      string
+	name    string
+	stage   Stage
+	weight  float64
+	message string
+	current int
+	total   int
+	done    bool
+	// rate is the rate of the items done
+	rate rate
+}
+
+// Task starts a sub-task with the weight it has among the other sub-tasks,
+// the sub-tasks with the same name are the same task.
+func (t *Task) Task(stage Stage, name string, weight float64) *Task {
+	if t == nil {
+		return nil
+	}
+	t.collector.mutex.Lock()
+	defer t.collector.mutex.Unlock()
+	for _, child := range t.children {
+		if child.name == name {
+			return child
+		}
+	}
+	if weight <= 0 {
+		weight = 1
+	}
+	child := &Task{
+		collector: t.collector,
+		parent:    t,
+		id:        t.id + "/" + strings.ReplaceAll(name, "/", "-"),
+		name:      name,
+		stage:     stage,
+		weight:    weight,
+	}
+	t.children = append(t.children, child)
+	t.collector.report(child)
+	return child
+}
+
+// SetTotal sets the number of items of the task
+func (t *Task) SetTotal(total int) {
+	if t == nil {
+		return
+	}
+	t.collector.mutex.Lock()
+	defer t.collector.mutex.Unlock()
+	t.total = total
+	t.collector.report(t)
+}
+
+// Add adds n items done to the task with a message telling what it does
+func (t *Task) Add(n int, message string) {
+	if t == nil {
+		return
+	}
+	t.collector.mutex.Lock()
+	defer t.collector.mutex.Unlock()
+	t.current += n
+	t.message = message
+	t.rate.add(t.collector.now(), float64(t.current))
+	t.collector.report(t)
+}
+
+// Set sets the items done of the task with a message telling what it does
+func (t *Task) Set(current int, message string) {
+	if t == nil {
+		return
+	}
+	t.collector.mutex.Lock()
+	defer t.collector.mutex.Unlock()
+	t.current = current
+	t.message = message
+	t.rate.add(t.collector.now(), float64(t.current))
+	t.collector.report(t)
+}
+
+// Update sets the items done and to do of the task with a message telling
+// what it does
+func (t *Task) Update(current, total int, message string) {
+	if t == nil {
+		return
+	}
+	t.collector.mutex.Lock()
+	defer t.collector.mutex.Unlock()
+	t.current = current
+	t.total = total
+	t.message = message
+	t.rate.add(t.collector.now(), float64(t.current))
+	t.collector.report(t)
+}
+
+// Done finishes the task along with its sub-tasks
+func (t *Task) Done() {
+	if t == nil {
+		return
+	}
+	t.collector.mutex.Lock()
+	defer t.collector.mutex.Unlock()
+	if t.done {
+		return
+	}
+	t.done = true
+	if t.total > 0 {
+		t.current = t.total
+	}
+	t.collector.report(t)
+}
+
+// progress returns the progress of the task from 0 to 1
+func (t *Task) progress() float64 {
+	if t.done {
+		return 1
+	}
+	if len(t.children) > 0 {
+		sum, weights := 0.0, 0.0
+		for _, child := range t.children {
+			sum += child.weight * child.progress()
+			weights += child.weight
+		}
+		return sum / weights
+	}
+	if t.total <= 0 {
+		return 0
+	}
+	if t.current >= t.total {
+		return 1
+	}
+	return float64(t.current) / float64(t.total)
+}
+
+// report sends the event of the change of the task to the reporters, the
+// mutex of the collector must be held
+func (c *Collector) report(t *Task) {
+	now := c.now()
+	overall := c.root.progress()
+	c.rate.add(now, overall)
+	event := ProgressEvent{
+		Timestamp:  now,
+		ID:         t.id,
+		Stage:      t.stage,
+		Name:       t.name,
+		Message:    t.message,
+		Weight:     t.weight,
+		Current:    t.current,
+		Total:      t.total,
+		Percent:    100 * t.progress(),
+		Overall:    100 * overall,
+		Throughput: t.rate.perSecond(),
+		Done:       t.done,
+		State:      c.control.state,
+	}
+	if r := c.rate.perSecond(); r > 0 && overall < 1 {
+		event.ETASeconds = (1 - overall) / r
+	}
+	if t.parent != nil {
+		event.ParentID = t.parent.id
+	}
+	for _, r := range c.reporters {
+		r.Report(event)
+	}
+}
+
+type taskKey struct{}
+
+// WithTask returns a context carrying the task, the code run with it reports
+// its progress as sub-tasks of it.
+func WithTask(ctx context.Context, t *Task) context.Context {
+	return context.WithValue(ctx, taskKey{}, t)
+}
+
+// FromContext returns the task of the context, nil when progress is not
+// collected.
+func FromContext(ctx context.Context) *Task {
+	t, _ := ctx.Value(taskKey{}).(*Task)
+	return t
+}
@@ -0,0 +1,163 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+type recordingReporter struct {
+	events []ProgressEvent
+}
+
+func (r *recordingReporter) Report(event ProgressEvent) {
+	r.events = append(r.events, event)
+}
+
+func (r *recordingReporter) last() ProgressEvent {
+	return r.events[len(r.events)-1]
+}
+
+func TestCollector(t *testing.T) {
+	reporter := &recordingReporter{}
+	c := NewCollector(WithReporter(reporter))
+	providers := c.Root().Task(StageProviderInit, "providers", 1)
+	rules := c.Root().Task(StageRuleExecution, "rules", 3)
+
+	java := providers.Task(StageProviderInit, "java", 1)
+	providers.Task(StageProviderInit, "builtin", 1).Done()
+	java.SetTotal(4000)
+	java.Set(1200, "indexing")
+	event := reporter.last()
+	if event.ID != "analysis/providers/java" || event.ParentID != "analysis/providers" || event.Stage != StageProviderInit {
+		t.Errorf("unexpected event of the sub-task %+v", event)
+	}
+	if event.Percent != 30 {
+		t.Errorf("expected the sub-task to be at 30%%, got %v", event.Percent)
+	}
+	// the providers are at (100 + 30) / 2, a quarter of the analysis
+	if math.Abs(event.Overall-16.25) > 1e-9 {
+		t.Errorf("expected the analysis to be at 16.25%%, got %v", event.Overall)
+	}
+	if got := describe(event); got != "java: indexing 1200/4000" {
+		t.Errorf("unexpected description %q", got)
+	}
+
+	if providers.Task(StageProviderInit, "java", 5) != java {
+		t.Errorf("expected the sub-task with the same name to be the same task")
+	}
+	providers.Done()
+	rules.SetTotal(10)
+	rules.Add(5, "rule-005")
+	if event := reporter.last(); math.Abs(event.Overall-62.5) > 1e-9 {
+		t.Errorf("expected the analysis to be at 62.5%%, got %v", event.Overall)
+	}
+	rules.Done()
+	rules.Done()
+	if event := reporter.last(); !event.Done || event.Current != 10 || event.Overall != 100 {
+		t.Errorf("expected the rules to be done, got %+v", event)
+	}
+	if len(reporter.events) != 11 {
+		t.Errorf("expected a task to be done once, got %d events", len(reporter.events))
+	}
+}
+
+func TestNilTask(t *testing.T) {
+	var c *Collector
+	task := FromContext(context.Background())
+	if task != nil || c.Root() != nil {
+		t.Fatalf("expected no task")
+	}
+	sub := task.Task(StageRuleExecution, "rules", 1)
+	sub.SetTotal(1)
+	sub.Add(1, "rule-001")
+	sub.Done()
+
+	task = NewCollector().Root()
+	if FromContext(WithTask(context.Background(), task)) != task {
+		t.Errorf("expected the task of the context")
+	}
+}
+
+func TestThrottledReporter(t *testing.T) {
+	reporter := &recordingReporter{}
+	throttled := NewThrottledReporter(reporter, time.Second)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		throttled.Report(ProgressEvent{ID: "rules", Current: i, Timestamp: start.Add(time.Duration(i) * 300 * time.Millisecond)})
+	}
+	throttled.Report(ProgressEvent{ID: "providers", Timestamp: start})
+	throttled.Report(ProgressEvent{ID: "rules", Current: 10, Done: true, Timestamp: start.Add(3 * time.Second)})
+	got := []int{}
+	for _, e := range reporter.events {
+		if e.ID == "rules" {
+			got = append(got, e.Current)
+		}
+	}
+	want := []int{0, 4, 8, 10}
+	if len(got) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected events %v, got %v", want, got)
+		}
+	}
+	if len(reporter.events) != 5 {
+		t.Errorf("expected the first event of another task to be reported")
+	}
+}
+
+func TestTextReporter(t *testing.T) {
+	b := &bytes.Buffer{}
+	r, err := NewReporter(TextFormat, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Report(ProgressEvent{Name: "java", Message: "indexing", Current: 1200, Total: 4000, Overall: 62})
+	if got := b.String(); got != "Analyzing (62%) - java: indexing 1200/4000\n" {
+		t.Errorf("unexpected text %q", got)
+	}
+	if _, err := NewReporter("xml", b); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestETA(t *testing.T) {
+	reporter := &recordingReporter{}
+	c := NewCollector(WithReporter(reporter))
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	rules := c.Root().Task(StageRuleExecution, "rules", 1)
+	rules.SetTotal(2400)
+	rules.Add(0, "")
+	if event := reporter.last(); event.Throughput != 0 || event.ETASeconds != 0 {
+		t.Errorf("expected no estimation before any progress, got %+v", event)
+	}
+	// 10 rules a second for two minutes, the first minute is out of the window
+	for i := 0; i < 120; i++ {
+		now = now.Add(100 * time.Millisecond)
+		rules.Add(1, "rule")
+		now = now.Add(900 * time.Millisecond)
+		rules.Add(9, "rule")
+	}
+	event := reporter.last()
+	if math.Abs(event.Throughput-10) > 0.5 {
+		t.Errorf("expected 10 rules per second, got %v", event.Throughput)
+	}
+	// half of the rules are done
+	if eta := event.ETA(); eta < 115*time.Second || eta > 125*time.Second {
+		t.Errorf("expected 2 minutes left, got %v", eta)
+	}
+	if len(rules.rate.samples) > int(rateWindow/sampleInterval)+2 {
+		t.Errorf("expected the samples out of the window to be dropped, got %d", len(rules.rate.samples))
+	}
+
+	b := &bytes.Buffer{}
+	NewTextReporter(b).Report(event)
+	if got := b.String(); got != "Analyzing (50%, 2m0s left) - rules: rule 1200/2400 (10.0/s)\n" {
+		t.Errorf("unexpected text %q", got)
+	}
+}
@@ -0,0 +1,52 @@
+package progress
+
+import "time"
+
+const (
+	// rateWindow is how far back the rates are measured, long enough for the
+	// rules that take minutes not to stall the estimations
+	rateWindow = time.Minute
+	// sampleInterval is the least time between the samples of a rate
+	sampleInterval = time.Second
+)
+
+type sample struct {
+	at    time.Time
+	value float64
+}
+
+// rate measures how fast a value grows over the last rateWindow
+type rate struct {
+	samples []sample
+}
+
+// add records the value at a time, it replaces the last sample until the
+// last sample is sampleInterval after the previous one
+func (r *rate) add(at time.Time, value float64) {
+	n := len(r.samples)
+	if n > 1 && r.samples[n-1].at.Sub(r.samples[n-2].at) < sampleInterval {
+		r.samples[n-1] = sample{at: at, value: value}
+	} else {
+		r.samples = append(r.samples, sample{at: at, value: value})
+	}
+	// keep the last sample older than the window for the rate to span it
+	old := 0
+	for old+1 < len(r.samples) && at.Sub(r.samples[old+1].at) >= rateWindow {
+		old++
+	}
+	r.samples = r.samples[old:]
+}
+
+// perSecond returns the growth of the value per second, 0 until the samples
+// span a sampleInterval
+func (r *rate) perSecond() float64 {
+	if len(r.samples) < 2 {
+		return 0
+	}
+	first, last := r.samples[0], r.samples[len(r.samples)-1]
+	elapsed := last.at.Sub(first.at)
+	if elapsed < sampleInterval {
+		return 0
+	}
+	return (last.value - first.value) / elapsed.Seconds()
+}
@@ -0,0 +1,150 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// TextFormat writes a line for every event
+	TextFormat = "text"
+	// BarFormat redraws a progress bar of the analysis on a terminal
+	BarFormat = "bar"
+	// JSONFormat writes the events as JSON, one event per line
+	JSONFormat = "json"
+)
+
+// Formats are the formats progress can be reported with
+var Formats = []string{TextFormat, BarFormat, JSONFormat}
+
+// NewReporter returns the reporter of the format writing to w
+func NewReporter(format string, w io.Writer) (Reporter, error) {
+	switch format {
+	case TextFormat:
+		return NewTextReporter(w), nil
+	case BarFormat:
+		return NewProgressBarReporter(w, 30), nil
+	case JSONFormat:
+		return NewJSONReporter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown progress format %s, it must be one of %v", format, Formats)
+	}
+}
+
+// describe describes the event as the task, what it does, its items and
+// how fast it does them
+func describe(event ProgressEvent) string {
+	s := event.Name
+	if event.Message != "" {
+		s += ": " + event.Message
+	}
+	if event.Total > 0 {
+		s += fmt.Sprintf(" %d/%d", event.Current, event.Total)
+	}
+	if event.Done {
+		s += " (done)"
+	} else if event.Throughput > 0 {
+		s += fmt.Sprintf(" (%.1f/s)", event.Throughput)
+	}
+	return s
+}
+
+// describeOverall describes the progress of the analysis and the time it has
+// left, or its state when it is not running
+func describeOverall(event ProgressEvent) string {
+	s := fmt.Sprintf("%.0f%%", event.Overall)
+	if event.State != "" && event.State != StateRunning {
+		return s + ", " + string(event.State)
+	}
+	if eta := event.ETA(); eta > 0 {
+		s += ", " + eta.String() + " left"
+	}
+	return s
+}
+
+// TextReporter writes a line for every event such as
+// "Analyzing (62%, 3m20s left) - rules: rule-001 500/800 (12.5/s)"
+type TextReporter struct {
+	w io.Writer
+}
+
+func NewTextReporter(w io.Writer) *TextReporter {
+	return &TextReporter{w: w}
+}
+
+func (r *TextReporter) Report(event ProgressEvent) {
+	fmt.Fprintf(r.w, "Analyzing (%s) - %s\n", describeOverall(event), describe(event))
+}
+
+// ProgressBarReporter redraws a single line with a bar of the progress of the
+// analysis and the last event
+type ProgressBarReporter struct {
+	w     io.Writer
+	width int
+	// last is the length of the last line, the next one is padded to erase
+	// it
+	last int
+}
+
+func NewProgressBarReporter(w io.Writer, width int) *ProgressBarReporter {
+	return &ProgressBarReporter{w: w, width: width}
+}
+
+func (r *ProgressBarReporter) Report(event ProgressEvent) {
+	filled := int(event.Overall / 100 * float64(r.width))
+	if filled > r.width {
+		filled = r.width
+	}
+	line := fmt.Sprintf("[%s%s] %s - %s", strings.Repeat("#", filled), strings.Repeat(" ", r.width-filled), describeOverall(event), describe(event))
+	padding := ""
+	if len(line) < r.last {
+		padding = strings.Repeat(" ", r.last-len(line))
+	}
+	r.last = len(line)
+	end := ""
+	if event.Done && event.ParentID == "" {
+		end = "\n"
+	}
+	fmt.Fprintf(r.w, "\r%s%s%s", line, padding, end)
+}
+
+// JSONReporter writes the events as JSON, one event per line
+type JSONReporter struct {
+	encoder *json.Encoder
+}
+
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{encoder: json.NewEncoder(w)}
+}
+
+func (r *JSONReporter) Report(event ProgressEvent) {
+	r.encoder.Encode(event)
+}
+
+// ThrottledReporter reports at most an event of a task every interval to a
+// reporter, the first and last events of every task are always reported.
+type ThrottledReporter struct {
+	reporter Reporter
+	interval time.Duration
+	reported map[string]time.Time
+}
+
+func NewThrottledReporter(reporter Reporter, interval time.Duration) *ThrottledReporter {
+	return &ThrottledReporter{
+		reporter: reporter,
+		interval: interval,
+		reported: map[string]time.Time{},
+	}
+}
+
+func (r *ThrottledReporter) Report(event ProgressEvent) {
+	last, ok := r.reported[event.ID]
+	if ok && !event.Done && event.Timestamp.Sub(last) < r.interval {
+		return
+	}
+	r.reported[event.ID] = event.Timestamp
+	r.reporter.Report(event)
+}
@@ -0,0 +1,167 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// WebhookFormat posts the events as JSON to a webhook
+	WebhookFormat = "webhook"
+
+	// webhookAttempts is how many times a payload is posted before it is
+	// dropped
+	webhookAttempts = 4
+	// webhookBackoff is the wait before the first retry, doubled for every
+	// other one
+	webhookBackoff = 500 * time.Millisecond
+	// webhookQueue is how many events wait to be posted before the next
+	// ones are dropped
+	webhookQueue = 100
+)
+
+// WebhookPayload is the JSON body posted to the webhook, with either an
+// event or the summary of the analysis
+type WebhookPayload struct {
+	Event   *ProgressEvent `json:"event,omitempty"`
+	Summary *Summary       `json:"summary,omitempty"`
+}
+
+// Summary is the summary of an analysis posted to the webhook once it ends
+type Summary struct {
+	StartTime       time.Time `json:"startTime"`
+	EndTime         time.Time `json:"endTime"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	// Overall is the progress the analysis ended at, 100 unless it failed
+	Overall float64 `json:"overall"`
+	// Done tells whether the analysis was done
+	Done bool `json:"done"`
+}
+
+// WebhookReporter posts the events as JSON to a webhook, and a summary of the
+// analysis once it is closed. The events are posted in the background in
+// the order they were reported, the payloads the webhook failed to take with
+// a server error are posted again after a backoff.
+type WebhookReporter struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+	log     logr.Logger
+	backoff time.Duration
+
+	events  chan ProgressEvent
+	wg      sync.WaitGroup
+	summary Summary
+	started bool
+	// dropped counts the events dropped because the webhook was too slow
+	dropped int
+}
+
+// NewWebhookReporter returns a reporter posting to the url with the headers,
+// such as the Authorization header the webhook expects.
+func NewWebhookReporter(url string, headers http.Header, log logr.Logger) *WebhookReporter {
+	r := &WebhookReporter{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		log:     log,
+		backoff: webhookBackoff,
+		events:  make(chan ProgressEvent, webhookQueue),
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for event := range r.events {
+			e := event
+			r.post(WebhookPayload{Event: &e})
+		}
+	}()
+	return r
+}
+
+func (r *WebhookReporter) Report(event ProgressEvent) {
+	if !r.started {
+		r.summary.StartTime = event.Timestamp
+		r.started = true
+	}
+	r.summary.EndTime = event.Timestamp
+	r.summary.Overall = event.Overall
+	if event.ParentID == "" && event.Done {
+		r.summary.Done = true
+	}
+	select {
+	case r.events <- event:
+	default:
+		r.dropped++
+	}
+}
+
+// Close posts the events left and the summary of the analysis
+func (r *WebhookReporter) Close() error {
+	close(r.events)
+	r.wg.Wait()
+	if r.dropped > 0 {
+		r.log.Info("dropped progress events the webhook was too slow to take", "url", r.url, "events", r.dropped)
+	}
+	summary := r.summary
+	summary.DurationSeconds = summary.EndTime.Sub(summary.StartTime).Seconds()
+	return r.post(WebhookPayload{Summary: &summary})
+}
+
+// post posts the payload, retrying with a backoff while the webhook can't be
+// reached or fails with a server error
+func (r *WebhookReporter) post(payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	backoff := r.backoff
+	for attempt := 1; ; attempt++ {
+		err = r.send(body)
+		if err == nil {
+			return nil
+		}
+		if _, retry := err.(retryableError); !retry || attempt == webhookAttempts {
+			r.log.V(3).Error(err, "unable to post progress to the webhook", "url", r.url, "attempts", attempt)
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// retryableError is an error the webhook may not fail with again
+type retryableError struct {
+	error
+}
+
+func (r *WebhookReporter) send(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range r.headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return retryableError{err}
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return retryableError{fmt.Errorf("webhook answered %s", resp.Status)}
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package progress
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestWebhookReporter(t *testing.T) {
+	var (
+		mutex    sync.Mutex
+		payloads []WebhookPayload
+		requests int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests++
+		if req.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// the first post fails and is retried
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		payload := WebhookPayload{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			t.Errorf("unexpected body: %v", err)
+		}
+		payloads = append(payloads, payload)
+	}))
+	defer server.Close()
+
+	r := NewWebhookReporter(server.URL, http.Header{"Authorization": {"Bearer token"}}, logr.Discard())
+	r.backoff = time.Millisecond
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	r.Report(ProgressEvent{ID: "analysis/rules", ParentID: "analysis", Timestamp: start, Total: 10})
+	r.Report(ProgressEvent{ID: "analysis/rules", ParentID: "analysis", Timestamp: start.Add(time.Minute), Current: 10, Total: 10, Done: true, Overall: 100})
+	r.Report(ProgressEvent{ID: "analysis", Timestamp: start.Add(2 * time.Minute), Done: true, Overall: 100})
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(payloads) != 4 {
+		t.Fatalf("expected the events and the summary to be posted, got %+v", payloads)
+	}
+	if payloads[0].Event == nil || payloads[0].Event.Total != 10 || payloads[2].Event == nil || payloads[2].Event.ID != "analysis" {
+		t.Errorf("expected the events to be posted in order, got %+v", payloads)
+	}
+	summary := payloads[3].Summary
+	if summary == nil || !summary.Done || summary.Overall != 100 || summary.DurationSeconds != 120 || !summary.StartTime.Equal(start) {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	r = NewWebhookReporter(server.URL, nil, logr.Discard())
+	r.backoff = time.Millisecond
+	before := requests
+	if err := r.Close(); err == nil {
+		t.Errorf("expected an error posting without the authorization")
+	}
+	if requests-before != 1 {
+		t.Errorf("expected a client error not to be retried, got %d requests", requests-before)
+	}
+}
@@ -0,0 +1,220 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+)
+
+// maxChainBindings is the most values of chained variables a condition is
+// evaluated with
+const maxChainBindings = 100
+
+// chainReferenceRegex matches the {{name.variable}} templates of a condition
+var chainReferenceRegex = regexp.MustCompile(`\{\{\s*([^{}\s.]+)\.([^{}\s.]+)\s*\}\}`)
+
+// chainBinding is a value of each of the chained variables a condition refers
+// to, by the name of the chained condition
+type chainBinding map[string]map[string]interface{}
+
+// chainReferences returns the variables of the incidents of chained
+// conditions a condition refers to, by the name of the chained conditions.
+// The fields of the chain template, such as filepaths, are not variables.
+func chainReferences(info interface{}, templates map[string]engine.ChainTemplate) map[string][]string {
+	references := map[string][]string{}
+	walkConditionStrings(info, func(s string) {
+		for _, match := range chainReferenceRegex.FindAllStringSubmatch(s, -1) {
+			name, variable := match[1], match[2]
+			if _, ok := templates[name]; !ok || isChainTemplateField(variable) {
+				continue
+			}
+			if !containsString(references[name], variable) {
+				references[name] = append(references[name], variable)
+			}
+		}
+	})
+	return references
+}
+
+func isChainTemplateField(name string) bool {
+	switch strings.ToLower(name) {
+	case "filepaths", "extras", "excludedpaths", "variables":
+		return true
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func walkConditionStrings(info interface{}, walk func(string)) {
+	switch v := info.(type) {
+	case string:
+		walk(v)
+	case map[string]interface{}:
+		for _, e := range v {
+			walkConditionStrings(e, walk)
+		}
+	case map[interface{}]interface{}:
+		for _, e := range v {
+			walkConditionStrings(e, walk)
+		}
+	case []interface{}:
+		for _, e := range v {
+			walkConditionStrings(e, walk)
+		}
+	}
+}
+
+// chainBindings returns the distinct values of the referenced variables, the
+// incidents without one of the variables of their condition are skipped. The
+// values of several chained conditions are combined, at most max bindings are
+// returned and truncated tells whether there were more of them.
+func chainBindings(references map[string][]string, templates map[string]engine.ChainTemplate, max int) (bindings []chainBinding, truncated bool) {
+	names := []string{}
+	for name := range references {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bindings = []chainBinding{{}}
+	for _, name := range names {
+		values := []map[string]interface{}{}
+		seen := map[string]bool{}
+	Incidents:
+		for _, incidentVariables := range templates[name].Variables {
+			value := map[string]interface{}{}
+			for _, variable := range references[name] {
+				v, ok := incidentVariables[variable]
+				if !ok {
+					continue Incidents
+				}
+				value[variable] = v
+			}
+			key, err := json.Marshal(value)
+			if err != nil {
+				key = []byte(fmt.Sprint(value))
+			}
+			if seen[string(key)] {
+				continue
+			}
+			seen[string(key)] = true
+			values = append(values, value)
+		}
+		combined := []chainBinding{}
+		for _, binding := range bindings {
+			for _, value := range values {
+				if len(combined) == max {
+					return combined, true
+				}
+				b := chainBinding{name: value}
+				for k, v := range binding {
+					b[k] = v
+				}
+				combined = append(combined, b)
+			}
+		}
+		bindings = combined
+	}
+	return bindings, false
+}
+
+// bindConditionInfo returns a copy of a condition with the templates of the
+// chained variables replaced by their values. A string that is only a
+// template gets the value itself, such as a list, the other ones get the
+// value as text.
+func bindConditionInfo(info interface{}, binding chainBinding) interface{} {
+	switch v := info.(type) {
+	case string:
+		if match := chainReferenceRegex.FindStringSubmatch(strings.TrimSpace(v)); match != nil && match[0] == strings.TrimSpace(v) {
+			if value, ok := binding[match[1]][match[2]]; ok {
+				return value
+			}
+		}
+		return chainReferenceRegex.ReplaceAllStringFunc(v, func(reference string) string {
+			match := chainReferenceRegex.FindStringSubmatch(reference)
+			if value, ok := binding[match[1]][match[2]]; ok {
+				return chainValueString(value)
+			}
+			return reference
+		})
+	case map[string]interface{}:
+		bound := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			bound[k] = bindConditionInfo(e, binding)
+		}
+		return bound
+	case map[interface{}]interface{}:
+		bound := make(map[interface{}]interface{}, len(v))
+		for k, e := range v {
+			bound[k] = bindConditionInfo(e, binding)
+		}
+		return bound
+	case []interface{}:
+		bound := make([]interface{}, len(v))
+		for i, e := range v {
+			bound[i] = bindConditionInfo(e, binding)
+		}
+		return bound
+	}
+	return info
+}
+
+// chainValueString returns a value of a variable as text, lists and maps as
+// json
+func chainValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}, []string, map[string]interface{}:
+		b, err := json.Marshal(v)
+		if err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// withBindingVariables returns the variables of an incident along with the
+// chained variables it was found with, the ones of the incident win
+func withBindingVariables(variables map[string]interface{}, binding chainBinding) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for _, values := range binding {
+		for k, v := range values {
+			merged[k] = v
+		}
+	}
+	for k, v := range variables {
+		merged[k] = v
+	}
+	return merged
+}
+
+// mergeTemplateContext adds a template context to another one, the lists of
+// both are concatenated
+func mergeTemplateContext(dst, src map[string]interface{}) {
+	for k, v := range src {
+		switch existing := dst[k].(type) {
+		case []string:
+			if values, ok := v.([]string); ok {
+				dst[k] = append(append([]string{}, existing...), values...)
+				continue
+			}
+		case []interface{}:
+			if values, ok := v.([]interface{}); ok {
+				dst[k] = append(append([]interface{}{}, existing...), values...)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
@@ -0,0 +1,323 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/konveyor/analyzer-lsp/atomicfile"
+	"github.com/konveyor/analyzer-lsp/fileuri"
+	"gopkg.in/yaml.v2"
+)
+
+// ConditionResultCache persists the responses of the providers to the
+// conditions across analyses, so that analyzing an application again only
+// evaluates the conditions that changed. The responses of a provider are
+// kept for its version and config, they are invalidated when the files of
+// its locations change, see NotifyFileChanges, and when the files of their
+// incidents change. Remove the directory to invalidate the cache or reclaim
+// the space of the responses of former versions and configs.
+type ConditionResultCache struct {
+	dir string
+	// providers are the versions and configs of the providers by their names
+	providers map[string]cachedProvider
+	// locations are the locations of the providers by their directories
+	locations map[string][]string
+	mutex     sync.Mutex
+}
+
+// cachedProvider is the version and config of a provider the responses are
+// kept for, in a directory named after them
+type cachedProvider struct {
+	dir        string
+	version    string
+	configHash string
+}
+
+// conditionResult is a response of a provider along with the digests of the
+// files of its incidents
+type conditionResult struct {
+	Files    map[string]string        `json:"files,omitempty"`
+	Response ProviderEvaluateResponse `json:"response"`
+}
+
+// NewConditionResultCache creates the cache of the responses of the
+// providers of the configs in the directory, versions are the builds of the
+// providers by their names, see BuildVersion.
+func NewConditionResultCache(dir string, configs []Config, versions map[string]string) (*ConditionResultCache, error) {
+	c := &ConditionResultCache{
+		dir:       dir,
+		providers: map[string]cachedProvider{},
+		locations: map[string][]string{},
+	}
+	for _, config := range configs {
+		content, err := yaml.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		p := cachedProvider{version: versions[config.Name], configHash: digest(content)}
+		providerDir := filepath.Join(dir, digest([]byte(p.version+"\x00"+p.configHash)))
+		p.dir = providerDir
+		if err := os.MkdirAll(providerDir, 0755); err != nil {
+			return nil, fmt.Errorf("unable to create condition cache directory %s: %w", providerDir, err)
+		}
+		locations := []string{}
+		for _, ic := range config.InitConfig {
+			if ic.Location == "" {
+				continue
+			}
+			if abs, err := filepath.Abs(ic.Location); err == nil {
+				locations = append(locations, abs)
+			}
+		}
+		c.providers[config.Name] = p
+		c.locations[providerDir] = locations
+	}
+	return c, nil
+}
+
+// ConditionKey returns the key of a condition of the provider in the
+// condition cache of a run
+func ConditionKey(providerName string, capability string, normalizedInfo []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", providerName, capability)
+	h.Write(normalizedInfo)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Key returns the key of a condition of the provider in the cache, it is
+// the key of the condition for the version and config of the provider
+func (c *ConditionResultCache) Key(providerName string, capability string, normalizedInfo []byte) string {
+	p := c.providers[providerName]
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00", providerName, p.version, p.configHash, capability)
+	h.Write(normalizedInfo)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func digest(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
+
+// Get returns the response of the provider to the condition, the responses
+// whose incidents are in files that changed since are removed
+func (c *ConditionResultCache) Get(providerName string, key string) (ProviderEvaluateResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	p, ok := c.providers[providerName]
+	if !ok {
+		return ProviderEvaluateResponse{}, false
+	}
+	path := filepath.Join(p.dir, key+".json")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ProviderEvaluateResponse{}, false
+	}
+	// the responses are kept as JSON, YAML would decode the maps of the
+	// variables of the incidents as maps of interfaces
+	result := conditionResult{}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return ProviderEvaluateResponse{}, false
+	}
+	for file, fileDigest := range result.Files {
+		if d, err := fileDigestOf(file); err != nil || d != fileDigest {
+			os.Remove(path)
+			return ProviderEvaluateResponse{}, false
+		}
+	}
+	return result.Response, true
+}
+
+// Put keeps the response of the provider to the condition
+func (c *ConditionResultCache) Put(providerName string, key string, resp ProviderEvaluateResponse) error {
+	result := conditionResult{Files: map[string]string{}, Response: resp}
+	for _, incident := range resp.Incidents {
+		file := fileuri.Path(string(incident.FileURI))
+		if file == "" {
+			continue
+		}
+		if _, ok := result.Files[file]; ok {
+			continue
+		}
+		d, err := fileDigestOf(file)
+		if err != nil {
+			// the response can't be checked against its files
+			return nil
+		}
+		result.Files[file] = d
+	}
+	content, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	p, ok := c.providers[providerName]
+	if !ok {
+		return nil
+	}
+	return atomicfile.WriteFile(filepath.Join(p.dir, key+".json"), content, 0644)
+}
+
+func fileDigestOf(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NotifyFileChanges invalidates the responses of the providers analyzing
+// the files that were changed, added or removed. A change can make any
+// condition of a provider match differently, not only the ones whose
+// incidents are in the file. It returns the number of responses removed.
+func (c *ConditionResultCache) NotifyFileChanges(files []string) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	removed := 0
+	for providerDir, locations := range c.locations {
+		if !containsAny(locations, files) {
+			continue
+		}
+		entries, err := os.ReadDir(providerDir)
+		if err != nil {
+			return removed, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			if err := os.Remove(filepath.Join(providerDir, entry.Name())); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func containsAny(locations []string, files []string) bool {
+	for _, location := range locations {
+		for _, file := range files {
+			if rel, err := filepath.Rel(location, file); err == nil && (rel == "." || filepath.IsLocal(rel)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fileState is the size and modification time of a file of a location
+type fileState struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"modTime"`
+}
+
+// DetectFileChanges returns the files of the locations of the providers
+// that were changed, added or removed since the last analysis using the
+// cache, comparing their sizes and modification times with the ones of the
+// last analysis. The files of a location are compared for each provider
+// config, as the last analysis may have been run with other configs. Every
+// file is new to the first analysis.
+func (c *ConditionResultCache) DetectFileChanges() ([]string, error) {
+	providerDirs := make([]string, 0, len(c.locations))
+	for providerDir := range c.locations {
+		providerDirs = append(providerDirs, providerDir)
+	}
+	sort.Strings(providerDirs)
+	seen := map[string]bool{}
+	changed := []string{}
+	for _, providerDir := range providerDirs {
+		for _, location := range c.locations[providerDir] {
+			files, err := c.detectLocationChanges(providerDir, location)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				if !seen[file] {
+					seen[file] = true
+					changed = append(changed, file)
+				}
+			}
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// detectLocationChanges compares the files of the location with the manifest
+// of the location of the provider directory and updates the manifest
+func (c *ConditionResultCache) detectLocationChanges(providerDir string, location string) ([]string, error) {
+	current := map[string]fileState{}
+	err := filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == location && os.IsNotExist(err) {
+				// such as maven coordinates
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() {
+			// the state of the repository changes without the files
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		current[path] = fileState{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to detect the changes of %s: %w", location, err)
+	}
+
+	manifestDir := filepath.Join(providerDir, "manifests")
+	if err := os.MkdirAll(manifestDir, 0755); err != nil {
+		return nil, err
+	}
+	manifest := filepath.Join(manifestDir, digest([]byte(location))+".json")
+	previous := map[string]fileState{}
+	if content, err := os.ReadFile(manifest); err == nil {
+		// a manifest that can't be read changes every file
+		json.Unmarshal(content, &previous)
+	}
+	changed := []string{}
+	for path, state := range current {
+		if p, ok := previous[path]; !ok || p != state {
+			changed = append(changed, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+
+	content, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := atomicfile.WriteFile(manifest, content, 0644); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
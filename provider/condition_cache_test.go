@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/fileuri"
+)
+
+type fileClient struct {
+	fakeClient
+	file        string
+	evaluations int
+}
+
+func (c *fileClient) Evaluate(context.Context, string, []byte) (ProviderEvaluateResponse, error) {
+	c.evaluations++
+	return ProviderEvaluateResponse{
+		Matched: true,
+		Incidents: []IncidentContext{{
+			FileURI:    fileuri.FromPath(c.file),
+			LineNumber: intPtr(3),
+			Variables:  map[string]interface{}{"name": "Main", "imports": []interface{}{"javax.ejb.Stateless"}},
+		}},
+		TemplateContext: map[string]interface{}{"name": "Main"},
+	}, nil
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func Test_ConditionResultCache(t *testing.T) {
+	location := t.TempDir()
+	file := filepath.Join(location, "Main.java")
+	if err := os.WriteFile(file, []byte("class Main {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	configs := []Config{{Name: "java", InitConfig: []InitConfig{{Location: location}}}}
+	newCache := func() *ConditionResultCache {
+		cache, err := NewConditionResultCache(dir, configs, map[string]string{"java": "v1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cache
+	}
+
+	client := &fileClient{file: file}
+	evaluate := func(cache *ConditionResultCache) engine.ConditionResponse {
+		response, err := ProviderCondition{
+			Client:        client,
+			ProviderName:  "java",
+			Capability:    "referenced",
+			ConditionInfo: map[string]interface{}{"pattern": "javax.ejb.*"},
+			ResultCache:   cache,
+		}.Evaluate(context.Background(), logr.Discard(), engine.ConditionContext{RuleID: "rule-001"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return response
+	}
+
+	first := evaluate(newCache())
+	second := evaluate(newCache())
+	if client.evaluations != 1 {
+		t.Fatalf("the condition must be evaluated once across runs, got %d evaluations", client.evaluations)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the cached response %#v, got %#v", first, second)
+	}
+
+	// another provider config has responses of its own
+	otherConfigs := []Config{{Name: "java", InitConfig: []InitConfig{{Location: location, AnalysisMode: SourceOnlyAnalysisMode}}}}
+	other, err := NewConditionResultCache(dir, otherConfigs, map[string]string{"java": "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluate(other)
+	if client.evaluations != 2 {
+		t.Errorf("the condition must be evaluated for another provider config, got %d evaluations", client.evaluations)
+	}
+
+	// another build of the provider has responses of its own
+	upgraded, err := NewConditionResultCache(dir, configs, map[string]string{"java": "v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgraded.Key("java", "referenced", nil) == newCache().Key("java", "referenced", nil) {
+		t.Errorf("expected the keys of the conditions to depend on the version of the provider")
+	}
+	evaluate(upgraded)
+	if client.evaluations != 3 {
+		t.Errorf("the condition must be evaluated for another provider version, got %d evaluations", client.evaluations)
+	}
+
+	// a change of the file of the incidents drops the response
+	if err := os.WriteFile(file, []byte("class Main { }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	evaluate(newCache())
+	if client.evaluations != 4 {
+		t.Errorf("the condition must be evaluated again after its incidents changed, got %d evaluations", client.evaluations)
+	}
+	evaluate(newCache())
+	if client.evaluations != 4 {
+		t.Errorf("the response evaluated again must be cached, got %d evaluations", client.evaluations)
+	}
+
+	// a change of another file of the location drops every response
+	cache := newCache()
+	removed, err := cache.NotifyFileChanges([]string{filepath.Join(location, "pom.xml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 response removed, got %d", removed)
+	}
+	if removed, err := cache.NotifyFileChanges([]string{filepath.Join(t.TempDir(), "pom.xml")}); err != nil || removed != 0 {
+		t.Errorf("the changes out of the locations must not remove responses, got %d, %v", removed, err)
+	}
+	evaluate(cache)
+	if client.evaluations != 5 {
+		t.Errorf("the condition must be evaluated again after its location changed, got %d evaluations", client.evaluations)
+	}
+}
+
+func Test_ConditionResultCache_DetectFileChanges(t *testing.T) {
+	location := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(location, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	main := write("src/Main.java", "class Main {}")
+	pom := write("pom.xml", "<project/>")
+	write(".git/HEAD", "ref: refs/heads/main")
+
+	cache, err := NewConditionResultCache(t.TempDir(), []Config{
+		{Name: "java", InitConfig: []InitConfig{{Location: location}}},
+		{Name: "builtin", InitConfig: []InitConfig{{Location: location}}},
+		{Name: "maven", InitConfig: []InitConfig{{Location: filepath.Join(location, "missing")}}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	detect := func() []string {
+		changed, err := cache.DetectFileChanges()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return changed
+	}
+
+	if changed := detect(); !reflect.DeepEqual(changed, []string{pom, main}) {
+		t.Errorf("every file is new to the first analysis, got %v", changed)
+	}
+	if changed := detect(); len(changed) != 0 {
+		t.Errorf("expected no changes, got %v", changed)
+	}
+	write("src/Main.java", "class Main { }")
+	os.Remove(pom)
+	added := write("src/Bean.java", "class Bean {}")
+	write(".git/HEAD", "ref: refs/heads/other")
+	if changed := detect(); !reflect.DeepEqual(changed, []string{pom, added, main}) {
+		t.Errorf("expected the changed, added and removed files, got %v", changed)
+	}
+
+	// an analysis with another provider config doesn't hide the changes
+	// from the next analysis with the first one
+	other, err := NewConditionResultCache(cache.dir, []Config{
+		{Name: "java", InitConfig: []InitConfig{{Location: location, AnalysisMode: SourceOnlyAnalysisMode}}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("src/Main.java", "class Main {}")
+	if changed, err := other.DetectFileChanges(); err != nil || len(changed) == 0 {
+		t.Fatalf("every file is new to the other provider config, got %v, %v", changed, err)
+	}
+	if changed := detect(); !reflect.DeepEqual(changed, []string{main}) {
+		t.Errorf("expected the file changed since the last analysis with the config, got %v", changed)
+	}
+}
@@ -0,0 +1,405 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/swaggest/jsonschema-go"
+)
+
+// ConfigSchema is the schema of the providerSpecificConfig of providers,
+// reflected from the typed config of the providers.
+type ConfigSchema struct {
+	// Providers are the names of the providers in the provider settings the
+	// schema applies to
+	Providers []string
+	// Config is the typed config the schema is reflected from
+	Config interface{}
+	// Schema is the reflected schema
+	Schema jsonschema.Schema
+}
+
+var configSchemas = mustReflectConfigSchemas([]ConfigSchema{
+	{Providers: []string{"builtin"}, Config: BuiltinProviderConfig{}},
+	{Providers: []string{"java"}, Config: JavaProviderConfig{}},
+	{Providers: []string{"go", "python", "nodejs", "php"}, Config: LSPServiceClientConfig{}},
+	{Providers: []string{"ruby"}, Config: RubyProviderConfig{}},
+	{Providers: []string{"yaml"}, Config: YqProviderConfig{}},
+	{Providers: []string{"dotnet"}, Config: DotnetProviderConfig{}},
+	{Providers: []string{"k8s"}, Config: K8sProviderConfig{}},
+})
+
+func mustReflectConfigSchemas(schemas []ConfigSchema) []ConfigSchema {
+	r := jsonschema.Reflector{}
+	for i := range schemas {
+		schema, err := r.Reflect(schemas[i].Config, jsonschema.InlineRefs)
+		if err != nil {
+			panic(fmt.Sprintf("unable to reflect the config schema of %v: %v", schemas[i].Providers, err))
+		}
+		schemas[i].Schema = schema
+	}
+	return schemas
+}
+
+// GetConfigSchema returns the schema of the providerSpecificConfig of the
+// provider, providers with other names than the ones of this repository have
+// none.
+func GetConfigSchema(name string) (ConfigSchema, bool) {
+	for _, schema := range configSchemas {
+		for _, provider := range schema.Providers {
+			if provider == name {
+				return schema, true
+			}
+		}
+	}
+	return ConfigSchema{}, false
+}
+
+// Validate checks the providerSpecificConfig against the schema and returns it
+// with the defaults of the missing keys. Every error of the config is
+// returned.
+func (c ConfigSchema) Validate(config map[string]interface{}) (map[string]interface{}, error) {
+	if errs := checkConfigValue("", &c.Schema, config); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, name := range sortedKeys(c.Schema.Properties) {
+		property := c.Schema.Properties[name].TypeObject
+		if _, ok := config[name]; ok || property == nil || property.Default == nil {
+			continue
+		}
+		if config == nil {
+			config = map[string]interface{}{}
+		}
+		value := *property.Default
+		// numbers are integers in the settings read as YAML
+		if i, ok := value.(int64); ok {
+			value = int(i)
+		}
+		config[name] = value
+	}
+	return config, nil
+}
+
+// checkConfigValue returns the errors of the value against the schema, with
+// the path of the keys they are about
+func checkConfigValue(path string, schema *jsonschema.Schema, value interface{}) []error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Type != nil && schema.Type.SimpleTypes != nil && value != nil {
+		expected := *schema.Type.SimpleTypes
+		if !isConfigType(expected, value) {
+			return []error{fmt.Errorf("%s must be a %s, not %s %v", path, expected, configType(value), value)}
+		}
+	}
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, e := range schema.Enum {
+			if e == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return []error{fmt.Errorf("%s must be one of %v, not %v", path, schema.Enum, value)}
+		}
+	}
+	errs := []error{}
+	switch v := value.(type) {
+	case []interface{}:
+		if schema.Items == nil || schema.Items.SchemaOrBool == nil {
+			return nil
+		}
+		for i, item := range v {
+			errs = append(errs, checkConfigValue(fmt.Sprintf("%s[%d]", path, i), schema.Items.SchemaOrBool.TypeObject, item)...)
+		}
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Errorf("%s is required", keyPath(path, name)))
+			}
+		}
+		for _, key := range sortedKeys(v) {
+			if property, ok := schema.Properties[key]; ok {
+				errs = append(errs, checkConfigValue(keyPath(path, key), property.TypeObject, v[key])...)
+				continue
+			}
+			if schema.AdditionalProperties == nil {
+				continue
+			}
+			if schema.AdditionalProperties.TypeBoolean != nil && !*schema.AdditionalProperties.TypeBoolean {
+				known := sortedKeys(schema.Properties)
+				if suggestion := closestKey(key, known); suggestion != "" {
+					errs = append(errs, fmt.Errorf("unknown key %s, did you mean %s?", keyPath(path, key), suggestion))
+				} else {
+					errs = append(errs, fmt.Errorf("unknown key %s, must be one of %s", keyPath(path, key), strings.Join(known, ", ")))
+				}
+				continue
+			}
+			errs = append(errs, checkConfigValue(keyPath(path, key), schema.AdditionalProperties.TypeObject, v[key])...)
+		}
+	}
+	return errs
+}
+
+func keyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closestKey returns the known key the unknown one is most likely a typo of,
+// a key differing in case or by at most two edits, empty when there is none
+func closestKey(key string, known []string) string {
+	closest, distance := "", 3
+	for _, k := range known {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+		if d := editDistance(strings.ToLower(k), strings.ToLower(key)); d < distance {
+			closest, distance = k, d
+		}
+	}
+	return closest
+}
+
+// editDistance is the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// providerSettingsSchema is the schema of a provider of the provider settings
+var providerSettingsSchema = mustReflectConfigSchemas([]ConfigSchema{{Config: ProviderSettings{}}})[0]
+
+// providersWithoutBinary are the providers running in the analyzer, the
+// others are started from their binaryPath or image or connected to at their
+// address
+var providersWithoutBinary = map[string]bool{"builtin": true, "k8s": true}
+
+// ValidateSettings checks the provider settings, as decoded from YAML or JSON,
+// against the schema of the settings and the schemas of the
+// providerSpecificConfig of the providers. Every error of the settings is
+// returned with the provider and the path of the key it is about, so that
+// misconfigured settings fail before any provider is started.
+func ValidateSettings(settings interface{}) error {
+	settings = stringKeys(settings)
+	if settings == nil {
+		return nil
+	}
+	list, ok := settings.([]interface{})
+	if !ok {
+		return fmt.Errorf("provider settings must be a list of providers, not %s", configType(settings))
+	}
+	errs := []error{}
+	for i, item := range list {
+		name := fmt.Sprintf("#%d", i+1)
+		p, ok := item.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Errorf("provider %s must be an object, not %s", name, configType(item)))
+			continue
+		}
+		if n, ok := p["name"].(string); ok && n != "" {
+			name = n
+		}
+		for _, err := range checkConfigValue("", &providerSettingsSchema.Schema, p) {
+			errs = append(errs, fmt.Errorf("provider %s: %w", name, err))
+		}
+		if name == "mock" && isEmptySetting(p["fixtureFile"]) {
+			errs = append(errs, fmt.Errorf("provider %s: fixtureFile is required", name))
+		} else if !providersWithoutBinary[name] && isEmptySetting(p["fixtureFile"]) && isEmptySetting(p["binaryPath"]) && isEmptySetting(p["image"]) && isEmptySetting(p["address"]) {
+			errs = append(errs, fmt.Errorf("provider %s: binaryPath, image or address is required", name))
+		}
+		schema, ok := GetConfigSchema(name)
+		if !ok {
+			continue
+		}
+		initConfigs, _ := p["initConfig"].([]interface{})
+		for j, ic := range initConfigs {
+			ic, _ := ic.(map[string]interface{})
+			config, ok := ic["providerSpecificConfig"].(map[string]interface{})
+			if !ok && ic["providerSpecificConfig"] != nil {
+				// the type is already reported
+				continue
+			}
+			for _, err := range checkConfigValue("", &schema.Schema, config) {
+				errs = append(errs, fmt.Errorf("provider %s: initConfig[%d].providerSpecificConfig: %w", name, j, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isEmptySetting(value interface{}) bool {
+	s, ok := value.(string)
+	return value == nil || (ok && s == "")
+}
+
+// stringKeys returns the value decoded from YAML with the keys of its maps as
+// strings, as they are decoded from JSON
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, item := range v {
+			m[fmt.Sprint(k)] = stringKeys(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, item := range v {
+			m[k] = stringKeys(item)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, item := range v {
+			l[i] = stringKeys(item)
+		}
+		return l
+	}
+	return value
+}
+
+func isConfigType(t jsonschema.SimpleType, value interface{}) bool {
+	actual := configType(value)
+	switch {
+	case actual == t:
+		return true
+	case t == jsonschema.Number && actual == jsonschema.Integer:
+		return true
+	case t == jsonschema.Integer && actual == jsonschema.Number:
+		// numbers of JSON settings are floats
+		f := value.(float64)
+		return f == math.Trunc(f)
+	}
+	return false
+}
+
+func configType(value interface{}) jsonschema.SimpleType {
+	switch value.(type) {
+	case nil:
+		return jsonschema.Null
+	case bool:
+		return jsonschema.Boolean
+	case string:
+		return jsonschema.String
+	case int, int32, int64, uint, uint32, uint64:
+		return jsonschema.Integer
+	case float32, float64:
+		return jsonschema.Number
+	case []interface{}:
+		return jsonschema.Array
+	}
+	return jsonschema.Object
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ConfigSchemaDocs returns the reference of the provider settings and of the
+// providerSpecificConfig of the providers as markdown.
+func ConfigSchemaDocs() string {
+	b := strings.Builder{}
+	b.WriteString("# Provider specific config reference\n\n")
+	b.WriteString("<!-- Generated with `konveyor-analyzer provider-config-docs`, do not edit. -->\n\n")
+	b.WriteString("The provider settings are validated when they are loaded, every unknown key, key of the wrong type ")
+	b.WriteString("and missing required key is reported with its provider before any provider is started.\n")
+	b.WriteString("\n## Provider settings\n\n")
+	b.WriteString("Keys of each provider of the list of the provider settings.\n\n")
+	writeSchemaTable(&b, &providerSettingsSchema.Schema)
+	initConfig := providerSettingsSchema.Schema.Properties["initConfig"].TypeObject.Items.SchemaOrBool.TypeObject
+	b.WriteString("\n### initConfig\n\n")
+	writeSchemaTable(&b, initConfig)
+	b.WriteString("\n### proxyConfig\n\n")
+	writeSchemaTable(&b, providerSettingsSchema.Schema.Properties["proxyConfig"].TypeObject)
+	b.WriteString("\n## providerSpecificConfig\n\n")
+	b.WriteString("The `providerSpecificConfig` of the providers below is validated when the provider settings are loaded, ")
+	b.WriteString("missing keys with a default are set to it. Providers with other names are not validated.\n")
+	for _, schema := range configSchemas {
+		fmt.Fprintf(&b, "\n### %s\n\n", strings.Join(schema.Providers, ", "))
+		if schema.Schema.AdditionalProperties == nil || schema.Schema.AdditionalProperties.TypeBoolean == nil {
+			b.WriteString("Keys of the service clients that are not listed are accepted.\n\n")
+		}
+		writeSchemaTable(&b, &schema.Schema)
+	}
+	return b.String()
+}
+
+// writeSchemaTable writes the keys of the schema of an object as a markdown
+// table
+func writeSchemaTable(b *strings.Builder, schema *jsonschema.Schema) {
+	b.WriteString("| Key | Type | Required | Default | Description |\n")
+	b.WriteString("|-----|------|----------|---------|-------------|\n")
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	for _, name := range sortedKeys(schema.Properties) {
+		property := schema.Properties[name].TypeObject
+		if property == nil {
+			continue
+		}
+		typ := schemaTypeText(property)
+		if property.Items != nil && property.Items.SchemaOrBool != nil {
+			typ = fmt.Sprintf("%s of %s", typ, schemaTypeText(property.Items.SchemaOrBool.TypeObject))
+		}
+		if property.AdditionalProperties != nil {
+			if valueType := schemaTypeText(property.AdditionalProperties.TypeObject); valueType != "" {
+				typ = fmt.Sprintf("%s of %s", typ, valueType)
+			}
+		}
+		if len(property.Enum) > 0 {
+			values := []string{}
+			for _, e := range property.Enum {
+				values = append(values, fmt.Sprintf("`%v`", e))
+			}
+			typ = fmt.Sprintf("%s, one of %s", typ, strings.Join(values, ", "))
+		}
+		requiredText := "No"
+		if required[name] {
+			requiredText = "Yes"
+		}
+		defaultText := ""
+		if property.Default != nil {
+			defaultText = fmt.Sprintf("`%v`", *property.Default)
+		}
+		description := ""
+		if property.Description != nil {
+			description = *property.Description
+		}
+		fmt.Fprintf(b, "| `%s` | %s | %s | %s | %s |\n", name, typ, requiredText, defaultText, description)
+	}
+}
+
+func schemaTypeText(schema *jsonschema.Schema) string {
+	if schema == nil || schema.Type == nil || schema.Type.SimpleTypes == nil {
+		return ""
+	}
+	return string(*schema.Type.SimpleTypes)
+}
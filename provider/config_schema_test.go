@@ -0,0 +1,249 @@
+package provider
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfigSchemaValidate(t *testing.T) {
+	tests := []struct {
+		title    string
+		provider string
+		config   map[string]interface{}
+		expected map[string]interface{}
+		errMsg   string
+	}{
+		{
+			title:    "defaults are set",
+			provider: "java",
+			config:   map[string]interface{}{"lspServerPath": "/jdtls/bin/jdtls", "mavenOffline": true},
+			expected: map[string]interface{}{
+				"lspServerPath":  "/jdtls/bin/jdtls",
+				"mavenOffline":   true,
+				"mavenInsecure":  false,
+				"fernFlowerPath": "/bin/fernflower.jar",
+			},
+		},
+		{
+			title:    "required key",
+			provider: "java",
+			config:   map[string]interface{}{"bundles": "/jdtls/bundle.jar"},
+			errMsg:   "lspServerPath is required",
+		},
+		{
+			title:    "unknown key",
+			provider: "builtin",
+			config:   map[string]interface{}{"tags": "tags.yaml"},
+			errMsg:   "unknown key tags, must be one of depLicensesFile, excludedPaths, featureFlags, fileBatchBytes, fileWorkers, includedPaths, preparedDir, respectIgnoreFiles, skipBinaryFiles, tagsFile",
+		},
+		{
+			title:    "unknown key close to a known one",
+			provider: "builtin",
+			config:   map[string]interface{}{"tagFile": "tags.yaml"},
+			errMsg:   "unknown key tagFile, did you mean tagsFile?",
+		},
+		{
+			title:    "every error",
+			provider: "java",
+			config:   map[string]interface{}{"mavenInsecure": "yes", "mavenOffline": 1},
+			errMsg:   "lspServerPath is required\nmavenInsecure must be a boolean, not string yes\nmavenOffline must be a boolean, not integer 1",
+		},
+		{
+			title:    "type of a key",
+			provider: "java",
+			config:   map[string]interface{}{"lspServerPath": "/jdtls/bin/jdtls", "mavenInsecure": "yes"},
+			errMsg:   "mavenInsecure must be a boolean, not string yes",
+		},
+		{
+			title:    "type of the items of a list",
+			provider: "builtin",
+			config:   map[string]interface{}{"includedPaths": []interface{}{"src", 1}},
+			errMsg:   "includedPaths[1] must be a string, not integer 1",
+		},
+		{
+			title:    "type of the values of a map",
+			provider: "builtin",
+			config:   map[string]interface{}{"featureFlags": map[string]interface{}{"flag": "on"}},
+			errMsg:   "featureFlags.flag must be a boolean, not string on",
+		},
+		{
+			title:    "integers of JSON settings",
+			provider: "go",
+			config:   map[string]interface{}{"lspServerPath": "gopls", "lspMaxConcurrentRequests": float64(4)},
+			expected: map[string]interface{}{
+				"lspServerPath":            "gopls",
+				"lspServerName":            "generic",
+				"lspMaxConcurrentRequests": float64(4),
+			},
+		},
+		{
+			title:    "keys of the service clients",
+			provider: "go",
+			config:   map[string]interface{}{"lspServerPath": "gopls", "lspServerName": "generic", "object": map[string]interface{}{}},
+			expected: map[string]interface{}{
+				"lspServerPath":            "gopls",
+				"lspServerName":            "generic",
+				"lspMaxConcurrentRequests": 10,
+				"object":                   map[string]interface{}{},
+			},
+		},
+		{
+			title:    "no config",
+			provider: "builtin",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			schema, ok := GetConfigSchema(tc.provider)
+			if !ok {
+				t.Fatalf("no schema for provider %s", tc.provider)
+			}
+			config, err := schema.Validate(tc.config)
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Fatalf("expected error %q, got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(config, tc.expected) {
+				t.Errorf("expected config %#v, got %#v", tc.expected, config)
+			}
+		})
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	tests := []struct {
+		title    string
+		settings string
+		errMsg   string
+	}{
+		{
+			title: "valid settings",
+			settings: `
+- name: builtin
+  initConfig:
+  - location: /app
+- name: java
+  binaryPath: /usr/local/bin/java-external-provider
+  proxyConfig:
+    httpproxy: http://proxy:3128
+  initConfig:
+  - location: /app
+    analysisMode: source-only
+    providerSpecificConfig:
+      lspServerPath: /jdtls/bin/jdtls
+- name: go
+  image: quay.io/konveyor/generic-external-provider:latest
+  providerArgs: [--log-level, "3"]
+  initConfig:
+  - location: /app
+    providerSpecificConfig:
+      lspServerPath: /usr/local/bin/gopls
+`,
+		},
+		{
+			title: "mock providers",
+			settings: `
+- name: java
+  fixtureFile: fixtures/java.yaml
+- name: mock
+  fixtureFile: fixtures/mock.yaml
+`,
+		},
+		{
+			title: "mock provider without fixture file",
+			settings: `
+- name: mock
+  initConfig:
+  - location: /app
+`,
+			errMsg: "provider mock: fixtureFile is required",
+		},
+		{
+			title:    "no providers",
+			settings: ``,
+		},
+		{
+			title:    "not a list",
+			settings: `name: java`,
+			errMsg:   "provider settings must be a list of providers, not object",
+		},
+		{
+			title: "keys of the providers",
+			settings: `
+- name: java
+  binarypath: /usr/local/bin/java-external-provider
+  maxConcurrent: many
+  initConfig:
+  - location: /app
+    analysisMode: fll
+    proxyConfig:
+      proxy: http://proxy:3128
+- binaryPath: /usr/local/bin/generic-external-provider
+`,
+			errMsg: `provider java: unknown key binarypath, did you mean binaryPath?
+provider java: initConfig[0].analysisMode must be one of [full source-only], not fll
+provider java: unknown key initConfig[0].proxyConfig.proxy, did you mean noproxy?
+provider java: maxConcurrent must be a integer, not string many
+provider java: binaryPath, image or address is required
+provider java: initConfig[0].providerSpecificConfig: lspServerPath is required
+provider #2: name is required`,
+		},
+		{
+			title: "providerSpecificConfig of the providers",
+			settings: `
+- name: go
+  address: localhost:14651
+  initConfig:
+  - providerSpecificConfig:
+      lspServerPath: gopls
+      lspMaxConcurrentRequests: ten
+  - providerSpecificConfig:
+      lspServerPath: gopls
+- name: dotnet
+  address: localhost:14652
+  initConfig:
+  - providerSpecificConfig:
+      lspServerPath: csharp-ls
+      solutionFile: [app.sln]
+`,
+			errMsg: `provider go: initConfig[0].providerSpecificConfig: lspMaxConcurrentRequests must be a integer, not string ten
+provider dotnet: initConfig[0].providerSpecificConfig: solutionFile must be a string, not array [app.sln]`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			var settings interface{}
+			if err := yaml.Unmarshal([]byte(tc.settings), &settings); err != nil {
+				t.Fatal(err)
+			}
+			err := ValidateSettings(settings)
+			if tc.errMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.errMsg {
+				t.Fatalf("expected error %q, got %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestConfigSchemaDocs(t *testing.T) {
+	content, err := os.ReadFile("../docs/provider_config.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != ConfigSchemaDocs() {
+		t.Errorf("docs/provider_config.md is not up to date, run make docs")
+	}
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/konveyor/analyzer-lsp/atomicfile"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+// DependencyConditionCache stores the results of dependency conditions on disk
+// keyed by the fingerprint of the dependencies they were evaluated against.
+// Applications of a portfolio that resolve to the same dependencies, at the
+// same locations, can share the cache to skip re-evaluating dependency rules.
+type DependencyConditionCache struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+func NewDependencyConditionCache(dir string) (*DependencyConditionCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create dependency cache directory %s: %w", dir, err)
+	}
+	return &DependencyConditionCache{dir: dir}, nil
+}
+
+// DependencyFingerprint returns a digest of the dependencies, it does not
+// depend on the order the provider returned them in.
+func DependencyFingerprint(deps map[uri.URI][]*Dep) string {
+	entries := []string{}
+	for u, ds := range deps {
+		for _, d := range ds {
+			entries = append(entries, fmt.Sprintf("%s|%s|%s|%s|%s|%v|%s",
+				u, d.Name, d.Version, d.Type, d.ResolvedIdentifier, d.Indirect, d.FileURIPrefix))
+		}
+	}
+	sort.Strings(entries)
+	h := sha256.New()
+	for _, e := range entries {
+		h.Write([]byte(e))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *DependencyConditionCache) key(fingerprint string, cond DependencyConditionCap) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s", fingerprint, cond.Name, cond.NameRegex, cond.Lowerbound, cond.Upperbound)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *DependencyConditionCache) Get(fingerprint string, cond DependencyConditionCap) (engine.ConditionResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	resp := engine.ConditionResponse{}
+	content, err := os.ReadFile(filepath.Join(c.dir, c.key(fingerprint, cond)+".yaml"))
+	if err != nil {
+		return resp, false
+	}
+	if err := yaml.Unmarshal(content, &resp); err != nil {
+		return resp, false
+	}
+	return resp, true
+}
+
+func (c *DependencyConditionCache) Put(fingerprint string, cond DependencyConditionCap, resp engine.ConditionResponse) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	content, err := yaml.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(c.dir, c.key(fingerprint, cond)+".yaml")
+	return atomicfile.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"slices"
+	"strings"
+)
+
+// SetDepDAGPaths sets the depth of the dependencies of a tree and their
+// shortest path from a direct dependency. A dependency added by several others
+// gets the shortest of their paths wherever it appears in the tree. The items
+// must not have cycles, see BreakDepDAGCycles.
+func SetDepDAGPaths(items []DepDAGItem) {
+	shortest := map[string][]string{}
+	type queued struct {
+		item *DepDAGItem
+		path []string
+	}
+	queue := []queued{}
+	for i := range items {
+		queue = append(queue, queued{item: &items[i], path: []string{}})
+	}
+	// a breadth first walk reaches every dependency by its shortest path first
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+		key := depDAGKey(q.item.Dep)
+		if _, ok := shortest[key]; ok {
+			continue
+		}
+		shortest[key] = q.path
+		path := append(slices.Clone(q.path), key)
+		for i := range q.item.AddedDeps {
+			queue = append(queue, queued{item: &q.item.AddedDeps[i], path: path})
+		}
+	}
+	var set func(items []DepDAGItem)
+	set = func(items []DepDAGItem) {
+		for i := range items {
+			path := shortest[depDAGKey(items[i].Dep)]
+			items[i].Dep.Depth = len(path) + 1
+			items[i].Dep.Path = path
+			set(items[i].AddedDeps)
+		}
+	}
+	set(items)
+}
+
+// DepPath is a chain of dependencies, as name@version, from a direct
+// dependency to a dependency it adds
+type DepPath struct {
+	Dep   Dep
+	Chain []string
+}
+
+func (p DepPath) String() string {
+	return strings.Join(p.Chain, " -> ")
+}
+
+// FindDepPaths returns why the dependencies matching the query are in a tree:
+// the shortest chain to them from every direct dependency adding them. The
+// query is the name, or part of the name, of the dependencies optionally
+// followed by @ and the prefix of their version, such as log4j@1. The items
+// must not have cycles, see BreakDepDAGCycles.
+func FindDepPaths(items []DepDAGItem, query string) []DepPath {
+	name, version, _ := strings.Cut(query, "@")
+	matches := func(d Dep) bool {
+		return strings.Contains(d.Name, name) && strings.HasPrefix(d.Version, version)
+	}
+	paths := []DepPath{}
+	for i := range items {
+		type queued struct {
+			item  *DepDAGItem
+			chain []string
+		}
+		queue := []queued{{item: &items[i]}}
+		seen := map[string]bool{}
+		for len(queue) > 0 {
+			q := queue[0]
+			queue = queue[1:]
+			key := depDAGKey(q.item.Dep)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			chain := append(slices.Clone(q.chain), key)
+			if matches(q.item.Dep) {
+				paths = append(paths, DepPath{Dep: q.item.Dep, Chain: chain})
+			}
+			for j := range q.item.AddedDeps {
+				queue = append(queue, queued{item: &q.item.AddedDeps[j], chain: chain})
+			}
+		}
+	}
+	return paths
+}
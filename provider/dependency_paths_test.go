@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDepDAGPaths(t *testing.T) {
+	log4j := DepDAGItem{Dep: Dep{Name: "log4j.log4j", Version: "1.2.17"}}
+	// app-core -> commons-logging -> log4j, app-web -> app-core -> ... and app-web -> log4j
+	core := DepDAGItem{
+		Dep: Dep{Name: "com.example.app-core", Version: "1.0"},
+		AddedDeps: []DepDAGItem{{
+			Dep:       Dep{Name: "commons-logging.commons-logging", Version: "1.1"},
+			AddedDeps: []DepDAGItem{log4j},
+		}},
+	}
+	web := DepDAGItem{
+		Dep:       Dep{Name: "com.example.app-web", Version: "1.0"},
+		AddedDeps: []DepDAGItem{core, log4j},
+	}
+	items, _ := BreakDepDAGCycles([]DepDAGItem{core, web})
+	SetDepDAGPaths(items)
+
+	logging := items[0].AddedDeps[0].Dep
+	if logging.Depth != 2 || !reflect.DeepEqual(logging.Path, []string{"com.example.app-core@1.0"}) {
+		t.Errorf("unexpected depth %d and path %v", logging.Depth, logging.Path)
+	}
+	// log4j is added by app-web directly, its shortest path wherever it is
+	deep := items[0].AddedDeps[0].AddedDeps[0].Dep
+	if deep.Depth != 2 || !reflect.DeepEqual(deep.Path, []string{"com.example.app-web@1.0"}) {
+		t.Errorf("unexpected depth %d and path %v", deep.Depth, deep.Path)
+	}
+	if direct := items[1].Dep; direct.Depth != 1 || len(direct.Path) != 0 {
+		t.Errorf("unexpected depth %d and path %v of a direct dependency", direct.Depth, direct.Path)
+	}
+	// app-core is added by app-web too, it keeps the depth of the direct dependency
+	if added := items[1].AddedDeps[0].Dep; added.Depth != 1 {
+		t.Errorf("expected the shortest depth 1, got %d", added.Depth)
+	}
+
+	paths := []string{}
+	for _, p := range FindDepPaths(items, "log4j@1.") {
+		paths = append(paths, p.String())
+	}
+	expected := []string{
+		"com.example.app-core@1.0 -> commons-logging.commons-logging@1.1 -> log4j.log4j@1.2.17",
+		"com.example.app-web@1.0 -> log4j.log4j@1.2.17",
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected paths %v, got %v", expected, paths)
+	}
+	if paths := FindDepPaths(items, "log4j@2."); len(paths) != 0 {
+		t.Errorf("expected no paths for another version, got %v", paths)
+	}
+}
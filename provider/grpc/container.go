@@ -0,0 +1,122 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+// ContainerToolEnvVar sets the container tool running the images of the
+// providers, podman or else docker found in the PATH by default
+const ContainerToolEnvVar = "CONTAINER_TOOL"
+
+const (
+	// imageLaunchTimeout is how long a provider run from its image has to
+	// serve, the image is pulled when it is missing
+	imageLaunchTimeout = 5 * time.Minute
+	// containerRemoveTimeout is how long the container of a stopped
+	// provider has to be removed
+	containerRemoveTimeout = 30 * time.Second
+)
+
+var invalidContainerNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// containerTool returns the container tool running the images
+func containerTool() (string, error) {
+	if tool := os.Getenv(ContainerToolEnvVar); tool != "" {
+		return tool, nil
+	}
+	for _, tool := range []string{"podman", "docker"} {
+		if path, err := exec.LookPath(tool); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("podman or docker is required to run the image of a provider, or set %s to the container tool", ContainerToolEnvVar)
+}
+
+// containerName returns the name of the container of the provider, the port
+// is unique among the running providers
+func containerName(config provider.Config, port int) string {
+	return fmt.Sprintf("konveyor-provider-%s-%d", invalidContainerNameChars.ReplaceAllString(config.Name, "-"), port)
+}
+
+// containerVolumes returns the volumes of the locations and dependency paths
+// of the provider, mounted at the same path in the container so that the
+// paths of the incidents are the ones of the host
+func containerVolumes(config provider.Config) []string {
+	seen := map[string]bool{}
+	volumes := []string{}
+	for _, ic := range config.InitConfig {
+		for _, path := range []string{ic.Location, ic.DependencyPath} {
+			if path == "" {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				if ic.Location == "" || path == ic.Location {
+					continue
+				}
+				// dependency paths are relative to the location
+				path = filepath.Join(ic.Location, path)
+			}
+			path = filepath.Clean(path)
+			if seen[path] {
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			seen[path] = true
+			volumes = append(volumes, fmt.Sprintf("%s:%s:Z", path, path))
+		}
+	}
+	return volumes
+}
+
+// containerArgs returns the args of the container tool running the image of
+// the provider in a container removed when it exits. Its port is only
+// published on the loopback interface of the host, the provider listens on
+// every interface of the container without TLS.
+func containerArgs(config provider.Config, container string, port int, name string) []string {
+	args := []string{"run", "--rm", "--name", container, "--pull", "missing",
+		"-p", fmt.Sprintf("127.0.0.1:%d:%d", port, port)}
+	for _, volume := range containerVolumes(config) {
+		args = append(args, "-v", volume)
+	}
+	env := map[string]string{}
+	if config.Proxy != nil {
+		for k, v := range config.Proxy.ToEnvVars() {
+			env[k] = v
+		}
+	}
+	for k, v := range config.ProviderEnv {
+		env[k] = v
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, env[k]))
+	}
+	args = append(args, config.Image, "--port", fmt.Sprintf("%v", port), "--name", name, "--insecure")
+	return append(args, config.ProviderArgs...)
+}
+
+// removeContainer removes the container of a stopped provider, killing the
+// container tool does not stop the container it runs
+func (l *launchedProvider) removeContainer() {
+	ctx, cancel := context.WithTimeout(context.Background(), containerRemoveTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, l.tool, "rm", "--force", l.container).CombinedOutput()
+	if err != nil {
+		l.log.Error(err, "unable to remove the container of the provider", "container", l.container, "output", string(out))
+	}
+}
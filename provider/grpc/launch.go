@@ -0,0 +1,241 @@
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/process"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/phayes/freeport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// launchTimeout is how long a started provider has to serve before it
+	// is considered failed
+	launchTimeout = 30 * time.Second
+	// healthCheckInterval is the delay between the health checks of a
+	// started provider that does not serve yet
+	healthCheckInterval = 500 * time.Millisecond
+	// stopTimeout is how long the output of a stopped provider is read
+	// before giving up on it
+	stopTimeout = 5 * time.Second
+	// outputTailLines are the last lines of the output of a provider
+	// reported when it fails
+	outputTailLines = 20
+)
+
+// launchedProvider is a provider binary or image the analyzer started on a
+// free port, its output is logged with the name of the provider
+type launchedProvider struct {
+	group   *process.Group
+	address string
+	timeout time.Duration
+	log     logr.Logger
+
+	// tool and container are set for the providers run from their image
+	tool      string
+	container string
+
+	outputs sync.WaitGroup
+	exited  chan struct{}
+	exitErr error
+
+	mutex sync.Mutex
+	tail  []string
+}
+
+// launch starts the binary, or else runs the image, of the provider with the
+// port it serves on. The binary, or the container tool, is stopped with its
+// process group when the context is done.
+func launch(ctx context.Context, log logr.Logger, config provider.Config) (*launchedProvider, error) {
+	port, err := freeport.GetFreePort()
+	if err != nil {
+		return nil, err
+	}
+
+	ic := config.InitConfig
+	// For the generic external provider
+	name := "generic"
+	if len(ic) != 0 {
+		if newName, ok := ic[0].ProviderSpecificConfig["lspServerName"].(string); ok {
+			name = newName
+		}
+	}
+
+	l := &launchedProvider{
+		address: fmt.Sprintf("127.0.0.1:%v", port),
+		timeout: launchTimeout,
+		log:     log,
+		exited:  make(chan struct{}),
+	}
+	if config.BinaryPath != "" {
+		args := append([]string{"--port", fmt.Sprintf("%v", port), "--name", name}, config.ProviderArgs...)
+		l.group = process.Command(ctx, config.BinaryPath, args...)
+		if len(config.ProviderEnv) != 0 {
+			l.group.Cmd.Env = os.Environ()
+			keys := make([]string, 0, len(config.ProviderEnv))
+			for k := range config.ProviderEnv {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				l.group.Cmd.Env = append(l.group.Cmd.Env, fmt.Sprintf("%s=%s", k, config.ProviderEnv[k]))
+			}
+		}
+	} else {
+		l.tool, err = containerTool()
+		if err != nil {
+			return nil, err
+		}
+		l.container = containerName(config, port)
+		l.timeout = imageLaunchTimeout
+		l.group = process.Command(ctx, l.tool, containerArgs(config, l.container, port, name)...)
+	}
+	stdout, err := l.group.Cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := l.group.Cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := l.group.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start provider %s: %w", l.group.Cmd.Path, err)
+	}
+	log.V(3).Info("started provider", "command", l.group.Cmd.Path, "image", config.Image, "pid", l.group.Cmd.Process.Pid, "port", port)
+
+	l.outputs.Add(2)
+	go l.logOutput(stdout)
+	go l.logOutput(stderr)
+	go func() {
+		// the pipes are read to their end before waiting for the process
+		l.outputs.Wait()
+		l.exitErr = l.group.Cmd.Wait()
+		close(l.exited)
+	}()
+	return l, nil
+}
+
+// logOutput logs the lines of an output of the provider and keeps the last
+// ones to report them when it fails
+func (l *launchedProvider) logOutput(out io.Reader) {
+	defer l.outputs.Done()
+	scan := bufio.NewScanner(out)
+	scan.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scan.Scan() {
+		l.log.V(3).Info(scan.Text())
+		l.mutex.Lock()
+		l.tail = append(l.tail, scan.Text())
+		if len(l.tail) > outputTailLines {
+			l.tail = l.tail[len(l.tail)-outputTailLines:]
+		}
+		l.mutex.Unlock()
+	}
+}
+
+// output returns the last lines of the output of the provider
+func (l *launchedProvider) output() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.tail) == 0 {
+		return ""
+	}
+	return "\nlast output of the provider:\n" + strings.Join(l.tail, "\n")
+}
+
+// waitServing checks the health of the provider until it serves, it fails
+// when the provider exits or does not serve before its timeout. Providers without the
+// health service serve when they answer that they do not implement it.
+func (l *launchedProvider) waitServing(ctx context.Context, conn *grpc.ClientConn) error {
+	timeout := l.timeout
+	client := healthpb.NewHealthClient(conn)
+	deadline := time.After(timeout)
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckInterval)
+		resp, err := client.Check(checkCtx, &healthpb.HealthCheckRequest{})
+		cancel()
+		if err == nil && resp.GetStatus() == healthpb.HealthCheckResponse_SERVING {
+			return nil
+		}
+		if status.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		select {
+		case <-l.exited:
+			return fmt.Errorf("provider exited before serving: %v%s", l.exitErr, l.output())
+		case <-deadline:
+			return fmt.Errorf("provider not serving on %s after %s%s", l.address, timeout, l.output())
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(healthCheckInterval):
+		}
+	}
+}
+
+// watch logs the exit of the provider unless the context stopping it is
+// done first
+func (l *launchedProvider) watch(ctx context.Context) {
+	<-l.exited
+	if ctx.Err() == nil {
+		l.log.Error(l.exitErr, "provider exited while the analyzer uses it", "output", l.output())
+	}
+}
+
+// stop stops the provider and waits for it to exit and its output to be
+// logged, the container of an image is removed
+func (l *launchedProvider) stop(timeout time.Duration) {
+	l.group.Stop()
+	select {
+	case <-l.exited:
+	case <-time.After(timeout):
+		l.log.V(3).Info("provider did not exit in time", "pid", l.group.Cmd.Process.Pid)
+	}
+	if l.container != "" {
+		l.removeContainer()
+	}
+}
+
+// Launch starts the provider binary of the config and waits for it to
+// serve, for several analyses to connect to its address. Every analysis
+// initializes the provider for its locations, the binary is started once.
+// The returned function stops the provider.
+func Launch(ctx context.Context, log logr.Logger, config provider.Config) (string, func(), error) {
+	if config.BinaryPath == "" {
+		return "", nil, fmt.Errorf("provider %s has no binary path to launch", config.Name)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	launched, err := launch(ctx, log, config)
+	if err != nil {
+		cancel()
+		return "", nil, err
+	}
+	stop := func() {
+		cancel()
+		launched.stop(stopTimeout)
+	}
+	conn, err := grpc.Dial(launched.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		stop()
+		return "", nil, err
+	}
+	defer conn.Close()
+	if err := launched.waitServing(ctx, conn); err != nil {
+		stop()
+		return "", nil, fmt.Errorf("unable to start provider %s: %w", config.Name, err)
+	}
+	go launched.watch(ctx)
+	return launched.address, stop, nil
+}
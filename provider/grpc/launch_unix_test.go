@@ -0,0 +1,150 @@
+//go:build !windows
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// writeProvider writes a shell script standing for a provider binary
+func writeProvider(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "provider")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func dial(t *testing.T, address string) *grpc.ClientConn {
+	t.Helper()
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func Test_launchExited(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	launched, err := launch(ctx, logr.Discard(), provider.Config{
+		Name:         "java",
+		BinaryPath:   writeProvider(t, "echo \"args $*\"\necho \"env $LEVEL\" >&2\nexit 3\n"),
+		ProviderArgs: []string{"--log-level", "7"},
+		ProviderEnv:  map[string]string{"LEVEL": "debug"},
+	})
+	if err != nil {
+		t.Fatalf("launch() error = %v", err)
+	}
+	err = launched.waitServing(ctx, dial(t, launched.address))
+	if err == nil {
+		t.Fatalf("waitServing() of a provider that exited must fail")
+	}
+	port := strings.TrimPrefix(launched.address, "127.0.0.1:")
+	for _, want := range []string{"exited before serving: exit status 3", "args --port " + port + " --name generic --log-level 7", "env debug"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("waitServing() error = %v, want %q in it", err, want)
+		}
+	}
+}
+
+func Test_launchNotServing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	launched, err := launch(ctx, logr.Discard(), provider.Config{
+		Name:       "java",
+		BinaryPath: writeProvider(t, "echo starting\nexec sleep 60\n"),
+	})
+	if err != nil {
+		t.Fatalf("launch() error = %v", err)
+	}
+	launched.timeout = time.Second
+	err = launched.waitServing(ctx, dial(t, launched.address))
+	if err == nil || !strings.Contains(err.Error(), "not serving") || !strings.Contains(err.Error(), "starting") {
+		t.Errorf("waitServing() error = %v, want a provider not serving with its output", err)
+	}
+	cancel()
+	select {
+	case <-launched.exited:
+	case <-time.After(10 * time.Second):
+		t.Errorf("provider must exit when its context is done")
+	}
+}
+
+func Test_waitServing(t *testing.T) {
+	tests := []struct {
+		name   string
+		health bool
+	}{
+		{name: "health service", health: true},
+		{name: "provider without the health service", health: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lis, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gs := grpc.NewServer()
+			if tt.health {
+				healthpb.RegisterHealthServer(gs, health.NewServer())
+			}
+			go gs.Serve(lis)
+			defer gs.Stop()
+
+			launched := &launchedProvider{address: lis.Addr().String(), timeout: 10 * time.Second, log: logr.Discard(), exited: make(chan struct{})}
+			if err := launched.waitServing(context.Background(), dial(t, launched.address)); err != nil {
+				t.Errorf("waitServing() error = %v", err)
+			}
+		})
+	}
+}
+
+func Test_launchImage(t *testing.T) {
+	removed := filepath.Join(t.TempDir(), "removed")
+	// the container tool prints the args it runs the image with and records
+	// the containers it removes
+	t.Setenv(ContainerToolEnvVar, writeProvider(t, "if [ \"$1\" = rm ]; then echo \"$3\" > "+removed+"; exit 0; fi\necho \"$*\"\nexit 125\n"))
+	app := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	launched, err := launch(ctx, logr.Discard(), provider.Config{
+		Name:         "java",
+		Image:        "quay.io/konveyor/java-external-provider:latest",
+		ProviderArgs: []string{"--log-level", "7"},
+		ProviderEnv:  map[string]string{"JAVA_HOME": "/usr/lib/jvm/java-17"},
+		Proxy:        &provider.Proxy{HTTPSProxy: "http://proxy:3128"},
+		InitConfig:   []provider.InitConfig{{Location: app, DependencyPath: "missing"}},
+	})
+	if err != nil {
+		t.Fatalf("launch() error = %v", err)
+	}
+	err = launched.waitServing(ctx, dial(t, launched.address))
+	port := strings.TrimPrefix(launched.address, "127.0.0.1:")
+	want := "run --rm --name konveyor-provider-java-" + port + " --pull missing -p 127.0.0.1:" + port + ":" + port +
+		" -v " + app + ":" + app + ":Z -e JAVA_HOME=/usr/lib/jvm/java-17 -e https_proxy=http://proxy:3128" +
+		" quay.io/konveyor/java-external-provider:latest --port " + port + " --name generic --insecure --log-level 7"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("waitServing() error = %v, want the args of the container tool %q", err, want)
+	}
+
+	launched.stop(10 * time.Second)
+	content, err := os.ReadFile(removed)
+	if err != nil || strings.TrimSpace(string(content)) != launched.container {
+		t.Errorf("stop() must remove the container %s, removed %q", launched.container, content)
+	}
+}
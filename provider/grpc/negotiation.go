@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/provider"
+	pb "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// negotiated is what the provider advertised of the protocol it implements
+type negotiated struct {
+	protocolVersion int32
+	features        map[string]bool
+	// version is the build of the provider
+	version string
+}
+
+// supports reports whether the requests of the feature can be sent. Providers
+// built before the protocol was versioned advertise nothing, their requests
+// are sent and the errors of unimplemented ones handled instead.
+func (n negotiated) supports(feature string) bool {
+	return n.protocolVersion == 0 || n.features[feature]
+}
+
+func negotiate(ctx context.Context, client pb.ProviderServiceClient, log logr.Logger) negotiated {
+	n := negotiated{features: map[string]bool{}}
+	r, err := client.Capabilities(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.V(5).Error(err, "unable to negotiate the protocol with the provider")
+		return n
+	}
+	n.protocolVersion = r.GetProtocolVersion()
+	n.version = r.GetVersion()
+	for _, feature := range r.GetFeatures() {
+		n.features[feature] = true
+	}
+	switch {
+	case n.protocolVersion == 0:
+		log.Info("provider was built with an older analyzer, requests it does not implement are skipped")
+	case n.protocolVersion > provider.ProtocolVersion:
+		log.Info("provider was built with a newer analyzer, only the features of this analyzer are used",
+			"providerProtocolVersion", n.protocolVersion, "protocolVersion", provider.ProtocolVersion)
+	default:
+		log.V(3).Info("negotiated provider protocol", "protocolVersion", n.protocolVersion, "features", r.GetFeatures())
+	}
+	return n
+}
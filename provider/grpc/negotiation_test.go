@@ -0,0 +1,63 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/provider"
+	pb "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type fakeCapabilitiesClient struct {
+	pb.ProviderServiceClient
+	response *pb.CapabilitiesResponse
+}
+
+func (f fakeCapabilitiesClient) Capabilities(context.Context, *emptypb.Empty, ...grpc.CallOption) (*pb.CapabilitiesResponse, error) {
+	return f.response, nil
+}
+
+func Test_negotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *pb.CapabilitiesResponse
+		feature  string
+		want     bool
+	}{
+		{
+			name:     "older provider is assumed to support everything",
+			response: &pb.CapabilitiesResponse{},
+			feature:  provider.DependencyDAGFeature,
+			want:     true,
+		},
+		{
+			name: "advertised feature",
+			response: &pb.CapabilitiesResponse{
+				ProtocolVersion: provider.ProtocolVersion,
+				Features:        []string{provider.DependencyDAGFeature},
+			},
+			feature: provider.DependencyDAGFeature,
+			want:    true,
+		},
+		{
+			name: "feature not advertised",
+			response: &pb.CapabilitiesResponse{
+				ProtocolVersion: provider.ProtocolVersion,
+				Features:        []string{provider.DependencyDAGFeature},
+			},
+			feature: provider.CodeSnipFeature,
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := negotiate(context.Background(), fakeCapabilitiesClient{response: tt.response}, logr.Discard())
+			if got := n.supports(tt.feature); got != tt.want {
+				t.Errorf("supports(%s) = %v, want %v", tt.feature, got, tt.want)
+			}
+		})
+	}
+}
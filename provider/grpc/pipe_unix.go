@@ -0,0 +1,13 @@
+//go:build !windows
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+func dialPipe(ctx context.Context, path string) (net.Conn, error) {
+	return nil, fmt.Errorf("named pipes are only supported on Windows, not %s", path)
+}
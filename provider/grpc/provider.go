@@ -1,25 +1,19 @@
 package grpc
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"io"
-	"log"
-	"os/exec"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
 	reflectClient "github.com/jhump/protoreflect/grpcreflect"
+	"github.com/konveyor/analyzer-lsp/progress"
 	"github.com/konveyor/analyzer-lsp/provider"
 	pb "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
-	"github.com/phayes/freeport"
 	"go.lsp.dev/uri"
-	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/credentials/oauth"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -31,18 +25,22 @@ type grpcProvider struct {
 	conn      *grpc.ClientConn
 	config    provider.Config
 	cancelCmd context.CancelFunc
+	launched  *launchedProvider
 
+	negotiated     negotiated
 	serviceClients []provider.ServiceClient
 }
 
 var _ provider.InternalProviderClient = &grpcProvider{}
+var _ provider.VersionedClient = &grpcProvider{}
 
 func NewGRPCClient(config provider.Config, log logr.Logger) (provider.InternalProviderClient, error) {
 	log = log.WithName(config.Name)
 	log = log.WithValues("provider", "grpc")
 	ctxCmd, cancelCmd := context.WithCancel(context.Background())
-	conn, out, err := start(ctxCmd, config)
+	conn, launched, err := start(ctxCmd, log, config)
 	if err != nil {
+		cancelCmd()
 		return nil, err
 	}
 	refCltCtx, cancel := context.WithCancel(context.Background())
@@ -52,6 +50,7 @@ func NewGRPCClient(config provider.Config, log logr.Logger) (provider.InternalPr
 	services, err := checkServicesRunning(refClt, log)
 	if err != nil {
 		log.Error(err, "failed to check if services are running")
+		cancelCmd()
 		return nil, err
 	}
 	foundCodeSnip := false
@@ -74,10 +73,12 @@ func NewGRPCClient(config provider.Config, log logr.Logger) (provider.InternalPr
 		conn:           conn,
 		config:         config,
 		cancelCmd:      cancelCmd,
+		launched:       launched,
+		negotiated:     negotiate(refCltCtx, provierClient, log),
 		serviceClients: []provider.ServiceClient{},
 	}
-	if out != nil {
-		go gp.LogProviderOut(context.Background(), out)
+	if launched != nil {
+		go launched.watch(ctxCmd)
 	}
 	if foundCodeSnip && foundDepResolve {
 		// create the clients, create the struct that will have all the methods
@@ -155,12 +156,36 @@ func (g *grpcProvider) ProviderInit(ctx context.Context, additionalConfigs []pro
 	if additionalConfigs != nil {
 		g.config.InitConfig = append(g.config.InitConfig, additionalConfigs...)
 	}
-	for _, c := range g.config.InitConfig {
-		s, builtinConf, err := g.Init(ctx, g.log, c)
+	task := progress.FromContext(ctx)
+	tasks := make([]*progress.Task, len(g.config.InitConfig))
+	locations := map[string]*progress.Task{}
+	for i, c := range g.config.InitConfig {
+		tasks[i] = task.Task(progress.StageProviderPrepare, c.Location, 1)
+		locations[c.Location] = tasks[i]
+	}
+	// initializing is the task of the location being initialized
+	initializing := &atomic.Pointer[progress.Task]{}
+	if task != nil && g.negotiated.supports(provider.ProgressFeature) {
+		progressCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			g.streamProgress(progressCtx, task, locations, initializing)
+		}()
+		// no event is merged into the tasks once they are initialized
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
+	for i, c := range g.config.InitConfig {
+		initializing.Store(tasks[i])
+		s, builtinConf, err := g.Init(progress.WithTask(ctx, tasks[i]), g.log, c)
 		if err != nil {
 			g.log.Error(err, "Error inside ProviderInit, after g.Init.")
 			return nil, err
 		}
+		tasks[i].Done()
 		g.serviceClients = append(g.serviceClients, s)
 		if builtinConf.Location != "" {
 			builtinConfs = append(builtinConfs, builtinConf)
@@ -169,6 +194,55 @@ func (g *grpcProvider) ProviderInit(ctx context.Context, additionalConfigs []pro
 	return builtinConfs, nil
 }
 
+// streamProgress merges the progress events of the provider into the task
+// until the context is canceled. The tasks of the locations of the provider
+// are the sub-tasks of the task named after them, the events of the tasks of
+// a location are merged into its task, found by its name, or the task of the
+// location being initialized when the events of its parents were sent before
+// the stream started.
+func (g *grpcProvider) streamProgress(ctx context.Context, task *progress.Task, locations map[string]*progress.Task, initializing *atomic.Pointer[progress.Task]) {
+	stream, err := g.Client.Progress(ctx, &emptypb.Empty{})
+	if err != nil {
+		g.log.V(5).Error(err, "unable to stream the progress of the provider")
+		return
+	}
+	tasks := map[string]*progress.Task{}
+	for {
+		e, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() == nil {
+				g.log.V(5).Error(err, "progress stream of the provider ended")
+			}
+			return
+		}
+		if e.ParentId == "" {
+			// the root task of the provider is the task
+			continue
+		}
+		t, ok := tasks[e.Id]
+		if !ok {
+			parent, ok := tasks[e.ParentId]
+			if location, isLocation := locations[e.Name]; !ok && isLocation {
+				t = location
+			} else {
+				if !ok {
+					parent = initializing.Load()
+				}
+				if parent == nil {
+					parent = task
+				}
+				t = parent.Task(progress.Stage(e.Stage), e.Name, e.Weight)
+			}
+			tasks[e.Id] = t
+		}
+		if e.Done {
+			t.Done()
+		} else {
+			t.Update(int(e.Current), int(e.Total), e.Message)
+		}
+	}
+}
+
 func (g *grpcProvider) Capabilities() []provider.Capability {
 	r, err := g.Client.Capabilities(context.TODO(), &emptypb.Empty{})
 	if err != nil {
@@ -219,9 +293,11 @@ func (g *grpcProvider) Init(ctx context.Context, log logr.Logger, config provide
 		additionalBuiltinConfig.Location = r.BuiltinConfig.Location
 	}
 	return &grpcServiceClient{
-		id:     r.Id,
-		config: config,
-		client: g.Client,
+		id:         r.Id,
+		config:     config,
+		client:     g.Client,
+		log:        g.log,
+		negotiated: g.negotiated,
 	}, additionalBuiltinConfig, nil
 }
 
@@ -229,6 +305,16 @@ func (g *grpcProvider) Evaluate(ctx context.Context, cap string, conditionInfo [
 	return provider.FullResponseFromServiceClients(ctx, g.serviceClients, cap, conditionInfo)
 }
 
+// ProtocolVersion returns the version of the protocol the provider
+// advertised, 0 for providers built before it was versioned
+func (g *grpcProvider) ProtocolVersion() int32 {
+	return g.negotiated.protocolVersion
+}
+
+func (g *grpcProvider) ProviderVersion() string {
+	return g.negotiated.version
+}
+
 func (g *grpcProvider) GetDependencies(ctx context.Context) (map[uri.URI][]*provider.Dep, error) {
 	return provider.FullDepsResponse(ctx, g.serviceClients)
 }
@@ -243,104 +329,43 @@ func (g *grpcProvider) Stop() {
 	}
 	g.conn.Close()
 	g.cancelCmd()
+	if g.launched != nil {
+		g.launched.stop(stopTimeout)
+	}
 }
 
-func start(ctx context.Context, config provider.Config) (*grpc.ClientConn, io.ReadCloser, error) {
-	// Here the Provider will start the GRPC Server if a binary is set.
-	if config.BinaryPath != "" {
-		port, err := freeport.GetFreePort()
+func start(ctx context.Context, logger logr.Logger, config provider.Config) (*grpc.ClientConn, *launchedProvider, error) {
+	// Here the Provider will start the GRPC Server if a binary or an image is set.
+	if config.BinaryPath != "" || config.Image != "" {
+		launched, err := launch(ctx, logger, config)
 		if err != nil {
 			return nil, nil, err
 		}
-
-		ic := config.InitConfig
-		// For the generic external provider
-		name := "generic"
-		if len(ic) != 0 {
-			if newName, ok := ic[0].ProviderSpecificConfig["lspServerName"].(string); ok {
-				name = newName
-			}
-		}
-
-		cmd := exec.CommandContext(ctx, config.BinaryPath, "--port", fmt.Sprintf("%v", port), "--name", name)
-		// TODO: For each output line, log that line here, allows the server's to output to the main log file. Make sure we name this correctly
-		// cmd will exit with the ending of the ctx.
-		out, err := cmd.StdoutPipe()
+		conn, err := grpc.Dial(launched.address,
+			grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(provider.MAX_MESSAGE_SIZE)),
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
 		if err != nil {
+			launched.stop(stopTimeout)
 			return nil, nil, err
 		}
-
-		err = cmd.Start()
+		if err := launched.waitServing(ctx, conn); err != nil {
+			conn.Close()
+			launched.stop(stopTimeout)
+			return nil, nil, fmt.Errorf("unable to start provider %s: %w", config.Name, err)
+		}
+		return conn, launched, nil
+	}
+	if config.Address != "" {
+		opts, err := dialOptions(config)
 		if err != nil {
 			return nil, nil, err
 		}
-		conn, err := grpc.Dial(fmt.Sprintf("localhost:%v", port),
-			grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(provider.MAX_MESSAGE_SIZE)),
-			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		target, targetOpts := dialTarget(config.Address)
+		conn, err := grpc.Dial(target, append(opts, targetOpts...)...)
 		if err != nil {
-			log.Fatalf("did not connect: %v", err)
-		}
-		return conn, out, nil
-	}
-	if config.Address != "" {
-		if config.CertPath == "" {
-			conn, err := grpc.Dial(fmt.Sprintf(config.Address),
-				grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(provider.MAX_MESSAGE_SIZE)),
-				grpc.WithTransportCredentials(insecure.NewCredentials()))
-			if err != nil {
-				log.Fatalf("did not connect: %v", err)
-			}
-			return conn, nil, nil
-		} else {
-			creds, err := credentials.NewClientTLSFromFile(config.CertPath, "")
-			if err != nil {
-				return nil, nil, err
-			}
-			if config.JWTToken == "" {
-				conn, err := grpc.Dial(fmt.Sprintf(config.Address),
-					grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(provider.MAX_MESSAGE_SIZE)),
-					grpc.WithTransportCredentials(creds))
-				if err != nil {
-					log.Fatalf("did not connect: %v", err)
-				}
-				return conn, nil, nil
-
-			} else {
-				i := &jwtTokeInterceptor{
-					Token: config.JWTToken,
-				}
-				conn, err := grpc.Dial(fmt.Sprintf(config.Address),
-					grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(provider.MAX_MESSAGE_SIZE)),
-					grpc.WithTransportCredentials(creds), grpc.WithUnaryInterceptor(i.unaryInterceptor))
-				if err != nil {
-					log.Fatalf("did not connect: %v", err)
-				}
-				return conn, nil, nil
-
-			}
+			return nil, nil, err
 		}
+		return conn, nil, nil
 	}
-	return nil, nil, fmt.Errorf("must set Address or Binary Path for a GRPC provider")
-}
-
-func (g *grpcProvider) LogProviderOut(ctx context.Context, out io.ReadCloser) {
-	scan := bufio.NewScanner(out)
-
-	for scan.Scan() {
-		g.log.V(3).Info(scan.Text())
-	}
-}
-
-type jwtTokeInterceptor struct {
-	Token string
-}
-
-func (t *jwtTokeInterceptor) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-	if t.Token != "" {
-		opts = append(opts, grpc.PerRPCCredentials(oauth.TokenSource{
-			TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: t.Token}),
-		}))
-	}
-	err := invoker(ctx, method, req, reply, cc, opts...)
-	return err
+	return nil, nil, fmt.Errorf("must set Address, Binary Path or Image for a GRPC provider")
 }
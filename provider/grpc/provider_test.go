@@ -0,0 +1,118 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/progress"
+	pb "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type fakeProgressClient struct {
+	pb.ProviderServiceClient
+	events []*pb.ProgressEvent
+}
+
+func (f fakeProgressClient) Progress(context.Context, *emptypb.Empty, ...grpc.CallOption) (pb.ProviderService_ProgressClient, error) {
+	return &fakeProgressStream{events: f.events}, nil
+}
+
+type fakeProgressStream struct {
+	pb.ProviderService_ProgressClient
+	events []*pb.ProgressEvent
+}
+
+func (f *fakeProgressStream) Recv() (*pb.ProgressEvent, error) {
+	if len(f.events) == 0 {
+		return nil, io.EOF
+	}
+	e := f.events[0]
+	f.events = f.events[1:]
+	return e, nil
+}
+
+type recordingReporter struct {
+	events []progress.ProgressEvent
+}
+
+func (r *recordingReporter) Report(event progress.ProgressEvent) {
+	r.events = append(r.events, event)
+}
+
+func Test_streamProgress(t *testing.T) {
+	reporter := &recordingReporter{}
+	task := progress.NewCollector(progress.WithReporter(reporter)).Root().Task(progress.StageProviderInit, "java", 1)
+	location := task.Task(progress.StageProviderPrepare, "/app", 1)
+	g := &grpcProvider{
+		log: logr.Discard(),
+		Client: fakeProgressClient{events: []*pb.ProgressEvent{
+			{Id: "analysis/-app", ParentId: "analysis", Stage: string(progress.StageProviderPrepare), Name: "/app", Weight: 1},
+			{Id: "analysis/-app/indexing", ParentId: "analysis/-app", Stage: string(progress.StageProviderPrepare), Name: "indexing", Weight: 3},
+			{Id: "analysis/-app/indexing", ParentId: "analysis/-app", Stage: string(progress.StageProviderPrepare), Name: "indexing", Current: 1200, Total: 4000, Message: "files", Weight: 3},
+			{Id: "analysis/-app/resolving", ParentId: "analysis/-app", Stage: string(progress.StageProviderPrepare), Name: "resolving", Weight: 1, Done: true},
+		}},
+	}
+	g.streamProgress(context.Background(), task, map[string]*progress.Task{"/app": location}, &atomic.Pointer[progress.Task]{})
+
+	last := reporter.events[len(reporter.events)-1]
+	if last.ID != "analysis/java/-app/resolving" || !last.Done {
+		t.Errorf("expected the tasks of the provider to be merged under the task of their location, got %+v", last)
+	}
+	var indexing progress.ProgressEvent
+	for _, e := range reporter.events {
+		if e.Name == "indexing" {
+			indexing = e
+		}
+	}
+	if indexing.ID != "analysis/java/-app/indexing" || indexing.Current != 1200 || indexing.Total != 4000 || indexing.Message != "files" {
+		t.Errorf("unexpected event of the indexing %+v", indexing)
+	}
+	// indexing is at 30% with a weight of 3, resolving is done
+	if math.Abs(last.Overall-47.5) > 1e-9 {
+		t.Errorf("expected the progress of the location to be weighted over its tasks, got %v", last.Overall)
+	}
+	location.Done()
+	if last := reporter.events[len(reporter.events)-1]; last.Overall != 100 {
+		t.Errorf("expected the analysis to be done with the location, got %v", last.Overall)
+	}
+}
+
+func Test_streamProgressUnknownParent(t *testing.T) {
+	reporter := &recordingReporter{}
+	task := progress.NewCollector(progress.WithReporter(reporter)).Root().Task(progress.StageProviderInit, "java", 1)
+	app := task.Task(progress.StageProviderPrepare, "/app", 1)
+	lib := task.Task(progress.StageProviderPrepare, "/lib", 1)
+	initializing := &atomic.Pointer[progress.Task]{}
+	initializing.Store(lib)
+	g := &grpcProvider{
+		log: logr.Discard(),
+		Client: fakeProgressClient{events: []*pb.ProgressEvent{
+			{Id: "analysis", Stage: string(progress.StageProviderInit), Name: "analysis", Weight: 1},
+			// the events of the location were sent before the stream started
+			{Id: "analysis/-lib/indexing", ParentId: "analysis/-lib", Stage: string(progress.StageProviderPrepare), Name: "indexing", Current: 1, Total: 2, Weight: 1},
+			{Id: "analysis/-app", ParentId: "analysis", Stage: string(progress.StageProviderPrepare), Name: "/app", Current: 1, Total: 4, Weight: 1},
+		}},
+	}
+	g.streamProgress(context.Background(), task, map[string]*progress.Task{"/app": app, "/lib": lib}, initializing)
+
+	ids := map[string]bool{}
+	for _, e := range reporter.events {
+		ids[e.ID] = true
+	}
+	if !ids["analysis/java/-lib/indexing"] {
+		t.Errorf("expected the task with an unknown parent under the location being initialized, got %v", ids)
+	}
+	if ids["analysis/java/analysis"] {
+		t.Errorf("expected the root task of the provider not to be merged, got %v", ids)
+	}
+	last := reporter.events[len(reporter.events)-1]
+	if last.ID != "analysis/java/-app" || last.Current != 1 || last.Total != 4 {
+		t.Errorf("expected the task of the location found by its name, got %+v", last)
+	}
+}
@@ -0,0 +1,99 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials/oauth"
+)
+
+// dialOptions returns the options of the connection to the address of a
+// running provider. The connection uses TLS when the CA certificate of the
+// provider is set, with the certificate of the client for mutual TLS, and
+// authenticates its requests with a JWT when one is set.
+func dialOptions(config provider.Config) ([]grpc.DialOption, error) {
+	opts := []grpc.DialOption{grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(provider.MAX_MESSAGE_SIZE))}
+	if config.CertPath == "" {
+		if config.ClientCertPath != "" || config.JWTToken != "" || config.JWTTokenFile != "" {
+			return nil, fmt.Errorf("certPath is required to use a client certificate or a JWT")
+		}
+		return append(opts, grpc.WithTransportCredentials(insecure.NewCredentials())), nil
+	}
+	content, err := os.ReadFile(config.CertPath)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no certificate in %s", config.CertPath)
+	}
+	tlsConfig := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	if config.ClientCertPath != "" || config.ClientKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(config.ClientCertPath, config.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate %s and key %s: %w", config.ClientCertPath, config.ClientKeyPath, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+
+	var tokens oauth2.TokenSource
+	switch {
+	case config.JWTTokenFile != "":
+		tokens = tokenFile(config.JWTTokenFile)
+	case config.JWTToken != "":
+		tokens = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: config.JWTToken})
+	}
+	if tokens != nil {
+		// the token is sent with the unary and the stream requests
+		opts = append(opts, grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: tokens}))
+	}
+	return opts, nil
+}
+
+// pipeScheme starts the addresses of the providers listening on a named pipe
+// of Windows, npipe:////./pipe/java is the named pipe \\.\pipe\java
+const pipeScheme = "npipe://"
+
+// dialTarget returns the gRPC target of the address of a provider and the
+// options to dial it. gRPC dials unix:///path sockets itself, named pipes
+// are dialed with a dialer of their own.
+func dialTarget(address string) (string, []grpc.DialOption) {
+	if !strings.HasPrefix(address, pipeScheme) {
+		return address, nil
+	}
+	path := strings.ReplaceAll(strings.TrimPrefix(address, pipeScheme), "/", `\`)
+	return "passthrough:///npipe", []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return dialPipe(ctx, path)
+		}),
+		// the name of the host in the certificate of the provider
+		grpc.WithAuthority("localhost"),
+	}
+}
+
+// tokenFile is a JWT read from its file for every request, so that the
+// token can be rotated while the analyzer runs
+type tokenFile string
+
+func (f tokenFile) Token() (*oauth2.Token, error) {
+	content, err := os.ReadFile(string(f))
+	if err != nil {
+		return nil, err
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return nil, fmt.Errorf("no token in %s", string(f))
+	}
+	return &oauth2.Token{AccessToken: token}, nil
+}
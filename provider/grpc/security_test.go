@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func Test_dialOptions(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		config provider.Config
+		errMsg string
+	}{
+		{name: "insecure", config: provider.Config{Address: "localhost:14651"}},
+		{name: "token without TLS", config: provider.Config{Address: "localhost:14651", JWTToken: "token"}, errMsg: "certPath is required to use a client certificate or a JWT"},
+		{name: "no certificate", config: provider.Config{Address: "localhost:14651", CertPath: filepath.Join(dir, "ca.crt")}, errMsg: "open " + filepath.Join(dir, "ca.crt") + ": no such file or directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := dialOptions(tt.config)
+			if tt.errMsg == "" && err != nil {
+				t.Errorf("dialOptions() error = %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || err.Error() != tt.errMsg) {
+				t.Errorf("dialOptions() error = %v, want %s", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func Test_tokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	for _, token := range []string{"first", "second"} {
+		if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := tokenFile(path).Token()
+		if err != nil || got.AccessToken != token {
+			t.Errorf("Token() = %v %v, want the rotated token %s", got, err, token)
+		}
+	}
+}
+
+func Test_dialTarget(t *testing.T) {
+	target, opts := dialTarget("unix:///run/konveyor/java.sock")
+	if target != "unix:///run/konveyor/java.sock" || len(opts) != 0 {
+		t.Errorf("unix sockets must be dialed by gRPC, got %s", target)
+	}
+	target, opts = dialTarget("npipe:////./pipe/konveyor-java")
+	if target != "passthrough:///npipe" || len(opts) != 2 {
+		t.Errorf("named pipes must be dialed with their dialer, got %s", target)
+	}
+}
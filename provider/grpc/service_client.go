@@ -2,17 +2,25 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/provider"
 	pb "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
 	"go.lsp.dev/uri"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type grpcServiceClient struct {
-	id     int64
-	config provider.InitConfig
-	client pb.ProviderServiceClient
+	id         int64
+	config     provider.InitConfig
+	client     pb.ProviderServiceClient
+	log        logr.Logger
+	negotiated negotiated
 }
 
 var _ provider.ServiceClient = &grpcServiceClient{}
@@ -26,17 +34,23 @@ func (g *grpcServiceClient) Evaluate(ctx context.Context, cap string, conditionI
 
 	r, err := g.client.Evaluate(ctx, &m)
 	if err != nil {
-		return provider.ProviderEvaluateResponse{}, err
+		return provider.ProviderEvaluateResponse{}, engine.NewProviderError(statusErrorCode(err), err)
 	}
 
 	if !r.Successful {
-		return provider.ProviderEvaluateResponse{}, fmt.Errorf(r.Error)
+		code := konveyor.ErrorCode(r.ErrorCode)
+		if code == "" {
+			// providers built before the errors were classified
+			code = konveyor.UnknownError
+		}
+		return provider.ProviderEvaluateResponse{}, engine.NewProviderError(code, errors.New(r.Error))
 	}
 
 	if !r.Response.Matched {
 		return provider.ProviderEvaluateResponse{
 			Matched:         false,
 			TemplateContext: r.Response.TemplateContext.AsMap(),
+			Warnings:        r.Response.Warnings,
 		}, nil
 	}
 
@@ -46,6 +60,7 @@ func (g *grpcServiceClient) Evaluate(ctx context.Context, cap string, conditionI
 			FileURI:              uri.URI(i.FileURI),
 			Variables:            i.GetVariables().AsMap(),
 			IsDependencyIncident: i.IsDependencyIncident,
+			MatchedText:          i.MatchedText,
 		}
 		if i.LineNumber != nil {
 			lineNumber := int(*i.LineNumber)
@@ -82,6 +97,7 @@ func (g *grpcServiceClient) Evaluate(ctx context.Context, cap string, conditionI
 		Matched:         true,
 		Incidents:       incs,
 		TemplateContext: r.Response.TemplateContext.AsMap(),
+		Warnings:        r.Response.Warnings,
 	}, nil
 }
 
@@ -146,7 +162,13 @@ func recreateDAGAddedItems(items []*pb.DependencyDAGItem) []provider.DepDAGItem
 
 // We don't have dependencies
 func (g *grpcServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]provider.DepDAGItem, error) {
+	if !g.negotiated.supports(provider.DependencyDAGFeature) {
+		return g.flatDependenciesDAG(ctx)
+	}
 	d, err := g.client.GetDependenciesDAG(ctx, &pb.ServiceRequest{Id: g.id})
+	if status.Code(err) == codes.Unimplemented {
+		return g.flatDependenciesDAG(ctx)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -167,6 +189,41 @@ func (g *grpcServiceClient) GetDependenciesDAG(ctx context.Context) (map[uri.URI
 
 }
 
+// flatDependenciesDAG returns the dependencies as a DAG without edges for the
+// providers that can't build the DAG, their dependencies are all listed at the
+// top level with the indirect ones flagged as such.
+func (g *grpcServiceClient) flatDependenciesDAG(ctx context.Context) (map[uri.URI][]provider.DepDAGItem, error) {
+	g.log.V(3).Info("provider does not support dependency DAGs, listing the dependencies without their tree")
+	deps, err := g.GetDependencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m := map[uri.URI][]provider.DepDAGItem{}
+	for u, ds := range deps {
+		items := []provider.DepDAGItem{}
+		for _, d := range ds {
+			items = append(items, provider.DepDAGItem{Dep: *d})
+		}
+		m[u] = items
+	}
+	return m, nil
+}
+
 func (g *grpcServiceClient) Stop() {
 	g.client.Stop(context.TODO(), &pb.ServiceRequest{Id: g.id})
 }
+
+// statusErrorCode classifies the error of a call to the provider by its
+// status
+func statusErrorCode(err error) konveyor.ErrorCode {
+	switch status.Code(err) {
+	case codes.Unavailable:
+		return konveyor.ProviderUnavailable
+	case codes.DeadlineExceeded:
+		return konveyor.ConditionTimeout
+	case codes.Unimplemented:
+		return konveyor.CapabilityUnsupported
+	default:
+		return konveyor.UnknownError
+	}
+}
@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/progress"
+	"github.com/konveyor/analyzer-lsp/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// InitProviders initializes the providers concurrently, running at most
+// parallelism initializations at a time, a parallelism under 1 initializes
+// all the providers at once. The builtin provider is initialized last with the
+// additional configs returned by the other providers.
+func InitProviders(ctx context.Context, log logr.Logger, providers map[string]InternalProviderClient, parallelism int) error {
+	names := []string{}
+	for name := range providers {
+		if name != "builtin" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if parallelism < 1 || parallelism > len(names) {
+		parallelism = len(names)
+	}
+
+	var (
+		mutex                    sync.Mutex
+		wg                       sync.WaitGroup
+		done                     int
+		errs                     []error
+		additionalBuiltinConfigs = map[string][]InitConfig{}
+	)
+	// the tasks of every provider are started first for the progress of the
+	// initialization to be weighted over all of them
+	task := progress.FromContext(ctx)
+	tasks := map[string]*progress.Task{}
+	for name := range providers {
+		tasks[name] = task.Task(progress.StageProviderInit, name, 1)
+	}
+	semaphore := make(chan struct{}, parallelism)
+	for _, name := range names {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(name string, prov InternalProviderClient) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			defer tasks[name].Done()
+			initCtx, initSpan := tracing.StartNewSpan(ctx, "init",
+				attribute.Key("provider").String(name))
+			defer initSpan.End()
+			initCtx = progress.WithTask(initCtx, tasks[name])
+
+			log.V(3).Info("initializing provider", "provider", name)
+			start := time.Now()
+			configs, err := prov.ProviderInit(initCtx, nil)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			done++
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to init the %s provider: %w", name, err))
+				return
+			}
+			additionalBuiltinConfigs[name] = configs
+			log.Info("provider initialized", "provider", name,
+				"duration", time.Since(start).Round(time.Millisecond).String(),
+				"done", done, "total", len(names))
+		}(name, providers[name])
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if builtinClient, ok := providers["builtin"]; ok {
+		// keep the order of the configs independent of which provider finished first
+		builtinConfigs := []InitConfig{}
+		for _, name := range names {
+			builtinConfigs = append(builtinConfigs, additionalBuiltinConfigs[name]...)
+		}
+		start := time.Now()
+		if _, err := builtinClient.ProviderInit(progress.WithTask(ctx, tasks["builtin"]), builtinConfigs); err != nil {
+			return fmt.Errorf("unable to init builtin provider: %w", err)
+		}
+		tasks["builtin"].Done()
+		log.Info("provider initialized", "provider", "builtin",
+			"duration", time.Since(start).Round(time.Millisecond).String())
+	}
+	return nil
+}
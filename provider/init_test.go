@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+type fakeInitClient struct {
+	fakeClient
+	location string
+	err      error
+	tracker  *initTracker
+	received []InitConfig
+}
+
+// initTracker records how many providers are initialized at the same time
+type initTracker struct {
+	mutex   sync.Mutex
+	running int
+	max     int
+}
+
+func (c *fakeInitClient) ProviderInit(ctx context.Context, configs []InitConfig) ([]InitConfig, error) {
+	c.received = configs
+	if c.tracker != nil {
+		c.tracker.mutex.Lock()
+		c.tracker.running++
+		if c.tracker.running > c.tracker.max {
+			c.tracker.max = c.tracker.running
+		}
+		c.tracker.mutex.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		c.tracker.mutex.Lock()
+		c.tracker.running--
+		c.tracker.mutex.Unlock()
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.location == "" {
+		return nil, nil
+	}
+	return []InitConfig{{Location: c.location}}, nil
+}
+
+func Test_InitProviders(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallelism int
+		providers   int
+		wantMax     int
+	}{
+		{name: "all at once", parallelism: 0, providers: 4, wantMax: 4},
+		{name: "bounded", parallelism: 2, providers: 4, wantMax: 2},
+		{name: "serial", parallelism: 1, providers: 3, wantMax: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := &initTracker{}
+			builtin := &fakeInitClient{}
+			providers := map[string]InternalProviderClient{"builtin": builtin}
+			wantConfigs := []InitConfig{}
+			for i := 0; i < tt.providers; i++ {
+				location := fmt.Sprintf("/location/%d", i)
+				providers[fmt.Sprintf("provider-%d", i)] = &fakeInitClient{location: location, tracker: tracker}
+				wantConfigs = append(wantConfigs, InitConfig{Location: location})
+			}
+			if err := InitProviders(context.Background(), logr.Discard(), providers, tt.parallelism); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tracker.max != tt.wantMax {
+				t.Errorf("expected at most %d providers initialized at once, got %d", tt.wantMax, tracker.max)
+			}
+			if !reflect.DeepEqual(builtin.received, wantConfigs) {
+				t.Errorf("expected builtin to be initialized with %v, got %v", wantConfigs, builtin.received)
+			}
+		})
+	}
+}
+
+func Test_InitProvidersError(t *testing.T) {
+	builtin := &fakeInitClient{}
+	providers := map[string]InternalProviderClient{
+		"builtin": builtin,
+		"java":    &fakeInitClient{err: fmt.Errorf("no java")},
+		"go":      &fakeInitClient{location: "/location"},
+	}
+	if err := InitProviders(context.Background(), logr.Discard(), providers, 0); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if builtin.received != nil {
+		t.Errorf("expected builtin not to be initialized after a provider failed")
+	}
+}
@@ -0,0 +1,735 @@
+package builtin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+// Formats of the configuration files of a configKey condition
+const (
+	configFormatProperties = "properties"
+	configFormatINI        = "ini"
+	configFormatTOML       = "toml"
+	configFormatEnv        = "env"
+)
+
+// configEntry is a key of a configuration file, Line is the one the key is
+// declared on, a value can span the following lines
+type configEntry struct {
+	Key     string
+	Value   string
+	Line    int
+	Profile string
+}
+
+func (p *builtinServiceClient) evaluateConfigKey(c configKeyCondition, providerContext provider.ProviderContext) (provider.ProviderEvaluateResponse, error) {
+	response := provider.ProviderEvaluateResponse{Matched: false}
+	if c.KeyPattern == "" {
+		return response, fmt.Errorf("keyPattern must be given for config keys")
+	}
+	regexes := map[string]*regexp.Regexp{}
+	for field, pattern := range map[string]string{"keyPattern": c.KeyPattern, "valuePattern": c.ValuePattern, "profile": c.Profile} {
+		if pattern == "" {
+			continue
+		}
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return response, fmt.Errorf("unable to compile %s `%s`: %w", field, pattern, err)
+		}
+		regexes[field] = regex
+	}
+	files, err := p.fileContentFiles(providerContext)
+	if err != nil {
+		return response, err
+	}
+	for _, file := range files {
+		if configFormat(file) == "" {
+			continue
+		}
+		containsFile, err := provider.FilterFilePattern(c.FilePattern, file)
+		if err != nil {
+			return response, err
+		}
+		if !containsFile {
+			continue
+		}
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			absPath = file
+		}
+		if !p.isFileIncluded(absPath) {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			p.log.V(5).Error(err, "unable to read file", "file", file)
+			response.Warnings = append(response.Warnings, fmt.Sprintf("unable to read file %s: %v", file, err))
+			continue
+		}
+		// the entries before an error are still searched
+		entries, err := parseConfigFile(file, string(content))
+		if err != nil {
+			p.log.V(5).Error(err, "error parsing configuration file", "file", file)
+			response.Warnings = append(response.Warnings, fmt.Sprintf("unable to parse %s file %s: %v", configFormat(file), file, err))
+		}
+		for _, entry := range entries {
+			if !regexes["keyPattern"].MatchString(entry.Key) {
+				continue
+			}
+			if regex, ok := regexes["valuePattern"]; ok && !regex.MatchString(entry.Value) {
+				continue
+			}
+			if regex, ok := regexes["profile"]; ok && (entry.Profile == "" || !regex.MatchString(entry.Profile)) {
+				continue
+			}
+			variables := map[string]interface{}{
+				"key":   entry.Key,
+				"value": entry.Value,
+			}
+			if entry.Profile != "" {
+				variables["profile"] = entry.Profile
+			}
+			lineNumber := entry.Line
+			response.Incidents = append(response.Incidents, provider.IncidentContext{
+				FileURI:    uri.File(absPath),
+				LineNumber: &lineNumber,
+				Variables:  variables,
+				CodeLocation: &provider.Location{
+					StartPosition: provider.Position{Line: float64(lineNumber)},
+					EndPosition:   provider.Position{Line: float64(lineNumber)},
+				},
+			})
+		}
+	}
+	response.Matched = len(response.Incidents) > 0
+	return response, nil
+}
+
+// configFormat returns the format of a configuration file from its name, an
+// empty string when the file isn't one
+func configFormat(file string) string {
+	name := filepath.Base(file)
+	if name == ".env" || strings.HasPrefix(name, ".env.") {
+		return configFormatEnv
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".properties":
+		return configFormatProperties
+	case ".ini", ".cfg":
+		return configFormatINI
+	case ".toml":
+		return configFormatTOML
+	case ".env":
+		return configFormatEnv
+	}
+	return ""
+}
+
+// fileProfile returns the profile a file is for from its name, such as prod
+// for application-prod.properties or .env.prod
+func fileProfile(file, format string) string {
+	name := filepath.Base(file)
+	switch format {
+	case configFormatProperties:
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		for _, prefix := range []string{"application-", "bootstrap-"} {
+			if strings.HasPrefix(base, prefix) {
+				return strings.TrimPrefix(base, prefix)
+			}
+		}
+	case configFormatEnv:
+		if strings.HasPrefix(name, ".env.") {
+			return strings.TrimPrefix(name, ".env.")
+		}
+	}
+	return ""
+}
+
+// parseConfigFile returns the entries of a configuration file in the order
+// they are declared
+func parseConfigFile(file string, content string) ([]configEntry, error) {
+	format := configFormat(file)
+	var entries []configEntry
+	var err error
+	switch format {
+	case configFormatProperties:
+		entries = parseProperties(content)
+	case configFormatINI:
+		entries = parseINI(content)
+	case configFormatTOML:
+		entries, err = parseTOML(content)
+	case configFormatEnv:
+		entries, err = parseEnv(content)
+	default:
+		return nil, fmt.Errorf("unknown format of configuration file %s", file)
+	}
+	if profile := fileProfile(file, format); profile != "" {
+		for i := range entries {
+			if entries[i].Profile == "" {
+				entries[i].Profile = profile
+			}
+		}
+	}
+	return entries, err
+}
+
+// Keys of spring properties documents activated for a profile
+var propertiesProfileKeys = []string{"spring.config.activate.on-profile", "spring.profiles"}
+
+// parseProperties parses a java properties file. Lines ending with a
+// backslash continue on the next line, and the #--- separators of spring
+// split a file in documents, the profile of the entries of a document is the
+// one it is activated on.
+func parseProperties(content string) []configEntry {
+	entries := []configEntry{}
+	documentStart := 0
+	setProfile := func() {
+		for _, entry := range entries[documentStart:] {
+			for _, key := range propertiesProfileKeys {
+				if entry.Key != key {
+					continue
+				}
+				for i := documentStart; i < len(entries); i++ {
+					entries[i].Profile = entry.Value
+				}
+				return
+			}
+		}
+	}
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimLeft(strings.TrimRight(lines[i], "\r"), " \t\f")
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' || line[0] == '!' {
+			if strings.TrimRight(line[1:], " \t") == "---" {
+				setProfile()
+				documentStart = len(entries)
+			}
+			continue
+		}
+		start := i
+		logical := line
+		for endsWithContinuation(logical) && i+1 < len(lines) {
+			i++
+			logical = logical[:len(logical)-1] + strings.TrimLeft(strings.TrimRight(lines[i], "\r"), " \t\f")
+		}
+		if endsWithContinuation(logical) {
+			logical = logical[:len(logical)-1]
+		}
+		key, value := splitProperty(logical)
+		entries = append(entries, configEntry{
+			Key:   unescapeProperty(key),
+			Value: unescapeProperty(value),
+			Line:  start + 1,
+		})
+	}
+	setProfile()
+	return entries
+}
+
+// endsWithContinuation reports whether a line ends with an odd number of
+// backslashes, an even number is escaped backslashes
+func endsWithContinuation(line string) bool {
+	backslashes := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+// splitProperty splits a logical line in its key and value, the key ends at
+// the first unescaped =, : or whitespace
+func splitProperty(line string) (string, string) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+	key, rest := line[:end], strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return key, rest
+}
+
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 <= len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// parseINI parses an ini file, the keys of a section are prefixed with its
+// name as in section.key and the indented lines following a key continue its
+// value
+func parseINI(content string) []configEntry {
+	entries := []configEntry{}
+	section := ""
+	last := -1
+	for i, rawLine := range strings.Split(content, "\n") {
+		rawLine = strings.TrimRight(rawLine, "\r")
+		line := strings.TrimSpace(rawLine)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if last >= 0 && (rawLine[0] == ' ' || rawLine[0] == '\t') {
+			entries[last].Value += "\n" + line
+			continue
+		}
+		last = -1
+		if line[0] == '[' {
+			if end := strings.IndexByte(line, ']'); end > 0 {
+				section = strings.TrimSpace(line[1:end])
+			}
+			continue
+		}
+		end := strings.IndexAny(line, "=:")
+		key, value := line, ""
+		if end >= 0 {
+			key, value = strings.TrimSpace(line[:end]), strings.TrimSpace(line[end+1:])
+		}
+		if section != "" {
+			key = section + "." + key
+		}
+		entries = append(entries, configEntry{Key: key, Value: unquote(value), Line: i + 1})
+		last = len(entries) - 1
+	}
+	return entries
+}
+
+// unquote removes the quotes around a value of an ini file
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// parseEnv parses a dotenv file. Values can be quoted, the double quoted ones
+// can span lines and have escapes, and the export prefix of shells is
+// ignored.
+func parseEnv(content string) ([]configEntry, error) {
+	entries := []configEntry{}
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(strings.TrimRight(lines[i], "\r"))
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		start := i
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return entries, fmt.Errorf("expected KEY=VALUE at line %d", i+1)
+		}
+		key, value := strings.TrimSpace(line[:eq]), strings.TrimSpace(line[eq+1:])
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[0]
+			value = value[1:]
+			for {
+				if end := closingQuote(value, quote); end >= 0 {
+					value = value[:end]
+					break
+				}
+				if i+1 == len(lines) {
+					return entries, fmt.Errorf("unterminated value of %s at line %d", key, start+1)
+				}
+				i++
+				value += "\n" + strings.TrimRight(lines[i], "\r")
+			}
+			if quote == '"' {
+				value = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(value)
+			}
+		} else if comment := strings.Index(value, " #"); comment >= 0 {
+			value = strings.TrimSpace(value[:comment])
+		}
+		entries = append(entries, configEntry{Key: key, Value: value, Line: start + 1})
+	}
+	return entries, nil
+}
+
+// closingQuote returns the index of the quote closing a value, the double
+// quotes can be escaped
+func closingQuote(value string, quote byte) int {
+	for i := 0; i < len(value); i++ {
+		if quote == '"' && value[i] == '\\' {
+			i++
+			continue
+		}
+		if value[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// tomlParser parses the subset of TOML the configuration of applications
+// uses: tables, arrays of tables, dotted keys, strings, arrays and inline
+// tables. The other values, such as numbers and dates, are kept as written.
+type tomlParser struct {
+	s       string
+	pos     int
+	line    int
+	entries []configEntry
+	// arrays counts the tables of each array of tables
+	arrays map[string]int
+}
+
+// parseTOML returns an entry per value of a TOML file, the keys are the
+// dotted keys of the values with the index of the arrays of tables, such as
+// servers[0].host. Arrays are values of their own, encoded as json.
+func parseTOML(content string) ([]configEntry, error) {
+	p := &tomlParser{s: content, line: 1, entries: []configEntry{}, arrays: map[string]int{}}
+	table := ""
+	for {
+		p.skipSpace(true)
+		if p.pos >= len(p.s) {
+			return p.entries, nil
+		}
+		line := p.line
+		if p.s[p.pos] == '[' {
+			array := strings.HasPrefix(p.s[p.pos:], "[[")
+			if array {
+				p.pos += 2
+			} else {
+				p.pos++
+			}
+			key, err := p.parseKey()
+			if err != nil {
+				return p.entries, err
+			}
+			closing := "]"
+			if array {
+				closing = "]]"
+			}
+			p.skipSpace(false)
+			if !strings.HasPrefix(p.s[p.pos:], closing) {
+				return p.entries, fmt.Errorf("expected %s at line %d", closing, line)
+			}
+			p.pos += len(closing)
+			table = key
+			if array {
+				table = fmt.Sprintf("%s[%d]", key, p.arrays[key])
+				p.arrays[key]++
+			}
+		} else {
+			key, err := p.parseKey()
+			if err != nil {
+				return p.entries, err
+			}
+			p.skipSpace(false)
+			if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+				return p.entries, fmt.Errorf("expected = after %s at line %d", key, line)
+			}
+			p.pos++
+			if table != "" {
+				key = table + "." + key
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return p.entries, err
+			}
+			p.addEntries(key, value, line)
+		}
+		p.skipSpace(false)
+		if p.pos < len(p.s) && p.s[p.pos] != '\n' && p.s[p.pos] != '\r' {
+			return p.entries, fmt.Errorf("unexpected %q at line %d", p.s[p.pos], p.line)
+		}
+	}
+}
+
+// addEntries adds the entry of a value, or the ones of the values of an
+// inline table
+func (p *tomlParser) addEntries(key string, value interface{}, line int) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p.addEntries(key+"."+k, v[k], line)
+		}
+	case []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			b = []byte(fmt.Sprint(v))
+		}
+		p.entries = append(p.entries, configEntry{Key: key, Value: string(b), Line: line})
+	default:
+		p.entries = append(p.entries, configEntry{Key: key, Value: fmt.Sprint(v), Line: line})
+	}
+}
+
+// skipSpace skips whitespace and comments, and line breaks when newlines is
+// set
+func (p *tomlParser) skipSpace(newlines bool) {
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && newlines:
+			p.pos++
+			p.line++
+		case c == '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// parseKey parses a dotted key, its parts can be quoted
+func (p *tomlParser) parseKey() (string, error) {
+	parts := []string{}
+	for {
+		p.skipSpace(false)
+		if p.pos >= len(p.s) {
+			return "", fmt.Errorf("expected a key at line %d", p.line)
+		}
+		switch p.s[p.pos] {
+		case '"', '\'':
+			part, err := p.parseString()
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && isBareKeyChar(p.s[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return "", fmt.Errorf("expected a key at line %d", p.line)
+			}
+			parts = append(parts, p.s[start:p.pos])
+		}
+		p.skipSpace(false)
+		if p.pos >= len(p.s) || p.s[p.pos] != '.' {
+			return strings.Join(parts, "."), nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// parseValue parses a value, strings are unquoted, arrays are slices and
+// inline tables are maps
+func (p *tomlParser) parseValue() (interface{}, error) {
+	p.skipSpace(false)
+	if p.pos >= len(p.s) {
+		return nil, fmt.Errorf("expected a value at line %d", p.line)
+	}
+	switch p.s[p.pos] {
+	case '"', '\'':
+		return p.parseString()
+	case '[':
+		p.pos++
+		array := []interface{}{}
+		for {
+			p.skipSpace(true)
+			if p.pos < len(p.s) && p.s[p.pos] == ']' {
+				p.pos++
+				return array, nil
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+			p.skipSpace(true)
+			if p.pos < len(p.s) && p.s[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.s) || p.s[p.pos] != ']' {
+				return nil, fmt.Errorf("expected , or ] at line %d", p.line)
+			}
+		}
+	case '{':
+		p.pos++
+		table := map[string]interface{}{}
+		for {
+			p.skipSpace(false)
+			if p.pos < len(p.s) && p.s[p.pos] == '}' {
+				p.pos++
+				return table, nil
+			}
+			key, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace(false)
+			if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+				return nil, fmt.Errorf("expected = after %s at line %d", key, p.line)
+			}
+			p.pos++
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			table[key] = value
+			p.skipSpace(false)
+			if p.pos < len(p.s) && p.s[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.s) || p.s[p.pos] != '}' {
+				return nil, fmt.Errorf("expected , or } at line %d", p.line)
+			}
+		}
+	}
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n,]}#", p.s[p.pos]) < 0 {
+		p.pos++
+	}
+	// the date and time of a local date time can be separated by a space
+	if p.pos-start == 10 && p.pos+1 < len(p.s) && p.s[p.pos] == ' ' && p.s[p.pos+1] >= '0' && p.s[p.pos+1] <= '9' {
+		p.pos++
+		for p.pos < len(p.s) && strings.IndexByte(" \t\r\n,]}#", p.s[p.pos]) < 0 {
+			p.pos++
+		}
+	}
+	if start == p.pos {
+		return nil, fmt.Errorf("expected a value at line %d", p.line)
+	}
+	return p.s[start:p.pos], nil
+}
+
+// parseString parses a basic or literal string, either of them can be a
+// multi-line one
+func (p *tomlParser) parseString() (string, error) {
+	line := p.line
+	quote := p.s[p.pos]
+	delimiter := string(quote)
+	if strings.HasPrefix(p.s[p.pos:], strings.Repeat(delimiter, 3)) {
+		delimiter = strings.Repeat(delimiter, 3)
+	}
+	p.pos += len(delimiter)
+	multiline := len(delimiter) == 3
+	// a line break right after the delimiter of a multi-line string is trimmed
+	if multiline && strings.HasPrefix(p.s[p.pos:], "\r\n") {
+		p.pos += 2
+		p.line++
+	} else if multiline && strings.HasPrefix(p.s[p.pos:], "\n") {
+		p.pos++
+		p.line++
+	}
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		if strings.HasPrefix(p.s[p.pos:], delimiter) {
+			p.pos += len(delimiter)
+			// up to two quotes can end a multi-line string
+			for extra := 0; multiline && extra < 2 && p.pos < len(p.s) && p.s[p.pos] == quote; extra++ {
+				b.WriteByte(quote)
+				p.pos++
+			}
+			return b.String(), nil
+		}
+		c := p.s[p.pos]
+		if c == '\n' {
+			if !multiline {
+				break
+			}
+			p.line++
+		}
+		if c != '\\' || quote == '\'' {
+			r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+			b.WriteRune(r)
+			p.pos += size
+			continue
+		}
+		p.pos++
+		if p.pos >= len(p.s) {
+			break
+		}
+		switch e := p.s[p.pos]; e {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u', 'U':
+			size := 4
+			if e == 'U' {
+				size = 8
+			}
+			if p.pos+size < len(p.s) {
+				if r, err := strconv.ParseUint(p.s[p.pos+1:p.pos+1+size], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					p.pos += size
+					break
+				}
+			}
+			return "", fmt.Errorf("invalid unicode escape at line %d", p.line)
+		case '\n', ' ', '\t', '\r':
+			// a backslash ending a line of a multi-line string trims the
+			// whitespace up to the next non whitespace character
+			for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+				if p.s[p.pos] == '\n' {
+					p.line++
+				}
+				p.pos++
+			}
+			continue
+		default:
+			b.WriteByte(e)
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated string starting at line %d", line)
+}
@@ -0,0 +1,257 @@
+package builtin
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Names of the project facts that can be used in projectFact conditions
+const (
+	buildToolFact         = "buildTool"
+	packagingFact         = "packaging"
+	jdkVersionFact        = "jdkVersion"
+	springBootVersionFact = "springBootVersion"
+)
+
+var projectFactNames = []string{buildToolFact, packagingFact, jdkVersionFact, springBootVersionFact}
+
+// projectFact is a fact about the project along with the file it was detected in
+type projectFact struct {
+	value string
+	file  string
+}
+
+var (
+	pomPropertyRefRegex      = regexp.MustCompile(`\$\{([^}]+)\}`)
+	gradleCompatibilityRegex = regexp.MustCompile(`(?m)^\s*(?:java\.)?(source|target)Compatibility\s*=\s*(?:JavaVersion\.VERSION_)?['"]?([0-9][0-9._]*)`)
+	gradleToolchainRegex     = regexp.MustCompile(`JavaLanguageVersion\.of\(\s*['"]?(\d+)`)
+	gradleSpringBootRegex    = regexp.MustCompile(`id\s*\(?\s*['"]org\.springframework\.boot['"]\s*\)?\s*version\s*\(?\s*['"]([^'"]+)['"]`)
+	gradleSpringBootDepRegex = regexp.MustCompile(`org\.springframework\.boot:spring-boot[\w-]*:(\d[^'"@:]*)`)
+	gradleWarPluginRegex     = gradlePluginRegex("war")
+	gradleEarPluginRegex     = gradlePluginRegex("ear")
+)
+
+func gradlePluginRegex(plugin string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`(?m)(id\s*\(?\s*['"]%[1]s['"]|apply\s+plugin:\s*['"]%[1]s['"]|^\s*%[1]s\s*$)`, plugin))
+}
+
+type pomFacts struct {
+	Packaging string `xml:"packaging"`
+	Parent    struct {
+		GroupID    string `xml:"groupId"`
+		ArtifactID string `xml:"artifactId"`
+		Version    string `xml:"version"`
+	} `xml:"parent"`
+	Properties struct {
+		Entries []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"properties"`
+	Dependencies []pomFactsArtifact `xml:"dependencies>dependency"`
+	Managed      []pomFactsArtifact `xml:"dependencyManagement>dependencies>dependency"`
+	Plugins      []struct {
+		pomFactsArtifact
+		Configuration struct {
+			Release string `xml:"release"`
+			Source  string `xml:"source"`
+			Target  string `xml:"target"`
+		} `xml:"configuration"`
+	} `xml:"build>plugins>plugin"`
+}
+
+type pomFactsArtifact struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+}
+
+// detectProjectFacts looks at the build files at the root of the location, or at the
+// location itself when it is an archive, and returns the facts that could be detected.
+func detectProjectFacts(location string) (map[string]projectFact, error) {
+	facts := map[string]projectFact{}
+	location, err := filepath.Abs(location)
+	if err != nil {
+		return facts, err
+	}
+	info, err := os.Stat(location)
+	if err != nil {
+		return facts, err
+	}
+	if !info.IsDir() {
+		switch ext := strings.ToLower(filepath.Ext(location)); ext {
+		case ".war", ".ear", ".jar":
+			facts[packagingFact] = projectFact{value: strings.TrimPrefix(ext, "."), file: location}
+		}
+		return facts, nil
+	}
+
+	pomPath := filepath.Join(location, "pom.xml")
+	if content, err := os.ReadFile(pomPath); err == nil {
+		facts[buildToolFact] = projectFact{value: "maven", file: pomPath}
+		for name, value := range pomProjectFacts(content) {
+			facts[name] = projectFact{value: value, file: pomPath}
+		}
+		return facts, nil
+	}
+
+	for _, name := range []string{"build.gradle", "build.gradle.kts"} {
+		gradlePath := filepath.Join(location, name)
+		content, err := os.ReadFile(gradlePath)
+		if err != nil {
+			continue
+		}
+		facts[buildToolFact] = projectFact{value: "gradle", file: gradlePath}
+		for name, value := range gradleProjectFacts(string(content)) {
+			facts[name] = projectFact{value: value, file: gradlePath}
+		}
+		break
+	}
+	return facts, nil
+}
+
+func pomProjectFacts(content []byte) map[string]string {
+	facts := map[string]string{}
+	pom := pomFacts{}
+	if err := xml.Unmarshal(content, &pom); err != nil {
+		return facts
+	}
+	properties := map[string]string{}
+	for _, p := range pom.Properties.Entries {
+		properties[p.XMLName.Local] = strings.TrimSpace(p.Value)
+	}
+	resolve := func(value string) string {
+		// properties can reference other properties, limit the depth to avoid cycles
+		for i := 0; i < 5 && pomPropertyRefRegex.MatchString(value); i++ {
+			value = pomPropertyRefRegex.ReplaceAllStringFunc(value, func(ref string) string {
+				if v, ok := properties[ref[2:len(ref)-1]]; ok {
+					return v
+				}
+				return ref
+			})
+		}
+		return strings.TrimSpace(value)
+	}
+
+	facts[packagingFact] = "jar"
+	if pom.Packaging != "" {
+		facts[packagingFact] = resolve(pom.Packaging)
+	}
+
+	jdkCandidates := []string{}
+	for _, plugin := range pom.Plugins {
+		if plugin.ArtifactID == "maven-compiler-plugin" {
+			jdkCandidates = append(jdkCandidates, plugin.Configuration.Release, plugin.Configuration.Target, plugin.Configuration.Source)
+		}
+	}
+	jdkCandidates = append(jdkCandidates,
+		properties["maven.compiler.release"],
+		properties["maven.compiler.target"],
+		properties["maven.compiler.source"],
+		properties["java.version"])
+	for _, candidate := range jdkCandidates {
+		if v := normalizeJDKVersion(resolve(candidate)); v != "" && !strings.Contains(v, "${") {
+			facts[jdkVersionFact] = v
+			break
+		}
+	}
+
+	springBootVersion := ""
+	if pom.Parent.GroupID == "org.springframework.boot" {
+		springBootVersion = pom.Parent.Version
+	}
+	for _, dep := range append(pom.Managed, pom.Dependencies...) {
+		if springBootVersion == "" && dep.GroupID == "org.springframework.boot" && dep.Version != "" {
+			springBootVersion = dep.Version
+		}
+	}
+	if springBootVersion == "" {
+		springBootVersion = properties["spring-boot.version"]
+	}
+	if v := resolve(springBootVersion); v != "" && !strings.Contains(v, "${") {
+		facts[springBootVersionFact] = v
+	}
+	return facts
+}
+
+func gradleProjectFacts(content string) map[string]string {
+	facts := map[string]string{packagingFact: "jar"}
+	if gradleEarPluginRegex.MatchString(content) {
+		facts[packagingFact] = "ear"
+	} else if gradleWarPluginRegex.MatchString(content) {
+		facts[packagingFact] = "war"
+	}
+
+	if match := gradleToolchainRegex.FindStringSubmatch(content); match != nil {
+		facts[jdkVersionFact] = match[1]
+	} else {
+		// prefer the target compatibility as that's what the project runs on
+		for _, match := range gradleCompatibilityRegex.FindAllStringSubmatch(content, -1) {
+			if _, ok := facts[jdkVersionFact]; !ok || match[1] == "target" {
+				facts[jdkVersionFact] = normalizeJDKVersion(strings.ReplaceAll(match[2], "_", "."))
+			}
+		}
+	}
+
+	if match := gradleSpringBootRegex.FindStringSubmatch(content); match != nil {
+		facts[springBootVersionFact] = match[1]
+	} else if match := gradleSpringBootDepRegex.FindStringSubmatch(content); match != nil {
+		facts[springBootVersionFact] = match[1]
+	}
+	return facts
+}
+
+// normalizeJDKVersion turns legacy versions such as 1.8 into 8
+func normalizeJDKVersion(v string) string {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, "1.") {
+		return strings.TrimPrefix(v, "1.")
+	}
+	return v
+}
+
+// matchProjectFact checks the value of a fact against the condition, bounds are
+// only satisfied by values that can be parsed as versions.
+func matchProjectFact(c projectFactCondition, value string) (bool, error) {
+	if c.Value != "" {
+		regex, err := regexp.Compile(c.Value)
+		if err != nil {
+			return false, fmt.Errorf("unable to compile project fact value pattern '%s': %w", c.Value, err)
+		}
+		if !regex.MatchString(value) {
+			return false, nil
+		}
+	}
+	if c.Lowerbound == "" && c.Upperbound == "" {
+		return true, nil
+	}
+	v, err := version.NewVersion(value)
+	if err != nil {
+		return false, nil
+	}
+	if c.Lowerbound != "" {
+		lb, err := version.NewVersion(normalizeJDKVersion(c.Lowerbound))
+		if err != nil {
+			return false, fmt.Errorf("invalid lowerbound '%s': %w", c.Lowerbound, err)
+		}
+		if v.LessThan(lb) {
+			return false, nil
+		}
+	}
+	if c.Upperbound != "" {
+		ub, err := version.NewVersion(normalizeJDKVersion(c.Upperbound))
+		if err != nil {
+			return false, fmt.Errorf("invalid upperbound '%s': %w", c.Upperbound, err)
+		}
+		if v.GreaterThan(ub) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,246 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+)
+
+const (
+	defaultFileContentContextLines = 5
+	// same heuristic grep uses to find binary files
+	binaryFileSniffLength = 8000
+	// most files given to a grep command at once
+	grepBatchFiles = 1000
+)
+
+// fileContentMatcher is a compiled filecontent condition that needs to be
+// evaluated in process rather than with grep, either because the pattern spans
+// multiple lines or because the context around the match must be checked.
+type fileContentMatcher struct {
+	pattern      *regexp.Regexp
+	before       *regexp.Regexp
+	after        *regexp.Regexp
+	notBefore    *regexp.Regexp
+	notAfter     *regexp.Regexp
+	contextLines int
+}
+
+type fileContentMatch struct {
+	startLine int
+	endLine   int
+	text      string
+}
+
+func (c fileContentCondition) needsMatcher() bool {
+	return c.Multiline || c.MatchesBefore != "" || c.MatchesAfter != "" ||
+		c.NotMatchesBefore != "" || c.NotMatchesAfter != ""
+}
+
+func newFileContentMatcher(c fileContentCondition) (*fileContentMatcher, error) {
+	// patterns are matched against the whole file, keep ^ and $ anchored to lines like grep does
+	flags := "(?m)"
+	if c.Multiline {
+		flags = "(?ms)"
+	}
+	pattern, err := compileRegex(flags + c.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile pattern '%s', multi-line and context matching use Go regex syntax: %w", c.Pattern, err)
+	}
+	m := &fileContentMatcher{
+		pattern:      pattern,
+		contextLines: defaultFileContentContextLines,
+	}
+	if c.ContextLines != nil && *c.ContextLines >= 0 {
+		m.contextLines = *c.ContextLines
+	}
+	for _, context := range []struct {
+		pattern string
+		regex   **regexp.Regexp
+	}{
+		{c.MatchesBefore, &m.before},
+		{c.MatchesAfter, &m.after},
+		{c.NotMatchesBefore, &m.notBefore},
+		{c.NotMatchesAfter, &m.notAfter},
+	} {
+		if context.pattern == "" {
+			continue
+		}
+		*context.regex, err = compileRegex("(?m)" + context.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("could not compile context pattern '%s': %w", context.pattern, err)
+		}
+	}
+	return m, nil
+}
+
+// findMatches returns the matches in content that satisfy the context requirements,
+// line numbers start at 1.
+func (m *fileContentMatcher) findMatches(content []byte) []fileContentMatch {
+	lineStarts := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	lineOf := func(offset int) int {
+		return sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > offset }) - 1
+	}
+	matches := []fileContentMatch{}
+	for _, loc := range m.pattern.FindAllIndex(content, -1) {
+		startLine := lineOf(loc[0])
+		endLine := startLine
+		if loc[1] > loc[0] {
+			endLine = lineOf(loc[1] - 1)
+		}
+		// context includes the rest of the lines the match starts and ends on
+		beforeStart := lineStarts[max(0, startLine-m.contextLines)]
+		before := content[beforeStart:loc[0]]
+		afterEnd := len(content)
+		if endLine+m.contextLines+1 < len(lineStarts) {
+			afterEnd = lineStarts[endLine+m.contextLines+1]
+		}
+		after := content[loc[1]:afterEnd]
+		if (m.before != nil && !m.before.Match(before)) ||
+			(m.after != nil && !m.after.Match(after)) ||
+			(m.notBefore != nil && m.notBefore.Match(before)) ||
+			(m.notAfter != nil && m.notAfter.Match(after)) {
+			continue
+		}
+		matches = append(matches, fileContentMatch{
+			startLine: startLine + 1,
+			endLine:   endLine + 1,
+			text:      string(content[loc[0]:loc[1]]),
+		})
+	}
+	return matches
+}
+
+// fileContentResult is what the matcher found in a file
+type fileContentResult struct {
+	incidents []provider.IncidentContext
+	warning   string
+}
+
+func (p *builtinServiceClient) evaluateFileContentMatcher(ctx context.Context, c fileContentCondition, providerContext provider.ProviderContext) (provider.ProviderEvaluateResponse, error) {
+	response := provider.ProviderEvaluateResponse{Matched: false}
+	matcher, err := newFileContentMatcher(c)
+	if err != nil {
+		return response, err
+	}
+	files, err := p.fileContentFiles(providerContext)
+	if err != nil {
+		return response, err
+	}
+	searched := []string{}
+	for _, file := range files {
+		containsFile, err := provider.FilterFilePattern(c.FilePattern, file)
+		if err != nil {
+			return response, err
+		}
+		if !containsFile {
+			continue
+		}
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			absPath = file
+		}
+		if !p.isFileIncluded(absPath) {
+			continue
+		}
+		searched = append(searched, absPath)
+	}
+	results, err := evaluateFiles(ctx, p, searched, func(file string) fileContentResult {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			p.log.V(5).Error(err, "unable to read file", "file", file)
+			return fileContentResult{warning: fmt.Sprintf("unable to read file %s: %v", file, err)}
+		}
+		if bytes.IndexByte(content[:min(len(content), binaryFileSniffLength)], 0) != -1 {
+			return fileContentResult{}
+		}
+		result := fileContentResult{}
+		for _, match := range matcher.findMatches(content) {
+			lineNumber := match.startLine
+			result.incidents = append(result.incidents, provider.IncidentContext{
+				FileURI:    uri.File(file),
+				LineNumber: &lineNumber,
+				Variables: map[string]interface{}{
+					"matchingText": match.text,
+				},
+				MatchedText: match.text,
+				CodeLocation: &provider.Location{
+					StartPosition: provider.Position{Line: float64(match.startLine)},
+					EndPosition:   provider.Position{Line: float64(match.endLine)},
+				},
+			})
+		}
+		return result
+	})
+	if err != nil {
+		return response, err
+	}
+	for _, result := range results {
+		if result.warning != "" {
+			response.Warnings = append(response.Warnings, result.warning)
+		}
+		response.Incidents = append(response.Incidents, result.incidents...)
+	}
+	response.Matched = len(response.Incidents) > 0
+	return response, nil
+}
+
+// fileContentFiles returns the files to search, the scoped filepaths when set
+// or every regular file under the location.
+func (p *builtinServiceClient) fileContentFiles(providerContext provider.ProviderContext) ([]string, error) {
+	if ok, paths := providerContext.GetScopedFilepaths(); ok {
+		return paths, nil
+	}
+	files, err := walkFiles(p.config.Location, p.pathFilter, p.ignore, p.workers(), func(path string, d fs.DirEntry) bool {
+		return d.Type().IsRegular()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list files in %s: %w", p.config.Location, err)
+	}
+	return files, nil
+}
+
+// grepFileContent greps the location for the pattern. When the ignore files
+// are respected the files that are not ignored are given to grep in batches,
+// so that it does not search the ignored directories, the commands used on
+// Windows and macOS always search the location and the ignored files are
+// left out of their output by the caller.
+func (p *builtinServiceClient) grepFileContent(pattern string, providerContext provider.ProviderContext) ([]byte, error) {
+	if ok, _ := providerContext.GetScopedFilepaths(); ok || p.ignore == nil ||
+		runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return runOSSpecificGrepCommand(pattern, p.config.Location, providerContext)
+	}
+	files, err := p.fileContentFiles(providerContext)
+	if err != nil {
+		return nil, err
+	}
+	output := []byte{}
+	for start := 0; start < len(files); start += grepBatchFiles {
+		batch := provider.ProviderContext{
+			Template: map[string]engine.ChainTemplate{
+				engine.TemplateContextPathScopeKey: {Filepaths: files[start:min(start+grepBatchFiles, len(files))]},
+			},
+		}
+		batchOutput, err := runOSSpecificGrepCommand(pattern, p.config.Location, batch)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, batchOutput...)
+	}
+	return output, nil
+}
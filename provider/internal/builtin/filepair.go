@@ -0,0 +1,26 @@
+package builtin
+
+import "path/filepath"
+
+// Scopes the paired files of a filePair condition are looked for in
+const (
+	filePairWithinLocation  = "location"
+	filePairWithinDirectory = "directory"
+)
+
+// pairsOf returns the paired files of a file, the ones in the same directory
+// when within is directory or any of them otherwise. A file is never paired
+// with itself.
+func pairsOf(file string, pairFiles []string, within string) []string {
+	pairs := []string{}
+	for _, pair := range pairFiles {
+		if pair == file {
+			continue
+		}
+		if within == filePairWithinDirectory && filepath.Dir(pair) != filepath.Dir(file) {
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
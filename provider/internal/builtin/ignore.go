@@ -0,0 +1,244 @@
+package builtin
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ignoreFiles are read in every directory of the location when the ignore
+// files are respected, the rules of the later files win
+var ignoreFiles = []string{".gitignore", ".konveyorignore"}
+
+// ignoreMatcher tells whether the files of a location are ignored by the
+// .gitignore and .konveyorignore files of their directories, with the
+// semantics of git. A nil matcher ignores nothing.
+type ignoreMatcher struct {
+	root string
+	log  func(err error, file string)
+
+	mutex sync.RWMutex
+	dirs  map[string]*ignoreDir
+}
+
+// ignoreDir is what applies to the files of a directory, the rules of the
+// ignore files of the directory and of its parents
+type ignoreDir struct {
+	ignored bool
+	rules   []ignoreRule
+}
+
+type ignoreRule struct {
+	// base is the directory of the ignore file, the rule matches the paths
+	// relative to it
+	base    string
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+func newIgnoreMatcher(root string, log func(err error, file string)) *ignoreMatcher {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &ignoreMatcher{root: root, log: log, dirs: map[string]*ignoreDir{}}
+}
+
+// Ignored reports whether the path, absolute or relative to the working
+// directory, is ignored because it or one of its parents matches the rules
+func (m *ignoreMatcher) Ignored(path string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if path == m.root || !strings.HasPrefix(path, m.root+string(filepath.Separator)) {
+		return false
+	}
+	if isDir && filepath.Base(path) == ".git" {
+		return true
+	}
+	dir := m.dir(filepath.Dir(path))
+	return dir.ignored || dir.match(path, isDir)
+}
+
+func (m *ignoreMatcher) dir(path string) *ignoreDir {
+	m.mutex.RLock()
+	dir, ok := m.dirs[path]
+	m.mutex.RUnlock()
+	if ok {
+		return dir
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.loadDir(path)
+}
+
+// loadDir reads the ignore files of the directory and its parents up to the
+// root, the mutex must be held
+func (m *ignoreMatcher) loadDir(path string) *ignoreDir {
+	if dir, ok := m.dirs[path]; ok {
+		return dir
+	}
+	dir := &ignoreDir{}
+	if path != m.root {
+		parent := m.loadDir(filepath.Dir(path))
+		dir.ignored = parent.ignored || filepath.Base(path) == ".git" || parent.match(path, true)
+		// the rules of the parent are shared, the ones of the directory are
+		// appended to a copy
+		dir.rules = parent.rules[:len(parent.rules):len(parent.rules)]
+	}
+	if !dir.ignored {
+		for _, name := range ignoreFiles {
+			rules, err := readIgnoreFile(filepath.Join(path, name))
+			if err != nil {
+				if !os.IsNotExist(err) && m.log != nil {
+					m.log(err, filepath.Join(path, name))
+				}
+				continue
+			}
+			dir.rules = append(dir.rules, rules...)
+		}
+	}
+	m.dirs[path] = dir
+	return dir
+}
+
+// match reports whether the last rule matching the path ignores it
+func (d *ignoreDir) match(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range d.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.base, path)
+		if err != nil {
+			continue
+		}
+		if rule.regex.MatchString(filepath.ToSlash(rel)) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func readIgnoreFile(path string) ([]ignoreRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseIgnoreRules(filepath.Dir(path), f)
+}
+
+// parseIgnoreRules parses the patterns of an ignore file in the base
+// directory, see https://git-scm.com/docs/gitignore#_pattern_format
+func parseIgnoreRules(base string, r io.Reader) ([]ignoreRule, error) {
+	rules := []ignoreRule{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.TrimSuffix(scanner.Text(), "\r"), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		regex, err := compileIgnorePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		rule.regex = regex
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+func compileIgnorePattern(pattern string) (*regexp.Regexp, error) {
+	b := strings.Builder{}
+	b.WriteString("^")
+	// a pattern without a slash but at the end matches a name at any depth,
+	// others are relative to the directory of the ignore file
+	if !strings.Contains(pattern, "/") {
+		b.WriteString("(?:.*/)?")
+	}
+	p := strings.TrimPrefix(pattern, "/")
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' && (i == 0 || p[i-1] == '/') {
+				i++
+				if i+1 < len(p) && p[i+1] == '/' {
+					// **/ matches any number of directories, none included
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(p) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(p[i])))
+			}
+		case '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	regex, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+	}
+	return regex, nil
+}
+
+// isBinaryFile reports whether the file has a NUL byte in its first bytes,
+// the same heuristic grep uses to find binary files
+func isBinaryFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	head := make([]byte, binaryFileSniffLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return bytes.IndexByte(head[:n], 0) != -1, nil
+}
@@ -0,0 +1,145 @@
+package builtin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for file, content := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_ignoreMatcher_Ignored(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":            "# dependencies\nnode_modules/\n/build\n*.log\n!keep.log\ndocs/**/*.html\n",
+		".konveyorignore":       "vendor\n",
+		"src/.gitignore":        "generated/\n!debug.log\n",
+		"src/main.js":           "",
+		"src/debug.log":         "",
+		"src/build/app.js":      "",
+		"src/generated/gen.js":  "",
+		"node_modules/x/pkg.js": "",
+		"build/out.js":          "",
+		"app.log":               "",
+		"keep.log":              "",
+		"docs/a/b/index.html":   "",
+		"docs/index.md":         "",
+		"vendor/lib.go":         "",
+	})
+	m := newIgnoreMatcher(root, nil)
+	for path, want := range map[string]bool{
+		"src/main.js":           false,
+		"src/debug.log":         false,
+		"src/build/app.js":      false,
+		"src/generated/gen.js":  true,
+		"node_modules/x/pkg.js": true,
+		"build/out.js":          true,
+		"app.log":               true,
+		"keep.log":              false,
+		"docs/a/b/index.html":   true,
+		"docs/index.md":         false,
+		"vendor/lib.go":         true,
+		".git/config":           true,
+	} {
+		if got := m.Ignored(filepath.Join(root, path), false); got != want {
+			t.Errorf("Ignored(%s) = %v, want %v", path, got, want)
+		}
+	}
+	if !m.Ignored(filepath.Join(root, "node_modules"), true) {
+		t.Errorf("expected the node_modules directory to be ignored")
+	}
+	if m.Ignored(filepath.Join(root, "node_modules"), false) {
+		t.Errorf("expected a node_modules file not to be ignored")
+	}
+
+	var nilMatcher *ignoreMatcher
+	if nilMatcher.Ignored(filepath.Join(root, "app.log"), false) {
+		t.Errorf("expected a nil matcher to ignore nothing")
+	}
+}
+
+func Test_builtinServiceClient_Evaluate_ignoredFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":               "node_modules/\n",
+		"src/app.js":               "require('lodash')\n",
+		"node_modules/lodash/x.js": "require('lodash')\n",
+		"lib/blob.bin":             "\x00require('lodash')\n",
+	})
+	for _, tt := range []struct {
+		name      string
+		config    map[string]interface{}
+		condition string
+		want      []string
+	}{
+		{
+			name:      "grep skipping binary files",
+			config:    map[string]interface{}{SKIP_BINARY_FILES_OPTION: true},
+			condition: "filecontent:\n  pattern: require\n",
+			want:      []string{"node_modules/lodash/x.js", "src/app.js"},
+		},
+		{
+			name:      "grep respecting the ignore files and skipping binary files",
+			config:    map[string]interface{}{RESPECT_IGNORE_FILES_OPTION: true, SKIP_BINARY_FILES_OPTION: true},
+			condition: "filecontent:\n  pattern: require\n",
+			want:      []string{"src/app.js"},
+		},
+		{
+			name:      "matcher respecting the ignore files",
+			config:    map[string]interface{}{RESPECT_IGNORE_FILES_OPTION: true},
+			condition: "filecontent:\n  pattern: require\n  multiline: true\n",
+			want:      []string{"src/app.js"},
+		},
+		{
+			name:      "file respecting the ignore files",
+			config:    map[string]interface{}{RESPECT_IGNORE_FILES_OPTION: true},
+			condition: "file:\n  pattern: '*.js'\n",
+			want:      []string{"src/app.js"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &builtinProvider{log: testr.New(t)}
+			client, _, err := p.Init(context.TODO(), testr.New(t), provider.InitConfig{Location: root, ProviderSpecificConfig: tt.config})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cap := "filecontent"
+			if tt.condition[:5] == "file:" {
+				cap = "file"
+			}
+			got, err := client.Evaluate(context.TODO(), cap, []byte(tt.condition))
+			if err != nil {
+				t.Fatal(err)
+			}
+			files := []string{}
+			for _, incident := range got.Incidents {
+				rel, err := filepath.Rel(root, incident.FileURI.Filename())
+				if err != nil {
+					t.Fatal(err)
+				}
+				files = append(files, filepath.ToSlash(rel))
+			}
+			sort.Strings(files)
+			if !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("expected incidents in %v, got %v", tt.want, files)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package builtin
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync"
+
+	"github.com/konveyor/analyzer-lsp/pathfilter"
+)
+
+// defaultFileBatchBytes is the most bytes of files the workers of a location
+// hold at once by default
+const defaultFileBatchBytes = 256 << 20
+
+// regexCache keeps the regexes of the conditions, compiled once for every
+// condition and worker using the same pattern
+var regexCache sync.Map
+
+type cachedRegex struct {
+	regex *regexp.Regexp
+	err   error
+}
+
+// compileRegex returns the compiled pattern from the cache
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := regexCache.Load(pattern); ok {
+		return cached.(cachedRegex).regex, cached.(cachedRegex).err
+	}
+	regex, err := regexp.Compile(pattern)
+	regexCache.Store(pattern, cachedRegex{regex: regex, err: err})
+	return regex, err
+}
+
+// byteBudget bounds the bytes of the files read at once by the workers of
+// all the conditions evaluated on a location, a file bigger than the budget
+// takes all of it
+type byteBudget struct {
+	mutex     sync.Mutex
+	cond      *sync.Cond
+	available int64
+	limit     int64
+}
+
+func newByteBudget(limit int64) *byteBudget {
+	b := &byteBudget{available: limit, limit: limit}
+	b.cond = sync.NewCond(&b.mutex)
+	return b
+}
+
+// acquire waits for n bytes of the budget and returns the bytes taken, to
+// be released
+func (b *byteBudget) acquire(n int64) int64 {
+	n = min(max(n, 1), b.limit)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for b.available < n {
+		b.cond.Wait()
+	}
+	b.available -= n
+	return n
+}
+
+func (b *byteBudget) release(n int64) {
+	b.mutex.Lock()
+	b.available += n
+	b.mutex.Unlock()
+	b.cond.Broadcast()
+}
+
+// workers returns the number of workers evaluating the files of a condition
+func (p *builtinServiceClient) workers() int {
+	if p.fileWorkers <= 0 {
+		return runtime.NumCPU()
+	}
+	return p.fileWorkers
+}
+
+// budget returns the byte budget of the files read by the workers
+func (p *builtinServiceClient) budget() *byteBudget {
+	p.budgetOnce.Do(func() {
+		limit := p.fileBatchBytes
+		if limit <= 0 {
+			limit = defaultFileBatchBytes
+		}
+		p.fileBudget = newByteBudget(limit)
+	})
+	return p.fileBudget
+}
+
+// evaluateFiles calls fn with every file on the workers of the service
+// client, within the byte budget, and returns the results in the order of
+// the files. fn is called concurrently.
+func evaluateFiles[T any](ctx context.Context, p *builtinServiceClient, files []string, fn func(file string) T) ([]T, error) {
+	results := make([]T, len(files))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	budget := p.budget()
+	for w := 0; w < min(p.workers(), len(files)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				size := int64(0)
+				if info, err := os.Stat(files[i]); err == nil {
+					size = info.Size()
+				}
+				taken := budget.acquire(size)
+				results[i] = fn(files[i])
+				budget.release(taken)
+			}
+		}()
+	}
+	var err error
+	for i := range files {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results, err
+}
+
+// walkFiles returns the paths under root, root included, that match, in the
+// order of filepath.WalkDir. The directories are read by up to workers
+// goroutines, the ones skipped by the filter or ignored are not walked.
+func walkFiles(root string, filter *pathfilter.Filter, ignore *ignoreMatcher, workers int, match func(path string, d fs.DirEntry) bool) ([]string, error) {
+	info, err := os.Lstat(root)
+	if err != nil {
+		return nil, err
+	}
+	w := &walker{filter: filter, ignore: ignore, match: match, workers: make(chan struct{}, max(workers-1, 0))}
+	paths := []string{}
+	d := fs.FileInfoToDirEntry(info)
+	if match(root, d) {
+		paths = append(paths, root)
+	}
+	if d.IsDir() {
+		paths = append(paths, w.walk(root)...)
+	}
+	return paths, w.err
+}
+
+type walker struct {
+	filter  *pathfilter.Filter
+	ignore  *ignoreMatcher
+	match   func(path string, d fs.DirEntry) bool
+	workers chan struct{}
+
+	mutex sync.Mutex
+	err   error
+}
+
+func (w *walker) walk(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		w.mutex.Lock()
+		if w.err == nil {
+			w.err = err
+		}
+		w.mutex.Unlock()
+		return nil
+	}
+	results := make([][]string, len(entries))
+	wg := sync.WaitGroup{}
+	for i, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && w.filter.SkipDir(path) {
+			continue
+		}
+		if w.ignore.Ignored(path, entry.IsDir()) {
+			continue
+		}
+		if w.match(path, entry) {
+			results[i] = append(results[i], path)
+		}
+		if !entry.IsDir() {
+			continue
+		}
+		// a directory is walked by the goroutine reading its parent when
+		// every worker is busy
+		select {
+		case w.workers <- struct{}{}:
+			wg.Add(1)
+			go func(i int, path string) {
+				defer wg.Done()
+				defer func() { <-w.workers }()
+				results[i] = append(results[i], w.walk(path)...)
+			}(i, path)
+		default:
+			results[i] = append(results[i], w.walk(path)...)
+		}
+	}
+	wg.Wait()
+	paths := []string{}
+	for _, r := range results {
+		paths = append(paths, r...)
+	}
+	return paths
+}
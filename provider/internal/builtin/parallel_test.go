@@ -0,0 +1,79 @@
+package builtin
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_walkFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{"a.txt", "b/c.txt", "b/d/e.xml", "b/d/f.txt", "g/h.txt", "z.xml"} {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		want = append(want, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{1, 2, 8} {
+		got, err := walkFiles(root, nil, nil, workers, func(string, fs.DirEntry) bool { return true })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("workers %d: expected %v, got %v", workers, want, got)
+		}
+	}
+}
+
+func Test_evaluateFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("0123456789"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+	// the budget only fits one file at a time
+	p := &builtinServiceClient{fileWorkers: 4, fileBatchBytes: 15}
+	var reading, most int32
+	got, err := evaluateFiles(context.TODO(), p, files, func(file string) string {
+		n := atomic.AddInt32(&reading, 1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		defer atomic.AddInt32(&reading, -1)
+		return filepath.Base(file)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c", "d", "e", "f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if most != 1 {
+		t.Errorf("expected one file read at a time, got %d", most)
+	}
+}
@@ -10,7 +10,13 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-const TAGS_FILE_INIT_OPTION = "tagsFile"
+const (
+	TAGS_FILE_INIT_OPTION        = "tagsFile"
+	FILE_WORKERS_INIT_OPTION     = "fileWorkers"
+	FILE_BATCH_BYTES_INIT_OPTION = "fileBatchBytes"
+	RESPECT_IGNORE_FILES_OPTION  = "respectIgnoreFiles"
+	SKIP_BINARY_FILES_OPTION     = "skipBinaryFiles"
+)
 
 var capabilities = []provider.Capability{}
 
@@ -20,6 +26,10 @@ type builtinCondition struct {
 	XML                      xmlCondition         `yaml:"xml"`
 	XMLPublicID              xmlPublicIDCondition `yaml:"xmlPublicID"`
 	JSON                     jsonCondition        `yaml:"json"`
+	JSONPath                 jsonPathCondition    `yaml:"jsonpath"`
+	ProjectFact              projectFactCondition `yaml:"projectFact"`
+	FilePair                 filePairCondition    `yaml:"filePair"`
+	ConfigKey                configKeyCondition   `yaml:"configKey"`
 	HasTags                  []string             `yaml:"hasTags"`
 	provider.ProviderContext `yaml:",inline"`
 }
@@ -27,6 +37,14 @@ type builtinCondition struct {
 type fileContentCondition struct {
 	FilePattern string `yaml:"filePattern" json:"filePattern,omitempty" title:"FilePattern" description:"Only search in files with names matching this pattern"`
 	Pattern     string `yaml:"pattern" json:"pattern" title:"Pattern" description:"Regex pattern to match in content"`
+	// Multiline and the context patterns are evaluated in process with Go regex
+	// syntax instead of grep.
+	Multiline        bool   `yaml:"multiline" json:"multiline,omitempty" title:"Multiline" description:"Allow the pattern to match across lines, '.' also matches line breaks"`
+	MatchesBefore    string `yaml:"matchesBefore" json:"matchesBefore,omitempty" title:"MatchesBefore" description:"Regex pattern that must match in the lines before the match"`
+	MatchesAfter     string `yaml:"matchesAfter" json:"matchesAfter,omitempty" title:"MatchesAfter" description:"Regex pattern that must match in the lines after the match"`
+	NotMatchesBefore string `yaml:"notMatchesBefore" json:"notMatchesBefore,omitempty" title:"NotMatchesBefore" description:"Regex pattern that must not match in the lines before the match"`
+	NotMatchesAfter  string `yaml:"notMatchesAfter" json:"notMatchesAfter,omitempty" title:"NotMatchesAfter" description:"Regex pattern that must not match in the lines after the match"`
+	ContextLines     *int   `yaml:"contextLines" json:"contextLines,omitempty" title:"ContextLines" description:"Number of lines before and after the match checked by the context patterns, defaults to 5"`
 }
 
 type fileCondition struct {
@@ -37,8 +55,14 @@ var _ provider.InternalProviderClient = &builtinProvider{}
 
 type xmlCondition struct {
 	XPath      string            `yaml:"xpath" json:"xpath" title:"XPath" description:"Xpath query"`
-	Namespaces map[string]string `yaml:"namespaces" json:"namespace,omitempty" title:"Namespaces" description:"A map to scope down query to namespaces"`
+	Namespaces map[string]string `yaml:"namespaces" json:"namespaces,omitempty" title:"Namespaces" description:"A map to scope down query to namespaces"`
 	Filepaths  []string          `yaml:"filepaths" json:"filepaths,omitempty" title:"Filepaths" description:"Optional list of files to scope down search"`
+	// Attributes are matched against the attributes of each node returned by the query,
+	// keys can be namespace qualified using a prefix from Namespaces.
+	Attributes map[string]string `yaml:"attributes" json:"attributes,omitempty" title:"Attributes" description:"A map of attribute names to regex patterns that matched nodes must satisfy"`
+	// Captures are evaluated relative to each matched node, the values are
+	// added to the incident variables and to the template context.
+	Captures map[string]string `yaml:"captures" json:"captures,omitempty" title:"Captures" description:"A map of variable names to xpath expressions evaluated against each matched node"`
 }
 
 type xmlPublicIDCondition struct {
@@ -52,6 +76,32 @@ type jsonCondition struct {
 	Filepaths []string `yaml:"filepaths" json:"filepaths,omitempty" title:"Filepaths" description:"Optional list of files to scope down search"`
 }
 
+type jsonPathCondition struct {
+	Path      string   `yaml:"path" json:"path" title:"Path" description:"JSONPath expression"`
+	Filepaths []string `yaml:"filepaths" json:"filepaths,omitempty" title:"Filepaths" description:"Optional list of files to scope down search"`
+}
+
+type projectFactCondition struct {
+	Name       string `yaml:"name" json:"name" title:"Name" description:"Name of the project fact, one of buildTool, packaging, jdkVersion or springBootVersion"`
+	Value      string `yaml:"value" json:"value,omitempty" title:"Value" description:"Regex pattern the value of the fact must match"`
+	Lowerbound string `yaml:"lowerbound" json:"lowerbound,omitempty" title:"Lowerbound" description:"Match versions greater than or equal to"`
+	Upperbound string `yaml:"upperbound" json:"upperbound,omitempty" title:"Upperbound" description:"Match versions lower than or equal to"`
+}
+
+type filePairCondition struct {
+	Pattern string `yaml:"pattern" json:"pattern" title:"Pattern" description:"Find files with names matching this pattern"`
+	Pair    string `yaml:"pair" json:"pair" title:"Pair" description:"Pattern of the names of the files paired with the matched files"`
+	Within  string `yaml:"within" json:"within,omitempty" title:"Within" description:"Where paired files are looked for, directory for the directory of the matched file or location for anywhere in the location, defaults to location"`
+	Missing bool   `yaml:"missing" json:"missing,omitempty" title:"Missing" description:"Match the files without a paired file instead of the files with one"`
+}
+
+type configKeyCondition struct {
+	KeyPattern   string `yaml:"keyPattern" json:"keyPattern" title:"KeyPattern" description:"Regex pattern the keys must match, the keys of sections and tables are dotted as in section.key"`
+	ValuePattern string `yaml:"valuePattern" json:"valuePattern,omitempty" title:"ValuePattern" description:"Regex pattern the values must match"`
+	FilePattern  string `yaml:"filePattern" json:"filePattern,omitempty" title:"FilePattern" description:"Only search in configuration files with names matching this pattern, defaults to the .properties, .ini, .cfg, .toml and .env files"`
+	Profile      string `yaml:"profile" json:"profile,omitempty" title:"Profile" description:"Regex pattern the profile of the keys must match, keys without a profile never match it"`
+}
+
 type builtinProvider struct {
 	log logr.Logger
 
@@ -80,6 +130,13 @@ func (p *builtinProvider) Capabilities() []provider.Capability {
 		caps = append(caps, jsonCap)
 	}
 
+	jsonPathCap, err := provider.ToProviderCap(r, p.log, jsonPathCondition{}, "jsonpath")
+	if err != nil {
+		p.log.Error(err, "unable to get jsonpath capability")
+	} else {
+		caps = append(caps, jsonPathCap)
+	}
+
 	xmlCap, err := provider.ToProviderCap(r, p.log, xmlCondition{}, "xml")
 	if err != nil {
 		p.log.Error(err, "unable to get xml capability")
@@ -108,6 +165,27 @@ func (p *builtinProvider) Capabilities() []provider.Capability {
 		caps = append(caps, xmlPublicIDCap)
 	}
 
+	projectFactCap, err := provider.ToProviderCap(r, p.log, projectFactCondition{}, "projectFact")
+	if err != nil {
+		p.log.Error(err, "unable to get projectFact capability")
+	} else {
+		caps = append(caps, projectFactCap)
+	}
+
+	filePairCap, err := provider.ToProviderCap(r, p.log, filePairCondition{}, "filePair")
+	if err != nil {
+		p.log.Error(err, "unable to get filePair capability")
+	} else {
+		caps = append(caps, filePairCap)
+	}
+
+	configKeyCap, err := provider.ToProviderCap(r, p.log, configKeyCondition{}, "configKey")
+	if err != nil {
+		p.log.Error(err, "unable to get configKey capability")
+	} else {
+		caps = append(caps, configKeyCap)
+	}
+
 	hasTags, err := provider.ToProviderCap(r, p.log, []string{}, "hasTags")
 	if err != nil {
 		p.log.Error(err, "unable to get hasTags capability")
@@ -147,16 +225,45 @@ func (p *builtinProvider) Init(ctx context.Context, log logr.Logger, config prov
 	if config.AnalysisMode != provider.AnalysisMode("") {
 		p.log.V(5).Info("skipping analysis mode setting for builtin")
 	}
+	pathFilter, err := provider.GetPathFilterFromConfig(config)
+	if err != nil {
+		return nil, provider.InitConfig{}, err
+	}
+	var ignore *ignoreMatcher
+	if respect, _ := config.ProviderSpecificConfig[RESPECT_IGNORE_FILES_OPTION].(bool); respect {
+		ignore = newIgnoreMatcher(config.Location, func(err error, file string) {
+			log.Error(err, "unable to read ignore file", "file", file)
+		})
+	}
+	skipBinaryFiles, _ := config.ProviderSpecificConfig[SKIP_BINARY_FILES_OPTION].(bool)
 	return &builtinServiceClient{
 		config:                             config,
 		tags:                               p.tags,
 		UnimplementedDependenciesComponent: provider.UnimplementedDependenciesComponent{},
 		locationCache:                      make(map[string]float64),
 		log:                                log,
-		includedPaths:                      provider.GetIncludedPathsFromConfig(config, true),
+		pathFilter:                         pathFilter,
+		fileWorkers:                        int(intOption(config, FILE_WORKERS_INIT_OPTION)),
+		fileBatchBytes:                     intOption(config, FILE_BATCH_BYTES_INIT_OPTION),
+		ignore:                             ignore,
+		skipBinaryFiles:                    skipBinaryFiles,
 	}, provider.InitConfig{}, nil
 }
 
+// intOption returns the number of the provider specific config, zero when it
+// is not set, the settings read from JSON have float numbers
+func intOption(config provider.InitConfig, key string) int64 {
+	switch v := config.ProviderSpecificConfig[key].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
 func (p *builtinProvider) loadTags(config provider.InitConfig) error {
 	tagsFile, ok := config.ProviderSpecificConfig[TAGS_FILE_INIT_OPTION].(string)
 	// for now, if the tags file is invalid, lets ignore
@@ -2,6 +2,7 @@ package builtin
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,7 +19,11 @@ import (
 	"github.com/antchfx/xmlquery"
 	"github.com/antchfx/xpath"
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/pathfilter"
 	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/internal/jsonpath"
 	"github.com/konveyor/analyzer-lsp/tracing"
 	"go.lsp.dev/uri"
 	"gopkg.in/yaml.v2"
@@ -31,7 +37,24 @@ type builtinServiceClient struct {
 
 	cacheMutex    sync.RWMutex
 	locationCache map[string]float64
-	includedPaths []string
+	pathFilter    *pathfilter.Filter
+
+	// facts about the project are detected once, on first use
+	factsOnce sync.Once
+	facts     map[string]projectFact
+
+	// the files of the conditions are walked and evaluated by fileWorkers
+	// goroutines holding up to fileBatchBytes of files at once
+	fileWorkers    int
+	fileBatchBytes int64
+	budgetOnce     sync.Once
+	fileBudget     *byteBudget
+
+	// files ignored by the .gitignore and .konveyorignore files, nil when they
+	// are not respected
+	ignore *ignoreMatcher
+	// skipBinaryFiles leaves the binary files out of the filecontent searches
+	skipBinaryFiles bool
 }
 
 type fileTemplateContext struct {
@@ -46,7 +69,7 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 	var cond builtinCondition
 	err := yaml.Unmarshal(conditionInfo, &cond)
 	if err != nil {
-		return provider.ProviderEvaluateResponse{}, fmt.Errorf("unable to get query info: %v", err)
+		return provider.ProviderEvaluateResponse{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("unable to get query info: %v", err))
 	}
 	log := p.log.WithValues("ruleID", cond.ProviderContext.RuleID)
 	log.V(5).Info("builtin condition context", "condition", cond, "provider context", cond.ProviderContext)
@@ -59,7 +82,7 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 		}
 		matchingFiles := []string{}
 		if ok, paths := cond.ProviderContext.GetScopedFilepaths(); ok {
-			regex, _ := regexp.Compile(c.Pattern)
+			regex, _ := compileRegex(c.Pattern)
 			for _, path := range paths {
 				matched := false
 				if regex != nil {
@@ -76,7 +99,7 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 				}
 			}
 		} else {
-			matchingFiles, err = findFilesMatchingPattern(p.config.Location, c.Pattern)
+			matchingFiles, err = findFilesMatchingPattern(p.config.Location, c.Pattern, p.pathFilter, p.ignore, p.workers())
 			if err != nil {
 				return response, fmt.Errorf("unable to find files using pattern `%s`: %v", c.Pattern, err)
 			}
@@ -106,10 +129,13 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 		if c.Pattern == "" {
 			return response, fmt.Errorf("could not parse provided regex pattern as string: %v", conditionInfo)
 		}
+		if c.needsMatcher() {
+			return p.evaluateFileContentMatcher(ctx, c, cond.ProviderContext)
+		}
 
 		var outputBytes []byte
 		//Runs on Windows using PowerShell.exe and Unix based systems using grep
-		outputBytes, err := runOSSpecificGrepCommand(c.Pattern, p.config.Location, cond.ProviderContext)
+		outputBytes, err := p.grepFileContent(c.Pattern, cond.ProviderContext)
 		if err != nil {
 			return response, err
 		}
@@ -119,6 +145,7 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 			matches = append(matches, strings.Split(outputString, "\n")...)
 		}
 
+		binaryFiles := map[string]bool{}
 		for _, match := range matches {
 			var pieces []string
 			pieces, err := parseGrepOutputForFileContent(match)
@@ -142,6 +169,18 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 			if !p.isFileIncluded(absPath) {
 				continue
 			}
+			if p.skipBinaryFiles {
+				if _, ok := binaryFiles[absPath]; !ok {
+					binary, err := isBinaryFile(absPath)
+					if err != nil {
+						p.log.V(5).Error(err, "unable to read file", "file", absPath)
+					}
+					binaryFiles[absPath] = binary
+				}
+				if binaryFiles[absPath] {
+					continue
+				}
+			}
 
 			lineNumber, err := strconv.Atoi(pieces[1])
 			if err != nil {
@@ -154,6 +193,7 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 				Variables: map[string]interface{}{
 					"matchingText": pieces[2],
 				},
+				MatchedText: pieces[2],
 				CodeLocation: &provider.Location{
 					StartPosition: provider.Position{Line: float64(lineNumber)},
 					EndPosition:   provider.Position{Line: float64(lineNumber)},
@@ -197,16 +237,31 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 		} else if len(cond.XML.Filepaths) > 0 {
 			filePaths = cond.XML.Filepaths
 		}
+		attributes, err := compileXMLAttributes(cond.XML.Attributes)
+		if err != nil {
+			return response, err
+		}
+		captures, err := compileXMLCaptures(cond.XML.Captures, cond.XML.Namespaces)
+		if err != nil {
+			return response, err
+		}
+		capturedValues := map[string][]string{}
 		xmlFiles, err := findXMLFiles(p.config.Location, filePaths, log)
 		if err != nil {
 			return response, fmt.Errorf("unable to find XML files: %v", err)
 		}
-		for _, file := range xmlFiles {
-			nodes, err := queryXMLFile(file, query)
+		queried, err := p.queryXMLFiles(ctx, xmlFiles, query)
+		if err != nil {
+			return response, err
+		}
+		for i, file := range xmlFiles {
+			nodes, err := queried[i].nodes, queried[i].err
 			if err != nil {
 				log.V(5).Error(err, "failed to query xml file", "file", file)
+				response.Warnings = append(response.Warnings, fmt.Sprintf("unable to query xml file %s: %v", file, err))
 				continue
 			}
+			nodes = filterXMLNodesByAttributes(nodes, attributes, cond.XML.Namespaces)
 			if len(nodes) != 0 {
 				response.Matched = true
 				for _, node := range nodes {
@@ -224,6 +279,12 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 							"innerText":   node.InnerText(),
 							"data":        node.Data,
 						},
+						MatchedText: node.OutputXML(false),
+					}
+					for name, capture := range captures {
+						value := evaluateXMLCapture(capture, node)
+						incident.Variables[name] = value
+						capturedValues[name] = append(capturedValues[name], value)
 					}
 					content := strings.TrimSpace(node.InnerText())
 					if content == "" {
@@ -239,10 +300,16 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 				}
 			}
 		}
+		if len(capturedValues) > 0 {
+			response.TemplateContext = map[string]interface{}{}
+			for name, values := range capturedValues {
+				response.TemplateContext[name] = values
+			}
+		}
 
 		return response, nil
 	case "xmlPublicID":
-		regex, err := regexp.Compile(cond.XMLPublicID.Regex)
+		regex, err := compileRegex(cond.XMLPublicID.Regex)
 		if err != nil {
 			return response, fmt.Errorf("could not parse provided public-id regex '%s': %v", cond.XMLPublicID.Regex, err)
 		}
@@ -279,10 +346,15 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 		if err != nil {
 			return response, fmt.Errorf("unable to find XML files: %v", err)
 		}
-		for _, file := range xmlFiles {
-			nodes, err := queryXMLFile(file, query)
+		queried, err := p.queryXMLFiles(ctx, xmlFiles, query)
+		if err != nil {
+			return response, err
+		}
+		for i, file := range xmlFiles {
+			nodes, err := queried[i].nodes, queried[i].err
 			if err != nil {
 				log.Error(err, "failed to query xml file", "file", file)
+				response.Warnings = append(response.Warnings, fmt.Sprintf("unable to query xml file %s: %v", file, err))
 				continue
 			}
 
@@ -306,6 +378,7 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 									"innerText":   node.InnerText(),
 									"data":        node.Data,
 								},
+								MatchedText: node.OutputXML(false),
 							})
 						}
 						break
@@ -335,11 +408,13 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 			f, err := os.Open(file)
 			if err != nil {
 				log.V(5).Error(err, "error opening json file", "file", file)
+				response.Warnings = append(response.Warnings, fmt.Sprintf("unable to open json file %s: %v", file, err))
 				continue
 			}
 			doc, err := jsonquery.Parse(f)
 			if err != nil {
 				log.V(5).Error(err, "error parsing json file", "file", file)
+				response.Warnings = append(response.Warnings, fmt.Sprintf("unable to parse json file %s: %v", file, err))
 				continue
 			}
 			list, err := jsonquery.QueryAll(doc, query)
@@ -362,6 +437,7 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 							"matchingJSON": node.InnerText(),
 							"data":         node.Data,
 						},
+						MatchedText: node.InnerText(),
 					}
 					location, err := p.getLocation(ctx, absPath, node.InnerText())
 					if err == nil {
@@ -374,6 +450,167 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 			}
 		}
 		return response, nil
+	case "jsonpath":
+		query := cond.JSONPath.Path
+		if query == "" {
+			return response, fmt.Errorf("could not parse provided jsonpath query as string: %v", conditionInfo)
+		}
+		segments, err := jsonpath.Compile(query)
+		if err != nil {
+			return response, fmt.Errorf("unable to compile jsonpath query `%s`: %w", query, err)
+		}
+		pattern := "*.json"
+		filePaths := []string{}
+		if ok, paths := cond.ProviderContext.GetScopedFilepaths(); ok {
+			if len(cond.JSONPath.Filepaths) > 0 {
+				newPaths := []string{}
+				// Respect the filepaths of the rule on the scoped filepaths
+				for _, p := range cond.JSONPath.Filepaths {
+					for _, path := range paths {
+						if p == path || filepath.Base(path) == p {
+							newPaths = append(newPaths, path)
+						}
+					}
+				}
+				if len(newPaths) == 0 {
+					// There are no files to search, return.
+					return response, nil
+				}
+				filePaths = newPaths
+			} else {
+				filePaths = paths
+			}
+		} else if len(cond.JSONPath.Filepaths) > 0 {
+			filePaths = cond.JSONPath.Filepaths
+		}
+		jsonFiles, err := provider.GetFiles(p.config.Location, filePaths, pattern)
+		if err != nil {
+			return response, fmt.Errorf("unable to find files using pattern `%s`: %v", pattern, err)
+		}
+		for _, file := range jsonFiles {
+			absPath, err := filepath.Abs(file)
+			if err != nil {
+				absPath = file
+			}
+			if !p.isFileIncluded(absPath) {
+				continue
+			}
+			content, err := os.ReadFile(file)
+			if err != nil {
+				log.V(5).Error(err, "error reading json file", "file", file)
+				response.Warnings = append(response.Warnings, fmt.Sprintf("unable to read json file %s: %v", file, err))
+				continue
+			}
+			var doc interface{}
+			if err := json.Unmarshal(content, &doc); err != nil {
+				log.V(5).Error(err, "error parsing json file", "file", file)
+				response.Warnings = append(response.Warnings, fmt.Sprintf("unable to parse json file %s: %v", file, err))
+				continue
+			}
+			matches := segments.Evaluate(doc)
+			if len(matches) == 0 {
+				continue
+			}
+			lines, err := jsonpath.Lines(content)
+			if err != nil {
+				log.V(5).Error(err, "error finding locations in json file", "file", file)
+			}
+			response.Matched = true
+			for _, match := range matches {
+				matchingJSON, err := json.Marshal(match.Value)
+				if err != nil {
+					matchingJSON = []byte(fmt.Sprintf("%v", match.Value))
+				}
+				incident := provider.IncidentContext{
+					FileURI: uri.File(absPath),
+					Variables: map[string]interface{}{
+						"matchingPath": match.Path,
+						"matchingJSON": string(matchingJSON),
+					},
+					MatchedText: string(matchingJSON),
+				}
+				if lineNo, ok := lines[match.Path]; ok {
+					incident.LineNumber = &lineNo
+					incident.CodeLocation = &provider.Location{
+						StartPosition: provider.Position{Line: float64(lineNo)},
+						EndPosition:   provider.Position{Line: float64(lineNo)},
+					}
+				}
+				response.Incidents = append(response.Incidents, incident)
+			}
+		}
+		return response, nil
+	case "projectFact":
+		c := cond.ProjectFact
+		if !slices.Contains(projectFactNames, c.Name) {
+			return response, fmt.Errorf("unknown project fact '%s', must be one of %v", c.Name, projectFactNames)
+		}
+		p.factsOnce.Do(func() {
+			p.facts, err = detectProjectFacts(p.config.Location)
+			if err != nil {
+				p.log.V(5).Error(err, "unable to detect project facts", "location", p.config.Location)
+			}
+		})
+		fact, ok := p.facts[c.Name]
+		if !ok {
+			return response, nil
+		}
+		matched, err := matchProjectFact(c, fact.value)
+		if err != nil || !matched {
+			return response, err
+		}
+		response.Matched = true
+		response.TemplateContext = map[string]interface{}{c.Name: fact.value}
+		response.Incidents = append(response.Incidents, provider.IncidentContext{
+			FileURI: uri.File(fact.file),
+			Variables: map[string]interface{}{
+				"name":  c.Name,
+				"value": fact.value,
+			},
+		})
+		return response, nil
+	case "filePair":
+		c := cond.FilePair
+		if c.Pattern == "" || c.Pair == "" {
+			return response, fmt.Errorf("pattern and pair must be given for file pairs: %v", conditionInfo)
+		}
+		if c.Within != "" && c.Within != filePairWithinLocation && c.Within != filePairWithinDirectory {
+			return response, fmt.Errorf("within of file pairs must be %s or %s, not %s", filePairWithinLocation, filePairWithinDirectory, c.Within)
+		}
+		files, err := findFilesMatchingPattern(p.config.Location, c.Pattern, p.pathFilter, p.ignore, p.workers())
+		if err != nil {
+			return response, fmt.Errorf("unable to find files using pattern `%s`: %v", c.Pattern, err)
+		}
+		pairFiles, err := findFilesMatchingPattern(p.config.Location, c.Pair, p.pathFilter, p.ignore, p.workers())
+		if err != nil {
+			return response, fmt.Errorf("unable to find files using pattern `%s`: %v", c.Pair, err)
+		}
+		matchingFiles := []string{}
+		for _, file := range files {
+			pairs := pairsOf(file, pairFiles, c.Within)
+			if (len(pairs) == 0) != c.Missing {
+				continue
+			}
+			absPath, err := filepath.Abs(file)
+			if err != nil {
+				p.log.V(5).Error(err, "failed to get absolute path to file", "path", file)
+				absPath = file
+			}
+			if !p.isFileIncluded(absPath) {
+				continue
+			}
+			matchingFiles = append(matchingFiles, file)
+			incident := provider.IncidentContext{FileURI: uri.File(absPath)}
+			if len(pairs) > 0 {
+				incident.Variables = map[string]interface{}{"pairs": pairs}
+			}
+			response.Incidents = append(response.Incidents, incident)
+		}
+		response.TemplateContext = map[string]interface{}{"filepaths": matchingFiles}
+		response.Matched = len(response.Incidents) > 0
+		return response, nil
+	case "configKey":
+		return p.evaluateConfigKey(cond.ConfigKey, cond.ProviderContext)
 	case "hasTags":
 		found := true
 		for _, tag := range cond.HasTags {
@@ -394,7 +631,7 @@ func (p *builtinServiceClient) Evaluate(ctx context.Context, cap string, conditi
 		}
 		return response, nil
 	default:
-		return response, fmt.Errorf("capability must be one of %v, not %s", capabilities, cap)
+		return response, engine.NewProviderError(konveyor.CapabilityUnsupported, fmt.Errorf("capability must be one of %v, not %s", capabilities, cap))
 	}
 }
 
@@ -463,31 +700,23 @@ func (b *builtinServiceClient) getLocation(ctx context.Context, path, content st
 	return location, nil
 }
 
-func findFilesMatchingPattern(root, pattern string) ([]string, error) {
-	var regex *regexp.Regexp
+func findFilesMatchingPattern(root, pattern string, filter *pathfilter.Filter, ignore *ignoreMatcher, workers int) ([]string, error) {
 	// if the regex doesn't compile, we'll default to using filepath.Match on the pattern directly
-	regex, _ = regexp.Compile(pattern)
-	matches := []string{}
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+	regex, _ := compileRegex(pattern)
+	if regex == nil {
+		// the pattern is the only cause of the errors of filepath.Match
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
 		}
-		var matched bool
+	}
+	return walkFiles(root, filter, ignore, workers, func(path string, d fs.DirEntry) bool {
 		if regex != nil {
-			matched = regex.MatchString(d.Name())
-		} else {
-			// TODO(fabianvf): is a fileglob style pattern sufficient or do we need regexes?
-			matched, err = filepath.Match(pattern, d.Name())
-			if err != nil {
-				return err
-			}
+			return regex.MatchString(d.Name())
 		}
-		if matched {
-			matches = append(matches, path)
-		}
-		return nil
+		// TODO(fabianvf): is a fileglob style pattern sufficient or do we need regexes?
+		matched, _ := filepath.Match(pattern, d.Name())
+		return matched
 	})
-	return matches, err
 }
 
 func findXMLFiles(baseLocation string, filePaths []string, log logr.Logger) ([]string, error) {
@@ -497,6 +726,21 @@ func findXMLFiles(baseLocation string, filePaths []string, log logr.Logger) ([]s
 	return xmlFiles, err
 }
 
+// xmlQueryResult is the nodes of an xml file selected by a query
+type xmlQueryResult struct {
+	nodes []*xmlquery.Node
+	err   error
+}
+
+// queryXMLFiles queries the xml files on the workers of the service client,
+// the results are in the order of the files
+func (p *builtinServiceClient) queryXMLFiles(ctx context.Context, files []string, query *xpath.Expr) ([]xmlQueryResult, error) {
+	return evaluateFiles(ctx, p, files, func(file string) xmlQueryResult {
+		nodes, err := queryXMLFile(file, query)
+		return xmlQueryResult{nodes: nodes, err: err}
+	})
+}
+
 func queryXMLFile(filePath string, query *xpath.Expr) (nodes []*xmlquery.Node, err error) {
 	f, err := os.Open(filePath)
 	if err != nil {
@@ -532,40 +776,107 @@ func queryXMLFile(filePath string, query *xpath.Expr) (nodes []*xmlquery.Node, e
 	return nodes, err
 }
 
-// filterByIncludedPaths given a list of file paths,
-// filters-out the ones not present in includedPaths
-func (b *builtinServiceClient) isFileIncluded(absolutePath string) bool {
-	if b.includedPaths == nil || len(b.includedPaths) == 0 {
-		return true
+// compileXMLAttributes compiles the attribute value patterns of an xml condition
+func compileXMLAttributes(attributes map[string]string) (map[string]*regexp.Regexp, error) {
+	compiled := map[string]*regexp.Regexp{}
+	for name, pattern := range attributes {
+		regex, err := compileRegex(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse pattern '%s' for attribute '%s': %v", pattern, name, err)
+		}
+		compiled[name] = regex
 	}
+	return compiled, nil
+}
 
-	getSegments := func(path string) []string {
-		segments := []string{}
-		path = filepath.Clean(path)
-		for _, segment := range strings.Split(
-			path, string(os.PathSeparator)) {
-			if segment != "" {
-				segments = append(segments, segment)
-			}
+// compileXMLCaptures compiles the capture expressions of an xml condition
+func compileXMLCaptures(captures map[string]string, namespaces map[string]string) (map[string]*xpath.Expr, error) {
+	compiled := map[string]*xpath.Expr{}
+	for name, expr := range captures {
+		query, err := xpath.CompileWithNS(expr, namespaces)
+		if query == nil || err != nil {
+			return nil, fmt.Errorf("could not parse xpath capture '%s' for variable '%s': %v", expr, name, err)
 		}
-		return segments
+		compiled[name] = query
 	}
+	return compiled, nil
+}
 
-	for _, path := range b.includedPaths {
-		includedPath := filepath.Join(b.config.Location, path)
-		if absPath, err := filepath.Abs(includedPath); err == nil {
-			includedPath = absPath
+// filterXMLNodesByAttributes only keeps nodes that have all of the given attributes with matching values.
+// Attribute names in the form prefix:name are resolved using the namespaces of the condition.
+func filterXMLNodesByAttributes(nodes []*xmlquery.Node, attributes map[string]*regexp.Regexp, namespaces map[string]string) []*xmlquery.Node {
+	if len(attributes) == 0 {
+		return nodes
+	}
+	filtered := []*xmlquery.Node{}
+	for _, node := range nodes {
+		matched := true
+		for name, regex := range attributes {
+			prefix, local, found := strings.Cut(name, ":")
+			if !found {
+				prefix, local = "", name
+			}
+			attrMatched := false
+			for _, attr := range node.Attr {
+				if attr.Name.Local != local {
+					continue
+				}
+				if prefix != "" {
+					if ns, ok := namespaces[prefix]; ok {
+						if attr.NamespaceURI != ns {
+							continue
+						}
+					} else if attr.Name.Space != prefix {
+						continue
+					}
+				}
+				if regex.MatchString(attr.Value) {
+					attrMatched = true
+					break
+				}
+			}
+			if !attrMatched {
+				matched = false
+				break
+			}
 		}
-		pathSegments := getSegments(absolutePath)
-		if stat, err := os.Stat(includedPath); err == nil && stat.IsDir() {
-			pathSegments = getSegments(filepath.Dir(absolutePath))
+		if matched {
+			filtered = append(filtered, node)
 		}
-		includedPathSegments := getSegments(includedPath)
-		if len(pathSegments) >= len(includedPathSegments) &&
-			strings.HasPrefix(strings.Join(pathSegments, ""),
-				strings.Join(includedPathSegments, "")) {
-			return true
+	}
+	return filtered
+}
+
+// evaluateXMLCapture evaluates a capture expression with the given node as the context,
+// node sets are converted to the value of their first node
+func evaluateXMLCapture(expr *xpath.Expr, node *xmlquery.Node) (value string) {
+	defer func() {
+		if r := recover(); r != nil {
+			value = ""
 		}
+	}()
+	switch v := expr.Evaluate(xmlquery.CreateXPathNavigator(node)).(type) {
+	case *xpath.NodeIterator:
+		if v.MoveNext() {
+			return strings.TrimSpace(v.Current().Value())
+		}
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// isFileIncluded tells whether the file is analyzed given the included and
+// excluded paths of the provider settings and the ignore files
+func (b *builtinServiceClient) isFileIncluded(absolutePath string) bool {
+	if b.pathFilter.Match(absolutePath) && !b.ignore.Ignored(absolutePath, false) {
+		return true
 	}
 	b.log.V(7).Info("excluding file from search", "file", absolutePath)
 	return false
@@ -632,7 +943,8 @@ func runOSSpecificGrepCommand(pattern string, location string, providerContext p
 		outputBytes, err = findstr.Output()
 
 	} else {
-		grep := exec.Command("grep", "-o", "-n", "-R", "-P", pattern)
+		// -H prints the file names when a single file is searched
+		grep := exec.Command("grep", "-o", "-n", "-H", "-R", "-P", pattern)
 		if ok, paths := providerContext.GetScopedFilepaths(); ok {
 			grep.Args = append(grep.Args, paths...)
 		} else {
@@ -2,13 +2,16 @@ package builtin
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"sync"
 	"testing"
 
 	"github.com/go-logr/logr/testr"
 	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/pathfilter"
 	"github.com/konveyor/analyzer-lsp/provider"
 )
 
@@ -68,6 +71,7 @@ func Test_builtinServiceClient_filterByIncludedPaths(t *testing.T) {
 		name          string
 		inputPath     string
 		includedPaths []string
+		excludedPaths []string
 		want          bool
 	}{
 		{
@@ -106,17 +110,28 @@ func Test_builtinServiceClient_filterByIncludedPaths(t *testing.T) {
 			includedPaths: []string{"////test/a/d//"},
 			want:          false,
 		},
+		{
+			name:          "input file path is excluded from the included path",
+			inputPath:     "/test/a/b/c/file_test.py",
+			includedPaths: []string{"/test/a/b"},
+			excludedPaths: []string{"/test/**/*_test.py"},
+			want:          false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			pathFilter, err := pathfilter.New("", tt.includedPaths, tt.excludedPaths)
+			if err != nil {
+				t.Fatal(err)
+			}
 			b := &builtinServiceClient{
 				config: provider.InitConfig{
 					ProviderSpecificConfig: map[string]interface{}{
 						"includedPaths": tt.includedPaths,
 					},
 				},
-				includedPaths: tt.includedPaths,
-				log:           testr.New(t),
+				pathFilter: pathFilter,
+				log:        testr.New(t),
 			}
 			if got := b.isFileIncluded(tt.inputPath); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("builtinServiceClient.filterByIncludedPaths() = %v, want %v", got, tt.want)
@@ -136,3 +151,723 @@ func BenchmarkRunOSSpecificGrepCommand(b *testing.B) {
 			provider.ProviderContext{Template: map[string]engine.ChainTemplate{}})
 	}
 }
+
+func Test_builtinServiceClient_Evaluate_xml(t *testing.T) {
+	location, err := filepath.Abs("./testdata/xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		condition     string
+		wantIncidents int
+		wantVariables []map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name: "namespace qualified query",
+			condition: `
+xml:
+  xpath: //j:servlet-class
+  namespaces:
+    j: http://xmlns.jcp.org/xml/ns/javaee
+`,
+			wantIncidents: 2,
+		},
+		{
+			name: "namespace qualified attribute",
+			condition: `
+xml:
+  xpath: /j:web-app
+  namespaces:
+    j: http://xmlns.jcp.org/xml/ns/javaee
+    xsi: http://www.w3.org/2001/XMLSchema-instance
+  attributes:
+    version: "^3\\."
+    xsi:schemaLocation: web-app_3_1\.xsd
+`,
+			wantIncidents: 1,
+		},
+		{
+			name: "attribute does not match",
+			condition: `
+xml:
+  xpath: /j:web-app
+  namespaces:
+    j: http://xmlns.jcp.org/xml/ns/javaee
+  attributes:
+    version: "^4\\."
+`,
+			wantIncidents: 0,
+		},
+		{
+			name: "captures values relative to the match",
+			condition: `
+xml:
+  xpath: //j:servlet[j:servlet-name='legacy']
+  namespaces:
+    j: http://xmlns.jcp.org/xml/ns/javaee
+  captures:
+    servletClass: j:servlet-class
+    version: string(ancestor::j:web-app/@version)
+`,
+			wantIncidents: 1,
+			wantVariables: []map[string]interface{}{
+				{"servletClass": "com.example.LegacyServlet", "version": "3.1"},
+			},
+		},
+		{
+			name: "invalid attribute pattern",
+			condition: `
+xml:
+  xpath: /j:web-app
+  attributes:
+    version: "(3"
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &builtinServiceClient{
+				config:        provider.InitConfig{Location: location},
+				log:           testr.New(t),
+				locationCache: make(map[string]float64),
+			}
+			got, err := b.Evaluate(context.TODO(), "xml", []byte(tt.condition))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("builtinServiceClient.Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got.Incidents) != tt.wantIncidents {
+				t.Fatalf("builtinServiceClient.Evaluate() got %d incidents, want %d", len(got.Incidents), tt.wantIncidents)
+			}
+			for i, vars := range tt.wantVariables {
+				for k, v := range vars {
+					if got.Incidents[i].Variables[k] != v {
+						t.Errorf("builtinServiceClient.Evaluate() variable %s = %v, want %v", k, got.Incidents[i].Variables[k], v)
+					}
+				}
+			}
+		})
+	}
+}
+
+func Test_builtinServiceClient_Evaluate_jsonpath(t *testing.T) {
+	location, err := filepath.Abs("./testdata/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		condition     string
+		scoped        bool
+		wantIncidents int
+		wantLines     []int
+		wantVariables []map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name: "member path",
+			condition: `
+jsonpath:
+  path: $.dependencies.moment
+`,
+			wantIncidents: 1,
+			wantLines:     []int{10},
+			wantVariables: []map[string]interface{}{
+				{"matchingPath": "$['dependencies']['moment']", "matchingJSON": `"^2.29.1"`},
+			},
+		},
+		{
+			name: "wildcard",
+			condition: `
+jsonpath:
+  path: $.scripts[*]
+`,
+			wantIncidents: 2,
+			wantLines:     []int{5, 6},
+		},
+		{
+			name: "recursive descent",
+			condition: `
+jsonpath:
+  path: $..name
+`,
+			wantIncidents: 3,
+			wantLines:     []int{2, 13, 14},
+		},
+		{
+			name: "filter on existence",
+			condition: `
+jsonpath:
+  path: $.contributors[?(@.email)].name
+`,
+			wantIncidents: 1,
+			wantVariables: []map[string]interface{}{
+				{"matchingPath": "$['contributors'][0]['name']", "matchingJSON": `"alice"`},
+			},
+		},
+		{
+			name: "filter with regex",
+			condition: `
+jsonpath:
+  path: $.contributors[?(@.name =~ '^b')]
+`,
+			wantIncidents: 1,
+			wantLines:     []int{14},
+		},
+		{
+			name: "no match",
+			condition: `
+jsonpath:
+  path: $.devDependencies
+`,
+			wantIncidents: 0,
+		},
+		{
+			name: "filepaths of the rule in the scoped filepaths",
+			condition: `
+jsonpath:
+  path: $.dependencies.moment
+  filepaths:
+    - package.json
+`,
+			scoped:        true,
+			wantIncidents: 1,
+		},
+		{
+			name: "filepaths of the rule not in the scoped filepaths",
+			condition: `
+jsonpath:
+  path: $.dependencies.moment
+  filepaths:
+    - tsconfig.json
+`,
+			scoped:        true,
+			wantIncidents: 0,
+		},
+		{
+			name: "invalid expression",
+			condition: `
+jsonpath:
+  path: dependencies
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &builtinServiceClient{
+				config:        provider.InitConfig{Location: location},
+				log:           testr.New(t),
+				locationCache: make(map[string]float64),
+			}
+			condition := tt.condition
+			if tt.scoped {
+				condition += fmt.Sprintf("template:\n  %s:\n    filepaths:\n      - %s\n",
+					engine.TemplateContextPathScopeKey, filepath.Join(location, "package.json"))
+			}
+			got, err := b.Evaluate(context.TODO(), "jsonpath", []byte(condition))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("builtinServiceClient.Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got.Incidents) != tt.wantIncidents {
+				t.Fatalf("builtinServiceClient.Evaluate() got %d incidents, want %d", len(got.Incidents), tt.wantIncidents)
+			}
+			for i, line := range tt.wantLines {
+				if got.Incidents[i].LineNumber == nil || *got.Incidents[i].LineNumber != line {
+					t.Errorf("builtinServiceClient.Evaluate() incident %d line = %v, want %d", i, got.Incidents[i].LineNumber, line)
+				}
+			}
+			for i, vars := range tt.wantVariables {
+				for k, v := range vars {
+					if got.Incidents[i].Variables[k] != v {
+						t.Errorf("builtinServiceClient.Evaluate() variable %s = %v, want %v", k, got.Incidents[i].Variables[k], v)
+					}
+				}
+			}
+		})
+	}
+}
+
+func Test_builtinServiceClient_Evaluate_projectFact(t *testing.T) {
+	tests := []struct {
+		name      string
+		location  string
+		condition string
+		wantMatch bool
+		wantValue string
+		wantErr   bool
+	}{
+		{
+			name:     "maven packaging",
+			location: "./testdata",
+			condition: `
+projectFact:
+  name: packaging
+  value: ^war$
+`,
+			wantMatch: true,
+			wantValue: "war",
+		},
+		{
+			name:     "maven jdk version resolved from properties",
+			location: "./testdata",
+			condition: `
+projectFact:
+  name: jdkVersion
+  upperbound: "1.8"
+`,
+			wantMatch: true,
+			wantValue: "8",
+		},
+		{
+			name:     "maven spring boot version from dependencies",
+			location: "./testdata",
+			condition: `
+projectFact:
+  name: springBootVersion
+  lowerbound: 3.0.0
+`,
+			wantMatch: false,
+		},
+		{
+			name:     "project without build files",
+			location: "./testdata/json",
+			condition: `
+projectFact:
+  name: buildTool
+`,
+			wantMatch: false,
+		},
+		{
+			name:     "gradle jdk version prefers target compatibility",
+			location: "./testdata/gradle",
+			condition: `
+projectFact:
+  name: jdkVersion
+  lowerbound: "11"
+`,
+			wantMatch: true,
+			wantValue: "11",
+		},
+		{
+			name:     "gradle spring boot version",
+			location: "./testdata/gradle",
+			condition: `
+projectFact:
+  name: springBootVersion
+  lowerbound: 2.0.0
+  upperbound: 2.7.99
+`,
+			wantMatch: true,
+			wantValue: "2.7.18",
+		},
+		{
+			name:     "gradle packaging",
+			location: "./testdata/gradle",
+			condition: `
+projectFact:
+  name: packaging
+`,
+			wantMatch: true,
+			wantValue: "war",
+		},
+		{
+			name:     "unknown fact",
+			location: "./testdata",
+			condition: `
+projectFact:
+  name: framework
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &builtinServiceClient{
+				config:        provider.InitConfig{Location: tt.location},
+				log:           testr.New(t),
+				locationCache: make(map[string]float64),
+			}
+			got, err := b.Evaluate(context.TODO(), "projectFact", []byte(tt.condition))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("builtinServiceClient.Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Matched != tt.wantMatch {
+				t.Fatalf("builtinServiceClient.Evaluate() matched = %v, want %v", got.Matched, tt.wantMatch)
+			}
+			if tt.wantMatch && got.Incidents[0].Variables["value"] != tt.wantValue {
+				t.Errorf("builtinServiceClient.Evaluate() value = %v, want %v", got.Incidents[0].Variables["value"], tt.wantValue)
+			}
+		})
+	}
+}
+
+func Test_builtinServiceClient_Evaluate_filePair(t *testing.T) {
+	location, err := filepath.Abs("./testdata/filepair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		condition string
+		wantFiles []string
+		wantPairs int
+		wantErr   bool
+	}{
+		{
+			name: "paired anywhere in the location",
+			condition: `
+filePair:
+  pattern: ^persistence\.xml$
+  pair: -ds\.xml$
+`,
+			wantFiles: []string{"a/META-INF/persistence.xml", "b/META-INF/persistence.xml"},
+			wantPairs: 1,
+		},
+		{
+			name: "paired in the same directory",
+			condition: `
+filePair:
+  pattern: ^persistence\.xml$
+  pair: -ds\.xml$
+  within: directory
+`,
+			wantFiles: []string{"a/META-INF/persistence.xml"},
+			wantPairs: 1,
+		},
+		{
+			name: "missing in the same directory",
+			condition: `
+filePair:
+  pattern: ^persistence\.xml$
+  pair: -ds\.xml$
+  within: directory
+  missing: true
+`,
+			wantFiles: []string{"b/META-INF/persistence.xml"},
+		},
+		{
+			name: "missing anywhere in the location",
+			condition: `
+filePair:
+  pattern: ^persistence\.xml$
+  pair: -ds\.xml$
+  missing: true
+`,
+			wantFiles: []string{},
+		},
+		{
+			name: "file is not paired with itself",
+			condition: `
+filePair:
+  pattern: ^persistence\.xml$
+  pair: \.xml$
+  within: directory
+  missing: true
+`,
+			wantFiles: []string{"b/META-INF/persistence.xml"},
+		},
+		{
+			name: "unknown within",
+			condition: `
+filePair:
+  pattern: ^persistence\.xml$
+  pair: -ds\.xml$
+  within: module
+`,
+			wantErr: true,
+		},
+		{
+			name: "pair is required",
+			condition: `
+filePair:
+  pattern: ^persistence\.xml$
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &builtinServiceClient{
+				config:        provider.InitConfig{Location: location},
+				log:           testr.New(t),
+				locationCache: make(map[string]float64),
+			}
+			got, err := b.Evaluate(context.TODO(), "filePair", []byte(tt.condition))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("builtinServiceClient.Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			files := []string{}
+			for _, incident := range got.Incidents {
+				rel, err := filepath.Rel(location, incident.FileURI.Filename())
+				if err != nil {
+					t.Fatal(err)
+				}
+				files = append(files, filepath.ToSlash(rel))
+				if pairs, _ := incident.Variables["pairs"].([]string); len(pairs) != tt.wantPairs {
+					t.Errorf("expected %d pairs of %s, got %v", tt.wantPairs, rel, pairs)
+				}
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("builtinServiceClient.Evaluate() files = %v, want %v", files, tt.wantFiles)
+			}
+			if got.Matched != (len(tt.wantFiles) > 0) {
+				t.Errorf("builtinServiceClient.Evaluate() matched = %v", got.Matched)
+			}
+		})
+	}
+}
+
+func Test_builtinServiceClient_Evaluate_filecontentMatcher(t *testing.T) {
+	location, err := filepath.Abs("./testdata/filecontent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		condition string
+		wantLines [][2]int
+		wantText  []string
+		wantErr   bool
+	}{
+		{
+			name: "annotation split across lines",
+			condition: `
+filecontent:
+  pattern: '@TransactionAttribute\(\s*TransactionAttributeType\.REQUIRES_NEW\s*\)'
+  filePattern: \.java$
+  multiline: true
+`,
+			wantLines: [][2]int{{9, 11}},
+			wantText:  []string{"@TransactionAttribute(\n        TransactionAttributeType.REQUIRES_NEW\n    )"},
+		},
+		{
+			name: "xml element formatted vertically",
+			condition: `
+filecontent:
+  pattern: <property\s+name="hibernate.dialect".*?/>
+  multiline: true
+`,
+			wantLines: [][2]int{{3, 5}},
+		},
+		{
+			name: "required context before the match",
+			condition: `
+filecontent:
+  pattern: public class \w+
+  matchesBefore: '@Stateless'
+`,
+			wantLines: [][2]int{{7, 7}},
+		},
+		{
+			name: "forbidden context before the match",
+			condition: `
+filecontent:
+  pattern: TransactionAttributeType\.\w+
+  filePattern: \.java$
+  notMatchesBefore: //
+  contextLines: 0
+`,
+			wantLines: [][2]int{{10, 10}},
+			wantText:  []string{"TransactionAttributeType.REQUIRES_NEW"},
+		},
+		{
+			name: "required context after the match",
+			condition: `
+filecontent:
+  pattern: public void \w+\(\)
+  matchesAfter: ^\s*}\s*$
+  contextLines: 1
+`,
+			wantLines: [][2]int{{12, 12}, {16, 16}},
+		},
+		{
+			name: "pcre only syntax is rejected",
+			condition: `
+filecontent:
+  pattern: (?<=@)Stateless
+  multiline: true
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &builtinServiceClient{
+				config:        provider.InitConfig{Location: location},
+				log:           testr.New(t),
+				locationCache: make(map[string]float64),
+			}
+			got, err := b.Evaluate(context.TODO(), "filecontent", []byte(tt.condition))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("builtinServiceClient.Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got.Incidents) != len(tt.wantLines) {
+				t.Fatalf("builtinServiceClient.Evaluate() got %d incidents, want %d", len(got.Incidents), len(tt.wantLines))
+			}
+			for i, lines := range tt.wantLines {
+				location := got.Incidents[i].CodeLocation
+				if int(location.StartPosition.Line) != lines[0] || int(location.EndPosition.Line) != lines[1] {
+					t.Errorf("builtinServiceClient.Evaluate() incident %d lines = %v-%v, want %v", i, location.StartPosition.Line, location.EndPosition.Line, lines)
+				}
+			}
+			for i, text := range tt.wantText {
+				if got.Incidents[i].Variables["matchingText"] != text {
+					t.Errorf("builtinServiceClient.Evaluate() matchingText = %q, want %q", got.Incidents[i].Variables["matchingText"], text)
+				}
+			}
+		})
+	}
+}
+
+func Test_builtinServiceClient_Evaluate_configKey(t *testing.T) {
+	location, err := filepath.Abs("./testdata/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		condition    string
+		want         []string
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name: "keys of properties files and their profiles",
+			condition: `
+configKey:
+  keyPattern: ^spring\.datasource\.url$
+`,
+			want: []string{
+				"src/main/resources/application-dev.properties:1 spring.datasource.url=jdbc:h2:file:./dev profile=dev",
+				"src/main/resources/application.properties:11 spring.datasource.url=jdbc:postgresql://db:5432/app profile=prod",
+				"src/main/resources/application.properties:2 spring.datasource.url=jdbc:h2:mem:test",
+			},
+			wantWarnings: 1,
+		},
+		{
+			name: "profile pattern",
+			condition: `
+configKey:
+  keyPattern: ^spring\.datasource\.url$
+  profile: ^prod$
+`,
+			want: []string{
+				"src/main/resources/application.properties:11 spring.datasource.url=jdbc:postgresql://db:5432/app profile=prod",
+			},
+			wantWarnings: 1,
+		},
+		{
+			name: "separators, continuations and escapes of properties files",
+			condition: `
+configKey:
+  keyPattern: ^(spring\.jpa|server|management|greeting)
+  filePattern: application\.properties$
+`,
+			want: []string{
+				"src/main/resources/application.properties:3 spring.jpa.hibernate.ddl-auto=update",
+				"src/main/resources/application.properties:4 server.servlet.context-path=/app",
+				"src/main/resources/application.properties:5 management.endpoints.web.exposure.include=health,info,metrics",
+				"src/main/resources/application.properties:8 greeting=café",
+			},
+		},
+		{
+			name: "value pattern matching a multi-line value of an ini file",
+			condition: `
+configKey:
+  keyPattern: ^database\.
+  valuePattern: useSSL=false
+`,
+			want:         []string{"deploy/app.ini:4 database.options=\nuseSSL=false\nserverTimezone=UTC"},
+			wantWarnings: 1,
+		},
+		{
+			name: "tables, inline tables and arrays of toml files",
+			condition: `
+configKey:
+  keyPattern: ^(tool|servers)
+  filePattern: pyproject\.toml$
+`,
+			want: []string{
+				"pyproject.toml:12 servers[0].host=alpha",
+				"pyproject.toml:13 servers[0].ports=[\"8000\",\"8001\"]",
+				"pyproject.toml:19 servers[1].host=beta",
+				"pyproject.toml:2 tool.poetry.name=app",
+				"pyproject.toml:3 tool.poetry.description=Sample application",
+				"pyproject.toml:8 tool.poetry.dependencies.python=^3.8",
+				"pyproject.toml:9 tool.poetry.dependencies.django.extras=[\"bcrypt\"]",
+				"pyproject.toml:9 tool.poetry.dependencies.django.version=3.2",
+			},
+		},
+		{
+			name: "env files",
+			condition: `
+configKey:
+  keyPattern: .*
+  filePattern: \.env
+`,
+			want: []string{
+				".env.production:2 DATABASE_URL=postgres://db:5432/app profile=production",
+				".env.production:3 PRIVATE_KEY=-----BEGIN KEY-----\nabc\n-----END KEY----- profile=production",
+				".env.production:6 DEBUG=false profile=production",
+			},
+		},
+		{
+			name: "entries before a parse error",
+			condition: `
+configKey:
+  keyPattern: ^title$
+`,
+			want:         []string{"broken.toml:1 title=ok"},
+			wantWarnings: 1,
+		},
+		{
+			name: "keyPattern is required",
+			condition: `
+configKey:
+  valuePattern: jdbc
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &builtinServiceClient{
+				config:        provider.InitConfig{Location: location},
+				log:           testr.New(t),
+				locationCache: make(map[string]float64),
+			}
+			got, err := b.Evaluate(context.TODO(), "configKey", []byte(tt.condition))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("builtinServiceClient.Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			incidents := []string{}
+			for _, incident := range got.Incidents {
+				rel, err := filepath.Rel(location, incident.FileURI.Filename())
+				if err != nil {
+					t.Fatal(err)
+				}
+				summary := fmt.Sprintf("%s:%d %s=%s", filepath.ToSlash(rel), *incident.LineNumber, incident.Variables["key"], incident.Variables["value"])
+				if profile, ok := incident.Variables["profile"]; ok {
+					summary += fmt.Sprintf(" profile=%s", profile)
+				}
+				incidents = append(incidents, summary)
+			}
+			sort.Strings(incidents)
+			if !reflect.DeepEqual(incidents, tt.want) {
+				t.Errorf("builtinServiceClient.Evaluate() incidents\n%q\nwant\n%q", incidents, tt.want)
+			}
+			if len(got.Warnings) != tt.wantWarnings {
+				t.Errorf("expected %d warnings, got %v", tt.wantWarnings, got.Warnings)
+			}
+		})
+	}
+}
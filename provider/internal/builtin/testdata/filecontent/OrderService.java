@@ -0,0 +1,18 @@
+package com.example;
+
+import javax.ejb.Stateless;
+import javax.ejb.TransactionAttribute;
+
+@Stateless
+public class OrderService {
+
+    @TransactionAttribute(
+        TransactionAttributeType.REQUIRES_NEW
+    )
+    public void create() {
+    }
+
+    // @TransactionAttribute(TransactionAttributeType.NEVER)
+    public void delete() {
+    }
+}
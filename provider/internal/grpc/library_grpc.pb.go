@@ -208,6 +208,7 @@ const (
 	ProviderService_Stop_FullMethodName               = "/provider.ProviderService/Stop"
 	ProviderService_GetDependencies_FullMethodName    = "/provider.ProviderService/GetDependencies"
 	ProviderService_GetDependenciesDAG_FullMethodName = "/provider.ProviderService/GetDependenciesDAG"
+	ProviderService_Progress_FullMethodName           = "/provider.ProviderService/Progress"
 )
 
 // ProviderServiceClient is the client API for ProviderService service.
@@ -220,6 +221,9 @@ type ProviderServiceClient interface {
 	Stop(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	GetDependencies(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*DependencyResponse, error)
 	GetDependenciesDAG(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*DependencyDAGResponse, error)
+	// Progress streams the progress of the provider until the stream is
+	// canceled, the analyzer streams it while initializing the provider
+	Progress(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (ProviderService_ProgressClient, error)
 }
 
 type providerServiceClient struct {
@@ -284,6 +288,38 @@ func (c *providerServiceClient) GetDependenciesDAG(ctx context.Context, in *Serv
 	return out, nil
 }
 
+func (c *providerServiceClient) Progress(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (ProviderService_ProgressClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ProviderService_ServiceDesc.Streams[0], ProviderService_Progress_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &providerServiceProgressClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProviderService_ProgressClient interface {
+	Recv() (*ProgressEvent, error)
+	grpc.ClientStream
+}
+
+type providerServiceProgressClient struct {
+	grpc.ClientStream
+}
+
+func (x *providerServiceProgressClient) Recv() (*ProgressEvent, error) {
+	m := new(ProgressEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ProviderServiceServer is the server API for ProviderService service.
 // All implementations must embed UnimplementedProviderServiceServer
 // for forward compatibility
@@ -294,6 +330,9 @@ type ProviderServiceServer interface {
 	Stop(context.Context, *ServiceRequest) (*emptypb.Empty, error)
 	GetDependencies(context.Context, *ServiceRequest) (*DependencyResponse, error)
 	GetDependenciesDAG(context.Context, *ServiceRequest) (*DependencyDAGResponse, error)
+	// Progress streams the progress of the provider until the stream is
+	// canceled, the analyzer streams it while initializing the provider
+	Progress(*emptypb.Empty, ProviderService_ProgressServer) error
 	mustEmbedUnimplementedProviderServiceServer()
 }
 
@@ -319,6 +358,9 @@ func (UnimplementedProviderServiceServer) GetDependencies(context.Context, *Serv
 func (UnimplementedProviderServiceServer) GetDependenciesDAG(context.Context, *ServiceRequest) (*DependencyDAGResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetDependenciesDAG not implemented")
 }
+func (UnimplementedProviderServiceServer) Progress(*emptypb.Empty, ProviderService_ProgressServer) error {
+	return status.Errorf(codes.Unimplemented, "method Progress not implemented")
+}
 func (UnimplementedProviderServiceServer) mustEmbedUnimplementedProviderServiceServer() {}
 
 // UnsafeProviderServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -440,6 +482,27 @@ func _ProviderService_GetDependenciesDAG_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProviderService_Progress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProviderServiceServer).Progress(m, &providerServiceProgressServer{stream})
+}
+
+type ProviderService_ProgressServer interface {
+	Send(*ProgressEvent) error
+	grpc.ServerStream
+}
+
+type providerServiceProgressServer struct {
+	grpc.ServerStream
+}
+
+func (x *providerServiceProgressServer) Send(m *ProgressEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // ProviderService_ServiceDesc is the grpc.ServiceDesc for ProviderService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -472,6 +535,12 @@ var ProviderService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ProviderService_GetDependenciesDAG_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Progress",
+			Handler:       _ProviderService_Progress_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "provider/internal/grpc/library.proto",
 }
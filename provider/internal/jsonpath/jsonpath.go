@@ -0,0 +1,554 @@
+// Package jsonpath evaluates jsonpath expressions, such as
+// $.spec.template.spec.containers[*].image, against decoded json documents.
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Path is a compiled jsonpath expression
+type Path []pathSegment
+
+// pathSegment is a single step of a jsonpath expression, descendant
+// segments (..) apply their selectors to the node and all of its descendants.
+type pathSegment struct {
+	descendant bool
+	selectors  []pathSelector
+}
+
+type pathSelector struct {
+	wildcard bool
+	name     *string
+	index    *int
+	slice    *pathSlice
+	filter   *pathFilter
+}
+
+type pathSlice struct {
+	start *int
+	end   *int
+	step  int
+}
+
+// pathFilter is a [?(@.path op value)] expression, an empty op only checks
+// that the relative path exists.
+type pathFilter struct {
+	path  Path
+	op    string
+	value interface{}
+	regex *regexp.Regexp
+}
+
+// Match is a value selected by a jsonpath expression along with its normalized path
+type Match struct {
+	Path  string
+	Value interface{}
+}
+
+var filterOperators = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+// Compile parses a jsonpath expression such as $.dependencies['react'] or
+// $..scripts[?(@.name =~ 'build.*')]
+func Compile(expr string) (Path, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("jsonpath expression must start with '$': %s", expr)
+	}
+	segments := []pathSegment{}
+	i := 1
+	for i < len(expr) {
+		segment := pathSegment{}
+		switch {
+		case strings.HasPrefix(expr[i:], ".."):
+			segment.descendant = true
+			i += 2
+			if i < len(expr) && expr[i] == '[' {
+				selectors, n, err := parseBracket(expr[i:])
+				if err != nil {
+					return nil, err
+				}
+				segment.selectors = selectors
+				i += n
+				break
+			}
+			selector, n, err := parseName(expr[i:])
+			if err != nil {
+				return nil, err
+			}
+			segment.selectors = []pathSelector{selector}
+			i += n
+		case expr[i] == '.':
+			i++
+			selector, n, err := parseName(expr[i:])
+			if err != nil {
+				return nil, err
+			}
+			segment.selectors = []pathSelector{selector}
+			i += n
+		case expr[i] == '[':
+			selectors, n, err := parseBracket(expr[i:])
+			if err != nil {
+				return nil, err
+			}
+			segment.selectors = selectors
+			i += n
+		default:
+			return nil, fmt.Errorf("unexpected character '%c' at position %d in jsonpath expression %s", expr[i], i, expr)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+func parseName(s string) (pathSelector, int, error) {
+	end := strings.IndexAny(s, ".[")
+	if end == -1 {
+		end = len(s)
+	}
+	name := strings.TrimSpace(s[:end])
+	if name == "" {
+		return pathSelector{}, 0, fmt.Errorf("missing member name in jsonpath expression")
+	}
+	if name == "*" {
+		return pathSelector{wildcard: true}, end, nil
+	}
+	return pathSelector{name: &name}, end, nil
+}
+
+// parseBracket parses a [...] selector, s must start with '['.
+// It returns the selectors and the number of characters consumed.
+func parseBracket(s string) ([]pathSelector, int, error) {
+	end := -1
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s) && end == -1; i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end == -1 {
+		return nil, 0, fmt.Errorf("unterminated bracket in jsonpath expression: %s", s)
+	}
+	content := strings.TrimSpace(s[1:end])
+	if strings.HasPrefix(content, "?") {
+		filter, err := parseFilter(content[1:])
+		if err != nil {
+			return nil, 0, err
+		}
+		return []pathSelector{{filter: filter}}, end + 1, nil
+	}
+	selectors := []pathSelector{}
+	for _, item := range splitUnion(content) {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "*":
+			selectors = append(selectors, pathSelector{wildcard: true})
+		case isQuoted(item):
+			name, err := unquote(item)
+			if err != nil {
+				return nil, 0, err
+			}
+			selectors = append(selectors, pathSelector{name: &name})
+		case strings.Contains(item, ":"):
+			slice, err := parseSlice(item)
+			if err != nil {
+				return nil, 0, err
+			}
+			selectors = append(selectors, pathSelector{slice: slice})
+		default:
+			index, err := strconv.Atoi(item)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid selector '%s' in jsonpath expression", item)
+			}
+			selectors = append(selectors, pathSelector{index: &index})
+		}
+	}
+	return selectors, end + 1, nil
+}
+
+func splitUnion(s string) []string {
+	items := []string{}
+	var quote byte
+	last := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ',':
+			items = append(items, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(items, s[last:])
+}
+
+func parseSlice(s string) (*pathSlice, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid slice '%s' in jsonpath expression", s)
+	}
+	slice := &pathSlice{step: 1}
+	bounds := []**int{&slice.start, &slice.end}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slice '%s' in jsonpath expression", s)
+		}
+		if i == 2 {
+			if v <= 0 {
+				return nil, fmt.Errorf("slice step must be positive in jsonpath expression: %s", s)
+			}
+			slice.step = v
+			continue
+		}
+		*bounds[i] = &v
+	}
+	return slice, nil
+}
+
+func parseFilter(s string) (*pathFilter, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	left, op, right := s, "", ""
+	var quote byte
+	for i := 0; i < len(s) && op == ""; i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '\'' || c == '"' {
+			quote = c
+			continue
+		}
+		for _, candidate := range filterOperators {
+			if strings.HasPrefix(s[i:], candidate) {
+				left, op, right = strings.TrimSpace(s[:i]), candidate, strings.TrimSpace(s[i+len(candidate):])
+				break
+			}
+		}
+	}
+	if !strings.HasPrefix(left, "@") {
+		return nil, fmt.Errorf("filter must start with '@' in jsonpath expression: %s", s)
+	}
+	path, err := Compile("$" + left[1:])
+	if err != nil {
+		return nil, err
+	}
+	filter := &pathFilter{path: path, op: op}
+	if op == "" {
+		return filter, nil
+	}
+	switch {
+	case isQuoted(right):
+		value, err := unquote(right)
+		if err != nil {
+			return nil, err
+		}
+		filter.value = value
+	case right == "true" || right == "false":
+		filter.value = right == "true"
+	case right == "null":
+		filter.value = nil
+	default:
+		value, err := strconv.ParseFloat(right, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' in jsonpath filter", right)
+		}
+		filter.value = value
+	}
+	if op == "=~" {
+		pattern, ok := filter.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("regex in jsonpath filter must be a string: %s", right)
+		}
+		filter.regex, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex in jsonpath filter: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+func isQuoted(s string) bool {
+	return len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]
+}
+
+func unquote(s string) (string, error) {
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	unquoted, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s in jsonpath expression", s)
+	}
+	return unquoted, nil
+}
+
+// Evaluate returns all the values of doc selected by the compiled expression
+func (segments Path) Evaluate(doc interface{}) []Match {
+	current := []Match{{Path: "$", Value: doc}}
+	for _, segment := range segments {
+		next := []Match{}
+		for _, match := range current {
+			nodes := []Match{match}
+			if segment.descendant {
+				nodes = descendantsOf(match)
+			}
+			for _, node := range nodes {
+				for _, selector := range segment.selectors {
+					next = append(next, selector.apply(node)...)
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+func (s pathSelector) apply(node Match) []Match {
+	switch {
+	case s.name != nil:
+		if object, ok := node.Value.(map[string]interface{}); ok {
+			if value, ok := object[*s.name]; ok {
+				return []Match{{Path: Member(node.Path, *s.name), Value: value}}
+			}
+		}
+	case s.wildcard:
+		return childrenOf(node)
+	case s.index != nil:
+		if array, ok := node.Value.([]interface{}); ok {
+			index := *s.index
+			if index < 0 {
+				index += len(array)
+			}
+			if index >= 0 && index < len(array) {
+				return []Match{{Path: Element(node.Path, index), Value: array[index]}}
+			}
+		}
+	case s.slice != nil:
+		array, ok := node.Value.([]interface{})
+		if !ok {
+			return nil
+		}
+		start, end := 0, len(array)
+		if s.slice.start != nil {
+			start = normalizeIndex(*s.slice.start, len(array))
+		}
+		if s.slice.end != nil {
+			end = normalizeIndex(*s.slice.end, len(array))
+		}
+		matches := []Match{}
+		for i := start; i < end; i += s.slice.step {
+			matches = append(matches, Match{Path: Element(node.Path, i), Value: array[i]})
+		}
+		return matches
+	case s.filter != nil:
+		matches := []Match{}
+		for _, child := range childrenOf(node) {
+			if s.filter.matches(child.Value) {
+				matches = append(matches, child)
+			}
+		}
+		return matches
+	}
+	return nil
+}
+
+func normalizeIndex(index, length int) int {
+	if index < 0 {
+		index += length
+	}
+	if index < 0 {
+		return 0
+	}
+	if index > length {
+		return length
+	}
+	return index
+}
+
+func (f *pathFilter) matches(value interface{}) bool {
+	results := f.path.Evaluate(value)
+	if f.op == "" {
+		return len(results) > 0
+	}
+	if len(results) == 0 {
+		return false
+	}
+	left := results[0].Value
+	switch f.op {
+	case "==":
+		return left == f.value
+	case "!=":
+		return left != f.value
+	case "=~":
+		s, ok := left.(string)
+		return ok && f.regex.MatchString(s)
+	}
+	switch l := left.(type) {
+	case float64:
+		r, ok := f.value.(float64)
+		if !ok {
+			return false
+		}
+		return compareOrdered(f.op, l < r, l == r)
+	case string:
+		r, ok := f.value.(string)
+		if !ok {
+			return false
+		}
+		return compareOrdered(f.op, l < r, l == r)
+	}
+	return false
+}
+
+func compareOrdered(op string, less, equal bool) bool {
+	switch op {
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	case ">=":
+		return !less
+	}
+	return false
+}
+
+func childrenOf(node Match) []Match {
+	children := []Match{}
+	switch v := node.Value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			children = append(children, Match{Path: Member(node.Path, k), Value: v[k]})
+		}
+	case []interface{}:
+		for i, e := range v {
+			children = append(children, Match{Path: Element(node.Path, i), Value: e})
+		}
+	}
+	return children
+}
+
+// descendantsOf returns the node followed by all of its descendants
+func descendantsOf(node Match) []Match {
+	nodes := []Match{node}
+	for _, child := range childrenOf(node) {
+		nodes = append(nodes, descendantsOf(child)...)
+	}
+	return nodes
+}
+
+// Member returns the normalized path of a member of an object, such as
+// $['spec']['replicas']
+func Member(parent, name string) string {
+	name = strings.ReplaceAll(name, `\`, `\\`)
+	name = strings.ReplaceAll(name, `'`, `\'`)
+	return fmt.Sprintf("%s['%s']", parent, name)
+}
+
+// Element returns the normalized path of an element of an array, such as
+// $['items'][0]
+func Element(parent string, index int) string {
+	return fmt.Sprintf("%s[%d]", parent, index)
+}
+
+// Lines maps the normalized path of every value in a json document
+// to the line the value starts on.
+func Lines(content []byte) (map[string]int, error) {
+	type frame struct {
+		path      string
+		array     bool
+		index     int
+		key       string
+		expectKey bool
+	}
+	lines := map[string]int{}
+	stack := []*frame{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if top != nil && top.expectKey {
+			top.key, _ = token.(string)
+			top.expectKey = false
+			continue
+		}
+		path := "$"
+		if top != nil {
+			if top.array {
+				path = Element(top.path, top.index)
+				top.index++
+			} else {
+				path = Member(top.path, top.key)
+				top.expectKey = true
+			}
+		}
+		start := int(offset)
+		for start < len(content) && strings.ContainsRune(" \t\r\n,:", rune(content[start])) {
+			start++
+		}
+		lines[path] = bytes.Count(content[:start], []byte("\n")) + 1
+		if delim, ok := token.(json.Delim); ok {
+			stack = append(stack, &frame{path: path, array: delim == '[', expectKey: delim == '{'})
+		}
+	}
+}
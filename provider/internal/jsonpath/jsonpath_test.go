@@ -0,0 +1,252 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const testDocument = `{
+  "name": "store",
+  "books": [
+    {"title": "Dune", "price": 9.5, "tags": ["scifi"]},
+    {"title": "Emma", "price": 12, "isbn": "0-14-143958-7"},
+    {"title": "Ubik", "price": 7.25, "tags": ["scifi", "classic"]},
+    {"title": "It's", "price": 20, "available": false}
+  ],
+  "owner": {"name": "alice", "it's": true}
+}`
+
+func TestPath_Evaluate(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(testDocument), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		expr string
+		want []string
+	}{
+		{
+			name: "root",
+			expr: "$",
+			want: []string{"$"},
+		},
+		{
+			name: "dot member",
+			expr: "$.owner.name",
+			want: []string{"$['owner']['name']"},
+		},
+		{
+			name: "bracket member with escaped quote",
+			expr: `$.owner['it\'s']`,
+			want: []string{`$['owner']['it\'s']`},
+		},
+		{
+			name: "missing member",
+			expr: "$.owner.email",
+			want: []string{},
+		},
+		{
+			name: "wildcard on an object in key order",
+			expr: "$.owner.*",
+			want: []string{`$['owner']['it\'s']`, "$['owner']['name']"},
+		},
+		{
+			name: "wildcard on an array",
+			expr: "$.books[*].title",
+			want: []string{"$['books'][0]['title']", "$['books'][1]['title']", "$['books'][2]['title']", "$['books'][3]['title']"},
+		},
+		{
+			name: "index",
+			expr: "$.books[1].title",
+			want: []string{"$['books'][1]['title']"},
+		},
+		{
+			name: "negative index",
+			expr: "$.books[-1].title",
+			want: []string{"$['books'][3]['title']"},
+		},
+		{
+			name: "index out of range",
+			expr: "$.books[4]",
+			want: []string{},
+		},
+		{
+			name: "union of indexes and names",
+			expr: "$.books[0,2]['title','price']",
+			want: []string{"$['books'][0]['title']", "$['books'][0]['price']", "$['books'][2]['title']", "$['books'][2]['price']"},
+		},
+		{
+			name: "slice",
+			expr: "$.books[1:3]",
+			want: []string{"$['books'][1]", "$['books'][2]"},
+		},
+		{
+			name: "slice with step",
+			expr: "$.books[::2]",
+			want: []string{"$['books'][0]", "$['books'][2]"},
+		},
+		{
+			name: "slice with negative start",
+			expr: "$.books[-2:]",
+			want: []string{"$['books'][2]", "$['books'][3]"},
+		},
+		{
+			name: "slice bounds are clamped",
+			expr: "$.books[-10:10]",
+			want: []string{"$['books'][0]", "$['books'][1]", "$['books'][2]", "$['books'][3]"},
+		},
+		{
+			name: "recursive descent",
+			expr: "$..name",
+			want: []string{"$['name']", "$['owner']['name']"},
+		},
+		{
+			name: "recursive descent with a bracket",
+			expr: "$..tags[0]",
+			want: []string{"$['books'][0]['tags'][0]", "$['books'][2]['tags'][0]"},
+		},
+		{
+			name: "recursive descent with a wildcard",
+			expr: "$.books[2]..*",
+			want: []string{"$['books'][2]['price']", "$['books'][2]['tags']", "$['books'][2]['title']", "$['books'][2]['tags'][0]", "$['books'][2]['tags'][1]"},
+		},
+		{
+			name: "filter on existence",
+			expr: "$.books[?(@.tags)].title",
+			want: []string{"$['books'][0]['title']", "$['books'][2]['title']"},
+		},
+		{
+			name: "filter on a nested path",
+			expr: "$.books[?(@.tags[1])].title",
+			want: []string{"$['books'][2]['title']"},
+		},
+		{
+			name: "filter with string equality",
+			expr: `$.books[?(@.title == "Emma")]`,
+			want: []string{"$['books'][1]"},
+		},
+		{
+			name: "filter with string inequality",
+			expr: "$.books[?(@.title != 'Emma')].price",
+			want: []string{"$['books'][0]['price']", "$['books'][2]['price']", "$['books'][3]['price']"},
+		},
+		{
+			name: "filter with a quoted operator in the value",
+			expr: "$.books[?(@.title == 'It\\'s')]",
+			want: []string{"$['books'][3]"},
+		},
+		{
+			name: "filter with number comparison",
+			expr: "$.books[?(@.price < 10)].title",
+			want: []string{"$['books'][0]['title']", "$['books'][2]['title']"},
+		},
+		{
+			name: "filter with inclusive number comparison",
+			expr: "$.books[?(@.price >= 12)].title",
+			want: []string{"$['books'][1]['title']", "$['books'][3]['title']"},
+		},
+		{
+			name: "filter with boolean",
+			expr: "$.books[?(@.available == false)].title",
+			want: []string{"$['books'][3]['title']"},
+		},
+		{
+			name: "filter with regex",
+			expr: "$.books[?(@.isbn =~ '^0-14')].title",
+			want: []string{"$['books'][1]['title']"},
+		},
+		{
+			name: "filter does not compare different types",
+			expr: "$.books[?(@.price > 'a')]",
+			want: []string{},
+		},
+		{
+			name: "filter on the current node",
+			expr: "$..tags[?(@ == 'classic')]",
+			want: []string{"$['books'][2]['tags'][1]"},
+		},
+		{
+			name: "recursive descent with a filter",
+			expr: "$..[?(@.price == 20)].title",
+			want: []string{"$['books'][3]['title']"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile(%s) error = %v", tt.expr, err)
+			}
+			got := []string{}
+			for _, match := range path.Evaluate(doc) {
+				got = append(got, match.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate(%s) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{name: "missing root", expr: "books[0]"},
+		{name: "missing member name", expr: "$.books."},
+		{name: "unterminated bracket", expr: "$.books[0"},
+		{name: "invalid index", expr: "$.books[first]"},
+		{name: "invalid slice", expr: "$.books[1:2:3:4]"},
+		{name: "negative slice step", expr: "$.books[::-1]"},
+		{name: "filter without current node", expr: "$.books[?(price > 10)]"},
+		{name: "invalid filter value", expr: "$.books[?(@.price > ten)]"},
+		{name: "regex that is not a string", expr: "$.books[?(@.title =~ 10)]"},
+		{name: "invalid regex", expr: "$.books[?(@.title =~ '(')]"},
+		{name: "unexpected character", expr: "$books"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.expr); err == nil {
+				t.Errorf("Compile(%s) expected an error", tt.expr)
+			}
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	lines, err := Lines([]byte(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		"$":                        1,
+		"$['name']":                2,
+		"$['books']":               3,
+		"$['books'][1]":            5,
+		"$['books'][2]['tags'][1]": 6,
+		"$['owner']":               9,
+		`$['owner']['it\'s']`:      9,
+	}
+	for path, line := range want {
+		if lines[path] != line {
+			t.Errorf("Lines() line of %s = %d, want %d", path, lines[path], line)
+		}
+	}
+	// every match of an expression has a line
+	var doc interface{}
+	if err := json.Unmarshal([]byte(testDocument), &doc); err != nil {
+		t.Fatal(err)
+	}
+	path, err := Compile("$..*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, match := range path.Evaluate(doc) {
+		if _, ok := lines[match.Path]; !ok {
+			t.Errorf("Lines() has no line for %s", match.Path)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// isChartFile reports whether a file belongs to a Helm chart rather than
+// being a manifest, the templates and the subcharts of the charts are
+func isChartFile(charts []string, file string) bool {
+	for _, chart := range charts {
+		for _, dir := range []string{filepath.Join(chart, "templates"), filepath.Join(chart, "charts")} {
+			if strings.HasPrefix(file, dir+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renderChart renders a chart with helm template and its default values, the
+// resources are declared in the templates they are rendered from. The lines
+// of the rendered values aren't the ones of the templates, the resources are
+// declared at the line of their kind in the template.
+func renderChart(ctx context.Context, helmPath, dir string) ([]*resource, []string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, helmPath, "template", "release", dir)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, nil, fmt.Errorf("unable to render helm chart %s: %w, %s", dir, err, strings.TrimSpace(stderr.String()))
+	}
+	resources := []*resource{}
+	warnings := []string{}
+	for _, doc := range splitDocuments(stdout.String()) {
+		template := ""
+		for _, line := range strings.Split(doc.Content, "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "# Source: ") {
+				// the path starts with the name of the chart
+				if _, path, found := strings.Cut(strings.TrimPrefix(line, "# Source: "), "/"); found {
+					template = filepath.Join(dir, filepath.FromSlash(path))
+				}
+				break
+			}
+		}
+		if template == "" {
+			continue
+		}
+		value, _, err := parseDocument(template, doc)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("unable to parse document rendered from %s, skipped it: %v", template, err))
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, r := range objectResources(object, map[string]origin{}, "$") {
+			r.Origins = map[string]origin{"$": {File: template, Line: templateLine(template, r.Kind())}}
+			resources = append(resources, r)
+		}
+	}
+	return resources, warnings, nil
+}
+
+// templateLine returns the line of a template declaring a resource of a kind,
+// the first apiVersion key when the kind is templated too
+func templateLine(template, kind string) int {
+	content, err := os.ReadFile(template)
+	if err != nil {
+		return 1
+	}
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "kind: "+kind {
+			return i + 1
+		}
+	}
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "apiVersion:") {
+			return i + 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,590 @@
+package k8s
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider/internal/jsonpath"
+	"gopkg.in/yaml.v3"
+)
+
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// kustomization is the part of a kustomization.yaml the provider understands,
+// the other transformers and generators are ignored
+type kustomization struct {
+	Resources             []string          `yaml:"resources"`
+	Bases                 []string          `yaml:"bases"`
+	Namespace             string            `yaml:"namespace"`
+	NamePrefix            string            `yaml:"namePrefix"`
+	NameSuffix            string            `yaml:"nameSuffix"`
+	CommonLabels          map[string]string `yaml:"commonLabels"`
+	CommonAnnotations     map[string]string `yaml:"commonAnnotations"`
+	PatchesStrategicMerge []string          `yaml:"patchesStrategicMerge"`
+	PatchesJSON6902       []kustomizePatch  `yaml:"patchesJson6902"`
+	Patches               []kustomizePatch  `yaml:"patches"`
+	Images                []kustomizeImage  `yaml:"images"`
+}
+
+type kustomizePatch struct {
+	Path   string           `yaml:"path"`
+	Patch  string           `yaml:"patch"`
+	Target *kustomizeTarget `yaml:"target"`
+	// line is the line of the kustomization an inline patch starts at
+	line int
+}
+
+type kustomizeTarget struct {
+	Group     string `yaml:"group"`
+	Version   string `yaml:"version"`
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+type kustomizeImage struct {
+	Name    string `yaml:"name"`
+	NewName string `yaml:"newName"`
+	NewTag  string `yaml:"newTag"`
+	Digest  string `yaml:"digest"`
+}
+
+// kustomizationFile returns the kustomization file of a directory, empty when
+// it isn't a kustomization
+func kustomizationFile(dir string) string {
+	for _, name := range kustomizationFiles {
+		path := filepath.Join(dir, name)
+		if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// kustomizeBuilder builds the resources of kustomizations, the resources of
+// the files are parsed once. The files and kustomizations used by other
+// kustomizations are recorded, they are analyzed as part of them.
+type kustomizeBuilder struct {
+	manifests func(file string) []*resource
+	warnings  []string
+	used      map[string]bool
+}
+
+// build returns the resources of a kustomization with its transformations
+// applied, building its bases first
+func (b *kustomizeBuilder) build(dir string, visiting map[string]bool) []*resource {
+	if visiting[dir] {
+		b.warn("kustomization %s is included in itself, skipped it", dir)
+		return nil
+	}
+	visiting[dir] = true
+	defer delete(visiting, dir)
+
+	file := kustomizationFile(dir)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		b.warn("unable to read kustomization %s: %v", file, err)
+		return nil
+	}
+	k := kustomization{}
+	if err := yaml.Unmarshal(content, &k); err != nil {
+		b.warn("unable to parse kustomization %s: %v", file, err)
+		return nil
+	}
+	setInlinePatchLines(content, "patchesJson6902", k.PatchesJSON6902)
+	setInlinePatchLines(content, "patches", k.Patches)
+
+	resources := []*resource{}
+	for _, entry := range append(append([]string{}, k.Bases...), k.Resources...) {
+		if strings.Contains(entry, "://") || strings.HasPrefix(entry, "github.com/") {
+			b.warn("remote resource %s of kustomization %s is not supported, skipped it", entry, file)
+			continue
+		}
+		path := filepath.Join(dir, entry)
+		stat, err := os.Stat(path)
+		if err != nil {
+			b.warn("unable to find resource %s of kustomization %s", entry, file)
+			continue
+		}
+		b.used[path] = true
+		if !stat.IsDir() {
+			for _, r := range b.manifests(path) {
+				resources = append(resources, r.copy())
+			}
+			continue
+		}
+		if kustomizationFile(path) == "" {
+			b.warn("resource %s of kustomization %s is a directory without a kustomization, skipped it", entry, file)
+			continue
+		}
+		resources = append(resources, b.build(path, visiting)...)
+	}
+
+	for _, entry := range k.PatchesStrategicMerge {
+		b.applyStrategicMergePatch(resources, file, dir, kustomizePatch{Path: entry}, nil)
+	}
+	for _, patch := range k.PatchesJSON6902 {
+		b.applyPatch(resources, file, dir, patch)
+	}
+	for _, patch := range k.Patches {
+		b.applyPatch(resources, file, dir, patch)
+	}
+
+	for _, r := range resources {
+		if k.Namespace != "" && !clusterScoped(r.Kind()) {
+			r.setMetadata("namespace", k.Namespace)
+		}
+		if k.NamePrefix != "" || k.NameSuffix != "" {
+			r.setMetadata("name", k.NamePrefix+r.Name()+k.NameSuffix)
+		}
+		addMetadataMap(r, "labels", k.CommonLabels)
+		addMetadataMap(r, "annotations", k.CommonAnnotations)
+		for _, image := range k.Images {
+			setImages(r, image)
+		}
+	}
+	return resources
+}
+
+func (b *kustomizeBuilder) warn(format string, args ...interface{}) {
+	b.warnings = append(b.warnings, fmt.Sprintf(format, args...))
+}
+
+// patchContent returns the content of a patch, inline or in a file, along with
+// the file and the line it starts at
+func (b *kustomizeBuilder) patchContent(kustomizationFile, dir string, patch kustomizePatch) (string, string, int, error) {
+	if patch.Patch != "" {
+		return patch.Patch, kustomizationFile, patch.line, nil
+	}
+	path := filepath.Join(dir, patch.Path)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		// patchesStrategicMerge entries may be inline patches too
+		if strings.Contains(patch.Path, "\n") {
+			return patch.Path, kustomizationFile, 1, nil
+		}
+		return "", "", 0, err
+	}
+	b.used[path] = true
+	return string(content), path, 1, nil
+}
+
+// setInlinePatchLines sets the lines the inline patches of a list of patches
+// of a kustomization start at
+func setInlinePatchLines(content []byte, key string, patches []kustomizePatch) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil || len(node.Content) == 0 {
+		return
+	}
+	root := node.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != key || root.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		for j, item := range root.Content[i+1].Content {
+			if j >= len(patches) {
+				break
+			}
+			for k := 0; k+1 < len(item.Content); k += 2 {
+				if item.Content[k].Value != "patch" {
+					continue
+				}
+				value := item.Content[k+1]
+				patches[j].line = value.Line
+				// the content of a block scalar starts on the line after |
+				if value.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+					patches[j].line++
+				}
+			}
+		}
+	}
+}
+
+// applyPatch applies a patch of the patches or patchesJson6902 of a
+// kustomization, a list of operations is a JSON 6902 patch, the others
+// strategic merge patches
+func (b *kustomizeBuilder) applyPatch(resources []*resource, file, dir string, patch kustomizePatch) {
+	content, patchFile, startLine, err := b.patchContent(file, dir, patch)
+	if err != nil {
+		b.warn("unable to read patch %s of kustomization %s: %v", patch.Path, file, err)
+		return
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil || len(node.Content) == 0 {
+		b.warn("unable to parse patch %s of kustomization %s: %v", patch.Path, file, err)
+		return
+	}
+	if node.Content[0].Kind != yaml.SequenceNode {
+		b.applyStrategicMergePatch(resources, file, dir, patch, patch.Target)
+		return
+	}
+	if patch.Target == nil {
+		b.warn("json patch %s of kustomization %s has no target, skipped it", patch.Path, file)
+		return
+	}
+	ops := []jsonPatchOperation{}
+	if err := node.Content[0].Decode(&ops); err != nil {
+		b.warn("unable to parse json patch %s of kustomization %s: %v", patch.Path, file, err)
+		return
+	}
+	for i := range ops {
+		ops[i].Line = node.Content[0].Content[i].Line + startLine - 1
+	}
+	for _, r := range resources {
+		if !patch.Target.matches(r) {
+			continue
+		}
+		for _, op := range ops {
+			if err := op.apply(r, patchFile); err != nil {
+				b.warn("unable to apply json patch %s of kustomization %s to %s %s: %v", patch.Path, file, r.Kind(), r.Name(), err)
+			}
+		}
+	}
+}
+
+// applyStrategicMergePatch merges the documents of a patch into the resources
+// they target, the resource of the same kind and name unless there is a
+// target
+func (b *kustomizeBuilder) applyStrategicMergePatch(resources []*resource, file, dir string, patch kustomizePatch, target *kustomizeTarget) {
+	content, patchFile, startLine, err := b.patchContent(file, dir, patch)
+	if err != nil {
+		b.warn("unable to read patch %s of kustomization %s: %v", patch.Path, file, err)
+		return
+	}
+	for _, doc := range splitDocuments(content) {
+		doc.StartLine += startLine - 1
+		value, origins, err := parseDocument(patchFile, doc)
+		if err != nil {
+			b.warn("unable to parse patch %s of kustomization %s: %v", patch.Path, file, err)
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		patchResource := &resource{Object: object, Origins: origins}
+		for _, r := range resources {
+			if target != nil {
+				if !target.matches(r) {
+					continue
+				}
+			} else if r.Kind() != patchResource.Kind() || r.Name() != patchResource.Name() {
+				continue
+			}
+			for key, value := range object {
+				if key == "apiVersion" || key == "kind" {
+					continue
+				}
+				path := jsonpath.Member("$", key)
+				r.Object[key] = mergeValue(r, r.Object[key], value, path, path, origins)
+			}
+		}
+	}
+}
+
+// mergeValue merges a value of a strategic merge patch into the value of a
+// resource. Objects are merged, the lists of objects with a name, such as
+// containers, are merged by name, the other values are replaced. A null
+// value removes the key. The origins of the values of the patch are recorded.
+func mergeValue(r *resource, current, patch interface{}, path, patchPath string, patchOrigins map[string]origin) interface{} {
+	r.Origins[path] = patchOrigins[patchPath]
+	switch p := patch.(type) {
+	case map[string]interface{}:
+		c, ok := current.(map[string]interface{})
+		if !ok {
+			copyOrigins(r, path, patchPath, patchOrigins)
+			return deepCopy(p)
+		}
+		for key, value := range p {
+			if value == nil {
+				delete(c, key)
+				continue
+			}
+			c[key] = mergeValue(r, c[key], value, jsonpath.Member(path, key), jsonpath.Member(patchPath, key), patchOrigins)
+		}
+		return c
+	case []interface{}:
+		c, ok := current.([]interface{})
+		if !ok || !namedObjects(p) || !namedObjects(c) {
+			copyOrigins(r, path, patchPath, patchOrigins)
+			return deepCopy(p)
+		}
+		for i, item := range p {
+			name := item.(map[string]interface{})["name"]
+			merged := false
+			for j, existing := range c {
+				if existing.(map[string]interface{})["name"] == name {
+					c[j] = mergeValue(r, existing, item, jsonpath.Element(path, j), jsonpath.Element(patchPath, i), patchOrigins)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				copyOrigins(r, jsonpath.Element(path, len(c)), jsonpath.Element(patchPath, i), patchOrigins)
+				c = append(c, deepCopy(item))
+			}
+		}
+		return c
+	}
+	return patch
+}
+
+// namedObjects reports whether the items of a list are objects with a name
+func namedObjects(items []interface{}) bool {
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := object["name"].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// copyOrigins records the origins of the values of the patch under patchPath
+// as the ones of the values of the resource under path
+func copyOrigins(r *resource, path, patchPath string, patchOrigins map[string]origin) {
+	for p, o := range patchOrigins {
+		if p == patchPath {
+			r.Origins[path] = o
+		} else if strings.HasPrefix(p, patchPath+"[") {
+			r.Origins[path+p[len(patchPath):]] = o
+		}
+	}
+}
+
+// jsonPatchOperation is an operation of a JSON 6902 patch, the add, replace and
+// remove operations are supported
+type jsonPatchOperation struct {
+	Op    string      `yaml:"op"`
+	Path  string      `yaml:"path"`
+	Value interface{} `yaml:"value"`
+	Line  int         `yaml:"-"`
+}
+
+func (op jsonPatchOperation) apply(r *resource, file string) error {
+	tokens := strings.Split(strings.TrimPrefix(op.Path, "/"), "/")
+	for i := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tokens[i], "~1", "/"), "~0", "~")
+	}
+	if op.Path == "" || op.Path == "/" || len(tokens) == 0 {
+		return fmt.Errorf("unsupported path %q", op.Path)
+	}
+	value := jsonValueTree(op.Value)
+	var parent interface{} = r.Object
+	path := "$"
+	for _, token := range tokens[:len(tokens)-1] {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			parent, path = p[token], jsonpath.Member(path, token)
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(p) {
+				return fmt.Errorf("invalid index %s of path %s", token, op.Path)
+			}
+			parent, path = p[index], jsonpath.Element(path, index)
+		default:
+			return fmt.Errorf("unable to find path %s", op.Path)
+		}
+	}
+	last := tokens[len(tokens)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		path = jsonpath.Member(path, last)
+		switch op.Op {
+		case "add", "replace":
+			p[last] = value
+		case "remove":
+			delete(p, last)
+			return nil
+		default:
+			return fmt.Errorf("unsupported operation %s", op.Op)
+		}
+	case []interface{}:
+		index := len(p)
+		if last != "-" {
+			var err error
+			if index, err = strconv.Atoi(last); err != nil || index < 0 || index > len(p) {
+				return fmt.Errorf("invalid index %s of path %s", last, op.Path)
+			}
+		}
+		switch {
+		case op.Op == "add":
+			p = append(p[:index], append([]interface{}{value}, p[index:]...)...)
+		case op.Op == "replace" && index < len(p):
+			p[index] = value
+		case op.Op == "remove" && index < len(p):
+			p = append(p[:index], p[index+1:]...)
+		default:
+			return fmt.Errorf("unsupported operation %s on path %s", op.Op, op.Path)
+		}
+		if err := setAt(r.Object, tokens[:len(tokens)-1], p); err != nil {
+			return err
+		}
+		if op.Op == "remove" {
+			return nil
+		}
+		path = jsonpath.Element(path, index)
+	default:
+		return fmt.Errorf("unable to find path %s", op.Path)
+	}
+	r.Origins[path] = origin{File: file, Line: op.Line}
+	return nil
+}
+
+// setAt replaces the value at the tokens of a path, the lists are replaced
+// when they grow or shrink
+func setAt(object map[string]interface{}, tokens []string, value interface{}) error {
+	if len(tokens) == 0 {
+		return fmt.Errorf("unable to replace the resource")
+	}
+	var parent interface{} = object
+	for _, token := range tokens[:len(tokens)-1] {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			parent = p[token]
+		case []interface{}:
+			index, _ := strconv.Atoi(token)
+			parent = p[index]
+		}
+	}
+	last := tokens[len(tokens)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[last] = value
+	case []interface{}:
+		index, _ := strconv.Atoi(last)
+		p[index] = value
+	}
+	return nil
+}
+
+// jsonValueTree converts a value decoded from yaml to the values of json
+func jsonValueTree(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := map[string]interface{}{}
+		for k, e := range v {
+			converted[k] = jsonValueTree(e)
+		}
+		return converted
+	case []interface{}:
+		converted := []interface{}{}
+		for _, e := range v {
+			converted = append(converted, jsonValueTree(e))
+		}
+		return converted
+	}
+	return jsonValue(value)
+}
+
+func (t *kustomizeTarget) matches(r *resource) bool {
+	group, version := "", r.APIVersion()
+	if i := strings.Index(version, "/"); i >= 0 {
+		group, version = version[:i], version[i+1:]
+	}
+	return (t.Kind == "" || t.Kind == r.Kind()) &&
+		(t.Name == "" || t.Name == r.Name()) &&
+		(t.Namespace == "" || t.Namespace == r.Namespace()) &&
+		(t.Group == "" || t.Group == group) &&
+		(t.Version == "" || t.Version == version)
+}
+
+func addMetadataMap(r *resource, key string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	m, _ := r.metadata()[key].(map[string]interface{})
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	for k, v := range values {
+		m[k] = v
+	}
+	r.setMetadata(key, m)
+}
+
+// setImages changes the images of the containers of a resource as the images
+// of a kustomization do
+func setImages(r *resource, image kustomizeImage) {
+	for _, match := range containerImages.Evaluate(r.Object) {
+		current, ok := match.Value.(string)
+		if !ok {
+			continue
+		}
+		name, tag := splitImage(current)
+		if name != image.Name {
+			continue
+		}
+		if image.NewName != "" {
+			name = image.NewName
+		}
+		switch {
+		case image.Digest != "":
+			tag = "@" + image.Digest
+		case image.NewTag != "":
+			tag = ":" + image.NewTag
+		}
+		setPath(r.Object, match.Path, name+tag)
+	}
+}
+
+var containerImages, _ = jsonpath.Compile("$..['containers','initContainers'][*].image")
+
+// splitImage splits an image into its name and its tag or digest, along with
+// the : or @ separating them
+func splitImage(image string) (string, string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i], image[i:]
+	}
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		return image[:i], image[i:]
+	}
+	return image, ""
+}
+
+// setPath sets the value at a normalized jsonpath of an object
+func setPath(object map[string]interface{}, path string, value interface{}) {
+	tokens := []string{}
+	rest := strings.TrimPrefix(path, "$")
+	for rest != "" {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return
+		}
+		token := rest[1:end]
+		if strings.HasPrefix(token, "'") {
+			// the names are quoted, the quotes and backslashes escaped
+			for strings.HasSuffix(token, `\'`) || !strings.HasSuffix(token, "'") || len(token) < 2 {
+				next := strings.Index(rest[end+1:], "]")
+				if next < 0 {
+					return
+				}
+				end += next + 1
+				token = rest[1:end]
+			}
+			token = strings.ReplaceAll(strings.ReplaceAll(token[1:len(token)-1], `\'`, `'`), `\\`, `\`)
+		}
+		tokens = append(tokens, token)
+		rest = rest[end+1:]
+	}
+	setAt(object, tokens, value)
+}
+
+// clusterScoped reports whether resources of a kind have no namespace, the
+// namespace of a kustomization isn't set on them
+func clusterScoped(kind string) bool {
+	switch kind {
+	case "Namespace", "ClusterRole", "ClusterRoleBinding", "CustomResourceDefinition",
+		"PersistentVolume", "StorageClass", "PriorityClass", "PodSecurityPolicy",
+		"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration", "APIService",
+		"IngressClass", "RuntimeClass", "Node":
+		return true
+	}
+	return false
+}
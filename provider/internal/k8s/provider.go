@@ -0,0 +1,100 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+// HelmPathConfigKey is the helm binary rendering the charts
+const HelmPathConfigKey = "helmPath"
+
+type k8sCondition struct {
+	Resource                 resourceCondition `yaml:"resource"`
+	provider.ProviderContext `yaml:",inline"`
+}
+
+type resourceCondition struct {
+	APIVersion string `yaml:"apiVersion" json:"apiVersion,omitempty" title:"APIVersion" description:"apiVersion of the resources, such as policy/v1beta1, group/* matches every version of a group"`
+	Kind       string `yaml:"kind" json:"kind" title:"Kind" description:"Kind of the resources, such as PodSecurityPolicy"`
+	Name       string `yaml:"name" json:"name,omitempty" title:"Name" description:"Regex pattern the name of the resources must match"`
+	Namespace  string `yaml:"namespace" json:"namespace,omitempty" title:"Namespace" description:"Regex pattern the namespace of the resources must match"`
+	JSONPath   string `yaml:"jsonpath" json:"jsonpath,omitempty" title:"JSONPath" description:"JSONPath expression evaluated on each resource, there is an incident per selected value"`
+	Value      string `yaml:"value" json:"value,omitempty" title:"Value" description:"Regex pattern the values selected by the jsonpath expression must match"`
+}
+
+var _ provider.InternalProviderClient = &k8sProvider{}
+
+// k8sProvider analyzes the Kubernetes resources of the manifests, kustomize
+// overlays and Helm charts of the locations
+type k8sProvider struct {
+	log logr.Logger
+
+	config provider.Config
+	provider.UnimplementedDependenciesComponent
+
+	clients []provider.ServiceClient
+}
+
+func NewK8sProvider(config provider.Config, log logr.Logger) *k8sProvider {
+	return &k8sProvider{
+		config: config,
+		log:    log,
+	}
+}
+
+func (p *k8sProvider) Capabilities() []provider.Capability {
+	r := openapi3.NewReflector()
+
+	caps := []provider.Capability{}
+	resourceCap, err := provider.ToProviderCap(r, p.log, resourceCondition{}, "resource")
+	if err != nil {
+		p.log.Error(err, "unable to get resource capability")
+	} else {
+		caps = append(caps, resourceCap)
+	}
+	return caps
+}
+
+func (p *k8sProvider) ProviderInit(ctx context.Context, additionalInitConfigs []provider.InitConfig) ([]provider.InitConfig, error) {
+	seenLocations := map[string]bool{}
+	for _, c := range p.config.InitConfig {
+		if seenLocations[c.Location] {
+			continue
+		}
+		client, _, err := p.Init(ctx, p.log, c)
+		if err != nil {
+			return nil, err
+		}
+		p.clients = append(p.clients, client)
+		seenLocations[c.Location] = true
+	}
+	return nil, nil
+}
+
+// The resources are loaded on the first evaluation
+func (p *k8sProvider) Init(ctx context.Context, log logr.Logger, config provider.InitConfig) (provider.ServiceClient, provider.InitConfig, error) {
+	pathFilter, err := provider.GetPathFilterFromConfig(config)
+	if err != nil {
+		return nil, provider.InitConfig{}, err
+	}
+	helmPath, ok := config.ProviderSpecificConfig[HelmPathConfigKey].(string)
+	if !ok || helmPath == "" {
+		helmPath = "helm"
+	}
+	return &k8sServiceClient{
+		config:     config,
+		log:        log.WithValues("provider", "k8s"),
+		pathFilter: pathFilter,
+		helmPath:   helmPath,
+	}, provider.InitConfig{}, nil
+}
+
+func (p *k8sProvider) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
+	return provider.FullResponseFromServiceClients(ctx, p.clients, cap, conditionInfo)
+}
+
+func (p *k8sProvider) Stop() {
+}
@@ -0,0 +1,314 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider/internal/jsonpath"
+	"gopkg.in/yaml.v3"
+)
+
+// origin is where a value of a resource is declared
+type origin struct {
+	File string
+	Line int
+}
+
+// resource is a Kubernetes object of the analyzed manifests. Object is decoded
+// as json would be, the jsonpath expressions of the conditions are evaluated
+// on it. Origins maps the normalized jsonpath of the values to where they are
+// declared, the values set by kustomize patches are declared in the patches.
+type resource struct {
+	Object  map[string]interface{}
+	Origins map[string]origin
+	// Overlay is the kustomization the resource is built by, relative to the
+	// location, empty for the resources of manifests
+	Overlay string
+	// Chart is the Helm chart the resource is rendered from, relative to the
+	// location, empty for the resources of manifests
+	Chart string
+}
+
+func (r *resource) APIVersion() string {
+	return stringAt(r.Object, "apiVersion")
+}
+
+func (r *resource) Kind() string {
+	return stringAt(r.Object, "kind")
+}
+
+func (r *resource) Name() string {
+	return stringAt(r.metadata(), "name")
+}
+
+func (r *resource) Namespace() string {
+	return stringAt(r.metadata(), "namespace")
+}
+
+func (r *resource) metadata() map[string]interface{} {
+	metadata, _ := r.Object["metadata"].(map[string]interface{})
+	return metadata
+}
+
+// setMetadata sets a key of the metadata of the resource
+func (r *resource) setMetadata(key string, value interface{}) {
+	metadata := r.metadata()
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		r.Object["metadata"] = metadata
+	}
+	metadata[key] = value
+}
+
+// Origin returns where the value at a normalized jsonpath is declared, the
+// closest of its parents when the value itself isn't known, such as the
+// values of a document rendered by Helm
+func (r *resource) Origin(path string) origin {
+	for {
+		if o, ok := r.Origins[path]; ok {
+			return o
+		}
+		i := strings.LastIndex(path, "[")
+		if i <= 0 {
+			return r.Origins["$"]
+		}
+		path = path[:i]
+	}
+}
+
+// File is the file the resource is declared in
+func (r *resource) File() string {
+	return r.Origins["$"].File
+}
+
+// copy returns a deep copy of the resource, the overlays change their copy
+// of the resources of their bases
+func (r *resource) copy() *resource {
+	origins := make(map[string]origin, len(r.Origins))
+	for k, v := range r.Origins {
+		origins[k] = v
+	}
+	object, _ := deepCopy(r.Object).(map[string]interface{})
+	return &resource{Object: object, Origins: origins, Overlay: r.Overlay, Chart: r.Chart}
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			copied[k] = deepCopy(e)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, e := range v {
+			copied[i] = deepCopy(e)
+		}
+		return copied
+	}
+	return value
+}
+
+func stringAt(object map[string]interface{}, key string) string {
+	s, _ := object[key].(string)
+	return s
+}
+
+// document is a YAML document of a file
+type document struct {
+	Index     int
+	StartLine int
+	Content   string
+}
+
+// isDocumentSeparator reports whether a line is a --- marker starting a
+// document
+func isDocumentSeparator(line string) bool {
+	if !strings.HasPrefix(line, "---") {
+		return false
+	}
+	rest := strings.TrimRight(line[3:], "\r")
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// splitDocuments splits a YAML stream into its documents, they are parsed
+// one by one so that a document that can't be parsed doesn't fail the others
+func splitDocuments(content string) []document {
+	docs := []document{}
+	current := document{Index: 1, StartLine: 1}
+	lines := []string{}
+	for i, line := range strings.Split(content, "\n") {
+		if isDocumentSeparator(line) {
+			if i > 0 {
+				current.Content = strings.Join(lines, "\n")
+				docs = append(docs, current)
+			}
+			current = document{Index: len(docs) + 1, StartLine: i + 2}
+			lines = []string{}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	current.Content = strings.Join(lines, "\n")
+	return append(docs, current)
+}
+
+// parseDocument decodes a document along with the lines of its values, the
+// lines are the ones of the file
+func parseDocument(file string, doc document) (interface{}, map[string]origin, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(doc.Content), &node); err != nil {
+		return nil, nil, err
+	}
+	if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
+		return nil, nil, nil
+	}
+	origins := map[string]origin{}
+	value, err := decodeNode(node.Content[0], "$", file, doc.StartLine-1, origins)
+	return value, origins, err
+}
+
+// decodeNode decodes a node as json would, numbers are float64, recording
+// where every value is declared
+func decodeNode(node *yaml.Node, path, file string, lineOffset int, origins map[string]origin) (interface{}, error) {
+	origins[path] = origin{File: file, Line: node.Line + lineOffset}
+	switch node.Kind {
+	case yaml.AliasNode:
+		return decodeNode(node.Alias, path, file, lineOffset, origins)
+	case yaml.MappingNode:
+		object := map[string]interface{}{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			// the keys of json are strings, a key such as the {{ .Values.name }}
+			// of a template is a mapping
+			if node.Content[i].Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("unsupported key at line %d", node.Content[i].Line+lineOffset)
+			}
+			key := node.Content[i].Value
+			childPath := jsonpath.Member(path, key)
+			value, err := decodeNode(node.Content[i+1], childPath, file, lineOffset, origins)
+			if err != nil {
+				return nil, err
+			}
+			// the line of a value is the one of its key, the value of a
+			// mapping starts on the next line
+			origins[childPath] = origin{File: file, Line: node.Content[i].Line + lineOffset}
+			object[key] = value
+		}
+		return object, nil
+	case yaml.SequenceNode:
+		array := []interface{}{}
+		for i, child := range node.Content {
+			value, err := decodeNode(child, jsonpath.Element(path, i), file, lineOffset, origins)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case yaml.ScalarNode:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return jsonValue(value), nil
+	}
+	return nil, fmt.Errorf("unexpected yaml node at line %d", node.Line+lineOffset)
+}
+
+// jsonValue converts the scalars of yaml to the ones of json
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return fmt.Sprint(v)
+		}
+		return v
+	case string, bool, nil:
+		return v
+	}
+	// timestamps and binary values are strings in json
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	var s interface{}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Sprint(value)
+	}
+	return s
+}
+
+// parseManifest returns the resources of a file along with warnings about the
+// documents that can't be parsed. The items of lists, such as the output of
+// kubectl get -o yaml, are resources of their own.
+func parseManifest(file string) ([]*resource, []string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	resources := []*resource{}
+	warnings := []string{}
+	for _, doc := range splitDocuments(string(content)) {
+		if len(bytes.TrimSpace([]byte(doc.Content))) == 0 {
+			continue
+		}
+		value, origins, err := parseDocument(file, doc)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("unable to parse document %d of yaml file %s at line %d, skipped it: %v", doc.Index, file, doc.StartLine, err))
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		resources = append(resources, objectResources(object, origins, "$")...)
+	}
+	return resources, warnings, nil
+}
+
+// objectResources returns the resource of an object, or the ones of the items
+// of a list
+func objectResources(object map[string]interface{}, origins map[string]origin, path string) []*resource {
+	kind, _ := object["kind"].(string)
+	apiVersion, _ := object["apiVersion"].(string)
+	if kind == "" || apiVersion == "" {
+		return nil
+	}
+	if items, ok := object["items"].([]interface{}); ok && strings.HasSuffix(kind, "List") {
+		resources := []*resource{}
+		for i, item := range items {
+			if itemObject, ok := item.(map[string]interface{}); ok {
+				resources = append(resources, objectResources(itemObject, origins, jsonpath.Element(jsonpath.Member(path, "items"), i))...)
+			}
+		}
+		return resources
+	}
+	return []*resource{{Object: object, Origins: rebase(origins, path)}}
+}
+
+// rebase returns the origins of the values under a path, relative to it
+func rebase(origins map[string]origin, path string) map[string]origin {
+	if path == "$" {
+		return origins
+	}
+	rebased := map[string]origin{}
+	for p, o := range origins {
+		if p == path {
+			rebased["$"] = o
+		} else if strings.HasPrefix(p, path+"[") {
+			rebased["$"+p[len(path):]] = o
+		}
+	}
+	return rebased
+}
@@ -0,0 +1,328 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/pathfilter"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/internal/jsonpath"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+type k8sServiceClient struct {
+	config provider.InitConfig
+	provider.UnimplementedDependenciesComponent
+	log        logr.Logger
+	pathFilter *pathfilter.Filter
+	helmPath   string
+
+	// the resources are loaded once, on first use
+	resourcesOnce sync.Once
+	resources     []*resource
+	warnings      []string
+}
+
+var _ provider.ServiceClient = &k8sServiceClient{}
+
+func (p *k8sServiceClient) Stop() {}
+
+func (p *k8sServiceClient) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
+	var cond k8sCondition
+	err := yaml.Unmarshal(conditionInfo, &cond)
+	if err != nil {
+		return provider.ProviderEvaluateResponse{}, engine.NewProviderError(konveyor.ParseError, fmt.Errorf("unable to get query info: %v", err))
+	}
+	switch cap {
+	case "resource":
+		return p.evaluateResource(ctx, cond.Resource, cond.ProviderContext)
+	}
+	return provider.ProviderEvaluateResponse{}, engine.NewProviderError(konveyor.CapabilityUnsupported, fmt.Errorf("capability must be one of %v, not %s", []string{"resource"}, cap))
+}
+
+func (p *k8sServiceClient) evaluateResource(ctx context.Context, c resourceCondition, providerContext provider.ProviderContext) (provider.ProviderEvaluateResponse, error) {
+	response := provider.ProviderEvaluateResponse{Matched: false}
+	if c.Kind == "" {
+		return response, fmt.Errorf("kind of resource condition must be set")
+	}
+	regexes := map[string]*regexp.Regexp{}
+	for field, pattern := range map[string]string{"name": c.Name, "namespace": c.Namespace, "value": c.Value} {
+		if pattern == "" {
+			continue
+		}
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return response, fmt.Errorf("unable to compile %s pattern `%s`: %w", field, pattern, err)
+		}
+		regexes[field] = regex
+	}
+	var path jsonpath.Path
+	if c.JSONPath != "" {
+		var err error
+		if path, err = jsonpath.Compile(c.JSONPath); err != nil {
+			return response, fmt.Errorf("unable to compile jsonpath query `%s`: %w", c.JSONPath, err)
+		}
+	}
+	scoped, scopedPaths := providerContext.GetScopedFilepaths()
+
+	resources, warnings := p.loadResources(ctx)
+	response.Warnings = warnings
+	seen := map[string]bool{}
+	for _, r := range resources {
+		if r.Kind() != c.Kind || !matchAPIVersion(c.APIVersion, r.APIVersion()) {
+			continue
+		}
+		if regex, ok := regexes["name"]; ok && !regex.MatchString(r.Name()) {
+			continue
+		}
+		if regex, ok := regexes["namespace"]; ok && !regex.MatchString(r.Namespace()) {
+			continue
+		}
+		if !p.pathFilter.Match(r.File()) || (scoped && !containsPath(scopedPaths, r.File())) {
+			continue
+		}
+		variables := map[string]interface{}{
+			"apiVersion": r.APIVersion(),
+			"kind":       r.Kind(),
+			"name":       r.Name(),
+			"namespace":  r.Namespace(),
+		}
+		if r.Overlay != "" {
+			variables["overlay"] = r.Overlay
+		}
+		if r.Chart != "" {
+			variables["chart"] = r.Chart
+		}
+		if path == nil {
+			addIncident(&response, seen, r.Origin("$"), variables)
+			continue
+		}
+		for _, match := range path.Evaluate(r.Object) {
+			value := valueString(match.Value)
+			if regex, ok := regexes["value"]; ok && !regex.MatchString(value) {
+				continue
+			}
+			matchVariables := map[string]interface{}{
+				"matchingPath":  match.Path,
+				"matchingValue": value,
+			}
+			for k, v := range variables {
+				matchVariables[k] = v
+			}
+			addIncident(&response, seen, r.Origin(match.Path), matchVariables)
+		}
+	}
+	response.Matched = len(response.Incidents) > 0
+	return response, nil
+}
+
+// addIncident adds an incident unless the same one was already added, the
+// resources of a manifest used by several overlays are analyzed for each
+func addIncident(response *provider.ProviderEvaluateResponse, seen map[string]bool, o origin, variables map[string]interface{}) {
+	b, _ := json.Marshal(variables)
+	key := fmt.Sprintf("%s:%d:%s", o.File, o.Line, b)
+	if seen[key] {
+		return
+	}
+	seen[key] = true
+	line := o.Line
+	response.Incidents = append(response.Incidents, provider.IncidentContext{
+		FileURI:    uri.File(o.File),
+		LineNumber: &line,
+		Variables:  variables,
+		CodeLocation: &provider.Location{
+			StartPosition: provider.Position{Line: float64(line)},
+			EndPosition:   provider.Position{Line: float64(line)},
+		},
+	})
+}
+
+// matchAPIVersion reports whether the apiVersion of a resource is the one of a
+// condition, group/* matches every version of the group
+func matchAPIVersion(expected, apiVersion string) bool {
+	switch {
+	case expected == "" || expected == "*":
+		return true
+	case strings.HasSuffix(expected, "/*"):
+		return strings.HasPrefix(apiVersion, strings.TrimSuffix(expected, "*"))
+	}
+	return expected == apiVersion
+}
+
+// valueString returns a value selected by a jsonpath expression as a string,
+// objects and lists as json
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return "null"
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// loadResources loads the resources of the location: the ones of the top
+// level kustomizations, the ones rendered from the Helm charts and the ones of
+// the other manifests
+func (p *k8sServiceClient) loadResources(ctx context.Context) ([]*resource, []string) {
+	p.resourcesOnce.Do(func() {
+		location, err := filepath.Abs(p.config.Location)
+		if err != nil {
+			location = p.config.Location
+		}
+		manifests, kustomizations, charts, err := p.findFiles(location)
+		if err != nil {
+			p.warnings = append(p.warnings, fmt.Sprintf("unable to find the manifests of %s: %v", location, err))
+		}
+
+		parsed := map[string][]*resource{}
+		parse := func(file string) []*resource {
+			if resources, ok := parsed[file]; ok {
+				return resources
+			}
+			resources, warnings, err := parseManifest(file)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("unable to read yaml file %s: %v", file, err))
+			}
+			p.warnings = append(p.warnings, warnings...)
+			parsed[file] = resources
+			return resources
+		}
+
+		builder := &kustomizeBuilder{manifests: parse, used: map[string]bool{}}
+		built := map[string][]*resource{}
+		for _, dir := range kustomizations {
+			built[dir] = builder.build(dir, map[string]bool{})
+		}
+		p.warnings = append(p.warnings, builder.warnings...)
+		for _, dir := range kustomizations {
+			if builder.used[dir] {
+				continue
+			}
+			for _, r := range built[dir] {
+				r.Overlay = relativePath(location, dir)
+				p.resources = append(p.resources, r)
+			}
+		}
+
+		if len(charts) > 0 {
+			if _, err := exec.LookPath(p.helmPath); err != nil {
+				p.warnings = append(p.warnings, fmt.Sprintf("unable to find helm to render the helm charts, skipped them: %v", err))
+				charts = nil
+			}
+		}
+		for _, dir := range charts {
+			rendered, warnings, err := renderChart(ctx, p.helmPath, dir)
+			if err != nil {
+				p.log.Error(err, "unable to render helm chart", "chart", dir)
+				p.warnings = append(p.warnings, err.Error())
+				continue
+			}
+			p.warnings = append(p.warnings, warnings...)
+			for _, r := range rendered {
+				r.Chart = relativePath(location, dir)
+				p.resources = append(p.resources, r)
+			}
+		}
+
+		for _, file := range manifests {
+			if builder.used[file] {
+				continue
+			}
+			p.resources = append(p.resources, parse(file)...)
+		}
+		p.warnings = uniqueSorted(p.warnings)
+		p.log.V(3).Info("loaded kubernetes resources", "resources", len(p.resources),
+			"kustomizations", len(kustomizations), "charts", len(charts))
+	})
+	return p.resources, p.warnings
+}
+
+// findFiles returns the YAML manifests, the kustomizations and the Helm charts
+// of the location. The subcharts are rendered with their charts, the files of
+// the charts are not manifests.
+func (p *k8sServiceClient) findFiles(location string) ([]string, []string, []string, error) {
+	manifests, kustomizations, charts := []string{}, []string{}, []string{}
+	err := filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "node_modules" || p.pathFilter.SkipDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch {
+		case d.Name() == "Chart.yaml":
+			charts = append(charts, filepath.Dir(path))
+		case containsPath(kustomizationFiles, d.Name()):
+			kustomizations = append(kustomizations, filepath.Dir(path))
+		case strings.HasSuffix(d.Name(), ".yaml") || strings.HasSuffix(d.Name(), ".yml"):
+			manifests = append(manifests, path)
+		}
+		return nil
+	})
+	topCharts := []string{}
+	for _, chart := range charts {
+		if !isChartFile(charts, chart) {
+			topCharts = append(topCharts, chart)
+		}
+	}
+	plainManifests := []string{}
+	for _, file := range manifests {
+		if !isChartFile(topCharts, file) {
+			plainManifests = append(plainManifests, file)
+		}
+	}
+	return plainManifests, kustomizations, topCharts, err
+}
+
+func relativePath(location, path string) string {
+	rel, err := filepath.Rel(location, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+func uniqueSorted(values []string) []string {
+	sort.Strings(values)
+	unique := []string{}
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
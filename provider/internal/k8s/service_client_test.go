@@ -0,0 +1,141 @@
+package k8s
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+func testServiceClient(t *testing.T) *k8sServiceClient {
+	location, err := filepath.Abs(filepath.Join("testdata", "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _, err := NewK8sProvider(provider.Config{}, testr.New(t)).Init(context.TODO(), testr.New(t), provider.InitConfig{
+		Location:               location,
+		ProviderSpecificConfig: map[string]interface{}{HelmPathConfigKey: "/nonexistent/helm"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.(*k8sServiceClient)
+}
+
+// incidentSummary is an incident as file:line followed by its variables
+func incidentSummary(response provider.ProviderEvaluateResponse, variables ...string) []string {
+	summary := []string{}
+	for _, incident := range response.Incidents {
+		parts := []string{}
+		for _, v := range variables {
+			parts = append(parts, v+"="+valueString(incident.Variables[v]))
+		}
+		file := strings.TrimPrefix(string(incident.FileURI), "file://")
+		rel := filepath.ToSlash(filepath.Join(filepath.Base(filepath.Dir(filepath.Dir(file))), filepath.Base(filepath.Dir(file)), filepath.Base(file)))
+		summary = append(summary, rel+":"+valueString(float64(*incident.LineNumber))+" "+strings.Join(parts, " "))
+	}
+	sort.Strings(summary)
+	return summary
+}
+
+func Test_k8sServiceClient_Evaluate_resource(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		variables []string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "resource of a multi-document manifest",
+			condition: "resource:\n  apiVersion: policy/v1beta1\n  kind: PodSecurityPolicy\n",
+			variables: []string{"name"},
+			want:      []string{"app/manifests/cluster.yaml:1 name=restricted"},
+		},
+		{
+			name:      "items of a list",
+			condition: "resource:\n  apiVersion: batch/*\n  kind: CronJob\n",
+			variables: []string{"name", "namespace"},
+			want:      []string{"app/manifests/list.yaml:4 name=cleanup namespace=jobs"},
+		},
+		{
+			name:      "resources of an overlay",
+			condition: "resource:\n  apiVersion: apps/v1\n  kind: Deployment\n  namespace: prod\n",
+			variables: []string{"name", "namespace", "overlay"},
+			want:      []string{"deploy/base/deployment.yaml:1 name=prod-web namespace=prod overlay=deploy/overlays/prod"},
+		},
+		{
+			name:      "values set by the patches of an overlay",
+			condition: "resource:\n  kind: Deployment\n  jsonpath: $.spec.replicas\n",
+			variables: []string{"name", "matchingValue"},
+			want:      []string{"overlays/prod/replicas.yaml:6 name=prod-web matchingValue=3"},
+		},
+		{
+			name:      "values matching a pattern",
+			condition: "resource:\n  kind: Deployment\n  jsonpath: $.spec.template.spec.containers[*].image\n  value: ^(nginx|envoyproxy)\n",
+			variables: []string{"matchingPath", "matchingValue"},
+			want: []string{
+				"deploy/base/deployment.yaml:11 matchingPath=$['spec']['template']['spec']['containers'][0]['image'] matchingValue=nginx:1.25",
+				"overlays/prod/replicas.yaml:11 matchingPath=$['spec']['template']['spec']['containers'][1]['image'] matchingValue=envoyproxy/envoy:v1.30.0",
+			},
+		},
+		{
+			name:      "values added by a json patch",
+			condition: "resource:\n  kind: Deployment\n  jsonpath: $.spec.template.spec.serviceAccountName\n",
+			variables: []string{"matchingValue"},
+			want:      []string{"overlays/prod/kustomization.yaml:14 matchingValue=web"},
+		},
+		{
+			name:      "name pattern",
+			condition: "resource:\n  kind: Deployment\n  name: ^work\n  jsonpath: $..image\n",
+			variables: []string{"name", "matchingValue"},
+			want:      []string{"app/manifests/cluster.yaml:18 name=worker matchingValue=quay.io/acme/worker:2.1"},
+		},
+		{
+			name:      "no kind",
+			condition: "resource:\n  apiVersion: apps/v1\n",
+			wantErr:   true,
+		},
+		{
+			name:      "invalid jsonpath",
+			condition: "resource:\n  kind: Deployment\n  jsonpath: spec\n",
+			wantErr:   true,
+		},
+	}
+	client := testServiceClient(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.Evaluate(context.TODO(), "resource", []byte(tt.condition))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if summary := incidentSummary(got, tt.variables...); !reflect.DeepEqual(summary, tt.want) {
+				t.Errorf("expected incidents\n%v\ngot\n%v", strings.Join(tt.want, "\n"), strings.Join(summary, "\n"))
+			}
+			if got.Matched != (len(tt.want) > 0) {
+				t.Errorf("expected matched to be %v", len(tt.want) > 0)
+			}
+		})
+	}
+}
+
+func Test_k8sServiceClient_warnings(t *testing.T) {
+	_, warnings := testServiceClient(t).loadResources(context.TODO())
+	expected := []string{"unable to find helm", "document 3 of yaml file"}
+	if len(warnings) != len(expected) {
+		t.Fatalf("expected %d warnings, got %v", len(expected), warnings)
+	}
+	for i, w := range warnings {
+		if !strings.Contains(w, expected[i]) {
+			t.Errorf("expected a warning about %s, got %s", expected[i], w)
+		}
+	}
+}
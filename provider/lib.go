@@ -1,15 +1,19 @@
 package provider
 
 import (
-	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 	"math"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/konveyor/analyzer-lsp/feature"
+	"github.com/konveyor/analyzer-lsp/pathfilter"
+	"github.com/konveyor/analyzer-lsp/textfile"
 )
 
 func FilterFilePattern(regex string, filepath string) (bool, error) {
@@ -113,10 +117,18 @@ func MultilineGrep(ctx context.Context, window int, path, pattern string) (int,
 
 	// make sure we never keep too big a chunk in memory
 	window = int(math.Min(float64(window), 5))
-	scanner := bufio.NewScanner(file)
+	// lines too long for a scanner, such as the lines of minified files, are truncated
+	reader := textfile.NewReader(file)
 	currLine := 1
 	lines := make([]string, window)
-	for scanner.Scan() {
+	for {
+		line, err := reader.Line()
+		if err == io.EOF {
+			return -1, nil
+		}
+		if err != nil {
+			return -1, err
+		}
 		select {
 		case <-ctx.Done():
 			return -1, fmt.Errorf("aborting search in file %s, timed out", path)
@@ -126,7 +138,6 @@ func MultilineGrep(ctx context.Context, window int, path, pattern string) (int,
 		if len(lines) == window {
 			lines = lines[1:]
 		}
-		line := scanner.Text()
 		line = strings.ReplaceAll(line, "\t", "")
 		line = strings.Trim(line, " ")
 		lines = append(lines, line)
@@ -136,15 +147,72 @@ func MultilineGrep(ctx context.Context, window int, path, pattern string) (int,
 		}
 		currLine += 1
 	}
+}
+
+// LocationText returns the text of the location in the file, the lines and
+// characters of the location start at 0 and the characters are counted in
+// UTF-16 code units, as in the ranges of LSP. Providers return it as the
+// matched text of their incidents.
+func LocationText(path string, location Location) (string, error) {
+	start, end := int(location.StartPosition.Line), int(location.EndPosition.Line)
+	if start < 0 || end < start {
+		return "", fmt.Errorf("invalid location %v in file %s", location, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
 
-	return -1, scanner.Err()
+	reader := textfile.NewReader(file)
+	lines := []string{}
+	for n := 0; n <= end; n++ {
+		line, err := reader.Line()
+		if err == io.EOF {
+			return "", fmt.Errorf("location %v is past the end of file %s", location, path)
+		}
+		if err != nil {
+			return "", err
+		}
+		if n >= start {
+			lines = append(lines, line)
+		}
+	}
+	last := len(lines) - 1
+	lines[last] = lines[last][:utf16Offset(lines[last], int(location.EndPosition.Character))]
+	lines[0] = lines[0][utf16Offset(lines[0], int(location.StartPosition.Character)):]
+	return strings.Join(lines, "\n"), nil
+}
+
+// utf16Offset returns the byte offset of the character of the line counted in
+// UTF-16 code units, the length of the line when it is shorter
+func utf16Offset(line string, character int) int {
+	units := 0
+	for offset, r := range line {
+		if units >= character {
+			return offset
+		}
+		units++
+		if r >= 0x10000 {
+			// surrogate pair
+			units++
+		}
+	}
+	return len(line)
 }
 
 // GetIncludedPathsFromConfig returns validated includedPaths from provider settings
-// if allowFilePaths is not set, path to a file is converted into a path to its base dir
+// if allowFilePaths is not set, path to a file is converted into a path to its base dir.
+// Globs can't be passed on as paths, none are returned when there are any, see
+// GetPathFilterFromConfig to match files against them.
 func GetIncludedPathsFromConfig(i InitConfig, allowFilePaths bool) []string {
 	validatedPaths := []string{}
 	if includedPaths, ok := i.ProviderSpecificConfig[IncludedPathsConfigKey].([]interface{}); ok {
+		for _, ipathRaw := range includedPaths {
+			if ipath, ok := ipathRaw.(string); ok && pathfilter.IsGlob(ipath) {
+				return []string{}
+			}
+		}
 		for _, ipathRaw := range includedPaths {
 			if ipath, ok := ipathRaw.(string); ok {
 				absPath := ipath
@@ -166,3 +234,41 @@ func GetIncludedPathsFromConfig(i InitConfig, allowFilePaths bool) []string {
 	}
 	return validatedPaths
 }
+
+// GetPathFilterFromConfig returns the filter of the included and excluded
+// paths of the provider settings, matching the files of the location.
+func GetPathFilterFromConfig(i InitConfig) (*pathfilter.Filter, error) {
+	globs := func(key string) []string {
+		values := []string{}
+		if raw, ok := i.ProviderSpecificConfig[key].([]interface{}); ok {
+			for _, v := range raw {
+				if glob, ok := v.(string); ok {
+					values = append(values, glob)
+				}
+			}
+		}
+		return values
+	}
+	filter, err := pathfilter.New(i.Location, globs(IncludedPathsConfigKey), globs(ExcludedPathsConfigKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s or %s: %w", IncludedPathsConfigKey, ExcludedPathsConfigKey, err)
+	}
+	return filter, nil
+}
+
+// GetFeatureFlagsFromConfig returns the feature flags passed to the provider in
+// its provider specific config, along with the ones set in its environment.
+func GetFeatureFlagsFromConfig(i InitConfig) feature.Flags {
+	flags, err := feature.Parse(os.Getenv(feature.EnvVar))
+	if err != nil {
+		flags = feature.Flags{}
+	}
+	if configFlags, ok := i.ProviderSpecificConfig[FeatureFlagsConfigKey].(map[string]interface{}); ok {
+		for name, enabled := range configFlags {
+			if enabled, ok := enabled.(bool); ok {
+				flags[name] = enabled
+			}
+		}
+	}
+	return flags
+}
@@ -5,13 +5,20 @@ import (
 	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/konveyor/analyzer-lsp/provider/grpc"
 	"github.com/konveyor/analyzer-lsp/provider/internal/builtin"
+	"github.com/konveyor/analyzer-lsp/provider/internal/k8s"
+	"github.com/konveyor/analyzer-lsp/provider/mock"
 )
 
 // We need some wrapper that can deal with out of tree providers, this will be a call, that will mock it out, but go against in tree.
 func GetProviderClient(config provider.Config, log logr.Logger) (provider.InternalProviderClient, error) {
+	if config.FixtureFile != "" || config.Name == "mock" {
+		return mock.NewMockProvider(config)
+	}
 	switch config.Name {
 	case "builtin":
 		return builtin.NewBuiltinProvider(config, log), nil
+	case "k8s":
+		return k8s.NewK8sProvider(config, log), nil
 	default:
 		return grpc.NewGRPCClient(config, log)
 	}
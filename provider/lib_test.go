@@ -2,6 +2,8 @@ package provider
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -81,3 +83,60 @@ func BenchmarkMultilineGrepFileSizeBig(b *testing.B) {
 		canMe()
 	}
 }
+
+func TestLocationText(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "Main.java")
+	content := "import javax.jms.Queue;\n\nString s = \"😀\" + queue.getQueueName();\nqueue\n  .send(m);\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	location := func(startLine, startCharacter, endLine, endCharacter float64) Location {
+		return Location{
+			StartPosition: Position{Line: startLine, Character: startCharacter},
+			EndPosition:   Position{Line: endLine, Character: endCharacter},
+		}
+	}
+	tests := []struct {
+		name     string
+		location Location
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "single line",
+			location: location(0, 7, 0, 22),
+			want:     "javax.jms.Queue",
+		},
+		{
+			name:     "utf-16 characters",
+			location: location(2, 18, 2, 38),
+			want:     "queue.getQueueName()",
+		},
+		{
+			name:     "multiple lines",
+			location: location(3, 0, 4, 7),
+			want:     "queue\n  .send",
+		},
+		{
+			name:     "end past the line",
+			location: location(0, 17, 0, 100),
+			want:     "Queue;",
+		},
+		{
+			name:     "past the end of the file",
+			location: location(10, 0, 10, 1),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LocationText(file, tt.location)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LocationText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LocationText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+// UnknownLicense is the value of the dep-license label of the dependencies
+// whose license couldn't be detected
+const UnknownLicense = "unknown"
+
+// LicenseDatabase maps the dependencies to the SPDX identifiers of their
+// licenses. It is loaded from a YAML file with a list of entries such as
+//
+//   - name: org\.springframework\..*
+//     version: ^5\.
+//     licenses: [Apache-2.0]
+//
+// name and the optional version are regexes matching the name and version of
+// the dependencies, as in the open source labels file of the java provider.
+// The licenses of the first matching entry are used.
+type LicenseDatabase struct {
+	entries []licenseEntry
+}
+
+type licenseEntry struct {
+	name     *regexp.Regexp
+	version  *regexp.Regexp
+	licenses []string
+}
+
+// LoadLicenseDatabase reads a license database from a file
+func LoadLicenseDatabase(path string) (*LicenseDatabase, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := []struct {
+		Name     string   `yaml:"name"`
+		Version  string   `yaml:"version,omitempty"`
+		Licenses []string `yaml:"licenses"`
+	}{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("unable to parse license database %s: %w", path, err)
+	}
+	valueRegex := regexp.MustCompile(labels.LabelValueFmt)
+	db := &LicenseDatabase{}
+	for i, r := range raw {
+		if r.Name == "" || len(r.Licenses) == 0 {
+			return nil, fmt.Errorf("entry %d of license database %s must have a name and licenses", i, path)
+		}
+		entry := licenseEntry{}
+		if entry.name, err = regexp.Compile(r.Name); err != nil {
+			return nil, fmt.Errorf("invalid name of entry %d of license database %s: %w", i, path, err)
+		}
+		if r.Version != "" {
+			if entry.version, err = regexp.Compile(r.Version); err != nil {
+				return nil, fmt.Errorf("invalid version of entry %d of license database %s: %w", i, path, err)
+			}
+		}
+		for _, license := range r.Licenses {
+			if spdx := NormalizeLicense(license); spdx != "" {
+				license = spdx
+			}
+			if !valueRegex.MatchString(license) {
+				return nil, fmt.Errorf("invalid license %q of entry %d of license database %s", license, i, path)
+			}
+			entry.licenses = append(entry.licenses, license)
+		}
+		db.entries = append(db.entries, entry)
+	}
+	return db, nil
+}
+
+// GetLicenseDatabaseFromConfig loads the license database of the
+// depLicensesFile provider specific config, nil when there is none
+func GetLicenseDatabaseFromConfig(i InitConfig) (*LicenseDatabase, error) {
+	path, ok := i.ProviderSpecificConfig[DepLicensesFileConfigKey].(string)
+	if !ok || path == "" {
+		return nil, nil
+	}
+	return LoadLicenseDatabase(path)
+}
+
+// Licenses returns the licenses of a dependency in the database, nil when it
+// isn't in it
+func (db *LicenseDatabase) Licenses(name, version string) []string {
+	if db == nil {
+		return nil
+	}
+	for _, entry := range db.entries {
+		if !entry.name.MatchString(name) {
+			continue
+		}
+		if entry.version != nil && !entry.version.MatchString(version) {
+			continue
+		}
+		return entry.licenses
+	}
+	return nil
+}
+
+// AddLicenseLabels labels the dependencies with the licenses of the database.
+// The licenses detected by the provider are kept for the dependencies that
+// aren't in it, the ones without any are labeled unknown.
+func (db *LicenseDatabase) AddLicenseLabels(deps map[uri.URI][]*Dep) {
+	for _, list := range deps {
+		for _, dep := range list {
+			db.addLicenseLabels(dep)
+		}
+	}
+}
+
+// AddLicenseLabelsDAG labels the dependencies of a DAG as AddLicenseLabels
+func (db *LicenseDatabase) AddLicenseLabelsDAG(deps map[uri.URI][]DepDAGItem) {
+	var walk func(items []DepDAGItem)
+	walk = func(items []DepDAGItem) {
+		for i := range items {
+			db.addLicenseLabels(&items[i].Dep)
+			walk(items[i].AddedDeps)
+		}
+	}
+	for _, items := range deps {
+		walk(items)
+	}
+}
+
+func (db *LicenseDatabase) addLicenseLabels(dep *Dep) {
+	known := db.Licenses(dep.Name, dep.Version)
+	found := false
+	depLabels := []string{}
+	for _, label := range dep.Labels {
+		if strings.HasPrefix(label, DepLicenseLabel+"=") {
+			found = true
+			if known != nil {
+				continue
+			}
+		}
+		depLabels = append(depLabels, label)
+	}
+	if known != nil || !found {
+		depLabels = append(depLabels, DepLicenseLabels(known)...)
+	}
+	dep.Labels = depLabels
+}
+
+// DepLicenseLabels returns the dep-license labels of the SPDX identifiers of
+// the licenses of a dependency, a single unknown label when there is none.
+func DepLicenseLabels(licenses []string) []string {
+	seen := map[string]bool{}
+	depLabels := []string{}
+	for _, license := range licenses {
+		if license == "" || seen[license] {
+			continue
+		}
+		seen[license] = true
+		depLabels = append(depLabels, labels.AsString(DepLicenseLabel, license))
+	}
+	if len(depLabels) == 0 {
+		depLabels = append(depLabels, labels.AsString(DepLicenseLabel, UnknownLicense))
+	}
+	return depLabels
+}
+
+// licenseNames recognizes the common names and URLs of the licenses of
+// dependencies, such as the ones in the licenses of poms. The order matters,
+// LGPL is matched before GPL.
+var licenseNames = []struct {
+	spdx     string
+	patterns []*regexp.Regexp
+}{
+	{"Apache-2.0", patterns(`apache.*2`, `asl.*2`, `apache\.org/licenses/license-2\.0`)},
+	{"Apache-1.1", patterns(`apache.*1\.1`)},
+	{"MIT", patterns(`^mit\b`, `\bmit license`, `licenses/mit\b`, `/mit-license`)},
+	{"BSD-3-Clause", patterns(`bsd.*3`, `(new|revised|modified) bsd`, `licenses/bsd-3-clause`, `eclipse distribution license`, `edl.*1\.0`)},
+	{"BSD-2-Clause", patterns(`bsd.*2`, `simplified bsd`, `freebsd`, `licenses/bsd-2-clause`)},
+	{"EPL-2.0", patterns(`eclipse public license.*2`, `epl.*2`)},
+	{"EPL-1.0", patterns(`eclipse public license`, `epl.*1`)},
+	{"MPL-2.0", patterns(`mozilla public license.*2`, `mpl.*2`)},
+	{"MPL-1.1", patterns(`mozilla public license.*1\.1`, `mpl.*1\.1`)},
+	{"LGPL-2.1-or-later", patterns(`(lesser|library) general public license.*2\.1.*(later|\+)`, `lgpl.*2\.1.*(later|\+)`)},
+	{"LGPL-2.1-only", patterns(`(lesser|library) general public license.*2\.1`, `lgpl.*2\.1`)},
+	{"LGPL-3.0-or-later", patterns(`lesser general public license.*3.*(later|\+)`, `lgpl.*3.*(later|\+)`)},
+	{"LGPL-3.0-only", patterns(`lesser general public license.*3`, `lgpl.*3`)},
+	{"AGPL-3.0-only", patterns(`affero general public license`, `agpl`)},
+	{"GPL-2.0-with-classpath-exception", patterns(`gpl.*2.*classpath`, `general public license.*2.*classpath`)},
+	{"GPL-2.0-or-later", patterns(`general public license.*2.*(later|\+)`, `gpl.*2.*(later|\+)`)},
+	{"GPL-2.0-only", patterns(`general public license.*2`, `gpl.*2`)},
+	{"GPL-3.0-or-later", patterns(`general public license.*3.*(later|\+)`, `gpl.*3.*(later|\+)`)},
+	{"GPL-3.0-only", patterns(`general public license.*3`, `gpl.*3`)},
+	{"CDDL-1.1", patterns(`common development and distribution license.*1\.1`, `cddl.*1\.1`)},
+	{"CDDL-1.0", patterns(`common development and distribution license`, `cddl`)},
+	{"ISC", patterns(`^isc\b`, `licenses/isc\b`)},
+	{"Unlicense", patterns(`^(the )?unlicense`, `unlicense\.org`)},
+	{"CC0-1.0", patterns(`cc0`, `creative commons zero`, `publicdomain/zero/1\.0`)},
+	{"BSL-1.0", patterns(`boost software license`, `^bsl.*1\.0`)},
+	{"Zlib", patterns(`^zlib`)},
+}
+
+func patterns(expressions ...string) []*regexp.Regexp {
+	compiled := []*regexp.Regexp{}
+	for _, e := range expressions {
+		compiled = append(compiled, regexp.MustCompile(e))
+	}
+	return compiled
+}
+
+// NormalizeLicense returns the SPDX identifier of the name or URL of a
+// license, empty when it isn't recognized
+func NormalizeLicense(license string) string {
+	license = strings.ToLower(strings.Join(strings.Fields(license), " "))
+	if license == "" {
+		return ""
+	}
+	for _, l := range licenseNames {
+		if strings.ToLower(l.spdx) == license {
+			return l.spdx
+		}
+	}
+	for _, l := range licenseNames {
+		for _, p := range l.patterns {
+			if p.MatchString(license) {
+				return l.spdx
+			}
+		}
+	}
+	return ""
+}
+
+// licenseTexts recognizes the licenses from the phrases of their texts, the
+// order matters as for licenseNames
+var licenseTexts = []struct {
+	spdx    string
+	phrases []string
+}{
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"EPL-2.0", []string{"eclipse public license - v 2.0"}},
+	{"EPL-1.0", []string{"eclipse public license - v 1.0"}},
+	{"LGPL-3.0-only", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1-only", []string{"gnu lesser general public license", "version 2.1"}},
+	{"AGPL-3.0-only", []string{"gnu affero general public license", "version 3"}},
+	{"GPL-3.0-only", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0-only", []string{"gnu general public license", "version 2"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "names of its contributors may be used"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+}
+
+// DetectLicense returns the SPDX identifier of the license of the text of a
+// LICENSE file, empty when it isn't recognized
+func DetectLicense(text []byte) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(string(text)), " "))
+	for _, l := range licenseTexts {
+		found := true
+		for _, phrase := range l.phrases {
+			if !strings.Contains(normalized, phrase) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return l.spdx
+		}
+	}
+	return ""
+}
+
+// IsLicenseFile reports whether the name of a file is the one of a license
+// file, such as LICENSE, LICENSE.txt or COPYING
+func IsLicenseFile(name string) bool {
+	base, _, _ := strings.Cut(strings.ToUpper(name), ".")
+	switch base {
+	case "LICENSE", "LICENCE", "COPYING", "LICENSE-MIT", "LICENSE-APACHE":
+		return true
+	}
+	return false
+}
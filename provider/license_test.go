@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+func TestNormalizeLicense(t *testing.T) {
+	tests := map[string]string{
+		"The Apache Software License, Version 2.0":        "Apache-2.0",
+		"https://www.apache.org/licenses/LICENSE-2.0.txt": "Apache-2.0",
+		"apache-2.0":                           "Apache-2.0",
+		"The MIT License":                      "MIT",
+		"MIT":                                  "MIT",
+		"New BSD License":                      "BSD-3-Clause",
+		"Eclipse Distribution License - v 1.0": "BSD-3-Clause",
+		"Eclipse Public License - v 2.0":       "EPL-2.0",
+		"Eclipse Public License 1.0":           "EPL-1.0",
+		"GNU Lesser General Public License, version 2.1":                     "LGPL-2.1-only",
+		"GNU General Public License, version 2 with the Classpath Exception": "GPL-2.0-with-classpath-exception",
+		"GPL v3 or later":                    "GPL-3.0-or-later",
+		"Mozilla Public License Version 2.0": "MPL-2.0",
+		"CDDL 1.1":                           "CDDL-1.1",
+		"Some proprietary license":           "",
+		"":                                   "",
+	}
+	for license, expected := range tests {
+		if spdx := NormalizeLicense(license); spdx != expected {
+			t.Errorf("expected %q for %q, got %q", expected, license, spdx)
+		}
+	}
+}
+
+func TestDetectLicense(t *testing.T) {
+	tests := map[string]string{
+		"Apache License\n   Version 2.0, January 2004\n   http://www.apache.org/licenses/": "Apache-2.0",
+		"Copyright (c) 2009 The Go Authors. All rights reserved.\n\nRedistribution and use in source and binary forms, with or without\nmodification, are permitted provided that the following conditions are\nmet:\n...\n   * Neither the name of Google Inc. nor the names of its": "BSD-3-Clause",
+		"MIT License\n\nPermission is hereby granted, free of charge, to any person obtaining a copy": "MIT",
+		"All rights reserved, do not copy": "",
+	}
+	for text, expected := range tests {
+		if spdx := DetectLicense([]byte(text)); spdx != expected {
+			t.Errorf("expected %q for %q, got %q", expected, text, spdx)
+		}
+	}
+}
+
+func TestLicenseDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "licenses.yaml")
+	content := `- name: org\.acme\..*
+  version: ^1\.
+  licenses: [LicenseRef-acme]
+- name: org\.acme\..*
+  licenses: ["The Apache Software License, Version 2.0"]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := GetLicenseDatabaseFromConfig(InitConfig{ProviderSpecificConfig: map[string]interface{}{
+		DepLicensesFileConfigKey: path,
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := map[uri.URI][]*Dep{
+		"file:///app/pom.xml": {
+			{Name: "org.acme.core", Version: "1.2.0", Labels: []string{"konveyor.io/dep-license=MIT"}},
+			{Name: "org.acme.web", Version: "2.0.0"},
+			{Name: "io.other.lib", Version: "1.0.0", Labels: []string{"konveyor.io/dep-license=MIT"}},
+			{Name: "io.other.util", Version: "1.0.0"},
+		},
+	}
+	db.AddLicenseLabels(deps)
+	expected := [][]string{
+		{"konveyor.io/dep-license=LicenseRef-acme"},
+		{"konveyor.io/dep-license=Apache-2.0"},
+		{"konveyor.io/dep-license=MIT"},
+		{"konveyor.io/dep-license=unknown"},
+	}
+	for i, dep := range deps["file:///app/pom.xml"] {
+		if !reflect.DeepEqual(dep.Labels, expected[i]) {
+			t.Errorf("expected labels %v for %s, got %v", expected[i], dep.Name, dep.Labels)
+		}
+	}
+
+	dag := map[uri.URI][]DepDAGItem{
+		"file:///app/pom.xml": {
+			{Dep: Dep{Name: "io.other.lib"}, AddedDeps: []DepDAGItem{{Dep: Dep{Name: "org.acme.web"}}}},
+		},
+	}
+	db.AddLicenseLabelsDAG(dag)
+	if labels := dag["file:///app/pom.xml"][0].AddedDeps[0].Dep.Labels; !reflect.DeepEqual(labels, expected[1]) {
+		t.Errorf("expected labels %v for the added dependency, got %v", expected[1], labels)
+	}
+
+	if err := os.WriteFile(path, []byte("- name: org\\.acme\\..*\n  licenses: [not a/license]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLicenseDatabase(path); err == nil {
+		t.Errorf("expected an error for an invalid license")
+	}
+	if db, err := GetLicenseDatabaseFromConfig(InitConfig{}); db != nil || err != nil {
+		t.Errorf("expected no database without %s", DepLicensesFileConfigKey)
+	}
+}
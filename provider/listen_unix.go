@@ -0,0 +1,34 @@
+//go:build !windows
+
+package provider
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// listenSocket listens on the unix socket of the path, the socket left behind
+// by a provider that did not stop is replaced. Only the user of the provider
+// can connect to the socket.
+func listenSocket(path string) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another server listens on %s", path)
+		}
+		os.Remove(path)
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		lis.Close()
+		return nil, err
+	}
+	return lis, nil
+}
@@ -0,0 +1,59 @@
+//go:build !windows
+
+package provider
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func Test_serverSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "java.sock")
+	// a socket left behind by a provider that did not stop
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	s := NewServer(&preparingClient{}, 0, "", "", "", logr.Discard(), WithSocket(path))
+	go s.Start(context.Background())
+
+	conn, err := grpc.Dial("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true)); err != nil {
+		t.Fatalf("request on the socket failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("only the user of the provider must connect to the socket, got %v %v", info.Mode(), err)
+	}
+	if _, err := listenSocket(path); err == nil {
+		t.Errorf("listening on the socket of a running server must fail")
+	}
+}
+
+func Test_listenSocketNotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "java.sock")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenSocket(path); err == nil || err.Error() != path+" is not a socket" {
+		t.Errorf("listenSocket() error = %v, files must not be replaced", err)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package provider
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// namedPipePrefix starts the paths of the named pipes
+const namedPipePrefix = `\\.\pipe\`
+
+// listenSocket listens on the named pipe of a path starting with
+// \\.\pipe\, or else on the unix socket of the path. The socket left behind
+// by a provider that did not stop is replaced.
+func listenSocket(path string) (net.Listener, error) {
+	if strings.HasPrefix(path, namedPipePrefix) {
+		return winio.ListenPipe(path, nil)
+	}
+	os.Remove(path)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,147 @@
+// Package mock is a provider responding to the conditions of the rules with
+// canned incidents instead of analyzing an application, to work on rules
+// without running language servers. The analyzer runs it for the providers
+// of the provider settings with a fixtureFile, the testing package runs it in
+// the Go tests of rulesets.
+package mock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
+)
+
+// FixtureFile is the content of the fixture file of a mock provider
+type FixtureFile struct {
+	// Fixtures are the responses of the provider
+	Fixtures []Fixture `yaml:"fixtures,omitempty" json:"fixtures,omitempty"`
+	// Capabilities are capabilities of the provider besides the ones of the
+	// fixtures, their conditions never match
+	Capabilities []string `yaml:"capabilities,omitempty" json:"capabilities,omitempty"`
+	// Dependencies are the dependencies of the application by the file they
+	// are declared in
+	Dependencies map[uri.URI][]*provider.Dep `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+}
+
+// Fixture is what a fixture provider responds to the conditions of one of
+// its capabilities
+type Fixture struct {
+	// Capability is the capability of the conditions the fixture responds to
+	Capability string `yaml:"capability" json:"capability"`
+	// Condition only responds to the conditions whose condition info, the
+	// YAML sent to the provider, contains it, such as the pattern of a
+	// referenced condition. Every condition of the capability by default.
+	Condition string `yaml:"condition,omitempty" json:"condition,omitempty"`
+	// Incidents are the incidents found, the condition matches when there is
+	// one
+	Incidents []provider.IncidentContext `yaml:"incidents,omitempty" json:"incidents,omitempty"`
+	// TemplateContext is the context of the conditions chained to the
+	// condition
+	TemplateContext map[string]interface{} `yaml:"templateContext,omitempty" json:"templateContext,omitempty"`
+}
+
+// Provider is a provider responding to conditions with fixtures instead of
+// analyzing an application. It has the capabilities of its fixtures and the
+// extra ones, and the dependency capability when it has dependencies.
+type Provider struct {
+	// Name is the name of the provider in the rules, such as java
+	Name string
+	// Fixtures are the responses of the provider, the incidents of every
+	// fixture responding to a condition are added up
+	Fixtures []Fixture
+	// ExtraCapabilities are capabilities besides the ones of the fixtures
+	ExtraCapabilities []string
+	// Dependencies are the dependencies of the application by the file they
+	// are declared in
+	Dependencies map[uri.URI][]*provider.Dep
+}
+
+var _ provider.InternalProviderClient = &Provider{}
+
+// NewMockProvider creates the provider of the config responding with the
+// fixtures of its fixture file
+func NewMockProvider(config provider.Config) (*Provider, error) {
+	if config.FixtureFile == "" {
+		return nil, fmt.Errorf("the fixtureFile of the mock provider %s is required", config.Name)
+	}
+	content, err := os.ReadFile(config.FixtureFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the fixture file of provider %s: %w", config.Name, err)
+	}
+	file := FixtureFile{}
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return nil, fmt.Errorf("unable to read the fixture file %s of provider %s: %w", config.FixtureFile, config.Name, err)
+	}
+	return &Provider{
+		Name:              config.Name,
+		Fixtures:          file.Fixtures,
+		ExtraCapabilities: file.Capabilities,
+		Dependencies:      file.Dependencies,
+	}, nil
+}
+
+func (p *Provider) Capabilities() []provider.Capability {
+	caps := []provider.Capability{}
+	for _, name := range p.ExtraCapabilities {
+		if !provider.HasCapability(caps, name) {
+			caps = append(caps, provider.Capability{Name: name})
+		}
+	}
+	for _, fixture := range p.Fixtures {
+		if !provider.HasCapability(caps, fixture.Capability) {
+			caps = append(caps, provider.Capability{Name: fixture.Capability})
+		}
+	}
+	if len(p.Dependencies) > 0 && !provider.HasCapability(caps, "dependency") {
+		caps = append(caps, provider.Capability{Name: "dependency"})
+	}
+	return caps
+}
+
+func (p *Provider) Init(context.Context, logr.Logger, provider.InitConfig) (provider.ServiceClient, provider.InitConfig, error) {
+	return p, provider.InitConfig{}, nil
+}
+
+func (p *Provider) ProviderInit(context.Context, []provider.InitConfig) ([]provider.InitConfig, error) {
+	return nil, nil
+}
+
+func (p *Provider) Evaluate(ctx context.Context, cap string, conditionInfo []byte) (provider.ProviderEvaluateResponse, error) {
+	response := provider.ProviderEvaluateResponse{
+		Incidents:       []provider.IncidentContext{},
+		TemplateContext: map[string]interface{}{},
+	}
+	for _, fixture := range p.Fixtures {
+		if fixture.Capability != cap || !strings.Contains(string(conditionInfo), fixture.Condition) {
+			continue
+		}
+		response.Incidents = append(response.Incidents, fixture.Incidents...)
+		for k, v := range fixture.TemplateContext {
+			response.TemplateContext[k] = v
+		}
+	}
+	response.Matched = len(response.Incidents) > 0
+	return response, nil
+}
+
+func (p *Provider) GetDependencies(ctx context.Context) (map[uri.URI][]*provider.Dep, error) {
+	return p.Dependencies, nil
+}
+
+func (p *Provider) GetDependenciesDAG(ctx context.Context) (map[uri.URI][]provider.DepDAGItem, error) {
+	dag := map[uri.URI][]provider.DepDAGItem{}
+	for file, deps := range p.Dependencies {
+		for _, dep := range deps {
+			dag[file] = append(dag[file], provider.DepDAGItem{Dep: *dep})
+		}
+	}
+	return dag, nil
+}
+
+func (p *Provider) Stop() {}
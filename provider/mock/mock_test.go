@@ -0,0 +1,93 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+)
+
+const fixtureFile = `
+capabilities:
+- referenced
+fixtures:
+- capability: referenced
+  condition: javax.ejb.Stateless
+  incidents:
+  - fileURI: file:///src/App.java
+    lineNumber: 5
+    variables:
+      name: Stateless
+- capability: xml
+  incidents:
+  - fileURI: file:///pom.xml
+  templateContext:
+    filepaths: [/pom.xml]
+dependencies:
+  file:///pom.xml:
+  - name: junit.junit
+    version: 4.13.2
+`
+
+func TestNewMockProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	if err := os.WriteFile(path, []byte(fixtureFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewMockProvider(provider.Config{Name: "java", FixtureFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caps := []string{}
+	for _, cap := range p.Capabilities() {
+		caps = append(caps, cap.Name)
+	}
+	if !reflect.DeepEqual(caps, []string{"referenced", "xml", "dependency"}) {
+		t.Errorf("Capabilities() = %v", caps)
+	}
+
+	tests := []struct {
+		name      string
+		cap       string
+		condition string
+		incidents int
+	}{
+		{name: "condition of the fixture", cap: "referenced", condition: "referenced:\n  pattern: javax.ejb.Stateless\n", incidents: 1},
+		{name: "other condition", cap: "referenced", condition: "referenced:\n  pattern: javax.ejb.Stateful\n"},
+		{name: "any condition of the capability", cap: "xml", condition: "xml:\n  xpath: //dependency\n", incidents: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := p.Evaluate(context.Background(), tt.cap, []byte(tt.condition))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Incidents) != tt.incidents || response.Matched != (tt.incidents > 0) {
+				t.Errorf("Evaluate() = %+v, want %d incidents", response, tt.incidents)
+			}
+		})
+	}
+	deps, err := p.GetDependencies(context.Background())
+	if err != nil || len(deps["file:///pom.xml"]) != 1 || deps["file:///pom.xml"][0].Version != "4.13.2" {
+		t.Errorf("GetDependencies() = %v %v", deps, err)
+	}
+}
+
+func TestNewMockProviderErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	if err := os.WriteFile(path, []byte("fixture: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, config := range []provider.Config{
+		{Name: "mock"},
+		{Name: "java", FixtureFile: filepath.Join(filepath.Dir(path), "missing.yaml")},
+		{Name: "java", FixtureFile: path},
+	} {
+		if _, err := NewMockProvider(config); err == nil {
+			t.Errorf("NewMockProvider(%v) must fail", config)
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/hashicorp/go-version"
 	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/fileuri"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/tracing"
 	jsonschema "github.com/swaggest/jsonschema-go"
@@ -31,9 +34,23 @@ const (
 	DepSourceLabel   = "konveyor.io/dep-source"
 	DepLanguageLabel = "konveyor.io/language"
 	DepExcludeLabel  = "konveyor.io/exclude"
+	// DepLicenseLabel is the SPDX identifier of the license of a dependency, unknown when it couldn't be detected
+	DepLicenseLabel = "konveyor.io/dep-license"
+	// DepLicensesFileConfigKey is a provider specific config with the path to a database of the licenses of the
+	// dependencies, it takes precedence over the licenses detected by the providers
+	DepLicensesFileConfigKey = "depLicensesFile"
 	// LspServerPath is a provider specific config used to specify path to a LSP server
 	LspServerPathConfigKey = "lspServerPath"
+	// IncludedPathsConfigKey and ExcludedPathsConfigKey are provider specific configs with the paths, or globs
+	// of paths, relative to the location the analysis is limited to and the ones left out of it
 	IncludedPathsConfigKey = "includedPaths"
+	ExcludedPathsConfigKey = "excludedPaths"
+	// FeatureFlagsConfigKey is a provider specific config set by the analyzer with the enabled feature flags
+	FeatureFlagsConfigKey = "featureFlags"
+	// PreparedDirConfigKey is a provider specific config set by the analyzer with the directory the provider
+	// keeps the artifacts of its preparation in, like decompiled dependencies, symbol caches or resolved
+	// dependencies. Artifacts found there are reused instead of preparing again.
+	PreparedDirConfigKey = "preparedDir"
 )
 
 // We need to make these Vars, because you can not take a pointer of the constant.
@@ -90,6 +107,28 @@ type Config struct {
 	Proxy        *Proxy       `yaml:"proxyConfig,omitempty" json:"proxyConfig,omitempty"`
 	InitConfig   []InitConfig `yaml:"initConfig,omitempty" json:"initConfig,omitempty"`
 	ContextLines int
+
+	// MaxConcurrent is the most rules with conditions of the provider that
+	// are evaluated at once, unlimited when 0
+	MaxConcurrent int `yaml:"maxConcurrent,omitempty" json:"maxConcurrent,omitempty"`
+
+	// Image is run with podman or docker when there is no BinaryPath
+	Image string `yaml:"image,omitempty" json:"image,omitempty"`
+	// ProviderArgs are passed to the binary or image after the port and
+	// name the analyzer sets, ProviderEnv is added to their environment
+	ProviderArgs []string          `yaml:"providerArgs,omitempty" json:"providerArgs,omitempty"`
+	ProviderEnv  map[string]string `yaml:"providerEnv,omitempty" json:"providerEnv,omitempty"`
+
+	// ClientCertPath and ClientKeyPath authenticate the analyzer to a
+	// provider verifying the certificates of its clients, JWTTokenFile is
+	// read again for every request so that the token can be rotated
+	ClientCertPath string `yaml:"clientCertPath,omitempty" json:"clientCertPath,omitempty"`
+	ClientKeyPath  string `yaml:"clientKeyPath,omitempty" json:"clientKeyPath,omitempty"`
+	JWTTokenFile   string `yaml:"jwtTokenFile,omitempty" json:"jwtTokenFile,omitempty"`
+
+	// FixtureFile replaces the provider by a mock responding with the canned
+	// incidents of the file, see the mock package
+	FixtureFile string `yaml:"fixtureFile,omitempty" json:"fixtureFile,omitempty"`
 }
 
 type Proxy httpproxy.Config
@@ -151,6 +190,17 @@ func GetConfig(filepath string) ([]Config, error) {
 		return nil, err
 	}
 
+	// the settings are checked before they are read in configs, whose errors
+	// are about Go types and stop at the first one
+	var settings interface{}
+	err = yaml.Unmarshal(content, &settings)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateSettings(settings); err != nil {
+		return nil, fmt.Errorf("invalid provider settings %s:\n%w", filepath, err)
+	}
+
 	configs := []Config{}
 
 	err = yaml.Unmarshal(content, &configs)
@@ -159,6 +209,9 @@ func GetConfig(filepath string) ([]Config, error) {
 	}
 	for idx := range configs {
 		c := &configs[idx]
+		if c.MaxConcurrent < 0 {
+			return configs, fmt.Errorf("maxConcurrent of provider %s must not be negative", c.Name)
+		}
 		// default to system-wide proxy
 		if c.Proxy == nil {
 			c.Proxy = (*Proxy)(httpproxy.FromEnvironment())
@@ -174,6 +227,12 @@ func GetConfig(filepath string) ([]Config, error) {
 			if err != nil {
 				return configs, err
 			}
+			if schema, ok := GetConfigSchema(c.Name); ok {
+				newConfig, err = schema.Validate(newConfig)
+				if err != nil {
+					return configs, fmt.Errorf("invalid providerSpecificConfig of provider %s: %w", c.Name, err)
+				}
+			}
 			ic.ProviderSpecificConfig = newConfig
 
 		}
@@ -285,6 +344,9 @@ type ProviderEvaluateResponse struct {
 	Matched         bool                   `yaml:"matched"`
 	Incidents       []IncidentContext      `yaml:"incidents"`
 	TemplateContext map[string]interface{} `yaml:"templateContext"`
+	// Warnings are non-fatal issues that may make the result incomplete,
+	// such as files that were skipped, they are shown in the output of the rule.
+	Warnings []string `yaml:"warnings,omitempty"`
 }
 
 type IncidentContext struct {
@@ -295,6 +357,9 @@ type IncidentContext struct {
 	Links                []ExternalLinks        `yaml:"externalLink,omitempty"`
 	CodeLocation         *Location              `yaml:"location,omitempty"`
 	IsDependencyIncident bool
+	// MatchedText is the exact text matched by the condition, the custom
+	// variables of the rule are extracted from it
+	MatchedText string `yaml:"matchedText,omitempty"`
 }
 
 type Location struct {
@@ -366,6 +431,7 @@ func FullResponseFromServiceClients(ctx context.Context, clients []ServiceClient
 			fullResp.Matched = r.Matched
 		}
 		fullResp.Incidents = append(fullResp.Incidents, r.Incidents...)
+		fullResp.Warnings = append(fullResp.Warnings, r.Warnings...)
 		for k, v := range r.TemplateContext {
 			fullResp.TemplateContext[k] = v
 		}
@@ -449,6 +515,15 @@ type Startable interface {
 	Start(context.Context) error
 }
 
+// VersionedClient is a provider client knowing the version of the protocol
+// its provider talks and the build of the provider, such as the clients of
+// providers run as gRPC servers. The build is empty for providers built
+// before it was reported.
+type VersionedClient interface {
+	ProtocolVersion() int32
+	ProviderVersion() string
+}
+
 type CodeSnipProvider struct {
 	Providers []engine.CodeSnip
 }
@@ -467,18 +542,74 @@ func (p CodeSnipProvider) GetCodeSnip(u uri.URI, l engine.Location) (string, err
 
 type ProviderCondition struct {
 	Client           ServiceClient
+	ProviderName     string
 	Capability       string
 	ConditionInfo    interface{}
 	Rule             engine.Rule
 	Ignore           bool
 	DepLabelSelector *labels.LabelSelector[*Dep]
+	// ResultCache keeps the responses of the client across runs when set
+	ResultCache *ConditionResultCache
 }
 
 func (p ProviderCondition) Ignorable() bool {
 	return p.Ignore
 }
 
+// Providers returns the provider of the condition, the engine limits the
+// rules evaluated at once by provider
+func (p ProviderCondition) Providers() []string {
+	return []string{p.ProviderName}
+}
+
+// Cost returns how expensive the condition is to evaluate, the conditions
+// of the builtin provider only read files while the other providers mostly
+// query language servers
+func (p ProviderCondition) Cost() engine.Cost {
+	if p.ProviderName == builtinConfig.Name {
+		return engine.LowCost
+	}
+	return engine.HighCost
+}
+
+// Evaluate evaluates the condition with the provider. A condition referring
+// to the variables of the incidents of a chained condition, such as
+// {{imports.package}}, is evaluated once per distinct value of the variables,
+// and its incidents get the values they were found with.
 func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx engine.ConditionContext) (engine.ConditionResponse, error) {
+	references := chainReferences(p.ConditionInfo, condCtx.Template)
+	if len(references) == 0 {
+		return p.evaluate(ctx, log, condCtx, p.ConditionInfo)
+	}
+	fullResponse := engine.ConditionResponse{
+		Incidents:       []engine.IncidentContext{},
+		TemplateContext: map[string]interface{}{},
+	}
+	bindings, truncated := chainBindings(references, condCtx.Template, maxChainBindings)
+	if truncated {
+		fullResponse.Warnings = append(fullResponse.Warnings, konveyor.Warning{
+			Provider: p.ProviderName,
+			Message:  fmt.Sprintf("only the first %d values of the chained variables of rule %s were evaluated", maxChainBindings, condCtx.RuleID),
+		})
+	}
+	log.V(5).Info("evaluating condition for each value of the chained variables", "ruleID", condCtx.RuleID, "variables", references, "values", len(bindings))
+	for _, binding := range bindings {
+		response, err := p.evaluate(ctx, log, condCtx, bindConditionInfo(p.ConditionInfo, binding))
+		if err != nil {
+			return engine.ConditionResponse{}, err
+		}
+		fullResponse.Matched = fullResponse.Matched || response.Matched
+		for _, incident := range response.Incidents {
+			incident.Variables = withBindingVariables(incident.Variables, binding)
+			fullResponse.Incidents = append(fullResponse.Incidents, incident)
+		}
+		fullResponse.Warnings = append(fullResponse.Warnings, response.Warnings...)
+		mergeTemplateContext(fullResponse.TemplateContext, response.TemplateContext)
+	}
+	return fullResponse, nil
+}
+
+func (p ProviderCondition) evaluate(ctx context.Context, log logr.Logger, condCtx engine.ConditionContext, conditionInfo interface{}) (engine.ConditionResponse, error) {
 	ctx, span := tracing.StartNewSpan(
 		ctx, "provider-condition", attribute.Key("cap").String(p.Capability))
 	defer span.End()
@@ -493,7 +624,7 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 			RuleID:   condCtx.RuleID,
 		},
 		Capability: map[string]interface{}{
-			p.Capability: p.ConditionInfo,
+			p.Capability: conditionInfo,
 		},
 	}
 
@@ -509,22 +640,38 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 		panic(err)
 	}
 	span.SetAttributes(attribute.Key("condition").String(string(templatedInfo)))
-	resp, err := p.Client.Evaluate(ctx, p.Capability, templatedInfo)
+	resp, cached, err := p.evaluateClient(ctx, log, providerInfo.Capability, condCtx, templatedInfo)
+	span.SetAttributes(attribute.Key("cached").Bool(cached))
+	record := EvaluationRecord{
+		Provider:   p.ProviderName,
+		Capability: p.Capability,
+		Condition:  string(templatedInfo),
+		Response:   resp,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	recordEvaluation(ctx, condCtx, record)
 	if err != nil {
 		// If an error always just return the empty
-		return engine.ConditionResponse{}, err
+		return engine.ConditionResponse{}, p.error(err)
+	}
+
+	warnings := []konveyor.Warning{}
+	for _, w := range resp.Warnings {
+		warnings = append(warnings, konveyor.Warning{Provider: p.ProviderName, Message: w})
 	}
 
 	if len(resp.Incidents) == 0 {
 		log.V(5).Info("no incidents found")
-		return engine.ConditionResponse{}, err
+		return engine.ConditionResponse{Warnings: warnings}, err
 	}
 
 	var deps map[uri.URI][]*Dep
 	if p.DepLabelSelector != nil {
 		deps, err = p.Client.GetDependencies(ctx)
 		if err != nil {
-			return engine.ConditionResponse{}, err
+			return engine.ConditionResponse{}, p.error(err)
 		}
 		deps = deduplicateDependencies(deps)
 	}
@@ -542,8 +689,11 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 			FileURI:    inc.FileURI,
 			Effort:     inc.Effort,
 			LineNumber: inc.LineNumber,
-			Variables:  inc.Variables,
-			Links:      p.Rule.Perform.Message.Links,
+			// the variables are changed with the incident, the response
+			// can be shared by the rules through the condition cache
+			Variables:   maps.Clone(inc.Variables),
+			Links:       p.Rule.Perform.Message.Links,
+			MatchedText: inc.MatchedText,
 		}
 
 		if inc.CodeLocation != nil {
@@ -565,7 +715,8 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 	if len(incidents) == 0 && len(resp.Incidents)-len(incidents) > 0 {
 		log.V(5).Info("filtered out all incidents based on dep label selector", "filteredOutCount", len(resp.Incidents)-len(incidents))
 		return engine.ConditionResponse{
-			Matched: resp.Matched,
+			Matched:  resp.Matched,
+			Warnings: warnings,
 		}, nil
 	}
 
@@ -573,9 +724,10 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 		Matched:         resp.Matched,
 		TemplateContext: resp.TemplateContext,
 		Incidents:       incidents,
+		Warnings:        warnings,
 	}
 
-	log.V(8).Info("condition response", "ruleID", p.Rule.RuleID, "response", cr, "cap", p.Capability, "conditionInfo", p.ConditionInfo, "client", p.Client)
+	log.V(8).Info("condition response", "ruleID", p.Rule.RuleID, "response", cr, "cap", p.Capability, "conditionInfo", conditionInfo, "client", p.Client)
 	if len(resp.Incidents)-len(incidents) > 0 {
 		log.V(5).Info("filtered out incidents based on dep label selector", "filteredOutCount", len(resp.Incidents)-len(incidents), "keptCount", len(incidents))
 	}
@@ -583,6 +735,70 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 
 }
 
+// evaluateClient evaluates the condition with the client, or returns the
+// response of the same condition evaluated before during the run, or during
+// a previous run when the condition has a result cache. The conditions are
+// the same for the same provider, capability, condition info, tags and chain
+// context, which holds the scope of the files, whatever rule they are in.
+func (p ProviderCondition) evaluateClient(ctx context.Context, log logr.Logger, capability map[string]interface{}, condCtx engine.ConditionContext, templatedInfo []byte) (ProviderEvaluateResponse, bool, error) {
+	cache := engine.ConditionCacheFromContext(ctx)
+	if cache == nil && p.ResultCache == nil {
+		resp, err := p.Client.Evaluate(ctx, p.Capability, templatedInfo)
+		return resp, false, err
+	}
+	normalizedInfo, err := yaml.Marshal(struct {
+		ProviderContext `yaml:",inline"`
+		Capability      map[string]interface{} `yaml:",inline"`
+	}{
+		ProviderContext: ProviderContext{Tags: condCtx.Tags, Template: condCtx.Template},
+		Capability:      capability,
+	})
+	if err != nil {
+		resp, err := p.Client.Evaluate(ctx, p.Capability, templatedInfo)
+		return resp, false, err
+	}
+	key := ConditionKey(p.ProviderName, p.Capability, normalizedInfo)
+	evaluate := func() (ProviderEvaluateResponse, bool, error) {
+		if p.ResultCache == nil {
+			resp, err := p.Client.Evaluate(ctx, p.Capability, templatedInfo)
+			return resp, false, err
+		}
+		resultKey := p.ResultCache.Key(p.ProviderName, p.Capability, normalizedInfo)
+		if resp, ok := p.ResultCache.Get(p.ProviderName, resultKey); ok {
+			return resp, true, nil
+		}
+		resp, err := p.Client.Evaluate(ctx, p.Capability, templatedInfo)
+		if err != nil {
+			return resp, false, err
+		}
+		if err := p.ResultCache.Put(p.ProviderName, resultKey, resp); err != nil {
+			log.V(5).Error(err, "unable to cache the response of the condition")
+		}
+		return resp, false, nil
+	}
+	if cache == nil {
+		return evaluate()
+	}
+	persisted := false
+	value, cached, err := cache.Evaluate(key, func() (interface{}, error) {
+		resp, ok, err := evaluate()
+		persisted = ok
+		return resp, err
+	})
+	resp, _ := value.(ProviderEvaluateResponse)
+	return resp, cached || persisted, err
+}
+
+// error adds the provider to an error of the client, keeping the code the
+// client classified it with
+func (p ProviderCondition) error(err error) error {
+	return &engine.ProviderError{
+		Code:     engine.ErrorCode(err),
+		Provider: p.ProviderName,
+		Err:      err,
+	}
+}
+
 // matchDepLabelSelector evaluates the dep label selector on incident
 func matchDepLabelSelector(s *labels.LabelSelector[*Dep], inc IncidentContext, deps map[uri.URI][]*konveyor.Dep) (bool, error) {
 	// always match non dependency URIs or when there are no deps or no dep selector
@@ -597,7 +813,7 @@ func matchDepLabelSelector(s *labels.LabelSelector[*Dep], inc IncidentContext, d
 		}
 		for _, d := range depList {
 			if d.FileURIPrefix != "" &&
-				strings.HasPrefix(string(inc.FileURI), d.FileURIPrefix) {
+				fileuri.HasPrefix(string(inc.FileURI), d.FileURIPrefix) {
 				matched = true
 			}
 		}
@@ -645,20 +861,53 @@ type DependencyCondition struct {
 	DependencyConditionCap
 
 	Client Client
+	// ProviderName is the provider the dependencies are listed by
+	ProviderName string
+	// Cache, when set, is used to share results between analyses of
+	// applications with the same dependencies
+	Cache *DependencyConditionCache
+}
+
+// Providers returns the provider of the condition, the engine limits the
+// rules evaluated at once by provider
+func (dc DependencyCondition) Providers() []string {
+	if dc.ProviderName == "" {
+		return nil
+	}
+	return []string{dc.ProviderName}
 }
 
 func (dc DependencyCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx engine.ConditionContext) (engine.ConditionResponse, error) {
 	_, span := tracing.StartNewSpan(ctx, "dep-condition")
 	defer span.End()
 
-	resp := engine.ConditionResponse{}
 	deps, err := dc.Client.GetDependencies(ctx)
+	if err != nil {
+		return engine.ConditionResponse{}, err
+	}
+	if dc.Cache == nil {
+		return dc.evaluate(ctx, log, deps)
+	}
+	fingerprint := DependencyFingerprint(deps)
+	if resp, ok := dc.Cache.Get(fingerprint, dc.DependencyConditionCap); ok {
+		log.V(5).Info("using cached dependency condition result", "rule", condCtx.RuleID, "fingerprint", fingerprint)
+		return resp, nil
+	}
+	resp, err := dc.evaluate(ctx, log, deps)
 	if err != nil {
 		return resp, err
 	}
+	if err := dc.Cache.Put(fingerprint, dc.DependencyConditionCap, resp); err != nil {
+		log.V(5).Error(err, "unable to cache dependency condition result", "rule", condCtx.RuleID)
+	}
+	return resp, nil
+}
+
+func (dc DependencyCondition) evaluate(ctx context.Context, log logr.Logger, deps map[uri.URI][]*Dep) (engine.ConditionResponse, error) {
+	resp := engine.ConditionResponse{}
 	regex, err := regexp.Compile(dc.NameRegex)
 	if err != nil {
-		return resp, err
+		return resp, engine.NewProviderError(konveyor.ParseError, err)
 	}
 	type matchedDep struct {
 		dep *Dep
@@ -824,15 +1073,59 @@ func getVersion(depVersion string) (*version.Version, error) {
 
 // Convert Dag Item List to flat list.
 func ConvertDagItemsToList(items []DepDAGItem) []*Dep {
+	items, _ = BreakDepDAGCycles(items)
+	return convertDagItemsToList(items)
+}
+
+func convertDagItemsToList(items []DepDAGItem) []*Dep {
 	deps := []*Dep{}
 	for _, i := range items {
 		d := i.Dep
 		deps = append(deps, &d)
-		deps = append(deps, ConvertDagItemsToList(i.AddedDeps)...)
+		deps = append(deps, convertDagItemsToList(i.AddedDeps)...)
 	}
 	return deps
 }
 
+// BreakDepDAGCycles returns a copy of the items where every dependency that is one of its
+// own ancestors is turned into a back-edge, with Cycle set and without added deps.
+// Dependency graphs from gradle or npm can contain cycles, the paths of all the cycles
+// found are returned so they can be reported.
+func BreakDepDAGCycles(items []DepDAGItem) ([]DepDAGItem, [][]string) {
+	cycles := [][]string{}
+	return breakDepDAGCycles(items, []string{}, map[string]bool{}, &cycles), cycles
+}
+
+func breakDepDAGCycles(items []DepDAGItem, path []string, ancestors map[string]bool, cycles *[][]string) []DepDAGItem {
+	if items == nil {
+		return nil
+	}
+	result := make([]DepDAGItem, 0, len(items))
+	for _, item := range items {
+		key := depDAGKey(item.Dep)
+		newItem := DepDAGItem{Dep: item.Dep, Cycle: item.Cycle}
+		if ancestors[key] {
+			start := slices.Index(path, key)
+			newItem.Cycle = append(slices.Clone(path[start:]), key)
+			*cycles = append(*cycles, newItem.Cycle)
+			result = append(result, newItem)
+			continue
+		}
+		ancestors[key] = true
+		newItem.AddedDeps = breakDepDAGCycles(item.AddedDeps, append(path, key), ancestors, cycles)
+		delete(ancestors, key)
+		result = append(result, newItem)
+	}
+	return result
+}
+
+func depDAGKey(d Dep) string {
+	if d.Version == "" {
+		return d.Name
+	}
+	return fmt.Sprintf("%s@%s", d.Name, d.Version)
+}
+
 func deduplicateDependencies(dependencies map[uri.URI][]*Dep) map[uri.URI][]*Dep {
 	// Just need this so I can differentiate between dependencies that aren't found
 	// and dependencies that are at index 0
@@ -0,0 +1,173 @@
+package provider
+
+// The typed providerSpecificConfig of the providers of this repository, the
+// provider settings are validated against their schemas when loaded and the
+// reference docs are generated from them. Struct tags follow the conditions of
+// the capabilities: json names the key, description and default document it.
+
+// ProviderSettings is a provider of the provider settings, the settings are
+// validated against its schema before they are read in a Config
+type ProviderSettings struct {
+	_              struct{}             `additionalProperties:"false"`
+	Name           string               `yaml:"name" json:"name" required:"true" description:"Name of the provider, the prefix of the capabilities of its conditions such as java in java.referenced"`
+	BinaryPath     string               `yaml:"binaryPath,omitempty" json:"binaryPath,omitempty" description:"Path to the binary of the provider the analyzer starts, binaryPath, image or address is required but for the builtin and k8s providers and the providers with a fixtureFile"`
+	Image          string               `yaml:"image,omitempty" json:"image,omitempty" description:"Image of the provider the analyzer runs with podman or docker, not used when binaryPath is set"`
+	ProviderArgs   []string             `yaml:"providerArgs,omitempty" json:"providerArgs,omitempty" description:"Args of the started binary or image, passed after the port and name the analyzer sets"`
+	ProviderEnv    map[string]string    `yaml:"providerEnv,omitempty" json:"providerEnv,omitempty" description:"Environment variables of the started binary, added to the environment of the analyzer, or of the container of the image"`
+	Address        string               `yaml:"address,omitempty" json:"address,omitempty" description:"Address of a running provider, host:port, unix:///path of a unix socket or npipe:////./pipe/name of a named pipe, not used when binaryPath or image is set"`
+	CertPath       string               `yaml:"certPath,omitempty" json:"certPath,omitempty" description:"Path to the CA certificate of the provider, the connection to the provider uses TLS when set"`
+	ClientCertPath string               `yaml:"clientCertPath,omitempty" json:"clientCertPath,omitempty" description:"Path to the certificate of the analyzer, for the providers verifying the certificates of their clients"`
+	ClientKeyPath  string               `yaml:"clientKeyPath,omitempty" json:"clientKeyPath,omitempty" description:"Path to the key of the certificate of the analyzer"`
+	JWTToken       string               `yaml:"jwtToken,omitempty" json:"jwtToken,omitempty" description:"Token authenticating the analyzer to the provider"`
+	JWTTokenFile   string               `yaml:"jwtTokenFile,omitempty" json:"jwtTokenFile,omitempty" description:"Path to the token authenticating the analyzer to the provider, read again for every request so that it can be rotated"`
+	ProxyConfig    *ProxySettings       `yaml:"proxyConfig,omitempty" json:"proxyConfig,omitempty" description:"HTTP proxy of the provider, defaults to the proxy of the environment"`
+	InitConfig     []InitConfigSettings `yaml:"initConfig,omitempty" json:"initConfig,omitempty" description:"Applications analyzed by the provider"`
+	MaxConcurrent  int                  `yaml:"maxConcurrent,omitempty" json:"maxConcurrent,omitempty" description:"Most rules with conditions of the provider evaluated at once, unlimited when 0"`
+	FixtureFile    string               `yaml:"fixtureFile,omitempty" json:"fixtureFile,omitempty" description:"Path to a fixture file of canned incidents the mock provider responds to the conditions with instead of the provider, required by the provider named mock"`
+}
+
+// InitConfigSettings is an init config of a provider of the provider settings
+type InitConfigSettings struct {
+	_                      struct{}               `additionalProperties:"false"`
+	Location               string                 `yaml:"location,omitempty" json:"location,omitempty" description:"Path to the application analyzed"`
+	DependencyPath         string                 `yaml:"dependencyPath,omitempty" json:"dependencyPath,omitempty" description:"Path to the dependencies of the application, relative to the location"`
+	AnalysisMode           string                 `yaml:"analysisMode,omitempty" json:"analysisMode,omitempty" enum:"full,source-only" description:"Whether the dependencies are analyzed with the source code (full) or not (source-only)"`
+	ProviderSpecificConfig map[string]interface{} `yaml:"providerSpecificConfig,omitempty" json:"providerSpecificConfig,omitempty" description:"Config of the provider, see the sections of the providers below"`
+	ProxyConfig            *ProxySettings         `yaml:"proxyConfig,omitempty" json:"proxyConfig,omitempty" description:"HTTP proxy of the application, defaults to the proxy of the provider"`
+}
+
+// ProxySettings is a proxyConfig of the provider settings
+type ProxySettings struct {
+	_          struct{} `additionalProperties:"false"`
+	HTTPProxy  string   `yaml:"httpproxy,omitempty" json:"httpproxy,omitempty" description:"Proxy of the HTTP requests"`
+	HTTPSProxy string   `yaml:"httpsproxy,omitempty" json:"httpsproxy,omitempty" description:"Proxy of the HTTPS requests"`
+	NoProxy    string   `yaml:"noproxy,omitempty" json:"noproxy,omitempty" description:"Comma separated hosts requested without the proxy"`
+}
+
+// CommonProviderConfig are the keys every provider takes
+type CommonProviderConfig struct {
+	IncludedPaths   []string        `yaml:"includedPaths,omitempty" json:"includedPaths,omitempty" description:"Paths or globs of paths the analysis is limited to, relative to the location"`
+	ExcludedPaths   []string        `yaml:"excludedPaths,omitempty" json:"excludedPaths,omitempty" description:"Paths or globs of paths left out of the analysis, relative to the location"`
+	DepLicensesFile string          `yaml:"depLicensesFile,omitempty" json:"depLicensesFile,omitempty" description:"Path to a YAML database of the SPDX licenses of the dependencies, taking precedence over the detected ones"`
+	FeatureFlags    map[string]bool `yaml:"featureFlags,omitempty" json:"featureFlags,omitempty" description:"Set by the analyzer with the enabled feature flags"`
+	PreparedDir     string          `yaml:"preparedDir,omitempty" json:"preparedDir,omitempty" description:"Set by the analyzer with the directory of a prepared analysis, see the prepare command"`
+}
+
+// BuiltinProviderConfig is the providerSpecificConfig of the builtin provider
+type BuiltinProviderConfig struct {
+	_                    struct{} `additionalProperties:"false"`
+	CommonProviderConfig `yaml:",inline"`
+	TagsFile             string `yaml:"tagsFile,omitempty" json:"tagsFile,omitempty" description:"Path to a YAML file with a list of tags of the application"`
+	FileWorkers          int    `yaml:"fileWorkers,omitempty" json:"fileWorkers,omitempty" description:"Number of workers walking the directories and evaluating the files of the filecontent, file and xml conditions, defaults to the number of CPUs"`
+	FileBatchBytes       int64  `yaml:"fileBatchBytes,omitempty" json:"fileBatchBytes,omitempty" description:"Most bytes of files read at once by the workers of all the conditions, defaults to 256MiB, a file bigger than this is read alone"`
+	RespectIgnoreFiles   bool   `yaml:"respectIgnoreFiles,omitempty" json:"respectIgnoreFiles,omitempty" description:"Leave out of the searches the files ignored by the .gitignore and .konveyorignore files of the location"`
+	SkipBinaryFiles      bool   `yaml:"skipBinaryFiles,omitempty" json:"skipBinaryFiles,omitempty" description:"Leave the binary files, the ones with a NUL byte in their first 8000 bytes, out of the filecontent searches"`
+}
+
+// JavaProviderConfig is the providerSpecificConfig of the java provider
+type JavaProviderConfig struct {
+	_                       struct{} `additionalProperties:"false"`
+	CommonProviderConfig    `yaml:",inline"`
+	LspServerName           string   `yaml:"lspServerName,omitempty" json:"lspServerName,omitempty" description:"Name of the language server"`
+	LspServerPath           string   `yaml:"lspServerPath" json:"lspServerPath" required:"true" description:"Path to the jdtls binary"`
+	Bundles                 string   `yaml:"bundles,omitempty" json:"bundles,omitempty" description:"Comma separated paths of extension bundles of the language server, such as the java-analyzer-bundle"`
+	Workspace               string   `yaml:"workspace,omitempty" json:"workspace,omitempty" description:"Path to the workspace of the language server, where it keeps its index and logs"`
+	DepOpenSourceLabelsFile string   `yaml:"depOpenSourceLabelsFile,omitempty" json:"depOpenSourceLabelsFile,omitempty" description:"Path to a file with a regex per line matching the open source dependencies"`
+	MavenSettingsFile       string   `yaml:"mavenSettingsFile,omitempty" json:"mavenSettingsFile,omitempty" description:"Path to the maven settings.xml to use"`
+	MavenCacheDir           string   `yaml:"mavenCacheDir,omitempty" json:"mavenCacheDir,omitempty" description:"Path to the local maven repository"`
+	MavenInsecure           bool     `yaml:"mavenInsecure,omitempty" json:"mavenInsecure,omitempty" default:"false" description:"Skip the verification of the certificates of the maven repositories"`
+	MavenIndexPath          string   `yaml:"mavenIndexPath,omitempty" json:"mavenIndexPath,omitempty" description:"Path to a database of checksums identifying the JARs embedded in binaries"`
+	MavenOffline            bool     `yaml:"mavenOffline,omitempty" json:"mavenOffline,omitempty" default:"false" description:"Never look up embedded JARs on search.maven.org"`
+	DecompileCacheDir       string   `yaml:"decompileCacheDir,omitempty" json:"decompileCacheDir,omitempty" description:"Path to a directory keeping the output of the decompiler between analyses"`
+	FernFlowerPath          string   `yaml:"fernFlowerPath,omitempty" json:"fernFlowerPath,omitempty" default:"/bin/fernflower.jar" description:"Path to the fernflower decompiler JAR"`
+	ExcludePackages         []string `yaml:"excludePackages,omitempty" json:"excludePackages,omitempty" description:"Dependency packages labeled as excluded"`
+	JvmMaxMem               string   `yaml:"jvmMaxMem,omitempty" json:"jvmMaxMem,omitempty" description:"Max memory of the JVM of the language server, passed as -Xmx"`
+	JvmOptions              []string `yaml:"jvmOptions,omitempty" json:"jvmOptions,omitempty" description:"Options of the JVM of the language server, such as -Xms2g, taking precedence over the default ones"`
+	WorkspaceCacheDir       string   `yaml:"workspaceCacheDir,omitempty" json:"workspaceCacheDir,omitempty" description:"Path to a directory keeping the workspace of the language server of a project, reused while the project does not change"`
+	BytecodeAnalysis        bool     `yaml:"bytecodeAnalysis,omitempty" json:"bytecodeAnalysis,omitempty" description:"Answer the referenced conditions of binaries with their class files, without decompiling them or starting the language server"`
+}
+
+// LSPServiceClientConfig is the providerSpecificConfig of the providers based
+// on the generic provider, the service clients embed it in their config.
+// Service clients take keys of their own, they are not rejected.
+type LSPServiceClientConfig struct {
+	CommonProviderConfig `yaml:",inline"`
+
+	// The name of the server. Think `yaml_language_server` not `yaml`
+	LspServerName string `yaml:"lspServerName,omitempty" json:"lspServerName,omitempty" default:"generic" description:"Name of the service client of the language server, such as generic, pylsp, nodejs, php, ruby or yaml_language_server"`
+
+	// Where the binary of the server is. Not a URI. Passed to exec.CommandContext
+	LspServerPath string `yaml:"lspServerPath,omitempty" json:"lspServerPath" required:"true" description:"Path to the language server binary"`
+
+	// The args of the lsp server. Passed to exec.CommandContext.
+	LspServerArgs []string `yaml:"lspServerArgs,omitempty" json:"lspServerArgs,omitempty" description:"Arguments of the language server"`
+
+	// JSON string that can get sent to the initialize request instead of the
+	// computed options in the service client. Each service client can implement
+	// this differently. Must be a string due to grpc not allowing nested structs.
+	LspServerInitializationOptions string `yaml:"lspServerInitializationOptions,omitempty" json:"lspServerInitializationOptions,omitempty" description:"JSON initialization options sent to the language server instead of the computed ones"`
+
+	// Full URI of the workspace folders under consideration
+	WorkspaceFolders []string `yaml:"workspaceFolders,omitempty" json:"workspaceFolders,omitempty" description:"URIs of the workspace folders"`
+	// Full URI of the dependency folders under consideration. Used for ignoring
+	// results from things like `referenced`
+	DependencyFolders []string `yaml:"dependencyFolders,omitempty" json:"dependencyFolders,omitempty" description:"URIs of the dependency folders, results in them are ignored"`
+
+	// Path to a simple binary that lists the dependencies for a given language.
+	DependencyProviderPath string `yaml:"dependencyProviderPath,omitempty" json:"dependencyProviderPath,omitempty" description:"Path to a binary printing the dependencies of the application"`
+
+	// Maximum number of requests sent to the server at the same time. Servers
+	// such as pylsp fail under bursts of requests while others, such as gopls,
+	// answer faster with more. Defaults to 10.
+	LspMaxConcurrentRequests int `yaml:"lspMaxConcurrentRequests,omitempty" json:"lspMaxConcurrentRequests,omitempty" default:"10" description:"Maximum number of requests sent to the language server at the same time"`
+
+	// Number of workers searching the files of the workspace for a pattern when
+	// the server can't find declarations itself. Defaults to the number of CPUs.
+	FileSearchWorkers int `yaml:"fileSearchWorkers,omitempty" json:"fileSearchWorkers,omitempty" description:"Number of workers searching the files for a pattern when the language server can't, defaults to the number of CPUs"`
+
+	// The quick fixes of the server at the location of the incidents are
+	// added to their variables when the server supports code actions, it
+	// takes a request per incident.
+	DisableCodeActions bool `yaml:"disableCodeActions,omitempty" json:"disableCodeActions,omitempty" description:"Do not ask the language server for the quick fixes of the incidents"`
+
+	// The incidents found by searching the text of the files, when the
+	// server can't find the declarations of a pattern, have the
+	// textSearchFallback variable. Strict matches leave them out.
+	StrictMatches bool `yaml:"strictMatches,omitempty" json:"strictMatches,omitempty" description:"Leave out the incidents found by searching the text of the files when the language server can't find the declarations of a pattern, they have the textSearchFallback variable otherwise"`
+}
+
+// RubyProviderConfig is the providerSpecificConfig of the ruby service client
+// of the generic provider
+type RubyProviderConfig struct {
+	LSPServiceClientConfig `yaml:",inline"`
+
+	BundlerPath string `yaml:"bundlerPath,omitempty" json:"bundlerPath,omitempty" default:"bundle" description:"Path to the bundler binary resolving the gems of applications without a Gemfile.lock"`
+}
+
+// YqProviderConfig is the providerSpecificConfig of the yq provider
+type YqProviderConfig struct {
+	_                      struct{} `additionalProperties:"false"`
+	CommonProviderConfig   `yaml:",inline"`
+	Name                   string   `yaml:"name,omitempty" json:"name,omitempty" description:"Name of the provider in the logs"`
+	LspServerPath          string   `yaml:"lspServerPath" json:"lspServerPath" required:"true" description:"Path to the yq binary"`
+	LspArgs                []string `yaml:"lspArgs,omitempty" json:"lspArgs,omitempty" description:"Arguments of yq"`
+	DependencyProviderPath string   `yaml:"dependencyProviderPath,omitempty" json:"dependencyProviderPath,omitempty" description:"Path to a binary printing the dependencies of the application"`
+	Templates              string   `yaml:"templates,omitempty" json:"templates,omitempty" default:"tolerate" enum:"tolerate,render,none" description:"How the go-template actions of the documents, such as the ones of Helm charts, are handled: tolerate replaces them so that the documents can be parsed, render renders the Helm charts with helm template, none skips the documents with any"`
+	HelmPath               string   `yaml:"helmPath,omitempty" json:"helmPath,omitempty" default:"helm" description:"Path to the helm binary rendering the charts"`
+}
+
+// K8sProviderConfig is the providerSpecificConfig of the k8s provider
+type K8sProviderConfig struct {
+	_                    struct{} `additionalProperties:"false"`
+	CommonProviderConfig `yaml:",inline"`
+	HelmPath             string `yaml:"helmPath,omitempty" json:"helmPath,omitempty" default:"helm" description:"Path to the helm binary rendering the charts"`
+}
+
+// DotnetProviderConfig is the providerSpecificConfig of the dotnet provider
+type DotnetProviderConfig struct {
+	_                    struct{} `additionalProperties:"false"`
+	CommonProviderConfig `yaml:",inline"`
+	LspServerPath        string   `yaml:"lspServerPath" json:"lspServerPath" required:"true" description:"Path to the csharp-ls binary"`
+	SolutionFile         string   `yaml:"solutionFile,omitempty" json:"solutionFile,omitempty" description:"Solution analyzed, relative to the location, defaults to the .sln at the root of the location"`
+	TargetFrameworks     []string `yaml:"targetFrameworks,omitempty" json:"targetFrameworks,omitempty" description:"Target frameworks the projects are analyzed for, such as net48, defaults to all of the ones of the projects"`
+}
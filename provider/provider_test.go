@@ -2,8 +2,10 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/go-logr/logr"
@@ -11,6 +13,7 @@ import (
 	"github.com/konveyor/analyzer-lsp/engine/labels"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
 )
 
 var _ Client = &fakeClient{}
@@ -146,6 +149,48 @@ func Test_dependencyConditionEvaluation(t *testing.T) {
 
 }
 
+func Test_dependencyConditionCache(t *testing.T) {
+	cache, err := NewDependencyConditionCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	capability := DependencyConditionCap{Name: "DE", Upperbound: "4.2.1"}
+	evaluate := func(deps []*Dep) engine.ConditionResponse {
+		resp, err := DependencyCondition{
+			DependencyConditionCap: capability,
+			Client:                 &fakeClient{dependencies: deps},
+			Cache:                  cache,
+		}.Evaluate(context.TODO(), logr.Discard(), engine.ConditionContext{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	deps := []*Dep{{Name: "DE", Version: "v4.0.0"}}
+	first := evaluate(deps)
+	if !first.Matched {
+		t.Fatalf("expected dependency condition to match")
+	}
+	if _, ok := cache.Get(DependencyFingerprint(map[uri.URI][]*Dep{"test": deps}), capability); !ok {
+		t.Fatalf("expected dependency condition result to be cached")
+	}
+
+	// another application with the same dependencies gets the cached result
+	cached := evaluate([]*Dep{{Name: "DE", Version: "v4.0.0"}})
+	if !cached.Matched || len(cached.Incidents) != 1 ||
+		cached.Incidents[0].FileURI != first.Incidents[0].FileURI ||
+		!reflect.DeepEqual(cached.Incidents[0].Variables, first.Incidents[0].Variables) ||
+		!reflect.DeepEqual(cached.TemplateContext, first.TemplateContext) {
+		t.Errorf("expected cached result %#v, got %#v", first, cached)
+	}
+
+	// different dependencies are evaluated again
+	if resp := evaluate([]*Dep{{Name: "DE", Version: "v5.0.0"}}); resp.Matched {
+		t.Errorf("expected dependency condition not to match a different dependency set")
+	}
+}
+
 func Test_matchDepLabelSelector(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -353,6 +398,7 @@ func Test_GetConfigs(t *testing.T) {
 				"lspServerName":                  "generic",
 				"lspServerPath":                  "/root/go/bin/gopls",
 				"lspServerArgs":                  []interface{}{"string"},
+				"lspMaxConcurrentRequests":       10,
 				"lspServerInitializationOptions": "",
 				"workspaceFolders":               []interface{}{"file:///analyzer-lsp/examples/golang"},
 				"dependencyFolders":              []interface{}{},
@@ -370,6 +416,7 @@ func Test_GetConfigs(t *testing.T) {
 				"lspServerName":                  "generic",
 				"lspServerPath":                  "/root/go/bin/gopls",
 				"lspServerArgs":                  []interface{}{"string"},
+				"lspMaxConcurrentRequests":       10,
 				"lspServerInitializationOptions": "",
 				"workspaceFolders":               []interface{}{"file:///analyzer-lsp/examples/golang"},
 				"dependencyFolders":              []interface{}{},
@@ -385,6 +432,11 @@ func Test_GetConfigs(t *testing.T) {
 			testdataFile: "testdata/provider_settings_invalid.yaml",
 			shouldErr:    true,
 		},
+		{
+			title:        "test unknown key of the schema",
+			testdataFile: "testdata/provider_settings_unknown_key.yaml",
+			shouldErr:    true,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.title, func(t *testing.T) {
@@ -412,3 +464,355 @@ func Test_GetConfigs(t *testing.T) {
 		})
 	}
 }
+
+func Test_BreakDepDAGCycles(t *testing.T) {
+	a := Dep{Name: "a", Version: "1.0"}
+	b := Dep{Name: "b", Version: "2.0"}
+	c := Dep{Name: "c"}
+
+	// a -> b -> c -> a, the slices are shared so walking the items never ends
+	cyclic := make([]DepDAGItem, 1)
+	cyclic[0] = DepDAGItem{
+		Dep: a,
+		AddedDeps: []DepDAGItem{{
+			Dep: b,
+			AddedDeps: []DepDAGItem{{
+				Dep:       c,
+				AddedDeps: cyclic,
+			}},
+		}},
+	}
+
+	tests := []struct {
+		title      string
+		items      []DepDAGItem
+		wantItems  []DepDAGItem
+		wantCycles [][]string
+		wantList   []string
+	}{
+		{
+			title: "graph without cycles is unchanged",
+			items: []DepDAGItem{
+				{Dep: a, AddedDeps: []DepDAGItem{{Dep: c}}},
+				{Dep: b, AddedDeps: []DepDAGItem{{Dep: c}}},
+			},
+			wantItems: []DepDAGItem{
+				{Dep: a, AddedDeps: []DepDAGItem{{Dep: c}}},
+				{Dep: b, AddedDeps: []DepDAGItem{{Dep: c}}},
+			},
+			wantCycles: [][]string{},
+			wantList:   []string{"a", "c", "b", "c"},
+		},
+		{
+			title: "cycle through shared slices becomes a back-edge",
+			items: cyclic,
+			wantItems: []DepDAGItem{{
+				Dep: a,
+				AddedDeps: []DepDAGItem{{
+					Dep: b,
+					AddedDeps: []DepDAGItem{{
+						Dep: c,
+						AddedDeps: []DepDAGItem{{
+							Dep:   a,
+							Cycle: []string{"a@1.0", "b@2.0", "c", "a@1.0"},
+						}},
+					}},
+				}},
+			}},
+			wantCycles: [][]string{{"a@1.0", "b@2.0", "c", "a@1.0"}},
+			wantList:   []string{"a", "b", "c", "a"},
+		},
+		{
+			title: "dependency depending on itself",
+			items: []DepDAGItem{
+				{Dep: b, AddedDeps: []DepDAGItem{{Dep: b}}},
+			},
+			wantItems: []DepDAGItem{
+				{Dep: b, AddedDeps: []DepDAGItem{{Dep: b, Cycle: []string{"b@2.0", "b@2.0"}}}},
+			},
+			wantCycles: [][]string{{"b@2.0", "b@2.0"}},
+			wantList:   []string{"b", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			gotItems, gotCycles := BreakDepDAGCycles(tt.items)
+			if !reflect.DeepEqual(gotItems, tt.wantItems) {
+				t.Errorf("BreakDepDAGCycles() items = %+v, want %+v", gotItems, tt.wantItems)
+			}
+			if !reflect.DeepEqual(gotCycles, tt.wantCycles) {
+				t.Errorf("BreakDepDAGCycles() cycles = %v, want %v", gotCycles, tt.wantCycles)
+			}
+			gotList := []string{}
+			for _, d := range ConvertDagItemsToList(tt.items) {
+				gotList = append(gotList, d.Name)
+			}
+			if !reflect.DeepEqual(gotList, tt.wantList) {
+				t.Errorf("ConvertDagItemsToList() = %v, want %v", gotList, tt.wantList)
+			}
+		})
+	}
+}
+
+type recordedClient struct {
+	fakeClient
+}
+
+func (c *recordedClient) Evaluate(context.Context, string, []byte) (ProviderEvaluateResponse, error) {
+	return ProviderEvaluateResponse{Matched: true, Incidents: []IncidentContext{{FileURI: uri.URI("file:///test/main.go")}}}, nil
+}
+
+func Test_evaluationRecorder(t *testing.T) {
+	recorder := NewEvaluationRecorder("rule-001")
+	ctx := WithEvaluationRecorder(context.Background(), recorder)
+	condition := ProviderCondition{
+		Client:        &recordedClient{},
+		ProviderName:  "test",
+		Capability:    "file",
+		ConditionInfo: map[string]interface{}{"pattern": "*.go"},
+	}
+	for _, ruleID := range []string{"rule-001", "rule-002"} {
+		condCtx := engine.ConditionContext{
+			RuleID:   ruleID,
+			Template: map[string]engine.ChainTemplate{"files": {Filepaths: []string{"/test/main.go"}}},
+		}
+		if _, err := condition.Evaluate(ctx, logr.Discard(), condCtx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	records := recorder.Records()
+	if len(records) != 1 {
+		t.Fatalf("expected only the evaluation of rule-001 to be recorded, got %d", len(records))
+	}
+	record := records[0]
+	if record.Provider != "test" || record.Capability != "file" || !record.Response.Matched {
+		t.Errorf("unexpected record %#v", record)
+	}
+	if !strings.Contains(record.Condition, "pattern: '*.go'") {
+		t.Errorf("expected the condition sent to the provider to be recorded, got %s", record.Condition)
+	}
+	if !reflect.DeepEqual(record.Template["files"].Filepaths, []string{"/test/main.go"}) {
+		t.Errorf("expected the chain context to be recorded, got %v", record.Template)
+	}
+}
+
+type countingClient struct {
+	fakeClient
+	evaluations int
+}
+
+func (c *countingClient) Evaluate(context.Context, string, []byte) (ProviderEvaluateResponse, error) {
+	c.evaluations++
+	return ProviderEvaluateResponse{
+		Matched:   true,
+		Incidents: []IncidentContext{{FileURI: uri.URI("file:///test/Main.java"), Variables: map[string]interface{}{"name": "Main"}}},
+	}, nil
+}
+
+func Test_ProviderCondition_cache(t *testing.T) {
+	client := &countingClient{}
+	ctx := engine.WithConditionCache(context.Background(), engine.NewConditionCache())
+	evaluate := func(ruleID string, capability string, pattern string, template map[string]engine.ChainTemplate) engine.ConditionResponse {
+		condition := ProviderCondition{
+			Client:        client,
+			ProviderName:  "java",
+			Capability:    capability,
+			ConditionInfo: map[string]interface{}{"pattern": pattern},
+		}
+		response, err := condition.Evaluate(ctx, logr.Discard(), engine.ConditionContext{RuleID: ruleID, Template: template})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return response
+	}
+	first := evaluate("rule-001", "referenced", "javax.ejb.*", nil)
+	second := evaluate("rule-002", "referenced", "javax.ejb.*", nil)
+	if client.evaluations != 1 {
+		t.Fatalf("the same condition of several rules must be evaluated once, got %d evaluations", client.evaluations)
+	}
+	first.Incidents[0].Variables["file"] = "changed"
+	if second.Incidents[0].Variables["file"] != nil {
+		t.Errorf("the incidents of the rules sharing a response must not share their variables")
+	}
+	evaluate("rule-003", "referenced", "javax.jms.*", nil)
+	evaluate("rule-004", "annotation", "javax.ejb.*", nil)
+	evaluate("rule-005", "referenced", "javax.ejb.*", map[string]engine.ChainTemplate{
+		engine.TemplateContextPathScopeKey: {Filepaths: []string{"/test/Main.java"}},
+	})
+	if client.evaluations != 4 {
+		t.Errorf("the conditions of other patterns, capabilities or scopes must be evaluated, got %d evaluations", client.evaluations)
+	}
+}
+
+type chainedClient struct {
+	fakeClient
+	conditions []string
+}
+
+func (c *chainedClient) Evaluate(_ context.Context, _ string, conditionInfo []byte) (ProviderEvaluateResponse, error) {
+	c.conditions = append(c.conditions, string(conditionInfo))
+	var cond struct {
+		Referenced struct {
+			Pattern string `yaml:"pattern"`
+		} `yaml:"referenced"`
+	}
+	if err := yaml.Unmarshal(conditionInfo, &cond); err != nil {
+		return ProviderEvaluateResponse{}, err
+	}
+	if cond.Referenced.Pattern == "org.unused.*" {
+		return ProviderEvaluateResponse{}, nil
+	}
+	return ProviderEvaluateResponse{
+		Matched: true,
+		Incidents: []IncidentContext{{
+			FileURI:   uri.URI("file:///test/Main.java"),
+			Variables: map[string]interface{}{"pattern": cond.Referenced.Pattern},
+		}},
+		TemplateContext: map[string]interface{}{"filepaths": []string{"/test/Main.java"}},
+	}, nil
+}
+
+func Test_ProviderCondition_chainedVariables(t *testing.T) {
+	templates := map[string]engine.ChainTemplate{
+		"imports": {
+			Filepaths: []string{"/test/a.properties", "/test/b.properties"},
+			Variables: []map[string]interface{}{
+				{"package": "com.example", "line": 1},
+				{"package": "org.unused", "line": 2},
+				{"package": "com.example", "line": 3},
+				{"line": 4},
+			},
+		},
+	}
+	tests := []struct {
+		name           string
+		conditionInfo  interface{}
+		wantConditions int
+		wantPatterns   []string
+		wantVariables  []string
+	}{
+		{
+			name:           "condition evaluated for each distinct value",
+			conditionInfo:  map[interface{}]interface{}{"pattern": "{{imports.package}}.*"},
+			wantConditions: 2,
+			wantPatterns:   []string{"com.example.*"},
+			wantVariables:  []string{"com.example"},
+		},
+		{
+			name:           "template of a whole value",
+			conditionInfo:  map[interface{}]interface{}{"pattern": "{{ imports.package }}"},
+			wantConditions: 2,
+			wantPatterns:   []string{"com.example", "org.unused"},
+			wantVariables:  []string{"com.example", "org.unused"},
+		},
+		{
+			name:           "unknown variables don't match",
+			conditionInfo:  map[interface{}]interface{}{"pattern": "{{imports.class}}"},
+			wantConditions: 0,
+		},
+		{
+			name:           "fields of the chain template are not variables",
+			conditionInfo:  map[interface{}]interface{}{"pattern": "org.unused.*", "filepaths": "{{imports.Filepaths}}"},
+			wantConditions: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &chainedClient{}
+			condition := ProviderCondition{
+				Client:        client,
+				ProviderName:  "java",
+				Capability:    "referenced",
+				ConditionInfo: tt.conditionInfo,
+			}
+			response, err := condition.Evaluate(context.TODO(), logr.Discard(), engine.ConditionContext{Template: templates})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(client.conditions) != tt.wantConditions {
+				t.Fatalf("expected %d evaluations, got %d: %v", tt.wantConditions, len(client.conditions), client.conditions)
+			}
+			if response.Matched != (len(tt.wantPatterns) > 0) {
+				t.Errorf("expected matched to be %v", len(tt.wantPatterns) > 0)
+			}
+			patterns, variables := []string{}, []string{}
+			for _, incident := range response.Incidents {
+				patterns = append(patterns, fmt.Sprint(incident.Variables["pattern"]))
+				variables = append(variables, fmt.Sprint(incident.Variables["package"]))
+			}
+			if len(tt.wantPatterns) > 0 && !reflect.DeepEqual(patterns, tt.wantPatterns) {
+				t.Errorf("expected incidents of the patterns %v, got %v", tt.wantPatterns, patterns)
+			}
+			if len(tt.wantVariables) > 0 && !reflect.DeepEqual(variables, tt.wantVariables) {
+				t.Errorf("expected incidents with the chained variables %v, got %v", tt.wantVariables, variables)
+			}
+		})
+	}
+}
+
+func Test_chainBindings(t *testing.T) {
+	templates := map[string]engine.ChainTemplate{
+		"a": {Variables: []map[string]interface{}{{"x": "1"}, {"x": "2"}, {"x": "3"}}},
+		"b": {Variables: []map[string]interface{}{{"y": "1"}, {"y": "2"}}},
+	}
+	references := map[string][]string{"a": {"x"}, "b": {"y"}}
+	bindings, truncated := chainBindings(references, templates, 10)
+	if len(bindings) != 6 || truncated {
+		t.Errorf("expected every combination of the values, got %v", bindings)
+	}
+	bindings, truncated = chainBindings(references, templates, 4)
+	if len(bindings) != 4 || !truncated {
+		t.Errorf("expected the bindings to be truncated, got %v", bindings)
+	}
+}
+
+type failingClient struct {
+	fakeClient
+	err error
+}
+
+func (c *failingClient) Evaluate(context.Context, string, []byte) (ProviderEvaluateResponse, error) {
+	return ProviderEvaluateResponse{}, c.err
+}
+
+func TestProviderConditionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code konveyor.ErrorCode
+	}{
+		{
+			name: "classified by the client",
+			err:  engine.NewProviderError(konveyor.ParseError, fmt.Errorf("unable to get query info")),
+			code: konveyor.ParseError,
+		},
+		{
+			name: "deadline exceeded",
+			err:  fmt.Errorf("request failed: %w", context.DeadlineExceeded),
+			code: konveyor.ConditionTimeout,
+		},
+		{
+			name: "unclassified",
+			err:  fmt.Errorf("something went wrong"),
+			code: konveyor.UnknownError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond := ProviderCondition{
+				Client:        &failingClient{err: tt.err},
+				ProviderName:  "java",
+				Capability:    "referenced",
+				ConditionInfo: map[string]interface{}{"pattern": "*"},
+			}
+			_, err := cond.Evaluate(context.Background(), logr.Discard(), engine.ConditionContext{})
+			var pe *engine.ProviderError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected a provider error, got %v", err)
+			}
+			if pe.Code != tt.code || pe.Provider != "java" || pe.Error() != tt.err.Error() {
+				t.Errorf("expected a %s error of the java provider, got %s error of %q: %v", tt.code, pe.Code, pe.Provider, pe)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"sync"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+)
+
+type recorderKey struct{}
+
+// EvaluationRecord is a provider condition evaluated for a rule, with the
+// payload sent to the provider and what it responded.
+type EvaluationRecord struct {
+	Provider   string                          `yaml:"provider"`
+	Capability string                          `yaml:"capability"`
+	Condition  string                          `yaml:"condition"`
+	Template   map[string]engine.ChainTemplate `yaml:"template,omitempty"`
+	Response   ProviderEvaluateResponse        `yaml:"response"`
+	Error      string                          `yaml:"error,omitempty"`
+}
+
+// EvaluationRecorder records the provider conditions evaluated for a single
+// rule, to reproduce its evaluation without the application or the providers.
+type EvaluationRecorder struct {
+	ruleID  string
+	mutex   sync.Mutex
+	records []EvaluationRecord
+}
+
+func NewEvaluationRecorder(ruleID string) *EvaluationRecorder {
+	return &EvaluationRecorder{ruleID: ruleID}
+}
+
+// WithEvaluationRecorder returns a context recording the evaluations of the
+// rule of the recorder in the provider conditions evaluated with it.
+func WithEvaluationRecorder(ctx context.Context, recorder *EvaluationRecorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, recorder)
+}
+
+func (r *EvaluationRecorder) RuleID() string {
+	return r.ruleID
+}
+
+// Records returns the evaluations in the order they finished
+func (r *EvaluationRecorder) Records() []EvaluationRecord {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]EvaluationRecord{}, r.records...)
+}
+
+func recordEvaluation(ctx context.Context, condCtx engine.ConditionContext, record EvaluationRecord) {
+	recorder, ok := ctx.Value(recorderKey{}).(*EvaluationRecorder)
+	if !ok || recorder == nil || recorder.ruleID != condCtx.RuleID {
+		return
+	}
+	// copy the chain context, it is changed by the conditions evaluated next
+	record.Template = map[string]engine.ChainTemplate{}
+	for k, v := range condCtx.Template {
+		record.Template[k] = v
+	}
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.records = append(recorder.records, record)
+}
@@ -15,10 +15,14 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/process"
+	"github.com/konveyor/analyzer-lsp/progress"
 	libgrpc "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
 	"go.lsp.dev/uri"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -30,6 +34,24 @@ const (
 	MAX_MESSAGE_SIZE   = 1024 * 1024 * 8
 )
 
+// ProtocolVersion is the version of the provider gRPC protocol, it is
+// exchanged with the capabilities. Providers built before the protocol was
+// versioned report 0 and no features.
+const ProtocolVersion = 2
+
+// Optional features of the provider gRPC protocol, the engine only sends the
+// requests of a feature to the providers advertising it and degrades otherwise.
+const (
+	DependencyDAGFeature      = "dependencyDAG"
+	CodeSnipFeature           = "codeSnip"
+	DependencyLocationFeature = "dependencyLocation"
+	ProgressFeature           = "progress"
+)
+
+// progressInterval is the most often the progress of a task of a provider is
+// streamed
+const progressInterval = 250 * time.Millisecond
+
 type Server interface {
 	// This will start the GRPC server and will wait until the context is cancelled.
 	Start(context.Context) error
@@ -45,9 +67,18 @@ type server struct {
 	KeyPath             string
 	SecretKey           string
 
+	// socket and security are the transport set by the server options
+	socket   string
+	security serverSecurity
+
 	mutex   sync.RWMutex
 	clients map[int64]clientMapItem
 	rand    rand.Rand
+
+	// progress collects the progress of the initialization of the clients,
+	// streamed to the Progress requests
+	progress        *progress.Collector
+	progressStreams *progressStreams
 	libgrpc.UnimplementedProviderCodeLocationServiceServer
 	libgrpc.UnimplementedProviderDependencyLocationServiceServer
 	libgrpc.UnimplementedProviderServiceServer
@@ -56,11 +87,33 @@ type server struct {
 type clientMapItem struct {
 	ctx    context.Context
 	client ServiceClient
+	// licenses labels the dependencies of the client with the licenses of the
+	// depLicensesFile database
+	licenses *LicenseDatabase
+	// inflight counts the requests being served so that stopping the client
+	// drains them first
+	inflight *sync.WaitGroup
+}
+
+// acquire returns the client with the id, the release function must be called
+// once the request is served.
+func (s *server) acquire(id int64) (clientMapItem, func(), error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	client, ok := s.clients[id]
+	if !ok {
+		return clientMapItem{}, nil, fmt.Errorf("no client with id %d, it was stopped or never initialized", id)
+	}
+	client.inflight.Add(1)
+	return client, client.inflight.Done, nil
 }
 
-// Provider GRPC Service
+// NewServer returns the Provider GRPC Service of the provider client. The
+// server uses TLS with the certificate and key, and verifies the JWTs of the
+// requests with the secret key, the JWT_SECRET environment variable by
+// default. The options set the rest of its transport security.
 // TOOD: HANDLE INIT CONFIG CHANGES
-func NewServer(client BaseClient, port int, certPath string, keyPath string, secretKey string, logger logr.Logger) Server {
+func NewServer(client BaseClient, port int, certPath string, keyPath string, secretKey string, logger logr.Logger, opts ...ServerOption) Server {
 	s := rand.NewSource(time.Now().Unix())
 
 	var depLocationResolver DependencyLocationResolver
@@ -80,7 +133,8 @@ func NewServer(client BaseClient, port int, certPath string, keyPath string, sec
 		secretKey = os.Getenv(JWT_SECRET_ENV_VAR)
 	}
 
-	return &server{
+	streams := &progressStreams{streams: map[chan progress.ProgressEvent]bool{}}
+	srv := &server{
 		Client:                             client,
 		Port:                               port,
 		Log:                                logger,
@@ -93,34 +147,59 @@ func NewServer(client BaseClient, port int, certPath string, keyPath string, sec
 		rand:                               *rand.New(s),
 		DepLocationResolver:                depLocationResolver,
 		CodeSnipeResolver:                  codeSnip,
+		progress:                           progress.NewCollector(progress.WithReporter(progress.NewThrottledReporter(streams, progressInterval))),
+		progressStreams:                    streams,
+	}
+	for _, opt := range opts {
+		opt(srv)
 	}
+	return srv
 }
 
 func (s *server) Start(ctx context.Context) error {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.Port))
-	if err != nil {
-		s.Log.Error(err, "failed to listen")
-		return err
+	// language servers left behind by a provider that crashed before
+	process.ReapOrphans(s.Log)
+	useTLS := s.CertPath != "" && s.KeyPath != ""
+	if !useTLS && (s.CertPath != "" || s.KeyPath != "") {
+		return fmt.Errorf("cert: %v, and key: %v are invalid", s.CertPath, s.KeyPath)
 	}
-	if s.SecretKey != "" && (s.CertPath == "" || s.KeyPath == "") {
+	if s.authenticates() && !useTLS {
 		return fmt.Errorf("to use JWT authentication you must use TLS")
 	}
-	var gs *grpc.Server
-	if s.CertPath != "" && s.KeyPath != "" {
-		creds, err := credentials.NewServerTLSFromFile(s.CertPath, s.KeyPath)
+	if s.security.clientCAPath != "" && !useTLS {
+		return fmt.Errorf("to verify the certificates of the clients you must use TLS")
+	}
+	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(MAX_MESSAGE_SIZE), grpc.MaxSendMsgSize(MAX_MESSAGE_SIZE)}
+	// without TLS only the clients of the host, such as the analyzer that
+	// started the provider, can connect unless it is insecure on purpose
+	address := fmt.Sprintf("127.0.0.1:%d", s.Port)
+	if useTLS {
+		config, err := s.tlsConfig()
 		if err != nil {
 			return err
 		}
-		if s.SecretKey != "" {
-			gs = grpc.NewServer(grpc.Creds(creds), grpc.UnaryInterceptor(s.authUnaryInterceptor))
-		} else {
-			gs = grpc.NewServer(grpc.Creds(creds))
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+		if s.authenticates() {
+			opts = append(opts, grpc.UnaryInterceptor(s.authUnaryInterceptor), grpc.StreamInterceptor(s.authStreamInterceptor))
 		}
-	} else if s.CertPath == "" && s.KeyPath == "" {
-		gs = grpc.NewServer(grpc.MaxRecvMsgSize(MAX_MESSAGE_SIZE), grpc.MaxSendMsgSize(MAX_MESSAGE_SIZE))
+		address = fmt.Sprintf(":%d", s.Port)
+	} else if s.security.insecure && s.socket == "" {
+		s.Log.Info("serving on every interface without TLS, the requests are neither encrypted nor authenticated")
+		address = fmt.Sprintf(":%d", s.Port)
+	}
+	var lis net.Listener
+	var err error
+	if s.socket != "" {
+		// only the users allowed to the socket can connect
+		lis, err = listenSocket(s.socket)
 	} else {
-		return fmt.Errorf("cert: %v, and key: %v are invalid", s.CertPath, s.KeyPath)
+		lis, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		s.Log.Error(err, "failed to listen")
+		return err
 	}
+	gs := grpc.NewServer(opts...)
 	if s.DepLocationResolver != nil {
 		libgrpc.RegisterProviderDependencyLocationServiceServer(gs, s)
 	}
@@ -128,6 +207,8 @@ func (s *server) Start(ctx context.Context) error {
 		libgrpc.RegisterProviderCodeLocationServiceServer(gs, s)
 	}
 	libgrpc.RegisterProviderServiceServer(gs, s)
+	// the analyzer waits for the providers it starts to serve
+	healthpb.RegisterHealthServer(gs, health.NewServer())
 	reflection.Register(gs)
 	s.Log.Info(fmt.Sprintf("server listening at %v", lis.Addr()))
 	if err := gs.Serve(lis); err != nil {
@@ -211,8 +292,19 @@ func (s *server) Capabilities(ctx context.Context, _ *emptypb.Empty) (*libgrpc.C
 		})
 	}
 
+	features := []string{DependencyDAGFeature, ProgressFeature}
+	if s.CodeSnipeResolver != nil {
+		features = append(features, CodeSnipFeature)
+	}
+	if s.DepLocationResolver != nil {
+		features = append(features, DependencyLocationFeature)
+	}
+
 	return &libgrpc.CapabilitiesResponse{
-		Capabilities: pbCaps,
+		Capabilities:    pbCaps,
+		ProtocolVersion: ProtocolVersion,
+		Features:        features,
+		Version:         BuildVersion(),
 	}, nil
 }
 
@@ -240,9 +332,21 @@ func (s *server) Init(ctx context.Context, config *libgrpc.Config) (*libgrpc.Ini
 		c.ProviderSpecificConfig = config.ProviderSpecificConfig.AsMap()
 	}
 
+	licenses, err := GetLicenseDatabaseFromConfig(c)
+	if err != nil {
+		return &libgrpc.InitResponse{
+			Error:      err.Error(),
+			Successful: false,
+		}, nil
+	}
+
 	id := rand.Int63()
 	log := s.Log.WithValues("client", id)
-	newCtx := context.Background()
+	// the client reports the progress of the preparation of the location
+	// with the task of the context
+	task := s.progress.Root().Task(progress.StageProviderPrepare, c.Location, 1)
+	defer task.Done()
+	newCtx := progress.WithTask(context.Background(), task)
 
 	client, builtinConf, err := s.Client.Init(newCtx, log, c)
 	if err != nil {
@@ -253,8 +357,10 @@ func (s *server) Init(ctx context.Context, config *libgrpc.Config) (*libgrpc.Ini
 	}
 	s.mutex.Lock()
 	s.clients[id] = clientMapItem{
-		client: client,
-		ctx:    ctx,
+		client:   client,
+		ctx:      ctx,
+		licenses: licenses,
+		inflight: &sync.WaitGroup{},
 	}
 	s.mutex.Unlock()
 
@@ -273,15 +379,22 @@ func (s *server) Init(ctx context.Context, config *libgrpc.Config) (*libgrpc.Ini
 
 func (s *server) Evaluate(ctx context.Context, req *libgrpc.EvaluateRequest) (*libgrpc.EvaluateResponse, error) {
 
-	s.mutex.RLock()
-	client := s.clients[req.Id]
-	s.mutex.RUnlock()
+	client, release, err := s.acquire(req.Id)
+	if err != nil {
+		return &libgrpc.EvaluateResponse{
+			Error:      err.Error(),
+			ErrorCode:  string(konveyor.ProviderUnavailable),
+			Successful: false,
+		}, nil
+	}
+	defer release()
 
 	r, err := client.client.Evaluate(ctx, req.Cap, []byte(req.ConditionInfo))
 
 	if err != nil {
 		return &libgrpc.EvaluateResponse{
 			Error:      err.Error(),
+			ErrorCode:  string(engine.ErrorCode(err)),
 			Successful: false,
 		}, nil
 	}
@@ -297,6 +410,7 @@ func (s *server) Evaluate(ctx context.Context, req *libgrpc.EvaluateRequest) (*l
 	resp := libgrpc.ProviderEvaluateResponse{
 		Matched:         r.Matched,
 		TemplateContext: templateContext,
+		Warnings:        r.Warnings,
 	}
 
 	incs := []*libgrpc.IncidentContext{}
@@ -323,6 +437,7 @@ func (s *server) Evaluate(ctx context.Context, req *libgrpc.EvaluateRequest) (*l
 			Variables:            variables,
 			Links:                links,
 			IsDependencyIncident: i.IsDependencyIncident,
+			MatchedText:          i.MatchedText,
 		}
 		if i.LineNumber != nil {
 			lineNumber := int64(*i.LineNumber)
@@ -357,17 +472,27 @@ func (s *server) Evaluate(ctx context.Context, req *libgrpc.EvaluateRequest) (*l
 
 func (s *server) Stop(ctx context.Context, in *libgrpc.ServiceRequest) (*emptypb.Empty, error) {
 	s.mutex.Lock()
-	client := s.clients[in.Id]
+	client, ok := s.clients[in.Id]
 	delete(s.clients, in.Id)
 	s.mutex.Unlock()
+	if !ok {
+		return &emptypb.Empty{}, nil
+	}
+	// new requests can't acquire the client anymore, wait for the ones in flight
+	client.inflight.Wait()
 	client.client.Stop()
 	return &emptypb.Empty{}, nil
 }
 
 func (s *server) GetDependencies(ctx context.Context, in *libgrpc.ServiceRequest) (*libgrpc.DependencyResponse, error) {
-	s.mutex.RLock()
-	client := s.clients[in.Id]
-	s.mutex.RUnlock()
+	client, release, err := s.acquire(in.Id)
+	if err != nil {
+		return &libgrpc.DependencyResponse{
+			Successful: false,
+			Error:      err.Error(),
+		}, nil
+	}
+	defer release()
 	deps, err := client.client.GetDependencies(ctx)
 	if err != nil {
 		return &libgrpc.DependencyResponse{
@@ -375,6 +500,7 @@ func (s *server) GetDependencies(ctx context.Context, in *libgrpc.ServiceRequest
 			Error:      err.Error(),
 		}, nil
 	}
+	client.licenses.AddLicenseLabels(deps)
 	fileDeps := []*libgrpc.FileDep{}
 	for f, ds := range deps {
 		fd := libgrpc.FileDep{
@@ -435,10 +561,15 @@ func recreateDAGAddedItems(items []DepDAGItem) []*libgrpc.DependencyDAGItem {
 	return deps
 }
 
-func (s *server) GetDependenciesLinkedList(ctx context.Context, in *libgrpc.ServiceRequest) (*libgrpc.DependencyDAGResponse, error) {
-	s.mutex.RLock()
-	client := s.clients[in.Id]
-	s.mutex.RUnlock()
+func (s *server) GetDependenciesDAG(ctx context.Context, in *libgrpc.ServiceRequest) (*libgrpc.DependencyDAGResponse, error) {
+	client, release, err := s.acquire(in.Id)
+	if err != nil {
+		return &libgrpc.DependencyDAGResponse{
+			Successful: false,
+			Error:      err.Error(),
+		}, nil
+	}
+	defer release()
 	deps, err := client.client.GetDependenciesDAG(ctx)
 	if err != nil {
 		return &libgrpc.DependencyDAGResponse{
@@ -446,8 +577,13 @@ func (s *server) GetDependenciesLinkedList(ctx context.Context, in *libgrpc.Serv
 			Error:      err.Error(),
 		}, nil
 	}
+	client.licenses.AddLicenseLabelsDAG(deps)
 	fileDagDeps := []*libgrpc.FileDAGDep{}
 	for f, ds := range deps {
+		ds, cycles := BreakDepDAGCycles(ds)
+		for _, cycle := range cycles {
+			s.Log.Info("dependency cycle found", "file", f, "cycle", strings.Join(cycle, " -> "))
+		}
 		l := recreateDAGAddedItems(ds)
 		fileDagDeps = append(fileDagDeps, &libgrpc.FileDAGDep{
 			FileURI: string(f),
@@ -463,31 +599,46 @@ func (s *server) GetDependenciesLinkedList(ctx context.Context, in *libgrpc.Serv
 }
 
 func (s *server) authUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *server) authStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorize(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// authorize checks the JWT of the request
+func (s *server) authorize(ctx context.Context) error {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, fmt.Errorf("invalid metadata")
+		return fmt.Errorf("invalid metadata")
 	}
 
 	tokenRaw, ok := md["authorization"]
 	if !ok {
-		return nil, fmt.Errorf("unauthorized")
+		return fmt.Errorf("unauthorized")
 	}
 	if len(tokenRaw) != 1 {
-		return nil, fmt.Errorf("unauthorized")
+		return fmt.Errorf("unauthorized")
 	}
 
 	tokenString := strings.TrimPrefix(tokenRaw[0], "Bearer ")
 
 	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		return []byte(s.SecretKey), nil
-	})
+		return s.verificationKeys()
+	}, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}))
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if !token.Valid {
-		return nil, fmt.Errorf("unauthorized")
+		return fmt.Errorf("unauthorized")
 	}
 	a, _ := token.Claims.GetAudience()
 	i, _ := token.Claims.GetIssuer()
@@ -497,6 +648,68 @@ func (s *server) authUnaryInterceptor(ctx context.Context, req any, info *grpc.U
 		name = fmt.Sprint(claims["name"])
 	}
 	s.Log.Info("user making request", "audience", a, "issuer", i, "subject", sub, "name", name)
+	return nil
+}
 
-	return handler(ctx, req)
+// Progress streams the progress events of the initialization of the clients
+// until the stream is canceled
+func (s *server) Progress(_ *emptypb.Empty, stream libgrpc.ProviderService_ProgressServer) error {
+	if s.progressStreams == nil {
+		return fmt.Errorf("progress is not collected")
+	}
+	events := s.progressStreams.subscribe()
+	defer s.progressStreams.unsubscribe(events)
+	for {
+		select {
+		case e := <-events:
+			err := stream.Send(&libgrpc.ProgressEvent{
+				Id:       e.ID,
+				ParentId: e.ParentID,
+				Stage:    string(e.Stage),
+				Name:     e.Name,
+				Message:  e.Message,
+				Current:  int64(e.Current),
+				Total:    int64(e.Total),
+				Weight:   e.Weight,
+				Done:     e.Done,
+			})
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// progressStreams reports the progress events to the Progress streams, the
+// events are dropped for the streams too slow to take them
+type progressStreams struct {
+	mutex   sync.Mutex
+	streams map[chan progress.ProgressEvent]bool
+}
+
+func (p *progressStreams) subscribe() chan progress.ProgressEvent {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	events := make(chan progress.ProgressEvent, 100)
+	p.streams[events] = true
+	return events
+}
+
+func (p *progressStreams) unsubscribe(events chan progress.ProgressEvent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.streams, events)
+}
+
+func (p *progressStreams) Report(event progress.ProgressEvent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for events := range p.streams {
+		select {
+		case events <- event:
+		default:
+		}
+	}
 }
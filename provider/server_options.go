@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ServerOption configures the transport of a provider server
+type ServerOption func(*server)
+
+// WithSocket makes the server listen on the unix socket of the path instead
+// of its port, or on the named pipe of a path starting with \\.\pipe\ on
+// Windows
+func WithSocket(path string) ServerOption {
+	return func(s *server) {
+		s.socket = path
+	}
+}
+
+// WithClientCA makes the server verify the certificates of the clients
+// against the CA certificates of the file, mutual TLS
+func WithClientCA(path string) ServerOption {
+	return func(s *server) {
+		s.security.clientCAPath = path
+	}
+}
+
+// WithSecretKeyFile makes the server verify the JWTs of the requests with
+// the secret keys of the file, one per line. The file is read again when it
+// changes, a key is rotated by adding the new key, moving the clients to
+// tokens signed with it, then removing the old key.
+func WithSecretKeyFile(path string) ServerOption {
+	return func(s *server) {
+		s.security.secretKeys = &secretKeyFile{path: path}
+	}
+}
+
+// WithTLSPolicy sets the lowest TLS version the server accepts, "1.2" or
+// "1.3", and the cipher suites of TLS 1.2 by their names, the defaults of Go
+// when empty
+func WithTLSPolicy(minVersion string, cipherSuites []string) ServerOption {
+	return func(s *server) {
+		s.security.minVersion = minVersion
+		s.security.cipherSuites = cipherSuites
+	}
+}
+
+// WithInsecure makes a server without TLS listen on every interface, it only
+// listens on the loopback interface otherwise
+func WithInsecure() ServerOption {
+	return func(s *server) {
+		s.security.insecure = true
+	}
+}
+
+// serverSecurity is the transport security of a server
+type serverSecurity struct {
+	clientCAPath string
+	secretKeys   *secretKeyFile
+	minVersion   string
+	cipherSuites []string
+	insecure     bool
+}
+
+// tlsConfig returns the TLS config of the certificate and key of the server
+func (s *server) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(s.CertPath, s.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the certificate %s and key %s: %w", s.CertPath, s.KeyPath, err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	switch s.security.minVersion {
+	case "", "1.2":
+	case "1.3":
+		config.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported TLS version %s, must be 1.2 or 1.3", s.security.minVersion)
+	}
+	if len(s.security.cipherSuites) != 0 {
+		suites := map[string]uint16{}
+		for _, suite := range tls.CipherSuites() {
+			suites[suite.Name] = suite.ID
+		}
+		for _, name := range s.security.cipherSuites {
+			id, ok := suites[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown or insecure cipher suite %s", name)
+			}
+			config.CipherSuites = append(config.CipherSuites, id)
+		}
+	}
+	if s.security.clientCAPath != "" {
+		content, err := os.ReadFile(s.security.clientCAPath)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no CA certificate in %s", s.security.clientCAPath)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// verificationKeys returns the keys the JWTs of the requests are verified
+// with, the secret key and the ones of the secret key file
+func (s *server) verificationKeys() (jwt.VerificationKeySet, error) {
+	keys := jwt.VerificationKeySet{}
+	if s.SecretKey != "" {
+		keys.Keys = append(keys.Keys, []byte(s.SecretKey))
+	}
+	if s.security.secretKeys != nil {
+		fileKeys, err := s.security.secretKeys.keys()
+		if err != nil {
+			return keys, err
+		}
+		for _, k := range fileKeys {
+			keys.Keys = append(keys.Keys, k)
+		}
+	}
+	if len(keys.Keys) == 0 {
+		return keys, fmt.Errorf("no secret key")
+	}
+	return keys, nil
+}
+
+// authenticates reports whether the requests are authenticated with JWTs
+func (s *server) authenticates() bool {
+	return s.SecretKey != "" || s.security.secretKeys != nil
+}
+
+// secretKeyFile is a file of secret keys read again when it changes
+type secretKeyFile struct {
+	path string
+
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+	secrets [][]byte
+}
+
+func (f *secretKeyFile) keys() ([][]byte, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.secrets != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.secrets, nil
+	}
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	secrets := [][]byte{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			secrets = append(secrets, []byte(line))
+		}
+	}
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("no secret key in %s", f.path)
+	}
+	f.secrets, f.modTime, f.size = secrets, info.ModTime(), info.Size()
+	return secrets, nil
+}
+
+// ServerFlags are the flags of the providers setting the transport of their
+// server
+type ServerFlags struct {
+	Socket        string
+	ClientCAFile  string
+	SecretKeyFile string
+	TLSMinVersion string
+	CipherSuites  string
+	Insecure      bool
+}
+
+// NewServerFlags registers the transport flags in the flag set, the options
+// of the server are read from them once it is parsed
+func NewServerFlags(fs *flag.FlagSet) *ServerFlags {
+	f := &ServerFlags{}
+	fs.StringVar(&f.Socket, "socket", "", "Path to the unix socket, or the named pipe on Windows, listened on instead of the port")
+	fs.StringVar(&f.ClientCAFile, "clientCAFile", "", "Path to the CA certificates of the clients, requires and verifies client certificates (mutual TLS)")
+	fs.StringVar(&f.SecretKeyFile, "secretKeyFile", "", "Path to a file of JWT secret keys, one per line, read again when it changes")
+	fs.StringVar(&f.TLSMinVersion, "tlsMinVersion", "1.2", "Lowest TLS version accepted, 1.2 or 1.3")
+	fs.StringVar(&f.CipherSuites, "tlsCipherSuites", "", "Comma separated TLS 1.2 cipher suites accepted, the defaults of Go when empty")
+	fs.BoolVar(&f.Insecure, "insecure", false, "Listen on every interface without TLS, only the loopback interface is listened on without TLS otherwise")
+	return f
+}
+
+// Options returns the server options of the flags
+func (f *ServerFlags) Options() []ServerOption {
+	opts := []ServerOption{}
+	if f.Socket != "" {
+		opts = append(opts, WithSocket(f.Socket))
+	}
+	if f.ClientCAFile != "" {
+		opts = append(opts, WithClientCA(f.ClientCAFile))
+	}
+	if f.SecretKeyFile != "" {
+		opts = append(opts, WithSecretKeyFile(f.SecretKeyFile))
+	}
+	suites := []string{}
+	for _, suite := range strings.Split(f.CipherSuites, ",") {
+		if suite = strings.TrimSpace(suite); suite != "" {
+			suites = append(suites, suite)
+		}
+	}
+	opts = append(opts, WithTLSPolicy(f.TLSMinVersion, suites))
+	if f.Insecure {
+		opts = append(opts, WithInsecure())
+	}
+	return opts
+}
@@ -0,0 +1,191 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/phayes/freeport"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/oauth"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// writeCert writes a certificate of the host signed by the parent, or self
+// signed without parent, and its key
+func writeCert(t *testing.T, dir, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func signedToken(t *testing.T, secret string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "analyzer"}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func Test_serverMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeCert(t, dir, "ca", true, nil, nil)
+	writeCert(t, dir, "server", false, ca, caKey)
+	writeCert(t, dir, "client", false, ca, caKey)
+	keys := filepath.Join(dir, "keys")
+	if err := os.WriteFile(keys, []byte("# keys of the analyzers\nfirst-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := freeport.GetFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(&preparingClient{}, port, filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), "", logr.Discard(),
+		WithClientCA(filepath.Join(dir, "ca.crt")), WithSecretKeyFile(keys), WithTLSPolicy("1.3", nil))
+	go s.Start(context.Background())
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(certs []tls.Certificate, token string) error {
+		opts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool, Certificates: certs}))}
+		if token != "" {
+			opts = append(opts, grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})}))
+		}
+		conn, err := grpc.Dial(net.JoinHostPort("localhost", strconv.Itoa(port)), opts...)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		// the handshakes refused by the server are retried until the timeout
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+		return err
+	}
+
+	// the server must be serving before the failures are checked
+	if err := check([]tls.Certificate{clientCert}, signedToken(t, "first-secret")); err != nil {
+		t.Fatalf("request with a client certificate and a valid token failed: %v", err)
+	}
+	if err := check(nil, signedToken(t, "first-secret")); err == nil {
+		t.Errorf("request without a client certificate must fail")
+	}
+	if err := check([]tls.Certificate{clientCert}, ""); err == nil {
+		t.Errorf("request without a token must fail")
+	}
+
+	// rotate the key
+	if err := os.WriteFile(keys, []byte("second-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(keys, later, later)
+	if err := check([]tls.Certificate{clientCert}, signedToken(t, "first-secret")); err == nil {
+		t.Errorf("request with a token of a removed key must fail")
+	}
+	if err := check([]tls.Certificate{clientCert}, signedToken(t, "second-secret")); err != nil {
+		t.Errorf("request with a token of the new key failed: %v", err)
+	}
+}
+
+func Test_serverTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeCert(t, dir, "ca", true, nil, nil)
+	writeCert(t, dir, "server", false, ca, caKey)
+	tests := []struct {
+		name   string
+		opts   []ServerOption
+		errMsg string
+	}{
+		{name: "default", opts: nil},
+		{name: "cipher suites", opts: []ServerOption{WithTLSPolicy("1.2", []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"})}},
+		{name: "old version", opts: []ServerOption{WithTLSPolicy("1.0", nil)}, errMsg: "unsupported TLS version 1.0, must be 1.2 or 1.3"},
+		{name: "insecure cipher suite", opts: []ServerOption{WithTLSPolicy("1.2", []string{"TLS_RSA_WITH_RC4_128_SHA"})}, errMsg: "unknown or insecure cipher suite TLS_RSA_WITH_RC4_128_SHA"},
+		{name: "no CA", opts: []ServerOption{WithClientCA(filepath.Join(dir, "server.key"))}, errMsg: "no CA certificate in " + filepath.Join(dir, "server.key")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(&preparingClient{}, 0, filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), "", logr.Discard(), tt.opts...).(*server)
+			config, err := s.tlsConfig()
+			if tt.errMsg != "" {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Errorf("tlsConfig() error = %v, want %s", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tlsConfig() error = %v", err)
+			}
+			if config.MinVersion != tls.VersionTLS12 {
+				t.Errorf("TLS versions before 1.2 must be refused")
+			}
+		})
+	}
+}
+
+func Test_serverStartRequiresTLS(t *testing.T) {
+	tests := map[string][]ServerOption{
+		"to use JWT authentication you must use TLS":                 {WithSecretKeyFile("keys")},
+		"to verify the certificates of the clients you must use TLS": {WithClientCA("ca.crt")},
+	}
+	for errMsg, opts := range tests {
+		s := NewServer(&preparingClient{}, 0, "", "", "", logr.Discard(), opts...)
+		if err := s.Start(context.Background()); err == nil || err.Error() != errMsg {
+			t.Errorf("Start() error = %v, want %s", err, errMsg)
+		}
+	}
+}
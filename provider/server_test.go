@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/progress"
+	libgrpc "github.com/konveyor/analyzer-lsp/provider/internal/grpc"
+)
+
+type blockingServiceClient struct {
+	fakeClient
+	started chan struct{}
+	finish  chan struct{}
+	mutex   sync.Mutex
+	stopped bool
+}
+
+func (c *blockingServiceClient) Evaluate(context.Context, string, []byte) (ProviderEvaluateResponse, error) {
+	close(c.started)
+	<-c.finish
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return ProviderEvaluateResponse{Matched: !c.stopped}, nil
+}
+
+func (c *blockingServiceClient) Stop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stopped = true
+}
+
+func Test_serverStopDrainsRequests(t *testing.T) {
+	client := &blockingServiceClient{started: make(chan struct{}), finish: make(chan struct{})}
+	s := &server{
+		Log: logr.Discard(),
+		clients: map[int64]clientMapItem{
+			1: {client: client, ctx: context.Background(), inflight: &sync.WaitGroup{}},
+		},
+	}
+
+	evaluated := make(chan *libgrpc.EvaluateResponse)
+	go func() {
+		r, _ := s.Evaluate(context.Background(), &libgrpc.EvaluateRequest{Id: 1, Cap: "test"})
+		evaluated <- r
+	}()
+	<-client.started
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop(context.Background(), &libgrpc.ServiceRequest{Id: 1})
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatalf("expected stop to wait for the evaluation in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(client.finish)
+	if r := <-evaluated; !r.Successful || !r.Response.Matched {
+		t.Errorf("expected the evaluation to complete before the client was stopped, got %v", r)
+	}
+	<-stopped
+
+	r, _ := s.Evaluate(context.Background(), &libgrpc.EvaluateRequest{Id: 1, Cap: "test"})
+	if r.Successful {
+		t.Errorf("expected an error evaluating with a stopped client")
+	}
+	if r.ErrorCode != string(konveyor.ProviderUnavailable) {
+		t.Errorf("expected the provider to be unavailable, got %q", r.ErrorCode)
+	}
+}
+
+type preparingClient struct {
+	fakeClient
+}
+
+func (c *preparingClient) Init(ctx context.Context, _ logr.Logger, _ InitConfig) (ServiceClient, InitConfig, error) {
+	indexing := progress.FromContext(ctx).Task(progress.StageProviderPrepare, "indexing", 1)
+	indexing.Update(1200, 4000, "files")
+	indexing.Done()
+	return &fakeClient{}, InitConfig{}, nil
+}
+
+func Test_serverProgress(t *testing.T) {
+	s := NewServer(&preparingClient{}, 0, "", "", "", logr.Discard()).(*server)
+	events := s.progressStreams.subscribe()
+	defer s.progressStreams.unsubscribe(events)
+
+	r, err := s.Init(context.Background(), &libgrpc.Config{Location: "/app", Proxy: &libgrpc.Proxy{}})
+	if err != nil || !r.Successful {
+		t.Fatalf("unexpected init failure %v %v", r, err)
+	}
+	got := []string{}
+	for len(events) > 0 {
+		e := <-events
+		got = append(got, fmt.Sprintf("%s %d/%d %v", e.ID, e.Current, e.Total, e.Done))
+	}
+	// the update of the indexing is throttled, its start and end are not
+	want := []string{
+		"analysis/-app 0/0 false",
+		"analysis/-app/indexing 0/0 false",
+		"analysis/-app/indexing 4000/4000 true",
+		"analysis/-app 0/0 true",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the events %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	buildVersion     string
+	buildVersionOnce sync.Once
+)
+
+// BuildVersion returns the version of the running binary, the version of its
+// main module and its revision. A binary built without them, such as one
+// built out of a checkout, is identified by the digest of its executable
+// instead, so that every build has a version of its own.
+func BuildVersion() string {
+	buildVersionOnce.Do(func() {
+		buildVersion = readBuildVersion()
+	})
+	return buildVersion
+}
+
+func readBuildVersion() string {
+	version, revision := "", ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.modified":
+				if setting.Value == "true" {
+					// the uncommitted changes are not part of the revision
+					revision = ""
+				}
+			}
+		}
+	}
+	if revision != "" {
+		return version + "+" + revision
+	}
+	if version != "" && version != "(devel)" {
+		return version
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return version
+	}
+	f, err := os.Open(executable)
+	if err != nil {
+		return version
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return version
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
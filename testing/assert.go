@@ -0,0 +1,127 @@
+package testing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+// UpdateGoldenEnvVar rewrites the golden files with the rulesets asserted
+// instead of comparing them when it is set to true
+const UpdateGoldenEnvVar = "KONVEYOR_UPDATE_GOLDEN"
+
+// FindViolation returns the violation, or the insight, of the rule in the
+// rulesets
+func FindViolation(ruleSets []konveyor.RuleSet, ruleID string) (konveyor.Violation, bool) {
+	for _, ruleSet := range ruleSets {
+		if v, ok := ruleSet.Violations[ruleID]; ok {
+			return v, true
+		}
+		if v, ok := ruleSet.Insights[ruleID]; ok {
+			return v, true
+		}
+	}
+	return konveyor.Violation{}, false
+}
+
+// AssertMatched checks that the rules have a violation or an insight
+func AssertMatched(t TB, ruleSets []konveyor.RuleSet, ruleIDs ...string) {
+	t.Helper()
+	for _, ruleID := range ruleIDs {
+		if _, ok := FindViolation(ruleSets, ruleID); !ok {
+			t.Errorf("rule %s did not match, %s", ruleID, ruleStatus(ruleSets, ruleID))
+		}
+	}
+}
+
+// AssertUnmatched checks that the rules were evaluated without matching
+func AssertUnmatched(t TB, ruleSets []konveyor.RuleSet, ruleIDs ...string) {
+	t.Helper()
+	for _, ruleID := range ruleIDs {
+		if status := ruleStatus(ruleSets, ruleID); status != "it is unmatched" {
+			t.Errorf("rule %s is not unmatched, %s", ruleID, status)
+		}
+	}
+}
+
+// AssertIncidents checks the number of incidents of the violation, or the
+// insight, of the rule
+func AssertIncidents(t TB, ruleSets []konveyor.RuleSet, ruleID string, want int) {
+	t.Helper()
+	v, ok := FindViolation(ruleSets, ruleID)
+	if !ok {
+		t.Errorf("rule %s did not match, %s", ruleID, ruleStatus(ruleSets, ruleID))
+		return
+	}
+	if len(v.Incidents) != want {
+		t.Errorf("rule %s has %d incidents, want %d", ruleID, len(v.Incidents), want)
+	}
+}
+
+// ruleStatus tells what became of the rule in the rulesets
+func ruleStatus(ruleSets []konveyor.RuleSet, ruleID string) string {
+	if _, ok := FindViolation(ruleSets, ruleID); ok {
+		return "it matched"
+	}
+	for _, ruleSet := range ruleSets {
+		if ruleErr, ok := ruleSet.Errors[ruleID]; ok {
+			return "it failed: " + ruleErr.Message
+		}
+		for _, id := range ruleSet.Unmatched {
+			if id == ruleID {
+				return "it is unmatched"
+			}
+		}
+		for _, id := range ruleSet.Skipped {
+			if id == ruleID {
+				return "it was skipped"
+			}
+		}
+	}
+	return "it was not evaluated"
+}
+
+// AssertGolden compares the rulesets with the ones of the golden file, as
+// YAML. The golden file is written with the rulesets when the
+// KONVEYOR_UPDATE_GOLDEN environment variable is true.
+func AssertGolden(t TB, ruleSets []konveyor.RuleSet, path string) {
+	t.Helper()
+	got, err := yaml.Marshal(ruleSets)
+	if err != nil {
+		t.Fatalf("unable to marshal the rulesets: %v", err)
+	}
+	if os.Getenv(UpdateGoldenEnvVar) == "true" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("unable to write the golden file %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("unable to write the golden file %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read the golden file %s, set %s=true to write it: %v", path, UpdateGoldenEnvVar, err)
+	}
+	if string(got) == string(want) {
+		return
+	}
+	gotLines := strings.Split(string(got), "\n")
+	wantLines := strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		gotLine, wantLine := "<end of file>", "<end of file>"
+		if i < len(gotLines) {
+			gotLine = gotLines[i]
+		}
+		if i < len(wantLines) {
+			wantLine = wantLines[i]
+		}
+		if gotLine != wantLine {
+			t.Errorf("the rulesets differ from the golden file %s at line %d:\ngot:  %s\nwant: %s\nset %s=true to update it", path, i+1, gotLine, wantLine, UpdateGoldenEnvVar)
+			return
+		}
+	}
+}
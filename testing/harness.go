@@ -0,0 +1,160 @@
+// Package testing runs rules in the Go tests of rulesets, against fixture
+// providers responding with canned incidents and the builtin provider over
+// fixture directories, and asserts on the rulesets they result in.
+//
+//	func TestRules(t *testing.T) {
+//		h := konveyortesting.New(t,
+//			konveyortesting.WithBuiltin("testdata/app"),
+//			konveyortesting.WithProvider(&konveyortesting.Provider{
+//				Name: "java",
+//				Fixtures: []konveyortesting.Fixture{{
+//					Capability: "referenced",
+//					Condition:  "javax.ejb.*",
+//					Incidents:  []provider.IncidentContext{{FileURI: "file:///src/Bean.java"}},
+//				}},
+//			}))
+//		ruleSets := h.Run("rules")
+//		konveyortesting.AssertMatched(t, ruleSets, "ejb-00001")
+//		konveyortesting.AssertGolden(t, ruleSets, "testdata/rules.golden.yaml")
+//	}
+package testing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/lib"
+)
+
+// TB is the part of testing.TB the harness uses
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	TempDir() string
+	Cleanup(func())
+}
+
+// Harness runs rules against its providers, every run parses the rules and
+// evaluates them with an engine of its own
+type Harness struct {
+	t         TB
+	log       logr.Logger
+	providers map[string]provider.InternalProviderClient
+	locations []string
+	options   []engine.Option
+}
+
+type Option func(*Harness)
+
+// WithProvider adds a fixture provider, the rules use it by its name
+func WithProvider(p *Provider) Option {
+	return func(h *Harness) {
+		h.providers[p.Name] = p
+	}
+}
+
+// WithBuiltin evaluates the builtin conditions on the files of the location.
+// The incidents in the locations given as relative paths are reported with
+// paths relative to the working directory of the test, such as
+// file:///testdata/app/pom.xml, so that they are the same on every machine.
+func WithBuiltin(locations ...string) Option {
+	return func(h *Harness) {
+		h.locations = append(h.locations, locations...)
+	}
+}
+
+// WithEngineOptions adds options to the engines evaluating the rules
+func WithEngineOptions(options ...engine.Option) Option {
+	return func(h *Harness) {
+		h.options = append(h.options, options...)
+	}
+}
+
+// WithLogger logs the parsing and evaluation of the rules, nothing is logged
+// by default
+func WithLogger(log logr.Logger) Option {
+	return func(h *Harness) {
+		h.log = log
+	}
+}
+
+// New creates a harness failing the test when the rules can't be run
+func New(t TB, options ...Option) *Harness {
+	h := &Harness{
+		t:         t,
+		log:       logr.Discard(),
+		providers: map[string]provider.InternalProviderClient{},
+	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// Run evaluates the rules of the file or directory of rulesets, as the
+// analyzer does, and returns the sorted rulesets resulting from them
+func (h *Harness) Run(rulesPath string, selectors ...engine.RuleSelector) []konveyor.RuleSet {
+	h.t.Helper()
+	providers := map[string]provider.InternalProviderClient{}
+	for name, p := range h.providers {
+		providers[name] = p
+	}
+	if len(h.locations) > 0 {
+		config := provider.Config{Name: "builtin"}
+		for _, location := range h.locations {
+			abs, err := filepath.Abs(location)
+			if err != nil {
+				h.t.Fatalf("unable to find the location %s: %v", location, err)
+			}
+			config.InitConfig = append(config.InitConfig, provider.InitConfig{Location: abs})
+		}
+		builtin, err := lib.GetProviderClient(config, h.log)
+		if err != nil {
+			h.t.Fatalf("unable to create the builtin provider: %v", err)
+		}
+		providers["builtin"] = builtin
+	}
+
+	ruleParser := parser.RuleParser{
+		ProviderNameToClient: providers,
+		Log:                  h.log,
+	}
+	ruleSets, needProviders, err := ruleParser.LoadRules(rulesPath)
+	if err != nil {
+		h.t.Fatalf("unable to parse the rules of %s: %v", rulesPath, err)
+	}
+	ctx := context.Background()
+	if err := provider.InitProviders(ctx, h.log, needProviders, 0); err != nil {
+		h.t.Fatalf("unable to init the providers: %v", err)
+	}
+	defer func() {
+		for _, p := range needProviders {
+			p.Stop()
+		}
+	}()
+
+	options := append([]engine.Option{engine.WithLocationPrefixes(h.locations)}, h.options...)
+	eng := engine.CreateRuleEngine(ctx, 10, h.log, options...)
+	defer eng.Stop()
+	results := eng.RunRules(ctx, ruleSets, selectors...)
+	konveyor.SortRuleSets(results)
+	return results
+}
+
+// RunRules evaluates the rules of the YAML, a list of rules of the default
+// ruleset, and returns the sorted rulesets resulting from them
+func (h *Harness) RunRules(rules string, selectors ...engine.RuleSelector) []konveyor.RuleSet {
+	h.t.Helper()
+	path := filepath.Join(h.t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(rules), 0644); err != nil {
+		h.t.Fatalf("unable to write the rules: %v", err)
+	}
+	return h.Run(path, selectors...)
+}
@@ -0,0 +1,100 @@
+package testing_test
+
+import (
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	konveyortesting "github.com/konveyor/analyzer-lsp/testing"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+var java = &konveyortesting.Provider{
+	Name: "java",
+	Fixtures: []konveyortesting.Fixture{
+		{
+			Capability: "referenced",
+			Condition:  "javax.ejb.Stateless",
+			Incidents: []provider.IncidentContext{{
+				FileURI:    "file:///testdata/app/src/App.java",
+				LineNumber: intPtr(5),
+				Variables:  map[string]interface{}{"name": "Stateless"},
+			}},
+		},
+		{
+			Capability: "referenced",
+			Condition:  "org.springframework",
+		},
+	},
+}
+
+func TestHarness(t *testing.T) {
+	h := konveyortesting.New(t,
+		konveyortesting.WithProvider(java),
+		konveyortesting.WithBuiltin("testdata/app"))
+	ruleSets := h.Run("testdata/rules")
+	konveyortesting.AssertMatched(t, ruleSets, "ejb-00001", "ejb-00002")
+	konveyortesting.AssertUnmatched(t, ruleSets, "spring-00001")
+	konveyortesting.AssertIncidents(t, ruleSets, "ejb-00002", 1)
+	konveyortesting.AssertGolden(t, ruleSets, "testdata/rules.golden.yaml")
+}
+
+func TestHarnessRunRules(t *testing.T) {
+	h := konveyortesting.New(t, konveyortesting.WithProvider(java))
+	ruleSets := h.RunRules(`
+- ruleID: chained
+  effort: 1
+  message: chained
+  when:
+    or:
+    - java.referenced:
+        pattern: org.springframework.*
+    - java.referenced:
+        pattern: javax.ejb.Stateless
+`)
+	if len(ruleSets) != 1 || ruleSets[0].Name != "konveyor-analysis" {
+		t.Fatalf("RunRules() = %v, want the default ruleset", ruleSets)
+	}
+	konveyortesting.AssertIncidents(t, ruleSets, "chained", 1)
+	v, _ := konveyortesting.FindViolation(ruleSets, "chained")
+	if v.Incidents[0].Variables["name"] != "Stateless" {
+		t.Errorf("the variables of the fixture are lost: %v", v.Incidents[0].Variables)
+	}
+}
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func TestAssertions(t *testing.T) {
+	h := konveyortesting.New(t, konveyortesting.WithProvider(java))
+	ruleSets := h.RunRules(`
+- ruleID: ejb-00001
+  effort: 3
+  message: EJB
+  when:
+    java.referenced:
+      pattern: javax.ejb.Stateless
+- ruleID: spring-00001
+  effort: 1
+  message: Spring
+  when:
+    java.referenced:
+      pattern: org.springframework.*
+`)
+	r := &recorder{TB: t}
+	konveyortesting.AssertMatched(r, ruleSets, "spring-00001")
+	konveyortesting.AssertUnmatched(r, ruleSets, "ejb-00001")
+	konveyortesting.AssertIncidents(r, ruleSets, "ejb-00001", 2)
+	konveyortesting.AssertGolden(r, ruleSets, "testdata/rules.golden.yaml")
+	if len(r.errors) != 4 {
+		t.Errorf("the assertions must fail, got %d failures", len(r.errors))
+	}
+}
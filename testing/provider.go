@@ -0,0 +1,11 @@
+package testing
+
+import "github.com/konveyor/analyzer-lsp/provider/mock"
+
+// Fixture is what a fixture provider responds to the conditions of one of
+// its capabilities
+type Fixture = mock.Fixture
+
+// Provider is a provider responding to conditions with fixtures instead of
+// analyzing an application, the mock provider of the analyzer
+type Provider = mock.Provider
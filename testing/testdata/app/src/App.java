@@ -0,0 +1,7 @@
+package app;
+
+import javax.ejb.Stateless;
+
+@Stateless
+public class App {
+}
@@ -0,0 +1,50 @@
+// Package textfile reads the lines of text files one at a time, so that only
+// the lines needed are held in memory rather than whole files, which can be
+// generated files of hundreds of megabytes such as SQL dumps.
+package textfile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// MaxLineLength is the most bytes of a line returned by the readers, the rest
+// of longer lines, such as the lines of minified files, is dropped
+const MaxLineLength = 64 * 1024
+
+// Reader reads the lines of a text.
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Line returns the next line without its line ending, truncated to
+// MaxLineLength bytes. It returns io.EOF after the last line.
+func (r *Reader) Line() (string, error) {
+	line := []byte{}
+	read := false
+	for {
+		chunk, err := r.r.ReadSlice('\n')
+		read = read || len(chunk) > 0
+		if len(line) < MaxLineLength {
+			line = append(line, chunk[:min(len(chunk), MaxLineLength-len(line))]...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && read {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		break
+	}
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	return string(line), nil
+}
@@ -0,0 +1,56 @@
+package textfile
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReaderLine(t *testing.T) {
+	long := strings.Repeat("x", MaxLineLength+100)
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "empty",
+			content: "",
+			want:    []string{},
+		},
+		{
+			name:    "line endings",
+			content: "a\nb\r\n\nc",
+			want:    []string{"a", "b", "", "c"},
+		},
+		{
+			name:    "trailing line ending",
+			content: "a\nb\n",
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "long lines are truncated",
+			content: "a\n" + long + "\nb\n",
+			want:    []string{"a", long[:MaxLineLength], "b"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.content))
+			got := []string{}
+			for {
+				line, err := r.Line()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, line)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}